@@ -11,7 +11,9 @@ import (
 	handlerDashboard "github.com/anoixa/image-bed/api/handler/dashboard"
 	handlerImages "github.com/anoixa/image-bed/api/handler/images"
 	"github.com/anoixa/image-bed/api/handler/key"
+	handlerShares "github.com/anoixa/image-bed/api/handler/shares"
 	handlerSystem "github.com/anoixa/image-bed/api/handler/system"
+	handlerTags "github.com/anoixa/image-bed/api/handler/tags"
 	handlerUser "github.com/anoixa/image-bed/api/handler/user"
 	"github.com/anoixa/image-bed/api/middleware"
 	"github.com/anoixa/image-bed/cache"
@@ -23,6 +25,7 @@ import (
 	"github.com/anoixa/image-bed/internal/auth"
 	svcDashboard "github.com/anoixa/image-bed/internal/dashboard"
 	imageSvc "github.com/anoixa/image-bed/internal/image"
+	svcTags "github.com/anoixa/image-bed/internal/tags"
 	svcUser "github.com/anoixa/image-bed/internal/user"
 	"github.com/anoixa/image-bed/public"
 	"github.com/anoixa/image-bed/storage"
@@ -47,6 +50,7 @@ type RouterDependencies struct {
 	Repositories      *Repositories
 	ConfigManager     *configSvc.Manager
 	Converter         *imageSvc.Converter
+	Reprocess         *imageSvc.ReprocessService
 	JWTService        *auth.JWTService
 	LoginService      *auth.LoginService
 	AuthRateLimiter   *middleware.IPRateLimiter
@@ -62,10 +66,14 @@ type RouterDependencies struct {
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(router *gin.Engine, deps *RouterDependencies) {
 	imageHandler := newImageHandler(deps)
+	shareHandler := handlerShares.NewHandler(
+		svcAlbums.NewShareService(deps.Repositories.SharesRepo, deps.Repositories.AlbumsRepo),
+		getBaseURL(deps.Config),
+	)
 
 	registerBasicRoutes(router, deps)
-	registerPublicRoutes(router, deps, imageHandler)
-	registerAPIRoutes(router, deps, imageHandler)
+	registerPublicRoutes(router, deps, imageHandler, shareHandler)
+	registerAPIRoutes(router, deps, imageHandler, shareHandler)
 
 	if deps.Config != nil && deps.Config.ServeFrontend {
 		registerStaticRoutes(router)
@@ -84,6 +92,7 @@ func newImageHandler(deps *RouterDependencies) *handlerImages.Handler {
 		cfg,
 		baseURL,
 		deps.Repositories.AlbumsRepo,
+		deps.Repositories.AccountsRepo,
 	)
 }
 
@@ -121,7 +130,7 @@ func registerBasicRoutes(router *gin.Engine, deps *RouterDependencies) {
 }
 
 // registerPublicRoutes 注册公共接口路由
-func registerPublicRoutes(router *gin.Engine, deps *RouterDependencies, imageHandler *handlerImages.Handler) {
+func registerPublicRoutes(router *gin.Engine, deps *RouterDependencies, imageHandler *handlerImages.Handler, shareHandler *handlerShares.Handler) {
 	// 公共图片访问
 	publicGroup := router.Group("/images")
 	if deps.PublicConcurrency != nil {
@@ -132,6 +141,7 @@ func registerPublicRoutes(router *gin.Engine, deps *RouterDependencies, imageHan
 	{
 		publicGroup.GET("/random", imageHandler.RandomImage)
 		publicGroup.GET("/:identifier", imageHandler.GetImage)
+		publicGroup.GET("/:identifier/srcset", imageHandler.GetSrcset)
 	}
 
 	thumbnailGroup := router.Group("/thumbnails")
@@ -144,10 +154,22 @@ func registerPublicRoutes(router *gin.Engine, deps *RouterDependencies, imageHan
 		thumbnailGroup.GET("/:identifier", imageHandler.GetThumbnail)
 	}
 
+	// 相册分享链接：只读访问相册及其中的图片（含私有图片）
+	shareGroup := router.Group("/s/:token")
+	if deps.PublicConcurrency != nil {
+		shareGroup.Use(deps.PublicConcurrency.Middleware())
+	}
+	shareGroup.Use(deps.ImageRateLimiter.Middleware())
+	{
+		shareGroup.GET("", shareHandler.GetSharedAlbum)
+		shareGroup.POST("/unlock", deps.AuthRateLimiter.Middleware(), shareHandler.UnlockShare)
+		shareGroup.GET("/images/:identifier", shareHandler.AuthorizeImage, imageHandler.GetImage)
+		shareGroup.GET("/thumbnails/:identifier", shareHandler.AuthorizeImage, imageHandler.GetThumbnail)
+	}
 }
 
 // registerAPIRoutes 注册 API 路由
-func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandler *handlerImages.Handler) {
+func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandler *handlerImages.Handler, shareHandler *handlerShares.Handler) {
 	cfg := deps.Config
 	baseURL := getBaseURL(cfg)
 	albumService := svcAlbums.NewService(deps.Repositories.AlbumsRepo)
@@ -158,10 +180,13 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 	loginHandler := api.NewLoginHandlerWithService(deps.LoginService, cfg)
 
 	dashboardService := svcDashboard.NewService(deps.DashboardRepo, deps.CacheProvider)
-	dashboardHandler := handlerDashboard.NewHandler(dashboardService)
+	dashboardHandler := handlerDashboard.NewHandler(dashboardService, svcDashboard.NewAnalyticsService(deps.DashboardRepo))
 
 	userService := svcUser.NewService(deps.Repositories.AccountsRepo, deps.Repositories.DevicesRepo)
-	userHandler := handlerUser.NewHandler(userService)
+	userHandler := handlerUser.NewHandler(userService, deps.ConfigManager)
+
+	tagService := svcTags.NewService(deps.Repositories.TagsRepo, deps.Repositories.ImagesRepo)
+	tagHandler := handlerTags.NewHandler(tagService)
 
 	apiGroup := router.Group("/api")
 	if deps.APIConcurrency != nil {
@@ -193,6 +218,22 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 				imagesGroup.POST("/delete", imageHandler.DeleteImages)
 				imagesGroup.DELETE("/:identifier", imageHandler.DeleteSingleImage)
 				imagesGroup.PUT("/:identifier/visibility", imageHandler.UpdateImageVisibility)
+				imagesGroup.PUT("/:identifier/focal-point", imageHandler.UpdateFocalPoint)
+				imagesGroup.PUT("/:identifier/metadata", imageHandler.UpdateImageMetadata)
+				imagesGroup.PUT("/:identifier/expiry", imageHandler.UpdateImageExpiry)
+				imagesGroup.POST("/:identifier/replace", imageHandler.ReplaceImage)
+				imagesGroup.GET("/:identifier/versions", imageHandler.ListImageVersions)
+				imagesGroup.POST("/:identifier/versions/:version/revert", imageHandler.RevertImageVersion)
+				imagesGroup.DELETE("/:identifier/versions/:version", imageHandler.DeleteImageVersion)
+			}
+
+			// Trash
+			trashGroup := v1.Group("/trash")
+			trashGroup.Use(middleware.Authorize(middleware.AllowAllAuth...))
+			{
+				trashGroup.GET("", imageHandler.ListTrash)
+				trashGroup.POST("/restore", imageHandler.RestoreTrash)
+				trashGroup.POST("/empty", imageHandler.EmptyTrash)
 			}
 
 			// User
@@ -200,6 +241,9 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 			userGroup.Use(middleware.Authorize(middleware.AllowJWTOnly...))
 			{
 				userGroup.POST("/password", userHandler.ChangePassword)
+				userGroup.GET("/ingest-policy", userHandler.GetIngestPolicy)
+				userGroup.PUT("/ingest-policy", userHandler.UpdateIngestPolicy)
+				userGroup.DELETE("/ingest-policy", userHandler.ResetIngestPolicy)
 			}
 
 			// Static Token
@@ -210,6 +254,7 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 				apiTokenGroup.GET("", keyHandler.GetToken)
 				apiTokenGroup.POST("/:id/disable", keyHandler.DisableToken)
 				apiTokenGroup.POST("/:id/enable", keyHandler.EnableToken)
+				apiTokenGroup.PUT("/:id/default-ttl", keyHandler.UpdateDefaultImageTTL)
 				apiTokenGroup.DELETE("/:id", keyHandler.RevokeToken)
 			}
 
@@ -219,12 +264,33 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 			{
 				albumsGroup.GET("", albumHandler.ListAlbumsHandler)
 				albumsGroup.POST("", albumHandler.CreateAlbumHandler)
+				albumsGroup.GET("/tree", albumHandler.GetAlbumTreeHandler)
 				albumsGroup.GET("/:id", albumHandler.GetAlbumDetailHandler)
 				albumsGroup.PUT("/:id", albumHandler.UpdateAlbumHandler)
 				albumsGroup.DELETE("/:id", albumHandler.DeleteAlbumHandler)
+				albumsGroup.GET("/:id/tree", albumHandler.GetAlbumSubtreeHandler)
+				albumsGroup.POST("/:id/move", albumHandler.MoveAlbumHandler)
+				albumsGroup.PUT("/:id/cover", albumImageHandler.SetCoverHandler)
+				albumsGroup.PUT("/:id/images/order", albumImageHandler.ReorderImagesHandler)
 				albumsGroup.POST("/:id/images", albumImageHandler.AddImagesToAlbumHandler)
 				albumsGroup.DELETE("/:id/images/:imageId", albumImageHandler.RemoveImageFromAlbumHandler)
 				albumsGroup.POST("/:id/images/remove", albumImageHandler.RemoveImagesFromAlbumHandler)
+				albumsGroup.GET("/:id/shares", shareHandler.ListShares)
+				albumsGroup.POST("/:id/shares", shareHandler.CreateShare)
+				albumsGroup.DELETE("/:id/shares/:shareId", shareHandler.RevokeShare)
+			}
+
+			// Tags
+			tagsGroup := v1.Group("/tags")
+			tagsGroup.Use(middleware.Authorize(middleware.AllowAllAuth...))
+			{
+				tagsGroup.GET("", tagHandler.ListTags)
+				tagsGroup.POST("", tagHandler.CreateTag)
+				tagsGroup.GET("/autocomplete", tagHandler.AutocompleteTags)
+				tagsGroup.PUT("/:id", tagHandler.RenameTag)
+				tagsGroup.DELETE("/:id", tagHandler.DeleteTag)
+				tagsGroup.POST("/bulk/tag", tagHandler.TagImages)
+				tagsGroup.POST("/bulk/untag", tagHandler.UntagImages)
 			}
 
 			dashboardGroup := v1.Group("/dashboard")
@@ -232,6 +298,9 @@ func registerAPIRoutes(router *gin.Engine, deps *RouterDependencies, imageHandle
 			{
 				dashboardGroup.GET("/stats", dashboardHandler.GetStats)
 				dashboardGroup.POST("/stats/refresh", dashboardHandler.RefreshStats)
+				dashboardGroup.GET("/images/top", dashboardHandler.GetTopImages)
+				dashboardGroup.GET("/images/:identifier/stats", dashboardHandler.GetImageStats)
+				dashboardGroup.GET("/users/stats", dashboardHandler.GetUserStats)
 			}
 
 			// Admin
@@ -277,6 +346,26 @@ func registerAdminRoutes(v1 *gin.RouterGroup, deps *RouterDependencies, imageHan
 		// 全局转发模式配置
 		adminGroup.GET("/transfer-mode", configHandler.GetGlobalTransferMode)
 		adminGroup.POST("/transfer-mode", configHandler.SetGlobalTransferMode)
+
+		// 批量重新生成变体任务
+		if deps.Reprocess != nil {
+			reprocessHandler := admin.NewReprocessHandler(deps.Reprocess)
+			reprocessGroup := adminGroup.Group("/reprocess")
+			reprocessGroup.POST("", reprocessHandler.CreateJob)
+			reprocessGroup.GET("", reprocessHandler.ListJobs)
+			reprocessGroup.GET("/:id", reprocessHandler.GetJob)
+			reprocessGroup.POST("/:id/pause", reprocessHandler.PauseJob)
+			reprocessGroup.POST("/:id/resume", reprocessHandler.ResumeJob)
+			reprocessGroup.POST("/:id/cancel", reprocessHandler.CancelJob)
+		}
+
+		// 持久化任务队列
+		if deps.Repositories.JobsRepo != nil {
+			jobHandler := admin.NewJobHandler(deps.Repositories.JobsRepo)
+			adminGroup.GET("/jobs", jobHandler.ListJobs)
+			adminGroup.GET("/jobs/stats", jobHandler.GetStats)
+			adminGroup.POST("/jobs/:id/retry", jobHandler.RetryJob)
+		}
 	}
 }
 
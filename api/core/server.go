@@ -15,7 +15,9 @@ import (
 	"github.com/anoixa/image-bed/database/repo/albums"
 	dashboardRepo "github.com/anoixa/image-bed/database/repo/dashboard"
 	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/database/repo/jobs"
 	"github.com/anoixa/image-bed/database/repo/keys"
+	"github.com/anoixa/image-bed/database/repo/tags"
 	"github.com/anoixa/image-bed/internal/auth"
 	imageSvc "github.com/anoixa/image-bed/internal/image"
 	"github.com/gin-contrib/cors"
@@ -30,7 +32,10 @@ type Repositories struct {
 	DevicesRepo  *accounts.DeviceRepository
 	ImagesRepo   *images.Repository
 	AlbumsRepo   *albums.Repository
+	SharesRepo   *albums.ShareRepository
 	KeysRepo     *keys.Repository
+	JobsRepo     *jobs.Repository
+	TagsRepo     *tags.Repository
 }
 
 // ServerVersion 服务器版本信息
@@ -47,6 +52,7 @@ type ServerDependencies struct {
 	DashboardRepo *dashboardRepo.Repository
 	ConfigManager *configSvc.Manager
 	Converter     *imageSvc.Converter
+	Reprocess     *imageSvc.ReprocessService
 	JWTService    *auth.JWTService
 	Config        *config.Config
 	CacheProvider cache.Provider
@@ -121,6 +127,7 @@ func setupRouter(deps *ServerDependencies) (*gin.Engine, func()) {
 		Repositories:      deps.Repositories,
 		ConfigManager:     deps.ConfigManager,
 		Converter:         deps.Converter,
+		Reprocess:         deps.Reprocess,
 		JWTService:        jwtService,
 		LoginService:      loginService,
 		AuthRateLimiter:   authRateLimiter,
@@ -21,6 +21,7 @@ type ConversionHandler struct {
 type ConversionConfigResponse struct {
 	*config.ImageProcessingSettings
 	AVIFSupported bool `json:"avif_supported"`
+	JXLSupported  bool `json:"jxl_supported"`
 }
 
 // NewConversionHandler 创建处理器
@@ -61,8 +62,23 @@ type UpdateConfigRequest struct {
 	AVIFQuality              *int                   `json:"avif_quality,omitempty"`
 	AVIFSpeed                *int                   `json:"avif_speed,omitempty"`
 	AVIFExperimental         *bool                  `json:"avif_experimental,omitempty"`
+	JXLQuality               *int                   `json:"jxl_quality,omitempty"`
+	JXLEffort                *int                   `json:"jxl_effort,omitempty"`
 	SkipSmallerThan          *int                   `json:"skip_smaller_than,omitempty"`
 	MaxDimension             *int                   `json:"max_dimension,omitempty"`
+	ColorSpace               *string                `json:"color_space,omitempty"`
+	AVIFWideGamut            *bool                  `json:"avif_wide_gamut,omitempty"`
+	EmbedICCProfile          *bool                  `json:"embed_icc_profile,omitempty"`
+	LazyVariants             *bool                  `json:"lazy_variants,omitempty"`
+	LazyWaitMs               *int                   `json:"lazy_wait_ms,omitempty"`
+	VariantEvictionDays      *int                   `json:"variant_eviction_days,omitempty"`
+	IngestMaxDimension       *int                   `json:"ingest_max_dimension,omitempty"`
+	IngestOptimize           *bool                  `json:"ingest_optimize,omitempty"`
+	IngestConvertFormat      *string                `json:"ingest_convert_format,omitempty"`
+	IngestQuality            *int                   `json:"ingest_quality,omitempty"`
+	PreserveAnimation        *bool                  `json:"preserve_animation,omitempty"`
+	StaticThumbnail          *bool                  `json:"static_thumbnail,omitempty"`
+	MaxAnimationFrames       *int                   `json:"max_animation_frames,omitempty"`
 	DefaultAlbumID           *uint                  `json:"default_album_id,omitempty"`
 	DefaultVisibility        *string                `json:"default_visibility,omitempty"`
 	ConcurrentUploadLimit    *int                   `json:"concurrent_upload_limit,omitempty"`
@@ -130,12 +146,57 @@ func (h *ConversionHandler) UpdateConfig(c *gin.Context) {
 	if req.AVIFExperimental != nil {
 		current.AVIFExperimental = *req.AVIFExperimental
 	}
+	if req.JXLQuality != nil {
+		current.JXLQuality = *req.JXLQuality
+	}
+	if req.JXLEffort != nil {
+		current.JXLEffort = *req.JXLEffort
+	}
 	if req.SkipSmallerThan != nil {
 		current.SkipSmallerThan = *req.SkipSmallerThan
 	}
 	if req.MaxDimension != nil {
 		current.MaxDimension = *req.MaxDimension
 	}
+	if req.ColorSpace != nil {
+		current.ColorSpace = *req.ColorSpace
+	}
+	if req.AVIFWideGamut != nil {
+		current.AVIFWideGamut = *req.AVIFWideGamut
+	}
+	if req.EmbedICCProfile != nil {
+		current.EmbedICCProfile = *req.EmbedICCProfile
+	}
+	if req.LazyVariants != nil {
+		current.LazyVariants = *req.LazyVariants
+	}
+	if req.LazyWaitMs != nil {
+		current.LazyWaitMs = *req.LazyWaitMs
+	}
+	if req.VariantEvictionDays != nil {
+		current.VariantEvictionDays = *req.VariantEvictionDays
+	}
+	if req.IngestMaxDimension != nil {
+		current.IngestMaxDimension = *req.IngestMaxDimension
+	}
+	if req.IngestOptimize != nil {
+		current.IngestOptimize = *req.IngestOptimize
+	}
+	if req.IngestConvertFormat != nil {
+		current.IngestConvertFormat = *req.IngestConvertFormat
+	}
+	if req.IngestQuality != nil {
+		current.IngestQuality = *req.IngestQuality
+	}
+	if req.PreserveAnimation != nil {
+		current.PreserveAnimation = *req.PreserveAnimation
+	}
+	if req.StaticThumbnail != nil {
+		current.StaticThumbnail = *req.StaticThumbnail
+	}
+	if req.MaxAnimationFrames != nil {
+		current.MaxAnimationFrames = *req.MaxAnimationFrames
+	}
 	if req.DefaultAlbumID != nil {
 		current.DefaultAlbumID = *req.DefaultAlbumID
 	}
@@ -155,7 +216,7 @@ func (h *ConversionHandler) UpdateConfig(c *gin.Context) {
 		current.APIKeyEnabled = *req.APIKeyEnabled
 	}
 
-	if err := validateConversionConfigUpdate(&req, current, vipsfile.SupportsAVIFEncoding()); err != nil {
+	if err := validateConversionConfigUpdate(&req, current, vipsfile.SupportsAVIFEncoding(), vipsfile.SupportsJXLEncoding()); err != nil {
 		common.RespondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -173,20 +234,33 @@ func buildConversionConfigResponse(settings *config.ImageProcessingSettings) Con
 	return ConversionConfigResponse{
 		ImageProcessingSettings: settings,
 		AVIFSupported:           vipsfile.SupportsAVIFEncoding(),
+		JXLSupported:            vipsfile.SupportsJXLEncoding(),
 	}
 }
 
-func validateConversionConfigUpdate(req *UpdateConfigRequest, current *config.ImageProcessingSettings, avifSupported bool) error {
-	if avifSupported {
+func validateConversionConfigUpdate(req *UpdateConfigRequest, current *config.ImageProcessingSettings, avifSupported, jxlSupported bool) error {
+	if err := validateEncoderSupport(req, current, models.FormatAVIF, avifSupported); err != nil {
+		return err
+	}
+	return validateEncoderSupport(req, current, models.FormatJXL, jxlSupported)
+}
+
+// validateEncoderSupport 拒绝启用当前 libvips 运行时无法编码的格式
+func validateEncoderSupport(req *UpdateConfigRequest, current *config.ImageProcessingSettings, format string, supported bool) error {
+	if supported {
 		return nil
 	}
 
-	if req.ConversionEnabledFormats != nil && slices.Contains(req.ConversionEnabledFormats, models.FormatAVIF) {
-		return fmt.Errorf("avif conversion is not supported by the current server runtime")
+	if req.ConversionEnabledFormats != nil && slices.Contains(req.ConversionEnabledFormats, format) {
+		return fmt.Errorf("%s conversion is not supported by the current server runtime", format)
+	}
+
+	if slices.Contains(current.ConversionEnabledFormats, format) {
+		return fmt.Errorf("%s conversion is not supported by the current server runtime", format)
 	}
 
-	if slices.Contains(current.ConversionEnabledFormats, models.FormatAVIF) {
-		return fmt.Errorf("avif conversion is not supported by the current server runtime")
+	if current.IngestConvertFormat == format {
+		return fmt.Errorf("%s conversion is not supported by the current server runtime", format)
 	}
 
 	return nil
@@ -16,7 +16,7 @@ func TestValidateConversionConfigUpdate(t *testing.T) {
 		}
 		req := &UpdateConfigRequest{}
 
-		err := validateConversionConfigUpdate(req, current, true)
+		err := validateConversionConfigUpdate(req, current, true, true)
 		require.NoError(t, err)
 	})
 
@@ -28,7 +28,7 @@ func TestValidateConversionConfigUpdate(t *testing.T) {
 			ConversionEnabledFormats: []string{models.FormatWebP, models.FormatAVIF},
 		}
 
-		err := validateConversionConfigUpdate(req, current, false)
+		err := validateConversionConfigUpdate(req, current, false, true)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "avif conversion is not supported")
 	})
@@ -41,7 +41,7 @@ func TestValidateConversionConfigUpdate(t *testing.T) {
 			ConversionEnabledFormats: []string{models.FormatWebP},
 		}
 
-		err := validateConversionConfigUpdate(req, current, false)
+		err := validateConversionConfigUpdate(req, current, false, true)
 		require.NoError(t, err)
 	})
 
@@ -53,7 +53,39 @@ func TestValidateConversionConfigUpdate(t *testing.T) {
 			WebPQuality: intPtr(80),
 		}
 
-		err := validateConversionConfigUpdate(req, current, false)
+		err := validateConversionConfigUpdate(req, current, false, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "avif conversion is not supported")
+	})
+
+	t.Run("rejects enabling jxl when runtime does not support it", func(t *testing.T) {
+		current := &config.ImageProcessingSettings{
+			ConversionEnabledFormats: []string{models.FormatWebP},
+		}
+		req := &UpdateConfigRequest{
+			ConversionEnabledFormats: []string{models.FormatWebP, models.FormatJXL},
+		}
+
+		err := validateConversionConfigUpdate(req, current, true, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "jxl conversion is not supported")
+	})
+
+	t.Run("allows jxl when runtime supports it", func(t *testing.T) {
+		current := &config.ImageProcessingSettings{
+			ConversionEnabledFormats: []string{models.FormatWebP, models.FormatJXL},
+		}
+		req := &UpdateConfigRequest{JXLQuality: intPtr(85)}
+
+		err := validateConversionConfigUpdate(req, current, false, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects ingest conversion to an unsupported encoder", func(t *testing.T) {
+		current := &config.ImageProcessingSettings{IngestConvertFormat: models.FormatAVIF}
+		req := &UpdateConfigRequest{}
+
+		err := validateConversionConfigUpdate(req, current, false, true)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "avif conversion is not supported")
 	})
@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/database/repo/jobs"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// JobHandler 持久化任务队列查看处理器
+type JobHandler struct {
+	repo *jobs.Repository
+}
+
+// NewJobHandler 创建处理器
+func NewJobHandler(repo *jobs.Repository) *JobHandler {
+	return &JobHandler{repo: repo}
+}
+
+// JobStatsResponse 任务队列统计
+type JobStatsResponse struct {
+	Counts map[string]int64   `json:"counts"`
+	Local  *worker.QueueStats `json:"local,omitempty"`
+}
+
+// ListJobs 列出持久化任务
+// @Summary      List background jobs
+// @Description  List durable background jobs, optionally filtered by status and type
+// @Tags         admin
+// @Produce      json
+// @Param        status  query     string  false  "Job status (pending, running, completed, failed)"
+// @Param        type    query     string  false  "Job type"
+// @Param        page    query     int     false  "Page number (default 1)"
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Success      200     {object}  common.Response  "Jobs"
+// @Failure      401     {object}  common.Response  "Unauthorized"
+// @Failure      500     {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/jobs [get]
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	filter := jobs.ListFilter{Status: c.Query("status"), Type: c.Query("type")}
+	list, total, err := h.repo.WithContext(c.Request.Context()).List(filter, page, limit)
+	if err != nil {
+		adminJobLog.Errorf("Failed to list jobs: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to list jobs")
+		return
+	}
+
+	common.RespondSuccess(c, gin.H{
+		"jobs":  list,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetStats 获取任务队列统计
+// @Summary      Get background job stats
+// @Description  Get job counts by status and this process's queue counters
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  common.Response  "Job stats"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/jobs/stats [get]
+func (h *JobHandler) GetStats(c *gin.Context) {
+	counts, err := h.repo.WithContext(c.Request.Context()).CountByStatus()
+	if err != nil {
+		adminJobLog.Errorf("Failed to count jobs: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get job stats")
+		return
+	}
+
+	response := JobStatsResponse{Counts: counts}
+	if queue := worker.GetGlobalQueue(); queue != nil {
+		stats := queue.Stats()
+		response.Local = &stats
+	}
+	common.RespondSuccess(c, response)
+}
+
+// RetryJob 重新执行失败的任务
+// @Summary      Retry background job
+// @Description  Put a failed job back into the queue with a fresh attempt budget
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  common.Response  "Job requeued"
+// @Failure      400  {object}  common.Response  "Invalid job ID"
+// @Failure      404  {object}  common.Response  "Job not found"
+// @Failure      409  {object}  common.Response  "Job is not failed"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	repo := h.repo.WithContext(c.Request.Context())
+	ok, err := repo.Retry(uint(id))
+	if err != nil {
+		adminJobLog.Errorf("Failed to retry job %d: %v", id, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to retry job")
+		return
+	}
+	if !ok {
+		if _, err := repo.GetByID(uint(id)); errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Job not found")
+			return
+		}
+		common.RespondError(c, http.StatusConflict, "Only failed jobs can be retried")
+		return
+	}
+
+	job, err := repo.GetByID(uint(id))
+	if err != nil {
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get job")
+		return
+	}
+	common.RespondSuccessMessage(c, "Job requeued", job)
+}
@@ -2,4 +2,8 @@ package admin
 
 import "github.com/anoixa/image-bed/utils"
 
-var adminConfigLog = utils.ForModule("AdminConfig")
+var (
+	adminConfigLog    = utils.ForModule("AdminConfig")
+	adminReprocessLog = utils.ForModule("AdminReprocess")
+	adminJobLog       = utils.ForModule("AdminJobs")
+)
@@ -0,0 +1,220 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/internal/image"
+	"github.com/gin-gonic/gin"
+)
+
+// ReprocessHandler 批量重新处理变体任务处理器
+type ReprocessHandler struct {
+	service *image.ReprocessService
+}
+
+// NewReprocessHandler 创建处理器
+func NewReprocessHandler(service *image.ReprocessService) *ReprocessHandler {
+	return &ReprocessHandler{service: service}
+}
+
+// CreateReprocessJobRequest 创建重新处理任务请求，筛选条件均可选
+type CreateReprocessJobRequest struct {
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	StorageConfigID *uint      `json:"storage_config_id,omitempty"`
+	MimeType        string     `json:"mime_type,omitempty"`
+	AlbumID         *uint      `json:"album_id,omitempty"`
+	Formats         []string   `json:"formats,omitempty"`
+	BatchSize       int        `json:"batch_size,omitempty"`
+	BatchIntervalMs int        `json:"batch_interval_ms,omitempty"`
+}
+
+func (r *CreateReprocessJobRequest) toServiceRequest() image.ReprocessRequest {
+	return image.ReprocessRequest{
+		StartTime:       r.StartTime,
+		EndTime:         r.EndTime,
+		StorageConfigID: r.StorageConfigID,
+		MimeType:        r.MimeType,
+		AlbumID:         r.AlbumID,
+		Formats:         r.Formats,
+		BatchSize:       r.BatchSize,
+		BatchIntervalMs: r.BatchIntervalMs,
+	}
+}
+
+// CreateJob 创建批量重新处理任务
+// @Summary      Create reprocess job
+// @Description  Regenerate variants for all images matching the filters in throttled batches
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateReprocessJobRequest  true  "Job filters and throttling"
+// @Success      200      {object}  common.Response  "Job created"
+// @Failure      400      {object}  common.Response  "Invalid request"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess [post]
+func (h *ReprocessHandler) CreateJob(c *gin.Context) {
+	var req CreateReprocessJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	serviceReq := req.toServiceRequest()
+	if err := serviceReq.Validate(); err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.service.Create(c.Request.Context(), serviceReq, c.GetUint(middleware.ContextUserIDKey))
+	if err != nil {
+		adminReprocessLog.Errorf("Failed to create reprocess job: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to create reprocess job")
+		return
+	}
+
+	common.RespondSuccessMessage(c, "Reprocess job created", job)
+}
+
+// ListJobs 列出重新处理任务
+// @Summary      List reprocess jobs
+// @Description  List recent reprocess jobs with progress
+// @Tags         admin
+// @Produce      json
+// @Param        limit  query     int  false  "Max jobs to return (default 50)"
+// @Success      200    {object}  common.Response  "Reprocess jobs"
+// @Failure      401    {object}  common.Response  "Unauthorized"
+// @Failure      500    {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess [get]
+func (h *ReprocessHandler) ListJobs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	jobs, err := h.service.List(c.Request.Context(), limit)
+	if err != nil {
+		adminReprocessLog.Errorf("Failed to list reprocess jobs: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to list reprocess jobs")
+		return
+	}
+
+	common.RespondSuccess(c, jobs)
+}
+
+// GetJob 获取任务进度
+// @Summary      Get reprocess job
+// @Description  Get a reprocess job with its progress counters
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  common.Response  "Reprocess job"
+// @Failure      400  {object}  common.Response  "Invalid job ID"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      404  {object}  common.Response  "Job not found"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess/{id} [get]
+func (h *ReprocessHandler) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.service.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		respondReprocessError(c, err, "Failed to get reprocess job")
+		return
+	}
+
+	common.RespondSuccess(c, job)
+}
+
+// PauseJob 暂停任务
+// @Summary      Pause reprocess job
+// @Description  Pause a running reprocess job after the current image
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  common.Response  "Job paused"
+// @Failure      400  {object}  common.Response  "Invalid job ID"
+// @Failure      404  {object}  common.Response  "Job not found"
+// @Failure      409  {object}  common.Response  "Job is not running"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess/{id}/pause [post]
+func (h *ReprocessHandler) PauseJob(c *gin.Context) {
+	h.changeState(c, h.service.Pause, "Reprocess job paused")
+}
+
+// ResumeJob 继续任务
+// @Summary      Resume reprocess job
+// @Description  Resume a paused reprocess job from where it stopped
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  common.Response  "Job resumed"
+// @Failure      400  {object}  common.Response  "Invalid job ID"
+// @Failure      404  {object}  common.Response  "Job not found"
+// @Failure      409  {object}  common.Response  "Job is not paused"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess/{id}/resume [post]
+func (h *ReprocessHandler) ResumeJob(c *gin.Context) {
+	h.changeState(c, h.service.Resume, "Reprocess job resumed")
+}
+
+// CancelJob 取消任务
+// @Summary      Cancel reprocess job
+// @Description  Cancel a running or paused reprocess job
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Job ID"
+// @Success      200  {object}  common.Response  "Job cancelled"
+// @Failure      400  {object}  common.Response  "Invalid job ID"
+// @Failure      404  {object}  common.Response  "Job not found"
+// @Failure      409  {object}  common.Response  "Job already finished"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/admin/reprocess/{id}/cancel [post]
+func (h *ReprocessHandler) CancelJob(c *gin.Context) {
+	h.changeState(c, h.service.Cancel, "Reprocess job cancelled")
+}
+
+type reprocessTransition func(ctx context.Context, id uint) (*models.ReprocessJob, error)
+
+func (h *ReprocessHandler) changeState(c *gin.Context, transition reprocessTransition, message string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := transition(c.Request.Context(), uint(id))
+	if err != nil {
+		respondReprocessError(c, err, "Failed to update reprocess job")
+		return
+	}
+
+	common.RespondSuccessMessage(c, message, job)
+}
+
+func respondReprocessError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, image.ErrReprocessJobNotFound):
+		common.RespondError(c, http.StatusNotFound, "Reprocess job not found")
+	case errors.Is(err, image.ErrReprocessJobState):
+		common.RespondError(c, http.StatusConflict, err.Error())
+	default:
+		adminReprocessLog.Errorf("%s: %v", message, err)
+		common.RespondError(c, http.StatusInternalServerError, message)
+	}
+}
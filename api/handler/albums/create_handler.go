@@ -1,12 +1,14 @@
 package albums
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/anoixa/image-bed/api/common"
 	"github.com/anoixa/image-bed/api/middleware"
 	"github.com/anoixa/image-bed/database/models"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
 	"github.com/anoixa/image-bed/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,8 @@ import (
 type createAlbumRequest struct {
 	Name        string `json:"name" binding:"required,max=100"`
 	Description string `json:"description" binding:"max=255"`
+	ParentID    *uint  `json:"parent_id"` // 为空时创建顶层相册
+	SortMode    string `json:"sort_mode" binding:"omitempty,oneof=manual added_asc added_desc created_asc created_desc name_asc name_desc"`
 }
 
 // CreateAlbumResponse 创建相册响应
@@ -21,19 +25,21 @@ type CreateAlbumResponse struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
+	ParentID    *uint  `json:"parent_id"`
+	SortMode    string `json:"sort_mode"`
 	CreatedAt   int64  `json:"created_at"`
 	UpdatedAt   int64  `json:"updated_at"`
 }
 
 // CreateAlbumHandler 创建相册
 // @Summary      Create album
-// @Description  Create a new album with name and optional description
+// @Description  Create a new album with name and optional description, optionally nested under a parent album
 // @Tags         albums
 // @Accept       json
 // @Produce      json
 // @Param        request  body      createAlbumRequest  true  "Album creation request"
 // @Success      200      {object}  common.Response{data=CreateAlbumResponse}  "Album created successfully"
-// @Failure      400      {object}  common.Response  "Invalid request body"
+// @Failure      400      {object}  common.Response  "Invalid request body or parent album"
 // @Failure      401      {object}  common.Response  "Unauthorized"
 // @Failure      500      {object}  common.Response  "Internal server error"
 // @Security     ApiKeyAuth
@@ -50,13 +56,23 @@ func (h *Handler) CreateAlbumHandler(c *gin.Context) {
 		return
 	}
 
+	if req.SortMode == "" {
+		req.SortMode = models.AlbumSortManual
+	}
+
 	album := models.Album{
 		Name:        req.Name,
 		Description: req.Description,
 		UserID:      userID,
+		ParentID:    req.ParentID,
+		SortMode:    req.SortMode,
 	}
 
 	if err := h.svc.CreateAlbum(&album); err != nil {
+		if errors.Is(err, svcAlbums.ErrInvalidAlbumParent) {
+			common.RespondError(c, http.StatusBadRequest, "Parent album not found")
+			return
+		}
 		common.RespondError(c, http.StatusInternalServerError, "Failed to create albums.")
 		return
 	}
@@ -74,6 +90,8 @@ func (h *Handler) CreateAlbumHandler(c *gin.Context) {
 		ID:          album.ID,
 		Name:        album.Name,
 		Description: album.Description,
+		ParentID:    album.ParentID,
+		SortMode:    album.SortMode,
 		CreatedAt:   album.CreatedAt.Unix(),
 		UpdatedAt:   album.UpdatedAt.Unix(),
 	}
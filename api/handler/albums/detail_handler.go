@@ -18,6 +18,9 @@ type AlbumImageDTO struct {
 	ID           uint   `json:"id"`
 	URL          string `json:"url"`
 	OriginalName string `json:"original_name"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	AltText      string `json:"alt_text"`
 	FileSize     int64  `json:"file_size"`
 	MimeType     string `json:"mime_type"`
 	Width        int    `json:"width"`
@@ -30,6 +33,9 @@ type AlbumDetailResponse struct {
 	ID          uint             `json:"id"`
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
+	ParentID    *uint            `json:"parent_id"`
+	SortMode    string           `json:"sort_mode"`
+	CoverURL    string           `json:"cover_url,omitempty"`
 	Images      []*AlbumImageDTO `json:"images"`
 	ImageCount  int64            `json:"image_count"`
 	CreatedAt   int64            `json:"created_at"`
@@ -38,7 +44,7 @@ type AlbumDetailResponse struct {
 
 // GetAlbumDetailHandler 获取相册详情
 // @Summary      Get album detail
-// @Description  Get detailed information about an album including all images
+// @Description  Get detailed information about an album including all images in the album's sort order
 // @Tags         albums
 // @Accept       json
 // @Produce      json
@@ -76,10 +82,29 @@ func (h *Handler) GetAlbumDetailHandler(c *gin.Context) {
 		images[i] = h.toAlbumImageDTO(img)
 	}
 
+	// 与列表一致：优先使用设置的封面，否则取最新的图片
+	var cover *models.Image
+	for _, img := range album.Images {
+		if album.CoverImageID != nil && img.ID == *album.CoverImageID {
+			cover = img
+			break
+		}
+		if cover == nil || img.CreatedAt.After(cover.CreatedAt) {
+			cover = img
+		}
+	}
+	coverURL := ""
+	if cover != nil {
+		coverURL = cover.Identifier
+	}
+
 	common.RespondSuccess(c, AlbumDetailResponse{
 		ID:          album.ID,
 		Name:        album.Name,
 		Description: album.Description,
+		ParentID:    album.ParentID,
+		SortMode:    album.SortMode,
+		CoverURL:    coverURL,
 		Images:      images,
 		ImageCount:  int64(len(album.Images)),
 		CreatedAt:   album.CreatedAt.Unix(),
@@ -98,6 +123,9 @@ func (h *Handler) toAlbumImageDTO(image *models.Image) *AlbumImageDTO {
 		ID:           image.ID,
 		URL:          imageUrl,
 		OriginalName: image.OriginalName,
+		Title:        image.Title,
+		Description:  image.Description,
+		AltText:      image.AltText,
 		FileSize:     image.FileSize,
 		MimeType:     image.MimeType,
 		Width:        image.Width,
@@ -306,6 +306,7 @@ func TestUpdateAlbumHandlerReturnsFreshUpdatedAt(t *testing.T) {
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	require.NoError(t, err)
+	require.NoError(t, models.SetupJoinTables(db))
 	require.NoError(t, db.AutoMigrate(&models.Album{}, &models.Image{}))
 
 	repo := repoalbums.NewRepository(db)
@@ -7,6 +7,7 @@ import (
 
 	"github.com/anoixa/image-bed/api/common"
 	"github.com/anoixa/image-bed/api/middleware"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
 	"github.com/anoixa/image-bed/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -22,12 +23,28 @@ type AlbumDTO struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+	SortMode    string `json:"sort_mode"`
 	ImageCount  int64  `json:"image_count"`
 	CoverURL    string `json:"cover_url,omitempty"`
 	CreatedAt   int64  `json:"created_at"`
 	UpdatedAt   int64  `json:"updated_at"`
 }
 
+func toAlbumDTO(info *svcAlbums.AlbumInfo) *AlbumDTO {
+	return &AlbumDTO{
+		ID:          info.Album.ID,
+		Name:        info.Album.Name,
+		Description: info.Album.Description,
+		ParentID:    info.Album.ParentID,
+		SortMode:    info.Album.SortMode,
+		ImageCount:  info.ImageCount,
+		CoverURL:    info.CoverURL,
+		CreatedAt:   info.Album.CreatedAt.Unix(),
+		UpdatedAt:   info.Album.UpdatedAt.Unix(),
+	}
+}
+
 // ListAlbumsResponse 相册列表响应
 type ListAlbumsResponse struct {
 	Albums     []*AlbumDTO `json:"albums"`
@@ -39,18 +56,20 @@ type ListAlbumsResponse struct {
 
 // ListAlbumsRequest 相册列表请求
 type ListAlbumsRequest struct {
-	Page  int `form:"page" json:"page" binding:"required,min=1"`
-	Limit int `form:"limit" json:"limit" binding:"required,min=1,max=100"`
+	Page     int   `form:"page" json:"page" binding:"required,min=1"`
+	Limit    int   `form:"limit" json:"limit" binding:"required,min=1,max=100"`
+	ParentID *uint `form:"parent_id" json:"parent_id"` // 只列出该相册的直接子相册，0 表示顶层相册
 }
 
 // ListAlbumsHandler 获取相册列表
 // @Summary      List albums
-// @Description  Get paginated list of user's albums with image counts and cover URLs
+// @Description  Get paginated list of user's albums with image counts and cover URLs. All nesting levels are listed unless parent_id is given
 // @Tags         albums
 // @Accept       json
 // @Produce      json
-// @Param        page   query     int  false  "Page number (default: 1)"  minimum(1)
-// @Param        limit  query     int  false  "Items per page (default: 10, max: 100)"  minimum(1)  maximum(100)
+// @Param        page       query     int  false  "Page number (default: 1)"  minimum(1)
+// @Param        limit      query     int  false  "Items per page (default: 10, max: 100)"  minimum(1)  maximum(100)
+// @Param        parent_id  query     int  false  "Only list direct children of this album, 0 for top-level albums"
 // @Success      200    {object}  common.Response{data=ListAlbumsResponse}  "Album list"
 // @Failure      400    {object}  common.Response  "Invalid request parameters"
 // @Failure      401    {object}  common.Response  "Unauthorized"
@@ -66,6 +85,27 @@ func (h *Handler) ListAlbumsHandler(c *gin.Context) {
 
 	userID := c.GetUint(middleware.ContextUserIDKey)
 
+	// 按父相册筛选的列表不走缓存
+	if req.ParentID != nil {
+		albums, total, err := h.svc.GetChildAlbums(userID, *req.ParentID, req.Page, req.Limit)
+		if err != nil {
+			common.RespondError(c, http.StatusInternalServerError, "Failed to get albums")
+			return
+		}
+		albumDTOs := make([]*AlbumDTO, len(albums))
+		for i, info := range albums {
+			albumDTOs[i] = toAlbumDTO(info)
+		}
+		common.RespondSuccess(c, ListAlbumsResponse{
+			Albums:     albumDTOs,
+			Total:      total,
+			Page:       req.Page,
+			Limit:      req.Limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(req.Limit))),
+		})
+		return
+	}
+
 	var cachedList CachedAlbumList
 	if err := h.cacheHelper.GetCachedAlbumList(c.Request.Context(), userID, req.Page, req.Limit, &cachedList); err == nil {
 		common.RespondSuccess(c, ListAlbumsResponse{
@@ -86,15 +126,7 @@ func (h *Handler) ListAlbumsHandler(c *gin.Context) {
 
 	albumDTOs := make([]*AlbumDTO, len(albums))
 	for i, info := range albums {
-		albumDTOs[i] = &AlbumDTO{
-			ID:          info.Album.ID,
-			Name:        info.Album.Name,
-			Description: info.Album.Description,
-			ImageCount:  info.ImageCount,
-			CoverURL:    info.CoverURL,
-			CreatedAt:   info.Album.CreatedAt.Unix(),
-			UpdatedAt:   info.Album.UpdatedAt.Unix(),
-		}
+		albumDTOs[i] = toAlbumDTO(info)
 	}
 
 	// 异步写入缓存
@@ -0,0 +1,169 @@
+package albums
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ReorderImagesRequest 重排相册图片请求
+type ReorderImagesRequest struct {
+	Identifiers []string `json:"identifiers" binding:"required,min=1,max=1000"`
+}
+
+// SetCoverRequest 设置相册封面请求
+type SetCoverRequest struct {
+	Identifier string `json:"identifier"` // 为空时恢复默认封面
+}
+
+// ReorderImagesHandler 重排相册图片
+// @Summary      Reorder album images
+// @Description  Set the manual order of images in an album, e.g. after drag-and-drop. Listed images are moved to the front in the given order, the rest keep their relative order behind them. Switches the album to manual sort mode
+// @Tags         albums
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                   true  "Album ID"
+// @Param        request  body      ReorderImagesRequest  true  "Image identifiers in the new order"
+// @Success      200      {object}  common.Response  "Images reordered successfully"
+// @Failure      400      {object}  common.Response  "Invalid request or image not in album"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Album not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/images/order [put]
+func (h *AlbumImageHandler) ReorderImagesHandler(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	imgs, err := h.imageRepo.GetImagesByIdentifiersAndUser(req.Identifiers, userID)
+	if err != nil {
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get images")
+		return
+	}
+	idByIdentifier := make(map[string]uint, len(imgs))
+	for _, img := range imgs {
+		idByIdentifier[img.Identifier] = img.ID
+	}
+
+	imageIDs := make([]uint, 0, len(req.Identifiers))
+	for _, ident := range req.Identifiers {
+		id, ok := idByIdentifier[ident]
+		if !ok {
+			common.RespondError(c, http.StatusBadRequest, "Image not found in album: "+ident)
+			return
+		}
+		imageIDs = append(imageIDs, id)
+	}
+
+	if err := h.svc.ReorderImages(uint(albumID), userID, imageIDs); err != nil {
+		switch {
+		case errors.Is(err, svcAlbums.ErrAlbumNotFound):
+			common.RespondError(c, http.StatusNotFound, "Album not found or access denied")
+		case errors.Is(err, svcAlbums.ErrImageNotInAlbum):
+			common.RespondError(c, http.StatusBadRequest, "Some images are not in the album")
+		default:
+			albumLog.Errorf("Failed to reorder album %d for user %d: %v", albumID, userID, err)
+			common.RespondError(c, http.StatusInternalServerError, "Failed to reorder images")
+		}
+		return
+	}
+
+	h.invalidateAlbumCache(uint(albumID), userID)
+	common.RespondSuccessMessage(c, "Images reordered successfully", nil)
+}
+
+// SetCoverHandler 设置相册封面
+// @Summary      Set album cover
+// @Description  Use an image of the album as its cover. An empty identifier restores the default cover (the newest image)
+// @Tags         albums
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int              true  "Album ID"
+// @Param        request  body      SetCoverRequest  true  "Cover image identifier"
+// @Success      200      {object}  common.Response  "Album cover updated"
+// @Failure      400      {object}  common.Response  "Invalid request or image not in album"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Album not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/cover [put]
+func (h *AlbumImageHandler) SetCoverHandler(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	var req SetCoverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	var imageID *uint
+	if req.Identifier != "" {
+		imgs, err := h.imageRepo.GetImagesByIdentifiersAndUser([]string{req.Identifier}, userID)
+		if err != nil {
+			common.RespondError(c, http.StatusInternalServerError, "Failed to get image")
+			return
+		}
+		if len(imgs) == 0 {
+			common.RespondError(c, http.StatusBadRequest, "Image is not in the album")
+			return
+		}
+		imageID = &imgs[0].ID
+	}
+
+	if err := h.svc.SetCover(uint(albumID), userID, imageID); err != nil {
+		switch {
+		case errors.Is(err, svcAlbums.ErrAlbumNotFound):
+			common.RespondError(c, http.StatusNotFound, "Album not found or access denied")
+		case errors.Is(err, svcAlbums.ErrImageNotInAlbum):
+			common.RespondError(c, http.StatusBadRequest, "Image is not in the album")
+		default:
+			albumLog.Errorf("Failed to set cover of album %d for user %d: %v", albumID, userID, err)
+			common.RespondError(c, http.StatusInternalServerError, "Failed to set album cover")
+		}
+		return
+	}
+
+	h.invalidateAlbumCache(uint(albumID), userID)
+	common.RespondSuccessMessage(c, "Album cover updated", gin.H{
+		"album_id":   albumID,
+		"identifier": req.Identifier,
+	})
+}
+
+// invalidateAlbumCache 异步清除相册缓存和用户的相册列表缓存
+func (h *AlbumImageHandler) invalidateAlbumCache(albumID, userID uint) {
+	albumAsync(func() {
+		ctx, cancel := utils.DetachedContext(5 * time.Second)
+		defer cancel()
+		if err := h.cacheHelper.DeleteCachedAlbum(ctx, albumID); err != nil {
+			albumLog.Debugf("Failed to delete album cache for %d: %v", albumID, err)
+		}
+		if err := h.cacheHelper.DeleteCachedAlbumList(ctx, userID); err != nil {
+			albumLog.Debugf("Failed to delete album list cache for user %d: %v", userID, err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package albums
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AlbumTreeNodeDTO 相册树节点
+type AlbumTreeNodeDTO struct {
+	AlbumDTO
+	Children []*AlbumTreeNodeDTO `json:"children"`
+}
+
+// MoveAlbumRequest 移动相册请求
+type MoveAlbumRequest struct {
+	ParentID *uint `json:"parent_id"` // 为空时移动到顶层
+}
+
+func toAlbumTreeNodeDTO(node *svcAlbums.AlbumNode) *AlbumTreeNodeDTO {
+	children := make([]*AlbumTreeNodeDTO, len(node.Children))
+	for i, child := range node.Children {
+		children[i] = toAlbumTreeNodeDTO(child)
+	}
+	return &AlbumTreeNodeDTO{
+		AlbumDTO: *toAlbumDTO(node.AlbumInfo),
+		Children: children,
+	}
+}
+
+// GetAlbumTreeHandler 获取完整相册树
+// @Summary      Get album tree
+// @Description  Get all albums of the user as a tree of top-level albums with their nested sub-albums
+// @Tags         albums
+// @Produce      json
+// @Success      200  {object}  common.Response{data=[]AlbumTreeNodeDTO}  "Album tree"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/tree [get]
+func (h *Handler) GetAlbumTreeHandler(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	roots, err := h.svc.GetAlbumTree(userID)
+	if err != nil {
+		albumLog.Errorf("Failed to get album tree for user %d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get album tree")
+		return
+	}
+
+	nodes := make([]*AlbumTreeNodeDTO, len(roots))
+	for i, root := range roots {
+		nodes[i] = toAlbumTreeNodeDTO(root)
+	}
+	common.RespondSuccess(c, nodes)
+}
+
+// GetAlbumSubtreeHandler 获取相册及其所有子孙相册
+// @Summary      Get album subtree
+// @Description  Get an album with all of its nested sub-albums
+// @Tags         albums
+// @Produce      json
+// @Param        id   path      int  true  "Album ID"
+// @Success      200  {object}  common.Response{data=AlbumTreeNodeDTO}  "Album subtree"
+// @Failure      400  {object}  common.Response  "Invalid album ID format"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      404  {object}  common.Response  "Album not found"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/tree [get]
+func (h *Handler) GetAlbumSubtreeHandler(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	node, err := h.svc.GetAlbumSubtree(uint(albumID), userID)
+	if err != nil {
+		if errors.Is(err, svcAlbums.ErrAlbumNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Album not found")
+			return
+		}
+		albumLog.Errorf("Failed to get subtree of album %d for user %d: %v", albumID, userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get album tree")
+		return
+	}
+
+	common.RespondSuccess(c, toAlbumTreeNodeDTO(node))
+}
+
+// MoveAlbumHandler 移动相册
+// @Summary      Move album
+// @Description  Move an album (with its sub-albums) under another album, or to the top level when parent_id is null
+// @Tags         albums
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int               true  "Album ID"
+// @Param        request  body      MoveAlbumRequest  true  "New parent album"
+// @Success      200      {object}  common.Response  "Album moved successfully"
+// @Failure      400      {object}  common.Response  "Invalid request or parent album"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Album not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/move [post]
+func (h *Handler) MoveAlbumHandler(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	var req MoveAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	if err := h.svc.MoveAlbum(uint(albumID), userID, req.ParentID); err != nil {
+		switch {
+		case errors.Is(err, svcAlbums.ErrAlbumNotFound):
+			common.RespondError(c, http.StatusNotFound, "Album not found or access denied")
+		case errors.Is(err, svcAlbums.ErrInvalidAlbumParent):
+			common.RespondError(c, http.StatusBadRequest, "Parent album not found or is a sub-album of this album")
+		default:
+			albumLog.Errorf("Failed to move album %d for user %d: %v", albumID, userID, err)
+			common.RespondError(c, http.StatusInternalServerError, "Failed to move album")
+		}
+		return
+	}
+
+	albumAsync(func() {
+		ctx, cancel := utils.DetachedContext(5 * time.Second)
+		defer cancel()
+		if err := h.cacheHelper.DeleteCachedAlbum(ctx, uint(albumID)); err != nil {
+			albumLog.Debugf("Failed to delete album cache for %d: %v", albumID, err)
+		}
+		if err := h.cacheHelper.DeleteCachedAlbumList(ctx, userID); err != nil {
+			albumLog.Debugf("Failed to delete album list cache for user %d: %v", userID, err)
+		}
+	})
+
+	common.RespondSuccessMessage(c, "Album moved successfully", gin.H{
+		"album_id":  albumID,
+		"parent_id": req.ParentID,
+	})
+}
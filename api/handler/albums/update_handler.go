@@ -17,6 +17,7 @@ import (
 type UpdateAlbumRequest struct {
 	Name        string `json:"name" binding:"required,max=100"`
 	Description string `json:"description" binding:"max=255"`
+	SortMode    string `json:"sort_mode" binding:"omitempty,oneof=manual added_asc added_desc created_asc created_desc name_asc name_desc"` // 为空时保持不变
 }
 
 // UpdateAlbumResponse 更新相册响应
@@ -24,12 +25,13 @@ type UpdateAlbumResponse struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	SortMode    string `json:"sort_mode"`
 	UpdatedAt   int64  `json:"updated_at"`
 }
 
 // UpdateAlbumHandler 更新相册
 // @Summary      Update album
-// @Description  Update album name and description
+// @Description  Update album name, description and image sort mode
 // @Tags         albums
 // @Accept       json
 // @Produce      json
@@ -83,6 +85,10 @@ func (h *Handler) UpdateAlbumHandler(c *gin.Context) {
 		"description": req.Description,
 		"updated_at":  updatedAt,
 	}
+	if req.SortMode != "" {
+		album.SortMode = req.SortMode
+		updates["sort_mode"] = req.SortMode
+	}
 	if err := h.svc.UpdateAlbum(album.ID, updates); err != nil {
 		common.RespondError(c, http.StatusInternalServerError, "Failed to update album")
 		return
@@ -105,6 +111,7 @@ func (h *Handler) UpdateAlbumHandler(c *gin.Context) {
 		ID:          album.ID,
 		Name:        album.Name,
 		Description: album.Description,
+		SortMode:    album.SortMode,
 		UpdatedAt:   album.UpdatedAt.Unix(),
 	})
 }
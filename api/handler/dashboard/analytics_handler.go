@@ -0,0 +1,159 @@
+package dashboard
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/internal/dashboard"
+	"github.com/gin-gonic/gin"
+)
+
+// statsScope 管理员默认查看所有用户，可用 user_id 指定用户；普通用户只能查看自己的图片
+func statsScope(c *gin.Context) (uint, bool) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if c.GetString(middleware.ContextRoleKey) != middleware.RoleAdmin {
+		return userID, true
+	}
+	raw := c.Query("user_id")
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func parsePeriodQuery(c *gin.Context) (dashboard.AnalyticsPeriod, bool) {
+	period, err := dashboard.ParsePeriod(c.Query("period"))
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid period, expected one of 24h, 7d, 30d, 90d")
+		return period, false
+	}
+	return period, true
+}
+
+// GetTopImages
+// @Summary      Get top images by traffic
+// @Description  List the most viewed images in a period with hits, bytes served and the part coming from other sites (hotlinks). Admins see all users unless user_id is given, other users only see their own images
+// @Tags         dashboard
+// @Accept       json
+// @Produce      json
+// @Param        period   query     string  false  "Period: 24h, 7d (default), 30d or 90d"
+// @Param        sort     query     string  false  "Sort by: hits (default), bytes, hotlink_hits or hotlink_bytes"
+// @Param        limit    query     int     false  "Number of images (default 20, max 100)"
+// @Param        user_id  query     int     false  "Only images of this user (admin only)"
+// @Success      200      {object}  common.Response  "Top images"
+// @Failure      400      {object}  common.Response  "Invalid query parameters"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/dashboard/images/top [get]
+func (h *Handler) GetTopImages(c *gin.Context) {
+	userID, ok := statsScope(c)
+	if !ok {
+		common.RespondError(c, http.StatusBadRequest, "Invalid user_id parameter")
+		return
+	}
+	period, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			common.RespondError(c, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		limit = n
+	}
+
+	top, err := h.analytics.GetTopImages(c.Request.Context(), userID, period, c.Query("sort"), limit)
+	if err != nil {
+		if errors.Is(err, dashboard.ErrInvalidSort) {
+			common.RespondError(c, http.StatusBadRequest, "Invalid sort, expected one of hits, bytes, hotlink_hits, hotlink_bytes")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get top images")
+		return
+	}
+
+	common.RespondSuccess(c, top)
+}
+
+// GetImageStats
+// @Summary      Get traffic of an image
+// @Description  Get hits and bytes served for an image over a period as an hourly (24h) or daily series, broken down by variant and by referrer domain. An empty referrer means no referrer or this site, "(other)" collects referrers beyond the per-image hourly limit
+// @Tags         dashboard
+// @Accept       json
+// @Produce      json
+// @Param        identifier  path      string  true   "Image identifier"
+// @Param        period      query     string  false  "Period: 24h, 7d (default), 30d or 90d"
+// @Success      200         {object}  common.Response  "Image traffic"
+// @Failure      400         {object}  common.Response  "Invalid period"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/dashboard/images/{identifier}/stats [get]
+func (h *Handler) GetImageStats(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if c.GetString(middleware.ContextRoleKey) == middleware.RoleAdmin {
+		userID = 0
+	}
+	period, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analytics.GetImageStats(c.Request.Context(), c.Param("identifier"), userID, period)
+	if err != nil {
+		if errors.Is(err, dashboard.ErrStatImageNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Image not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get image stats")
+		return
+	}
+
+	common.RespondSuccess(c, stats)
+}
+
+// GetUserStats
+// @Summary      Get traffic per user
+// @Description  Get hits and bytes served for all images of each user over a period, sorted by bytes. Admins see all users unless user_id is given, other users only see themselves
+// @Tags         dashboard
+// @Accept       json
+// @Produce      json
+// @Param        period   query     string  false  "Period: 24h, 7d (default), 30d or 90d"
+// @Param        user_id  query     int     false  "Only this user (admin only)"
+// @Success      200      {object}  common.Response  "Traffic per user"
+// @Failure      400      {object}  common.Response  "Invalid query parameters"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/dashboard/users/stats [get]
+func (h *Handler) GetUserStats(c *gin.Context) {
+	userID, ok := statsScope(c)
+	if !ok {
+		common.RespondError(c, http.StatusBadRequest, "Invalid user_id parameter")
+		return
+	}
+	period, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.analytics.GetUserStats(c.Request.Context(), userID, period)
+	if err != nil {
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get user stats")
+		return
+	}
+
+	common.RespondSuccess(c, stats)
+}
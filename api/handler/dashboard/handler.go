@@ -9,12 +9,14 @@ import (
 )
 
 type Handler struct {
-	svc *dashboard.Service
+	svc       *dashboard.Service
+	analytics *dashboard.AnalyticsService
 }
 
-func NewHandler(svc *dashboard.Service) *Handler {
+func NewHandler(svc *dashboard.Service, analytics *dashboard.AnalyticsService) *Handler {
 	return &Handler{
-		svc: svc,
+		svc:       svc,
+		analytics: analytics,
 	}
 }
 
@@ -64,5 +66,8 @@ func (h *Handler) SetupRoutes(router *gin.RouterGroup, authMiddleware gin.Handle
 	{
 		dashboard.GET("/stats", h.GetStats)
 		dashboard.POST("/stats/refresh", h.RefreshStats)
+		dashboard.GET("/images/top", h.GetTopImages)
+		dashboard.GET("/images/:identifier/stats", h.GetImageStats)
+		dashboard.GET("/users/stats", h.GetUserStats)
 	}
 }
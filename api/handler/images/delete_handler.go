@@ -14,7 +14,7 @@ type DeleteRequestBody struct {
 
 // DeleteImages 批量删除图片
 // @Summary      Delete multiple images
-// @Description  Delete multiple images by their identifiers in a single request
+// @Description  Move multiple images to the trash by their identifiers in a single request. They can be restored until the trash is emptied or the retention period ends
 // @Tags         images
 // @Accept       json
 // @Produce      json
@@ -59,7 +59,7 @@ func (h *Handler) DeleteImages(c *gin.Context) {
 
 // DeleteSingleImage 删除单张图片
 // @Summary      Delete single image
-// @Description  Delete a single image by its identifier
+// @Description  Move a single image to the trash by its identifier. It can be restored until the trash is emptied or the retention period ends
 // @Tags         images
 // @Accept       json
 // @Produce      json
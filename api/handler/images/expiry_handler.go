@@ -0,0 +1,167 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	imagesvc "github.com/anoixa/image-bed/internal/image"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateExpiryRequest 设置图片过期请求，字段均为 0 时清除过期设置，图片永久保存
+type UpdateExpiryRequest struct {
+	ExpiresIn int64 `json:"expires_in" binding:"min=0"` // 从现在起的有效期（秒），与 expires_at 二选一
+	ExpiresAt int64 `json:"expires_at" binding:"min=0"` // Unix 时间戳（秒）
+	MaxViews  int   `json:"max_views" binding:"min=0"`  // 查看次数上限，所有者查看不计入，0 表示不限
+}
+
+func expiryPayload(img *models.Image) gin.H {
+	return gin.H{
+		"identifier": img.Identifier,
+		"expires_at": unixOrNil(img.ExpiresAt),
+		"max_views":  img.MaxViews,
+		"view_count": img.ViewCount,
+	}
+}
+
+// UpdateImageExpiry 设置或清除图片的过期时间和查看次数限制
+// @Summary      Update image expiry
+// @Description  Make an image expire at a point in time or after a number of views by anyone other than the owner. Expired images return 410 and are deleted permanently by a background job. Send all fields as 0 to keep the image forever
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        identifier  path      string               true  "Image identifier"
+// @Param        request     body      UpdateExpiryRequest  true  "Expiry settings"
+// @Success      200         {object}  common.Response  "Image expiry updated successfully"
+// @Failure      400         {object}  common.Response  "Invalid request body"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      403         {object}  common.Response  "Permission denied"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/expiry [put]
+func (h *Handler) UpdateImageExpiry(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "Invalid user session")
+		return
+	}
+	identifier := c.Param("identifier")
+	ctx := c.Request.Context()
+
+	var req UpdateExpiryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresAt > 0 {
+		t := time.Unix(req.ExpiresAt, 0)
+		expiresAt = &t
+	}
+	expiry, err := imagesvc.NewImageExpiry(time.Duration(req.ExpiresIn)*time.Second, expiresAt, req.MaxViews, time.Now())
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	image, err := h.queryService.GetImageByIdentifier(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Image not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get image information")
+		return
+	}
+	if image.UserID != userID {
+		common.RespondError(c, http.StatusForbidden, "You don't have permission to update this image")
+		return
+	}
+
+	updated, err := h.queryService.UpdateImageExpiry(ctx, identifier, userID, expiry)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to update expiry of image %s: %v", utils.SanitizeLogMessage(identifier), err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to update image expiry")
+		return
+	}
+
+	_ = h.cacheHelper.CacheImage(ctx, updated)
+	common.RespondSuccessMessage(c, "Image expiry updated successfully", expiryPayload(updated))
+}
+
+// resolveUploadExpiry 解析上传请求的过期设置；请求未指定时使用 API Token 的默认有效期
+func resolveUploadExpiry(c *gin.Context, request *parsedUploadRequest) (imagesvc.ImageExpiry, error) {
+	now := time.Now()
+	if request.expiresIn == "" && request.expiresAt == "" && request.maxViews == "" {
+		if ttl, ok := c.Get(middleware.ContextDefaultImageTTLKey); ok {
+			if d, ok := ttl.(time.Duration); ok && d > 0 {
+				return imagesvc.NewImageExpiry(d, nil, 0, now)
+			}
+		}
+		return imagesvc.ImageExpiry{}, nil
+	}
+
+	var (
+		expiresIn time.Duration
+		expiresAt *time.Time
+		maxViews  int
+		err       error
+	)
+	if request.expiresIn != "" {
+		if expiresIn, err = parseExpiresIn(request.expiresIn); err != nil {
+			return imagesvc.ImageExpiry{}, err
+		}
+	}
+	if request.expiresAt != "" {
+		t, err := parseExpiresAt(request.expiresAt)
+		if err != nil {
+			return imagesvc.ImageExpiry{}, err
+		}
+		expiresAt = &t
+	}
+	if request.maxViews != "" {
+		if maxViews, err = strconv.Atoi(request.maxViews); err != nil {
+			return imagesvc.ImageExpiry{}, fmt.Errorf("%w: invalid max_views", imagesvc.ErrInvalidExpiry)
+		}
+	}
+	return imagesvc.NewImageExpiry(expiresIn, expiresAt, maxViews, now)
+}
+
+// parseExpiresIn 支持秒数、Go 时长（如 90m、24h）和天数（如 7d）
+func parseExpiresIn(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid expires_in", imagesvc.ErrInvalidExpiry)
+	}
+	return d, nil
+}
+
+// parseExpiresAt 支持 Unix 时间戳（秒）和 RFC 3339
+func parseExpiresAt(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid expires_at", imagesvc.ErrInvalidExpiry)
+	}
+	return t, nil
+}
@@ -0,0 +1,122 @@
+package images
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateFocalPointRequest 设置图片焦点请求，x/y 均为空时清除焦点
+type UpdateFocalPointRequest struct {
+	X *float64 `json:"x"`
+	Y *float64 `json:"y"`
+}
+
+// validate 校验焦点坐标：必须同时提供或同时为空，取值范围 0-1
+func (r *UpdateFocalPointRequest) validate() error {
+	if (r.X == nil) != (r.Y == nil) {
+		return errors.New("both 'x' and 'y' are required, or neither to clear the focal point")
+	}
+	if r.X == nil {
+		return nil
+	}
+	if *r.X < 0 || *r.X > 1 || *r.Y < 0 || *r.Y > 1 {
+		return errors.New("'x' and 'y' must be between 0 and 1")
+	}
+	return nil
+}
+
+// UpdateFocalPoint 设置图片手动焦点并重新生成裁剪缩略图
+// @Summary      Update image focal point
+// @Description  Set or clear the manual focal point (fractions of width and height) used for fixed-aspect thumbnail crops, and regenerate those thumbnails
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        identifier  path      string                   true  "Image identifier"
+// @Param        request     body      UpdateFocalPointRequest  true  "Focal point, null to clear"
+// @Success      200         {object}  common.Response  "Focal point updated successfully"
+// @Failure      400         {object}  common.Response  "Invalid request body"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      403         {object}  common.Response  "Permission denied"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/focal-point [put]
+func (h *Handler) UpdateFocalPoint(c *gin.Context) {
+	if c.IsAborted() {
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "Invalid user session")
+		return
+	}
+
+	identifier := c.Param("identifier")
+	if identifier == "" {
+		common.RespondError(c, http.StatusBadRequest, "Image identifier is required")
+		return
+	}
+	ctx := c.Request.Context()
+
+	var req UpdateFocalPointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	image, err := h.queryService.GetImageByIdentifier(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Image not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get image information")
+		return
+	}
+
+	if image.UserID != userID {
+		common.RespondError(c, http.StatusForbidden, "You don't have permission to update this image")
+		return
+	}
+
+	updates := map[string]any{
+		"focal_x": req.X,
+		"focal_y": req.Y,
+	}
+	updatedImage, err := h.queryService.UpdateImageByIdentifier(ctx, identifier, updates)
+	if err != nil {
+		common.RespondError(c, http.StatusInternalServerError, "Failed to update image focal point")
+		return
+	}
+
+	_ = h.cacheHelper.CacheImage(ctx, updatedImage)
+
+	queued := 0
+	if h.converter != nil {
+		queued, err = h.converter.RegenerateCroppedThumbnails(updatedImage)
+		if err != nil {
+			imageHandlerLog.Warnf("Failed to regenerate cropped thumbnails for %s: %v", updatedImage.Identifier, err)
+		}
+	}
+
+	var focalPoint gin.H
+	if x, y, ok := updatedImage.FocalPoint(); ok {
+		focalPoint = gin.H{"x": x, "y": y}
+	}
+
+	common.RespondSuccessMessage(c, "Image focal point updated successfully", gin.H{
+		"identifier":            updatedImage.Identifier,
+		"focal_point":           focalPoint,
+		"regenerating_variants": queued,
+	})
+}
@@ -0,0 +1,35 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateFocalPointRequestValidate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		req     UpdateFocalPointRequest
+		wantErr bool
+	}{
+		{"clear", UpdateFocalPointRequest{}, false},
+		{"centre", UpdateFocalPointRequest{X: f(0.5), Y: f(0.5)}, false},
+		{"edges", UpdateFocalPointRequest{X: f(0), Y: f(1)}, false},
+		{"missing y", UpdateFocalPointRequest{X: f(0.5)}, true},
+		{"out of range", UpdateFocalPointRequest{X: f(1.5), Y: f(0.5)}, true},
+		{"negative", UpdateFocalPointRequest{X: f(0.5), Y: f(-0.1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
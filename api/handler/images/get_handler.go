@@ -18,6 +18,7 @@ import (
 	"github.com/anoixa/image-bed/internal/image"
 	"github.com/anoixa/image-bed/storage"
 	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/svg"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/sync/singleflight"
 )
@@ -26,11 +27,25 @@ var fileDownloadGroup singleflight.Group
 
 const privateImageCacheControl = "private, no-store"
 
-func cacheControlForImage(isPublic bool) string {
-	if isPublic {
-		return config.CacheControlPublic
+// 图片响应依赖的 Client Hints，通过 Accept-CH 向浏览器声明
+const (
+	acceptClientHints = "Sec-CH-Width, Sec-CH-DPR, Save-Data"
+	imageVaryHeaders  = "Accept, Sec-CH-Width, Sec-CH-DPR, Save-Data"
+)
+
+// cacheControlForImage 限次图片每次访问都要计数，不允许缓存；有过期时间的图片缓存不超过剩余有效期
+func cacheControlForImage(img *models.Image) string {
+	if !img.IsPublic || img.MaxViews > 0 {
+		return privateImageCacheControl
+	}
+	if img.ExpiresAt != nil {
+		maxAge := int(time.Until(*img.ExpiresAt).Seconds())
+		if maxAge <= 0 {
+			return privateImageCacheControl
+		}
+		return fmt.Sprintf("public, max-age=%d", min(maxAge, 86400))
 	}
-	return privateImageCacheControl
+	return config.CacheControlPublic
 }
 
 // checkETag 检查客户端缓存是否有效
@@ -95,15 +110,19 @@ func trimWeakETag(etag string) string {
 
 // GetImage 获取图片
 // @Summary      Get image by identifier
-// @Description  Retrieve an image by its unique identifier. Returns original or converted format based on Accept header
+// @Description  Retrieve an image by its unique identifier. Returns original or converted format based on Accept header, and a smaller thumbnail when Sec-CH-Width, Sec-CH-DPR or Save-Data hints allow
 // @Tags         images
 // @Accept       json
 // @Produce      image/*
-// @Param        identifier  path      string  true   "Image identifier"
+// @Param        identifier    path      string  true   "Image identifier"
+// @Param        Sec-CH-Width  header    int     false  "Intended display width in physical pixels"
+// @Param        Sec-CH-DPR    header    number  false  "Device pixel ratio"
+// @Param        Save-Data     header    string  false  "on to prefer smaller responses"
 // @Success      200         {file}    binary   "Image data"
 // @Failure      400         {object}  common.Response  "Invalid identifier"
 // @Failure      403         {object}  common.Response  "Private image, access denied"
 // @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      410         {object}  common.Response  "Image expired or out of views"
 // @Failure      500         {object}  common.Response  "Internal server error"
 // @Security     ApiKeyAuth
 // @Router       /images/{identifier} [get]
@@ -120,10 +139,12 @@ func (h *Handler) GetImage(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetUint(middleware.ContextUserIDKey)
+	userID := viewerID(c)
 	acceptHeader := c.GetHeader("Accept")
+	c.Header("Accept-CH", acceptClientHints)
+	c.Header("Vary", imageVaryHeaders)
 
-	result, err := h.readService.GetImageWithVariant(c.Request.Context(), identifier, acceptHeader, userID)
+	result, err := h.readService.GetImageWithVariant(c.Request.Context(), identifier, acceptHeader, parseClientHints(c), userID)
 	if err != nil {
 		if errors.Is(err, image.ErrForbidden) {
 			common.RespondError(c, http.StatusForbidden, "This image is private")
@@ -132,6 +153,14 @@ func (h *Handler) GetImage(c *gin.Context) {
 		h.handleMetadataError(c, utils.SanitizeLogMessage(identifier), err)
 		return
 	}
+	// 分享链接按相册所有者读取，访客的查看仍需计数
+	if isShareRequest(c) {
+		if err := h.readService.CheckImageAvailability(c.Request.Context(), result.Image, true); err != nil {
+			h.handleMetadataError(c, utils.SanitizeLogMessage(identifier), err)
+			return
+		}
+	}
+	defer h.recordImageHit(c, result.Image)
 
 	if result.IsOriginal {
 		middleware.RecordImageOriginalResponse()
@@ -149,16 +178,52 @@ func (h *Handler) GetImage(c *gin.Context) {
 	}
 }
 
+func isShareRequest(c *gin.Context) bool {
+	return c.GetUint(middleware.ContextShareOwnerIDKey) != 0
+}
+
+// viewerID 返回用于权限校验的用户 ID。经分享链接校验过的请求（图片已确认属于分享的相册）按相册所有者读取
+func viewerID(c *gin.Context) uint {
+	if isShareRequest(c) {
+		return c.GetUint(middleware.ContextShareOwnerIDKey)
+	}
+	return c.GetUint(middleware.ContextUserIDKey)
+}
+
+// parseClientHints 读取 Client Hints，兼容旧版无前缀的 Width/DPR 头；非法值按未提供处理
+func parseClientHints(c *gin.Context) image.ClientHints {
+	var hints image.ClientHints
+	width := c.GetHeader("Sec-CH-Width")
+	if width == "" {
+		width = c.GetHeader("Width")
+	}
+	if w, err := strconv.Atoi(strings.TrimSpace(width)); err == nil && w > 0 {
+		hints.Width = w
+	}
+	dpr := c.GetHeader("Sec-CH-DPR")
+	if dpr == "" {
+		dpr = c.GetHeader("DPR")
+	}
+	if d, err := strconv.ParseFloat(strings.TrimSpace(dpr), 64); err == nil && d > 0 && d <= 10 {
+		hints.DPR = d
+	}
+	hints.SaveData = strings.EqualFold(strings.TrimSpace(c.GetHeader("Save-Data")), "on")
+	return hints
+}
+
 // serveOriginalImage 提供原图
 func (h *Handler) serveOriginalImage(c *gin.Context, image *models.Image) {
 	if h.cacheHelper == nil {
 		common.RespondError(c, http.StatusInternalServerError, "Cache not initialized")
 		return
 	}
+	setOriginalSecurityHeaders(c, image.MimeType)
+	markServed(c, models.ImageStatVariantOriginal, 0)
 
 	// 检查是否可以使用直链
 	if directURL := h.getDirectURLIfPossible(c, image); directURL != "" {
 		middleware.RecordImageDirectRedirect()
+		markServed(c, models.ImageStatVariantOriginal, image.FileSize)
 		c.Header("Cache-Control", config.CacheControlPublic)
 		c.Redirect(http.StatusFound, directURL)
 		return
@@ -206,18 +271,31 @@ func (h *Handler) serveOriginalImage(c *gin.Context, image *models.Image) {
 	}()
 
 	middleware.RecordImageReaderResponse()
-	h.serveReadSeekerContent(c, image.Identifier, image.MimeType, image.FileHash, stream, false, cacheControlForImage(image.IsPublic))
+	h.serveReadSeekerContent(c, image.Identifier, image.MimeType, image.FileHash, stream, false, cacheControlForImage(image))
+}
+
+// setOriginalSecurityHeaders SVG 原图可能被浏览器当作文档打开，用 CSP 禁止脚本和外部资源，并禁止 MIME 嗅探
+func setOriginalSecurityHeaders(c *gin.Context, mimeType string) {
+	if !utils.IsVectorImage(mimeType) {
+		return
+	}
+	c.Header("Content-Security-Policy", svg.ContentSecurityPolicy)
+	c.Header("X-Content-Type-Options", "nosniff")
 }
 
 // getDirectURLIfPossible 尝试获取直链 URL
 func (h *Handler) getDirectURLIfPossible(c *gin.Context, img *models.Image) string {
+	// SVG 必须经由本服务返回，才能附带 CSP 响应头
+	if utils.IsVectorImage(img.MimeType) {
+		return ""
+	}
 	return h.getVariantDirectURLIfPossible(c, img, img.StoragePath)
 }
 
 // getVariantDirectURLIfPossible 尝试获取变体的直链 URL
 func (h *Handler) getVariantDirectURLIfPossible(c *gin.Context, img *models.Image, storagePath string) string {
-	// 私有图片不支持直链
-	if !img.IsPublic {
+	// 私有图片不支持直链，过期和限次图片必须经由本服务校验
+	if !img.IsPublic || img.IsEphemeral() {
 		return ""
 	}
 
@@ -260,7 +338,7 @@ func (h *Handler) serveByStreaming(c *gin.Context, img *models.Image, streamer s
 		return true
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(img.IsPublic))
+	c.Header("Cache-Control", cacheControlForImage(img))
 	c.Header("Content-Type", img.MimeType)
 
 	_, err := streamer.StreamTo(c.Request.Context(), img.StoragePath, c.Writer)
@@ -306,7 +384,7 @@ func (h *Handler) serveBySendfile(c *gin.Context, img *models.Image, opener stor
 	}
 	defer func() { _ = file.Close() }()
 
-	c.Header("Cache-Control", cacheControlForImage(img.IsPublic))
+	c.Header("Cache-Control", cacheControlForImage(img))
 	c.Header("Content-Type", img.MimeType)
 
 	http.ServeContent(c.Writer, c.Request, img.Identifier, time.Time{}, file)
@@ -321,7 +399,7 @@ func (h *Handler) serveImageData(c *gin.Context, img *models.Image, data []byte)
 		return
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(img.IsPublic))
+	c.Header("Cache-Control", cacheControlForImage(img))
 	c.Header("Content-Type", img.MimeType)
 	c.Header("Content-Length", strconv.Itoa(len(data)))
 
@@ -330,9 +408,11 @@ func (h *Handler) serveImageData(c *gin.Context, img *models.Image, data []byte)
 
 // serveVariantImage 提供格式变体（支持直链模式）
 func (h *Handler) serveVariantImage(c *gin.Context, img *models.Image, result *image.VariantResult) {
+	markServed(c, result.Variant.Format, 0)
 	// 检查变体是否可以使用直链（使用变体自己的路径）
 	if directURL := h.getVariantDirectURLIfPossible(c, img, result.StoragePath); directURL != "" {
 		middleware.RecordImageDirectRedirect()
+		markServed(c, result.Variant.Format, result.Variant.FileSize)
 		c.Header("Cache-Control", config.CacheControlPublic)
 		c.Redirect(http.StatusFound, directURL)
 		return
@@ -350,18 +430,18 @@ func (h *Handler) serveVariantImage(c *gin.Context, img *models.Image, result *i
 
 	if imageData, ok := h.getOrPopulateImageDataCache(c.Request.Context(), provider, remoteImageDataCacheKey(img.StorageConfigID, result.StoragePath), result.StoragePath); ok {
 		middleware.RecordImageCacheResponse()
-		h.serveVariantData(c, result, imageData, img.IsPublic)
+		h.serveVariantData(c, result, imageData, cacheControlForImage(img))
 		return
 	}
 
 	if opener, ok := provider.(storage.FileOpener); ok {
-		if h.serveVariantBySendfile(c, result, opener, img.IsPublic) {
+		if h.serveVariantBySendfile(c, result, opener, cacheControlForImage(img)) {
 			return
 		}
 	}
 
 	if streamer, ok := provider.(storage.StreamProvider); ok {
-		if h.serveVariantByStreaming(c, result, streamer, img.IsPublic) {
+		if h.serveVariantByStreaming(c, result, streamer, cacheControlForImage(img)) {
 			return
 		}
 	}
@@ -380,17 +460,17 @@ func (h *Handler) serveVariantImage(c *gin.Context, img *models.Image, result *i
 	}()
 
 	middleware.RecordImageReaderResponse()
-	h.serveReadSeekerContent(c, result.Identifier, result.MIMEType, result.Variant.FileHash, stream, true, cacheControlForImage(img.IsPublic))
+	h.serveReadSeekerContent(c, result.Identifier, result.MIMEType, result.Variant.FileHash, stream, true, cacheControlForImage(img))
 }
 
 // serveVariantByStreaming 使用流式传输格式变体
-func (h *Handler) serveVariantByStreaming(c *gin.Context, result *image.VariantResult, streamer storage.StreamProvider, isPublic bool) bool {
+func (h *Handler) serveVariantByStreaming(c *gin.Context, result *image.VariantResult, streamer storage.StreamProvider, cacheControl string) bool {
 	// 使用 FileHash 作为变体 ETag
 	if checkETag(c, result.Variant.FileHash) {
 		return true
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(isPublic))
+	c.Header("Cache-Control", cacheControl)
 	c.Header("Content-Type", result.MIMEType)
 	c.Header("X-Content-Type-Options", "nosniff")
 
@@ -404,7 +484,7 @@ func (h *Handler) serveVariantByStreaming(c *gin.Context, result *image.VariantR
 }
 
 // serveVariantBySendfile 使用 sendfile 传输格式变体
-func (h *Handler) serveVariantBySendfile(c *gin.Context, result *image.VariantResult, opener storage.FileOpener, isPublic bool) bool {
+func (h *Handler) serveVariantBySendfile(c *gin.Context, result *image.VariantResult, opener storage.FileOpener, cacheControl string) bool {
 	// 检查 ETag 缓存（使用 FileHash 作为变体 ETag）
 	if checkETag(c, result.Variant.FileHash) {
 		return true
@@ -421,7 +501,7 @@ func (h *Handler) serveVariantBySendfile(c *gin.Context, result *image.VariantRe
 		return false
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(isPublic))
+	c.Header("Cache-Control", cacheControl)
 	c.Header("Content-Type", result.MIMEType)
 	c.Header("Content-Length", strconv.FormatInt(stat.Size(), 10))
 	c.Header("X-Content-Type-Options", "nosniff")
@@ -432,13 +512,13 @@ func (h *Handler) serveVariantBySendfile(c *gin.Context, result *image.VariantRe
 }
 
 // serveVariantData 从内存提供格式变体数据
-func (h *Handler) serveVariantData(c *gin.Context, result *image.VariantResult, data []byte, isPublic bool) {
+func (h *Handler) serveVariantData(c *gin.Context, result *image.VariantResult, data []byte, cacheControl string) {
 	// 使用 FileHash 作为变体 ETag
 	if checkETag(c, result.Variant.FileHash) {
 		return
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(isPublic))
+	c.Header("Cache-Control", cacheControl)
 	c.Header("Content-Type", result.MIMEType)
 	c.Header("Content-Length", strconv.Itoa(len(data)))
 	c.Header("X-Content-Type-Options", "nosniff")
@@ -582,6 +662,11 @@ func (h *Handler) handleMetadataError(c *gin.Context, identifier string, err err
 		return
 	}
 
+	if errors.Is(err, image.ErrImageExpired) {
+		common.RespondError(c, http.StatusGone, "This image has expired")
+		return
+	}
+
 	// 临时错误返回 503
 	if errors.Is(err, image.ErrTemporaryFailure) {
 		imageHandlerLog.Errorf("Temporary failure fetching metadata for '%s': %v", utils.SanitizeLogMessage(identifier), err)
@@ -15,6 +15,7 @@ import (
 	"github.com/anoixa/image-bed/database/models"
 	imageSvc "github.com/anoixa/image-bed/internal/image"
 	"github.com/anoixa/image-bed/storage"
+	"github.com/anoixa/image-bed/utils/svg"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -630,3 +631,59 @@ func TestServeImageDataUsesPrivateCacheControlForPrivateImages(t *testing.T) {
 
 	assert.Equal(t, privateImageCacheControl, w.Header().Get("Cache-Control"))
 }
+
+func TestSetOriginalSecurityHeadersForSVG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	setOriginalSecurityHeaders(c, "image/svg+xml")
+	assert.Equal(t, svg.ContentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	setOriginalSecurityHeaders(c, "image/png")
+	assert.Empty(t, w.Header().Get("Content-Security-Policy"))
+
+	h := &Handler{}
+	c.Request = httptest.NewRequest(http.MethodGet, "/images/logo", nil)
+	assert.Empty(t, h.getDirectURLIfPossible(c, &models.Image{MimeType: "image/svg+xml", IsPublic: true}), "svg must not bypass the CSP via direct links")
+}
+
+func TestParseClientHints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    imageSvc.ClientHints
+	}{
+		{name: "none", want: imageSvc.ClientHints{}},
+		{
+			name:    "sec-ch headers",
+			headers: map[string]string{"Sec-CH-Width": "640", "Sec-CH-DPR": "2", "Save-Data": "on"},
+			want:    imageSvc.ClientHints{Width: 640, DPR: 2, SaveData: true},
+		},
+		{
+			name:    "legacy headers",
+			headers: map[string]string{"Width": "320", "DPR": "1.5"},
+			want:    imageSvc.ClientHints{Width: 320, DPR: 1.5},
+		},
+		{
+			name:    "invalid values ignored",
+			headers: map[string]string{"Sec-CH-Width": "-1", "Sec-CH-DPR": "abc", "Save-Data": "off"},
+			want:    imageSvc.ClientHints{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/images/abc", nil)
+			for k, v := range tt.headers {
+				c.Request.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, parseClientHints(c))
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/anoixa/image-bed/cache"
 	"github.com/anoixa/image-bed/config"
 	configSvc "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/repo/accounts"
 	"github.com/anoixa/image-bed/database/repo/albums"
 	"github.com/anoixa/image-bed/database/repo/images"
 	"github.com/anoixa/image-bed/internal/image"
@@ -20,15 +21,19 @@ type Handler struct {
 	variantService   *image.VariantService
 	thumbnailService *image.ThumbnailService
 	variantRepo      *images.VariantRepository
+	converter        *image.Converter
 	writeService     *image.WriteService
 	readService      *image.ReadService
 	deleteService    *image.DeleteService
+	versionService   *image.VersionService
 	queryService     *image.QueryService
 	randomService    *random.Service
 	baseURL          string
+
+	trashRetentionDays int
 }
 
-func NewHandler(cacheProvider cache.Provider, imagesRepo *images.Repository, variantRepo *images.VariantRepository, converter *image.Converter, configManager *configSvc.Manager, cfg *config.Config, baseURL string, albumsRepo *albums.Repository) *Handler {
+func NewHandler(cacheProvider cache.Provider, imagesRepo *images.Repository, variantRepo *images.VariantRepository, converter *image.Converter, configManager *configSvc.Manager, cfg *config.Config, baseURL string, albumsRepo *albums.Repository, accountsRepo *accounts.Repository) *Handler {
 	helperCfg := cache.HelperConfig{
 		ImageCacheTTL:         cache.DefaultImageCacheExpiration,
 		ImageDataCacheTTL:     1 * time.Hour,
@@ -48,29 +53,39 @@ func NewHandler(cacheProvider cache.Provider, imagesRepo *images.Repository, var
 
 	cacheHelper := cache.NewHelper(cacheProvider, helperCfg)
 	variantService := image.NewVariantService(variantRepo, configManager, cacheHelper)
-	thumbnailService := image.NewThumbnailService(variantRepo)
-	writeService := image.NewWriteService(imagesRepo, albumsRepo, converter, cacheHelper, baseURL)
+	thumbnailService := image.NewThumbnailService(variantRepo, converter)
+	writeService := image.NewWriteService(imagesRepo, albumsRepo, converter, cacheHelper, baseURL, image.NewIngestPolicyResolver(accountsRepo, configManager))
 	readService := image.NewReadService(imagesRepo, variantService, converter, cacheHelper, baseURL, image.SubmitBackgroundTask)
 	deleteService := image.NewDeleteService(imagesRepo, variantRepo, cacheHelper)
+	versionService := image.NewVersionService(imagesRepo, writeService, deleteService, converter)
 	queryService := image.NewQueryService(imagesRepo, configManager)
 	var randomService *random.Service
 	if configManager != nil {
 		randomService = random.NewService(configManager)
 	}
 
+	trashRetentionDays := 0
+	if cfg != nil {
+		trashRetentionDays = cfg.TrashRetentionDays
+	}
+
 	return &Handler{
 		cacheHelper:      cacheHelper,
 		imageDataCaching: cfg != nil && cfg.CacheEnableImageCaching,
 		repo:             imagesRepo,
 		configManager:    configManager,
 		variantRepo:      variantRepo,
+		converter:        converter,
 		variantService:   variantService,
 		thumbnailService: thumbnailService,
 		writeService:     writeService,
 		readService:      readService,
 		deleteService:    deleteService,
+		versionService:   versionService,
 		queryService:     queryService,
 		randomService:    randomService,
 		baseURL:          baseURL,
+
+		trashRetentionDays: trashRetentionDays,
 	}
 }
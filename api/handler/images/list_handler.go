@@ -2,37 +2,52 @@ package images
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/anoixa/image-bed/api/common"
 	"github.com/anoixa/image-bed/api/middleware"
 	"github.com/anoixa/image-bed/config"
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
+	"github.com/anoixa/image-bed/database/repo/images"
+	svcTags "github.com/anoixa/image-bed/internal/tags"
 	"github.com/anoixa/image-bed/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type ImageDTO struct {
-	ID           uint   `json:"id"`
-	Identifier   string `json:"identifier"`
-	URL          string `json:"url"`
-	ThumbnailURL string `json:"thumbnail_url"`
-	OriginalName string `json:"original_name"`
-	FileSize     int64  `json:"file_size"`
-	MimeType     string `json:"mime_type"`
-	Width        int    `json:"width"`
-	Height       int    `json:"height"`
-	IsPublic     bool   `json:"is_public"`
-	CreatedAt    int64  `json:"created_at"`
+	ID           uint                `json:"id"`
+	Identifier   string              `json:"identifier"`
+	URL          string              `json:"url"`
+	ThumbnailURL string              `json:"thumbnail_url"`
+	OriginalName string              `json:"original_name"`
+	FileSize     int64               `json:"file_size"`
+	MimeType     string              `json:"mime_type"`
+	Width        int                 `json:"width"`
+	Height       int                 `json:"height"`
+	IsPublic     bool                `json:"is_public"`
+	Title        string              `json:"title"`
+	Description  string              `json:"description"`
+	AltText      string              `json:"alt_text"`
+	Tags         []string            `json:"tags"`
+	CreatedAt    int64               `json:"created_at"`
+	ExpiresAt    *int64              `json:"expires_at,omitempty"` // Unix 秒，未设置时永久保存
+	MaxViews     int                 `json:"max_views,omitempty"`
+	ViewCount    int64               `json:"view_count,omitempty"` // 仅限次图片计数
+	Highlight    *fulltext.Highlight `json:"highlight,omitempty"`  // 仅在按关键词搜索时返回
 }
 
 type ImageRequestBody struct {
-	StorageType string `json:"storage_type"`
-	Identifier  string `json:"identifier"`
-	Search      string `json:"search"`
-	AlbumID     *uint  `json:"album_id"`
-	StartTime   int64  `json:"start_time"` // Unix时间戳（毫秒）
-	EndTime     int64  `json:"end_time"`   // Unix时间戳（毫秒）
-	Sort        string `json:"sort"`       // asc 或 desc，默认 desc
+	StorageType string   `json:"storage_type"`
+	Identifier  string   `json:"identifier"`
+	Search      string   `json:"search"` // 全文搜索原图名、标题、描述、标签和相册名，按词前缀匹配
+	AlbumID     *uint    `json:"album_id"`
+	Tags        []string `json:"tags"`         // 必须同时包含的标签
+	AnyTags     []string `json:"any_tags"`     // 至少包含其一的标签
+	ExcludeTags []string `json:"exclude_tags"` // 排除包含这些标签的图片
+	StartTime   int64    `json:"start_time"`   // Unix时间戳（毫秒）
+	EndTime     int64    `json:"end_time"`     // Unix时间戳（毫秒）
+	Sort        string   `json:"sort"`         // asc 或 desc，默认 desc；带 search 且留空时按相关度排序
 
 	Page  int `json:"page" binding:"required"`
 	Limit int `json:"limit" binding:"required"`
@@ -82,7 +97,13 @@ func (h *Handler) ListImages(c *gin.Context) {
 		limit = config.MaxPerPage
 	}
 
-	result, err := h.queryService.ListImages(c.Request.Context(), body.StorageType, body.Identifier, body.Search, body.AlbumID, body.StartTime, body.EndTime, body.Sort, page, limit, int(userID))
+	tagFilter, err := buildTagFilter(body.Tags, body.AnyTags, body.ExcludeTags)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid tag filter")
+		return
+	}
+
+	result, err := h.queryService.ListImages(c.Request.Context(), body.StorageType, body.Identifier, body.Search, body.AlbumID, tagFilter, body.StartTime, body.EndTime, body.Sort, page, limit, int(userID))
 	if err != nil {
 		imageHandlerLog.Errorf("Failed to get image list for user=%d: %v", userID, err)
 		common.RespondError(c, http.StatusInternalServerError, "Failed to get image list")
@@ -90,6 +111,9 @@ func (h *Handler) ListImages(c *gin.Context) {
 	}
 
 	imageListDTO := h.toImageDTOs(result.Images)
+	for i := range imageListDTO {
+		imageListDTO[i].Highlight = result.Highlights[imageListDTO[i].ID]
+	}
 
 	common.RespondSuccess(c, ImageListResponse{
 		Images:     imageListDTO,
@@ -105,6 +129,11 @@ func (h *Handler) toImageDTO(image *models.Image) ImageDTO {
 		return ImageDTO{}
 	}
 
+	tagNames := make([]string, len(image.Tags))
+	for i, tag := range image.Tags {
+		tagNames[i] = tag.Name
+	}
+
 	imageUrl := utils.BuildImageURL(h.baseURL, image.Identifier)
 	thumbnailUrl := utils.BuildThumbnailURL(h.baseURL, image.Identifier)
 
@@ -119,8 +148,37 @@ func (h *Handler) toImageDTO(image *models.Image) ImageDTO {
 		Width:        image.Width,
 		Height:       image.Height,
 		IsPublic:     image.IsPublic,
+		Title:        image.Title,
+		Description:  image.Description,
+		AltText:      image.AltText,
+		Tags:         tagNames,
 		CreatedAt:    image.CreatedAt.Unix(),
+		ExpiresAt:    unixOrNil(image.ExpiresAt),
+		MaxViews:     image.MaxViews,
+		ViewCount:    image.ViewCount,
+	}
+}
+
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
+
+// buildTagFilter 规范化标签筛选条件
+func buildTagFilter(all, anyOf, exclude []string) (images.TagFilter, error) {
+	var filter images.TagFilter
+	var err error
+	if filter.Tags, err = svcTags.NormalizeNames(all); err != nil {
+		return filter, err
+	}
+	if filter.AnyTags, err = svcTags.NormalizeNames(anyOf); err != nil {
+		return filter, err
 	}
+	filter.ExcludeTags, err = svcTags.NormalizeNames(exclude)
+	return filter, err
 }
 
 func (h *Handler) toImageDTOs(images []*models.Image) []ImageDTO {
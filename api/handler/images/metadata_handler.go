@@ -0,0 +1,136 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 元数据字段的最大长度（字符数），与 models.Image 的列宽一致
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 2000
+	maxAltTextLength     = 500
+)
+
+// UpdateMetadataRequest 更新图片元数据请求，未提供的字段保持不变，空字符串清除该字段
+type UpdateMetadataRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	AltText     *string `json:"alt_text"`
+}
+
+// updates 去除首尾空白并校验长度，返回需要更新的列
+func (r *UpdateMetadataRequest) updates() (map[string]any, error) {
+	fields := []struct {
+		column string
+		value  *string
+		max    int
+	}{
+		{"title", r.Title, maxTitleLength},
+		{"description", r.Description, maxDescriptionLength},
+		{"alt_text", r.AltText, maxAltTextLength},
+	}
+
+	updates := make(map[string]any, len(fields))
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(value) > field.max {
+			return nil, fmt.Errorf("'%s' must be at most %d characters", field.column, field.max)
+		}
+		updates[field.column] = value
+	}
+	if len(updates) == 0 {
+		return nil, errors.New("at least one of 'title', 'description' or 'alt_text' is required")
+	}
+	return updates, nil
+}
+
+// UpdateImageMetadata 更新图片标题、描述和替代文本
+// @Summary      Update image metadata
+// @Description  Set the title, description and alt text of an image. Omitted fields are left unchanged, empty strings clear them. The response includes embed links using the new alt text
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        identifier  path      string                 true  "Image identifier"
+// @Param        request     body      UpdateMetadataRequest  true  "Metadata fields"
+// @Success      200         {object}  common.Response  "Image metadata updated successfully"
+// @Failure      400         {object}  common.Response  "Invalid request body"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      403         {object}  common.Response  "Permission denied"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/metadata [put]
+func (h *Handler) UpdateImageMetadata(c *gin.Context) {
+	if c.IsAborted() {
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "Invalid user session")
+		return
+	}
+
+	identifier := c.Param("identifier")
+	if identifier == "" {
+		common.RespondError(c, http.StatusBadRequest, "Image identifier is required")
+		return
+	}
+	ctx := c.Request.Context()
+
+	var req UpdateMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	updates, err := req.updates()
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	image, err := h.queryService.GetImageByIdentifier(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Image not found")
+			return
+		}
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get image information")
+		return
+	}
+
+	if image.UserID != userID {
+		common.RespondError(c, http.StatusForbidden, "You don't have permission to update this image")
+		return
+	}
+
+	updatedImage, err := h.queryService.UpdateImageByIdentifier(ctx, identifier, updates)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to update metadata for %s: %v", identifier, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to update image metadata")
+		return
+	}
+
+	_ = h.cacheHelper.CacheImage(ctx, updatedImage)
+
+	common.RespondSuccessMessage(c, "Image metadata updated successfully", gin.H{
+		"identifier":  updatedImage.Identifier,
+		"title":       updatedImage.Title,
+		"description": updatedImage.Description,
+		"alt_text":    updatedImage.AltText,
+		"links":       utils.BuildLinkFormats(h.baseURL, updatedImage.Identifier, updatedImage.EmbedAlt()),
+	})
+}
@@ -0,0 +1,26 @@
+package images
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateMetadataRequestUpdates(t *testing.T) {
+	s := func(v string) *string { return &v }
+
+	updates, err := (&UpdateMetadataRequest{Title: s("  Harbour at night "), AltText: s("")}).updates()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Harbour at night", "alt_text": ""}, updates)
+
+	_, err = (&UpdateMetadataRequest{}).updates()
+	assert.Error(t, err, "empty request")
+
+	_, err = (&UpdateMetadataRequest{AltText: s(strings.Repeat("a", maxAltTextLength+1))}).updates()
+	assert.Error(t, err, "alt text too long")
+
+	_, err = (&UpdateMetadataRequest{Title: s(strings.Repeat("字", maxTitleLength))}).updates()
+	assert.NoError(t, err, "length counts characters, not bytes")
+}
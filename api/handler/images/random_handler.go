@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/anoixa/image-bed/api/common"
 	"github.com/anoixa/image-bed/database/repo/images"
@@ -31,11 +32,25 @@ type RandomImageQuery struct {
 	MaxHeight   int    `form:"max_height"`    // 最大高度
 	RequireWebP bool   `form:"require_webp"`  // 是否只返回有WebP变体的图片
 	MaxFileSize int64  `form:"max_file_size"` // 最大文件大小（字节），例如10485760表示10MB
+	Tags        string `form:"tags"`          // 逗号分隔，必须同时包含的标签
+	AnyTags     string `form:"any_tags"`      // 逗号分隔，至少包含其一的标签
+	ExcludeTags string `form:"exclude_tags"`  // 逗号分隔，排除包含这些标签的图片
+}
+
+// parseTagFilterQuery 解析逗号分隔的标签筛选参数
+func parseTagFilterQuery(all, anyOf, exclude string) (images.TagFilter, error) {
+	split := func(raw string) []string {
+		if strings.TrimSpace(raw) == "" {
+			return nil
+		}
+		return strings.Split(raw, ",")
+	}
+	return buildTagFilter(split(all), split(anyOf), split(exclude))
 }
 
 // RandomImage 随机图片API
 // @Summary      Get random image
-// @Description  Get a random image, optionally filtered by album, dimensions, WebP availability and file size
+// @Description  Get a random image, optionally filtered by album, dimensions, WebP availability, file size and tags
 // @Tags         images
 // @Accept       json
 // @Produce      image/*,application/json
@@ -47,6 +62,9 @@ type RandomImageQuery struct {
 // @Param        max_height     query     int     false  "Maximum image height"
 // @Param        require_webp   query     bool    false  "Only return images with WebP variant (default: false)"
 // @Param        max_file_size  query     int     false  "Maximum file size in bytes (e.g., 10485760 for 10MB)"
+// @Param        tags           query     string  false  "Comma-separated tags the image must all have"
+// @Param        any_tags       query     string  false  "Comma-separated tags the image must have at least one of"
+// @Param        exclude_tags   query     string  false  "Comma-separated tags the image must not have"
 // @Success      200  {file}    binary           "Image data (when format=image)"
 // @Success      200  {object}  common.Response  "Image metadata (when format=json)"
 // @Failure      400  {object}  common.Response  "Invalid query parameters"
@@ -64,6 +82,12 @@ func (h *Handler) RandomImage(c *gin.Context) {
 		return
 	}
 
+	tagFilter, err := parseTagFilterQuery(query.Tags, query.AnyTags, query.ExcludeTags)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid tag filter")
+		return
+	}
+
 	// 构建筛选条件
 	filter := &images.RandomImageFilter{
 		MinWidth:    query.MinWidth,
@@ -72,6 +96,7 @@ func (h *Handler) RandomImage(c *gin.Context) {
 		MaxHeight:   query.MaxHeight,
 		RequireWebP: query.RequireWebP,
 		MaxFileSize: query.MaxFileSize,
+		TagFilter:   tagFilter,
 	}
 
 	albumIDRaw, hasAlbumOverride := c.GetQuery("album_id")
@@ -116,6 +141,7 @@ func (h *Handler) RandomImage(c *gin.Context) {
 	}
 
 	// 图片模式：直接输出图片内容
+	defer h.recordImageHit(c, result.Image)
 	if result.IsOriginal {
 		h.serveOriginalImage(c, result.Image)
 	} else {
@@ -147,6 +173,9 @@ func (h *Handler) respondRandomJSON(c *gin.Context, result *image.ImageResultDTO
 		"height":       img.Height,
 		"size":         img.FileSize,
 		"mime_type":    img.MimeType,
+		"title":        img.Title,
+		"description":  img.Description,
+		"alt_text":     img.AltText,
 		"is_public":    img.IsPublic,
 		"created_at":   img.CreatedAt,
 	}
@@ -0,0 +1,75 @@
+package images
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSrcset 获取响应式图片标记
+// @Summary      Get responsive image markup
+// @Description  Return <picture>/srcset markup and candidate URLs across the configured thumbnail widths and enabled variant formats. Use format=html to get the <picture> markup as text/html
+// @Tags         images
+// @Produce      json
+// @Produce      html
+// @Param        identifier  path      string  true   "Image identifier"
+// @Param        sizes       query     string  false  "sizes attribute (default: 100vw)"
+// @Param        alt         query     string  false  "alt text (default: the image's alt text, then its title)"
+// @Param        format      query     string  false  "json (default) or html"
+// @Success      200         {object}  common.Response{data=image.SrcsetResult}  "Responsive markup"
+// @Failure      400         {object}  common.Response  "Invalid identifier"
+// @Failure      403         {object}  common.Response  "Private image, access denied"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      410         {object}  common.Response  "Image expired"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /images/{identifier}/srcset [get]
+func (h *Handler) GetSrcset(c *gin.Context) {
+	identifier := c.Param("identifier")
+	if identifier == "" || strings.ContainsAny(identifier, "/\\") || strings.Contains(identifier, "..") {
+		common.RespondError(c, http.StatusBadRequest, "Invalid image identifier")
+		return
+	}
+	outputFormat := c.DefaultQuery("format", "json")
+	if outputFormat != "json" && outputFormat != "html" {
+		common.RespondError(c, http.StatusBadRequest, "Invalid format parameter")
+		return
+	}
+
+	ctx := c.Request.Context()
+	image, err := h.readService.GetImageMetadata(ctx, identifier)
+	if err != nil {
+		common.RespondError(c, http.StatusNotFound, "Image not found")
+		return
+	}
+	if !h.readService.CheckImagePermission(image, c.GetUint(middleware.ContextUserIDKey)) {
+		common.RespondError(c, http.StatusForbidden, "This image is private")
+		return
+	}
+	// 标记本身不含图片内容，只检查是否到期，不计入查看次数
+	if err := h.readService.CheckImageAvailability(ctx, image, false); err != nil {
+		h.handleMetadataError(c, identifier, err)
+		return
+	}
+
+	alt, ok := c.GetQuery("alt")
+	if !ok {
+		alt = image.EmbedAlt()
+	}
+	result, err := h.variantService.BuildSrcset(ctx, image, h.baseURL, c.Query("sizes"), alt)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to build srcset for %s: %v", image.Identifier, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to build srcset")
+		return
+	}
+
+	c.Header("Cache-Control", cacheControlForImage(image))
+	if outputFormat == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(result.Picture))
+		return
+	}
+	common.RespondSuccess(c, result)
+}
@@ -0,0 +1,95 @@
+package images
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/internal/dashboard"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contextServedVariantKey = "stats_served_variant"
+	contextRedirectBytesKey = "stats_redirect_bytes"
+
+	maxReferrerLength = 255
+)
+
+// markServed 标记本次实际返回的内容；直链跳转时流量不经过本服务，按文件大小估算
+func markServed(c *gin.Context, variant string, redirectBytes int64) {
+	c.Set(contextServedVariantKey, variant)
+	c.Set(contextRedirectBytesKey, redirectBytes)
+}
+
+// recordImageHit 响应完成后记录一次访问，出错或未返回图片内容时不计入
+func (h *Handler) recordImageHit(c *gin.Context, img *models.Image) {
+	variant := c.GetString(contextServedVariantKey)
+	if variant == "" || c.Writer.Status() >= http.StatusBadRequest {
+		return
+	}
+
+	bytes := int64(max(c.Writer.Size(), 0))
+	if c.Writer.Status() == http.StatusFound {
+		bytes = c.GetInt64(contextRedirectBytesKey)
+	}
+	dashboard.RecordImageHit(dashboard.ImageHit{
+		ImageID:  img.ID,
+		UserID:   img.UserID,
+		Variant:  variant,
+		Referrer: h.referrerDomain(c),
+		Bytes:    bytes,
+	})
+}
+
+// referrerDomain 返回外站来源的域名，无来源或来自本站时返回空字符串
+func (h *Handler) referrerDomain(c *gin.Context) string {
+	referer := c.GetHeader("Referer")
+	if referer == "" {
+		return ""
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := normalizeDomain(u.Hostname())
+	if !validDomain(host) {
+		return models.ImageStatReferrerOther
+	}
+	if host == normalizeDomain(hostname(c.Request.Host)) {
+		return ""
+	}
+	if base, err := url.Parse(h.baseURL); err == nil && host == normalizeDomain(base.Hostname()) {
+		return ""
+	}
+	if len(host) > maxReferrerLength {
+		host = host[:maxReferrerLength]
+	}
+	return host
+}
+
+// validDomain 只接受由字母、数字、点、连字符、下划线和 IPv6 冒号组成的主机名，其余按其他来源统计
+func validDomain(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, r := range host {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeDomain(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// hostname 去掉 Host 头中的端口
+func hostname(host string) string {
+	return (&url.URL{Host: host}).Hostname()
+}
@@ -0,0 +1,38 @@
+package images
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrerDomain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{baseURL: "https://img.example.com"}
+
+	tests := []struct {
+		referer string
+		want    string
+	}{
+		{"", ""},
+		{"not a url", ""},
+		{"https://img.example.com/gallery", ""},
+		{"http://localhost:8080/admin", ""},
+		{"https://www.Forum.example.org/thread/1", "forum.example.org"},
+		{"https://blog.example.net:8443/post", "blog.example.net"},
+		{"https://bad%20host.example/post", models.ImageStatReferrerOther},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "http://localhost:8080/images/abc", nil)
+		if tt.referer != "" {
+			c.Request.Header.Set("Referer", tt.referer)
+		}
+		assert.Equal(t, tt.want, h.referrerDomain(c), tt.referer)
+	}
+}
@@ -19,16 +19,18 @@ import (
 
 // GetThumbnail 获取缩略图
 // @Summary      Get image thumbnail
-// @Description  Retrieve a thumbnail version of an image with specified width
+// @Description  Retrieve a thumbnail version of an image with specified width, or a cropped thumbnail when height is also given
 // @Tags         images
 // @Accept       json
 // @Produce      image/webp
 // @Param        identifier  path      string  true   "Image identifier"
 // @Param        width       query     int     false  "Thumbnail width (default: 300)"
+// @Param        height      query     int     false  "Thumbnail height for fixed-aspect sizes"
 // @Success      200         {file}    binary   "Thumbnail image data"
 // @Failure      400         {object}  common.Response  "Invalid identifier"
 // @Failure      403         {object}  common.Response  "Private image, access denied"
 // @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      410         {object}  common.Response  "Image expired or out of views"
 // @Failure      500         {object}  common.Response  "Internal server error"
 // @Security     ApiKeyAuth
 // @Router       /thumbnails/{identifier} [get]
@@ -45,6 +47,7 @@ func (h *Handler) GetThumbnail(c *gin.Context) {
 	}
 
 	width := h.parseThumbnailWidth(c)
+	height := h.parseThumbnailHeight(c)
 
 	ctx := c.Request.Context()
 
@@ -54,11 +57,19 @@ func (h *Handler) GetThumbnail(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetUint(middleware.ContextUserIDKey)
+	userID := viewerID(c)
 	if !h.readService.CheckImagePermission(image, userID) {
 		common.RespondError(c, http.StatusForbidden, "This image is private")
 		return
 	}
+	// 缩略图同样展示图片内容，非所有者访问计入查看次数
+	countView := isShareRequest(c) || userID != image.UserID
+	if err := h.readService.CheckImageAvailability(ctx, image, countView); err != nil {
+		h.handleMetadataError(c, identifier, err)
+		return
+	}
+	defer h.recordImageHit(c, image)
+
 	settings, err := h.configManager.GetImageProcessingSettings(ctx)
 	if err != nil {
 		// return 原图
@@ -71,11 +82,12 @@ func (h *Handler) GetThumbnail(c *gin.Context) {
 		return
 	}
 
-	if !settings.IsValidWidth(width) {
-		width = 600
+	size := models.ThumbnailSize{Width: 600}
+	if configured := settings.GetSizeByDimensions(width, height); configured != nil {
+		size = *configured
 	}
 
-	thumbnailResult, exists, err := h.thumbnailService.EnsureThumbnail(ctx, image, width)
+	thumbnailResult, exists, err := h.thumbnailService.EnsureThumbnail(ctx, image, size)
 	if err != nil {
 		h.serveOriginalImage(c, image)
 		return
@@ -98,8 +110,10 @@ func (h *Handler) GetThumbnail(c *gin.Context) {
 
 // serveThumbnailImage 提供缩略图（支持直链模式）
 func (h *Handler) serveThumbnailImage(c *gin.Context, image *models.Image, result *image.ThumbnailResult) {
+	markServed(c, result.Format, 0)
 	if directURL := h.getVariantDirectURLIfPossible(c, image, result.StoragePath); directURL != "" {
 		middleware.RecordImageDirectRedirect()
+		markServed(c, result.Format, result.FileSize)
 		c.Header("Cache-Control", config.CacheControlPublic)
 		c.Redirect(http.StatusFound, directURL)
 		return
@@ -134,7 +148,7 @@ func (h *Handler) serveThumbnailImage(c *gin.Context, image *models.Image, resul
 		}
 	}()
 	middleware.RecordImageReaderResponse()
-	h.serveReadSeekerContent(c, result.Identifier, result.MIMEType, result.FileHash, stream, true, cacheControlForImage(image.IsPublic))
+	h.serveReadSeekerContent(c, result.Identifier, result.MIMEType, result.FileHash, stream, true, cacheControlForImage(image))
 }
 
 func (h *Handler) serveThumbnailByStreaming(c *gin.Context, image *models.Image, result *image.ThumbnailResult, streamer storage.StreamProvider) bool {
@@ -142,7 +156,7 @@ func (h *Handler) serveThumbnailByStreaming(c *gin.Context, image *models.Image,
 		return true
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(image.IsPublic))
+	c.Header("Cache-Control", cacheControlForImage(image))
 	c.Header("Content-Type", result.MIMEType)
 	c.Header("X-Content-Type-Options", "nosniff")
 
@@ -170,7 +184,7 @@ func (h *Handler) serveThumbnailBySendfile(c *gin.Context, image *models.Image,
 		return false
 	}
 
-	c.Header("Cache-Control", cacheControlForImage(image.IsPublic))
+	c.Header("Cache-Control", cacheControlForImage(image))
 	c.Header("Content-Type", result.MIMEType)
 	c.Header("Content-Length", strconv.FormatInt(stat.Size(), 10))
 	c.Header("X-Content-Type-Options", "nosniff")
@@ -189,3 +203,12 @@ func (h *Handler) parseThumbnailWidth(c *gin.Context) int {
 	}
 	return width
 }
+
+// parseThumbnailHeight 解析缩略图高度参数，未指定或非法时返回 0（等比缩略图）
+func (h *Handler) parseThumbnailHeight(c *gin.Context) int {
+	height, err := strconv.Atoi(c.Query("height"))
+	if err != nil || height <= 0 {
+		return 0
+	}
+	return height
+}
@@ -90,6 +90,27 @@ func TestParseThumbnailWidth(t *testing.T) {
 	}
 }
 
+func TestParseThumbnailHeight(t *testing.T) {
+	h := &Handler{}
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 0},
+		{"height=400", 400},
+		{"height=abc", 0},
+		{"height=-1", 0},
+	}
+
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(nil)
+		c.Request = &http.Request{URL: &url.URL{RawQuery: tt.query}}
+		assert.Equal(t, tt.want, h.parseThumbnailHeight(c), tt.query)
+	}
+}
+
 func TestServeThumbnailByStreaming(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
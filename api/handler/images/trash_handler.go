@@ -0,0 +1,192 @@
+package images
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrashRequest 回收站列表请求
+type ListTrashRequest struct {
+	Page  int `form:"page" binding:"required,min=1"`
+	Limit int `form:"limit" binding:"required,min=1,max=100"`
+}
+
+// TrashedImageDTO 回收站中的图片，图片链接在恢复前不可访问
+type TrashedImageDTO struct {
+	ID           uint   `json:"id"`
+	Identifier   string `json:"identifier"`
+	OriginalName string `json:"original_name"`
+	Title        string `json:"title"`
+	FileSize     int64  `json:"file_size"`
+	MimeType     string `json:"mime_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    int64  `json:"created_at"`
+	DeletedAt    int64  `json:"deleted_at"`
+	PurgeAt      int64  `json:"purge_at,omitempty"` // 自动彻底删除的时间，未开启自动清理时为空
+}
+
+// TrashListResponse 回收站列表响应
+type TrashListResponse struct {
+	Images        []TrashedImageDTO `json:"images"`
+	Total         int64             `json:"total"`
+	Page          int               `json:"page"`
+	Limit         int               `json:"limit"`
+	TotalPages    int               `json:"total_pages"`
+	RetentionDays int               `json:"retention_days"`
+}
+
+// TrashRestoreRequest 从回收站恢复请求
+type TrashRestoreRequest struct {
+	Identifiers []string `json:"identifiers" binding:"required,min=1,max=100"`
+}
+
+// TrashEmptyRequest 彻底删除请求
+type TrashEmptyRequest struct {
+	Identifiers []string `json:"identifiers" binding:"max=100"` // 为空时清空整个回收站
+}
+
+func (h *Handler) toTrashedImageDTO(img *models.Image) TrashedImageDTO {
+	dto := TrashedImageDTO{
+		ID:           img.ID,
+		Identifier:   img.Identifier,
+		OriginalName: img.OriginalName,
+		Title:        img.Title,
+		FileSize:     img.FileSize,
+		MimeType:     img.MimeType,
+		Width:        img.Width,
+		Height:       img.Height,
+		CreatedAt:    img.CreatedAt.Unix(),
+		DeletedAt:    img.DeletedAt.Time.Unix(),
+	}
+	if h.trashRetentionDays > 0 {
+		dto.PurgeAt = img.DeletedAt.Time.Add(time.Duration(h.trashRetentionDays) * 24 * time.Hour).Unix()
+	}
+	return dto
+}
+
+// ListTrash 获取回收站图片列表
+// @Summary      List trash
+// @Description  Get paginated list of deleted images in the trash, newest deletions first. Trashed images are purged automatically after the configured retention days
+// @Tags         trash
+// @Produce      json
+// @Param        page   query     int  true  "Page number"
+// @Param        limit  query     int  true  "Items per page (max 100)"
+// @Success      200    {object}  common.Response{data=TrashListResponse}  "Trashed images"
+// @Failure      400    {object}  common.Response  "Invalid request parameters"
+// @Failure      401    {object}  common.Response  "Unauthorized"
+// @Failure      500    {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/trash [get]
+func (h *Handler) ListTrash(c *gin.Context) {
+	var req ListTrashRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request parameters")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	trashed, total, err := h.deleteService.ListTrash(c.Request.Context(), userID, req.Page, req.Limit)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to list trash for user=%d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get trash")
+		return
+	}
+
+	dtos := make([]TrashedImageDTO, len(trashed))
+	for i, img := range trashed {
+		dtos[i] = h.toTrashedImageDTO(img)
+	}
+
+	common.RespondSuccess(c, TrashListResponse{
+		Images:        dtos,
+		Total:         total,
+		Page:          req.Page,
+		Limit:         req.Limit,
+		TotalPages:    int(math.Ceil(float64(total) / float64(req.Limit))),
+		RetentionDays: h.trashRetentionDays,
+	})
+}
+
+// RestoreTrash 从回收站恢复图片
+// @Summary      Restore images from trash
+// @Description  Restore deleted images with their original identifier, URL, variants and album memberships. Images whose original file no longer exists are reported as failed
+// @Tags         trash
+// @Accept       json
+// @Produce      json
+// @Param        request  body      TrashRestoreRequest  true  "Identifiers of images to restore"
+// @Success      200      {object}  common.Response  "Restore request processed"
+// @Failure      400      {object}  common.Response  "Invalid request body"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/trash/restore [post]
+func (h *Handler) RestoreTrash(c *gin.Context) {
+	var req TrashRestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body. 'identifiers' field with a list of strings is required.")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	result, err := h.deleteService.RestoreFromTrash(c.Request.Context(), req.Identifiers, userID)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to restore images for user=%d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to restore images")
+		return
+	}
+
+	restored := make([]string, len(result.Restored))
+	for i, img := range result.Restored {
+		restored[i] = img.Identifier
+	}
+
+	common.RespondSuccessMessage(c, "Restore request processed successfully.", gin.H{
+		"restored_count": len(restored),
+		"restored":       restored,
+		"failed":         result.Failed,
+		"file_missing":   result.FileMissing,
+	})
+}
+
+// EmptyTrash 彻底删除回收站中的图片
+// @Summary      Empty trash
+// @Description  Permanently delete the given images from the trash, or the whole trash when no identifiers are given. Deleted files cannot be restored
+// @Tags         trash
+// @Accept       json
+// @Produce      json
+// @Param        request  body      TrashEmptyRequest  false  "Identifiers of images to delete permanently"
+// @Success      200      {object}  common.Response  "Trash emptied"
+// @Failure      400      {object}  common.Response  "Invalid request body"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/trash/empty [post]
+func (h *Handler) EmptyTrash(c *gin.Context) {
+	var req TrashEmptyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	purged, err := h.deleteService.EmptyTrash(c.Request.Context(), req.Identifiers, userID)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to empty trash for user=%d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to empty trash")
+		return
+	}
+
+	common.RespondSuccessMessage(c, "Trash emptied successfully.", gin.H{"deleted_count": purged})
+}
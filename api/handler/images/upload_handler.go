@@ -22,13 +22,16 @@ import (
 
 // UploadImage 统一图片上传接口（支持单文件和多文件）
 // @Summary      Upload images
-// @Description  Upload one or multiple image files (max 10 per request)
+// @Description  Upload one or multiple image files (max 10 per request). Images can be made temporary with expires_in or expires_at and max_views; without them the API key's default image TTL applies
 // @Tags         images
 // @Accept       multipart/form-data
 // @Produce      json
 // @Param        files        formData  file    true   "Image file(s) to upload (max 10)"
 // @Param        strategy_id  formData  string  false  "Storage strategy ID"
 // @Param        is_public    formData  bool    false  "Whether images are public (default: true)"
+// @Param        expires_in   formData  string  false  "Lifetime as seconds, a duration (90m, 24h) or days (7d)"
+// @Param        expires_at   formData  string  false  "Expiry time as Unix seconds or RFC 3339, exclusive with expires_in"
+// @Param        max_views    formData  int     false  "Maximum number of views by anyone other than the owner (0: unlimited)"
 // @Success      200  {object}  common.Response  "Upload successful"
 // @Failure      400  {object}  common.Response  "Invalid form data or too many files"
 // @Failure      401  {object}  common.Response  "Unauthorized"
@@ -76,6 +79,15 @@ func (h *Handler) UploadImage(c *gin.Context) {
 		return
 	}
 
+	expiry, err := resolveUploadExpiry(c, request)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range request.files {
+		request.files[i].Expiry = expiry
+	}
+
 	userID := c.GetUint(middleware.ContextUserIDKey)
 
 	// 确定可见性
@@ -99,6 +111,8 @@ func (h *Handler) UploadImage(c *gin.Context) {
 			"filename":   result.FileName,
 			"file_size":  result.FileSize,
 			"links":      result.Links,
+			"expires_at": unixOrNil(result.Image.ExpiresAt),
+			"max_views":  result.Image.MaxViews,
 		})
 		return
 	}
@@ -124,6 +138,8 @@ func (h *Handler) UploadImage(c *gin.Context) {
 				"filename":   result.FileName,
 				"file_size":  result.FileSize,
 				"links":      result.Links,
+				"expires_at": unixOrNil(result.Image.ExpiresAt),
+				"max_views":  result.Image.MaxViews,
 			})
 		}
 	}
@@ -162,6 +178,9 @@ type parsedUploadRequest struct {
 	files      []imagesvc.UploadSource
 	strategyID string
 	visibility string
+	expiresIn  string
+	expiresAt  string
+	maxViews   string
 }
 
 type uploadRequestError struct {
@@ -242,6 +261,12 @@ func parseMultipartUploadRequest(r *http.Request, settings *dbconfig.ImageProces
 				request.strategyID = value
 			case "is_public":
 				request.visibility = strings.ToLower(value)
+			case "expires_in":
+				request.expiresIn = strings.TrimSpace(value)
+			case "expires_at":
+				request.expiresAt = strings.TrimSpace(value)
+			case "max_views":
+				request.maxViews = strings.TrimSpace(value)
 			}
 			continue
 		}
@@ -5,10 +5,15 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/anoixa/image-bed/api/middleware"
 	dbconfig "github.com/anoixa/image-bed/config/db"
+	imagesvc "github.com/anoixa/image-bed/internal/image"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -143,3 +148,50 @@ func TestParseMultipartUploadRequestCleanupSkipsReleasedTempFile(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, os.Remove(path))
 }
+
+func TestResolveUploadExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newContext := func() *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		return c
+	}
+
+	expiry, err := resolveUploadExpiry(newContext(), &parsedUploadRequest{})
+	require.NoError(t, err)
+	assert.True(t, expiry.IsZero(), "permanent without fields or token default")
+
+	c := newContext()
+	c.Set(middleware.ContextDefaultImageTTLKey, 24*time.Hour)
+	expiry, err = resolveUploadExpiry(c, &parsedUploadRequest{})
+	require.NoError(t, err)
+	require.NotNil(t, expiry.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *expiry.ExpiresAt, time.Minute)
+
+	// 请求中的设置优先于 Token 的默认有效期
+	expiry, err = resolveUploadExpiry(c, &parsedUploadRequest{maxViews: "3"})
+	require.NoError(t, err)
+	assert.Nil(t, expiry.ExpiresAt)
+	assert.Equal(t, 3, expiry.MaxViews)
+
+	for value, want := range map[string]time.Duration{"3600": time.Hour, "90m": 90 * time.Minute, "7d": 7 * 24 * time.Hour} {
+		expiry, err = resolveUploadExpiry(newContext(), &parsedUploadRequest{expiresIn: value})
+		require.NoError(t, err, value)
+		assert.WithinDuration(t, time.Now().Add(want), *expiry.ExpiresAt, time.Minute, value)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	expiry, err = resolveUploadExpiry(newContext(), &parsedUploadRequest{expiresAt: expiresAt.Format(time.RFC3339)})
+	require.NoError(t, err)
+	assert.True(t, expiresAt.Equal(*expiry.ExpiresAt))
+
+	for _, request := range []*parsedUploadRequest{
+		{expiresIn: "soon"},
+		{expiresIn: "-5"},
+		{expiresAt: "1"},
+		{expiresIn: "1h", expiresAt: expiresAt.Format(time.RFC3339)},
+		{maxViews: "-1"},
+	} {
+		_, err = resolveUploadExpiry(newContext(), request)
+		assert.ErrorIs(t, err, imagesvc.ErrInvalidExpiry, "%+v", request)
+	}
+}
@@ -0,0 +1,245 @@
+package images
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	imagesvc "github.com/anoixa/image-bed/internal/image"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ImageVersionDTO 图片内容版本
+type ImageVersionDTO struct {
+	Version      int    `json:"version"`
+	OriginalName string `json:"original_name"`
+	FileSize     int64  `json:"file_size"`
+	MimeType     string `json:"mime_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	ETag         string `json:"etag"`
+	CreatedAt    int64  `json:"created_at"` // 当前版本为最近一次修改时间，历史版本为被替换的时间
+}
+
+// ImageVersionsResponse 图片版本历史
+type ImageVersionsResponse struct {
+	Identifier string            `json:"identifier"`
+	Current    ImageVersionDTO   `json:"current"`
+	History    []ImageVersionDTO `json:"history"`
+}
+
+func currentVersionDTO(img *models.Image) ImageVersionDTO {
+	return ImageVersionDTO{
+		Version:      max(img.Version, 1),
+		OriginalName: img.OriginalName,
+		FileSize:     img.FileSize,
+		MimeType:     img.MimeType,
+		Width:        img.Width,
+		Height:       img.Height,
+		ETag:         normalizeETag(img.FileHash),
+		CreatedAt:    img.UpdatedAt.Unix(),
+	}
+}
+
+func (h *Handler) respondImageContent(c *gin.Context, message string, img *models.Image) {
+	common.RespondSuccessMessage(c, message, gin.H{
+		"identifier": img.Identifier,
+		"version":    img.Version,
+		"file_size":  img.FileSize,
+		"mime_type":  img.MimeType,
+		"width":      img.Width,
+		"height":     img.Height,
+		"etag":       normalizeETag(img.FileHash),
+		"links":      utils.BuildLinkFormats(h.baseURL, img.Identifier, img.EmbedAlt()),
+	})
+}
+
+func respondVersionError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, imagesvc.ErrImageNotFound):
+		common.RespondError(c, http.StatusNotFound, "Image not found")
+	case errors.Is(err, imagesvc.ErrImageVersionNotFound):
+		common.RespondError(c, http.StatusNotFound, "Image version not found")
+	case errors.Is(err, imagesvc.ErrContentUnchanged):
+		common.RespondError(c, http.StatusBadRequest, "The uploaded file is identical to the current image")
+	default:
+		imageHandlerLog.Errorf("Failed to %s: %v", action, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+func parseVersionParam(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		common.RespondError(c, http.StatusBadRequest, "Invalid version")
+		return 0, false
+	}
+	return version, true
+}
+
+// ReplaceImage 替换图片内容
+// @Summary      Replace image content
+// @Description  Upload new content for an existing image. The identifier and all links stay the same, the previous content is kept as a version that can be reverted to. Variants are regenerated and the ETag changes so clients fetch the new content
+// @Tags         images
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        identifier  path      string  true  "Image identifier"
+// @Param        files       formData  file    true  "New image file (exactly one)"
+// @Success      200         {object}  common.Response  "Image replaced successfully"
+// @Failure      400         {object}  common.Response  "Invalid form data or unchanged content"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      413         {object}  common.Response  "File too large"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/replace [post]
+func (h *Handler) ReplaceImage(c *gin.Context) {
+	if c.IsAborted() {
+		return
+	}
+
+	ctx := c.Request.Context()
+	settings, err := h.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		imageHandlerLog.Errorf("Failed to get processing settings: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get processing settings")
+		return
+	}
+
+	request, cleanup, err := parseMultipartUploadRequest(c.Request, settings)
+	if err != nil {
+		if uploadErr, ok := err.(*uploadRequestError); ok {
+			common.RespondError(c, uploadErr.status, uploadErr.message)
+			return
+		}
+		imageHandlerLog.Errorf("Failed to parse multipart request: %v", err)
+		common.RespondError(c, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+	defer cleanup()
+
+	if len(request.files) != 1 {
+		common.RespondError(c, http.StatusBadRequest, "Exactly one file is required under the 'files' key")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	img, err := h.versionService.Replace(ctx, c.Param("identifier"), userID, request.files[0])
+	if err != nil {
+		if errors.Is(err, imagesvc.ErrImageNotFound) || errors.Is(err, imagesvc.ErrContentUnchanged) {
+			respondVersionError(c, err, "replace image")
+			return
+		}
+		if !c.IsAborted() {
+			common.RespondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	h.respondImageContent(c, "Image replaced successfully", img)
+}
+
+// ListImageVersions 获取图片版本历史
+// @Summary      List image versions
+// @Description  Get the current content version of an image and the previous versions it replaced, newest first
+// @Tags         images
+// @Produce      json
+// @Param        identifier  path      string  true  "Image identifier"
+// @Success      200         {object}  common.Response{data=ImageVersionsResponse}  "Image versions"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      404         {object}  common.Response  "Image not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/versions [get]
+func (h *Handler) ListImageVersions(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	img, versions, err := h.versionService.ListVersions(c.Request.Context(), c.Param("identifier"), userID)
+	if err != nil {
+		respondVersionError(c, err, "get image versions")
+		return
+	}
+
+	history := make([]ImageVersionDTO, len(versions))
+	for i, v := range versions {
+		history[i] = ImageVersionDTO{
+			Version:      v.Version,
+			OriginalName: v.OriginalName,
+			FileSize:     v.FileSize,
+			MimeType:     v.MimeType,
+			Width:        v.Width,
+			Height:       v.Height,
+			ETag:         normalizeETag(v.FileHash),
+			CreatedAt:    v.CreatedAt.Unix(),
+		}
+	}
+
+	common.RespondSuccess(c, ImageVersionsResponse{
+		Identifier: img.Identifier,
+		Current:    currentVersionDTO(img),
+		History:    history,
+	})
+}
+
+// RevertImageVersion 回滚图片内容到历史版本
+// @Summary      Revert image to a version
+// @Description  Restore the content of a previous version as a new version. The current content is kept in the history, links stay the same
+// @Tags         images
+// @Produce      json
+// @Param        identifier  path      string  true  "Image identifier"
+// @Param        version     path      int     true  "Version number to revert to"
+// @Success      200         {object}  common.Response  "Image reverted successfully"
+// @Failure      400         {object}  common.Response  "Invalid version"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      404         {object}  common.Response  "Image or version not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/versions/{version}/revert [post]
+func (h *Handler) RevertImageVersion(c *gin.Context) {
+	version, ok := parseVersionParam(c)
+	if !ok {
+		return
+	}
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	img, err := h.versionService.Revert(c.Request.Context(), c.Param("identifier"), userID, version)
+	if err != nil {
+		respondVersionError(c, err, "revert image")
+		return
+	}
+
+	h.respondImageContent(c, "Image reverted successfully", img)
+}
+
+// DeleteImageVersion 删除图片历史版本
+// @Summary      Delete image version
+// @Description  Permanently delete a previous version of an image. The current content cannot be deleted this way
+// @Tags         images
+// @Produce      json
+// @Param        identifier  path      string  true  "Image identifier"
+// @Param        version     path      int     true  "Version number"
+// @Success      200         {object}  common.Response  "Image version deleted successfully"
+// @Failure      400         {object}  common.Response  "Invalid version"
+// @Failure      401         {object}  common.Response  "Unauthorized"
+// @Failure      404         {object}  common.Response  "Image or version not found"
+// @Failure      500         {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/images/{identifier}/versions/{version} [delete]
+func (h *Handler) DeleteImageVersion(c *gin.Context) {
+	version, ok := parseVersionParam(c)
+	if !ok {
+		return
+	}
+	userID := c.GetUint(middleware.ContextUserIDKey)
+
+	if err := h.versionService.DeleteVersion(c.Request.Context(), c.Param("identifier"), userID, version); err != nil {
+		respondVersionError(c, err, "delete image version")
+		return
+	}
+
+	common.RespondSuccessMessage(c, "Image version deleted successfully", nil)
+}
@@ -14,16 +14,17 @@ import (
 )
 
 type req struct {
-	Description string `json:"description"`
+	Description     string `json:"description"`
+	DefaultImageTTL int64  `json:"default_image_ttl"` // 上传图片的默认有效期（秒），0 为永久保存
 }
 
 // CreateStaticToken 创建新的static token
 // @Summary      Create API key
-// @Description  Create a new API key for programmatic access
+// @Description  Create a new API key for programmatic access. default_image_ttl (seconds) makes images uploaded with this key expire unless the upload sets its own expiry
 // @Tags         keys
 // @Accept       json
 // @Produce      json
-// @Param        request  body      req  true  "API key creation request (description is optional)"
+// @Param        request  body      req  true  "API key creation request (description and default_image_ttl are optional)"
 // @Success      200      {object}  common.Response  "API key created successfully"
 // @Failure      400      {object}  common.Response  "Invalid request body"
 // @Failure      401      {object}  common.Response  "Unauthorized"
@@ -43,6 +44,11 @@ func (h *Handler) CreateStaticToken(context *gin.Context) {
 		}
 	}
 
+	if requestBody.DefaultImageTTL < 0 {
+		common.RespondError(context, http.StatusBadRequest, "default_image_ttl must not be negative")
+		return
+	}
+
 	userID := context.GetUint(middleware.ContextUserIDKey)
 
 	randomToken, err := utils.GenerateRandomToken(64)
@@ -56,11 +62,12 @@ func (h *Handler) CreateStaticToken(context *gin.Context) {
 	hashedToken := hex.EncodeToString(hasher.Sum(nil))
 
 	token := models.ApiToken{
-		UserID:      userID,
-		Token:       hashedToken,
-		TokenPrefix: tokenPrefix,
-		Description: requestBody.Description,
-		IsActive:    true,
+		UserID:          userID,
+		Token:           hashedToken,
+		TokenPrefix:     tokenPrefix,
+		Description:     requestBody.Description,
+		IsActive:        true,
+		DefaultImageTTL: requestBody.DefaultImageTTL,
 	}
 
 	err = h.svc.CreateKey(&token)
@@ -11,12 +11,13 @@ import (
 )
 
 type apiTokenResponse struct {
-	ID          uint       `json:"id"`
-	IsActive    bool       `json:"is_active"`
-	Prefix      string     `json:"prefix"`
-	CreatedAt   time.Time  `json:"created_at"`
-	Description string     `json:"description"`
-	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	ID              uint       `json:"id"`
+	IsActive        bool       `json:"is_active"`
+	Prefix          string     `json:"prefix"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Description     string     `json:"description"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	DefaultImageTTL int64      `json:"default_image_ttl"` // 上传图片的默认有效期（秒），0 为永久保存
 }
 
 // GetToken 获取 API Key 列表
@@ -48,12 +49,13 @@ func (h *Handler) GetToken(context *gin.Context) {
 	responseDTOs := make([]apiTokenResponse, 0, len(apiTokens))
 	for _, tokenModel := range apiTokens {
 		responseDTOs = append(responseDTOs, apiTokenResponse{
-			ID:          tokenModel.ID,
-			Prefix:      tokenModel.TokenPrefix,
-			IsActive:    tokenModel.IsActive,
-			Description: tokenModel.Description,
-			CreatedAt:   tokenModel.CreatedAt,
-			LastUsedAt:  tokenModel.LastUsedAt,
+			ID:              tokenModel.ID,
+			Prefix:          tokenModel.TokenPrefix,
+			IsActive:        tokenModel.IsActive,
+			Description:     tokenModel.Description,
+			CreatedAt:       tokenModel.CreatedAt,
+			LastUsedAt:      tokenModel.LastUsedAt,
+			DefaultImageTTL: tokenModel.DefaultImageTTL,
 		})
 	}
 
@@ -0,0 +1,64 @@
+package key
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// updateDefaultTTLRequest 更新上传图片默认有效期请求
+type updateDefaultTTLRequest struct {
+	DefaultImageTTL *int64 `json:"default_image_ttl" binding:"required"` // 秒，0 为永久保存
+}
+
+// UpdateDefaultImageTTL 更新 API Key 上传图片的默认有效期
+// @Summary      Update API key default image TTL
+// @Description  Set how long images uploaded with this API key live by default (seconds, 0 keeps them forever). Uploads that set expires_in, expires_at or max_views override it
+// @Tags         keys
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                      true  "API Key ID"
+// @Param        request  body      updateDefaultTTLRequest  true  "Default image TTL"
+// @Success      200      {object}  common.Response  "Default image TTL updated successfully"
+// @Failure      400      {object}  common.Response  "Invalid request body or API key ID"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "API key not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/token/{id}/default-ttl [put]
+func (h *Handler) UpdateDefaultImageTTL(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "Invalid user session.")
+		return
+	}
+
+	tokenID, ok := parseTokenID(c)
+	if !ok {
+		return
+	}
+
+	var req updateDefaultTTLRequest
+	if err := c.ShouldBindJSON(&req); err != nil || *req.DefaultImageTTL < 0 {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body. 'default_image_ttl' must be a non-negative number of seconds.")
+		return
+	}
+
+	if err := h.svc.UpdateDefaultImageTTL(tokenID, userID, *req.DefaultImageTTL); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.RespondError(c, http.StatusNotFound, "API token not found or you do not have permission to update it.")
+			return
+		}
+		keyLog.Errorf("Failed to update default image TTL of API token %d for user %d: %v", tokenID, userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to update the API token due to an internal error.")
+		return
+	}
+
+	common.RespondSuccessMessage(c, "Default image TTL has been successfully updated.", gin.H{
+		"id":                tokenID,
+		"default_image_ttl": *req.DefaultImageTTL,
+	})
+}
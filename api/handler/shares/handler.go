@@ -0,0 +1,14 @@
+package shares
+
+import (
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
+)
+
+type Handler struct {
+	svc     *svcAlbums.ShareService
+	baseURL string
+}
+
+func NewHandler(svc *svcAlbums.ShareService, baseURL string) *Handler {
+	return &Handler{svc: svc, baseURL: baseURL}
+}
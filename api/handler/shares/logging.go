@@ -0,0 +1,5 @@
+package shares
+
+import "github.com/anoixa/image-bed/utils"
+
+var shareLog = utils.ForModule("Shares")
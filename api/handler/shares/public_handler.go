@@ -0,0 +1,221 @@
+package shares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/config"
+	"github.com/anoixa/image-bed/database/models"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 访问密钥通过 Cookie（解锁时设置，限定在分享链接路径下）或请求头传递。
+// 密钥由密码哈希派生且不会轮换，不接受查询参数，避免经访问日志、代理和 Referer 泄露
+const (
+	accessKeyCookie = "share_key"
+	accessKeyHeader = "X-Share-Key"
+)
+
+// UnlockShareRequest 解锁密码保护的分享链接
+type UnlockShareRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UnlockShareResponse 解锁结果
+type UnlockShareResponse struct {
+	AccessKey string `json:"access_key"`
+}
+
+// SharedImageDTO 分享相册中的图片
+type SharedImageDTO struct {
+	Identifier   string `json:"identifier"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	OriginalName string `json:"original_name"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	AltText      string `json:"alt_text"`
+	FileSize     int64  `json:"file_size"`
+	MimeType     string `json:"mime_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// SharedAlbumResponse 分享的相册
+type SharedAlbumResponse struct {
+	Name           string           `json:"name"`
+	Description    string           `json:"description"`
+	Images         []SharedImageDTO `json:"images"`
+	ImageCount     int              `json:"image_count"`
+	ExpiresAt      *int64           `json:"expires_at"`
+	ViewsRemaining *int             `json:"views_remaining"` // 为空表示不限
+}
+
+// GetSharedAlbum 通过分享链接查看相册
+// @Summary      View shared album
+// @Description  Return the album behind a share link with read-only image URLs scoped to the link. Each call counts as one view. Password-protected links need the access key from /s/{token}/unlock
+// @Tags         shares
+// @Produce      json
+// @Param        token        path      string  true   "Share token"
+// @Param        X-Share-Key  header    string  false  "Access key for password-protected links (browsers use the cookie set by /s/{token}/unlock)"
+// @Success      200          {object}  common.Response{data=SharedAlbumResponse}  "Shared album"
+// @Failure      401          {object}  common.Response  "Password required"
+// @Failure      404          {object}  common.Response  "Share link not found"
+// @Failure      410          {object}  common.Response  "Share link expired, revoked or out of views"
+// @Failure      500          {object}  common.Response  "Internal server error"
+// @Router       /s/{token} [get]
+func (h *Handler) GetSharedAlbum(c *gin.Context) {
+	token := c.Param("token")
+	c.Header("Referrer-Policy", "no-referrer")
+
+	share, album, err := h.svc.OpenAlbum(c.Request.Context(), token, accessKeyFromRequest(c))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	images := make([]SharedImageDTO, 0, len(album.Images))
+	for _, img := range album.Images {
+		images = append(images, h.toSharedImageDTO(share, img))
+	}
+
+	var viewsRemaining *int
+	if share.MaxViews > 0 {
+		remaining := max(share.MaxViews-share.ViewCount, 0)
+		viewsRemaining = &remaining
+	}
+
+	c.Header("Cache-Control", config.CacheControlNoStore)
+	common.RespondSuccess(c, SharedAlbumResponse{
+		Name:           album.Name,
+		Description:    album.Description,
+		Images:         images,
+		ImageCount:     len(images),
+		ExpiresAt:      unixOrNil(share.ExpiresAt),
+		ViewsRemaining: viewsRemaining,
+	})
+}
+
+// UnlockShare 使用密码解锁分享链接
+// @Summary      Unlock shared album
+// @Description  Verify the password of a protected share link. Returns an access key and sets it as a cookie scoped to /s/{token}
+// @Tags         shares
+// @Accept       json
+// @Produce      json
+// @Param        token    path      string              true  "Share token"
+// @Param        request  body      UnlockShareRequest  true  "Password"
+// @Success      200      {object}  common.Response{data=UnlockShareResponse}  "Unlocked"
+// @Failure      400      {object}  common.Response  "Invalid request body"
+// @Failure      401      {object}  common.Response  "Invalid password"
+// @Failure      404      {object}  common.Response  "Share link not found"
+// @Failure      410      {object}  common.Response  "Share link expired or revoked"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Router       /s/{token}/unlock [post]
+func (h *Handler) UnlockShare(c *gin.Context) {
+	token := c.Param("token")
+
+	var req UnlockShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	share, accessKey, err := h.svc.Unlock(c.Request.Context(), token, req.Password)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	if accessKey != "" {
+		maxAge := 0
+		if share.ExpiresAt != nil {
+			maxAge = int(time.Until(*share.ExpiresAt).Seconds())
+		}
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     accessKeyCookie,
+			Value:    accessKey,
+			MaxAge:   maxAge,
+			Path:     "/s/" + share.Token,
+			Secure:   config.IsProduction(),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	c.Header("Cache-Control", config.CacheControlNoStore)
+	common.RespondSuccess(c, UnlockShareResponse{AccessKey: accessKey})
+}
+
+// AuthorizeImage 校验分享链接及图片是否属于分享的相册，通过后交由图片处理器按相册所有者的权限读取
+func (h *Handler) AuthorizeImage(c *gin.Context) {
+	c.Header("Referrer-Policy", "no-referrer")
+	share, _, err := h.svc.GetSharedImage(c.Request.Context(), c.Param("token"), accessKeyFromRequest(c), c.Param("identifier"))
+	if err != nil {
+		respondShareError(c, err)
+		c.Abort()
+		return
+	}
+	c.Set(middleware.ContextShareOwnerIDKey, share.UserID)
+	c.Next()
+}
+
+func (h *Handler) toSharedImageDTO(share *models.AlbumShare, img *models.Image) SharedImageDTO {
+	base := shareURL(h.baseURL, share.Token)
+	return SharedImageDTO{
+		Identifier:   img.Identifier,
+		URL:          fmt.Sprintf("%s/images/%s", base, img.Identifier),
+		ThumbnailURL: fmt.Sprintf("%s/thumbnails/%s?width=600", base, img.Identifier),
+		OriginalName: img.OriginalName,
+		Title:        img.Title,
+		Description:  img.Description,
+		AltText:      img.AltText,
+		FileSize:     img.FileSize,
+		MimeType:     img.MimeType,
+		Width:        img.Width,
+		Height:       img.Height,
+		CreatedAt:    img.CreatedAt.Unix(),
+	}
+}
+
+// accessKeyFromRequest 读取访问密钥，请求头优先于 Cookie
+func accessKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(accessKeyHeader); key != "" {
+		return key
+	}
+	key, _ := c.Cookie(accessKeyCookie)
+	return key
+}
+
+func respondShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, svcAlbums.ErrShareNotFound):
+		common.RespondError(c, http.StatusNotFound, "Share link not found")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.RespondError(c, http.StatusNotFound, "Image not found")
+	case errors.Is(err, svcAlbums.ErrShareRevoked):
+		common.RespondError(c, http.StatusGone, "Share link has been revoked")
+	case errors.Is(err, svcAlbums.ErrShareExpired):
+		common.RespondError(c, http.StatusGone, "Share link has expired")
+	case errors.Is(err, svcAlbums.ErrShareViewLimitReached):
+		common.RespondError(c, http.StatusGone, "Share link has reached its view limit")
+	case errors.Is(err, svcAlbums.ErrSharePasswordRequired):
+		common.RespondError(c, http.StatusUnauthorized, "Password required")
+	case errors.Is(err, svcAlbums.ErrInvalidSharePassword):
+		common.RespondError(c, http.StatusUnauthorized, "Invalid password")
+	default:
+		shareLog.Errorf("Share link request failed: %v", err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to open share link")
+	}
+}
+
+// shareURL 返回分享链接地址
+func shareURL(baseURL, token string) string {
+	return fmt.Sprintf("%s/s/%s", baseURL, token)
+}
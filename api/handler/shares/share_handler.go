@@ -0,0 +1,192 @@
+package shares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	svcAlbums "github.com/anoixa/image-bed/internal/albums"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateShareRequest 创建分享链接请求
+type CreateShareRequest struct {
+	Password  string `json:"password" binding:"max=128"` // 为空表示无需密码
+	ExpiresAt int64  `json:"expires_at" binding:"min=0"` // Unix 时间戳（秒），0 表示永不过期
+	MaxViews  int    `json:"max_views" binding:"min=0"`  // 相册页访问次数上限，0 表示不限
+}
+
+// ShareDTO 分享链接信息
+type ShareDTO struct {
+	ID          uint   `json:"id"`
+	AlbumID     uint   `json:"album_id"`
+	Token       string `json:"token"`
+	URL         string `json:"url"`
+	HasPassword bool   `json:"has_password"`
+	ExpiresAt   *int64 `json:"expires_at"`
+	MaxViews    int    `json:"max_views"`
+	ViewCount   int    `json:"view_count"`
+	Revoked     bool   `json:"revoked"`
+	RevokedAt   *int64 `json:"revoked_at"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+func (h *Handler) toShareDTO(share *models.AlbumShare) ShareDTO {
+	return ShareDTO{
+		ID:          share.ID,
+		AlbumID:     share.AlbumID,
+		Token:       share.Token,
+		URL:         shareURL(h.baseURL, share.Token),
+		HasPassword: share.HasPassword(),
+		ExpiresAt:   unixOrNil(share.ExpiresAt),
+		MaxViews:    share.MaxViews,
+		ViewCount:   share.ViewCount,
+		Revoked:     share.IsRevoked(),
+		RevokedAt:   unixOrNil(share.RevokedAt),
+		CreatedAt:   share.CreatedAt.Unix(),
+	}
+}
+
+// CreateShare 创建相册分享链接
+// @Summary      Create album share link
+// @Description  Create a read-only public link (/s/{token}) to an album, optionally protected by a password and limited by expiry time and number of views
+// @Tags         albums
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                 true  "Album ID"
+// @Param        request  body      CreateShareRequest  true  "Share options"
+// @Success      200      {object}  common.Response{data=ShareDTO}  "Share link created"
+// @Failure      400      {object}  common.Response  "Invalid request"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Album not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/shares [post]
+func (h *Handler) CreateShare(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	opts := svcAlbums.ShareOptions{Password: req.Password, MaxViews: req.MaxViews}
+	if req.ExpiresAt > 0 {
+		expiresAt := time.Unix(req.ExpiresAt, 0)
+		opts.ExpiresAt = &expiresAt
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	share, err := h.svc.CreateShare(c.Request.Context(), uint(albumID), userID, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, svcAlbums.ErrInvalidShareOptions):
+			common.RespondError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, svcAlbums.ErrAlbumNotFound):
+			common.RespondError(c, http.StatusNotFound, "Album not found or access denied")
+		default:
+			shareLog.Errorf("Failed to create share for album %d, user %d: %v", albumID, userID, err)
+			common.RespondError(c, http.StatusInternalServerError, "Failed to create share link")
+		}
+		return
+	}
+
+	common.RespondSuccess(c, h.toShareDTO(share))
+}
+
+// ListShares 获取相册分享链接
+// @Summary      List album share links
+// @Description  List all share links of an album, including revoked and expired ones
+// @Tags         albums
+// @Produce      json
+// @Param        id   path      int  true  "Album ID"
+// @Success      200  {object}  common.Response{data=[]ShareDTO}  "Share links"
+// @Failure      400  {object}  common.Response  "Invalid album ID format"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      404  {object}  common.Response  "Album not found"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/shares [get]
+func (h *Handler) ListShares(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	shares, err := h.svc.ListShares(c.Request.Context(), uint(albumID), userID)
+	if err != nil {
+		if errors.Is(err, svcAlbums.ErrAlbumNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Album not found or access denied")
+			return
+		}
+		shareLog.Errorf("Failed to list shares for album %d, user %d: %v", albumID, userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to list share links")
+		return
+	}
+
+	dtos := make([]ShareDTO, len(shares))
+	for i, share := range shares {
+		dtos[i] = h.toShareDTO(share)
+	}
+	common.RespondSuccess(c, dtos)
+}
+
+// RevokeShare 撤销相册分享链接
+// @Summary      Revoke album share link
+// @Description  Revoke a share link. The link and the image URLs it exposed stop working immediately
+// @Tags         albums
+// @Produce      json
+// @Param        id       path      int  true  "Album ID"
+// @Param        shareId  path      int  true  "Share link ID"
+// @Success      200      {object}  common.Response{data=ShareDTO}  "Share link revoked"
+// @Failure      400      {object}  common.Response  "Invalid ID format"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Share link not found"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/albums/{id}/shares/{shareId} [delete]
+func (h *Handler) RevokeShare(c *gin.Context) {
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid album ID format")
+		return
+	}
+	shareID, err := strconv.ParseUint(c.Param("shareId"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid share ID format")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	share, err := h.svc.RevokeShare(c.Request.Context(), uint(shareID), uint(albumID), userID)
+	if err != nil {
+		if errors.Is(err, svcAlbums.ErrShareNotFound) {
+			common.RespondError(c, http.StatusNotFound, "Share link not found")
+			return
+		}
+		shareLog.Errorf("Failed to revoke share %d for user %d: %v", shareID, userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to revoke share link")
+		return
+	}
+
+	common.RespondSuccessMessage(c, "Share link revoked", h.toShareDTO(share))
+}
+
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
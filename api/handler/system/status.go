@@ -9,6 +9,8 @@ import (
 	"github.com/anoixa/image-bed/api/middleware"
 	"github.com/anoixa/image-bed/cache"
 	"github.com/anoixa/image-bed/config"
+	"github.com/anoixa/image-bed/internal/image"
+	"github.com/anoixa/image-bed/internal/vipsfile"
 	"github.com/anoixa/image-bed/internal/worker"
 	"github.com/anoixa/image-bed/utils"
 	"github.com/gin-gonic/gin"
@@ -24,6 +26,7 @@ type StatusResponse struct {
 	Worker      WorkerStatus        `json:"worker"`
 	Sweeper     worker.SweeperStats `json:"sweeper"`
 	Cache       CacheStatus         `json:"cache"`
+	Formats     FormatStatus        `json:"formats"`
 	DataDir     DirStatus           `json:"data_dir"`
 }
 
@@ -74,6 +77,19 @@ type WorkerStatus struct {
 	WorkerCount      int    `json:"worker_count"`
 	InFlightTasks    int    `json:"in_flight_tasks"`
 	InFlightVariants int    `json:"in_flight_variants"`
+
+	Lanes []worker.LaneStats `json:"lanes"` // 各优先级通道的排队深度
+}
+
+// FormatStatus 当前 libvips 运行时对可选格式的支持情况
+type FormatStatus struct {
+	HEICDecode bool `json:"heic_decode"`
+	AVIFDecode bool `json:"avif_decode"`
+	TIFFDecode bool `json:"tiff_decode"`
+	JXLDecode  bool `json:"jxl_decode"`
+	SVGDecode  bool `json:"svg_decode"`
+	AVIFEncode bool `json:"avif_encode"`
+	JXLEncode  bool `json:"jxl_encode"`
 }
 
 type CacheStatus struct {
@@ -121,11 +137,7 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	if pool := worker.GetGlobalPool(); pool != nil {
 		workerStats = pool.GetStats()
 	}
-	inFlightTasks := worker.CurrentInFlightTasks()
-	inFlightVariants := 0
-	for _, task := range inFlightTasks {
-		inFlightVariants += len(task.VariantIDs)
-	}
+	inFlightTasks, inFlightVariants := countInFlightWork(c)
 
 	// 构建响应
 	response := StatusResponse{
@@ -176,20 +188,35 @@ func (h *Handler) GetStatus(c *gin.Context) {
 			QueueSize:        workerStats.QueueSize,
 			QueueCap:         workerStats.QueueCap,
 			WorkerCount:      workerStats.WorkerCount,
-			InFlightTasks:    len(inFlightTasks),
+			InFlightTasks:    inFlightTasks,
 			InFlightVariants: inFlightVariants,
+			Lanes:            workerStats.Lanes,
 		},
 		Sweeper: worker.GetSweeperStats(),
 		Cache: CacheStatus{
 			Provider: cacheName,
 			Type:     cacheType,
 		},
+		Formats: getFormatStatus(),
 		DataDir: dataDirInfo,
 	}
 
 	common.RespondSuccess(c, response)
 }
 
+func getFormatStatus() FormatStatus {
+	heifDecode := vipsfile.SupportsHEIFDecoding()
+	return FormatStatus{
+		HEICDecode: heifDecode,
+		AVIFDecode: heifDecode,
+		TIFFDecode: vipsfile.SupportsTIFFDecoding(),
+		JXLDecode:  vipsfile.SupportsJXLDecoding(),
+		SVGDecode:  vipsfile.SupportsSVGDecoding(),
+		AVIFEncode: vipsfile.SupportsAVIFEncoding(),
+		JXLEncode:  vipsfile.SupportsJXLEncoding(),
+	}
+}
+
 func getGoVersion() string {
 	return runtime.Version()
 }
@@ -292,11 +319,7 @@ func (h *Handler) GetMetrics(c *gin.Context) {
 	if pool := worker.GetGlobalPool(); pool != nil {
 		workerStats = pool.GetStats()
 	}
-	inFlightTasks := worker.CurrentInFlightTasks()
-	inFlightVariants := 0
-	for _, task := range inFlightTasks {
-		inFlightVariants += len(task.VariantIDs)
-	}
+	inFlightTasks, inFlightVariants := countInFlightWork(c)
 	metrics["worker"] = WorkerStatus{
 		Submitted:        workerStats.Submitted,
 		Executed:         workerStats.Executed,
@@ -304,9 +327,25 @@ func (h *Handler) GetMetrics(c *gin.Context) {
 		QueueSize:        workerStats.QueueSize,
 		QueueCap:         workerStats.QueueCap,
 		WorkerCount:      workerStats.WorkerCount,
-		InFlightTasks:    len(inFlightTasks),
+		InFlightTasks:    inFlightTasks,
 		InFlightVariants: inFlightVariants,
+		Lanes:            workerStats.Lanes,
 	}
 	metrics["sweeper"] = worker.GetSweeperStats()
+	if queue := worker.GetGlobalQueue(); queue != nil {
+		metrics["job_queue"] = queue.Stats()
+	}
 	common.RespondSuccess(c, metrics)
 }
+
+// countInFlightWork 统计本进程持有租约的变体流水线任务及其变体数
+func countInFlightWork(c *gin.Context) (tasks, variants int) {
+	snapshots, err := image.InFlightPipelineTasks(c.Request.Context())
+	if err != nil {
+		return 0, 0
+	}
+	for _, task := range snapshots {
+		variants += len(task.VariantIDs)
+	}
+	return len(snapshots), variants
+}
@@ -0,0 +1,69 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	svcTags "github.com/anoixa/image-bed/internal/tags"
+	"github.com/gin-gonic/gin"
+)
+
+// BulkTagRequest 批量打标签/取消标签请求
+type BulkTagRequest struct {
+	Identifiers []string `json:"identifiers" binding:"required,min=1,max=100"`
+	Tags        []string `json:"tags" binding:"required,min=1,max=20"`
+}
+
+// TagImages 批量为图片添加标签
+// @Summary      Tag images
+// @Description  Add tags to multiple images; missing tags are created
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkTagRequest  true  "Image identifiers and tag names"
+// @Success      200      {object}  common.Response{data=svcTags.BulkResult}  "Number of new image-tag links"
+// @Failure      400      {object}  common.Response  "Invalid request"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags/bulk/tag [post]
+func (h *Handler) TagImages(c *gin.Context) {
+	h.bulk(c, h.svc.TagImages, "Failed to tag images")
+}
+
+// UntagImages 批量移除图片标签
+// @Summary      Untag images
+// @Description  Remove tags from multiple images; the tags themselves are kept
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BulkTagRequest  true  "Image identifiers and tag names"
+// @Success      200      {object}  common.Response{data=svcTags.BulkResult}  "Number of removed image-tag links"
+// @Failure      400      {object}  common.Response  "Invalid request"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags/bulk/untag [post]
+func (h *Handler) UntagImages(c *gin.Context) {
+	h.bulk(c, h.svc.UntagImages, "Failed to untag images")
+}
+
+type bulkFunc func(ctx context.Context, userID uint, identifiers, names []string) (*svcTags.BulkResult, error)
+
+func (h *Handler) bulk(c *gin.Context, fn bulkFunc, message string) {
+	var req BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	result, err := fn(c.Request.Context(), userID, req.Identifiers, req.Tags)
+	if err != nil {
+		h.respondTagError(c, err, message)
+		return
+	}
+	common.RespondSuccess(c, result)
+}
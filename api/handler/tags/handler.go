@@ -0,0 +1,13 @@
+package tags
+
+import (
+	svcTags "github.com/anoixa/image-bed/internal/tags"
+)
+
+type Handler struct {
+	svc *svcTags.Service
+}
+
+func NewHandler(svc *svcTags.Service) *Handler {
+	return &Handler{svc: svc}
+}
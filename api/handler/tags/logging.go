@@ -0,0 +1,5 @@
+package tags
+
+import "github.com/anoixa/image-bed/utils"
+
+var tagLog = utils.ForModule("Tags")
@@ -0,0 +1,189 @@
+package tags
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anoixa/image-bed/api/common"
+	"github.com/anoixa/image-bed/api/middleware"
+	"github.com/anoixa/image-bed/database/models"
+	svcTags "github.com/anoixa/image-bed/internal/tags"
+	"github.com/gin-gonic/gin"
+)
+
+// TagRequest 创建或重命名标签请求
+type TagRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// TagResponse 标签信息
+type TagResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+func toTagResponse(tag *models.Tag) TagResponse {
+	return TagResponse{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		CreatedAt: tag.CreatedAt.Unix(),
+		UpdatedAt: tag.UpdatedAt.Unix(),
+	}
+}
+
+// ListTags 获取标签列表
+// @Summary      List tags
+// @Description  List all tags of the current user with image counts, ordered by name
+// @Tags         tags
+// @Produce      json
+// @Success      200  {object}  common.Response{data=[]svcTags.TagInfo}  "Tag list"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags [get]
+func (h *Handler) ListTags(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	infos, err := h.svc.ListTags(c.Request.Context(), userID)
+	if err != nil {
+		tagLog.Errorf("Failed to list tags for user=%d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to list tags")
+		return
+	}
+	common.RespondSuccess(c, infos)
+}
+
+// AutocompleteTags 标签输入补全
+// @Summary      Autocomplete tags
+// @Description  Return tags of the current user whose name starts with the given prefix, most used first
+// @Tags         tags
+// @Produce      json
+// @Param        q      query     string  false  "Name prefix"
+// @Param        limit  query     int     false  "Maximum results (default: 10, max: 50)"
+// @Success      200    {object}  common.Response{data=[]svcTags.TagInfo}  "Matching tags"
+// @Failure      401    {object}  common.Response  "Unauthorized"
+// @Failure      500    {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags/autocomplete [get]
+func (h *Handler) AutocompleteTags(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	infos, err := h.svc.Autocomplete(c.Request.Context(), userID, c.Query("q"), limit)
+	if err != nil {
+		tagLog.Errorf("Failed to autocomplete tags for user=%d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to search tags")
+		return
+	}
+	common.RespondSuccess(c, infos)
+}
+
+// CreateTag 创建标签
+// @Summary      Create tag
+// @Description  Create a tag. Names are trimmed and lower-cased, must be 1-50 characters and must not contain commas
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        request  body      TagRequest  true  "Tag name"
+// @Success      200      {object}  common.Response{data=TagResponse}  "Tag created"
+// @Failure      400      {object}  common.Response  "Invalid tag name"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      409      {object}  common.Response  "Tag already exists"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags [post]
+func (h *Handler) CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	tag, err := h.svc.CreateTag(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		h.respondTagError(c, err, "Failed to create tag")
+		return
+	}
+	common.RespondSuccess(c, toTagResponse(tag))
+}
+
+// RenameTag 重命名标签
+// @Summary      Rename tag
+// @Description  Rename a tag; images keep the tag under its new name
+// @Tags         tags
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int         true  "Tag ID"
+// @Param        request  body      TagRequest  true  "New tag name"
+// @Success      200      {object}  common.Response{data=TagResponse}  "Tag renamed"
+// @Failure      400      {object}  common.Response  "Invalid request"
+// @Failure      401      {object}  common.Response  "Unauthorized"
+// @Failure      404      {object}  common.Response  "Tag not found"
+// @Failure      409      {object}  common.Response  "Tag already exists"
+// @Failure      500      {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags/{id} [put]
+func (h *Handler) RenameTag(c *gin.Context) {
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid tag ID format")
+		return
+	}
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	tag, err := h.svc.RenameTag(c.Request.Context(), uint(tagID), userID, req.Name)
+	if err != nil {
+		h.respondTagError(c, err, "Failed to rename tag")
+		return
+	}
+	common.RespondSuccess(c, toTagResponse(tag))
+}
+
+// DeleteTag 删除标签
+// @Summary      Delete tag
+// @Description  Delete a tag and remove it from all images
+// @Tags         tags
+// @Produce      json
+// @Param        id   path      int  true  "Tag ID"
+// @Success      200  {object}  common.Response  "Tag deleted"
+// @Failure      400  {object}  common.Response  "Invalid tag ID"
+// @Failure      401  {object}  common.Response  "Unauthorized"
+// @Failure      404  {object}  common.Response  "Tag not found"
+// @Failure      500  {object}  common.Response  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/tags/{id} [delete]
+func (h *Handler) DeleteTag(c *gin.Context) {
+	tagID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		common.RespondError(c, http.StatusBadRequest, "Invalid tag ID format")
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if err := h.svc.DeleteTag(c.Request.Context(), uint(tagID), userID); err != nil {
+		h.respondTagError(c, err, "Failed to delete tag")
+		return
+	}
+	common.RespondSuccessMessage(c, "Tag deleted successfully", nil)
+}
+
+func (h *Handler) respondTagError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, svcTags.ErrInvalidTagName):
+		common.RespondError(c, http.StatusBadRequest, "Tag name must be 1-50 characters and must not contain commas")
+	case errors.Is(err, svcTags.ErrTagNotFound):
+		common.RespondError(c, http.StatusNotFound, "Tag not found or access denied")
+	case errors.Is(err, svcTags.ErrTagExists):
+		common.RespondError(c, http.StatusConflict, "Tag already exists")
+	default:
+		tagLog.Errorf("%s: %v", message, err)
+		common.RespondError(c, http.StatusInternalServerError, message)
+	}
+}
@@ -2,21 +2,30 @@ package user
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/anoixa/image-bed/api/common"
 	"github.com/anoixa/image-bed/api/middleware"
+	configSvc "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/internal/user"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/utils"
 	"github.com/gin-gonic/gin"
 )
 
+var userHandlerLog = utils.ForModule("UserHandler")
+
 type Handler struct {
-	service *user.Service
+	service       *user.Service
+	configManager *configSvc.Manager
 }
 
-func NewHandler(service *user.Service) *Handler {
+func NewHandler(service *user.Service, configManager *configSvc.Manager) *Handler {
 	return &Handler{
-		service: service,
+		service:       service,
+		configManager: configManager,
 	}
 }
 
@@ -83,3 +92,149 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 		Message: "Password changed successfully",
 	})
 }
+
+// IngestPolicyRequest 用户上传策略
+type IngestPolicyRequest struct {
+	MaxDimension  int    `json:"max_dimension"`
+	Optimize      bool   `json:"optimize"`
+	ConvertFormat string `json:"convert_format"`
+	Quality       int    `json:"quality"`
+}
+
+// IngestPolicyResponse 用户自定义策略（未设置时为 null）与当前生效的策略
+type IngestPolicyResponse struct {
+	Custom    *models.UserIngestPolicy `json:"custom"`
+	Effective models.IngestPolicy      `json:"effective"`
+}
+
+// GetIngestPolicy
+// @Summary      获取上传策略
+// @Description  返回用户自定义的上传策略和当前生效的策略，未自定义时使用全局配置
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  common.Response{data=IngestPolicyResponse}
+// @Failure      401  {object}  common.Response  "未认证"
+// @Failure      500  {object}  common.Response  "服务器内部错误"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/user/ingest-policy [get]
+func (h *Handler) GetIngestPolicy(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	custom, err := h.service.GetIngestPolicy(userID)
+	if err != nil {
+		userHandlerLog.Errorf("Failed to get ingest policy for user %d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to get ingest policy")
+		return
+	}
+	h.respondIngestPolicy(c, custom)
+}
+
+// UpdateIngestPolicy
+// @Summary      设置上传策略
+// @Description  保存用户自定义的上传策略，整体覆盖全局配置：缩小超过最大边长的原图、无损优化 PNG/JPEG、转存为指定格式
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        request  body      IngestPolicyRequest  true  "上传策略"
+// @Success      200      {object}  common.Response{data=IngestPolicyResponse}
+// @Failure      400      {object}  common.Response  "请求参数错误"
+// @Failure      401      {object}  common.Response  "未认证"
+// @Failure      500      {object}  common.Response  "服务器内部错误"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/user/ingest-policy [put]
+func (h *Handler) UpdateIngestPolicy(c *gin.Context) {
+	var req IngestPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	policy := models.IngestPolicy{
+		MaxDimension:  req.MaxDimension,
+		Optimize:      req.Optimize,
+		ConvertFormat: req.ConvertFormat,
+		Quality:       req.Quality,
+	}
+	if err := validateIngestEncoder(policy.ConvertFormat); err != nil {
+		common.RespondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	saved, err := h.service.UpdateIngestPolicy(userID, policy)
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidPolicy) {
+			common.RespondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		userHandlerLog.Errorf("Failed to save ingest policy for user %d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to save ingest policy")
+		return
+	}
+	h.respondIngestPolicy(c, saved)
+}
+
+// ResetIngestPolicy
+// @Summary      重置上传策略
+// @Description  删除用户自定义的上传策略，恢复使用全局配置
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  common.Response{data=IngestPolicyResponse}
+// @Failure      401  {object}  common.Response  "未认证"
+// @Failure      500  {object}  common.Response  "服务器内部错误"
+// @Security     ApiKeyAuth
+// @Router       /api/v1/user/ingest-policy [delete]
+func (h *Handler) ResetIngestPolicy(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextUserIDKey)
+	if userID == 0 {
+		common.RespondError(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.service.ResetIngestPolicy(userID); err != nil {
+		userHandlerLog.Errorf("Failed to reset ingest policy for user %d: %v", userID, err)
+		common.RespondError(c, http.StatusInternalServerError, "Failed to reset ingest policy")
+		return
+	}
+	h.respondIngestPolicy(c, nil)
+}
+
+func (h *Handler) respondIngestPolicy(c *gin.Context, custom *models.UserIngestPolicy) {
+	resp := IngestPolicyResponse{Custom: custom}
+	if custom != nil {
+		resp.Effective = custom.IngestPolicy
+	} else if h.configManager != nil {
+		settings, err := h.configManager.GetImageProcessingSettings(c.Request.Context())
+		if err != nil {
+			userHandlerLog.Errorf("Failed to load image processing settings: %v", err)
+			common.RespondError(c, http.StatusInternalServerError, "Failed to get ingest policy")
+			return
+		}
+		resp.Effective = settings.IngestPolicy()
+	}
+	common.RespondSuccess(c, resp)
+}
+
+// validateIngestEncoder 拒绝转存为当前 libvips 运行时无法编码的格式
+func validateIngestEncoder(format string) error {
+	supported := true
+	switch format {
+	case models.FormatAVIF:
+		supported = vipsfile.SupportsAVIFEncoding()
+	case models.FormatJXL:
+		supported = vipsfile.SupportsJXLEncoding()
+	}
+	if !supported {
+		return fmt.Errorf("%s conversion is not supported by the current server runtime", format)
+	}
+	return nil
+}
@@ -111,6 +111,9 @@ func handleStaticTokenAuth(c *gin.Context, token string, jwtService *auth.JWTSer
 	c.Set(ContextUsernameKey, user.Username)
 	c.Set(ContextRoleKey, role)
 	c.Set(AuthTypeKey, AuthTypeStaticToken)
+	if user.DefaultImageTTL > 0 {
+		c.Set(ContextDefaultImageTTLKey, user.DefaultImageTTL)
+	}
 
 	return nil
 }
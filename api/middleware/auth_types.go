@@ -42,4 +42,8 @@ const (
 	ContextRoleKey = "role"
 	// AuthTypeKey 认证类型上下文键
 	AuthTypeKey = "auth_type"
+	// ContextShareOwnerIDKey 经分享链接校验的图片请求中，相册所有者的用户 ID
+	ContextShareOwnerIDKey = "share_owner_id"
+	// ContextDefaultImageTTLKey 静态令牌设置的上传图片默认有效期（time.Duration）
+	ContextDefaultImageTTLKey = "default_image_ttl"
 )
@@ -68,8 +68,10 @@ type backupMetadata struct {
 
 // albumImageRecord album_images 关联记录
 type albumImageRecord struct {
-	AlbumID uint `json:"album_id"`
-	ImageID uint `json:"image_id"`
+	AlbumID   uint       `json:"album_id"`
+	ImageID   uint       `json:"image_id"`
+	Position  int        `json:"position"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
 }
 
 // initDB 初始化数据库连接
@@ -147,6 +147,9 @@ func cleanOrphanDBRecords(db *gorm.DB, stats *cleanStats, dryRun bool) error {
 		if err := db.Exec("DELETE FROM album_images WHERE image_id IN ?", orphanIDs).Error; err != nil {
 			cleanLog.Warnf("Failed to delete album_image associations: %v", err)
 		}
+		if err := db.Exec("DELETE FROM image_tags WHERE image_id IN ?", orphanIDs).Error; err != nil {
+			cleanLog.Warnf("Failed to delete image_tag associations: %v", err)
+		}
 
 		result := db.Delete(&models.Image{}, "id IN ?", orphanIDs)
 		if result.Error != nil {
@@ -175,7 +178,7 @@ func cleanOrphanStorageFiles(db *gorm.DB, stats *cleanStats, dryRun bool) error
 	}
 
 	var storagePaths []string
-	if err := db.Model(&models.Image{}).Where("deleted_at IS NULL").Pluck("storage_path", &storagePaths).Error; err != nil {
+	if err := db.Unscoped().Model(&models.Image{}).Pluck("storage_path", &storagePaths).Error; err != nil {
 		return fmt.Errorf("failed to fetch image storage paths: %w", err)
 	}
 
@@ -185,6 +188,13 @@ func cleanOrphanStorageFiles(db *gorm.DB, stats *cleanStats, dryRun bool) error
 	}
 	storagePaths = append(storagePaths, variantPaths...)
 
+	// 历史版本无法重新生成，查询失败时不能继续清理
+	var versionPaths []string
+	if err := db.Table("image_versions").Pluck("storage_path", &versionPaths).Error; err != nil {
+		return fmt.Errorf("failed to fetch image version paths: %w", err)
+	}
+	storagePaths = append(storagePaths, versionPaths...)
+
 	identifierMap := make(map[string]bool)
 	for _, p := range storagePaths {
 		identifierMap[filepath.ToSlash(p)] = true
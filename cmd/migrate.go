@@ -252,6 +252,9 @@ func openDatabase(dbType, dsn string) (*gorm.DB, error) {
 
 // autoMigrate 自动迁移数据库结构
 func autoMigrate(db *gorm.DB) error {
+	if err := models.SetupJoinTables(db); err != nil {
+		return err
+	}
 	return db.AutoMigrate(
 		&models.User{},
 		&models.Device{},
@@ -491,13 +494,8 @@ func migrateAlbums(ctx context.Context, sourceDB, targetDB *gorm.DB, stats *migr
 
 // migrateAlbumImages 迁移相册-图片关联关系
 func migrateAlbumImages(ctx context.Context, sourceDB, targetDB *gorm.DB, stats *migrateStats, onConflict string) error {
-	type AlbumImage struct {
-		AlbumID uint
-		ImageID uint
-	}
-
-	var relations []AlbumImage
-	if err := sourceDB.WithContext(ctx).Raw("SELECT album_id, image_id FROM album_images").Scan(&relations).Error; err != nil {
+	var relations []albumImageRecord
+	if err := sourceDB.WithContext(ctx).Table("album_images").Find(&relations).Error; err != nil {
 		// 表可能不存在
 		return nil
 	}
@@ -536,10 +534,8 @@ func migrateAlbumImages(ctx context.Context, sourceDB, targetDB *gorm.DB, stats
 			continue
 		}
 
-		if err := targetDB.WithContext(ctx).Exec(
-			"INSERT INTO album_images (album_id, image_id) VALUES (?, ?)",
-			rel.AlbumID, rel.ImageID,
-		).Error; err != nil {
+		// 保留相册内排序和加入时间
+		if err := targetDB.WithContext(ctx).Table("album_images").Create(&rel).Error; err != nil {
 			stats.errors = append(stats.errors, fmt.Sprintf(
 				"failed to migrate album_image relation (album=%d, image=%d): %v",
 				rel.AlbumID, rel.ImageID, err))
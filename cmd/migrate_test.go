@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrateAlbumImagesKeepsPositionAndCreatedAt(t *testing.T) {
+	sourceDB := setupMigrateTestDB(t)
+	targetDB := setupMigrateTestDB(t)
+
+	for _, db := range []*gorm.DB{sourceDB, targetDB} {
+		require.NoError(t, db.Create(&models.Image{ID: 1, Identifier: "migrate-image", FileHash: "migrate-hash", StoragePath: "original/migrate.png"}).Error)
+		require.NoError(t, db.Create(&models.Album{Name: "migrate-album", UserID: 1}).Error)
+	}
+	addedAt := time.Now().Add(-48 * time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, sourceDB.Create(&models.AlbumImage{AlbumID: 1, ImageID: 1, Position: 7, CreatedAt: addedAt}).Error)
+
+	stats := &migrateStats{}
+	require.NoError(t, migrateAlbumImages(context.Background(), sourceDB, targetDB, stats, "skip"))
+	assert.Empty(t, stats.errors)
+
+	var migrated models.AlbumImage
+	require.NoError(t, targetDB.Where("album_id = ? AND image_id = ?", 1, 1).First(&migrated).Error)
+	assert.Equal(t, 7, migrated.Position)
+	assert.True(t, migrated.CreatedAt.Equal(addedAt))
+}
+
+func setupMigrateTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, models.SetupJoinTables(db))
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.Album{}))
+	return db
+}
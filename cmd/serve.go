@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/anoixa/image-bed/api"
 	"github.com/anoixa/image-bed/api/core"
@@ -21,8 +22,12 @@ import (
 	"github.com/anoixa/image-bed/database/repo/accounts"
 	"github.com/anoixa/image-bed/database/repo/albums"
 	dashboardRepo "github.com/anoixa/image-bed/database/repo/dashboard"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/database/repo/jobs"
 	"github.com/anoixa/image-bed/database/repo/keys"
+	"github.com/anoixa/image-bed/database/repo/tags"
+	dashboardSvc "github.com/anoixa/image-bed/internal/dashboard"
 	imageSvc "github.com/anoixa/image-bed/internal/image"
 	"github.com/anoixa/image-bed/internal/vipsfile"
 	"github.com/anoixa/image-bed/internal/worker"
@@ -47,11 +52,15 @@ var serveCmd = &cobra.Command{
 		if err := initCommandLogger(); err != nil {
 			exitWithErrorf("Failed to initialize config/logger: %v", err)
 		}
+		if noProcessing, _ := cmd.Flags().GetBool("no-processing"); noProcessing {
+			config.Get().ProcessingEnabled = false
+		}
 		RunServer()
 	},
 }
 
 func init() {
+	serveCmd.Flags().Bool("no-processing", false, "only enqueue variant jobs and leave processing to the worker command")
 	rootCmd.AddCommand(serveCmd)
 }
 
@@ -64,6 +73,7 @@ type Dependencies struct {
 	DashboardRepo *dashboardRepo.Repository
 	ConfigManager *configSvc.Manager
 	Converter     *imageSvc.Converter
+	Reprocess     *imageSvc.ReprocessService
 }
 
 // InitDependencies 初始化所有依赖
@@ -91,7 +101,10 @@ func InitDependencies(cfg *config.Config) (*Dependencies, error) {
 		DevicesRepo:  accounts.NewDeviceRepository(db),
 		ImagesRepo:   images.NewRepository(db),
 		AlbumsRepo:   albums.NewRepository(db),
+		SharesRepo:   albums.NewShareRepository(db),
 		KeysRepo:     keys.NewRepository(db),
+		JobsRepo:     jobs.NewRepository(db),
+		TagsRepo:     tags.NewRepository(db),
 	}
 
 	// 从配置文件初始化缓存
@@ -133,6 +146,7 @@ func InitDependencies(cfg *config.Config) (*Dependencies, error) {
 	dashboardRepository := dashboardRepo.NewRepository(db)
 	cacheHelper := cache.NewHelper(cache.GetDefault())
 	converter := imageSvc.NewConverter(configManager, variantRepo, imageRepo, storage.GetDefault(), cacheHelper)
+	reprocessService := imageSvc.NewReprocessService(images.NewReprocessJobRepository(db), imageRepo, converter)
 
 	return &Dependencies{
 		DB:            db,
@@ -142,6 +156,7 @@ func InitDependencies(cfg *config.Config) (*Dependencies, error) {
 		DashboardRepo: dashboardRepository,
 		ConfigManager: configManager,
 		Converter:     converter,
+		Reprocess:     reprocessService,
 	}, nil
 }
 
@@ -161,29 +176,16 @@ func RunServer() {
 
 	utils.InitLogger(config.IsDevelopment())
 
-	dataDir := utils.GetDataDir()
-
-	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
-		exitWithErrorf("Failed to create data directory: %v", err)
-	}
-	if err := os.MkdirAll(filepath.Join(dataDir, "temp"), os.ModePerm); err != nil {
-		exitWithErrorf("Failed to create temp directory: %v", err)
-	}
-
-	if err := vipsfile.Startup(&vips.Config{
-		MaxCacheMem:      1,
-		MaxCacheSize:     1,
-		MaxCacheFiles:    0,
-		ConcurrencyLevel: 2,
-	}); err != nil {
-		exitWithErrorf("Failed to initialize govips: %v", err)
+	if cfg.ProcessingEnabled {
+		prepareProcessingRuntime()
+	} else {
+		// 只负责入队时不在启动阶段初始化 govips，上传探测等少量操作首次使用时再初始化
+		prepareDataDirs()
+		vipsfile.StartupOnDemand(vipsConfig())
+		imageSvc.SetRemoteProcessing(true)
 	}
 	defer vipsfile.Shutdown()
-
-	vipsLog.Infof("Govips initialized with cache limited to 1 byte / 1 entry")
-	if config.IsDevelopment() {
-		utils.LogMemoryStats("VIPS_INIT")
-	}
+	defer enableVipsIsolation(cfg)()
 
 	deps, err := InitDependencies(cfg)
 	if err != nil {
@@ -195,11 +197,31 @@ func RunServer() {
 		exitWithErrorf("Failed to initialize database: %v", err)
 	}
 
-	worker.InitGlobalPool(cfg.WorkerCount, 1000)
+	jobQueue := startJobQueue(deps, cfg, cfg.ProcessingEnabled)
 
 	sweeperCtx, sweeperCancel := context.WithCancel(context.Background())
 	defer sweeperCancel()
-	worker.StartVariantSweeper(sweeperCtx, deps.VariantRepo, deps.Repositories.ImagesRepo, deps.Converter.TriggerConversionFromSweeper)
+	if cfg.ProcessingEnabled {
+		worker.StartVariantSweeper(sweeperCtx, deps.VariantRepo, deps.Repositories.ImagesRepo, imageSvc.ActivePipelineImages, deps.Converter.TriggerConversionFromSweeper)
+		imageSvc.StartVariantEvictor(sweeperCtx, deps.Converter)
+	} else {
+		serveLog.Infof("Variant processing disabled, run `image-bed worker` to generate variants")
+	}
+	deleteSvc := imageSvc.NewDeleteService(deps.Repositories.ImagesRepo, deps.VariantRepo, cache.NewHelper(cache.GetDefault()))
+	imageSvc.StartTrashPurger(sweeperCtx, deleteSvc, cfg.TrashRetentionDays)
+	imageSvc.StartExpiredImageSweeper(sweeperCtx, deleteSvc)
+	deps.Reprocess.ResumeRunning(context.Background())
+	utils.SafeGo(func() {
+		rebuilt, err := fulltext.RebuildIfStale(sweeperCtx, deps.DB)
+		if err != nil {
+			serveLog.Warnf("Failed to rebuild full-text index: %v", err)
+		} else if rebuilt > 0 {
+			serveLog.Infof("Rebuilt full-text index for %d images", rebuilt)
+		}
+	})
+	stopVariantAccess := imageSvc.StartVariantAccessTracker(deps.VariantRepo)
+	stopImageStats := dashboardSvc.StartImageStatsTracker(deps.DashboardRepo)
+	dashboardSvc.StartImageStatsPruner(sweeperCtx, deps.DashboardRepo, cfg.AnalyticsRetentionDays)
 
 	jwtService, err := api.NewJWTServiceFromConfig(cfg, deps.ConfigManager, deps.Repositories.KeysRepo)
 	if err != nil {
@@ -213,6 +235,7 @@ func RunServer() {
 		DashboardRepo: deps.DashboardRepo,
 		ConfigManager: deps.ConfigManager,
 		Converter:     deps.Converter,
+		Reprocess:     deps.Reprocess,
 		JWTService:    jwtService,
 		Config:        cfg,
 		CacheProvider: cache.GetDefault(),
@@ -251,22 +274,105 @@ func RunServer() {
 		serveLog.Warnf("Server forced to shutdown: %v", err)
 	}
 
+	stopVariantAccess()
+	stopImageStats()
 	sweeperCancel()
+	jobQueue.Stop()
 
 	workerCtx, cancelWorkers := context.WithTimeout(context.Background(), cfg.ServerWriteTimeout)
 	defer cancelWorkers()
 
-	if err := worker.ShutdownGlobalPool(workerCtx); err != nil {
-		serveLog.Warnf("Worker pool did not drain before shutdown deadline: %v", err)
-		if rollbackErr := resetInFlightVariantWork(deps); rollbackErr != nil {
-			serveLog.Warnf("Failed to reset in-flight variant work during shutdown: %v", rollbackErr)
-		}
+	if err := deps.Reprocess.Shutdown(workerCtx); err != nil {
+		serveLog.Warnf("Reprocess jobs did not stop before shutdown deadline: %v", err)
 	}
 
+	drainWorkerPool(workerCtx, deps, jobQueue, serveLog)
+
 	cleanup()
 	serveLog.Infof("Server exited")
 }
 
+// prepareProcessingRuntime 创建数据目录并初始化 govips
+func prepareProcessingRuntime() {
+	prepareDataDirs()
+
+	startVips()
+	vipsLog.Infof("Govips initialized with cache limited to 1 byte / 1 entry")
+	if config.IsDevelopment() {
+		utils.LogMemoryStats("VIPS_INIT")
+	}
+}
+
+// prepareDataDirs 创建数据目录和上传使用的临时目录
+func prepareDataDirs() {
+	dataDir := utils.GetDataDir()
+
+	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
+		exitWithErrorf("Failed to create data directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dataDir, "temp"), os.ModePerm); err != nil {
+		exitWithErrorf("Failed to create temp directory: %v", err)
+	}
+}
+
+// vipsConfig 关闭操作缓存以控制内存
+func vipsConfig() *vips.Config {
+	return &vips.Config{
+		MaxCacheMem:      1,
+		MaxCacheSize:     1,
+		MaxCacheFiles:    0,
+		ConcurrencyLevel: 2,
+	}
+}
+
+// startVips 初始化 govips
+func startVips() {
+	if err := vipsfile.Startup(vipsConfig()); err != nil {
+		exitWithErrorf("Failed to initialize govips: %v", err)
+	}
+}
+
+// enableVipsIsolation 按配置把 libvips 操作交给子进程，返回的函数在 Pool 停止后调用
+func enableVipsIsolation(cfg *config.Config) func() {
+	if !cfg.VipsIsolation {
+		return func() {}
+	}
+	if err := worker.EnableVipsIsolation(worker.VipsIsolationOptions{
+		MemoryLimitMB: cfg.VipsHelperMemoryMB,
+		Timeout:       cfg.VipsHelperTimeout,
+	}); err != nil {
+		exitWithErrorf("Failed to enable libvips isolation: %v", err)
+	}
+	return worker.DisableVipsIsolation
+}
+
+// startJobQueue 初始化 Pool 与持久化队列；processing 为 false 时不领取变体流水线任务
+func startJobQueue(deps *Dependencies, cfg *config.Config, processing bool) *worker.Queue {
+	worker.InitGlobalPool(cfg.WorkerCount, 1000)
+	jobQueue := worker.InitGlobalQueue(deps.Repositories.JobsRepo, worker.GetGlobalPool())
+	if processing {
+		imageSvc.RegisterPipelineJobHandlers(jobQueue, deps.Converter)
+	}
+	imageSvc.RegisterStorageJobHandlers(jobQueue)
+	jobQueue.Start()
+	return jobQueue
+}
+
+// drainWorkerPool 等待 Pool 执行完毕；超时则回滚进行中的变体并归还任务租约
+func drainWorkerPool(ctx context.Context, deps *Dependencies, jobQueue *worker.Queue, log *utils.Logger) {
+	if err := worker.ShutdownGlobalPool(ctx); err != nil {
+		log.Warnf("Worker pool did not drain before shutdown deadline: %v", err)
+		if rollbackErr := resetInFlightVariantWork(deps); rollbackErr != nil {
+			log.Warnf("Failed to reset in-flight variant work during shutdown: %v", rollbackErr)
+		}
+		if released, err := jobQueue.ReleaseRunning(context.Background()); err != nil {
+			log.Warnf("Failed to release job leases during shutdown: %v", err)
+		} else if released > 0 {
+			log.Infof("Released %d unfinished jobs back to the queue", released)
+		}
+	}
+}
+
 // InitDatabase 初始化数据库
 func InitDatabase(deps *Dependencies) error {
 	serveLog.Infof("Initializing database")
@@ -288,8 +394,15 @@ func InitDatabase(deps *Dependencies) error {
 	return nil
 }
 
+// resetInFlightVariantWork 回滚本进程持有租约的流水线任务所涉及的变体，须在归还租约前调用
 func resetInFlightVariantWork(deps *Dependencies) error {
-	return resetVariantWorkSnapshots(deps, worker.CurrentInFlightTasks())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	snapshots, err := imageSvc.InFlightPipelineTasks(ctx)
+	if err != nil {
+		return err
+	}
+	return resetVariantWorkSnapshots(deps, snapshots)
 }
 
 func resetVariantWorkSnapshots(deps *Dependencies, snapshots []worker.InFlightTaskSnapshot) error {
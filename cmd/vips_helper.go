@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/spf13/cobra"
+)
+
+// vipsHelperCmd 由 serve/worker 在启用 vips_isolation 时自动启动，不应手动运行
+var vipsHelperCmd = &cobra.Command{
+	Use:    worker.VipsHelperCommand,
+	Short:  "Run libvips operations for a supervising process",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		memoryMB, _ := cmd.Flags().GetInt("memory-mb")
+
+		startVips()
+		defer vipsfile.Shutdown()
+
+		if err := worker.RunVipsHelper(memoryMB); err != nil {
+			exitWithErrorf("vips helper stopped: %v", err)
+		}
+	},
+}
+
+func init() {
+	vipsHelperCmd.Flags().Int("memory-mb", 0, "data segment limit in MB, 0 for no limit")
+	rootCmd.AddCommand(vipsHelperCmd)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/anoixa/image-bed/config"
+	configSvc "github.com/anoixa/image-bed/config/db"
+	imageSvc "github.com/anoixa/image-bed/internal/image"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/spf13/cobra"
+)
+
+var workerLog = utils.ForModule("WorkerCmd")
+
+const (
+	// workerRefreshInterval worker 进程刷新配置缓存和存储配置的间隔
+	workerRefreshInterval = time.Minute
+	// workerShutdownTimeout 退出时等待进行中任务完成的时长
+	workerShutdownTimeout = 30 * time.Second
+)
+
+// workerCmd represents the worker command
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run the variant pipeline without the API server",
+	Long: `Run only the variant pipeline. Work is claimed from the shared jobs table,
+so any number of workers can run next to a serve process started with
+--no-processing (or PROCESSING_ENABLED=false).
+
+Use cache_type=redis so cached variant lists invalidated here are also
+invalidated for the API server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := initCommandLogger(); err != nil {
+			exitWithErrorf("Failed to initialize config/logger: %v", err)
+		}
+		RunWorker()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+}
+
+// RunWorker 启动独立的变体处理进程
+func RunWorker() {
+	cfg := config.Get()
+
+	prepareProcessingRuntime()
+	defer vipsfile.Shutdown()
+	defer enableVipsIsolation(cfg)()
+
+	deps, err := InitDependencies(cfg)
+	if err != nil {
+		exitWithErrorf("Failed to initialize dependencies: %v", err)
+	}
+	defer func() { _ = deps.Close() }()
+
+	if cfg.CacheType != "redis" {
+		workerLog.Warnf("cache_type is %q, the API server will keep serving cached variant lists until they expire; use redis to share invalidations", cfg.CacheType)
+	}
+
+	jobQueue := startJobQueue(deps, cfg, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	worker.StartVariantSweeper(ctx, deps.VariantRepo, deps.Repositories.ImagesRepo, imageSvc.ActivePipelineImages, deps.Converter.TriggerConversionFromSweeper)
+	imageSvc.StartVariantEvictor(ctx, deps.Converter)
+	go refreshWorkerConfig(ctx, deps.ConfigManager)
+
+	workerLog.Infof("Worker started with %d workers", worker.GetGlobalPool().GetStats().WorkerCount)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	workerLog.Infof("Received signal: %v, shutting down", sig)
+
+	cancel()
+	jobQueue.Stop()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), workerShutdownTimeout)
+	defer cancelShutdown()
+	drainWorkerPool(shutdownCtx, deps, jobQueue, workerLog)
+
+	workerLog.Infof("Worker exited")
+}
+
+// refreshWorkerConfig 定期清除配置缓存并加载新增的存储配置
+// worker 进程收不到 API 进程内的配置变更事件；已有存储的凭据变更需要重启 worker 生效
+func refreshWorkerConfig(ctx context.Context, manager *configSvc.Manager) {
+	ticker := time.NewTicker(workerRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			manager.ClearAllCache()
+			syncStorageProviders(ctx, manager)
+		}
+	}
+}
+
+// syncStorageProviders 注册尚未加载的存储配置
+func syncStorageProviders(ctx context.Context, manager *configSvc.Manager) {
+	configs, err := manager.GetStorageConfigs(ctx)
+	if err != nil {
+		workerLog.Warnf("Failed to refresh storage configs: %v", err)
+		return
+	}
+
+	for _, cfg := range configs {
+		if _, err := storage.GetByID(cfg.ID); err == nil {
+			continue
+		}
+		if err := storage.AddOrUpdateProvider(cfg); err != nil {
+			workerLog.Warnf("Failed to load storage config %d: %v", cfg.ID, err)
+			continue
+		}
+		workerLog.Infof("Loaded storage config %d", cfg.ID)
+	}
+}
@@ -65,6 +65,11 @@ type Config struct {
 
 	UploadMaxBatchTotalMB int `mapstructure:"upload_max_batch_total_mb"`
 
+	// TrashRetentionDays 图片在回收站中保留的天数，超过后彻底删除；0 表示不自动清理
+	TrashRetentionDays int `mapstructure:"trash_retention_days"`
+	// AnalyticsRetentionDays 图片访问统计保留的天数；0 表示不自动清理
+	AnalyticsRetentionDays int `mapstructure:"analytics_retention_days"`
+
 	// JWT 配置
 	JWTSecret          string `mapstructure:"jwt_secret"`
 	JWTAccessTokenTTL  string `mapstructure:"jwt_access_token_ttl"`
@@ -73,6 +78,13 @@ type Config struct {
 	// Worker 配置
 	WorkerCount         int `mapstructure:"worker_count"`
 	WorkerMemoryLimitMB int `mapstructure:"worker_memory_limit_mb"`
+	// ProcessingEnabled 为 false 时 serve 只负责入队，变体由独立的 worker 进程生成
+	ProcessingEnabled bool `mapstructure:"processing_enabled"`
+
+	// libvips 子进程隔离：开启后 cgo 崩溃或内存超限只会结束子进程
+	VipsIsolation      bool          `mapstructure:"vips_isolation"`
+	VipsHelperMemoryMB int           `mapstructure:"vips_helper_memory_mb"`
+	VipsHelperTimeout  time.Duration `mapstructure:"vips_helper_timeout"`
 
 	// 前端配置
 	ServeFrontend bool `mapstructure:"serve_frontend"` // 是否提供前端静态文件服务，默认 true
@@ -169,6 +181,8 @@ func setDefaults() {
 	viper.SetDefault("rate_limit_expire_time", "10m")
 
 	viper.SetDefault("upload_max_batch_total_mb", 500)
+	viper.SetDefault("trash_retention_days", 30)
+	viper.SetDefault("analytics_retention_days", 90)
 
 	viper.SetDefault("jwt_secret", "")
 	viper.SetDefault("jwt_access_token_ttl", "15m")
@@ -177,6 +191,10 @@ func setDefaults() {
 	// Worker 配置默认值
 	viper.SetDefault("worker_count", 0)             // 0 表示使用默认值
 	viper.SetDefault("worker_memory_limit_mb", 512) // Worker 内存限制，默认 512MB
+	viper.SetDefault("processing_enabled", true)    // 默认在 serve 进程内生成变体
+	viper.SetDefault("vips_isolation", false)
+	viper.SetDefault("vips_helper_memory_mb", 1024) // 每个子进程的内存上限
+	viper.SetDefault("vips_helper_timeout", "2m")   // 单次 libvips 操作超时
 
 	// 前端配置默认值
 	viper.SetDefault("serve_frontend", true) // 默认启用前端服务
@@ -50,3 +50,12 @@ func TestSetDefaultsLeavesServerDomainEmpty(t *testing.T) {
 
 	assert.Equal(t, "", viper.GetString("server_domain"))
 }
+
+func TestSetDefaultsEnablesInProcessProcessing(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	setDefaults()
+
+	assert.True(t, viper.GetBool("processing_enabled"))
+}
@@ -25,9 +25,34 @@ type ImageProcessingSettings struct {
 	AVIFQuality              int      `json:"avif_quality" mapstructure:"avif_quality"`
 	AVIFSpeed                int      `json:"avif_speed" mapstructure:"avif_speed"`
 	AVIFExperimental         bool     `json:"avif_experimental" mapstructure:"avif_experimental"`
+	JXLQuality               int      `json:"jxl_quality" mapstructure:"jxl_quality"`
+	JXLEffort                int      `json:"jxl_effort" mapstructure:"jxl_effort"`
 	SkipSmallerThan          int      `json:"skip_smaller_than" mapstructure:"skip_smaller_than"`
 	MaxDimension             int      `json:"max_dimension" mapstructure:"max_dimension"`
 
+	// 色彩管理配置：变体默认转换为 sRGB，避免广色域和 CMYK 原图在忽略配置文件的浏览器中偏色
+	ColorSpace      string `json:"color_space" mapstructure:"color_space"`             // srgb 或 preserve，空值按 srgb 处理
+	AVIFWideGamut   bool   `json:"avif_wide_gamut" mapstructure:"avif_wide_gamut"`     // 带 ICC 配置的原图生成 AVIF 时保留 Display P3 色域
+	EmbedICCProfile bool   `json:"embed_icc_profile" mapstructure:"embed_icc_profile"` // 在变体中嵌入输出色彩配置
+
+	// 按需生成配置：上传时不预先生成变体，首次访问时再触发
+	LazyVariants bool `json:"lazy_variants" mapstructure:"lazy_variants"`
+	LazyWaitMs   int  `json:"lazy_wait_ms" mapstructure:"lazy_wait_ms"` // 首次请求缩略图时同步等待生成的时间，0 表示直接返回原图
+
+	// 变体清理配置：超过天数未被访问的变体从存储删除，之后按需重新生成，0 表示不清理
+	VariantEvictionDays int `json:"variant_eviction_days" mapstructure:"variant_eviction_days"`
+
+	// 上传策略：保存前缩小、优化或转换原图，用户可单独设置覆盖
+	IngestMaxDimension  int    `json:"ingest_max_dimension" mapstructure:"ingest_max_dimension"`
+	IngestOptimize      bool   `json:"ingest_optimize" mapstructure:"ingest_optimize"`
+	IngestConvertFormat string `json:"ingest_convert_format" mapstructure:"ingest_convert_format"`
+	IngestQuality       int    `json:"ingest_quality" mapstructure:"ingest_quality"`
+
+	// 动图配置
+	PreserveAnimation  bool `json:"preserve_animation" mapstructure:"preserve_animation"`
+	StaticThumbnail    bool `json:"static_thumbnail" mapstructure:"static_thumbnail"`
+	MaxAnimationFrames int  `json:"max_animation_frames" mapstructure:"max_animation_frames"`
+
 	// 用户偏好配置
 	DefaultAlbumID        uint   `json:"default_album_id" mapstructure:"default_album_id"`
 	DefaultVisibility     string `json:"default_visibility" mapstructure:"default_visibility"`
@@ -37,6 +62,24 @@ type ImageProcessingSettings struct {
 	APIKeyEnabled         bool   `json:"api_key_enabled" mapstructure:"api_key_enabled"`
 }
 
+// 变体色彩空间处理方式
+const (
+	ColorSpaceSRGB     = "srgb"     // 转换为 sRGB
+	ColorSpacePreserve = "preserve" // 保持原图像素，不做色彩转换
+)
+
+// DefaultMaxAnimationFrames 动图最大帧数默认值
+const DefaultMaxAnimationFrames = 300
+
+// maxAnimationFramesLimit 动图帧数上限的允许最大值
+const maxAnimationFramesLimit = 5000
+
+// maxLazyWaitMs 按需生成同步等待时间的允许最大值
+const maxLazyWaitMs = 10000
+
+// maxVariantEvictionDays 变体清理天数的允许最大值
+const maxVariantEvictionDays = 3650
+
 // DefaultImageProcessingSettings 默认图片处理配置
 func DefaultImageProcessingSettings() *ImageProcessingSettings {
 	return &ImageProcessingSettings{
@@ -52,9 +95,34 @@ func DefaultImageProcessingSettings() *ImageProcessingSettings {
 		AVIFQuality:              80,
 		AVIFSpeed:                4,
 		AVIFExperimental:         false,
+		JXLQuality:               80,
+		JXLEffort:                7,
 		SkipSmallerThan:          10,
 		MaxDimension:             4096,
 
+		// 色彩管理默认值
+		ColorSpace:      ColorSpaceSRGB,
+		AVIFWideGamut:   false,
+		EmbedICCProfile: false,
+
+		// 按需生成默认值
+		LazyVariants: false,
+		LazyWaitMs:   0,
+
+		// 变体清理默认值
+		VariantEvictionDays: 0,
+
+		// 上传策略默认不修改原图
+		IngestMaxDimension:  0,
+		IngestOptimize:      false,
+		IngestConvertFormat: "",
+		IngestQuality:       0,
+
+		// 动图默认值
+		PreserveAnimation:  true,
+		StaticThumbnail:    false,
+		MaxAnimationFrames: DefaultMaxAnimationFrames,
+
 		// 用户偏好默认值
 		DefaultAlbumID:        0,
 		DefaultVisibility:     "public",
@@ -80,6 +148,17 @@ func (s *ImageProcessingSettings) Validate() error {
 		if size.Width > maxThumbnailSize || size.Height > maxThumbnailSize {
 			return fmt.Errorf("thumbnail size at index %d exceeds maximum allowed (%dx%d)", i, maxThumbnailSize, maxThumbnailSize)
 		}
+		if size.Height > 0 && size.Width == 0 {
+			return fmt.Errorf("thumbnail size at index %d: width is required when height is set", i)
+		}
+		if !models.IsValidThumbnailCrop(size.Crop) {
+			return fmt.Errorf("invalid thumbnail crop mode %q at index %d", size.Crop, i)
+		}
+		for j := 0; j < i; j++ {
+			if s.ThumbnailSizes[j].Format() == size.Format() {
+				return fmt.Errorf("duplicate thumbnail size at index %d", i)
+			}
+		}
 	}
 	if s.WebPQuality != 0 && (s.WebPQuality < 1 || s.WebPQuality > 100) {
 		return fmt.Errorf("webp quality must be between 1 and 100")
@@ -93,6 +172,27 @@ func (s *ImageProcessingSettings) Validate() error {
 	if s.AVIFSpeed < 0 || s.AVIFSpeed > 8 {
 		return fmt.Errorf("avif speed must be between 0 and 8")
 	}
+	if s.JXLQuality != 0 && (s.JXLQuality < 1 || s.JXLQuality > 100) {
+		return fmt.Errorf("jxl quality must be between 1 and 100")
+	}
+	if s.JXLEffort < 0 || s.JXLEffort > 9 {
+		return fmt.Errorf("jxl effort must be between 0 and 9")
+	}
+	if s.LazyWaitMs < 0 || s.LazyWaitMs > maxLazyWaitMs {
+		return fmt.Errorf("lazy wait must be between 0 and %d ms", maxLazyWaitMs)
+	}
+	if s.VariantEvictionDays < 0 || s.VariantEvictionDays > maxVariantEvictionDays {
+		return fmt.Errorf("variant eviction days must be between 0 and %d", maxVariantEvictionDays)
+	}
+	if s.ColorSpace != "" && s.ColorSpace != ColorSpaceSRGB && s.ColorSpace != ColorSpacePreserve {
+		return fmt.Errorf("color space must be '%s' or '%s'", ColorSpaceSRGB, ColorSpacePreserve)
+	}
+	if err := s.IngestPolicy().Validate(); err != nil {
+		return err
+	}
+	if s.MaxAnimationFrames < 0 || s.MaxAnimationFrames > maxAnimationFramesLimit {
+		return fmt.Errorf("max animation frames must be between 0 and %d", maxAnimationFramesLimit)
+	}
 	// 用户偏好验证（非零值才验证）
 	if s.ConcurrentUploadLimit != 0 && (s.ConcurrentUploadLimit < 1 || s.ConcurrentUploadLimit > 10) {
 		return fmt.Errorf("concurrent upload limit must be between 1 and 10")
@@ -114,6 +214,21 @@ func (s *ImageProcessingSettings) IsThumbnailEnabled() bool {
 	return s.ThumbnailEnabled
 }
 
+// ConvertsToSRGB 变体是否需要转换到 sRGB
+func (s *ImageProcessingSettings) ConvertsToSRGB() bool {
+	return s.ColorSpace != ColorSpacePreserve
+}
+
+// IngestPolicy 全局上传策略
+func (s *ImageProcessingSettings) IngestPolicy() models.IngestPolicy {
+	return models.IngestPolicy{
+		MaxDimension:  s.IngestMaxDimension,
+		Optimize:      s.IngestOptimize,
+		ConvertFormat: s.IngestConvertFormat,
+		Quality:       s.IngestQuality,
+	}
+}
+
 // IsValidWidth 检查是否为有效的缩略图宽度
 func (s *ImageProcessingSettings) IsValidWidth(width int) bool {
 	for _, size := range s.ThumbnailSizes {
@@ -124,6 +239,16 @@ func (s *ImageProcessingSettings) IsValidWidth(width int) bool {
 	return false
 }
 
+// GetSizeByDimensions 根据宽高获取尺寸配置，height 为 0 时匹配等比缩略图
+func (s *ImageProcessingSettings) GetSizeByDimensions(width, height int) *models.ThumbnailSize {
+	for _, size := range s.ThumbnailSizes {
+		if size.Width == width && max(size.Height, 0) == height {
+			return &size
+		}
+	}
+	return nil
+}
+
 // GetSizeByWidth 根据宽度获取尺寸配置
 func (s *ImageProcessingSettings) GetSizeByWidth(width int) *models.ThumbnailSize {
 	for _, size := range s.ThumbnailSizes {
@@ -134,6 +259,14 @@ func (s *ImageProcessingSettings) GetSizeByWidth(width int) *models.ThumbnailSiz
 	return nil
 }
 
+// AnimationFrameLimit 返回动图帧数上限，未配置时使用默认值
+func (s *ImageProcessingSettings) AnimationFrameLimit() int {
+	if s.MaxAnimationFrames <= 0 {
+		return DefaultMaxAnimationFrames
+	}
+	return s.MaxAnimationFrames
+}
+
 // IsFormatEnabled 检查格式是否启用
 func (s *ImageProcessingSettings) IsFormatEnabled(format string) bool {
 	for _, f := range s.ConversionEnabledFormats {
@@ -220,8 +353,23 @@ func (m *Manager) ensureDefaultImageProcessingConfig(ctx context.Context) error
 			"avif_quality":               defaultSettings.AVIFQuality,
 			"avif_speed":                 defaultSettings.AVIFSpeed,
 			"avif_experimental":          defaultSettings.AVIFExperimental,
+			"jxl_quality":                defaultSettings.JXLQuality,
+			"jxl_effort":                 defaultSettings.JXLEffort,
 			"skip_smaller_than":          defaultSettings.SkipSmallerThan,
 			"max_dimension":              defaultSettings.MaxDimension,
+			"color_space":                defaultSettings.ColorSpace,
+			"avif_wide_gamut":            defaultSettings.AVIFWideGamut,
+			"embed_icc_profile":          defaultSettings.EmbedICCProfile,
+			"lazy_variants":              defaultSettings.LazyVariants,
+			"lazy_wait_ms":               defaultSettings.LazyWaitMs,
+			"variant_eviction_days":      defaultSettings.VariantEvictionDays,
+			"ingest_max_dimension":       defaultSettings.IngestMaxDimension,
+			"ingest_optimize":            defaultSettings.IngestOptimize,
+			"ingest_convert_format":      defaultSettings.IngestConvertFormat,
+			"ingest_quality":             defaultSettings.IngestQuality,
+			"preserve_animation":         defaultSettings.PreserveAnimation,
+			"static_thumbnail":           defaultSettings.StaticThumbnail,
+			"max_animation_frames":       defaultSettings.MaxAnimationFrames,
 			"default_album_id":           defaultSettings.DefaultAlbumID,
 			"default_visibility":         defaultSettings.DefaultVisibility,
 			"concurrent_upload_limit":    defaultSettings.ConcurrentUploadLimit,
@@ -270,8 +418,23 @@ func (m *Manager) SaveImageProcessingSettings(ctx context.Context, settings *Ima
 			"avif_quality":               settings.AVIFQuality,
 			"avif_speed":                 settings.AVIFSpeed,
 			"avif_experimental":          settings.AVIFExperimental,
+			"jxl_quality":                settings.JXLQuality,
+			"jxl_effort":                 settings.JXLEffort,
 			"skip_smaller_than":          settings.SkipSmallerThan,
 			"max_dimension":              settings.MaxDimension,
+			"color_space":                settings.ColorSpace,
+			"avif_wide_gamut":            settings.AVIFWideGamut,
+			"embed_icc_profile":          settings.EmbedICCProfile,
+			"lazy_variants":              settings.LazyVariants,
+			"lazy_wait_ms":               settings.LazyWaitMs,
+			"variant_eviction_days":      settings.VariantEvictionDays,
+			"ingest_max_dimension":       settings.IngestMaxDimension,
+			"ingest_optimize":            settings.IngestOptimize,
+			"ingest_convert_format":      settings.IngestConvertFormat,
+			"ingest_quality":             settings.IngestQuality,
+			"preserve_animation":         settings.PreserveAnimation,
+			"static_thumbnail":           settings.StaticThumbnail,
+			"max_animation_frames":       settings.MaxAnimationFrames,
 			"default_album_id":           settings.DefaultAlbumID,
 			"default_visibility":         settings.DefaultVisibility,
 			"concurrent_upload_limit":    settings.ConcurrentUploadLimit,
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateThumbnailSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []models.ThumbnailSize
+		wantErr string
+	}{
+		{"width only", []models.ThumbnailSize{{Width: 600}}, ""},
+		{"cropped sizes", []models.ThumbnailSize{{Width: 600}, {Width: 400, Height: 400}, {Width: 640, Height: 360, Crop: models.ThumbnailCropFocal}}, ""},
+		{"unknown crop", []models.ThumbnailSize{{Width: 400, Height: 400, Crop: "faces"}}, "invalid thumbnail crop mode"},
+		{"height without width", []models.ThumbnailSize{{Height: 400}}, "width is required"},
+		{"duplicate", []models.ThumbnailSize{{Width: 400, Height: 400}, {Width: 400, Height: 400, Crop: models.ThumbnailCropEntropy}}, "duplicate thumbnail size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &ImageProcessingSettings{ThumbnailSizes: tt.sizes}
+			err := settings.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGetSizeByDimensions(t *testing.T) {
+	settings := &ImageProcessingSettings{ThumbnailSizes: []models.ThumbnailSize{
+		{Width: 600},
+		{Width: 600, Height: 600},
+	}}
+
+	size := settings.GetSizeByDimensions(600, 0)
+	if assert.NotNil(t, size) {
+		assert.False(t, size.IsCropped())
+	}
+	size = settings.GetSizeByDimensions(600, 600)
+	if assert.NotNil(t, size) {
+		assert.Equal(t, "thumbnail_600x600", size.Format())
+	}
+	assert.Nil(t, settings.GetSizeByDimensions(600, 338))
+}
+
+func TestValidateLazyWait(t *testing.T) {
+	assert.NoError(t, (&ImageProcessingSettings{LazyVariants: true, LazyWaitMs: 1500}).Validate())
+	assert.ErrorContains(t, (&ImageProcessingSettings{LazyWaitMs: -1}).Validate(), "lazy wait")
+	assert.ErrorContains(t, (&ImageProcessingSettings{LazyWaitMs: maxLazyWaitMs + 1}).Validate(), "lazy wait")
+	assert.False(t, DefaultImageProcessingSettings().LazyVariants)
+}
+
+func TestValidateVariantEvictionDays(t *testing.T) {
+	assert.NoError(t, (&ImageProcessingSettings{VariantEvictionDays: 90}).Validate())
+	assert.ErrorContains(t, (&ImageProcessingSettings{VariantEvictionDays: -1}).Validate(), "variant eviction days")
+	assert.ErrorContains(t, (&ImageProcessingSettings{VariantEvictionDays: maxVariantEvictionDays + 1}).Validate(), "variant eviction days")
+}
+
+func TestValidateIngestPolicy(t *testing.T) {
+	assert.NoError(t, (&ImageProcessingSettings{IngestMaxDimension: 4096, IngestConvertFormat: "webp", IngestQuality: 90}).Validate())
+	assert.ErrorContains(t, (&ImageProcessingSettings{IngestMaxDimension: -1}).Validate(), "ingest max dimension")
+	assert.ErrorContains(t, (&ImageProcessingSettings{IngestConvertFormat: "bmp"}).Validate(), "ingest convert format")
+	assert.ErrorContains(t, (&ImageProcessingSettings{IngestQuality: 101}).Validate(), "ingest quality")
+
+	settings := &ImageProcessingSettings{IngestOptimize: true, IngestQuality: 80}
+	assert.Equal(t, models.IngestPolicy{Optimize: true, Quality: 80}, settings.IngestPolicy())
+}
+
+func TestValidateColorSpace(t *testing.T) {
+	assert.NoError(t, (&ImageProcessingSettings{}).Validate())
+	assert.NoError(t, (&ImageProcessingSettings{ColorSpace: ColorSpacePreserve}).Validate())
+	assert.ErrorContains(t, (&ImageProcessingSettings{ColorSpace: "adobe-rgb"}).Validate(), "color space")
+
+	assert.True(t, (&ImageProcessingSettings{}).ConvertsToSRGB(), "stored configs without the field keep converting")
+	assert.True(t, DefaultImageProcessingSettings().ConvertsToSRGB())
+	assert.False(t, (&ImageProcessingSettings{ColorSpace: ColorSpacePreserve}).ConvertsToSRGB())
+}
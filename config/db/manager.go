@@ -81,6 +81,11 @@ func (m *Manager) ClearCache() {
 	m.cache.Invalidate(models.ConfigCategoryStorage)
 }
 
+// ClearAllCache 清除全部配置缓存，供无法收到本进程变更事件的独立进程定期刷新
+func (m *Manager) ClearAllCache() {
+	m.cache.InvalidateAll()
+}
+
 // CreateConfig 创建配置
 func (m *Manager) CreateConfig(ctx context.Context, req *models.SystemConfigStoreRequest, userID uint) (*models.ConfigResponse, error) {
 	baseKey := fmt.Sprintf("%s:%s", req.Category, req.Name)
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/anoixa/image-bed/config"
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"github.com/anoixa/image-bed/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -139,6 +141,9 @@ func configurePool(db *gorm.DB, cfg *config.Config) {
 
 // AutoMigrate 自动迁移数据库结构
 func AutoMigrate(db *gorm.DB) error {
+	if err := models.SetupJoinTables(db); err != nil {
+		return err
+	}
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Device{},
@@ -147,6 +152,14 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.Album{},
 		&models.SystemConfig{},
 		&models.ImageVariant{},
+		&models.ReprocessJob{},
+		&models.Job{},
+		&models.UserIngestPolicy{},
+		&models.ImageStageRun{},
+		&models.Tag{},
+		&models.AlbumShare{},
+		&models.ImageVersion{},
+		&models.ImageStat{},
 	); err != nil {
 		return err
 	}
@@ -156,6 +169,12 @@ func AutoMigrate(db *gorm.DB) error {
 		return err
 	}
 
+	if err := fulltext.EnsureIndex(db); errors.Is(err, fulltext.ErrFTS5Unavailable) {
+		dbMigrationLog.Warnf("SQLite is built without FTS5 (build with -tags sqlite_fts5), full-text search falls back to name matching")
+	} else if err != nil {
+		return fmt.Errorf("failed to create full-text index: %w", err)
+	}
+
 	return fixImageIdentifierIndexes(db)
 }
 
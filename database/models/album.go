@@ -1,6 +1,31 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 相册内图片的排序方式
+const (
+	AlbumSortManual      = "manual" // 按手动拖拽的顺序，新加入的图片排在末尾
+	AlbumSortAddedAsc    = "added_asc"
+	AlbumSortAddedDesc   = "added_desc"
+	AlbumSortCreatedAsc  = "created_asc"
+	AlbumSortCreatedDesc = "created_desc"
+	AlbumSortNameAsc     = "name_asc"
+	AlbumSortNameDesc    = "name_desc"
+)
+
+// IsValidAlbumSort 检查排序方式是否受支持
+func IsValidAlbumSort(mode string) bool {
+	switch mode {
+	case AlbumSortManual, AlbumSortAddedAsc, AlbumSortAddedDesc,
+		AlbumSortCreatedAsc, AlbumSortCreatedDesc, AlbumSortNameAsc, AlbumSortNameDesc:
+		return true
+	}
+	return false
+}
 
 type Album struct {
 	gorm.Model
@@ -8,5 +33,26 @@ type Album struct {
 	Name        string `gorm:"type:varchar(100);not null;index"`
 	Description string `gorm:"type:varchar(255)"`
 
+	// ParentID 为空表示顶层相册
+	ParentID     *uint  `gorm:"index"`
+	SortMode     string `gorm:"type:varchar(20);not null;default:'manual'"`
+	CoverImageID *uint
+
 	Images []*Image `gorm:"many2many:album_images;"`
 }
+
+// AlbumImage 相册与图片的关联，Position 为手动排序的位置
+type AlbumImage struct {
+	AlbumID   uint `gorm:"primaryKey;autoIncrement:false"`
+	ImageID   uint `gorm:"primaryKey;autoIncrement:false"`
+	Position  int  `gorm:"not null;default:0"`
+	CreatedAt time.Time
+}
+
+// SetupJoinTables 注册自定义的多对多关联表，需在 AutoMigrate 之前调用
+func SetupJoinTables(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&Album{}, "Images", &AlbumImage{}); err != nil {
+		return err
+	}
+	return db.SetupJoinTable(&Image{}, "Albums", &AlbumImage{})
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AlbumShare 相册的公开分享链接，持有链接即可只读访问相册及其中的私有图片
+type AlbumShare struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	AlbumID   uint   `gorm:"not null;index"`
+	UserID    uint   `gorm:"not null;index"`
+	Token     string `gorm:"type:varchar(64);not null;uniqueIndex"`
+
+	// PasswordHash 为空表示无需密码，否则为 argon2id 编码的哈希
+	PasswordHash string `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	ExpiresAt    *time.Time
+	MaxViews     int `gorm:"not null;default:0"` // 0 表示不限
+	ViewCount    int `gorm:"not null;default:0"`
+	LastViewedAt *time.Time
+	RevokedAt    *time.Time
+}
+
+// HasPassword 是否需要密码访问
+func (s *AlbumShare) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// IsExpired 是否已过期
+func (s *AlbumShare) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// ViewsUsedUp 访问次数是否已用完
+func (s *AlbumShare) ViewsUsedUp() bool {
+	return s.MaxViews > 0 && s.ViewCount >= s.MaxViews
+}
+
+// IsRevoked 是否已撤销
+func (s *AlbumShare) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
@@ -44,12 +44,59 @@ type Image struct {
 	Height   int
 	IsPublic bool `gorm:"default:true;not null"`
 
+	// 用户可编辑的标题、描述和无障碍替代文本
+	Title       string `gorm:"type:varchar(200);not null;default:''"`
+	Description string `gorm:"type:varchar(2000);not null;default:''"`
+	AltText     string `gorm:"type:varchar(500);not null;default:''"`
+
 	VariantStatus ImageVariantStatus `gorm:"default:0;not null"`
 
+	// 内容版本号，每次替换或回滚内容后递增，历史内容见 ImageVersion
+	Version int `gorm:"default:1;not null"`
+
+	// 手动焦点（相对坐标 0-1），用于固定宽高比缩略图裁剪
+	FocalX *float64
+	FocalY *float64
+
+	// 过期时间和最大查看次数（0 为不限），到期或次数用完后不再提供访问并由定时任务删除
+	ExpiresAt *time.Time `gorm:"index"`
+	MaxViews  int        `gorm:"default:0;not null"`
+	ViewCount int64      `gorm:"default:0;not null"`
+
 	UserID uint `gorm:"index:idx_user_created_at,priority:1"`
 	User   User `gorm:"foreignKey:UserID"`
 
 	Albums []*Album `gorm:"many2many:album_images;"`
+	Tags   []*Tag   `gorm:"many2many:image_tags;"`
 
 	IsPendingDeletion bool `gorm:"default:false;not null" json:"-"`
 }
+
+// FocalPoint 返回手动焦点，未设置时 ok 为 false
+func (i *Image) FocalPoint() (x, y float64, ok bool) {
+	if i.FocalX == nil || i.FocalY == nil {
+		return 0, 0, false
+	}
+	return *i.FocalX, *i.FocalY, true
+}
+
+// EmbedAlt 返回嵌入标记使用的替代文本，未设置时回退到标题
+func (i *Image) EmbedAlt() string {
+	if i.AltText != "" {
+		return i.AltText
+	}
+	return i.Title
+}
+
+// IsEphemeral 是否设置了过期时间或查看次数限制
+func (i *Image) IsEphemeral() bool {
+	return i.ExpiresAt != nil || i.MaxViews > 0
+}
+
+// IsExpired 是否已到期或查看次数已用完
+func (i *Image) IsExpired(now time.Time) bool {
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return true
+	}
+	return i.MaxViews > 0 && i.ViewCount >= int64(i.MaxViews)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 流水线阶段执行状态
+const (
+	StageRunCompleted = "completed"
+	StageRunSkipped   = "skipped"
+	StageRunFailed    = "failed"
+)
+
+// ImageStageRun 图片处理流水线中单个阶段最近一次的执行结果
+type ImageStageRun struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	ImageID    uint      `gorm:"not null;index:idx_image_stage,unique" json:"image_id"`
+	Stage      string    `gorm:"not null;size:64;index:idx_image_stage,unique" json:"stage"`
+	Status     string    `gorm:"not null;size:20" json:"status"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	Output     string    `gorm:"type:text" json:"output,omitempty"` // 阶段产出的 JSON
+	DurationMs int64     `gorm:"not null;default:0" json:"duration_ms"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ImageStatVariantOriginal 原图的变体标识，其余取 ImageVariant.Format（如 webp、thumbnail_600）
+const ImageStatVariantOriginal = "original"
+
+// ImageStatReferrerOther 超出单张图片每小时来源域名上限的访问合并到该来源，不会与合法域名冲突
+const ImageStatReferrerOther = "(other)"
+
+// ImageStat 图片按小时汇总的访问统计，访问先在内存中聚合再批量写入
+type ImageStat struct {
+	ID      uint      `gorm:"primarykey" json:"-"`
+	ImageID uint      `gorm:"not null;uniqueIndex:idx_image_stat_bucket" json:"image_id"`
+	UserID  uint      `gorm:"not null;index" json:"user_id"`                                  // 图片所有者
+	Bucket  time.Time `gorm:"not null;uniqueIndex:idx_image_stat_bucket;index" json:"bucket"` // UTC 整点
+	Variant string    `gorm:"not null;size:32;uniqueIndex:idx_image_stat_bucket" json:"variant"`
+	// Referrer 来源域名，空字符串表示无来源或来自本站；非空即为外链引用
+	Referrer string `gorm:"not null;size:255;default:'';uniqueIndex:idx_image_stat_bucket" json:"referrer"`
+	Hits     int64  `gorm:"not null;default:0" json:"hits"`
+	Bytes    int64  `gorm:"not null;default:0" json:"bytes"` // 直链跳转按文件大小估算
+}
+
+// TableName 指定表名
+func (ImageStat) TableName() string {
+	return "image_stats"
+}
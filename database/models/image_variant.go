@@ -9,22 +9,23 @@ import (
 
 // ImageVariant 图片格式变体
 type ImageVariant struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-	ImageID      uint           `gorm:"not null;index:idx_image_format,unique" json:"image_id"`
-	Format       string         `gorm:"not null;size:20;index:idx_image_format,unique" json:"format"` // webp, avif, thumbnail_150
-	Identifier   string         `gorm:"not null;size:255" json:"identifier"`                          // 业务标识符: a1b2c3d4e5f6_300.webp
-	StoragePath  string         `gorm:"not null;size:255" json:"storage_path"`                        // 存储路径: thumbnails/2024/01/15/a1b2c3d4e5f6_300.webp
-	FileSize     int64          `gorm:"not null" json:"file_size"`
-	FileHash     string         `gorm:"not null;size:64;index:idx_variant_filehash" json:"file_hash"` // 文件哈希，用于 ETag 和缓存验证
-	Width        int            `json:"width"`
-	Height       int            `json:"height"`
-	Status       string         `gorm:"default:pending;size:20;index" json:"status"`
-	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"`
-	RetryCount   int            `gorm:"default:0" json:"retry_count"`
-	NextRetryAt  *time.Time     `gorm:"index" json:"next_retry_at,omitempty"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ImageID        uint           `gorm:"not null;index:idx_image_format,unique" json:"image_id"`
+	Format         string         `gorm:"not null;size:20;index:idx_image_format,unique" json:"format"` // webp, avif, thumbnail_150
+	Identifier     string         `gorm:"not null;size:255" json:"identifier"`                          // 业务标识符: a1b2c3d4e5f6_300.webp
+	StoragePath    string         `gorm:"not null;size:255" json:"storage_path"`                        // 存储路径: thumbnails/2024/01/15/a1b2c3d4e5f6_300.webp
+	FileSize       int64          `gorm:"not null" json:"file_size"`
+	FileHash       string         `gorm:"not null;size:64;index:idx_variant_filehash" json:"file_hash"` // 文件哈希，用于 ETag 和缓存验证
+	Width          int            `json:"width"`
+	Height         int            `json:"height"`
+	Status         string         `gorm:"default:pending;size:20;index" json:"status"`
+	ErrorMessage   string         `gorm:"type:text" json:"error_message,omitempty"`
+	RetryCount     int            `gorm:"default:0" json:"retry_count"`
+	NextRetryAt    *time.Time     `gorm:"index" json:"next_retry_at,omitempty"`
+	LastAccessedAt *time.Time     `gorm:"index" json:"last_accessed_at,omitempty"` // 最近访问时间，批量写入；为空时按 UpdatedAt 判断冷热
 }
 
 // TableName 指定表名
@@ -43,14 +44,54 @@ const (
 const (
 	FormatWebP      = "webp"
 	FormatAVIF      = "avif"
+	FormatJXL       = "jxl"
 	FormatThumbnail = "thumbnail"
 )
 
+// 缩略图裁剪模式，仅在同时指定宽高时生效
+const (
+	ThumbnailCropCentre    = "centre"    // 居中裁剪
+	ThumbnailCropAttention = "attention" // 按显著区域（人脸、肤色、高饱和度）裁剪
+	ThumbnailCropEntropy   = "entropy"   // 按信息熵最高的区域裁剪
+	ThumbnailCropFocal     = "focal"     // 按手动焦点裁剪，未设置焦点时居中
+)
+
 // ThumbnailSize 缩略图尺寸配置
 type ThumbnailSize struct {
 	Name   string
 	Width  int
-	Height int // 0 表示保持比例
+	Height int    // 0 表示保持比例
+	Crop   string // 裁剪模式，Height > 0 时生效，默认 attention
+}
+
+// IsCropped 是否为固定宽高比的裁剪缩略图
+func (s ThumbnailSize) IsCropped() bool {
+	return s.Height > 0
+}
+
+// CropMode 返回生效的裁剪模式
+func (s ThumbnailSize) CropMode() string {
+	if s.Crop == "" {
+		return ThumbnailCropAttention
+	}
+	return s.Crop
+}
+
+// Format 返回该尺寸对应的变体格式标识
+func (s ThumbnailSize) Format() string {
+	if s.IsCropped() {
+		return FormatCroppedThumbnailSize(s.Width, s.Height)
+	}
+	return FormatThumbnailSize(s.Width)
+}
+
+// IsValidThumbnailCrop 检查裁剪模式是否合法，空值表示默认
+func IsValidThumbnailCrop(crop string) bool {
+	switch crop {
+	case "", ThumbnailCropCentre, ThumbnailCropAttention, ThumbnailCropEntropy, ThumbnailCropFocal:
+		return true
+	}
+	return false
 }
 
 // DefaultThumbnailSizes 默认缩略图尺寸（单尺寸 600px）
@@ -63,6 +104,11 @@ func FormatThumbnailSize(width int) string {
 	return fmt.Sprintf("thumbnail_%d", width)
 }
 
+// FormatCroppedThumbnailSize 生成固定宽高缩略图的格式标识
+func FormatCroppedThumbnailSize(width, height int) string {
+	return fmt.Sprintf("thumbnail_%dx%d", width, height)
+}
+
 // ParseThumbnailSize 从格式标识解析缩略图尺寸
 func ParseThumbnailSize(format string) (width int, ok bool) {
 	if _, err := fmt.Sscanf(format, "thumbnail_%d", &width); err == nil {
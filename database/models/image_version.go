@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ImageVersion 图片被替换前的内容，标识符和链接不变，可回滚到任一历史版本
+type ImageVersion struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time `json:"created_at"` // 该版本被替换下来的时间
+	ImageID         uint      `gorm:"not null;uniqueIndex:idx_image_version" json:"image_id"`
+	Version         int       `gorm:"not null;uniqueIndex:idx_image_version" json:"version"`
+	StoragePath     string    `gorm:"not null;size:255;index" json:"-"`
+	StorageConfigID uint      `gorm:"not null" json:"-"`
+	OriginalName    string    `gorm:"not null" json:"original_name"`
+	FileSize        int64     `gorm:"not null" json:"file_size"`
+	MimeType        string    `gorm:"not null" json:"mime_type"`
+	FileHash        string    `gorm:"not null;size:64" json:"file_hash"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+}
+
+// TableName 指定表名
+func (ImageVersion) TableName() string {
+	return "image_versions"
+}
+
+// ContentVersion 返回图片当前内容对应的历史版本记录
+func (i *Image) ContentVersion() *ImageVersion {
+	return &ImageVersion{
+		ImageID:         i.ID,
+		Version:         i.Version,
+		StoragePath:     i.StoragePath,
+		StorageConfigID: i.StorageConfigID,
+		OriginalName:    i.OriginalName,
+		FileSize:        i.FileSize,
+		MimeType:        i.MimeType,
+		FileHash:        i.FileHash,
+		Width:           i.Width,
+		Height:          i.Height,
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// 原图转存格式
+const (
+	IngestFormatPNG  = "png"
+	IngestFormatJPEG = "jpeg"
+)
+
+// IngestConvertFormats 上传策略允许的原图转存格式
+var IngestConvertFormats = []string{IngestFormatPNG, IngestFormatJPEG, FormatWebP, FormatAVIF, FormatJXL}
+
+// maxIngestDimension 上传策略缩放边长的允许最大值
+const maxIngestDimension = 32768
+
+// IngestPolicy 上传原图在保存前的处理策略
+type IngestPolicy struct {
+	MaxDimension  int    `gorm:"not null;default:0" json:"max_dimension"`           // 长边超过该值时等比缩小，0 表示不缩放
+	Optimize      bool   `gorm:"not null;default:false" json:"optimize"`            // 无损优化 PNG/JPEG，结果更小时才替换
+	ConvertFormat string `gorm:"size:16;not null;default:''" json:"convert_format"` // 原图转存格式，空表示保持原格式
+	Quality       int    `gorm:"not null;default:0" json:"quality"`                 // 缩放或转存为有损格式时的质量，0 使用默认值
+}
+
+// Active 策略是否会修改原图
+func (p IngestPolicy) Active() bool {
+	return p.MaxDimension > 0 || p.Optimize || p.ConvertFormat != ""
+}
+
+// Validate 验证策略取值
+func (p IngestPolicy) Validate() error {
+	if p.MaxDimension < 0 || p.MaxDimension > maxIngestDimension {
+		return fmt.Errorf("ingest max dimension must be between 0 and %d", maxIngestDimension)
+	}
+	if p.ConvertFormat != "" && !slices.Contains(IngestConvertFormats, p.ConvertFormat) {
+		return fmt.Errorf("invalid ingest convert format %q", p.ConvertFormat)
+	}
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("ingest quality must be between 0 and 100")
+	}
+	return nil
+}
+
+// UserIngestPolicy 用户级上传策略，存在时整体覆盖全局配置
+type UserIngestPolicy struct {
+	ID           uint `gorm:"primarykey" json:"-"`
+	UserID       uint `gorm:"uniqueIndex;not null" json:"-"`
+	IngestPolicy `gorm:"embedded"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
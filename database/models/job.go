@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 持久化任务状态
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// DefaultJobMaxAttempts 任务默认最大尝试次数
+const DefaultJobMaxAttempts = 5
+
+// Job 持久化后台任务，通过租约（lease）在多个进程间安全领取
+type Job struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Type        string    `gorm:"size:64;not null;index" json:"type"`
+	Payload     string    `gorm:"type:text" json:"payload"`
+	Priority    int       `gorm:"not null;default:0" json:"priority"`                   // 越大越先执行
+	OwnerID     uint      `gorm:"not null;default:0" json:"owner_id"`                   // 所属用户，同优先级内按用户轮转领取
+	SubjectID   uint      `gorm:"not null;default:0;index" json:"subject_id,omitempty"` // 任务处理的对象，如变体流水线对应的图片 ID
+	Status      string    `gorm:"size:20;not null;index:idx_jobs_claim,priority:1" json:"status"`
+	RunAt       time.Time `gorm:"not null;index:idx_jobs_claim,priority:2" json:"run_at"`
+	Attempts    int       `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int       `gorm:"not null;default:5" json:"max_attempts"`
+
+	LeaseOwner     string     `gorm:"size:128;index" json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+
+	LastError  string     `gorm:"type:text" json:"last_error,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "jobs"
+}
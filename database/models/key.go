@@ -15,7 +15,9 @@ type ApiToken struct {
 	TokenPrefix string     `gorm:"size:64"`
 	Description string     `gorm:"size:255"`
 	LastUsedAt  *time.Time `json:"last_used_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	// 使用该 Token 上传且未指定过期设置时的默认有效期（秒），0 为永久保存
+	DefaultImageTTL int64     `gorm:"default:0;not null" json:"default_image_ttl"`
+	CreatedAt       time.Time `json:"created_at"`
 
 	User User `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 重新处理任务状态
+const (
+	ReprocessJobStatusRunning   = "running"
+	ReprocessJobStatusPaused    = "paused"
+	ReprocessJobStatusCancelled = "cancelled"
+	ReprocessJobStatusCompleted = "completed"
+	ReprocessJobStatusFailed    = "failed"
+)
+
+// ReprocessJob 批量重新生成变体任务
+type ReprocessJob struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Status    string `gorm:"size:20;not null;index" json:"status"`
+	CreatedBy uint   `json:"created_by"`
+
+	// 筛选条件
+	StartTime       *time.Time `json:"start_time,omitempty"`
+	EndTime         *time.Time `json:"end_time,omitempty"`
+	StorageConfigID *uint      `json:"storage_config_id,omitempty"`
+	MimeType        string     `gorm:"size:100" json:"mime_type,omitempty"`
+	AlbumID         *uint      `json:"album_id,omitempty"`
+	Formats         string     `gorm:"size:255" json:"formats,omitempty"` // 逗号分隔，空表示全部变体
+
+	// 节流参数
+	BatchSize       int `gorm:"not null" json:"batch_size"`
+	BatchIntervalMs int `gorm:"not null" json:"batch_interval_ms"`
+
+	// 进度。变体由流水线异步生成，Queued 只表示已提交，转换结果以各图片的变体状态为准
+	Total        int64      `json:"total"`
+	Queued       int64      `json:"queued"`        // 已提交流水线的图片数
+	SubmitFailed int64      `json:"submit_failed"` // 提交失败的图片数（读取设置、清理变体或入队失败）
+	Purged       int64      `json:"purged"`        // 清理的过期变体数
+	LastImageID  uint       `json:"last_image_id"` // 游标，按图片 ID 递增处理
+	ErrorMessage string     `gorm:"type:text" json:"error_message,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (ReprocessJob) TableName() string {
+	return "reprocess_jobs"
+}
+
+// FormatList 返回需要重新生成的变体格式，空表示全部
+func (j *ReprocessJob) FormatList() []string {
+	if j.Formats == "" {
+		return nil
+	}
+	return strings.Split(j.Formats, ",")
+}
+
+// IsFinished 任务是否已进入终态
+func (j *ReprocessJob) IsFinished() bool {
+	switch j.Status {
+	case ReprocessJobStatusCancelled, ReprocessJobStatusCompleted, ReprocessJobStatusFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TagNameMaxLength 标签名最大长度
+const TagNameMaxLength = 50
+
+// Tag 用户自定义的图片标签，同一用户下名称唯一
+type Tag struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_tag_user_name,priority:1"`
+	Name      string `gorm:"type:varchar(50);not null;uniqueIndex:idx_tag_user_name,priority:2"`
+
+	Images []*Image `gorm:"many2many:image_tags;"`
+}
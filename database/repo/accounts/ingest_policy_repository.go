@@ -0,0 +1,43 @@
+package accounts
+
+import (
+	"errors"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetIngestPolicy 获取用户级上传策略，未设置时返回 nil
+func (r *Repository) GetIngestPolicy(userID uint) (*models.UserIngestPolicy, error) {
+	var policy models.UserIngestPolicy
+	err := r.db.Where("user_id = ?", userID).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SaveIngestPolicy 创建或覆盖用户级上传策略
+func (r *Repository) SaveIngestPolicy(userID uint, policy models.IngestPolicy) (*models.UserIngestPolicy, error) {
+	record := &models.UserIngestPolicy{
+		UserID:       userID,
+		IngestPolicy: policy,
+	}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_dimension", "optimize", "convert_format", "quality", "updated_at"}),
+	}).Create(record).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetIngestPolicy(userID)
+}
+
+// DeleteIngestPolicy 删除用户级上传策略，恢复使用全局配置
+func (r *Repository) DeleteIngestPolicy(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserIngestPolicy{}).Error
+}
@@ -0,0 +1,53 @@
+package accounts
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupAccountsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.UserIngestPolicy{}))
+	return db
+}
+
+func TestIngestPolicyLifecycle(t *testing.T) {
+	repo := NewRepository(setupAccountsTestDB(t))
+
+	policy, err := repo.GetIngestPolicy(7)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	saved, err := repo.SaveIngestPolicy(7, models.IngestPolicy{MaxDimension: 4096, Optimize: true})
+	require.NoError(t, err)
+	assert.Equal(t, models.IngestPolicy{MaxDimension: 4096, Optimize: true}, saved.IngestPolicy)
+
+	updated, err := repo.SaveIngestPolicy(7, models.IngestPolicy{ConvertFormat: models.FormatWebP, Quality: 85})
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, updated.ID, "saving again overwrites the existing policy")
+	assert.Equal(t, models.IngestPolicy{ConvertFormat: models.FormatWebP, Quality: 85}, updated.IngestPolicy)
+
+	_, err = repo.SaveIngestPolicy(8, models.IngestPolicy{Optimize: true})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteIngestPolicy(7))
+	policy, err = repo.GetIngestPolicy(7)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	other, err := repo.GetIngestPolicy(8)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	assert.True(t, other.Optimize)
+}
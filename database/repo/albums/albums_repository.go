@@ -5,12 +5,19 @@ import (
 	"errors"
 
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-// ErrAlbumNotFound 相册未找到或无权限
-var ErrAlbumNotFound = errors.New("album not found or access denied")
+var (
+	// ErrAlbumNotFound 相册未找到或无权限
+	ErrAlbumNotFound = errors.New("album not found or access denied")
+	// ErrInvalidAlbumParent 父相册不存在，或移动后会形成循环
+	ErrInvalidAlbumParent = errors.New("parent album not found or would create a cycle")
+	// ErrImageNotInAlbum 图片不在相册中
+	ErrImageNotInAlbum = errors.New("image is not in the album")
+)
 
 // Repository 相册仓库
 type Repository struct {
@@ -29,12 +36,26 @@ type AlbumInfo struct {
 	CoverURL   string
 }
 
-// GetUserAlbums 获取用户相册列表
+// GetUserAlbums 获取用户相册列表（包含所有层级）
 func (r *Repository) GetUserAlbums(userID uint, page, pageSize int) ([]*AlbumInfo, int64, error) {
+	return r.listAlbums(r.db.Model(&models.Album{}).Where("user_id = ?", userID), page, pageSize)
+}
+
+// GetChildAlbums 获取指定相册的直接子相册，parentID 为 0 时返回顶层相册
+func (r *Repository) GetChildAlbums(userID, parentID uint, page, pageSize int) ([]*AlbumInfo, int64, error) {
+	db := r.db.Model(&models.Album{}).Where("user_id = ?", userID)
+	if parentID == 0 {
+		db = db.Where("parent_id IS NULL")
+	} else {
+		db = db.Where("parent_id = ?", parentID)
+	}
+	return r.listAlbums(db, page, pageSize)
+}
+
+func (r *Repository) listAlbums(db *gorm.DB, page, pageSize int) ([]*AlbumInfo, int64, error) {
 	var albums []*models.Album
 	var total int64
 
-	db := r.db.Model(&models.Album{}).Where("user_id = ?", userID)
 	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
@@ -48,8 +69,14 @@ func (r *Repository) GetUserAlbums(userID uint, page, pageSize int) ([]*AlbumInf
 		return nil, 0, err
 	}
 
+	result, err := r.albumInfos(albums)
+	return result, total, err
+}
+
+// albumInfos 补充图片数量和封面（不含回收站中的图片），封面优先使用手动设置的图片，否则取最新的图片
+func (r *Repository) albumInfos(albums []*models.Album) ([]*AlbumInfo, error) {
 	if len(albums) == 0 {
-		return []*AlbumInfo{}, total, nil
+		return []*AlbumInfo{}, nil
 	}
 
 	albumIDs := make([]uint, len(albums))
@@ -64,14 +91,16 @@ func (r *Repository) GetUserAlbums(userID uint, page, pageSize int) ([]*AlbumInf
 		Cover   string
 	}
 	subQuery := r.db.Table("album_images ai").
-		Select("ai.album_id, COUNT(*) OVER (PARTITION BY ai.album_id) AS count, i.identifier AS cover, ROW_NUMBER() OVER (PARTITION BY ai.album_id ORDER BY i.created_at DESC) AS rn").
-		Joins("JOIN images i ON ai.image_id = i.id").
+		Select("ai.album_id, COUNT(*) OVER (PARTITION BY ai.album_id) AS count, i.identifier AS cover, "+
+			"ROW_NUMBER() OVER (PARTITION BY ai.album_id ORDER BY CASE WHEN i.id = a.cover_image_id THEN 0 ELSE 1 END, i.created_at DESC) AS rn").
+		Joins("JOIN images i ON ai.image_id = i.id AND i.deleted_at IS NULL").
+		Joins("JOIN albums a ON ai.album_id = a.id").
 		Where("ai.album_id IN ?", albumIDs)
 	if err := r.db.Table("(?) AS sub", subQuery).
 		Select("album_id, count, cover").
 		Where("rn = 1").
 		Scan(&coverCounts).Error; err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	countMap := make(map[uint]int64, len(coverCounts))
@@ -90,16 +119,60 @@ func (r *Repository) GetUserAlbums(userID uint, page, pageSize int) ([]*AlbumInf
 		}
 	}
 
-	return result, total, nil
+	return result, nil
 }
 
-// GetAlbumWithImagesByID 获取相册及其图片
+// GetAlbumWithImagesByID 获取相册及其图片，图片按相册的排序方式排列
 func (r *Repository) GetAlbumWithImagesByID(albumID, userID uint) (*models.Album, error) {
 	var album models.Album
-	err := r.db.Preload("Images").First(&album, "id = ? AND user_id = ?", albumID, userID).Error
+	if err := r.db.First(&album, "id = ? AND user_id = ?", albumID, userID).Error; err != nil {
+		return &album, err
+	}
+	err := r.db.Select("images.*").
+		Joins("JOIN album_images ON album_images.image_id = images.id").
+		Where("album_images.album_id = ?", album.ID).
+		Order(albumImageOrder(album.SortMode)).
+		Find(&album.Images).Error
 	return &album, err
 }
 
+// albumImageOrder 返回排序方式对应的 ORDER BY 子句
+func albumImageOrder(mode string) string {
+	switch mode {
+	case models.AlbumSortAddedAsc:
+		return "album_images.created_at ASC, album_images.image_id ASC"
+	case models.AlbumSortAddedDesc:
+		return "album_images.created_at DESC, album_images.image_id DESC"
+	case models.AlbumSortCreatedAsc:
+		return "images.created_at ASC, images.id ASC"
+	case models.AlbumSortCreatedDesc:
+		return "images.created_at DESC, images.id DESC"
+	case models.AlbumSortNameAsc:
+		return "images.original_name ASC, images.id ASC"
+	case models.AlbumSortNameDesc:
+		return "images.original_name DESC, images.id DESC"
+	default:
+		return "album_images.position ASC, album_images.image_id ASC"
+	}
+}
+
+// nextImagePosition 返回相册末尾的下一个手动排序位置
+func nextImagePosition(tx *gorm.DB, albumID uint) (int, error) {
+	var next int
+	err := tx.Model(&models.AlbumImage{}).
+		Where("album_id = ?", albumID).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Scan(&next).Error
+	return next, err
+}
+
+// clearRemovedCover 封面图片被移出相册时清除封面设置
+func clearRemovedCover(tx *gorm.DB, albumID uint, imageIDs []uint) error {
+	return tx.Model(&models.Album{}).
+		Where("id = ? AND cover_image_id IN ?", albumID, imageIDs).
+		Update("cover_image_id", nil).Error
+}
+
 // AddImageToAlbum 添加图片到相册
 func (r *Repository) AddImageToAlbum(albumID, userID uint, image *models.Image) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
@@ -110,7 +183,15 @@ func (r *Repository) AddImageToAlbum(albumID, userID uint, image *models.Image)
 			}
 			return err
 		}
-		return tx.Model(&album).Association("Images").Append(image)
+		position, err := nextImagePosition(tx, album.ID)
+		if err != nil {
+			return err
+		}
+		link := models.AlbumImage{AlbumID: album.ID, ImageID: image.ID, Position: position}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, []uint{image.ID})
 	})
 }
 
@@ -124,7 +205,13 @@ func (r *Repository) RemoveImageFromAlbum(albumID, userID uint, image *models.Im
 			}
 			return err
 		}
-		return tx.Model(&album).Association("Images").Delete(image)
+		if err := tx.Model(&album).Association("Images").Delete(image); err != nil {
+			return err
+		}
+		if err := clearRemovedCover(tx, album.ID, []uint{image.ID}); err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, []uint{image.ID})
 	})
 }
 
@@ -165,33 +252,52 @@ func (r *Repository) AddImagesToAlbum(albumID, userID uint, imageIDs []uint) (in
 			existing[id] = struct{}{}
 		}
 
-		associations := make([]map[string]any, 0, len(uniqueIDs))
+		position, err := nextImagePosition(tx, albumID)
+		if err != nil {
+			return err
+		}
+
+		associations := make([]models.AlbumImage, 0, len(uniqueIDs))
 		for _, id := range uniqueIDs {
 			if _, ok := existing[id]; ok {
 				continue
 			}
-			associations = append(associations, map[string]any{
-				"album_id": albumID,
-				"image_id": id,
+			associations = append(associations, models.AlbumImage{
+				AlbumID:  albumID,
+				ImageID:  id,
+				Position: position,
 			})
+			position++
 		}
 
 		if len(associations) == 0 {
 			return nil
 		}
 
-		if err := tx.Table("album_images").Create(associations).Error; err != nil {
+		if err := tx.Create(&associations).Error; err != nil {
 			return err
 		}
 		insertedCount = int64(len(associations))
-		return nil
+		return fulltext.Refresh(tx, uniqueIDs)
 	})
 	return insertedCount, err
 }
 
-// CreateAlbum 创建相册
+// CreateAlbum 创建相册，指定父相册时校验其属于同一用户
 func (r *Repository) CreateAlbum(album *models.Album) error {
-	return r.db.Create(album).Error
+	if album.ParentID == nil {
+		return r.db.Create(album).Error
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Album{}).Where("id = ? AND user_id = ?", *album.ParentID, album.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrInvalidAlbumParent
+		}
+		return tx.Create(album).Error
+	})
 }
 
 // DeleteAlbum 删除相册
@@ -205,10 +311,24 @@ func (r *Repository) DeleteAlbum(albumID, userID uint) error {
 			return err
 		}
 
+		var imageIDs []uint
+		if err := tx.Table("album_images").Where("album_id = ?", album.ID).Pluck("image_id", &imageIDs).Error; err != nil {
+			return err
+		}
 		if err := tx.Model(&album).Association("Images").Clear(); err != nil {
 			return err
 		}
-		return tx.Delete(&album).Error
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumShare{}).Error; err != nil {
+			return err
+		}
+		// 子相册上移一级，不随父相册删除
+		if err := tx.Model(&models.Album{}).Where("parent_id = ?", album.ID).Update("parent_id", album.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&album).Error; err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, imageIDs)
 	})
 }
 
@@ -226,10 +346,17 @@ func (r *Repository) AlbumExists(albumID uint) (bool, error) {
 	return count > 0, err
 }
 
-// UpdateAlbum 更新相册指定字段
+// UpdateAlbum 更新相册指定字段，改名时刷新相册内图片的搜索索引
 func (r *Repository) UpdateAlbum(albumID uint, updates map[string]any) error {
-	result := r.db.Model(&models.Album{}).Where("id = ?", albumID).Updates(updates)
-	return result.Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Album{}).Where("id = ?", albumID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if _, renamed := updates["name"]; !renamed {
+			return nil
+		}
+		return fulltext.RefreshAlbum(tx, albumID)
+	})
 }
 
 // RemoveImagesFromAlbum 批量从相册移除图片
@@ -257,7 +384,10 @@ func (r *Repository) RemoveImagesFromAlbum(albumID, userID uint, imageIDs []uint
 		}
 
 		result = res.RowsAffected
-		return nil
+		if err := clearRemovedCover(tx, albumID, imageIDs); err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, imageIDs)
 	})
 
 	return result, err
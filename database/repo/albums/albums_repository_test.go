@@ -18,7 +18,8 @@ func setupAlbumsRepositoryTestDB(t *testing.T) *gorm.DB {
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Album{}, &models.Image{}))
+	require.NoError(t, models.SetupJoinTables(db))
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Album{}, &models.Image{}, &models.AlbumShare{}))
 	return db
 }
 
@@ -0,0 +1,100 @@
+package albums
+
+import (
+	"errors"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockAlbum 加锁读取用户的相册
+func lockAlbum(tx *gorm.DB, albumID, userID uint) (*models.Album, error) {
+	var album models.Album
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&album, "id = ? AND user_id = ?", albumID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlbumNotFound
+		}
+		return nil, err
+	}
+	return &album, nil
+}
+
+// ReorderImages 按给定顺序重排相册图片，未列出的图片保持原有相对顺序排在其后，并将相册切换为手动排序
+func (r *Repository) ReorderImages(albumID, userID uint, imageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		album, err := lockAlbum(tx, albumID, userID)
+		if err != nil {
+			return err
+		}
+
+		var current []models.AlbumImage
+		if err := tx.Select("image_id", "position").
+			Where("album_id = ?", album.ID).
+			Order("position ASC, image_id ASC").
+			Find(&current).Error; err != nil {
+			return err
+		}
+
+		positions := make(map[uint]int, len(current))
+		for _, link := range current {
+			positions[link.ImageID] = link.Position
+		}
+
+		order := make([]uint, 0, len(current))
+		placed := make(map[uint]struct{}, len(imageIDs))
+		for _, id := range imageIDs {
+			if _, ok := positions[id]; !ok {
+				return ErrImageNotInAlbum
+			}
+			if _, ok := placed[id]; ok {
+				continue
+			}
+			placed[id] = struct{}{}
+			order = append(order, id)
+		}
+		for _, link := range current {
+			if _, ok := placed[link.ImageID]; !ok {
+				order = append(order, link.ImageID)
+			}
+		}
+
+		for i, id := range order {
+			if positions[id] == i {
+				continue
+			}
+			if err := tx.Model(&models.AlbumImage{}).
+				Where("album_id = ? AND image_id = ?", album.ID, id).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+
+		if album.SortMode == models.AlbumSortManual {
+			return nil
+		}
+		return tx.Model(album).Update("sort_mode", models.AlbumSortManual).Error
+	})
+}
+
+// SetCover 设置相册封面，imageID 为空时恢复为默认封面（最新的图片）
+func (r *Repository) SetCover(albumID, userID uint, imageID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		album, err := lockAlbum(tx, albumID, userID)
+		if err != nil {
+			return err
+		}
+		if imageID != nil {
+			var count int64
+			if err := tx.Model(&models.AlbumImage{}).
+				Where("album_id = ? AND image_id = ?", album.ID, *imageID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrImageNotInAlbum
+			}
+		}
+		return tx.Model(album).Update("cover_image_id", imageID).Error
+	})
+}
@@ -0,0 +1,100 @@
+package albums
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createAlbumWithImages(t *testing.T, db *gorm.DB, repo *Repository, n int) (*models.Album, []uint) {
+	t.Helper()
+
+	album := &models.Album{UserID: 1, Name: "shoot"}
+	require.NoError(t, repo.CreateAlbum(album))
+
+	ids := make([]uint, n)
+	for i := range n {
+		image := &models.Image{
+			Identifier:   fmt.Sprintf("img-%d", i),
+			OriginalName: fmt.Sprintf("%c.jpg", 'z'-i),
+			FileHash:     fmt.Sprintf("hash-%d", i),
+			UserID:       1,
+		}
+		require.NoError(t, db.Create(image).Error)
+		ids[i] = image.ID
+	}
+	_, err := repo.AddImagesToAlbum(album.ID, 1, ids)
+	require.NoError(t, err)
+	return album, ids
+}
+
+func albumImageIDs(t *testing.T, repo *Repository, albumID uint) []uint {
+	t.Helper()
+
+	album, err := repo.GetAlbumWithImagesByID(albumID, 1)
+	require.NoError(t, err)
+	ids := make([]uint, len(album.Images))
+	for i, img := range album.Images {
+		ids[i] = img.ID
+	}
+	return ids
+}
+
+func TestReorderImages(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	album, ids := createAlbumWithImages(t, db, repo, 4)
+
+	assert.Equal(t, ids, albumImageIDs(t, repo, album.ID))
+
+	// 未列出的图片保持原有相对顺序排在后面
+	require.NoError(t, repo.ReorderImages(album.ID, 1, []uint{ids[3], ids[1]}))
+	assert.Equal(t, []uint{ids[3], ids[1], ids[0], ids[2]}, albumImageIDs(t, repo, album.ID))
+
+	err := repo.ReorderImages(album.ID, 1, []uint{9999})
+	assert.ErrorIs(t, err, ErrImageNotInAlbum)
+	err = repo.ReorderImages(album.ID, 2, []uint{ids[0]})
+	assert.ErrorIs(t, err, ErrAlbumNotFound)
+}
+
+func TestReorderSwitchesToManualSort(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	album, ids := createAlbumWithImages(t, db, repo, 3)
+
+	require.NoError(t, repo.UpdateAlbum(album.ID, map[string]any{"sort_mode": models.AlbumSortNameAsc}))
+	assert.Equal(t, []uint{ids[2], ids[1], ids[0]}, albumImageIDs(t, repo, album.ID))
+
+	require.NoError(t, repo.ReorderImages(album.ID, 1, []uint{ids[1]}))
+	stored, err := repo.GetAlbumByID(album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.AlbumSortManual, stored.SortMode)
+	assert.Equal(t, []uint{ids[1], ids[0], ids[2]}, albumImageIDs(t, repo, album.ID))
+}
+
+func TestSetCover(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	album, ids := createAlbumWithImages(t, db, repo, 2)
+
+	require.NoError(t, repo.SetCover(album.ID, 1, &ids[0]))
+	infos, _, err := repo.GetUserAlbums(1, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "img-0", infos[0].CoverURL)
+
+	outsider := &models.Image{Identifier: "other", OriginalName: "o.jpg", FileHash: "hash-other", UserID: 1}
+	require.NoError(t, db.Create(outsider).Error)
+	assert.ErrorIs(t, repo.SetCover(album.ID, 1, &outsider.ID), ErrImageNotInAlbum)
+
+	// 封面被移出相册后自动清除
+	_, err = repo.RemoveImagesFromAlbum(album.ID, 1, []uint{ids[0]})
+	require.NoError(t, err)
+	stored, err := repo.GetAlbumByID(album.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.CoverImageID)
+}
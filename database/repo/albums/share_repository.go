@@ -0,0 +1,101 @@
+package albums
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+)
+
+// ErrShareNotFound 分享链接不存在或无权限
+var ErrShareNotFound = errors.New("share link not found")
+
+// ShareRepository 相册分享链接仓库
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+// NewShareRepository 创建分享链接仓库
+func NewShareRepository(db *gorm.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+// WithContext 返回带上下文的仓库
+func (r *ShareRepository) WithContext(ctx context.Context) *ShareRepository {
+	return &ShareRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateShare 创建分享链接
+func (r *ShareRepository) CreateShare(share *models.AlbumShare) error {
+	return r.db.Create(share).Error
+}
+
+// ListShares 获取相册的全部分享链接，最新创建的在前
+func (r *ShareRepository) ListShares(albumID, userID uint) ([]*models.AlbumShare, error) {
+	var shares []*models.AlbumShare
+	err := r.db.Where("album_id = ? AND user_id = ?", albumID, userID).
+		Order("created_at desc").
+		Find(&shares).Error
+	return shares, err
+}
+
+// GetShareByToken 通过令牌获取分享链接
+func (r *ShareRepository) GetShareByToken(token string) (*models.AlbumShare, error) {
+	var share models.AlbumShare
+	if err := r.db.Where("token = ?", token).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// RevokeShare 撤销分享链接，已撤销的链接保持原撤销时间
+func (r *ShareRepository) RevokeShare(shareID, albumID, userID uint) (*models.AlbumShare, error) {
+	var share models.AlbumShare
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&share, "id = ? AND album_id = ? AND user_id = ?", shareID, albumID, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShareNotFound
+			}
+			return err
+		}
+		if share.RevokedAt != nil {
+			return nil
+		}
+		now := time.Now()
+		share.RevokedAt = &now
+		return tx.Model(&share).Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// RecordView 在未达到访问次数上限时原子地增加一次访问，达到上限返回 false
+func (r *ShareRepository) RecordView(shareID uint) (bool, error) {
+	res := r.db.Model(&models.AlbumShare{}).
+		Where("id = ? AND (max_views = 0 OR view_count < max_views)", shareID).
+		UpdateColumns(map[string]any{
+			"view_count":     gorm.Expr("view_count + 1"),
+			"last_viewed_at": time.Now(),
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// GetSharedImage 获取相册中指定标识符的图片，图片不在相册中时返回 gorm.ErrRecordNotFound
+func (r *ShareRepository) GetSharedImage(albumID uint, identifier string) (*models.Image, error) {
+	var image models.Image
+	err := r.db.Model(&models.Image{}).
+		Joins("JOIN album_images ON album_images.image_id = images.id").
+		Where("album_images.album_id = ? AND images.identifier = ?", albumID, identifier).
+		First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
@@ -0,0 +1,112 @@
+package albums
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func createSharedAlbum(t *testing.T, db *gorm.DB) (*models.Album, *models.Image) {
+	t.Helper()
+
+	album := &models.Album{UserID: 1, Name: "clients"}
+	require.NoError(t, db.Create(album).Error)
+	image := &models.Image{
+		Identifier:   "shared-1",
+		OriginalName: "screenshot.png",
+		FileHash:     "hash-shared-1",
+		MimeType:     "image/png",
+		StoragePath:  "original/screenshot.png",
+		UserID:       1,
+	}
+	require.NoError(t, db.Create(image).Error)
+	require.NoError(t, db.Table("album_images").Create(map[string]any{"album_id": album.ID, "image_id": image.ID}).Error)
+	return album, image
+}
+
+func TestRecordViewStopsAtLimit(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewShareRepository(db)
+	album, _ := createSharedAlbum(t, db)
+
+	share := &models.AlbumShare{AlbumID: album.ID, UserID: 1, Token: "limited", MaxViews: 2}
+	require.NoError(t, repo.CreateShare(share))
+
+	for range 2 {
+		recorded, err := repo.RecordView(share.ID)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+	}
+	recorded, err := repo.RecordView(share.ID)
+	require.NoError(t, err)
+	assert.False(t, recorded)
+
+	stored, err := repo.GetShareByToken("limited")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.ViewCount)
+}
+
+func TestRecordViewUnlimited(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewShareRepository(db)
+	album, _ := createSharedAlbum(t, db)
+
+	share := &models.AlbumShare{AlbumID: album.ID, UserID: 1, Token: "unlimited"}
+	require.NoError(t, repo.CreateShare(share))
+	for range 3 {
+		recorded, err := repo.RecordView(share.ID)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+	}
+}
+
+func TestGetSharedImageRequiresAlbumMembership(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewShareRepository(db)
+	album, image := createSharedAlbum(t, db)
+
+	other := &models.Image{Identifier: "private-2", OriginalName: "secret.png", FileHash: "hash-private-2", UserID: 1}
+	require.NoError(t, db.Create(other).Error)
+
+	found, err := repo.GetSharedImage(album.ID, image.Identifier)
+	require.NoError(t, err)
+	assert.Equal(t, image.ID, found.ID)
+
+	_, err = repo.GetSharedImage(album.ID, other.Identifier)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRevokeShareChecksOwnership(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewShareRepository(db)
+	album, _ := createSharedAlbum(t, db)
+
+	share := &models.AlbumShare{AlbumID: album.ID, UserID: 1, Token: "revocable"}
+	require.NoError(t, repo.CreateShare(share))
+
+	_, err := repo.RevokeShare(share.ID, album.ID, 2)
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	revoked, err := repo.RevokeShare(share.ID, album.ID, 1)
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+
+	stored, err := repo.GetShareByToken("revocable")
+	require.NoError(t, err)
+	assert.True(t, stored.IsRevoked())
+}
+
+func TestDeleteAlbumRemovesShares(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	album, _ := createSharedAlbum(t, db)
+	shares := NewShareRepository(db)
+	require.NoError(t, shares.CreateShare(&models.AlbumShare{AlbumID: album.ID, UserID: 1, Token: "gone"}))
+
+	require.NoError(t, NewRepository(db).DeleteAlbum(album.ID, 1))
+
+	_, err := shares.GetShareByToken("gone")
+	assert.ErrorIs(t, err, ErrShareNotFound)
+}
@@ -0,0 +1,155 @@
+package albums
+
+import (
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AlbumNode 相册树节点
+type AlbumNode struct {
+	*AlbumInfo
+	Children []*AlbumNode
+}
+
+// GetAlbumTree 获取用户的完整相册树，返回顶层相册
+func (r *Repository) GetAlbumTree(userID uint) ([]*AlbumNode, error) {
+	roots, _, err := r.buildAlbumTree(userID)
+	return roots, err
+}
+
+// GetAlbumSubtree 获取以指定相册为根的子树
+func (r *Repository) GetAlbumSubtree(albumID, userID uint) (*AlbumNode, error) {
+	_, nodes, err := r.buildAlbumTree(userID)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[albumID]
+	if !ok {
+		return nil, ErrAlbumNotFound
+	}
+	return node, nil
+}
+
+// buildAlbumTree 一次性加载用户的全部相册并组装成树，同级按名称排序
+func (r *Repository) buildAlbumTree(userID uint) ([]*AlbumNode, map[uint]*AlbumNode, error) {
+	var albums []*models.Album
+	if err := r.db.Where("user_id = ?", userID).Order("name ASC, id ASC").Find(&albums).Error; err != nil {
+		return nil, nil, err
+	}
+
+	infos, err := r.albumInfos(albums)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes := make(map[uint]*AlbumNode, len(infos))
+	for _, info := range infos {
+		nodes[info.Album.ID] = &AlbumNode{AlbumInfo: info, Children: []*AlbumNode{}}
+	}
+
+	// 并发移动等异常数据可能让相册互为祖先，这些相册作为顶层相册展示，避免从树中消失
+	cyclic := cyclicAlbums(infos)
+
+	roots := make([]*AlbumNode, 0)
+	for _, info := range infos {
+		node := nodes[info.Album.ID]
+		if info.Album.ParentID != nil && !cyclic[info.Album.ID] {
+			if parent, ok := nodes[*info.Album.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		// 父相册不存在时作为顶层相册展示
+		roots = append(roots, node)
+	}
+	return roots, nodes, nil
+}
+
+// cyclicAlbums 找出父相册链上形成循环的相册
+func cyclicAlbums(infos []*AlbumInfo) map[uint]bool {
+	parents := make(map[uint]uint, len(infos))
+	for _, info := range infos {
+		if info.Album.ParentID != nil {
+			parents[info.Album.ID] = *info.Album.ParentID
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[uint]int, len(infos))
+	cyclic := make(map[uint]bool)
+	for _, info := range infos {
+		var path []uint
+		current := info.Album.ID
+		for state[current] == 0 {
+			state[current] = visiting
+			path = append(path, current)
+			parent, ok := parents[current]
+			if !ok {
+				break
+			}
+			current = parent
+		}
+		// 回到本轮路径上的相册，说明从该相册起的路径构成循环
+		if state[current] == visiting {
+			for i := len(path) - 1; i >= 0; i-- {
+				cyclic[path[i]] = true
+				if path[i] == current {
+					break
+				}
+			}
+		}
+		for _, id := range path {
+			state[id] = visited
+		}
+	}
+	return cyclic
+}
+
+// MoveAlbum 移动相册到新的父相册下，parentID 为空时移动到顶层
+func (r *Repository) MoveAlbum(albumID, userID uint, parentID *uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 按 ID 顺序锁定用户的全部相册，串行化同一用户的移动，
+		// 避免两个并发移动各自通过祖先检查后形成循环
+		var albums []models.Album
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "parent_id").
+			Where("user_id = ?", userID).
+			Order("id ASC").
+			Find(&albums).Error; err != nil {
+			return err
+		}
+		parents := make(map[uint]*uint, len(albums))
+		for _, album := range albums {
+			parents[album.ID] = album.ParentID
+		}
+		if _, ok := parents[albumID]; !ok {
+			return ErrAlbumNotFound
+		}
+
+		// 从新父相册向上遍历祖先，遇到自身说明会形成循环
+		if parentID != nil {
+			visited := make(map[uint]struct{})
+			for current := parentID; current != nil; {
+				if *current == albumID {
+					return ErrInvalidAlbumParent
+				}
+				if _, ok := visited[*current]; ok {
+					return ErrInvalidAlbumParent
+				}
+				visited[*current] = struct{}{}
+
+				parent, ok := parents[*current]
+				if !ok {
+					return ErrInvalidAlbumParent
+				}
+				current = parent
+			}
+		}
+
+		return tx.Model(&models.Album{}).Where("id = ? AND user_id = ?", albumID, userID).Update("parent_id", parentID).Error
+	})
+}
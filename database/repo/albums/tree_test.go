@@ -0,0 +1,103 @@
+package albums
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlbumTreeAndMove(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+
+	client := &models.Album{UserID: 1, Name: "Client"}
+	require.NoError(t, repo.CreateAlbum(client))
+	event := &models.Album{UserID: 1, Name: "Event", ParentID: &client.ID}
+	require.NoError(t, repo.CreateAlbum(event))
+	selects := &models.Album{UserID: 1, Name: "Selects", ParentID: &event.ID}
+	require.NoError(t, repo.CreateAlbum(selects))
+
+	roots, err := repo.GetAlbumTree(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	require.Len(t, roots[0].Children, 1)
+	require.Len(t, roots[0].Children[0].Children, 1)
+	assert.Equal(t, "Selects", roots[0].Children[0].Children[0].Album.Name)
+
+	// 不能移动到自身或子孙相册下
+	assert.ErrorIs(t, repo.MoveAlbum(client.ID, 1, &client.ID), ErrInvalidAlbumParent)
+	assert.ErrorIs(t, repo.MoveAlbum(client.ID, 1, &selects.ID), ErrInvalidAlbumParent)
+
+	require.NoError(t, repo.MoveAlbum(selects.ID, 1, nil))
+	children, total, err := repo.GetChildAlbums(1, 0, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, children, 2)
+
+	subtree, err := repo.GetAlbumSubtree(event.ID, 1)
+	require.NoError(t, err)
+	assert.Empty(t, subtree.Children)
+}
+
+func TestAlbumTreeShowsCycleMembersAsRoots(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+
+	a := &models.Album{UserID: 1, Name: "A"}
+	require.NoError(t, repo.CreateAlbum(a))
+	b := &models.Album{UserID: 1, Name: "B", ParentID: &a.ID}
+	require.NoError(t, repo.CreateAlbum(b))
+	c := &models.Album{UserID: 1, Name: "C", ParentID: &b.ID}
+	require.NoError(t, repo.CreateAlbum(c))
+	// 模拟并发移动留下的循环：A 与 B 互为父相册
+	require.NoError(t, db.Model(&models.Album{}).Where("id = ?", a.ID).Update("parent_id", b.ID).Error)
+
+	roots, err := repo.GetAlbumTree(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 2)
+	assert.Equal(t, "A", roots[0].Album.Name)
+	assert.Equal(t, "B", roots[1].Album.Name)
+	assert.Empty(t, roots[0].Children)
+	require.Len(t, roots[1].Children, 1)
+	assert.Equal(t, "C", roots[1].Children[0].Album.Name)
+
+	// 仍可把循环中的相册移回顶层
+	require.NoError(t, repo.MoveAlbum(a.ID, 1, nil))
+	roots, err = repo.GetAlbumTree(1)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, "A", roots[0].Album.Name)
+}
+
+func TestCreateAlbumRejectsForeignParent(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+
+	parent := &models.Album{UserID: 2, Name: "someone else"}
+	require.NoError(t, repo.CreateAlbum(parent))
+
+	err := repo.CreateAlbum(&models.Album{UserID: 1, Name: "child", ParentID: &parent.ID})
+	assert.ErrorIs(t, err, ErrInvalidAlbumParent)
+	assert.ErrorIs(t, repo.MoveAlbum(parent.ID, 1, nil), ErrAlbumNotFound)
+}
+
+func TestDeleteAlbumReparentsChildren(t *testing.T) {
+	db := setupAlbumsRepositoryTestDB(t)
+	repo := NewRepository(db)
+
+	client := &models.Album{UserID: 1, Name: "Client"}
+	require.NoError(t, repo.CreateAlbum(client))
+	event := &models.Album{UserID: 1, Name: "Event", ParentID: &client.ID}
+	require.NoError(t, repo.CreateAlbum(event))
+	selects := &models.Album{UserID: 1, Name: "Selects", ParentID: &event.ID}
+	require.NoError(t, repo.CreateAlbum(selects))
+
+	require.NoError(t, repo.DeleteAlbum(event.ID, 1))
+
+	stored, err := repo.GetAlbumByID(selects.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.ParentID)
+	assert.Equal(t, client.ID, *stored.ParentID)
+}
@@ -0,0 +1,184 @@
+package dashboard
+
+import (
+	"context"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 图片访问统计排序字段
+const (
+	ImageStatSortHits         = "hits"
+	ImageStatSortBytes        = "bytes"
+	ImageStatSortHotlinkHits  = "hotlink_hits"
+	ImageStatSortHotlinkBytes = "hotlink_bytes"
+)
+
+// 图片访问统计的分组维度
+const (
+	ImageStatByVariant  = "variant"
+	ImageStatByReferrer = "referrer"
+)
+
+// imageStatTotalsSelect 访问量、流量及其中外链（Referrer 非空）部分的汇总
+const imageStatTotalsSelect = "SUM(s.hits) AS hits, SUM(s.bytes) AS bytes, " +
+	"SUM(CASE WHEN s.referrer <> '' THEN s.hits ELSE 0 END) AS hotlink_hits, " +
+	"SUM(CASE WHEN s.referrer <> '' THEN s.bytes ELSE 0 END) AS hotlink_bytes"
+
+// ImageStatTotals 访问汇总
+type ImageStatTotals struct {
+	Hits         int64
+	Bytes        int64
+	HotlinkHits  int64
+	HotlinkBytes int64
+}
+
+// ImageStatRank 单张图片的访问汇总
+type ImageStatRank struct {
+	ImageID      uint
+	UserID       uint
+	Identifier   string
+	OriginalName string
+	ImageStatTotals
+}
+
+// UserStatTotal 单个用户所有图片的访问汇总
+type UserStatTotal struct {
+	UserID   uint
+	Username string
+	Images   int64
+	ImageStatTotals
+}
+
+// ImageStatPoint 某个时间段的访问量
+type ImageStatPoint struct {
+	Bucket time.Time
+	Hits   int64
+	Bytes  int64
+}
+
+// ImageStatGroup 按变体或来源分组的访问量
+type ImageStatGroup struct {
+	Name  string
+	Hits  int64
+	Bytes int64
+}
+
+// AddImageStats 把一批按小时聚合的访问累加到统计表
+func (r *Repository) AddImageStats(ctx context.Context, stats []models.ImageStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "image_id"}, {Name: "bucket"}, {Name: "variant"}, {Name: "referrer"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"hits":  gorm.Expr("image_stats.hits + excluded.hits"),
+			"bytes": gorm.Expr("image_stats.bytes + excluded.bytes"),
+		}),
+	}).CreateInBatches(&stats, 500).Error
+}
+
+// GetTopImages 获取 since 之后访问最多的图片，userID 为 0 时统计所有用户，已彻底删除的图片不计入
+func (r *Repository) GetTopImages(ctx context.Context, userID uint, since time.Time, sort string, limit int) ([]ImageStatRank, error) {
+	orderBy := ImageStatSortHits
+	switch sort {
+	case ImageStatSortBytes, ImageStatSortHotlinkHits, ImageStatSortHotlinkBytes:
+		orderBy = sort
+	}
+
+	var ranks []ImageStatRank
+	query := r.db.WithContext(ctx).Table("image_stats s").
+		Select("s.image_id, s.user_id, i.identifier, i.original_name, "+imageStatTotalsSelect).
+		Joins("JOIN images i ON i.id = s.image_id").
+		Where("s.bucket >= ?", since)
+	if userID > 0 {
+		query = query.Where("s.user_id = ?", userID)
+	}
+	err := query.Group("s.image_id, s.user_id, i.identifier, i.original_name").
+		Order(orderBy + " DESC, s.image_id ASC").
+		Limit(limit).
+		Scan(&ranks).Error
+	return ranks, err
+}
+
+// GetImageStatTotals 获取单张图片 since 之后的访问汇总
+func (r *Repository) GetImageStatTotals(ctx context.Context, imageID uint, since time.Time) (*ImageStatTotals, error) {
+	var totals ImageStatTotals
+	err := r.db.WithContext(ctx).Table("image_stats s").
+		Select("COALESCE(SUM(s.hits), 0) AS hits, COALESCE(SUM(s.bytes), 0) AS bytes, "+
+			"COALESCE(SUM(CASE WHEN s.referrer <> '' THEN s.hits ELSE 0 END), 0) AS hotlink_hits, "+
+			"COALESCE(SUM(CASE WHEN s.referrer <> '' THEN s.bytes ELSE 0 END), 0) AS hotlink_bytes").
+		Where("s.image_id = ? AND s.bucket >= ?", imageID, since).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// GetImageStatSeries 获取单张图片 since 之后每小时的访问量，无访问的小时不返回
+func (r *Repository) GetImageStatSeries(ctx context.Context, imageID uint, since time.Time) ([]ImageStatPoint, error) {
+	var points []ImageStatPoint
+	err := r.db.WithContext(ctx).Model(&models.ImageStat{}).
+		Select("bucket, SUM(hits) AS hits, SUM(bytes) AS bytes").
+		Where("image_id = ? AND bucket >= ?", imageID, since).
+		Group("bucket").
+		Order("bucket ASC").
+		Scan(&points).Error
+	return points, err
+}
+
+// GetImageStatBreakdown 获取单张图片 since 之后按变体或来源分组的访问量，按流量降序
+func (r *Repository) GetImageStatBreakdown(ctx context.Context, imageID uint, since time.Time, by string, limit int) ([]ImageStatGroup, error) {
+	column := ImageStatByVariant
+	if by == ImageStatByReferrer {
+		column = ImageStatByReferrer
+	}
+
+	var groups []ImageStatGroup
+	err := r.db.WithContext(ctx).Model(&models.ImageStat{}).
+		Select(column+" AS name, SUM(hits) AS hits, SUM(bytes) AS bytes").
+		Where("image_id = ? AND bucket >= ?", imageID, since).
+		Group(column).
+		Order("bytes DESC, name ASC").
+		Limit(limit).
+		Scan(&groups).Error
+	return groups, err
+}
+
+// GetUserStatTotals 获取 since 之后各用户图片的访问汇总，按流量降序，userID 为 0 时返回所有用户
+func (r *Repository) GetUserStatTotals(ctx context.Context, userID uint, since time.Time) ([]UserStatTotal, error) {
+	var totals []UserStatTotal
+	query := r.db.WithContext(ctx).Table("image_stats s").
+		Select("s.user_id, COALESCE(u.username, '') AS username, COUNT(DISTINCT s.image_id) AS images, "+imageStatTotalsSelect).
+		Joins("LEFT JOIN users u ON u.id = s.user_id").
+		Where("s.bucket >= ?", since)
+	if userID > 0 {
+		query = query.Where("s.user_id = ?", userID)
+	}
+	err := query.Group("s.user_id, u.username").
+		Order("bytes DESC, s.user_id ASC").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetStatImage 按标识符获取图片，包括回收站中的图片
+func (r *Repository) GetStatImage(ctx context.Context, identifier string) (*models.Image, error) {
+	var image models.Image
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("identifier = ?", identifier).
+		First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// PruneImageStats 删除 before 之前的统计数据
+func (r *Repository) PruneImageStats(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("bucket < ?", before).Delete(&models.ImageStat{})
+	return res.RowsAffected, res.Error
+}
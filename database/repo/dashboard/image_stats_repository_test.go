@@ -0,0 +1,98 @@
+package dashboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupImageStatsRepo(t *testing.T) *Repository {
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Image{}, &models.ImageStat{}))
+
+	require.NoError(t, db.Create(&models.User{Username: "alice", Password: "x"}).Error)
+	require.NoError(t, db.Create(&models.User{Username: "bob", Password: "x"}).Error)
+	for _, img := range []*models.Image{
+		{Identifier: "a1", FileHash: "h1", UserID: 1, OriginalName: "a1.png"},
+		{Identifier: "a2", FileHash: "h2", UserID: 1, OriginalName: "a2.png"},
+		{Identifier: "b1", FileHash: "h3", UserID: 2, OriginalName: "b1.png"},
+	} {
+		require.NoError(t, db.Create(img).Error)
+	}
+	return NewRepository(db)
+}
+
+func TestAddImageStatsAccumulates(t *testing.T) {
+	repo := setupImageStatsRepo(t)
+	ctx := context.Background()
+	bucket := time.Now().UTC().Truncate(time.Hour)
+
+	batch := []models.ImageStat{
+		{ImageID: 1, UserID: 1, Bucket: bucket, Variant: models.ImageStatVariantOriginal, Hits: 2, Bytes: 200},
+		{ImageID: 1, UserID: 1, Bucket: bucket, Variant: "webp", Referrer: "forum.example", Hits: 5, Bytes: 250},
+	}
+	require.NoError(t, repo.AddImageStats(ctx, batch))
+	require.NoError(t, repo.AddImageStats(ctx, batch[1:]))
+
+	totals, err := repo.GetImageStatTotals(ctx, 1, bucket)
+	require.NoError(t, err)
+	assert.Equal(t, ImageStatTotals{Hits: 12, Bytes: 700, HotlinkHits: 10, HotlinkBytes: 500}, *totals)
+
+	series, err := repo.GetImageStatSeries(ctx, 1, bucket.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, series, 1)
+	assert.True(t, series[0].Bucket.Equal(bucket))
+	assert.Equal(t, int64(12), series[0].Hits)
+
+	referrers, err := repo.GetImageStatBreakdown(ctx, 1, bucket, ImageStatByReferrer, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []ImageStatGroup{{Name: "forum.example", Hits: 10, Bytes: 500}, {Name: "", Hits: 2, Bytes: 200}}, referrers)
+}
+
+func TestTopImagesAndUserTotals(t *testing.T) {
+	repo := setupImageStatsRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Hour)
+	old := now.Add(-48 * time.Hour)
+
+	require.NoError(t, repo.AddImageStats(ctx, []models.ImageStat{
+		{ImageID: 1, UserID: 1, Bucket: now, Variant: "original", Hits: 10, Bytes: 100},
+		{ImageID: 2, UserID: 1, Bucket: now, Variant: "webp", Referrer: "blog.example", Hits: 3, Bytes: 900},
+		{ImageID: 3, UserID: 2, Bucket: now, Variant: "original", Hits: 1, Bytes: 50},
+		{ImageID: 3, UserID: 2, Bucket: old, Variant: "original", Hits: 100, Bytes: 5000},
+	}))
+	since := now.Add(-24 * time.Hour)
+
+	top, err := repo.GetTopImages(ctx, 0, since, ImageStatSortHits, 10)
+	require.NoError(t, err)
+	require.Len(t, top, 3)
+	assert.Equal(t, []string{"a1", "a2", "b1"}, []string{top[0].Identifier, top[1].Identifier, top[2].Identifier})
+
+	top, err = repo.GetTopImages(ctx, 1, since, ImageStatSortHotlinkBytes, 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, "a2", top[0].Identifier)
+	assert.Equal(t, int64(900), top[0].HotlinkBytes)
+
+	users, err := repo.GetUserStatTotals(ctx, 0, since)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice", users[0].Username)
+	assert.Equal(t, int64(2), users[0].Images)
+	assert.Equal(t, int64(1000), users[0].Bytes)
+	assert.Equal(t, int64(50), users[1].Bytes)
+
+	pruned, err := repo.PruneImageStats(ctx, since)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+}
@@ -0,0 +1,306 @@
+// Package fulltext 维护图片全文索引：SQLite 使用 FTS5 虚拟表，PostgreSQL 使用带 GIN 索引的 tsvector 列。
+// 索引文档由原图名、标题、描述、标签和相册名组成，在这些数据变更时由各仓库在同一事务内刷新
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+)
+
+// TableName 索引表名
+const TableName = "image_search"
+
+// refreshBatchSize 单次刷新的图片数
+const refreshBatchSize = 500
+
+// ErrFTS5Unavailable SQLite 未编译 FTS5 模块（需以 -tags sqlite_fts5 构建）
+var ErrFTS5Unavailable = errors.New("sqlite is built without FTS5")
+
+// EnsureIndex 创建索引表。SQLite 未编译 FTS5 时返回 ErrFTS5Unavailable，此时搜索退化为 LIKE 匹配
+func EnsureIndex(db *gorm.DB) error {
+	if db.Name() == "sqlite" {
+		return ensureSQLiteIndex(db)
+	}
+
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + TableName + ` (
+		image_id BIGINT PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		title TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		albums TEXT NOT NULL DEFAULT '',
+		document TSVECTOR NOT NULL
+	)`).Error; err != nil {
+		return err
+	}
+	// 早期版本的索引表没有标题和描述列，补列后清空旧文档，由 RebuildIfStale 按新列重新填充
+	for _, column := range []string{"title", "description"} {
+		if db.Migrator().HasColumn(TableName, column) {
+			continue
+		}
+		if err := db.Exec(`ALTER TABLE ` + TableName + ` ADD COLUMN ` + column + ` TEXT NOT NULL DEFAULT ''`).Error; err != nil {
+			return err
+		}
+		if err := db.Exec(`DELETE FROM ` + TableName).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_image_search_document ON ` + TableName + ` USING GIN (document)`).Error
+}
+
+// ensureSQLiteIndex FTS5 虚拟表不支持增加列，列不一致时删除重建，由 RebuildIfStale 重新填充
+func ensureSQLiteIndex(db *gorm.DB) error {
+	if db.Migrator().HasTable(TableName) {
+		var columns []string
+		if err := db.Raw(`SELECT name FROM pragma_table_info('` + TableName + `')`).Scan(&columns).Error; err != nil {
+			return err
+		}
+		if strings.Join(columns, ",") == strings.Join(sqliteColumns, ",") {
+			return nil
+		}
+		if err := db.Exec(`DROP TABLE ` + TableName).Error; err != nil {
+			return err
+		}
+	}
+
+	err := db.Exec(`CREATE VIRTUAL TABLE ` + TableName +
+		` USING fts5(image_id UNINDEXED, ` + strings.Join(sqliteColumns[1:], ", ") + `)`).Error
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		return ErrFTS5Unavailable
+	}
+	return err
+}
+
+// sqliteColumns FTS5 表的列，顺序与 Match 中 bm25 的列权重一致
+var sqliteColumns = []string{"image_id", "name", "title", "description", "tags", "albums"}
+
+// Available 索引表是否存在
+func Available(db *gorm.DB) bool {
+	return db.Migrator().HasTable(TableName)
+}
+
+// document 一张图片的索引内容
+type document struct {
+	ImageID     uint
+	Name        string
+	Title       string
+	Description string
+	Tags        []string
+	Albums      []string
+}
+
+// Refresh 按当前数据重建指定图片的索引文档，已删除的图片只移除文档
+func Refresh(db *gorm.DB, imageIDs []uint) error {
+	if len(imageIDs) == 0 || !Available(db) {
+		return nil
+	}
+	for start := 0; start < len(imageIDs); start += refreshBatchSize {
+		batch := imageIDs[start:min(start+refreshBatchSize, len(imageIDs))]
+		docs, err := loadDocuments(db, batch)
+		if err != nil {
+			return err
+		}
+		if err := db.Table(TableName).Where("image_id IN ?", batch).Delete(nil).Error; err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err := insertDocument(db, doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RefreshAlbum 刷新相册内全部图片的索引文档，相册改名后调用
+func RefreshAlbum(db *gorm.DB, albumID uint) error {
+	if !Available(db) {
+		return nil
+	}
+	var imageIDs []uint
+	if err := db.Table("album_images").Where("album_id = ?", albumID).Pluck("image_id", &imageIDs).Error; err != nil {
+		return err
+	}
+	return Refresh(db, imageIDs)
+}
+
+// RefreshTag 刷新带有该标签的全部图片的索引文档，标签改名后调用
+func RefreshTag(db *gorm.DB, tagID uint) error {
+	if !Available(db) {
+		return nil
+	}
+	var imageIDs []uint
+	if err := db.Table("image_tags").Where("tag_id = ?", tagID).Pluck("image_id", &imageIDs).Error; err != nil {
+		return err
+	}
+	return Refresh(db, imageIDs)
+}
+
+// Remove 移除图片的索引文档
+func Remove(db *gorm.DB, imageIDs []uint) error {
+	if len(imageIDs) == 0 || !Available(db) {
+		return nil
+	}
+	return db.Table(TableName).Where("image_id IN ?", imageIDs).Delete(nil).Error
+}
+
+// RebuildIfStale 索引文档数与图片数不一致时（首次启用、升级重建索引表、索引不可用期间上传或回填中断后）
+// 重建全部文档，返回重建的图片数
+func RebuildIfStale(ctx context.Context, db *gorm.DB) (int, error) {
+	db = db.WithContext(ctx)
+	if !Available(db) {
+		return 0, nil
+	}
+	var indexed, images int64
+	if err := db.Table(TableName).Count(&indexed).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Model(&models.Image{}).Count(&images).Error; err != nil {
+		return 0, err
+	}
+	if indexed == images {
+		return 0, nil
+	}
+
+	var afterID uint
+	total := 0
+	for {
+		var ids []uint
+		if err := db.Model(&models.Image{}).Where("id > ?", afterID).Order("id asc").
+			Limit(refreshBatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error { return Refresh(tx, ids) }); err != nil {
+			return total, err
+		}
+		total += len(ids)
+		afterID = ids[len(ids)-1]
+	}
+	return total, nil
+}
+
+func loadDocuments(db *gorm.DB, imageIDs []uint) ([]*document, error) {
+	var images []struct {
+		ID           uint
+		OriginalName string
+		Title        string
+		Description  string
+	}
+	if err := db.Model(&models.Image{}).Select("id, original_name, title, description").Where("id IN ?", imageIDs).Scan(&images).Error; err != nil {
+		return nil, err
+	}
+
+	docs := make([]*document, 0, len(images))
+	byID := make(map[uint]*document, len(images))
+	for _, img := range images {
+		doc := &document{ImageID: img.ID, Name: img.OriginalName, Title: img.Title, Description: img.Description}
+		docs = append(docs, doc)
+		byID[img.ID] = doc
+	}
+
+	var tagRows []struct {
+		ImageID uint
+		Name    string
+	}
+	if err := db.Table("image_tags").
+		Select("image_tags.image_id, tags.name").
+		Joins("JOIN tags ON tags.id = image_tags.tag_id").
+		Where("image_tags.image_id IN ?", imageIDs).
+		Scan(&tagRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range tagRows {
+		if doc := byID[row.ImageID]; doc != nil {
+			doc.Tags = append(doc.Tags, row.Name)
+		}
+	}
+
+	var albumRows []struct {
+		ImageID uint
+		Name    string
+	}
+	if err := db.Table("album_images").
+		Select("album_images.image_id, albums.name").
+		Joins("JOIN albums ON albums.id = album_images.album_id AND albums.deleted_at IS NULL").
+		Where("album_images.image_id IN ?", imageIDs).
+		Scan(&albumRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range albumRows {
+		if doc := byID[row.ImageID]; doc != nil {
+			doc.Albums = append(doc.Albums, row.Name)
+		}
+	}
+	return docs, nil
+}
+
+// 多值字段用换行分隔，便于高亮时按项拆分
+const listSeparator = "\n"
+
+func insertDocument(db *gorm.DB, doc *document) error {
+	tags := strings.Join(doc.Tags, listSeparator)
+	albums := strings.Join(doc.Albums, listSeparator)
+	if db.Name() == "sqlite" {
+		return db.Exec(`INSERT INTO `+TableName+` (image_id, name, title, description, tags, albums) VALUES (?, ?, ?, ?, ?, ?)`,
+			doc.ImageID, doc.Name, doc.Title, doc.Description, tags, albums).Error
+	}
+	// 'simple' 配置不做词干提取，与 FTS5 的 unicode61 分词行为保持一致；
+	// 预先把标点替换为空格，避免 PostgreSQL 把 IMG_0001.jpg 整体识别为文件名
+	terms := func(text string) string { return strings.Join(Terms(text), " ") }
+	return db.Exec(`INSERT INTO `+TableName+` (image_id, name, title, description, tags, albums, document) VALUES (?, ?, ?, ?, ?, ?,
+		setweight(to_tsvector('simple', ?), 'A') ||
+		setweight(to_tsvector('simple', ?), 'B') ||
+		setweight(to_tsvector('simple', ?), 'C') ||
+		setweight(to_tsvector('simple', ?), 'D'))`,
+		doc.ImageID, doc.Name, doc.Title, doc.Description, tags, albums,
+		terms(doc.Name+" "+doc.Title), terms(tags), terms(doc.Description), terms(albums)).Error
+}
+
+// Terms 把文本拆分为小写的字母数字词
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ErrEmptyQuery 查询中没有可搜索的词
+var ErrEmptyQuery = errors.New("search query has no searchable terms")
+
+// Match 返回命中查询的图片及相关度子查询，列为 image_id 和 search_rank（越小越相关）。
+// 每个词按前缀匹配，多个词需同时命中
+func Match(db *gorm.DB, query string) (*gorm.DB, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	sub := db.Session(&gorm.Session{NewDB: true})
+
+	if db.Name() == "sqlite" {
+		parts := make([]string, len(terms))
+		for i, term := range terms {
+			parts[i] = fmt.Sprintf(`"%s"*`, term)
+		}
+		// 列权重依次为 image_id、name、title、description、tags、albums
+		return sub.Table(TableName).
+			Select("image_id, bm25("+TableName+", 0, 10.0, 10.0, 3.0, 5.0, 2.0) AS search_rank").
+			Where(TableName+" MATCH ?", strings.Join(parts, " ")), nil
+	}
+
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = fmt.Sprintf("'%s':*", term)
+	}
+	tsquery := strings.Join(parts, " & ")
+	return sub.Table(TableName).
+		Select("image_id, -ts_rank(document, to_tsquery('simple', ?)) AS search_rank", tsquery).
+		Where("document @@ to_tsquery('simple', ?)", tsquery), nil
+}
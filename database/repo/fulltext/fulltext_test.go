@@ -0,0 +1,181 @@
+package fulltext
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupFullTextTestDB 需要以 -tags sqlite_fts5 运行，否则跳过
+func setupFullTextTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.Tag{}, &models.Album{}))
+	if err := EnsureIndex(db); errors.Is(err, ErrFTS5Unavailable) {
+		t.Skip("SQLite built without FTS5")
+	} else {
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func createSearchImage(t *testing.T, db *gorm.DB, name string) uint {
+	t.Helper()
+
+	img := &models.Image{
+		Identifier:   name,
+		OriginalName: name,
+		FileHash:     "hash-" + name,
+		MimeType:     "image/jpeg",
+		StoragePath:  "original/" + name,
+		UserID:       1,
+	}
+	require.NoError(t, db.Create(img).Error)
+	return img.ID
+}
+
+func matchIDs(t *testing.T, db *gorm.DB, query string) []uint {
+	t.Helper()
+
+	match, err := Match(db, query)
+	require.NoError(t, err)
+	var rows []struct {
+		ImageID    uint
+		SearchRank float64
+	}
+	require.NoError(t, match.Order("search_rank asc").Scan(&rows).Error)
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ImageID
+	}
+	return ids
+}
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"img", "0001", "jpg"}, Terms("IMG_0001.jpg"))
+	assert.Equal(t, []string{"東京", "tower"}, Terms("東京 - Tower"))
+	assert.Empty(t, Terms(`"*" - ()`))
+}
+
+func TestMatchRejectsEmptyQuery(t *testing.T) {
+	_, err := Match(&gorm.DB{}, " ** ")
+	assert.ErrorIs(t, err, ErrEmptyQuery)
+}
+
+func TestHighlightText(t *testing.T) {
+	terms := Terms("sun bea")
+	assert.Equal(t, "<mark>Sunset</mark> at the <mark>beach</mark>.jpg", highlightText("Sunset at the beach.jpg", terms))
+	assert.Equal(t, "&lt;b&gt;<mark>sun</mark>&lt;/b&gt;", highlightText("<b>sun</b>", terms))
+	assert.Equal(t, "moon", highlightText("moon", terms))
+}
+
+func TestMatchPrefixAndAllTerms(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	beach := createSearchImage(t, db, "sunset_beach.jpg")
+	city := createSearchImage(t, db, "sunset_city.jpg")
+	require.NoError(t, Refresh(db, []uint{beach, city}))
+
+	assert.ElementsMatch(t, []uint{beach, city}, matchIDs(t, db, "sun"))
+	assert.Equal(t, []uint{beach}, matchIDs(t, db, "sunset bea"))
+	assert.Empty(t, matchIDs(t, db, "forest"))
+}
+
+func TestRefreshIndexesTagsAndAlbums(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	imageID := createSearchImage(t, db, "IMG_0001.jpg")
+
+	tag := &models.Tag{UserID: 1, Name: "holiday"}
+	require.NoError(t, db.Create(tag).Error)
+	require.NoError(t, db.Table("image_tags").Create(map[string]any{"image_id": imageID, "tag_id": tag.ID}).Error)
+	album := &models.Album{UserID: 1, Name: "Iceland 2024"}
+	require.NoError(t, db.Create(album).Error)
+	require.NoError(t, db.Table("album_images").Create(map[string]any{"album_id": album.ID, "image_id": imageID}).Error)
+	require.NoError(t, Refresh(db, []uint{imageID}))
+
+	assert.Equal(t, []uint{imageID}, matchIDs(t, db, "holi"))
+	assert.Equal(t, []uint{imageID}, matchIDs(t, db, "iceland"))
+
+	highlights, err := Highlights(db, []uint{imageID}, "ice holiday")
+	require.NoError(t, err)
+	require.Contains(t, highlights, imageID)
+	assert.Equal(t, "IMG_0001.jpg", highlights[imageID].Name)
+	assert.Equal(t, []string{"<mark>holiday</mark>"}, highlights[imageID].Tags)
+	assert.Equal(t, []string{"<mark>Iceland</mark> 2024"}, highlights[imageID].Albums)
+
+	require.NoError(t, Remove(db, []uint{imageID}))
+	assert.Empty(t, matchIDs(t, db, "holiday"))
+}
+
+func TestRefreshIndexesTitleAndDescription(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	imageID := createSearchImage(t, db, "DSC_1234.jpg")
+	require.NoError(t, db.Model(&models.Image{}).Where("id = ?", imageID).Updates(map[string]any{
+		"title":       "Northern lights",
+		"description": "Aurora over the fjord",
+	}).Error)
+	require.NoError(t, Refresh(db, []uint{imageID}))
+
+	assert.Equal(t, []uint{imageID}, matchIDs(t, db, "north"))
+	assert.Equal(t, []uint{imageID}, matchIDs(t, db, "fjord"))
+
+	highlights, err := Highlights(db, []uint{imageID}, "fjord")
+	require.NoError(t, err)
+	assert.Empty(t, highlights[imageID].Title)
+	assert.Equal(t, "Aurora over the <mark>fjord</mark>", highlights[imageID].Description)
+}
+
+func TestEnsureIndexRecreatesOutdatedSQLiteTable(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	require.NoError(t, db.Exec(`DROP TABLE `+TableName).Error)
+	require.NoError(t, db.Exec(`CREATE VIRTUAL TABLE `+TableName+` USING fts5(image_id UNINDEXED, name, tags, albums)`).Error)
+
+	require.NoError(t, EnsureIndex(db))
+	var columns []string
+	require.NoError(t, db.Raw(`SELECT name FROM pragma_table_info('`+TableName+`')`).Scan(&columns).Error)
+	assert.Equal(t, sqliteColumns, columns)
+}
+
+func TestNameRanksAboveTags(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	tagged := createSearchImage(t, db, "IMG_0002.jpg")
+	named := createSearchImage(t, db, "mountain.jpg")
+
+	tag := &models.Tag{UserID: 1, Name: "mountain"}
+	require.NoError(t, db.Create(tag).Error)
+	require.NoError(t, db.Table("image_tags").Create(map[string]any{"image_id": tagged, "tag_id": tag.ID}).Error)
+	require.NoError(t, Refresh(db, []uint{tagged, named}))
+
+	assert.Equal(t, []uint{named, tagged}, matchIDs(t, db, "mountain"))
+}
+
+func TestRebuildIfStale(t *testing.T) {
+	db := setupFullTextTestDB(t)
+	imageID := createSearchImage(t, db, "forest.png")
+	assert.Empty(t, matchIDs(t, db, "forest"))
+
+	rebuilt, err := RebuildIfStale(t.Context(), db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rebuilt)
+	assert.Equal(t, []uint{imageID}, matchIDs(t, db, "forest"))
+
+	rebuilt, err = RebuildIfStale(t.Context(), db)
+	require.NoError(t, err)
+	assert.Zero(t, rebuilt, "an up-to-date index is left alone")
+
+	// 索引非空但缺少部分图片（如索引不可用期间上传）时同样回填
+	missed := createSearchImage(t, db, "forest-river.png")
+	rebuilt, err = RebuildIfStale(t.Context(), db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rebuilt)
+	assert.ElementsMatch(t, []uint{imageID, missed}, matchIDs(t, db, "forest"))
+}
@@ -0,0 +1,101 @@
+package fulltext
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Highlight 搜索结果中命中的字段，已做 HTML 转义，命中的词用 <mark> 包裹
+type Highlight struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Albums      []string `json:"albums,omitempty"`
+}
+
+// Highlights 为搜索结果生成高亮，标题、描述、标签和相册只返回命中的项
+func Highlights(db *gorm.DB, imageIDs []uint, query string) (map[uint]*Highlight, error) {
+	result := make(map[uint]*Highlight, len(imageIDs))
+	terms := Terms(query)
+	if len(imageIDs) == 0 || len(terms) == 0 {
+		return result, nil
+	}
+
+	docs, err := loadDocuments(db, imageIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		h := &Highlight{Name: highlightText(doc.Name, terms)}
+		if marked, ok := highlightMatch(doc.Title, terms); ok {
+			h.Title = marked
+		}
+		if marked, ok := highlightMatch(doc.Description, terms); ok {
+			h.Description = marked
+		}
+		for _, tag := range doc.Tags {
+			if marked, ok := highlightMatch(tag, terms); ok {
+				h.Tags = append(h.Tags, marked)
+			}
+		}
+		for _, album := range doc.Albums {
+			if marked, ok := highlightMatch(album, terms); ok {
+				h.Albums = append(h.Albums, marked)
+			}
+		}
+		result[doc.ImageID] = h
+	}
+	return result, nil
+}
+
+// highlightMatch 高亮文本，ok 表示至少命中一个词
+func highlightMatch(text string, terms []string) (string, bool) {
+	marked := highlightText(text, terms)
+	return marked, marked != html.EscapeString(text)
+}
+
+// highlightText 用 <mark> 包裹以任一查询词开头的词
+func highlightText(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		if j == i {
+			for j < len(runes) && !isWordRune(runes[j]) {
+				j++
+			}
+			b.WriteString(html.EscapeString(string(runes[i:j])))
+			i = j
+			continue
+		}
+
+		word := string(runes[i:j])
+		if matchesAnyTerm(strings.ToLower(word), terms) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func matchesAnyTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
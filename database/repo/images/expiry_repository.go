@@ -0,0 +1,52 @@
+package images
+
+import (
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+)
+
+// 过期图片：到达 expires_at 或查看次数达到 max_views（0 为不限）后不再提供访问，由定时任务彻底删除
+
+// expiredCondition 已到期或查看次数已用完
+const expiredCondition = "(expires_at IS NOT NULL AND expires_at <= ?) OR (max_views > 0 AND view_count >= max_views)"
+
+// ListExpiredImages 获取已过期但尚未删除的图片（所有用户），按 ID 升序
+func (r *Repository) ListExpiredImages(now time.Time, limit int) ([]*models.Image, error) {
+	var images []*models.Image
+	err := r.db.Where(expiredCondition, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&images).Error
+	return images, err
+}
+
+// ConsumeImageView 原子地计入一次查看，查看次数已用完时返回 false
+func (r *Repository) ConsumeImageView(imageID uint) (bool, error) {
+	res := r.db.Model(&models.Image{}).
+		Where("id = ? AND (max_views = 0 OR view_count < max_views)", imageID).
+		UpdateColumn("view_count", gorm.Expr("view_count + ?", 1))
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UpdateImageExpiry 设置图片的过期时间和最大查看次数，expiresAt 为 nil、maxViews 为 0 表示不限
+func (r *Repository) UpdateImageExpiry(identifier string, userID uint, expiresAt *time.Time, maxViews int) (*models.Image, error) {
+	res := r.db.Model(&models.Image{}).
+		Where("identifier = ? AND user_id = ?", identifier, userID).
+		Updates(map[string]any{
+			"expires_at": expiresAt,
+			"max_views":  maxViews,
+			"updated_at": time.Now(),
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetImageByIdentifier(identifier)
+}
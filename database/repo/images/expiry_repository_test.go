@@ -0,0 +1,80 @@
+package images
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestListExpiredImages(t *testing.T) {
+	repo := NewRepository(setupTestDB(t))
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	images := []*models.Image{
+		{Identifier: "permanent", FileHash: "ttl-h1", UserID: 1},
+		{Identifier: "expired", FileHash: "ttl-h2", UserID: 1, ExpiresAt: &past},
+		{Identifier: "valid", FileHash: "ttl-h3", UserID: 1, ExpiresAt: &future},
+		{Identifier: "used-up", FileHash: "ttl-h4", UserID: 2, MaxViews: 2, ViewCount: 2},
+		{Identifier: "views-left", FileHash: "ttl-h5", UserID: 2, MaxViews: 2, ViewCount: 1},
+	}
+	for _, img := range images {
+		require.NoError(t, repo.SaveImage(img))
+	}
+
+	expired, err := repo.ListExpiredImages(now, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+	assert.Equal(t, "expired", expired[0].Identifier)
+	assert.Equal(t, "used-up", expired[1].Identifier)
+}
+
+func TestConsumeImageView(t *testing.T) {
+	repo := NewRepository(setupTestDB(t))
+
+	img := &models.Image{Identifier: "limited", FileHash: "view-h1", UserID: 1, MaxViews: 2}
+	require.NoError(t, repo.SaveImage(img))
+
+	for range 2 {
+		ok, err := repo.ConsumeImageView(img.ID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := repo.ConsumeImageView(img.ID)
+	require.NoError(t, err)
+	assert.False(t, ok, "no views left")
+
+	found, err := repo.GetImageByIdentifier("limited")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), found.ViewCount)
+
+	// 改为不限次数后可以继续查看
+	updated, err := repo.UpdateImageExpiry("limited", 1, nil, 0)
+	require.NoError(t, err)
+	assert.Zero(t, updated.MaxViews)
+	assert.Nil(t, updated.ExpiresAt)
+	ok, err = repo.ConsumeImageView(img.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = repo.UpdateImageExpiry("limited", 2, nil, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "other users cannot change expiry")
+}
+
+func TestRandomPublicImageSkipsEphemeralImages(t *testing.T) {
+	repo := NewRepository(setupTestDB(t))
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(t, repo.SaveImage(&models.Image{Identifier: "limited", FileHash: "rand-h1", UserID: 1, IsPublic: true, MaxViews: 1}))
+	_, err := repo.GetRandomPublicImage(nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.SaveImage(&models.Image{Identifier: "expiring", FileHash: "rand-h2", UserID: 1, IsPublic: true, ExpiresAt: &future}))
+	random, err := repo.GetRandomPublicImage(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "expiring", random.Identifier)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/database/repo"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +30,9 @@ var imageListSelectColumns = []string{
 	"images.width",
 	"images.height",
 	"images.is_public",
+	"images.title",
+	"images.description",
+	"images.alt_text",
 	"images.created_at",
 }
 
@@ -42,6 +46,7 @@ type RandomImageFilter struct {
 	MaxHeight        int
 	RequireWebP      bool  // 是否要求必须有WebP变体
 	MaxFileSize      int64 // 最大文件大小（字节）
+	TagFilter
 }
 
 // NewRepository 创建新的图片仓库
@@ -54,12 +59,17 @@ func NewRepository(db *gorm.DB) *Repository {
 
 // SaveImage 保存图片
 func (r *Repository) SaveImage(image *models.Image) error {
-	return r.db.Create(&image).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.CreateWithTx(tx, image)
+	})
 }
 
 // CreateWithTx 在指定事务中创建图片记录
 func (r *Repository) CreateWithTx(tx *gorm.DB, image *models.Image) error {
-	return tx.Create(image).Error
+	if err := tx.Create(image).Error; err != nil {
+		return err
+	}
+	return fulltext.Refresh(tx, []uint{image.ID})
 }
 
 // CountImagesByStoragePath 统计使用相同存储路径的图片数量（用于秒传引用计数）
@@ -229,12 +239,14 @@ func (r *Repository) UpdateImageByIdentifier(identifier string, updates map[stri
 	}
 
 	var image models.Image
-	err := r.db.Where("identifier = ?", identifier).First(&image).Error
-	return &image, err
+	if err := r.db.Where("identifier = ?", identifier).First(&image).Error; err != nil {
+		return &image, err
+	}
+	return &image, fulltext.Refresh(r.db, []uint{image.ID})
 }
 
 // GetImageList 获取图片列表
-func (r *Repository) GetImageList(storageConfigIDs []uint, identifier, search string, albumID *uint, startTime, endTime int64, sort string, page, pageSize, userID int) ([]*models.Image, int64, error) {
+func (r *Repository) GetImageList(storageConfigIDs []uint, identifier, search string, albumID *uint, tags TagFilter, startTime, endTime int64, sort string, page, pageSize, userID int) ([]*models.Image, int64, error) {
 	var imageList []*models.Image
 	var total int64
 
@@ -246,14 +258,21 @@ func (r *Repository) GetImageList(storageConfigIDs []uint, identifier, search st
 	if identifier != "" {
 		db = db.Where("identifier = ?", identifier)
 	}
+	ranked := false
 	if search != "" {
-		escaped := strings.NewReplacer("%", "\\%", "_", "\\_").Replace(search)
-		db = db.Where("original_name LIKE ? ESCAPE '\\'", "%"+escaped+"%")
+		if match, err := fulltext.Match(r.db, search); err == nil && fulltext.Available(r.db) {
+			db = db.Joins("JOIN (?) AS search_hits ON search_hits.image_id = images.id", match)
+			ranked = true
+		} else {
+			escaped := strings.NewReplacer("%", "\\%", "_", "\\_").Replace(search)
+			db = db.Where("original_name LIKE ? ESCAPE '\\'", "%"+escaped+"%")
+		}
 	}
 	if albumID != nil {
 		db = db.Joins("JOIN album_images ON album_images.image_id = images.id").
 			Where("album_images.album_id = ?", *albumID)
 	}
+	db = tags.apply(db)
 	// 时间区间过滤（Unix时间戳秒）
 	if startTime > 0 {
 		db = db.Where("created_at >= ?", time.Unix(startTime, 0))
@@ -268,16 +287,26 @@ func (r *Repository) GetImageList(storageConfigIDs []uint, identifier, search st
 
 	offset := (page - 1) * pageSize
 
-	// 根据 sort 参数设置排序方向
-	orderBy := "created_at desc"
-	if sort == "asc" {
-		orderBy = "created_at asc"
+	// 根据 sort 参数设置排序方向，全文搜索默认按相关度排序
+	orderBy := "images.created_at desc"
+	switch {
+	case sort == "asc":
+		orderBy = "images.created_at asc"
+	case ranked && sort != "desc":
+		orderBy = "search_hits.search_rank asc, images.created_at desc"
 	}
 
-	err := db.Select(imageListSelectColumns).Order(orderBy).Offset(offset).Limit(pageSize).Find(&imageList).Error
+	err := db.Select(imageListSelectColumns).
+		Preload("Tags", func(tx *gorm.DB) *gorm.DB { return tx.Order("tags.name asc") }).
+		Order(orderBy).Offset(offset).Limit(pageSize).Find(&imageList).Error
 	return imageList, total, err
 }
 
+// SearchHighlights 生成搜索结果的命中高亮
+func (r *Repository) SearchHighlights(imageIDs []uint, search string) (map[uint]*fulltext.Highlight, error) {
+	return fulltext.Highlights(r.db, imageIDs, search)
+}
+
 // GetImagesByAlbumID 根据相册ID获取图片列表
 func (r *Repository) GetImagesByAlbumID(albumID uint, page, pageSize int) ([]*models.Image, int64, error) {
 	var imageList []*models.Image
@@ -298,8 +327,9 @@ func (r *Repository) GetImagesByAlbumID(albumID uint, page, pageSize int) ([]*mo
 
 // MarkStaleProcessingAsFailed updates stale processing images to Failed if they
 // have no processing variants but at least one failed variant.
-// excludeIDs are excluded from the update (e.g. re-triggered images).
-func (r *Repository) MarkStaleProcessingAsFailed(cutoff time.Time, excludeIDs []uint) (int64, error) {
+// excludeIDs are excluded from the update (e.g. re-triggered images), as are
+// the images selected by the optional activeImages subquery.
+func (r *Repository) MarkStaleProcessingAsFailed(cutoff time.Time, excludeIDs []uint, activeImages *gorm.DB) (int64, error) {
 	processingVariants := r.db.Table("image_variants").Select("1").
 		Where("image_variants.image_id = images.id AND image_variants.status = ?", models.VariantStatusProcessing)
 	failedVariants := r.db.Table("image_variants").Select("1").
@@ -313,6 +343,9 @@ func (r *Repository) MarkStaleProcessingAsFailed(cutoff time.Time, excludeIDs []
 	if len(excludeIDs) > 0 {
 		q = q.Where("id NOT IN ?", excludeIDs)
 	}
+	if activeImages != nil {
+		q = q.Where("id NOT IN (?)", activeImages)
+	}
 
 	result := q.Update("variant_status", models.ImageVariantStatusFailed)
 	return result.RowsAffected, result.Error
@@ -320,8 +353,9 @@ func (r *Repository) MarkStaleProcessingAsFailed(cutoff time.Time, excludeIDs []
 
 // ResetStaleProcessingToNone resets stale processing images back to None when
 // they have no remaining processing variants.
-// excludeIDs are excluded from the update (e.g. re-triggered images).
-func (r *Repository) ResetStaleProcessingToNone(cutoff time.Time, excludeIDs []uint) (int64, error) {
+// excludeIDs are excluded from the update (e.g. re-triggered images), as are
+// the images selected by the optional activeImages subquery.
+func (r *Repository) ResetStaleProcessingToNone(cutoff time.Time, excludeIDs []uint, activeImages *gorm.DB) (int64, error) {
 	processingVariants := r.db.Table("image_variants").Select("1").
 		Where("image_variants.image_id = images.id AND image_variants.status = ?", models.VariantStatusProcessing)
 
@@ -332,6 +366,9 @@ func (r *Repository) ResetStaleProcessingToNone(cutoff time.Time, excludeIDs []u
 	if len(excludeIDs) > 0 {
 		q = q.Where("id NOT IN ?", excludeIDs)
 	}
+	if activeImages != nil {
+		q = q.Where("id NOT IN (?)", activeImages)
+	}
 
 	result := q.Update("variant_status", models.ImageVariantStatusNone)
 	return result.RowsAffected, result.Error
@@ -365,6 +402,25 @@ func (r *Repository) UpdateVariantStatus(imageID uint, status models.ImageVarian
 	return r.db.Model(&models.Image{}).Where("id = ?", imageID).Update("variant_status", status).Error
 }
 
+// advanceFrom 各目标状态允许覆盖的当前状态：状态只前进，已失败的图片不被覆盖
+var advanceFrom = map[models.ImageVariantStatus][]models.ImageVariantStatus{
+	models.ImageVariantStatusNone:               {models.ImageVariantStatusProcessing},
+	models.ImageVariantStatusThumbnailCompleted: {models.ImageVariantStatusNone, models.ImageVariantStatusProcessing},
+	models.ImageVariantStatusCompleted:          {models.ImageVariantStatusNone, models.ImageVariantStatusProcessing, models.ImageVariantStatusThumbnailCompleted},
+}
+
+// AdvanceVariantStatus 按 none/processing < thumbnail_completed < completed 的顺序推进图片变体状态，
+// 用于缩略图与全尺寸变体分属不同任务时，后完成的任务不回退先完成任务写入的状态
+func (r *Repository) AdvanceVariantStatus(imageID uint, status models.ImageVariantStatus) error {
+	from, ok := advanceFrom[status]
+	if !ok {
+		return r.UpdateVariantStatus(imageID, status)
+	}
+	return r.db.Model(&models.Image{}).
+		Where("id = ? AND variant_status IN ?", imageID, from).
+		Update("variant_status", status).Error
+}
+
 func (r *Repository) ResetProcessingVariantStatus(imageIDs []uint, status models.ImageVariantStatus) (int64, error) {
 	if len(imageIDs) == 0 {
 		return 0, nil
@@ -380,6 +436,25 @@ func (r *Repository) ResetProcessingVariantStatus(imageIDs []uint, status models
 	return result.RowsAffected, result.Error
 }
 
+// ResetEvictedVariantStatus 变体被清理后把已完成的图片重置为 none，下次访问时重新生成
+func (r *Repository) ResetEvictedVariantStatus(imageIDs []uint) (int64, error) {
+	if len(imageIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.Image{}).
+		Where("id IN ? AND variant_status IN ?", imageIDs, []models.ImageVariantStatus{
+			models.ImageVariantStatusCompleted,
+			models.ImageVariantStatusThumbnailCompleted,
+		}).
+		Updates(map[string]any{
+			"variant_status": models.ImageVariantStatusNone,
+			"updated_at":     time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
 // TouchVariantProcessingStatus refreshes updated_at while an image remains in
 // processing so stale detection does not race with active work.
 func (r *Repository) TouchVariantProcessingStatus(imageID uint) error {
@@ -398,7 +473,9 @@ func (r *Repository) GetImagesByVariantStatus(statuses []models.ImageVariantStat
 
 // GetRandomPublicImage 随机获取一张公开图片
 func (r *Repository) GetRandomPublicImage(filter *RandomImageFilter) (*models.Image, error) {
-	db := r.db.Model(&models.Image{}).Where("is_public = ?", true)
+	db := r.db.Model(&models.Image{}).Where("is_public = ?", true).
+		// 限次查看的图片不参与随机，避免被随机接口消耗查看次数
+		Where("max_views = 0 AND (expires_at IS NULL OR expires_at > ?)", time.Now())
 
 	if filter != nil && filter.AlbumID != nil && !filter.IncludeAllPublic {
 		db = db.Joins("JOIN album_images ON album_images.image_id = images.id").
@@ -426,6 +503,7 @@ func (r *Repository) GetRandomPublicImage(filter *RandomImageFilter) (*models.Im
 		if filter.RequireWebP {
 			db = db.Where("variant_status = ?", models.ImageVariantStatusCompleted)
 		}
+		db = filter.TagFilter.apply(db)
 	}
 
 	idQuery := db.Session(&gorm.Session{}).
@@ -498,7 +576,7 @@ type DeleteBatchResult struct {
 	ImageIDs     []uint
 }
 
-// DeleteBatchTransaction 在事务中批量删除图片及其关联数据
+// DeleteBatchTransaction 在事务中批量将图片移入回收站
 func (r *Repository) DeleteBatchTransaction(ctx context.Context, identifiers []string, userID uint) (*DeleteBatchResult, []*models.Image, error) {
 	if len(identifiers) == 0 {
 		return &DeleteBatchResult{DeletedCount: 0, ImageIDs: []uint{}}, []*models.Image{}, nil
@@ -523,12 +601,12 @@ func (r *Repository) DeleteBatchTransaction(ctx context.Context, identifiers []s
 			imageIDs[i] = img.ID
 		}
 
-		// 2. 从所有相册中移除关联
-		if err := tx.Table("album_images").Where("image_id IN ?", imageIDs).Delete(nil).Error; err != nil {
-			return fmt.Errorf("failed to remove images from albums: %w", err)
+		// 2. 移除搜索索引，标签和相册关联保留到彻底删除，以便从回收站恢复
+		if err := fulltext.Remove(tx, imageIDs); err != nil {
+			return fmt.Errorf("failed to remove search documents: %w", err)
 		}
 
-		// 3. 删除图片记录
+		// 3. 软删除图片记录（移入回收站）
 		deleteResult := tx.Where("identifier IN ? AND user_id = ?", identifiers, userID).Delete(&models.Image{})
 		if deleteResult.Error != nil {
 			return fmt.Errorf("failed to delete images: %w", deleteResult.Error)
@@ -1,10 +1,12 @@
 package images
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
@@ -224,13 +226,48 @@ func TestRepository_GetImageListFiltersByStorageConfigIDs(t *testing.T) {
 		require.NoError(t, repo.SaveImage(image))
 	}
 
-	result, total, err := repo.GetImageList([]uint{10}, "", "", nil, 0, 0, "desc", 1, 10, 1)
+	result, total, err := repo.GetImageList([]uint{10}, "", "", nil, TagFilter{}, 0, 0, "desc", 1, 10, 1)
 	require.NoError(t, err)
 	require.Equal(t, int64(1), total)
 	require.Len(t, result, 1)
 	assert.Equal(t, "local-1", result[0].Identifier)
 }
 
+func TestRepository_GetImageListSearch(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Tag{}))
+	repo := NewRepository(db)
+
+	// 未建立全文索引时按原图名模糊匹配
+	require.NoError(t, repo.SaveImage(&models.Image{Identifier: "tagged", OriginalName: "IMG_0001.jpg", FileHash: "search-h1", UserID: 1}))
+	require.NoError(t, repo.SaveImage(&models.Image{Identifier: "named", OriginalName: "harbour_night.jpg", FileHash: "search-h2", UserID: 1}))
+	result, total, err := repo.GetImageList(nil, "", "bour_ni", nil, TagFilter{}, 0, 0, "", 1, 10, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, "named", result[0].Identifier)
+
+	if err := fulltext.EnsureIndex(db); errors.Is(err, fulltext.ErrFTS5Unavailable) {
+		t.Skip("SQLite built without FTS5")
+	} else {
+		require.NoError(t, err)
+	}
+	tag := &models.Tag{UserID: 1, Name: "harbour"}
+	require.NoError(t, db.Create(tag).Error)
+	require.NoError(t, db.Table("image_tags").Create(map[string]any{"image_id": 1, "tag_id": tag.ID}).Error)
+	_, err = fulltext.RebuildIfStale(t.Context(), db)
+	require.NoError(t, err)
+
+	result, total, err = repo.GetImageList(nil, "", "harb", nil, TagFilter{}, 0, 0, "", 1, 10, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	assert.Equal(t, "named", result[0].Identifier, "name matches rank above tag matches")
+	assert.Equal(t, "tagged", result[1].Identifier)
+
+	result, _, err = repo.GetImageList(nil, "", "harb", nil, TagFilter{}, 0, 0, "asc", 1, 10, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "tagged", result[0].Identifier, "explicit sort overrides relevance")
+}
+
 func TestRepository_DeleteImageByIdentifierAndUser(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db)
@@ -316,3 +353,116 @@ func TestRepository_UpdateImageByIdentifier(t *testing.T) {
 	_, err = repo.UpdateImageByIdentifier("not-exist", updates)
 	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 }
+
+func TestRepository_ResetEvictedVariantStatus(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+
+	statuses := []models.ImageVariantStatus{
+		models.ImageVariantStatusCompleted,
+		models.ImageVariantStatusThumbnailCompleted,
+		models.ImageVariantStatusProcessing,
+	}
+	var ids []uint
+	for i, status := range statuses {
+		img := &models.Image{
+			Identifier:    fmt.Sprintf("evict-%d", i),
+			FileHash:      fmt.Sprintf("evict-hash-%d", i),
+			StoragePath:   fmt.Sprintf("uploads/evict-%d.jpg", i),
+			VariantStatus: status,
+		}
+		require.NoError(t, repo.SaveImage(img))
+		ids = append(ids, img.ID)
+	}
+
+	rows, err := repo.ResetEvictedVariantStatus(ids)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+
+	processing, err := repo.GetImageByID(ids[2])
+	require.NoError(t, err)
+	assert.Equal(t, models.ImageVariantStatusProcessing, processing.VariantStatus, "in-flight images keep their status")
+}
+
+func TestRepository_GetImageListFiltersByTags(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Tag{}))
+	repo := NewRepository(db)
+
+	imageTags := map[string][]string{
+		"tag-a": {"travel", "family"},
+		"tag-b": {"travel"},
+		"tag-c": {"work"},
+		"tag-d": nil,
+	}
+	tagIDs := map[string]uint{}
+	for _, identifier := range []string{"tag-a", "tag-b", "tag-c", "tag-d"} {
+		image := &models.Image{Identifier: identifier, OriginalName: identifier, FileHash: "hash-" + identifier, UserID: 1}
+		require.NoError(t, repo.SaveImage(image))
+		for _, name := range imageTags[identifier] {
+			if _, ok := tagIDs[name]; !ok {
+				tag := &models.Tag{UserID: 1, Name: name}
+				require.NoError(t, db.Create(tag).Error)
+				tagIDs[name] = tag.ID
+			}
+			require.NoError(t, db.Table("image_tags").Create(map[string]any{"image_id": image.ID, "tag_id": tagIDs[name]}).Error)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter TagFilter
+		want   []string
+	}{
+		{name: "all tags", filter: TagFilter{Tags: []string{"travel", "family"}}, want: []string{"tag-a"}},
+		{name: "any tags", filter: TagFilter{AnyTags: []string{"family", "work"}}, want: []string{"tag-a", "tag-c"}},
+		{name: "exclude tags", filter: TagFilter{ExcludeTags: []string{"travel"}}, want: []string{"tag-c", "tag-d"}},
+		{name: "combined", filter: TagFilter{Tags: []string{"travel"}, ExcludeTags: []string{"family"}}, want: []string{"tag-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, total, err := repo.GetImageList(nil, "", "", nil, tt.filter, 0, 0, "asc", 1, 10, 1)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), total)
+			got := make([]string, len(result))
+			for i, image := range result {
+				got[i] = image.Identifier
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+
+	result, _, err := repo.GetImageList(nil, "tag-a", "", nil, TagFilter{}, 0, 0, "asc", 1, 10, 1)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Len(t, result[0].Tags, 2)
+	assert.Equal(t, "family", result[0].Tags[0].Name)
+
+	random, err := repo.GetRandomPublicImage(&RandomImageFilter{TagFilter: TagFilter{Tags: []string{"work"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "tag-c", random.Identifier)
+}
+
+func TestRepository_AdvanceVariantStatus(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db)
+
+	image := &models.Image{Identifier: "advance", FileHash: "advance-hash", StoragePath: "a.jpg", UserID: 1, VariantStatus: models.ImageVariantStatusProcessing}
+	require.NoError(t, repo.SaveImage(image))
+
+	status := func() models.ImageVariantStatus {
+		got, err := repo.GetImageByID(image.ID)
+		require.NoError(t, err)
+		return got.VariantStatus
+	}
+
+	require.NoError(t, repo.AdvanceVariantStatus(image.ID, models.ImageVariantStatusCompleted))
+	assert.Equal(t, models.ImageVariantStatusCompleted, status())
+
+	require.NoError(t, repo.AdvanceVariantStatus(image.ID, models.ImageVariantStatusThumbnailCompleted))
+	assert.Equal(t, models.ImageVariantStatusCompleted, status(), "a later thumbnail job must not downgrade the image")
+
+	require.NoError(t, repo.UpdateVariantStatus(image.ID, models.ImageVariantStatusFailed))
+	require.NoError(t, repo.AdvanceVariantStatus(image.ID, models.ImageVariantStatusCompleted))
+	assert.Equal(t, models.ImageVariantStatusFailed, status(), "a failed part keeps the image failed")
+}
@@ -0,0 +1,131 @@
+package images
+
+import (
+	"context"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+)
+
+// ReprocessFilter 批量重新处理的图片筛选条件
+type ReprocessFilter struct {
+	StartTime       *time.Time
+	EndTime         *time.Time
+	StorageConfigID *uint
+	MimeType        string
+	AlbumID         *uint
+}
+
+// ReprocessFilterFromJob 从任务记录构造筛选条件
+func ReprocessFilterFromJob(job *models.ReprocessJob) ReprocessFilter {
+	return ReprocessFilter{
+		StartTime:       job.StartTime,
+		EndTime:         job.EndTime,
+		StorageConfigID: job.StorageConfigID,
+		MimeType:        job.MimeType,
+		AlbumID:         job.AlbumID,
+	}
+}
+
+func (f ReprocessFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.StartTime != nil {
+		db = db.Where("images.created_at >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		db = db.Where("images.created_at <= ?", *f.EndTime)
+	}
+	if f.StorageConfigID != nil {
+		db = db.Where("images.storage_config_id = ?", *f.StorageConfigID)
+	}
+	if f.MimeType != "" {
+		db = db.Where("images.mime_type = ?", f.MimeType)
+	}
+	if f.AlbumID != nil {
+		db = db.Joins("JOIN album_images ON album_images.image_id = images.id").
+			Where("album_images.album_id = ?", *f.AlbumID)
+	}
+	return db.Where("images.is_pending_deletion = ?", false)
+}
+
+// CountImagesForReprocess 统计符合条件的图片数
+func (r *Repository) CountImagesForReprocess(filter ReprocessFilter) (int64, error) {
+	var total int64
+	err := filter.apply(r.db.Model(&models.Image{})).Count(&total).Error
+	return total, err
+}
+
+// ListImagesForReprocess 按 ID 递增分批获取符合条件的图片
+func (r *Repository) ListImagesForReprocess(filter ReprocessFilter, afterID uint, limit int) ([]*models.Image, error) {
+	var imageList []*models.Image
+	err := filter.apply(r.db.Model(&models.Image{})).
+		Where("images.id > ?", afterID).
+		Order("images.id asc").
+		Limit(limit).
+		Find(&imageList).Error
+	return imageList, err
+}
+
+// ReprocessJobRepository 重新处理任务仓库
+type ReprocessJobRepository struct {
+	db *gorm.DB
+}
+
+// NewReprocessJobRepository 创建重新处理任务仓库
+func NewReprocessJobRepository(db *gorm.DB) *ReprocessJobRepository {
+	return &ReprocessJobRepository{db: db}
+}
+
+func (r *ReprocessJobRepository) WithContext(ctx context.Context) *ReprocessJobRepository {
+	return &ReprocessJobRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 创建任务
+func (r *ReprocessJobRepository) Create(job *models.ReprocessJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetByID 获取任务
+func (r *ReprocessJobRepository) GetByID(id uint) (*models.ReprocessJob, error) {
+	var job models.ReprocessJob
+	err := r.db.First(&job, id).Error
+	return &job, err
+}
+
+// List 按创建时间倒序列出任务
+func (r *ReprocessJobRepository) List(limit int) ([]*models.ReprocessJob, error) {
+	var jobs []*models.ReprocessJob
+	err := r.db.Order("id desc").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+// ListByStatus 列出指定状态的任务
+func (r *ReprocessJobRepository) ListByStatus(status string) ([]*models.ReprocessJob, error) {
+	var jobs []*models.ReprocessJob
+	err := r.db.Where("status = ?", status).Order("id asc").Find(&jobs).Error
+	return jobs, err
+}
+
+// TransitionStatus 条件更新任务状态，仅当当前状态属于 from 时生效
+func (r *ReprocessJobRepository) TransitionStatus(id uint, from []string, to string, updates map[string]any) (bool, error) {
+	values := map[string]any{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	for k, v := range updates {
+		values[k] = v
+	}
+	result := r.db.Model(&models.ReprocessJob{}).Where("id = ? AND status IN ?", id, from).Updates(values)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AdvanceProgress 累加进度并移动游标
+func (r *ReprocessJobRepository) AdvanceProgress(id uint, lastImageID uint, queued, submitFailed, purged int64) error {
+	return r.db.Model(&models.ReprocessJob{}).Where("id = ?", id).Updates(map[string]any{
+		"last_image_id": lastImageID,
+		"queued":        gorm.Expr("queued + ?", queued),
+		"submit_failed": gorm.Expr("submit_failed + ?", submitFailed),
+		"purged":        gorm.Expr("purged + ?", purged),
+		"updated_at":    time.Now(),
+	}).Error
+}
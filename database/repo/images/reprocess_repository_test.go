@@ -0,0 +1,98 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestListImagesForReprocessFiltersAndPaginates(t *testing.T) {
+	db := setupReprocessRepoTestDB(t)
+	repo := NewRepository(db)
+
+	seed := []*models.Image{
+		{Identifier: "a", StoragePath: "a", OriginalName: "a", MimeType: "image/png", StorageConfigID: 1, FileHash: "ha"},
+		{Identifier: "b", StoragePath: "b", OriginalName: "b", MimeType: "image/jpeg", StorageConfigID: 1, FileHash: "hb"},
+		{Identifier: "c", StoragePath: "c", OriginalName: "c", MimeType: "image/png", StorageConfigID: 2, FileHash: "hc"},
+		{Identifier: "d", StoragePath: "d", OriginalName: "d", MimeType: "image/png", StorageConfigID: 1, FileHash: "hd", IsPendingDeletion: true},
+		{Identifier: "e", StoragePath: "e", OriginalName: "e", MimeType: "image/png", StorageConfigID: 1, FileHash: "he"},
+	}
+	require.NoError(t, db.Create(&seed).Error)
+
+	storageID := uint(1)
+	filter := ReprocessFilter{StorageConfigID: &storageID, MimeType: "image/png"}
+
+	total, err := repo.CountImagesForReprocess(filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+
+	first, err := repo.ListImagesForReprocess(filter, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	assert.Equal(t, "a", first[0].Identifier)
+
+	rest, err := repo.ListImagesForReprocess(filter, first[0].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, "e", rest[0].Identifier)
+}
+
+func TestListImagesForReprocessByAlbum(t *testing.T) {
+	db := setupReprocessRepoTestDB(t)
+	repo := NewRepository(db)
+
+	inAlbum := &models.Image{Identifier: "in", StoragePath: "in", OriginalName: "in", MimeType: "image/png", FileHash: "hin"}
+	outside := &models.Image{Identifier: "out", StoragePath: "out", OriginalName: "out", MimeType: "image/png", FileHash: "hout"}
+	require.NoError(t, db.Create(inAlbum).Error)
+	require.NoError(t, db.Create(outside).Error)
+	album := &models.Album{Name: "album", Images: []*models.Image{inAlbum}}
+	require.NoError(t, db.Create(album).Error)
+
+	list, err := repo.ListImagesForReprocess(ReprocessFilter{AlbumID: &album.ID}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "in", list[0].Identifier)
+}
+
+func TestReprocessJobTransitionAndProgress(t *testing.T) {
+	db := setupReprocessRepoTestDB(t)
+	repo := NewReprocessJobRepository(db)
+
+	job := &models.ReprocessJob{Status: models.ReprocessJobStatusRunning, BatchSize: 10, Total: 5}
+	require.NoError(t, repo.Create(job))
+
+	ok, err := repo.TransitionStatus(job.ID, []string{models.ReprocessJobStatusPaused}, models.ReprocessJobStatusRunning, nil)
+	require.NoError(t, err)
+	assert.False(t, ok, "running job must not transition from paused")
+
+	require.NoError(t, repo.AdvanceProgress(job.ID, 7, 2, 1, 3))
+	require.NoError(t, repo.AdvanceProgress(job.ID, 9, 1, 0, 0))
+
+	ok, err = repo.TransitionStatus(job.ID, []string{models.ReprocessJobStatusRunning}, models.ReprocessJobStatusPaused, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	stored, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReprocessJobStatusPaused, stored.Status)
+	assert.Equal(t, uint(9), stored.LastImageID)
+	assert.Equal(t, int64(3), stored.Queued)
+	assert.Equal(t, int64(1), stored.SubmitFailed)
+	assert.Equal(t, int64(3), stored.Purged)
+}
+
+func setupReprocessRepoTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.Album{}, &models.ReprocessJob{}))
+	return db
+}
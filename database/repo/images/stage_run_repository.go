@@ -0,0 +1,32 @@
+package images
+
+import (
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm/clause"
+)
+
+// SaveStageRuns 写入图片各流水线阶段的执行结果，同一阶段只保留最近一次
+func (r *VariantRepository) SaveStageRuns(imageID uint, runs []models.ImageStageRun) error {
+	if len(runs) == 0 {
+		return nil
+	}
+	for i := range runs {
+		runs[i].ImageID = imageID
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "image_id"}, {Name: "stage"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "error", "output", "duration_ms", "updated_at"}),
+	}).Create(&runs).Error
+}
+
+// GetStageRuns 获取图片各流水线阶段最近一次的执行结果
+func (r *VariantRepository) GetStageRuns(imageID uint) ([]models.ImageStageRun, error) {
+	var runs []models.ImageStageRun
+	err := r.db.Where("image_id = ?", imageID).Order("id ASC").Find(&runs).Error
+	return runs, err
+}
+
+// DeleteStageRuns 删除图片的流水线阶段记录
+func (r *VariantRepository) DeleteStageRuns(imageID uint) error {
+	return r.db.Where("image_id = ?", imageID).Delete(&models.ImageStageRun{}).Error
+}
@@ -0,0 +1,46 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageRunsKeepLatestPerStage(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ImageStageRun{}))
+	repo := NewVariantRepository(db)
+
+	require.NoError(t, repo.SaveStageRuns(1, []models.ImageStageRun{
+		{Stage: "thumbnail", Status: models.StageRunCompleted, DurationMs: 12},
+		{Stage: "webp", Status: models.StageRunFailed, Error: "export webp: boom", DurationMs: 40},
+	}))
+	require.NoError(t, repo.SaveStageRuns(1, []models.ImageStageRun{
+		{Stage: "webp", Status: models.StageRunCompleted, Output: `{"file_size":10}`, DurationMs: 35},
+	}))
+	require.NoError(t, repo.SaveStageRuns(2, []models.ImageStageRun{
+		{Stage: "thumbnail", Status: models.StageRunSkipped},
+	}))
+
+	runs, err := repo.GetStageRuns(1)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "thumbnail", runs[0].Stage)
+	assert.Equal(t, int64(12), runs[0].DurationMs)
+	assert.Equal(t, "webp", runs[1].Stage)
+	assert.Equal(t, models.StageRunCompleted, runs[1].Status)
+	assert.Empty(t, runs[1].Error, "a later run replaces the previous result")
+	assert.Equal(t, `{"file_size":10}`, runs[1].Output)
+	assert.Equal(t, int64(35), runs[1].DurationMs)
+
+	require.NoError(t, repo.DeleteStageRuns(1))
+	runs, err = repo.GetStageRuns(1)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	other, err := repo.GetStageRuns(2)
+	require.NoError(t, err)
+	assert.Len(t, other, 1)
+}
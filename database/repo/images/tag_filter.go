@@ -0,0 +1,39 @@
+package images
+
+import "gorm.io/gorm"
+
+// TagFilter 按标签名筛选图片
+type TagFilter struct {
+	Tags        []string // 必须同时包含的标签
+	AnyTags     []string // 至少包含其一的标签
+	ExcludeTags []string // 不能包含的标签
+}
+
+// IsEmpty 是否未设置任何标签条件
+func (f TagFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.AnyTags) == 0 && len(f.ExcludeTags) == 0
+}
+
+// apply 标签属于图片所有者，按名称匹配即可，无需额外限定用户
+func (f TagFilter) apply(db *gorm.DB) *gorm.DB {
+	if len(f.Tags) > 0 {
+		db = db.Where("images.id IN (?)", taggedImageIDs(db, f.Tags).
+			Group("image_tags.image_id").
+			Having("COUNT(DISTINCT tags.name) = ?", len(f.Tags)))
+	}
+	if len(f.AnyTags) > 0 {
+		db = db.Where("images.id IN (?)", taggedImageIDs(db, f.AnyTags))
+	}
+	if len(f.ExcludeTags) > 0 {
+		db = db.Where("images.id NOT IN (?)", taggedImageIDs(db, f.ExcludeTags))
+	}
+	return db
+}
+
+func taggedImageIDs(db *gorm.DB, names []string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table("image_tags").
+		Select("image_tags.image_id").
+		Joins("JOIN tags ON tags.id = image_tags.tag_id").
+		Where("tags.name IN ?", names)
+}
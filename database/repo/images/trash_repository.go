@@ -0,0 +1,156 @@
+package images
+
+import (
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 回收站即软删除的图片，相册关联在移入回收站时保留，恢复后随之生效
+
+// ListTrashedImages 分页获取用户回收站中的图片，按删除时间倒序
+func (r *Repository) ListTrashedImages(userID uint, page, pageSize int) ([]*models.Image, int64, error) {
+	var images []*models.Image
+	var total int64
+
+	db := r.db.Unscoped().Model(&models.Image{}).Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.Image{}, 0, nil
+	}
+
+	offset := (page - 1) * pageSize
+	err := db.Order("deleted_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&images).Error
+	return images, total, err
+}
+
+// GetTrashedImagesByIdentifiers 获取用户回收站中的指定图片
+func (r *Repository) GetTrashedImagesByIdentifiers(identifiers []string, userID uint) ([]*models.Image, error) {
+	if len(identifiers) == 0 {
+		return []*models.Image{}, nil
+	}
+
+	var images []*models.Image
+	err := r.db.Unscoped().
+		Where("identifier IN ? AND user_id = ? AND deleted_at IS NOT NULL", identifiers, userID).
+		Find(&images).Error
+	return images, err
+}
+
+// ListExpiredTrashedImages 获取在 cutoff 之前移入回收站的图片（所有用户）
+func (r *Repository) ListExpiredTrashedImages(cutoff time.Time, limit int) ([]*models.Image, error) {
+	var images []*models.Image
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC, id ASC").
+		Limit(limit).
+		Find(&images).Error
+	return images, err
+}
+
+// RestoreTrashedImages 从回收站恢复图片并重建搜索索引，返回恢复的数量
+func (r *Repository) RestoreTrashedImages(imageIDs []uint, userID uint) (int64, error) {
+	if len(imageIDs) == 0 {
+		return 0, nil
+	}
+
+	var restored int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.Image{}).
+			Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", imageIDs, userID).
+			Updates(map[string]any{
+				"deleted_at": nil,
+				"updated_at": time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		restored = res.RowsAffected
+		return fulltext.Refresh(tx, imageIDs)
+	})
+	return restored, err
+}
+
+// RestoreTrashedImageWithUpdates 从回收站恢复单张图片并同时更新字段，用于同一用户重新上传回收站中的图片
+func (r *Repository) RestoreTrashedImageWithUpdates(imageID uint, updates map[string]any) (*models.Image, error) {
+	var image models.Image
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		values := make(map[string]any, len(updates)+2)
+		for k, v := range updates {
+			values[k] = v
+		}
+		values["deleted_at"] = nil
+		values["updated_at"] = time.Now()
+
+		res := tx.Unscoped().Model(&models.Image{}).
+			Where("id = ? AND deleted_at IS NOT NULL", imageID).
+			Updates(values)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.First(&image, imageID).Error; err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, []uint{imageID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// PurgeTrashedImages 彻底删除回收站中的图片记录及其相册、标签关联和历史版本。
+// 仅删除仍在回收站中的记录，返回实际删除的图片 ID
+func (r *Repository) PurgeTrashedImages(imageIDs []uint) ([]uint, error) {
+	if len(imageIDs) == 0 {
+		return []uint{}, nil
+	}
+
+	var purged []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Image{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND deleted_at IS NOT NULL", imageIDs).
+			Pluck("id", &purged).Error; err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+
+		if err := tx.Table("album_images").Where("image_id IN ?", purged).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("image_tags").Where("image_id IN ?", purged).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("image_id IN ?", purged).Delete(&models.ImageVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Album{}).Where("cover_image_id IN ?", purged).Update("cover_image_id", nil).Error; err != nil {
+			return err
+		}
+		if err := fulltext.Remove(tx, purged); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", purged).Delete(&models.Image{}).Error
+	})
+	return purged, err
+}
+
+// CountTrashedImagesByStoragePath 统计回收站中引用相同存储路径的图片数量
+func (r *Repository) CountTrashedImagesByStoragePath(storagePath string) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Image{}).
+		Where("storage_path = ? AND deleted_at IS NOT NULL", storagePath).
+		Count(&count).Error
+	return count, err
+}
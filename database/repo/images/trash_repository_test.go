@@ -0,0 +1,144 @@
+package images
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTrashTestDB(t *testing.T) (*gorm.DB, *Repository) {
+	db := setupTestDB(t)
+	require.NoError(t, models.SetupJoinTables(db))
+	require.NoError(t, db.AutoMigrate(&models.Tag{}, &models.AlbumImage{}, &models.ImageVersion{}))
+	return db, NewRepository(db)
+}
+
+func TestTrashKeepsAlbumMembershipsAndTagsUntilRestore(t *testing.T) {
+	db, repo := setupTrashTestDB(t)
+
+	img := &models.Image{Identifier: "trash-a", FileHash: "trash-h1", StoragePath: "uploads/a.jpg", UserID: 1}
+	require.NoError(t, repo.SaveImage(img))
+	album := &models.Album{Name: "trip", UserID: 1}
+	require.NoError(t, db.Create(album).Error)
+	require.NoError(t, db.Create(&models.AlbumImage{AlbumID: album.ID, ImageID: img.ID, Position: 1}).Error)
+	tag := &models.Tag{UserID: 1, Name: "beach"}
+	require.NoError(t, db.Create(tag).Error)
+	require.NoError(t, db.Table("image_tags").Create(map[string]any{"image_id": img.ID, "tag_id": tag.ID}).Error)
+
+	result, _, err := repo.DeleteBatchTransaction(t.Context(), []string{"trash-a"}, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.DeletedCount)
+
+	_, err = repo.GetImageByIdentifier("trash-a")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	trashed, total, err := repo.ListTrashedImages(1, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, "trash-a", trashed[0].Identifier)
+	assert.True(t, trashed[0].DeletedAt.Valid)
+
+	_, total, err = repo.ListTrashedImages(2, 1, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total, "other users' trash is not visible")
+
+	restored, err := repo.RestoreTrashedImages([]uint{img.ID}, 2)
+	require.NoError(t, err)
+	assert.Zero(t, restored, "other users cannot restore")
+
+	restored, err = repo.RestoreTrashedImages([]uint{img.ID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), restored)
+
+	found, err := repo.GetImageByIdentifier("trash-a")
+	require.NoError(t, err)
+	assert.Equal(t, img.ID, found.ID)
+
+	var links int64
+	require.NoError(t, db.Model(&models.AlbumImage{}).Where("album_id = ? AND image_id = ?", album.ID, img.ID).Count(&links).Error)
+	assert.Equal(t, int64(1), links)
+
+	var tagLinks int64
+	require.NoError(t, db.Table("image_tags").Where("tag_id = ? AND image_id = ?", tag.ID, img.ID).Count(&tagLinks).Error)
+	assert.Equal(t, int64(1), tagLinks, "tags survive a round trip through the trash")
+}
+
+func TestPurgeTrashedImages(t *testing.T) {
+	db, repo := setupTrashTestDB(t)
+
+	live := &models.Image{Identifier: "live", FileHash: "purge-h1", StoragePath: "uploads/shared.jpg", UserID: 1}
+	trashed := &models.Image{Identifier: "trashed", FileHash: "purge-h2", StoragePath: "uploads/shared.jpg", UserID: 1}
+	require.NoError(t, repo.SaveImage(live))
+	require.NoError(t, repo.SaveImage(trashed))
+
+	album := &models.Album{Name: "covers", UserID: 1, CoverImageID: &trashed.ID}
+	require.NoError(t, db.Create(album).Error)
+	require.NoError(t, db.Create(&models.AlbumImage{AlbumID: album.ID, ImageID: trashed.ID}).Error)
+	require.NoError(t, repo.DeleteImage(trashed))
+
+	count, err := repo.CountTrashedImagesByStoragePath("uploads/shared.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// 未在回收站中的图片不会被彻底删除
+	purged, err := repo.PurgeTrashedImages([]uint{live.ID, trashed.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{trashed.ID}, purged)
+
+	var remaining int64
+	require.NoError(t, db.Unscoped().Model(&models.Image{}).Count(&remaining).Error)
+	assert.Equal(t, int64(1), remaining)
+
+	var links int64
+	require.NoError(t, db.Model(&models.AlbumImage{}).Where("image_id = ?", trashed.ID).Count(&links).Error)
+	assert.Zero(t, links)
+
+	var reloaded models.Album
+	require.NoError(t, db.First(&reloaded, album.ID).Error)
+	assert.Nil(t, reloaded.CoverImageID)
+
+	count, err = repo.CountTrashedImagesByStoragePath("uploads/shared.jpg")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestListExpiredTrashedImages(t *testing.T) {
+	db, repo := setupTrashTestDB(t)
+
+	old := &models.Image{Identifier: "old", FileHash: "exp-h1", UserID: 1}
+	recent := &models.Image{Identifier: "recent", FileHash: "exp-h2", UserID: 2}
+	require.NoError(t, repo.SaveImage(old))
+	require.NoError(t, repo.SaveImage(recent))
+	require.NoError(t, db.Unscoped().Model(old).Update("deleted_at", time.Now().AddDate(0, 0, -40)).Error)
+	require.NoError(t, db.Unscoped().Model(recent).Update("deleted_at", time.Now().AddDate(0, 0, -1)).Error)
+
+	expired, err := repo.ListExpiredTrashedImages(time.Now().AddDate(0, 0, -30), 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "old", expired[0].Identifier)
+}
+
+func TestRestoreTrashedImageWithUpdates(t *testing.T) {
+	_, repo := setupTrashTestDB(t)
+
+	img := &models.Image{Identifier: "trash-r", FileHash: "trash-r1", StoragePath: "uploads/r.jpg", UserID: 1, IsPublic: true}
+	require.NoError(t, repo.SaveImage(img))
+	_, _, err := repo.DeleteBatchTransaction(t.Context(), []string{"trash-r"}, 1)
+	require.NoError(t, err)
+
+	_, err = repo.UpdateImageByIdentifier("trash-r", map[string]any{"is_public": false})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "regular updates do not touch trashed images")
+
+	restored, err := repo.RestoreTrashedImageWithUpdates(img.ID, map[string]any{"original_name": "again.jpg", "is_public": false})
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Equal(t, "again.jpg", restored.OriginalName)
+	assert.False(t, restored.IsPublic)
+
+	_, err = repo.RestoreTrashedImageWithUpdates(img.ID, nil)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "only trashed images can be restored")
+}
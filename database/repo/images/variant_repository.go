@@ -175,9 +175,9 @@ func (r *VariantRepository) GetImageByID(imageID uint) (*models.Image, error) {
 	return &image, err
 }
 
-// DeleteByImageID 根据图片ID删除所有变体
+// DeleteByImageID 根据图片ID物理删除所有变体，避免残留记录被当作存储对象的引用
 func (r *VariantRepository) DeleteByImageID(imageID uint) error {
-	return r.db.Where("image_id = ?", imageID).Delete(&models.ImageVariant{}).Error
+	return r.db.Unscoped().Where("image_id = ?", imageID).Delete(&models.ImageVariant{}).Error
 }
 
 // DeleteVariant 根据ID删除单个变体
@@ -189,14 +189,19 @@ func (r *VariantRepository) DeleteVariant(id uint) error {
 // duration back to pending so they can be retried. Returns the number of
 // affected rows.
 func (r *VariantRepository) ResetStaleProcessing(olderThan time.Duration) (int64, error) {
-	reset, _, _, err := r.RecoverStaleProcessing(olderThan, 3)
+	reset, _, _, err := r.RecoverStaleProcessing(olderThan, 3, nil)
 	return reset, err
 }
 
-func (r *VariantRepository) RecoverStaleProcessing(olderThan time.Duration, maxRetries int) (resetCount, failedCount int64, retriedImageIDs []uint, err error) {
+// RecoverStaleProcessing 回收长时间停留在 processing 的变体；skipImages 子查询选出的图片仍有未结束的任务，由任务自身重试
+func (r *VariantRepository) RecoverStaleProcessing(olderThan time.Duration, maxRetries int, skipImages *gorm.DB) (resetCount, failedCount int64, retriedImageIDs []uint, err error) {
 	cutoff := time.Now().Add(-olderThan)
 	var staleVariants []models.ImageVariant
-	if err := r.db.Where("status = ? AND updated_at < ?", models.VariantStatusProcessing, cutoff).Find(&staleVariants).Error; err != nil {
+	query := r.db.Where("status = ? AND updated_at < ?", models.VariantStatusProcessing, cutoff)
+	if skipImages != nil {
+		query = query.Where("image_id NOT IN (?)", skipImages)
+	}
+	if err := query.Find(&staleVariants).Error; err != nil {
 		return 0, 0, nil, err
 	}
 
@@ -271,3 +276,97 @@ func (r *VariantRepository) ResetVariantsToPending(ids []uint) (int64, error) {
 
 	return result.RowsAffected, result.Error
 }
+
+// ResetVariantsForRetry 任务重试前将上次执行遗留的 processing 与 failed 变体置回 pending，已完成的变体保持不变
+func (r *VariantRepository) ResetVariantsForRetry(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.ImageVariant{}).
+		Where("id IN ? AND status IN ?", ids, []string{models.VariantStatusProcessing, models.VariantStatusFailed}).
+		Updates(map[string]any{
+			"status":        models.VariantStatusPending,
+			"error_message": "",
+			"next_retry_at": nil,
+			"updated_at":    time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// RequeueVariants 将已结束（completed/failed/pending）的变体重新置为 pending，processing 中的变体保持不变
+func (r *VariantRepository) RequeueVariants(ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.ImageVariant{}).
+		Where("id IN ? AND status <> ?", ids, models.VariantStatusProcessing).
+		Updates(map[string]any{
+			"status":        models.VariantStatusPending,
+			"error_message": "",
+			"retry_count":   0,
+			"next_retry_at": nil,
+			"updated_at":    time.Now(),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// PurgeVariant 物理删除变体记录，便于之后以相同格式重新创建
+func (r *VariantRepository) PurgeVariant(id uint) error {
+	return r.db.Unscoped().Delete(&models.ImageVariant{}, id).Error
+}
+
+// variantAccessBatchSize 批量更新访问时间时每条 SQL 的最大 ID 数
+const variantAccessBatchSize = 500
+
+// coldVariantCondition 已完成且最近访问（从未访问则按完成时间）早于 cutoff 的变体
+const coldVariantCondition = "status = ? AND COALESCE(last_accessed_at, updated_at) < ?"
+
+// TouchAccessed 批量更新变体最近访问时间，不修改 updated_at
+func (r *VariantRepository) TouchAccessed(ids []uint, at time.Time) error {
+	for start := 0; start < len(ids); start += variantAccessBatchSize {
+		end := min(start+variantAccessBatchSize, len(ids))
+		err := r.db.Model(&models.ImageVariant{}).
+			Where("id IN ?", ids[start:end]).
+			UpdateColumn("last_accessed_at", at).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListColdVariants 获取长时间未被访问的已完成变体，回收站中或已不存在的图片的变体不参与清理；
+// deliveryMimeTypes 格式原图的 WebP 变体是浏览器唯一可显示的版本，同样保留
+func (r *VariantRepository) ListColdVariants(cutoff time.Time, limit int, deliveryMimeTypes []string) ([]models.ImageVariant, error) {
+	var variants []models.ImageVariant
+	query := r.db.Where(coldVariantCondition, models.VariantStatusCompleted, cutoff).
+		Where("image_id IN (?)", r.db.Model(&models.Image{}).Select("id"))
+	if len(deliveryMimeTypes) > 0 {
+		query = query.Not("format = ? AND image_id IN (?)", models.FormatWebP,
+			r.db.Model(&models.Image{}).Select("id").Where("mime_type IN ?", deliveryMimeTypes))
+	}
+	err := query.Order("id ASC").
+		Limit(limit).
+		Find(&variants).Error
+	return variants, err
+}
+
+// PurgeColdVariant 仍未被访问时物理删除变体记录，返回是否删除
+func (r *VariantRepository) PurgeColdVariant(id uint, cutoff time.Time) (bool, error) {
+	result := r.db.Unscoped().
+		Where("id = ?", id).
+		Where(coldVariantCondition, models.VariantStatusCompleted, cutoff).
+		Delete(&models.ImageVariant{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountVariantsByStoragePath 统计引用同一存储对象的变体数（去重图片共享变体文件），包含软删除的记录
+func (r *VariantRepository) CountVariantsByStoragePath(storagePath string) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.ImageVariant{}).Where("storage_path = ?", storagePath).Count(&count).Error
+	return count, err
+}
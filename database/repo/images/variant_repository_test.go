@@ -1,6 +1,7 @@
 package images
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -62,7 +63,7 @@ func TestRecoverStaleProcessing(t *testing.T) {
 	require.NoError(t, db.Create(resetVariant).Error)
 	require.NoError(t, db.Create(failedVariant).Error)
 
-	resetCount, failedCount, _, err := repo.RecoverStaleProcessing(15*time.Minute, 3)
+	resetCount, failedCount, _, err := repo.RecoverStaleProcessing(15*time.Minute, 3, nil)
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), resetCount)
 	assert.Equal(t, int64(1), failedCount)
@@ -83,6 +84,65 @@ func TestRecoverStaleProcessing(t *testing.T) {
 	assert.Nil(t, updatedFailed.NextRetryAt)
 }
 
+func TestRecoverStaleProcessingSkipsImagesWithActiveJobs(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	staleTime := time.Now().Add(-20 * time.Minute)
+	variant := &models.ImageVariant{
+		ImageID:    7,
+		Format:     models.FormatWebP,
+		Status:     models.VariantStatusProcessing,
+		Identifier: "active.webp",
+		CreatedAt:  staleTime,
+		UpdatedAt:  staleTime,
+	}
+	require.NoError(t, db.Create(variant).Error)
+
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	require.NoError(t, db.Create(&models.Job{Type: "variant_pipeline", SubjectID: 7, Status: models.JobStatusPending, RunAt: time.Now()}).Error)
+	activeJobs := db.Model(&models.Job{}).Select("subject_id").Where("status = ?", models.JobStatusPending)
+
+	resetCount, failedCount, retried, err := repo.RecoverStaleProcessing(15*time.Minute, 3, activeJobs)
+	require.NoError(t, err)
+	assert.Zero(t, resetCount)
+	assert.Zero(t, failedCount)
+	assert.Empty(t, retried)
+
+	updated, err := repo.GetByID(variant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusProcessing, updated.Status)
+}
+
+func TestResetVariantsForRetry(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	statuses := []string{models.VariantStatusProcessing, models.VariantStatusFailed, models.VariantStatusCompleted}
+	ids := make([]uint, 0, len(statuses))
+	for i, status := range statuses {
+		variant := &models.ImageVariant{
+			ImageID:      1,
+			Format:       models.FormatThumbnailSize(100 * (i + 1)),
+			Status:       status,
+			Identifier:   fmt.Sprintf("retry-%d.webp", i),
+			ErrorMessage: "old error",
+		}
+		require.NoError(t, db.Create(variant).Error)
+		ids = append(ids, variant.ID)
+	}
+
+	reset, err := repo.ResetVariantsForRetry(ids)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), reset)
+
+	for i, want := range []string{models.VariantStatusPending, models.VariantStatusPending, models.VariantStatusCompleted} {
+		updated, err := repo.GetByID(ids[i])
+		require.NoError(t, err)
+		assert.Equal(t, want, updated.Status)
+	}
+}
+
 func TestUpdateCompletedClearsRetryMetadata(t *testing.T) {
 	db := setupVariantRepoTestDB(t)
 	repo := NewVariantRepository(db)
@@ -138,6 +198,134 @@ func TestResetVariantsToPendingClearsRetryWindow(t *testing.T) {
 	assert.Equal(t, 2, updated.RetryCount)
 }
 
+func TestRequeueVariantsSkipsProcessing(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	completed := &models.ImageVariant{ImageID: 1, Format: "thumbnail_400x400", Status: models.VariantStatusCompleted, RetryCount: 1}
+	processing := &models.ImageVariant{ImageID: 1, Format: "thumbnail_640x360", Status: models.VariantStatusProcessing}
+	require.NoError(t, db.Create(completed).Error)
+	require.NoError(t, db.Create(processing).Error)
+
+	rows, err := repo.RequeueVariants([]uint{completed.ID, processing.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+
+	updated, err := repo.GetByID(completed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusPending, updated.Status)
+	assert.Equal(t, 0, updated.RetryCount)
+
+	untouched, err := repo.GetByID(processing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusProcessing, untouched.Status)
+}
+
+func TestColdVariantsUseLastAccessOrCompletionTime(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	now := time.Now()
+	old := now.Add(-60 * 24 * time.Hour)
+	image := &models.Image{Identifier: "cold-image", FileHash: "cold-hash", StoragePath: "original/cold.jpg"}
+	require.NoError(t, db.Create(image).Error)
+	create := func(format, status string) *models.ImageVariant {
+		v := &models.ImageVariant{ImageID: image.ID, Format: format, Status: status, StoragePath: "converted/" + format}
+		require.NoError(t, db.Create(v).Error)
+		require.NoError(t, db.Model(v).UpdateColumn("updated_at", old).Error)
+		return v
+	}
+	neverAccessed := create(models.FormatWebP, models.VariantStatusCompleted)
+	recentlyAccessed := create(models.FormatAVIF, models.VariantStatusCompleted)
+	failed := create(models.FormatJXL, models.VariantStatusFailed)
+	fresh := &models.ImageVariant{ImageID: image.ID, Format: "thumbnail_300", Status: models.VariantStatusCompleted}
+	require.NoError(t, db.Create(fresh).Error)
+
+	require.NoError(t, repo.TouchAccessed([]uint{recentlyAccessed.ID}, now))
+	touched, err := repo.GetByID(recentlyAccessed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, touched.LastAccessedAt)
+	assert.WithinDuration(t, old, touched.UpdatedAt, time.Second, "touching must not bump updated_at")
+
+	cutoff := now.Add(-30 * 24 * time.Hour)
+	cold, err := repo.ListColdVariants(cutoff, 10, nil)
+	require.NoError(t, err)
+	require.Len(t, cold, 1)
+	assert.Equal(t, neverAccessed.ID, cold[0].ID)
+
+	require.NoError(t, repo.TouchAccessed([]uint{neverAccessed.ID}, now))
+	purged, err := repo.PurgeColdVariant(neverAccessed.ID, cutoff)
+	require.NoError(t, err)
+	assert.False(t, purged, "a variant accessed after listing must be kept")
+
+	purged, err = repo.PurgeColdVariant(failed.ID, cutoff)
+	require.NoError(t, err)
+	assert.False(t, purged)
+
+	count, err := repo.CountVariantsByStoragePath("converted/" + models.FormatWebP)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestColdVariantsSkipTrashedImagesAndCountSoftDeletedRefs(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	trashed := &models.Image{Identifier: "trashed-image", FileHash: "trashed-hash", StoragePath: "original/trashed.jpg"}
+	require.NoError(t, db.Create(trashed).Error)
+	require.NoError(t, db.Delete(trashed).Error)
+
+	variant := &models.ImageVariant{ImageID: trashed.ID, Format: models.FormatWebP, Status: models.VariantStatusCompleted, StoragePath: "converted/trashed.webp"}
+	require.NoError(t, db.Create(variant).Error)
+	require.NoError(t, db.Model(variant).UpdateColumn("updated_at", old).Error)
+	orphan := &models.ImageVariant{ImageID: trashed.ID + 100, Format: models.FormatWebP, Status: models.VariantStatusCompleted, StoragePath: "converted/orphan.webp"}
+	require.NoError(t, db.Create(orphan).Error)
+	require.NoError(t, db.Model(orphan).UpdateColumn("updated_at", old).Error)
+
+	cold, err := repo.ListColdVariants(time.Now().Add(-30*24*time.Hour), 10, nil)
+	require.NoError(t, err)
+	assert.Empty(t, cold, "variants of trashed or missing images are kept until the trash purge")
+
+	require.NoError(t, repo.DeleteVariant(variant.ID))
+	count, err := repo.CountVariantsByStoragePath(variant.StoragePath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "soft-deleted rows still reference the object")
+
+	require.NoError(t, repo.DeleteByImageID(trashed.ID))
+	count, err = repo.CountVariantsByStoragePath(variant.StoragePath)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestColdVariantsKeepDeliveryWebP(t *testing.T) {
+	db := setupVariantRepoTestDB(t)
+	repo := NewVariantRepository(db)
+
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	heic := &models.Image{Identifier: "heic-image", FileHash: "heic-hash", StoragePath: "original/a.heic", MimeType: "image/heic"}
+	jpeg := &models.Image{Identifier: "jpeg-image", FileHash: "jpeg-hash", StoragePath: "original/b.jpg", MimeType: "image/jpeg"}
+	require.NoError(t, db.Create(heic).Error)
+	require.NoError(t, db.Create(jpeg).Error)
+	create := func(image *models.Image, format string) *models.ImageVariant {
+		v := &models.ImageVariant{ImageID: image.ID, Format: format, Status: models.VariantStatusCompleted, StoragePath: image.Identifier + "/" + format}
+		require.NoError(t, db.Create(v).Error)
+		require.NoError(t, db.Model(v).UpdateColumn("updated_at", old).Error)
+		return v
+	}
+	create(heic, models.FormatWebP)
+	heicThumb := create(heic, "thumbnail_300")
+	jpegWebP := create(jpeg, models.FormatWebP)
+
+	cold, err := repo.ListColdVariants(time.Now().Add(-30*24*time.Hour), 10, []string{"image/heic", "image/tiff"})
+	require.NoError(t, err)
+	ids := make([]uint, 0, len(cold))
+	for _, v := range cold {
+		ids = append(ids, v.ID)
+	}
+	assert.Equal(t, []uint{heicThumb.ID, jpegWebP.ID}, ids, "the delivery WebP of a HEIC original is never evicted")
+}
+
 func setupVariantRepoTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 
@@ -145,6 +333,6 @@ func setupVariantRepoTestDB(t *testing.T) *gorm.DB {
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.ImageVariant{}))
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.ImageVariant{}))
 	return db
 }
@@ -0,0 +1,120 @@
+package images
+
+import (
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReplaceImageContent 将图片当前内容存为历史版本并换成新内容，版本号加一、变体状态重置。
+// 图片不存在或不属于该用户时返回 gorm.ErrRecordNotFound
+func (r *Repository) ReplaceImageContent(imageID, userID uint, content *models.ImageVersion) (*models.Image, error) {
+	var image *models.Image
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		image, err = replaceContentTx(tx, imageID, userID, content)
+		return err
+	})
+	return image, err
+}
+
+// RevertImageToVersion 以历史版本的内容作为新版本，历史记录保持不变。
+// 图片或版本不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository) RevertImageToVersion(imageID, userID uint, version int) (*models.Image, error) {
+	var image *models.Image
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var target models.ImageVersion
+		if err := tx.Where("image_id = ? AND version = ?", imageID, version).First(&target).Error; err != nil {
+			return err
+		}
+		var err error
+		image, err = replaceContentTx(tx, imageID, userID, &target)
+		return err
+	})
+	return image, err
+}
+
+func replaceContentTx(tx *gorm.DB, imageID, userID uint, content *models.ImageVersion) (*models.Image, error) {
+	var image models.Image
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", imageID, userID).
+		First(&image).Error; err != nil {
+		return nil, err
+	}
+
+	current := image.ContentVersion()
+	if current.Version < 1 {
+		current.Version = 1
+	}
+	if err := tx.Create(current).Error; err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{
+		"storage_path":      content.StoragePath,
+		"storage_config_id": content.StorageConfigID,
+		"original_name":     content.OriginalName,
+		"file_size":         content.FileSize,
+		"mime_type":         content.MimeType,
+		"file_hash":         content.FileHash,
+		"width":             content.Width,
+		"height":            content.Height,
+		"version":           current.Version + 1,
+		"variant_status":    models.ImageVariantStatusNone,
+		"updated_at":        time.Now(),
+	}
+	if err := tx.Model(&image).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := fulltext.Refresh(tx, []uint{image.ID}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.First(&image, image.ID).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// ListImageVersions 获取图片的历史版本，按版本号倒序
+func (r *Repository) ListImageVersions(imageID uint) ([]*models.ImageVersion, error) {
+	var versions []*models.ImageVersion
+	err := r.db.Where("image_id = ?", imageID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// ListVersionsByImageIDs 获取多张图片的全部历史版本
+func (r *Repository) ListVersionsByImageIDs(imageIDs []uint) ([]*models.ImageVersion, error) {
+	if len(imageIDs) == 0 {
+		return []*models.ImageVersion{}, nil
+	}
+
+	var versions []*models.ImageVersion
+	err := r.db.Where("image_id IN ?", imageIDs).Find(&versions).Error
+	return versions, err
+}
+
+// DeleteImageVersion 删除一个历史版本记录并返回它，不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository) DeleteImageVersion(imageID uint, version int) (*models.ImageVersion, error) {
+	var target models.ImageVersion
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("image_id = ? AND version = ?", imageID, version).First(&target).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&target).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// CountVersionsByStoragePath 统计引用相同存储路径的历史版本数量
+func (r *Repository) CountVersionsByStoragePath(storagePath string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ImageVersion{}).Where("storage_path = ?", storagePath).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,85 @@
+package images
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestReplaceAndRevertImageContent(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ImageVersion{}))
+	repo := NewRepository(db)
+
+	img := &models.Image{
+		Identifier:    "versioned",
+		OriginalName:  "v1.png",
+		FileHash:      "hash-v1",
+		FileSize:      100,
+		MimeType:      "image/png",
+		StoragePath:   "uploads/v1.png",
+		UserID:        1,
+		VariantStatus: models.ImageVariantStatusCompleted,
+	}
+	require.NoError(t, repo.SaveImage(img))
+
+	_, err := repo.ReplaceImageContent(img.ID, 2, &models.ImageVersion{StoragePath: "uploads/v2.png", FileHash: "hash-v2"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "other users cannot replace")
+
+	replaced, err := repo.ReplaceImageContent(img.ID, 1, &models.ImageVersion{
+		StoragePath:  "uploads/v2.jpg",
+		OriginalName: "v2.jpg",
+		FileHash:     "hash-v2",
+		FileSize:     200,
+		MimeType:     "image/jpeg",
+		Width:        640,
+		Height:       480,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "versioned", replaced.Identifier)
+	assert.Equal(t, 2, replaced.Version)
+	assert.Equal(t, "hash-v2", replaced.FileHash)
+	assert.Equal(t, "uploads/v2.jpg", replaced.StoragePath)
+	assert.Equal(t, models.ImageVariantStatusNone, replaced.VariantStatus)
+
+	versions, err := repo.ListImageVersions(img.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.Equal(t, "hash-v1", versions[0].FileHash)
+	assert.Equal(t, "uploads/v1.png", versions[0].StoragePath)
+
+	reverted, err := repo.RevertImageToVersion(img.ID, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reverted.Version)
+	assert.Equal(t, "hash-v1", reverted.FileHash)
+	assert.Equal(t, "v1.png", reverted.OriginalName)
+
+	versions, err = repo.ListImageVersions(img.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version, "newest version first")
+	assert.Equal(t, "hash-v2", versions[0].FileHash)
+
+	_, err = repo.RevertImageToVersion(img.ID, 1, 9)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// 当前内容和历史版本 1 共用同一文件
+	refs, err := repo.CountVersionsByStoragePath("uploads/v1.png")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), refs)
+
+	deleted, err := repo.DeleteImageVersion(img.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "uploads/v1.png", deleted.StoragePath)
+
+	refs, err = repo.CountVersionsByStoragePath("uploads/v1.png")
+	require.NoError(t, err)
+	assert.Zero(t, refs)
+
+	_, err = repo.DeleteImageVersion(img.ID, 1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
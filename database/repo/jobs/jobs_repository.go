@@ -0,0 +1,352 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"gorm.io/gorm"
+)
+
+// Repository 持久化任务队列仓库
+// 领取任务使用“先查询候选、再条件更新”的乐观方式，SQLite 与 PostgreSQL 均适用。
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建任务仓库
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	return &Repository{db: r.db.WithContext(ctx)}
+}
+
+// ListFilter 任务列表筛选条件
+type ListFilter struct {
+	Status string
+	Type   string
+}
+
+// Enqueue 写入新任务
+func (r *Repository) Enqueue(job *models.Job) error {
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = models.DefaultJobMaxAttempts
+	}
+	return r.db.Create(job).Error
+}
+
+// claimWindowFactor 领取时多读取的候选倍数，条件更新被其他进程抢先时仍有余量
+const claimWindowFactor = 8
+
+type claimCandidate struct {
+	ID       uint
+	Priority int
+	OwnerID  uint
+}
+
+// fairOrder 从按优先级排序的候选中选出 limit 个：高优先级先选，同一优先级内各用户轮流，
+// 避免单个用户的大批量任务占满所有领取名额
+func fairOrder(rows []claimCandidate, limit int) []uint {
+	picked := make([]uint, 0, limit)
+	for start := 0; start < len(rows) && len(picked) < limit; {
+		end := start
+		for end < len(rows) && rows[end].Priority == rows[start].Priority {
+			end++
+		}
+
+		var owners []uint
+		byOwner := make(map[uint][]uint)
+		for _, row := range rows[start:end] {
+			if _, ok := byOwner[row.OwnerID]; !ok {
+				owners = append(owners, row.OwnerID)
+			}
+			byOwner[row.OwnerID] = append(byOwner[row.OwnerID], row.ID)
+		}
+		for remaining := end - start; remaining > 0 && len(picked) < limit; {
+			for _, owner := range owners {
+				ids := byOwner[owner]
+				if len(ids) == 0 {
+					continue
+				}
+				picked = append(picked, ids[0])
+				byOwner[owner] = ids[1:]
+				remaining--
+				if len(picked) == limit {
+					break
+				}
+			}
+		}
+		start = end
+	}
+	return picked
+}
+
+// claimable 可领取的任务：到期的 pending 任务，或租约已过期的 running 任务
+func claimable(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where(
+		"(status = ? AND run_at <= ?) OR (status = ? AND lease_expires_at < ?)",
+		models.JobStatusPending, now, models.JobStatusRunning, now,
+	)
+}
+
+// Claim 为 owner 领取最多 limit 个指定类型的任务，按优先级和计划时间排序
+func (r *Repository) Claim(owner string, types []string, lease time.Duration, limit int) ([]*models.Job, error) {
+	if limit <= 0 || len(types) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+
+	// 候选在 SQL 中按 (优先级, 用户) 编号，先取各用户排在最前的任务，
+	// 单个用户积压再多也不会挤掉同一优先级内其他用户的任务
+	ranked := claimable(r.db.Model(&models.Job{}), now).
+		Where("type IN ?", types).
+		Select("id, priority, owner_id, run_at, ROW_NUMBER() OVER (PARTITION BY priority, owner_id ORDER BY run_at asc, id asc) AS owner_rank")
+
+	var rows []claimCandidate
+	err := r.db.Table("(?) AS candidates", ranked).
+		Where("owner_rank <= ?", limit).
+		Order("priority desc, owner_rank asc, run_at asc, id asc").
+		Limit(limit * claimWindowFactor).
+		Select("id, priority, owner_id").
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	candidates := fairOrder(rows, limit)
+
+	expiresAt := now.Add(lease)
+	claimed := make([]uint, 0, len(candidates))
+	for _, id := range candidates {
+		// 条件更新保证同一任务只会被一个进程领取
+		result := claimable(r.db.Model(&models.Job{}).Where("id = ?", id), now).
+			Updates(map[string]any{
+				"status":           models.JobStatusRunning,
+				"lease_owner":      owner,
+				"lease_expires_at": expiresAt,
+				"attempts":         gorm.Expr("attempts + 1"),
+				"updated_at":       now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			claimed = append(claimed, id)
+		}
+	}
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	var jobs []*models.Job
+	err = r.db.Where("id IN ? AND lease_owner = ?", claimed, owner).Order("priority desc, run_at asc, id asc").Find(&jobs).Error
+	return jobs, err
+}
+
+// FailExhausted 将租约过期且已用尽重试次数的任务标记为失败，避免反复导致进程崩溃的任务被无限领取
+func (r *Repository) FailExhausted() (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&models.Job{}).
+		Where("status = ? AND lease_expires_at < ? AND attempts >= max_attempts", models.JobStatusRunning, now).
+		Updates(map[string]any{
+			"status":           models.JobStatusFailed,
+			"last_error":       "lease expired after final attempt",
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+			"finished_at":      now,
+			"updated_at":       now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Complete 标记任务完成，仅租约持有者可以操作
+func (r *Repository) Complete(id uint, owner string) error {
+	now := time.Now()
+	return r.ownedBy(id, owner).Updates(map[string]any{
+		"status":           models.JobStatusCompleted,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"last_error":       "",
+		"finished_at":      now,
+		"updated_at":       now,
+	}).Error
+}
+
+// Reschedule 任务执行失败后重新排队，在 runAt 之后重试
+func (r *Repository) Reschedule(id uint, owner string, runAt time.Time, errMsg string) error {
+	return r.ownedBy(id, owner).Updates(map[string]any{
+		"status":           models.JobStatusPending,
+		"run_at":           runAt,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"last_error":       errMsg,
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+// Fail 标记任务最终失败
+func (r *Repository) Fail(id uint, owner string, errMsg string) error {
+	now := time.Now()
+	return r.ownedBy(id, owner).Updates(map[string]any{
+		"status":           models.JobStatusFailed,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"last_error":       errMsg,
+		"finished_at":      now,
+		"updated_at":       now,
+	}).Error
+}
+
+// ExtendLeases 为 owner 持有的运行中任务续租
+func (r *Repository) ExtendLeases(owner string, ids []uint, until time.Time) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Model(&models.Job{}).
+		Where("id IN ? AND lease_owner = ? AND status = ?", ids, owner, models.JobStatusRunning).
+		Updates(map[string]any{
+			"lease_expires_at": until,
+			"updated_at":       time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// HeldLeases 返回 ids 中仍由 owner 持有租约的运行中任务
+func (r *Repository) HeldLeases(owner string, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return []uint{}, nil
+	}
+	var held []uint
+	err := r.db.Model(&models.Job{}).
+		Where("id IN ? AND lease_owner = ? AND status = ?", ids, owner, models.JobStatusRunning).
+		Pluck("id", &held).Error
+	return held, err
+}
+
+// ReleaseLeases 归还未执行完的任务（如进程退出时），不计入尝试次数
+func (r *Repository) ReleaseLeases(owner string, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	result := r.db.Model(&models.Job{}).
+		Where("id IN ? AND lease_owner = ? AND status = ?", ids, owner, models.JobStatusRunning).
+		Updates(map[string]any{
+			"status":           models.JobStatusPending,
+			"run_at":           now,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+			"attempts":         gorm.Expr("CASE WHEN attempts > 0 THEN attempts - 1 ELSE 0 END"),
+			"updated_at":       now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Retry 将失败的任务重新放回队列
+func (r *Repository) Retry(id uint) (bool, error) {
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobStatusFailed).
+		Updates(map[string]any{
+			"status":      models.JobStatusPending,
+			"run_at":      time.Now(),
+			"attempts":    0,
+			"finished_at": nil,
+			"updated_at":  time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetByID 获取任务
+func (r *Repository) GetByID(id uint) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 分页列出任务，按 ID 倒序
+func (r *Repository) List(filter ListFilter, page, limit int) ([]*models.Job, int64, error) {
+	query := r.db.Model(&models.Job{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []*models.Job
+	offset := (page - 1) * limit
+	err := query.Order("id desc").Offset(offset).Limit(limit).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// CountByStatus 按状态统计任务数
+func (r *Repository) CountByStatus() (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.Model(&models.Job{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{
+		models.JobStatusPending:   0,
+		models.JobStatusRunning:   0,
+		models.JobStatusCompleted: 0,
+		models.JobStatusFailed:    0,
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ListRunningByOwner 获取 owner 当前持有租约的指定类型任务
+func (r *Repository) ListRunningByOwner(owner, jobType string) ([]*models.Job, error) {
+	var jobs []*models.Job
+	err := r.db.Where("lease_owner = ? AND status = ? AND type = ?", owner, models.JobStatusRunning, jobType).
+		Order("id asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// ActiveSubjectsQuery 返回仍在等待或执行中的指定类型任务所处理对象 ID 的子查询，
+// 供调用方以 NOT IN (?) 过滤，避免把大量 ID 作为绑定参数
+func (r *Repository) ActiveSubjectsQuery(jobType string) *gorm.DB {
+	return r.db.Model(&models.Job{}).
+		Select("subject_id").
+		Where("type = ? AND status IN ? AND subject_id > 0", jobType, []string{models.JobStatusPending, models.JobStatusRunning})
+}
+
+// CountReady 统计已到期、等待领取的任务数
+func (r *Repository) CountReady() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Job{}).
+		Where("status = ? AND run_at <= ?", models.JobStatusPending, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteCompletedBefore 清理早于 cutoff 完成的任务
+func (r *Repository) DeleteCompletedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND finished_at < ?", models.JobStatusCompleted, cutoff).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *Repository) ownedBy(id uint, owner string) *gorm.DB {
+	return r.db.Model(&models.Job{}).Where("id = ? AND lease_owner = ? AND status = ?", id, owner, models.JobStatusRunning)
+}
@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestClaimOrdersByPriorityAndSkipsFutureJobs(t *testing.T) {
+	repo := NewRepository(setupJobsTestDB(t))
+
+	low := &models.Job{Type: "a", Priority: 0}
+	high := &models.Job{Type: "a", Priority: 10}
+	future := &models.Job{Type: "a", Priority: 20, RunAt: time.Now().Add(time.Hour)}
+	other := &models.Job{Type: "b"}
+	for _, job := range []*models.Job{low, high, future, other} {
+		require.NoError(t, repo.Enqueue(job))
+	}
+
+	claimed, err := repo.Claim("owner-1", []string{"a"}, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, high.ID, claimed[0].ID)
+	assert.Equal(t, low.ID, claimed[1].ID)
+	assert.Equal(t, models.JobStatusRunning, claimed[0].Status)
+	assert.Equal(t, "owner-1", claimed[0].LeaseOwner)
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	again, err := repo.Claim("owner-2", []string{"a"}, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again, "leased jobs must not be claimed by another owner")
+}
+
+func TestClaimRotatesOwnersWithinPriority(t *testing.T) {
+	repo := NewRepository(setupJobsTestDB(t))
+
+	var bulk []*models.Job
+	for range 5 {
+		job := &models.Job{Type: "a", Priority: 10, OwnerID: 1}
+		require.NoError(t, repo.Enqueue(job))
+		bulk = append(bulk, job)
+	}
+	other := &models.Job{Type: "a", Priority: 10, OwnerID: 2}
+	require.NoError(t, repo.Enqueue(other))
+	urgent := &models.Job{Type: "a", Priority: 20, OwnerID: 1}
+	require.NoError(t, repo.Enqueue(urgent))
+
+	claimed, err := repo.Claim("owner-1", []string{"a"}, time.Minute, 3)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+
+	ids := []uint{claimed[0].ID, claimed[1].ID, claimed[2].ID}
+	assert.Contains(t, ids, urgent.ID, "higher priority is claimed first")
+	assert.Contains(t, ids, bulk[0].ID)
+	assert.Contains(t, ids, other.ID, "a later job from another user must not wait behind the whole batch")
+}
+
+func TestClaimReachesOtherOwnersBeyondLargeBacklog(t *testing.T) {
+	repo := NewRepository(setupJobsTestDB(t))
+
+	for range 2 * claimWindowFactor * 2 {
+		require.NoError(t, repo.Enqueue(&models.Job{Type: "a", OwnerID: 1}))
+	}
+	late := &models.Job{Type: "a", OwnerID: 2}
+	require.NoError(t, repo.Enqueue(late))
+
+	claimed, err := repo.Claim("owner-1", []string{"a"}, time.Minute, 2)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Contains(t, []uint{claimed[0].ID, claimed[1].ID}, late.ID,
+		"another user's job must be claimed even when one user's backlog exceeds the candidate window")
+}
+
+func TestFairOrder(t *testing.T) {
+	rows := []claimCandidate{
+		{ID: 1, Priority: 20, OwnerID: 1},
+		{ID: 2, Priority: 10, OwnerID: 1},
+		{ID: 3, Priority: 10, OwnerID: 1},
+		{ID: 4, Priority: 10, OwnerID: 2},
+		{ID: 5, Priority: 10, OwnerID: 3},
+		{ID: 6, Priority: 10, OwnerID: 2},
+	}
+	assert.Equal(t, []uint{1, 2, 4, 5, 3, 6}, fairOrder(rows, 10))
+	assert.Equal(t, []uint{1, 2, 4}, fairOrder(rows, 3))
+}
+
+func TestClaimReclaimsExpiredLeases(t *testing.T) {
+	db := setupJobsTestDB(t)
+	repo := NewRepository(db)
+
+	job := &models.Job{Type: "a"}
+	require.NoError(t, repo.Enqueue(job))
+	claimed, err := repo.Claim("dead-owner", []string{"a"}, time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	expired := time.Now().Add(-time.Second)
+	require.NoError(t, db.Model(&models.Job{}).Where("id = ?", job.ID).Update("lease_expires_at", expired).Error)
+
+	reclaimed, err := repo.Claim("owner-2", []string{"a"}, time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+	assert.Equal(t, "owner-2", reclaimed[0].LeaseOwner)
+	assert.Equal(t, 2, reclaimed[0].Attempts)
+
+	// 原持有者的迟到结果不能覆盖新租约
+	require.NoError(t, repo.Complete(job.ID, "dead-owner"))
+	stored, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusRunning, stored.Status)
+}
+
+func TestFailExhaustedAndRetry(t *testing.T) {
+	db := setupJobsTestDB(t)
+	repo := NewRepository(db)
+
+	job := &models.Job{Type: "a", MaxAttempts: 1}
+	require.NoError(t, repo.Enqueue(job))
+	_, err := repo.Claim("owner", []string{"a"}, time.Minute, 1)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.Job{}).Where("id = ?", job.ID).Update("lease_expires_at", time.Now().Add(-time.Second)).Error)
+
+	failed, err := repo.FailExhausted()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), failed)
+
+	ok, err := repo.Retry(job.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	stored, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, 0, stored.Attempts)
+}
+
+func TestRescheduleReleaseAndCounts(t *testing.T) {
+	repo := NewRepository(setupJobsTestDB(t))
+
+	first := &models.Job{Type: "a"}
+	second := &models.Job{Type: "a"}
+	require.NoError(t, repo.Enqueue(first))
+	require.NoError(t, repo.Enqueue(second))
+	_, err := repo.Claim("owner", []string{"a"}, time.Minute, 2)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Reschedule(first.ID, "owner", time.Now().Add(time.Hour), "boom"))
+	held, err := repo.HeldLeases("owner", []uint{first.ID, second.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{second.ID}, held, "rescheduled jobs no longer hold a lease")
+	released, err := repo.ReleaseLeases("owner", []uint{second.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), released)
+	held, err = repo.HeldLeases("owner", []uint{first.ID, second.ID})
+	require.NoError(t, err)
+	assert.Empty(t, held)
+
+	stored, err := repo.GetByID(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, "boom", stored.LastError)
+	assert.Equal(t, 1, stored.Attempts)
+
+	stored, err = repo.GetByID(second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.Attempts, "released jobs must not consume an attempt")
+
+	counts, err := repo.CountByStatus()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counts[models.JobStatusPending])
+	assert.Equal(t, int64(0), counts[models.JobStatusRunning])
+}
+
+func TestListRunningByOwnerAndActiveSubjects(t *testing.T) {
+	repo := NewRepository(setupJobsTestDB(t))
+
+	running := &models.Job{Type: "a", SubjectID: 1}
+	pending := &models.Job{Type: "a", SubjectID: 2, RunAt: time.Now().Add(time.Hour)}
+	done := &models.Job{Type: "a", SubjectID: 3}
+	noSubject := &models.Job{Type: "a"}
+	otherType := &models.Job{Type: "b", SubjectID: 4}
+	for _, job := range []*models.Job{running, pending, done, noSubject, otherType} {
+		require.NoError(t, repo.Enqueue(job))
+	}
+	claimed, err := repo.Claim("owner-1", []string{"a"}, time.Minute, 3)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+	require.NoError(t, repo.Complete(done.ID, "owner-1"))
+	require.NoError(t, repo.Reschedule(noSubject.ID, "owner-1", time.Now(), "retry"))
+
+	list, err := repo.ListRunningByOwner("owner-1", "a")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, running.ID, list[0].ID)
+
+	list, err = repo.ListRunningByOwner("owner-2", "a")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	var subjects []uint
+	require.NoError(t, repo.ActiveSubjectsQuery("a").Pluck("subject_id", &subjects).Error)
+	assert.ElementsMatch(t, []uint{1, 2}, subjects)
+}
+
+func setupJobsTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	return db
+}
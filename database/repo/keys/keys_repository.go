@@ -26,6 +26,15 @@ func NewRepository(db *gorm.DB) *Repository {
 
 // GetUserByApiToken 通过 API Token 获取用户
 func (r *Repository) GetUserByApiToken(token string) (*models.User, error) {
+	apiToken, err := r.GetApiToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return &apiToken.User, nil
+}
+
+// GetApiToken 通过 API Token 获取启用中的 Token 记录（含用户）
+func (r *Repository) GetApiToken(token string) (*models.ApiToken, error) {
 	if token == "" {
 		return nil, errors.New("invalid or non-existent API token")
 	}
@@ -53,7 +62,7 @@ func (r *Repository) GetUserByApiToken(token string) (*models.User, error) {
 		}()
 		r.updateTokenLastUsed(apiToken.ID)
 	}()
-	return &apiToken.User, nil
+	return &apiToken, nil
 }
 
 // updateTokenLastUsed 更新 Token 最后使用时间
@@ -134,6 +143,22 @@ func (r *Repository) EnableApiToken(tokenID, userID uint) error {
 	return nil
 }
 
+// UpdateDefaultImageTTL 更新 Token 上传图片的默认有效期（秒）
+func (r *Repository) UpdateDefaultImageTTL(tokenID, userID uint, ttlSeconds int64) error {
+	if tokenID == 0 || userID == 0 {
+		return errors.New("invalid token ID or user ID")
+	}
+
+	result := r.db.Model(&models.ApiToken{}).Where("id = ? AND user_id = ?", tokenID, userID).Update("default_image_ttl", ttlSeconds)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // GetTokenByID 通过 ID 获取 Token
 func (r *Repository) GetTokenByID(tokenID uint) (*models.ApiToken, error) {
 	var token models.ApiToken
@@ -0,0 +1,243 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTagNotFound 标签未找到或无权限
+	ErrTagNotFound = errors.New("tag not found or access denied")
+	// ErrTagExists 同名标签已存在
+	ErrTagExists = errors.New("tag already exists")
+)
+
+// Repository 标签仓库
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建新的标签仓库
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// TagInfo 包含图片数量的标签信息
+type TagInfo struct {
+	ID         uint   `json:"id"`
+	Name       string `json:"name"`
+	ImageCount int64  `json:"image_count"`
+}
+
+// ListTags 获取用户全部标签及图片数量，按名称排序
+func (r *Repository) ListTags(userID uint) ([]*TagInfo, error) {
+	return r.queryTagInfos(userID, "", 0)
+}
+
+// SearchTags 按名称前缀搜索用户标签，常用标签优先，用于输入补全
+func (r *Repository) SearchTags(userID uint, prefix string, limit int) ([]*TagInfo, error) {
+	return r.queryTagInfos(userID, prefix, limit)
+}
+
+func (r *Repository) queryTagInfos(userID uint, prefix string, limit int) ([]*TagInfo, error) {
+	db := r.db.Table("tags").
+		Select("tags.id, tags.name, COUNT(images.id) AS image_count").
+		Joins("LEFT JOIN image_tags ON image_tags.tag_id = tags.id").
+		// 回收站中的图片保留标签关联以便恢复，但不计入标签的图片数
+		Joins("LEFT JOIN images ON images.id = image_tags.image_id AND images.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id, tags.name")
+
+	if prefix != "" {
+		escaped := strings.NewReplacer("%", "\\%", "_", "\\_").Replace(prefix)
+		db = db.Where("tags.name LIKE ? ESCAPE '\\'", escaped+"%").Order("image_count desc")
+	}
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+
+	infos := []*TagInfo{}
+	err := db.Order("tags.name asc").Scan(&infos).Error
+	return infos, err
+}
+
+// GetTagByID 获取用户的标签
+func (r *Repository) GetTagByID(tagID, userID uint) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.First(&tag, "id = ? AND user_id = ?", tagID, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag 创建标签
+func (r *Repository) CreateTag(tag *models.Tag) error {
+	exists, err := r.tagNameExists(r.db, tag.UserID, tag.Name, 0)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrTagExists
+	}
+	return r.db.Create(tag).Error
+}
+
+// RenameTag 重命名标签
+func (r *Repository) RenameTag(tagID, userID uint, name string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, "id = ? AND user_id = ?", tagID, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+		exists, err := r.tagNameExists(tx, userID, name, tagID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrTagExists
+		}
+		tag.Name = name
+		if err := tx.Model(&tag).Update("name", name).Error; err != nil {
+			return err
+		}
+		return fulltext.RefreshTag(tx, tag.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag 删除标签及其图片关联
+func (r *Repository) DeleteTag(tagID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.First(&tag, "id = ? AND user_id = ?", tagID, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+		var imageIDs []uint
+		if err := tx.Table("image_tags").Where("tag_id = ?", tag.ID).Pluck("image_id", &imageIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("image_tags").Where("tag_id = ?", tag.ID).Delete(nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		return fulltext.Refresh(tx, imageIDs)
+	})
+}
+
+func (r *Repository) tagNameExists(db *gorm.DB, userID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	query := db.Model(&models.Tag{}).Where("user_id = ? AND name = ?", userID, name)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// TagImages 为用户的图片批量添加标签，不存在的标签自动创建，返回新增的关联数
+func (r *Repository) TagImages(userID uint, imageIDs []uint, names []string) (int64, error) {
+	if len(imageIDs) == 0 || len(names) == 0 {
+		return 0, nil
+	}
+
+	var inserted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		tagIDs, err := ensureTags(tx, userID, names)
+		if err != nil {
+			return err
+		}
+
+		imageIDs = uniqueIDs(imageIDs)
+		associations := make([]map[string]any, 0, len(imageIDs)*len(tagIDs))
+		for _, imageID := range imageIDs {
+			for _, tagID := range tagIDs {
+				associations = append(associations, map[string]any{"image_id": imageID, "tag_id": tagID})
+			}
+		}
+		res := tx.Table("image_tags").Clauses(clause.OnConflict{DoNothing: true}).Create(associations)
+		if res.Error != nil {
+			return res.Error
+		}
+		inserted = res.RowsAffected
+		return fulltext.Refresh(tx, imageIDs)
+	})
+	return inserted, err
+}
+
+// UntagImages 从用户的图片上批量移除标签，返回删除的关联数
+func (r *Repository) UntagImages(userID uint, imageIDs []uint, names []string) (int64, error) {
+	if len(imageIDs) == 0 || len(names) == 0 {
+		return 0, nil
+	}
+
+	imageIDs = uniqueIDs(imageIDs)
+	var removed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		tagIDs := tx.Model(&models.Tag{}).Select("id").Where("user_id = ? AND name IN ?", userID, names)
+		res := tx.Table("image_tags").
+			Where("image_id IN ? AND tag_id IN (?)", imageIDs, tagIDs).
+			Delete(nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+		return fulltext.Refresh(tx, imageIDs)
+	})
+	return removed, err
+}
+
+// ensureTags 获取或创建用户的同名标签，返回标签 ID
+func ensureTags(tx *gorm.DB, userID uint, names []string) ([]uint, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{UserID: userID, Name: name})
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoNothing: true,
+	}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	err := tx.Model(&models.Tag{}).Where("user_id = ? AND name IN ?", userID, names).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+// WithContext 返回带上下文的仓库
+func (r *Repository) WithContext(ctx context.Context) *Repository {
+	return &Repository{db: r.db.WithContext(ctx)}
+}
@@ -0,0 +1,167 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTagsRepositoryTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.Tag{}))
+	return db
+}
+
+func createTestImages(t *testing.T, db *gorm.DB, userID uint, identifiers ...string) []uint {
+	t.Helper()
+
+	ids := make([]uint, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		img := &models.Image{
+			Identifier:   identifier,
+			OriginalName: identifier + ".jpg",
+			FileHash:     "hash-" + identifier,
+			MimeType:     "image/jpeg",
+			StoragePath:  "original/" + identifier + ".jpg",
+			UserID:       userID,
+		}
+		require.NoError(t, db.Create(img).Error)
+		ids = append(ids, img.ID)
+	}
+	return ids
+}
+
+func imageTagNames(t *testing.T, db *gorm.DB, imageID uint) []string {
+	t.Helper()
+
+	var names []string
+	require.NoError(t, db.Table("image_tags").
+		Joins("JOIN tags ON tags.id = image_tags.tag_id").
+		Where("image_tags.image_id = ?", imageID).
+		Order("tags.name asc").
+		Pluck("tags.name", &names).Error)
+	return names
+}
+
+func TestTagImagesCreatesTagsAndIsIdempotent(t *testing.T) {
+	db := setupTagsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	ids := createTestImages(t, db, 1, "a", "b")
+
+	inserted, err := repo.TagImages(1, ids, []string{"travel", "2026"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), inserted)
+
+	inserted, err = repo.TagImages(1, []uint{ids[0], ids[0]}, []string{"travel", "family"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+
+	infos, err := repo.ListTags(1)
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+	assert.Equal(t, "2026", infos[0].Name)
+	assert.Equal(t, int64(2), infos[0].ImageCount)
+	assert.Equal(t, "family", infos[1].Name)
+	assert.Equal(t, int64(1), infos[1].ImageCount)
+
+	assert.Equal(t, []string{"2026", "family", "travel"}, imageTagNames(t, db, ids[0]))
+	assert.Equal(t, []string{"2026", "travel"}, imageTagNames(t, db, ids[1]))
+}
+
+func TestUntagImagesOnlyAffectsOwnTags(t *testing.T) {
+	db := setupTagsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	mine := createTestImages(t, db, 1, "mine")
+	theirs := createTestImages(t, db, 2, "theirs")
+
+	_, err := repo.TagImages(1, mine, []string{"shared"})
+	require.NoError(t, err)
+	_, err = repo.TagImages(2, theirs, []string{"shared"})
+	require.NoError(t, err)
+
+	removed, err := repo.UntagImages(1, append(mine, theirs...), []string{"shared"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	assert.Equal(t, []string{"shared"}, imageTagNames(t, db, theirs[0]))
+}
+
+func TestSearchTagsByPrefix(t *testing.T) {
+	db := setupTagsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	ids := createTestImages(t, db, 1, "a", "b")
+
+	_, err := repo.TagImages(1, ids, []string{"project-x"})
+	require.NoError(t, err)
+	_, err = repo.TagImages(1, ids[:1], []string{"project-a", "personal", "100%"})
+	require.NoError(t, err)
+
+	infos, err := repo.SearchTags(1, "pro", 10)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "project-x", infos[0].Name, "most used tag first")
+	assert.Equal(t, "project-a", infos[1].Name)
+
+	infos, err = repo.SearchTags(1, "100%", 10)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+
+	infos, err = repo.SearchTags(2, "pro", 10)
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+}
+
+func TestListTagsIgnoresTrashedImages(t *testing.T) {
+	db := setupTagsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	ids := createTestImages(t, db, 1, "kept", "trashed")
+
+	_, err := repo.TagImages(1, ids, []string{"holiday"})
+	require.NoError(t, err)
+	require.NoError(t, db.Delete(&models.Image{}, ids[1]).Error)
+
+	infos, err := repo.SearchTags(1, "holi", 10)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, int64(1), infos[0].ImageCount)
+}
+
+func TestCreateRenameDeleteTag(t *testing.T) {
+	db := setupTagsRepositoryTestDB(t)
+	repo := NewRepository(db)
+	ids := createTestImages(t, db, 1, "a")
+
+	tag := &models.Tag{UserID: 1, Name: "cats"}
+	require.NoError(t, repo.CreateTag(tag))
+	assert.ErrorIs(t, repo.CreateTag(&models.Tag{UserID: 1, Name: "cats"}), ErrTagExists)
+	require.NoError(t, repo.CreateTag(&models.Tag{UserID: 2, Name: "cats"}))
+	require.NoError(t, repo.CreateTag(&models.Tag{UserID: 1, Name: "dogs"}))
+
+	_, err := repo.RenameTag(tag.ID, 1, "dogs")
+	assert.ErrorIs(t, err, ErrTagExists)
+	_, err = repo.RenameTag(tag.ID, 2, "kittens")
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	renamed, err := repo.RenameTag(tag.ID, 1, "kittens")
+	require.NoError(t, err)
+	assert.Equal(t, "kittens", renamed.Name)
+
+	_, err = repo.TagImages(1, ids, []string{"kittens"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, repo.DeleteTag(tag.ID, 2), ErrTagNotFound)
+	require.NoError(t, repo.DeleteTag(tag.ID, 1))
+
+	var links int64
+	require.NoError(t, db.Table("image_tags").Count(&links).Error)
+	assert.Zero(t, links)
+}
@@ -11,12 +11,20 @@ type Service struct {
 
 type AlbumInfo = albums.AlbumInfo
 
+type AlbumNode = albums.AlbumNode
+
 func NewService(repo *albums.Repository) *Service {
 	return &Service{repo: repo}
 }
 
-// ErrAlbumNotFound 相册未找到或无权限
-var ErrAlbumNotFound = albums.ErrAlbumNotFound
+var (
+	// ErrAlbumNotFound 相册未找到或无权限
+	ErrAlbumNotFound = albums.ErrAlbumNotFound
+	// ErrInvalidAlbumParent 父相册不存在或会形成循环
+	ErrInvalidAlbumParent = albums.ErrInvalidAlbumParent
+	// ErrImageNotInAlbum 图片不在相册中
+	ErrImageNotInAlbum = albums.ErrImageNotInAlbum
+)
 
 func (s *Service) GetAlbumWithImagesByID(albumID, userID uint) (*models.Album, error) {
 	return s.repo.GetAlbumWithImagesByID(albumID, userID)
@@ -49,3 +57,27 @@ func (s *Service) RemoveImageFromAlbum(albumID, userID uint, image *models.Image
 func (s *Service) RemoveImagesFromAlbum(albumID, userID uint, imageIDs []uint) (int64, error) {
 	return s.repo.RemoveImagesFromAlbum(albumID, userID, imageIDs)
 }
+
+func (s *Service) GetChildAlbums(userID, parentID uint, page, limit int) ([]*AlbumInfo, int64, error) {
+	return s.repo.GetChildAlbums(userID, parentID, page, limit)
+}
+
+func (s *Service) GetAlbumTree(userID uint) ([]*AlbumNode, error) {
+	return s.repo.GetAlbumTree(userID)
+}
+
+func (s *Service) GetAlbumSubtree(albumID, userID uint) (*AlbumNode, error) {
+	return s.repo.GetAlbumSubtree(albumID, userID)
+}
+
+func (s *Service) MoveAlbum(albumID, userID uint, parentID *uint) error {
+	return s.repo.MoveAlbum(albumID, userID, parentID)
+}
+
+func (s *Service) ReorderImages(albumID, userID uint, imageIDs []uint) error {
+	return s.repo.ReorderImages(albumID, userID, imageIDs)
+}
+
+func (s *Service) SetCover(albumID, userID uint, imageID *uint) error {
+	return s.repo.SetCover(albumID, userID, imageID)
+}
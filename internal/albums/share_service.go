@@ -0,0 +1,212 @@
+package albums
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/albums"
+	"github.com/anoixa/image-bed/utils"
+	cryptopackage "github.com/anoixa/image-bed/utils/crypto"
+	"gorm.io/gorm"
+)
+
+// shareTokenBytes 分享令牌的随机字节数，编码后为 24 个 URL 安全字符
+const shareTokenBytes = 18
+
+// sharedImageGracePeriod 访问次数用完后图片仍可加载的时间，保证用掉最后一次访问的访客能看完相册
+var sharedImageGracePeriod = 10 * time.Minute
+
+var (
+	// ErrShareNotFound 分享链接不存在
+	ErrShareNotFound = albums.ErrShareNotFound
+	// ErrShareRevoked 分享链接已撤销
+	ErrShareRevoked = errors.New("share link has been revoked")
+	// ErrShareExpired 分享链接已过期
+	ErrShareExpired = errors.New("share link has expired")
+	// ErrShareViewLimitReached 分享链接访问次数已用完
+	ErrShareViewLimitReached = errors.New("share link view limit reached")
+	// ErrSharePasswordRequired 需要密码才能访问
+	ErrSharePasswordRequired = errors.New("share link requires a password")
+	// ErrInvalidSharePassword 密码错误
+	ErrInvalidSharePassword = errors.New("invalid share link password")
+	// ErrInvalidShareOptions 分享参数不合法
+	ErrInvalidShareOptions = errors.New("invalid share options")
+)
+
+// ShareOptions 创建分享链接的参数
+type ShareOptions struct {
+	Password  string     // 为空表示无需密码
+	ExpiresAt *time.Time // 为空表示永不过期
+	MaxViews  int        // 0 表示不限访问次数
+}
+
+// ShareService 相册分享链接服务
+type ShareService struct {
+	repo       *albums.ShareRepository
+	albumsRepo *albums.Repository
+}
+
+func NewShareService(repo *albums.ShareRepository, albumsRepo *albums.Repository) *ShareService {
+	return &ShareService{repo: repo, albumsRepo: albumsRepo}
+}
+
+// CreateShare 为用户的相册创建分享链接
+func (s *ShareService) CreateShare(ctx context.Context, albumID, userID uint, opts ShareOptions) (*models.AlbumShare, error) {
+	if opts.MaxViews < 0 {
+		return nil, fmt.Errorf("%w: max views must not be negative", ErrInvalidShareOptions)
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidShareOptions)
+	}
+	if err := s.checkAlbumOwner(albumID, userID); err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(shareTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	share := &models.AlbumShare{
+		AlbumID:   albumID,
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: opts.ExpiresAt,
+		MaxViews:  opts.MaxViews,
+	}
+	if opts.Password != "" {
+		if share.PasswordHash, err = cryptopackage.GenerateFromPassword(opts.Password); err != nil {
+			return nil, fmt.Errorf("failed to hash share password: %w", err)
+		}
+	}
+	if err := s.repo.WithContext(ctx).CreateShare(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// ListShares 获取用户相册的分享链接
+func (s *ShareService) ListShares(ctx context.Context, albumID, userID uint) ([]*models.AlbumShare, error) {
+	if err := s.checkAlbumOwner(albumID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.WithContext(ctx).ListShares(albumID, userID)
+}
+
+// RevokeShare 撤销分享链接
+func (s *ShareService) RevokeShare(ctx context.Context, shareID, albumID, userID uint) (*models.AlbumShare, error) {
+	return s.repo.WithContext(ctx).RevokeShare(shareID, albumID, userID)
+}
+
+// Unlock 校验密码，返回访问密钥
+func (s *ShareService) Unlock(ctx context.Context, token, password string) (*models.AlbumShare, string, error) {
+	share, err := s.getActiveShare(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+	if !share.HasPassword() {
+		return share, "", nil
+	}
+	ok, err := cryptopackage.ComparePasswordAndHash(password, share.PasswordHash)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to verify share password: %w", err)
+	}
+	if !ok {
+		return nil, "", ErrInvalidSharePassword
+	}
+	return share, AccessKey(share), nil
+}
+
+// OpenAlbum 通过分享链接查看相册，计一次访问
+func (s *ShareService) OpenAlbum(ctx context.Context, token, accessKey string) (*models.AlbumShare, *models.Album, error) {
+	share, err := s.Authorize(ctx, token, accessKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorded, err := s.repo.WithContext(ctx).RecordView(share.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !recorded {
+		return nil, nil, ErrShareViewLimitReached
+	}
+	share.ViewCount++
+
+	album, err := s.albumsRepo.GetAlbumWithImagesByID(share.AlbumID, share.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShareNotFound
+		}
+		return nil, nil, err
+	}
+	return share, album, nil
+}
+
+// GetSharedImage 通过分享链接获取相册中的图片。图片访问不计次数，访问次数用完后只在最后一次访问后的
+// sharedImageGracePeriod 内可用，之前下发的图片链接随之失效
+func (s *ShareService) GetSharedImage(ctx context.Context, token, accessKey, identifier string) (*models.AlbumShare, *models.Image, error) {
+	share, err := s.Authorize(ctx, token, accessKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if share.ViewsUsedUp() && (share.LastViewedAt == nil || time.Since(*share.LastViewedAt) > sharedImageGracePeriod) {
+		return nil, nil, ErrShareViewLimitReached
+	}
+	image, err := s.repo.WithContext(ctx).GetSharedImage(share.AlbumID, identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	return share, image, nil
+}
+
+// Authorize 校验分享链接状态和访问密钥
+func (s *ShareService) Authorize(ctx context.Context, token, accessKey string) (*models.AlbumShare, error) {
+	share, err := s.getActiveShare(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if share.HasPassword() && !hmac.Equal([]byte(accessKey), []byte(AccessKey(share))) {
+		return nil, ErrSharePasswordRequired
+	}
+	return share, nil
+}
+
+func (s *ShareService) getActiveShare(ctx context.Context, token string) (*models.AlbumShare, error) {
+	share, err := s.repo.WithContext(ctx).GetShareByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if share.IsRevoked() {
+		return nil, ErrShareRevoked
+	}
+	if share.IsExpired(time.Now()) {
+		return nil, ErrShareExpired
+	}
+	return share, nil
+}
+
+func (s *ShareService) checkAlbumOwner(albumID, userID uint) error {
+	album, err := s.albumsRepo.GetAlbumByID(albumID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAlbumNotFound
+		}
+		return err
+	}
+	if album.UserID != userID {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// AccessKey 返回密码保护链接解锁后的访问密钥，由密码哈希派生因此无需额外存储
+func AccessKey(share *models.AlbumShare) string {
+	mac := hmac.New(sha256.New, []byte(share.PasswordHash))
+	mac.Write([]byte(share.Token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
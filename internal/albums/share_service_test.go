@@ -0,0 +1,119 @@
+package albums
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/albums"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupShareService(t *testing.T) (*ShareService, *models.Album) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, models.SetupJoinTables(db))
+	require.NoError(t, db.AutoMigrate(&models.Album{}, &models.Image{}, &models.AlbumShare{}))
+
+	album := &models.Album{UserID: 1, Name: "clients"}
+	require.NoError(t, db.Create(album).Error)
+	image := &models.Image{Identifier: "shot-1", OriginalName: "shot.png", FileHash: "hash-shot-1", UserID: 1, IsPublic: false}
+	require.NoError(t, db.Create(image).Error)
+	require.NoError(t, db.Table("album_images").Create(map[string]any{"album_id": album.ID, "image_id": image.ID}).Error)
+
+	return NewShareService(albums.NewShareRepository(db), albums.NewRepository(db)), album
+}
+
+func TestCreateShareRejectsOtherUsersAlbum(t *testing.T) {
+	svc, album := setupShareService(t)
+
+	_, err := svc.CreateShare(context.Background(), album.ID, 2, ShareOptions{})
+	assert.ErrorIs(t, err, ErrAlbumNotFound)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = svc.CreateShare(context.Background(), album.ID, 1, ShareOptions{ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidShareOptions)
+}
+
+func TestPasswordProtectedShare(t *testing.T) {
+	ctx := context.Background()
+	svc, album := setupShareService(t)
+
+	share, err := svc.CreateShare(ctx, album.ID, 1, ShareOptions{Password: "s3cret"})
+	require.NoError(t, err)
+	assert.True(t, share.HasPassword())
+
+	_, _, err = svc.OpenAlbum(ctx, share.Token, "")
+	assert.ErrorIs(t, err, ErrSharePasswordRequired)
+
+	_, _, err = svc.Unlock(ctx, share.Token, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidSharePassword)
+
+	_, key, err := svc.Unlock(ctx, share.Token, "s3cret")
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	_, opened, err := svc.OpenAlbum(ctx, share.Token, key)
+	require.NoError(t, err)
+	require.Len(t, opened.Images, 1)
+
+	_, image, err := svc.GetSharedImage(ctx, share.Token, key, "shot-1")
+	require.NoError(t, err)
+	assert.Equal(t, "shot-1", image.Identifier)
+	_, _, err = svc.GetSharedImage(ctx, share.Token, "", "shot-1")
+	assert.ErrorIs(t, err, ErrSharePasswordRequired)
+}
+
+func TestShareViewLimitAndRevocation(t *testing.T) {
+	ctx := context.Background()
+	svc, album := setupShareService(t)
+
+	share, err := svc.CreateShare(ctx, album.ID, 1, ShareOptions{MaxViews: 1})
+	require.NoError(t, err)
+
+	opened, _, err := svc.OpenAlbum(ctx, share.Token, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, opened.ViewCount)
+	_, _, err = svc.OpenAlbum(ctx, share.Token, "")
+	assert.ErrorIs(t, err, ErrShareViewLimitReached)
+
+	_, _, err = svc.GetSharedImage(ctx, share.Token, "", "shot-1")
+	require.NoError(t, err, "the last viewer can still load the album's images")
+	sharedImageGracePeriod = 0
+	t.Cleanup(func() { sharedImageGracePeriod = 10 * time.Minute })
+	_, _, err = svc.GetSharedImage(ctx, share.Token, "", "shot-1")
+	assert.ErrorIs(t, err, ErrShareViewLimitReached, "image links stop working once the views are used up")
+
+	_, err = svc.RevokeShare(ctx, share.ID, album.ID, 1)
+	require.NoError(t, err)
+	_, _, err = svc.GetSharedImage(ctx, share.Token, "", "shot-1")
+	assert.ErrorIs(t, err, ErrShareRevoked)
+}
+
+func TestExpiredShare(t *testing.T) {
+	ctx := context.Background()
+	svc, album := setupShareService(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	share, err := svc.CreateShare(ctx, album.ID, 1, ShareOptions{ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	_, err = svc.Authorize(ctx, share.Token, "")
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Second)
+	require.NoError(t, svc.repo.WithContext(ctx).CreateShare(&models.AlbumShare{AlbumID: album.ID, UserID: 1, Token: "expired", ExpiresAt: &expired}))
+	_, err = svc.Authorize(ctx, "expired", "")
+	assert.ErrorIs(t, err, ErrShareExpired)
+
+	_, err = svc.Authorize(ctx, "missing", "")
+	assert.ErrorIs(t, err, ErrShareNotFound)
+}
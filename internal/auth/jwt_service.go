@@ -284,15 +284,16 @@ func (s *JWTService) ValidateStaticToken(ctx context.Context, token string) (*St
 		return nil, errors.New("keys repository not initialized")
 	}
 
-	user, err := s.keysRepo.GetUserByApiToken(token)
+	apiToken, err := s.keysRepo.GetApiToken(token)
 	if err != nil {
 		return nil, err
 	}
 
 	return &StaticTokenUser{
-		ID:       user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		ID:              apiToken.User.ID,
+		Username:        apiToken.User.Username,
+		Role:            apiToken.User.Role,
+		DefaultImageTTL: time.Duration(apiToken.DefaultImageTTL) * time.Second,
 	}, nil
 }
 
@@ -301,4 +302,6 @@ type StaticTokenUser struct {
 	ID       uint
 	Username string
 	Role     string
+	// DefaultImageTTL 该令牌上传图片的默认有效期，0 为永久保存
+	DefaultImageTTL time.Duration
 }
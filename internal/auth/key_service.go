@@ -39,3 +39,8 @@ func (s *KeyService) EnableApiToken(tokenID, userID uint) error {
 func (s *KeyService) RevokeApiToken(tokenID, userID uint) error {
 	return s.repo.RevokeApiToken(tokenID, userID)
 }
+
+// UpdateDefaultImageTTL 更新 API Token 上传图片的默认有效期（秒）
+func (s *KeyService) UpdateDefaultImageTTL(tokenID, userID uint, ttlSeconds int64) error {
+	return s.repo.UpdateDefaultImageTTL(tokenID, userID, ttlSeconds)
+}
@@ -0,0 +1,291 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/dashboard"
+	"github.com/anoixa/image-bed/utils/format"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPeriod 统计周期不合法
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrInvalidSort 排序字段不合法
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrStatImageNotFound 图片不存在或不属于当前用户
+	ErrStatImageNotFound = errors.New("image not found")
+)
+
+const (
+	defaultTopImagesLimit = 20
+	maxTopImagesLimit     = 100
+	breakdownLimit        = 20
+)
+
+// AnalyticsRepository 图片访问统计查询
+type AnalyticsRepository interface {
+	GetTopImages(ctx context.Context, userID uint, since time.Time, sort string, limit int) ([]dashboard.ImageStatRank, error)
+	GetImageStatTotals(ctx context.Context, imageID uint, since time.Time) (*dashboard.ImageStatTotals, error)
+	GetImageStatSeries(ctx context.Context, imageID uint, since time.Time) ([]dashboard.ImageStatPoint, error)
+	GetImageStatBreakdown(ctx context.Context, imageID uint, since time.Time, by string, limit int) ([]dashboard.ImageStatGroup, error)
+	GetUserStatTotals(ctx context.Context, userID uint, since time.Time) ([]dashboard.UserStatTotal, error)
+	GetStatImage(ctx context.Context, identifier string) (*models.Image, error)
+}
+
+// AnalyticsPeriod 统计周期，24h 按小时、其余按天出图
+type AnalyticsPeriod struct {
+	Name  string
+	Hours int
+	Daily bool
+}
+
+var analyticsPeriods = map[string]AnalyticsPeriod{
+	"24h": {Name: "24h", Hours: 24},
+	"7d":  {Name: "7d", Hours: 7 * 24, Daily: true},
+	"30d": {Name: "30d", Hours: 30 * 24, Daily: true},
+	"90d": {Name: "90d", Hours: 90 * 24, Daily: true},
+}
+
+// ParsePeriod 解析统计周期（24h、7d、30d、90d），为空时默认 7d
+func ParsePeriod(value string) (AnalyticsPeriod, error) {
+	if value == "" {
+		value = "7d"
+	}
+	period, ok := analyticsPeriods[value]
+	if !ok {
+		return AnalyticsPeriod{}, ErrInvalidPeriod
+	}
+	return period, nil
+}
+
+// start 周期内第一个点的起始时间：按小时为 24 个整点，按天为本地时间的零点
+func (p AnalyticsPeriod) start(now time.Time) time.Time {
+	if !p.Daily {
+		return now.Truncate(time.Hour).Add(-time.Duration(p.Hours-1) * time.Hour)
+	}
+	days := p.Hours / 24
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(days - 1))
+}
+
+// AnalyticsService 图片访问量和流量统计
+type AnalyticsService struct {
+	repo AnalyticsRepository
+}
+
+// NewAnalyticsService 创建访问统计服务
+func NewAnalyticsService(repo AnalyticsRepository) *AnalyticsService {
+	return &AnalyticsService{repo: repo}
+}
+
+// TrafficStats 访问量和流量，hotlink 为来自外站引用的部分
+type TrafficStats struct {
+	Hits              int64  `json:"hits"`
+	Bytes             int64  `json:"bytes"`
+	BytesHuman        string `json:"bytes_human"`
+	HotlinkHits       int64  `json:"hotlink_hits"`
+	HotlinkBytes      int64  `json:"hotlink_bytes"`
+	HotlinkBytesHuman string `json:"hotlink_bytes_human"`
+}
+
+func newTrafficStats(totals dashboard.ImageStatTotals) TrafficStats {
+	return TrafficStats{
+		Hits:              totals.Hits,
+		Bytes:             totals.Bytes,
+		BytesHuman:        format.HumanReadableSize(totals.Bytes),
+		HotlinkHits:       totals.HotlinkHits,
+		HotlinkBytes:      totals.HotlinkBytes,
+		HotlinkBytesHuman: format.HumanReadableSize(totals.HotlinkBytes),
+	}
+}
+
+type TopImageItem struct {
+	ImageID      uint   `json:"image_id"`
+	UserID       uint   `json:"user_id"`
+	Identifier   string `json:"identifier"`
+	OriginalName string `json:"original_name"`
+	TrafficStats
+}
+
+type TopImagesResponse struct {
+	Period string         `json:"period"`
+	Sort   string         `json:"sort"`
+	Images []TopImageItem `json:"images"`
+}
+
+type StatPoint struct {
+	Time  string `json:"time"`
+	Hits  int64  `json:"hits"`
+	Bytes int64  `json:"bytes"`
+}
+
+type StatGroup struct {
+	Name  string `json:"name"`
+	Hits  int64  `json:"hits"`
+	Bytes int64  `json:"bytes"`
+}
+
+type ImageStatsResponse struct {
+	Identifier string       `json:"identifier"`
+	Period     string       `json:"period"`
+	Interval   string       `json:"interval"` // hour 或 day
+	Totals     TrafficStats `json:"totals"`
+	Series     []StatPoint  `json:"series"`
+	Variants   []StatGroup  `json:"variants"`
+	Referrers  []StatGroup  `json:"referrers"` // 空名称表示无来源或来自本站
+}
+
+type UserStatsItem struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Images   int64  `json:"images"`
+	TrafficStats
+}
+
+type UserStatsResponse struct {
+	Period string          `json:"period"`
+	Users  []UserStatsItem `json:"users"`
+}
+
+// GetTopImages 获取周期内访问最多的图片，sort 为 hits、bytes、hotlink_hits 或 hotlink_bytes；userID 为 0 时统计所有用户
+func (s *AnalyticsService) GetTopImages(ctx context.Context, userID uint, period AnalyticsPeriod, sort string, limit int) (*TopImagesResponse, error) {
+	switch sort {
+	case "":
+		sort = dashboard.ImageStatSortHits
+	case dashboard.ImageStatSortHits, dashboard.ImageStatSortBytes, dashboard.ImageStatSortHotlinkHits, dashboard.ImageStatSortHotlinkBytes:
+	default:
+		return nil, ErrInvalidSort
+	}
+	if limit <= 0 {
+		limit = defaultTopImagesLimit
+	}
+	limit = min(limit, maxTopImagesLimit)
+
+	since := time.Now().Add(-time.Duration(period.Hours) * time.Hour)
+	ranks, err := s.repo.GetTopImages(ctx, userID, since, sort, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TopImageItem, len(ranks))
+	for i, rank := range ranks {
+		items[i] = TopImageItem{
+			ImageID:      rank.ImageID,
+			UserID:       rank.UserID,
+			Identifier:   rank.Identifier,
+			OriginalName: rank.OriginalName,
+			TrafficStats: newTrafficStats(rank.ImageStatTotals),
+		}
+	}
+	return &TopImagesResponse{Period: period.Name, Sort: sort, Images: items}, nil
+}
+
+// GetImageStats 获取单张图片周期内的访问曲线和按变体、来源的分布；userID 非 0 时只能查看自己的图片
+func (s *AnalyticsService) GetImageStats(ctx context.Context, identifier string, userID uint, period AnalyticsPeriod) (*ImageStatsResponse, error) {
+	image, err := s.repo.GetStatImage(ctx, identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStatImageNotFound
+		}
+		return nil, err
+	}
+	if userID != 0 && image.UserID != userID {
+		return nil, ErrStatImageNotFound
+	}
+
+	since := period.start(time.Now())
+	totals, err := s.repo.GetImageStatTotals(ctx, image.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.repo.GetImageStatSeries(ctx, image.ID, since)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := s.repo.GetImageStatBreakdown(ctx, image.ID, since, dashboard.ImageStatByVariant, breakdownLimit)
+	if err != nil {
+		return nil, err
+	}
+	referrers, err := s.repo.GetImageStatBreakdown(ctx, image.ID, since, dashboard.ImageStatByReferrer, breakdownLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := "hour"
+	if period.Daily {
+		interval = "day"
+	}
+	return &ImageStatsResponse{
+		Identifier: image.Identifier,
+		Period:     period.Name,
+		Interval:   interval,
+		Totals:     newTrafficStats(*totals),
+		Series:     buildSeries(points, period, since),
+		Variants:   toStatGroups(variants),
+		Referrers:  toStatGroups(referrers),
+	}, nil
+}
+
+// GetUserStats 获取周期内各用户图片的访问汇总；userID 非 0 时只返回该用户
+func (s *AnalyticsService) GetUserStats(ctx context.Context, userID uint, period AnalyticsPeriod) (*UserStatsResponse, error) {
+	since := time.Now().Add(-time.Duration(period.Hours) * time.Hour)
+	totals, err := s.repo.GetUserStatTotals(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]UserStatsItem, len(totals))
+	for i, total := range totals {
+		users[i] = UserStatsItem{
+			UserID:       total.UserID,
+			Username:     total.Username,
+			Images:       total.Images,
+			TrafficStats: newTrafficStats(total.ImageStatTotals),
+		}
+	}
+	return &UserStatsResponse{Period: period.Name, Users: users}, nil
+}
+
+// buildSeries 把按小时的统计补齐为连续的时间点，按天统计时以本地日期汇总
+func buildSeries(points []dashboard.ImageStatPoint, period AnalyticsPeriod, since time.Time) []StatPoint {
+	layout, count := time.RFC3339, period.Hours
+	if period.Daily {
+		layout, count = "2006-01-02", period.Hours/24
+	}
+
+	series := make([]StatPoint, count)
+	index := make(map[string]int, count)
+	for i := range series {
+		var t time.Time
+		if period.Daily {
+			t = since.AddDate(0, 0, i)
+		} else {
+			t = since.Add(time.Duration(i) * time.Hour).UTC()
+		}
+		series[i].Time = t.Format(layout)
+		index[series[i].Time] = i
+	}
+
+	for _, point := range points {
+		t := point.Bucket.UTC()
+		if period.Daily {
+			t = point.Bucket.In(since.Location())
+		}
+		if i, ok := index[t.Format(layout)]; ok {
+			series[i].Hits += point.Hits
+			series[i].Bytes += point.Bytes
+		}
+	}
+	return series
+}
+
+func toStatGroups(groups []dashboard.ImageStatGroup) []StatGroup {
+	result := make([]StatGroup, len(groups))
+	for i, group := range groups {
+		result[i] = StatGroup(group)
+	}
+	return result
+}
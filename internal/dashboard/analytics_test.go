@@ -0,0 +1,43 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/repo/dashboard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriod(t *testing.T) {
+	period, err := ParsePeriod("")
+	require.NoError(t, err)
+	assert.Equal(t, "7d", period.Name)
+
+	_, err = ParsePeriod("1y")
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func TestBuildSeriesFillsGaps(t *testing.T) {
+	now := time.Date(2026, 5, 3, 10, 20, 0, 0, time.UTC)
+
+	hourly, _ := ParsePeriod("24h")
+	since := hourly.start(now)
+	series := buildSeries([]dashboard.ImageStatPoint{
+		{Bucket: time.Date(2026, 5, 3, 10, 0, 0, 0, time.UTC), Hits: 4, Bytes: 40},
+	}, hourly, since)
+	require.Len(t, series, 24)
+	assert.Equal(t, "2026-05-02T11:00:00Z", series[0].Time)
+	assert.Equal(t, StatPoint{Time: "2026-05-03T10:00:00Z", Hits: 4, Bytes: 40}, series[23])
+
+	daily, _ := ParsePeriod("7d")
+	since = daily.start(now)
+	series = buildSeries([]dashboard.ImageStatPoint{
+		{Bucket: time.Date(2026, 5, 3, 1, 0, 0, 0, time.UTC), Hits: 1, Bytes: 10},
+		{Bucket: time.Date(2026, 5, 3, 9, 0, 0, 0, time.UTC), Hits: 2, Bytes: 20},
+		{Bucket: time.Date(2026, 4, 20, 9, 0, 0, 0, time.UTC), Hits: 100, Bytes: 100},
+	}, daily, since)
+	require.Len(t, series, 7)
+	assert.Equal(t, "2026-04-27", series[0].Time)
+	assert.Equal(t, StatPoint{Time: "2026-05-03", Hits: 3, Bytes: 30}, series[6])
+}
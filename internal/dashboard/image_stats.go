@@ -0,0 +1,196 @@
+package dashboard
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/batch"
+)
+
+var imageStatsLog = utils.ForModule("ImageStats")
+
+// imageStatsFlushInterval 访问统计批量写库的间隔
+var imageStatsFlushInterval = time.Minute
+
+// imageStatsPruneInterval 过期统计数据的清理间隔
+var imageStatsPruneInterval = time.Hour
+
+// imageStatsMaxReferrers 每张图片每小时单独统计的来源域名数，其余合并为 models.ImageStatReferrerOther，
+// 防止伪造的 Referer 撑满待写入的统计
+const imageStatsMaxReferrers = 50
+
+// ImageHit 一次图片访问
+type ImageHit struct {
+	ImageID  uint
+	UserID   uint // 图片所有者
+	Variant  string
+	Referrer string // 来源域名，空字符串表示无来源或来自本站
+	Bytes    int64
+	At       time.Time
+}
+
+// ImageStatsWriter 统计数据写入
+type ImageStatsWriter interface {
+	AddImageStats(ctx context.Context, stats []models.ImageStat) error
+}
+
+type imageStatKey struct {
+	imageID  uint
+	bucket   int64
+	variant  string
+	referrer string
+}
+
+type imageHourKey struct {
+	imageID uint
+	bucket  int64
+}
+
+// ImageStatsTracker 在内存中按图片、小时、变体和来源汇总访问，定期批量累加到统计表
+type ImageStatsTracker struct {
+	batch *batch.Batcher[imageStatKey, models.ImageStat]
+
+	mu sync.Mutex
+	// referrers 各图片每小时已单独统计的来源域名，跨多次写库保留到该小时结束
+	referrers    map[imageHourKey]map[string]struct{}
+	referrerHour int64 // 上次清理 referrers 时所在的小时
+}
+
+// activeImageStatsTracker 未启动时不记录访问
+var activeImageStatsTracker atomic.Pointer[ImageStatsTracker]
+
+// NewImageStatsTracker 创建访问统计记录器
+func NewImageStatsTracker(repo ImageStatsWriter) *ImageStatsTracker {
+	return &ImageStatsTracker{
+		batch:     batch.New[imageStatKey](mergeImageStat, repo.AddImageStats),
+		referrers: make(map[imageHourKey]map[string]struct{}),
+	}
+}
+
+func mergeImageStat(existing *models.ImageStat, stat models.ImageStat) {
+	existing.Hits += stat.Hits
+	existing.Bytes += stat.Bytes
+}
+
+// StartImageStatsTracker 启动全局访问统计，返回的函数停止记录并写入剩余数据
+func StartImageStatsTracker(repo ImageStatsWriter) func() {
+	tracker := NewImageStatsTracker(repo)
+	activeImageStatsTracker.Store(tracker)
+
+	stop := tracker.batch.Start(imageStatsFlushInterval, func(err error) {
+		imageStatsLog.Warnf("Failed to record image stats: %v", err)
+	})
+	return func() {
+		activeImageStatsTracker.CompareAndSwap(tracker, nil)
+		stop()
+	}
+}
+
+// RecordImageHit 记录一次图片访问，统计未启动时忽略
+func RecordImageHit(hit ImageHit) {
+	if tracker := activeImageStatsTracker.Load(); tracker != nil {
+		tracker.Record(hit)
+	}
+}
+
+// Record 把访问累加到所在小时的统计中
+func (t *ImageStatsTracker) Record(hit ImageHit) {
+	if hit.ImageID == 0 {
+		return
+	}
+	if hit.At.IsZero() {
+		hit.At = time.Now()
+	}
+	bucket := hit.At.UTC().Truncate(time.Hour)
+	referrer := t.capReferrer(hit.ImageID, bucket.Unix(), hit.Referrer)
+	t.batch.Add(imageStatKey{hit.ImageID, bucket.Unix(), hit.Variant, referrer}, models.ImageStat{
+		ImageID:  hit.ImageID,
+		UserID:   hit.UserID,
+		Bucket:   bucket,
+		Variant:  hit.Variant,
+		Referrer: referrer,
+		Hits:     1,
+		Bytes:    max(hit.Bytes, 0),
+	})
+}
+
+// capReferrer 图片在该小时内的来源域名达到上限后，新出现的域名归入 models.ImageStatReferrerOther
+func (t *ImageStatsTracker) capReferrer(imageID uint, bucket int64, referrer string) string {
+	if referrer == "" || referrer == models.ImageStatReferrerOther {
+		return referrer
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneReferrers(time.Now())
+
+	hour := imageHourKey{imageID, bucket}
+	seen := t.referrers[hour]
+	if _, ok := seen[referrer]; ok {
+		return referrer
+	}
+	if len(seen) >= imageStatsMaxReferrers {
+		return models.ImageStatReferrerOther
+	}
+	if seen == nil {
+		seen = make(map[string]struct{})
+		t.referrers[hour] = seen
+	}
+	seen[referrer] = struct{}{}
+	return referrer
+}
+
+// pruneReferrers 进入新的小时后丢弃上一小时之前的来源域名记录，迟到的访问仍可计入上一小时；调用方持有锁
+func (t *ImageStatsTracker) pruneReferrers(now time.Time) {
+	current := now.UTC().Truncate(time.Hour).Unix()
+	if current == t.referrerHour {
+		return
+	}
+	t.referrerHour = current
+	cutoff := current - int64(time.Hour/time.Second)
+	for hour := range t.referrers {
+		if hour.bucket < cutoff {
+			delete(t.referrers, hour)
+		}
+	}
+}
+
+// Flush 把已汇总的访问写入数据库，失败的数据留到下一轮
+func (t *ImageStatsTracker) Flush(ctx context.Context) error {
+	return t.batch.Flush(ctx)
+}
+
+// ImageStatsPruner 统计数据清理
+type ImageStatsPruner interface {
+	PruneImageStats(ctx context.Context, before time.Time) (int64, error)
+}
+
+// StartImageStatsPruner 定期删除超过保留天数的统计数据，retentionDays 为 0 时不清理
+func StartImageStatsPruner(ctx context.Context, repo ImageStatsPruner, retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(imageStatsPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cutoff := time.Now().AddDate(0, 0, -retentionDays)
+				pruned, err := repo.PruneImageStats(ctx, cutoff)
+				if err != nil {
+					imageStatsLog.Warnf("Failed to prune image stats: %v", err)
+				} else if pruned > 0 {
+					imageStatsLog.Infof("Pruned %d image stat rows older than %d days", pruned, retentionDays)
+				}
+			}
+		}
+	}()
+}
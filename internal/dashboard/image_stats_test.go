@@ -0,0 +1,109 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStatsWriter struct {
+	mu    sync.Mutex
+	err   error
+	calls [][]models.ImageStat
+}
+
+func (m *mockStatsWriter) AddImageStats(_ context.Context, stats []models.ImageStat) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.calls = append(m.calls, stats)
+	return nil
+}
+
+func TestImageStatsTrackerAggregatesByHour(t *testing.T) {
+	writer := &mockStatsWriter{}
+	tracker := NewImageStatsTracker(writer)
+	at := time.Date(2026, 5, 1, 10, 15, 0, 0, time.UTC)
+
+	tracker.Record(ImageHit{ImageID: 1, UserID: 7, Variant: "webp", Bytes: 100, At: at})
+	tracker.Record(ImageHit{ImageID: 1, UserID: 7, Variant: "webp", Bytes: 50, At: at.Add(30 * time.Minute)})
+	tracker.Record(ImageHit{ImageID: 1, UserID: 7, Variant: "webp", Referrer: "forum.example", Bytes: 10, At: at})
+	tracker.Record(ImageHit{ImageID: 1, UserID: 7, Variant: "webp", Bytes: 1, At: at.Add(time.Hour)})
+	tracker.Record(ImageHit{ImageID: 0, Bytes: 1})
+
+	writer.err = errors.New("db down")
+	require.Error(t, tracker.Flush(context.Background()))
+
+	writer.err = nil
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, writer.calls, 1)
+	require.Len(t, writer.calls[0], 3, "failed flush keeps the rows for the next round")
+
+	var direct *models.ImageStat
+	for i, stat := range writer.calls[0] {
+		if stat.Referrer == "" && stat.Bucket.Equal(at.Truncate(time.Hour)) {
+			direct = &writer.calls[0][i]
+		}
+	}
+	require.NotNil(t, direct)
+	assert.Equal(t, int64(2), direct.Hits)
+	assert.Equal(t, int64(150), direct.Bytes)
+	assert.Equal(t, uint(7), direct.UserID)
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Len(t, writer.calls, 1, "nothing left to write")
+}
+
+func TestImageStatsTrackerCapsReferrersPerImageHour(t *testing.T) {
+	writer := &mockStatsWriter{}
+	tracker := NewImageStatsTracker(writer)
+	at := time.Now().UTC()
+
+	for i := range imageStatsMaxReferrers + 10 {
+		tracker.Record(ImageHit{ImageID: 1, Variant: "webp", Referrer: fmt.Sprintf("site-%d.example", i), At: at})
+	}
+	tracker.Record(ImageHit{ImageID: 2, Variant: "webp", Referrer: "site-99.example", At: at})
+	require.NoError(t, tracker.Flush(context.Background()))
+
+	// 上限跨多次写库生效
+	tracker.Record(ImageHit{ImageID: 1, Variant: "webp", Referrer: "late.example", At: at})
+	tracker.Record(ImageHit{ImageID: 1, Variant: "webp", Referrer: "site-0.example", At: at})
+	require.NoError(t, tracker.Flush(context.Background()))
+
+	other := map[uint]int64{}
+	referrers := map[string]bool{}
+	for _, call := range writer.calls {
+		for _, stat := range call {
+			if stat.Referrer == models.ImageStatReferrerOther {
+				other[stat.ImageID] += stat.Hits
+			} else if stat.ImageID == 1 {
+				referrers[stat.Referrer] = true
+			}
+		}
+	}
+	assert.Len(t, referrers, imageStatsMaxReferrers)
+	assert.Equal(t, int64(11), other[1])
+	assert.Zero(t, other[2], "the cap applies per image")
+}
+
+func TestStartImageStatsTrackerFlushesOnStop(t *testing.T) {
+	writer := &mockStatsWriter{}
+	stop := StartImageStatsTracker(writer)
+
+	RecordImageHit(ImageHit{ImageID: 3, Variant: models.ImageStatVariantOriginal, Bytes: 20})
+	stop()
+	RecordImageHit(ImageHit{ImageID: 4, Variant: models.ImageStatVariantOriginal})
+
+	require.Len(t, writer.calls, 1)
+	require.Len(t, writer.calls[0], 1)
+	assert.Equal(t, uint(3), writer.calls[0][0].ImageID)
+}
@@ -0,0 +1,76 @@
+package image
+
+import (
+	"math"
+
+	config "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils/format"
+)
+
+// ClientHints 客户端通过 Client Hints 提供的显示信息，零值表示未提供
+type ClientHints struct {
+	Width    int     // Sec-CH-Width，图片显示宽度（物理像素）
+	DPR      float64 // Sec-CH-DPR，设备像素比
+	SaveData bool    // Save-Data: on，客户端希望节省流量
+}
+
+// IsZero 是否未提供任何提示
+func (h ClientHints) IsZero() bool {
+	return h.Width <= 0 && !h.SaveData
+}
+
+// targetWidth 期望的交付宽度；省流模式下按 1 倍像素比交付，0 表示未知
+func (h ClientHints) targetWidth() int {
+	if h.Width <= 0 {
+		return 0
+	}
+	if h.SaveData && h.DPR > 1 {
+		return int(math.Ceil(float64(h.Width) / h.DPR))
+	}
+	return h.Width
+}
+
+// selectThumbnailForHints 按客户端提示挑选比全尺寸结果更小的已完成缩略图，无合适缩略图时返回 nil。
+// 有宽度提示时选不小于目标宽度的最小缩略图；仅省流时选小于全尺寸的最大缩略图
+func selectThumbnailForHints(hints ClientHints, acceptHeader string, settings *config.ImageProcessingSettings, variants []models.ImageVariant, image *models.Image) *models.ImageVariant {
+	if hints.IsZero() || !settings.ThumbnailEnabled || !format.Accepts(acceptHeader, format.FormatWebP) {
+		return nil
+	}
+	// 动图的缩略图可能只有首帧（静态缩略图或帧数超限），不能替代原图
+	if image.MimeType == "image/gif" {
+		return nil
+	}
+
+	target := hints.targetWidth()
+	var best *models.ImageVariant
+	bestWidth := 0
+	for _, size := range settings.ThumbnailSizes {
+		// 裁剪缩略图宽高比与原图不同，不能替代原图
+		if size.IsCropped() || (image.Width > 0 && size.Width >= image.Width) {
+			continue
+		}
+		variant := completedVariant(variants, size.Format())
+		if variant == nil {
+			continue
+		}
+		switch {
+		case target > 0:
+			if size.Width >= target && (best == nil || size.Width < bestWidth) {
+				best, bestWidth = variant, size.Width
+			}
+		case best == nil || size.Width > bestWidth:
+			best, bestWidth = variant, size.Width
+		}
+	}
+	return best
+}
+
+func completedVariant(variants []models.ImageVariant, variantFormat string) *models.ImageVariant {
+	for i := range variants {
+		if variants[i].Format == variantFormat && variants[i].Status == models.VariantStatusCompleted {
+			return &variants[i]
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"github.com/anoixa/image-bed/internal/worker"
 	"github.com/anoixa/image-bed/storage"
 	"github.com/anoixa/image-bed/utils"
@@ -58,6 +59,7 @@ type ListImagesResult struct {
 	Page       int
 	Limit      int
 	TotalPages int
+	Highlights map[uint]*fulltext.Highlight // 按关键词搜索时各图片的命中高亮
 }
 
 // DeleteResult 删除结果
@@ -67,15 +69,28 @@ type DeleteResult struct {
 	Error        error
 }
 
-// submitBackgroundTask 提交后台任务到 worker pool，队列满时丢弃并记录警告。
+// submitBackgroundTask 以默认通道提交后台任务到 worker pool，队列满时丢弃并记录警告。
 // 返回值表示任务是否成功进入后台队列。
 func submitBackgroundTask(task func()) bool {
+	return submitBackgroundTaskWith(worker.TaskOptions{}, task)
+}
+
+// maintenanceTask 缓存预热等可延后的后台任务
+var maintenanceTask = worker.TaskOptions{Priority: worker.PriorityMaintenance}
+
+// uploadTask 上传后准备变体并入队的轻量任务，按图片所属用户轮转；实际处理由缩略图与全尺寸变体任务分别排队
+func uploadTask(image *models.Image) worker.TaskOptions {
+	return worker.TaskOptions{Priority: worker.PriorityInteractive, Owner: image.UserID}
+}
+
+// submitBackgroundTaskWith 按优先级通道和所属用户提交后台任务
+func submitBackgroundTaskWith(opts worker.TaskOptions, task func()) bool {
 	pool := worker.GetGlobalPool()
 	if pool == nil {
 		imageCommonLog.Infof("Worker pool not initialized, dropping background task")
 		return false
 	}
-	if ok := pool.Submit(task); !ok {
+	if ok := pool.SubmitWith(opts, task); !ok {
 		imageCommonLog.Warnf("Worker pool queue full, dropping background task")
 		return false
 	}
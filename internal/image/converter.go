@@ -1,8 +1,11 @@
 package image
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/anoixa/image-bed/cache"
@@ -37,10 +40,18 @@ func NewConverter(cm *config.Manager, variantRepo *images.VariantRepository, ima
 	}
 }
 
-// TriggerConversion 触发图片转换（统一流水线）
-// 使用 PipelineTask 顺序生成缩略图、WebP 和 AVIF。
+// TriggerConversion 触发图片转换，用于刚上传的图片；按需生成模式下跳过
+// 缩略图走交互优先级，WebP、AVIF 等全尺寸变体作为单独任务走格式转换优先级。
 func (c *Converter) TriggerConversion(image *models.Image) {
-	c.triggerConversion(image, false, "")
+	if c.deferUploadConversion(image) {
+		return
+	}
+	_ = c.triggerConversion(image, false, "", worker.PriorityInteractive, nil)
+}
+
+// TriggerDeferredConversion 访问时发现缺少变体后补齐，走格式转换优先级
+func (c *Converter) TriggerDeferredConversion(image *models.Image) {
+	_ = c.triggerConversion(image, false, "", worker.PriorityConversion, nil)
 }
 
 // TriggerConversionWithLocalFile triggers conversion with a pre-staged local
@@ -48,17 +59,33 @@ func (c *Converter) TriggerConversion(image *models.Image) {
 // Ownership of localPath transfers to the pipeline; it will be cleaned up
 // after processing completes or on submission failure.
 func (c *Converter) TriggerConversionWithLocalFile(image *models.Image, localPath string) {
-	c.triggerConversion(image, false, localPath)
+	if c.deferUploadConversion(image) {
+		_ = os.Remove(localPath)
+		return
+	}
+	_ = c.triggerConversion(image, false, localPath, worker.PriorityInteractive, nil)
 }
 
 // TriggerConversionFromSweeper re-submits stale work recovered by the sweeper.
 // This path intentionally ignores variant retry windows because the sweeper has
 // already decided the stale work should be retried now.
 func (c *Converter) TriggerConversionFromSweeper(image *models.Image) {
-	c.triggerConversion(image, true, "")
+	_ = c.triggerConversion(image, true, "", worker.PriorityConversion, nil)
+}
+
+// deferUploadConversion 按需生成模式下上传不预先生成变体，等首次访问时再补齐；
+// 浏览器无法直接显示的原图仍需立即生成交付变体
+func (c *Converter) deferUploadConversion(image *models.Image) bool {
+	if utils.RequiresDeliveryVariant(image.MimeType) {
+		return false
+	}
+	ctx, cancel := utils.DetachedContext(5 * time.Second)
+	defer cancel()
+	settings, err := c.configManager.GetImageProcessingSettings(ctx)
+	return err == nil && settings.LazyVariants
 }
 
-func (c *Converter) triggerConversion(image *models.Image, ignoreRetryWindow bool, localFilePath string) {
+func (c *Converter) triggerConversion(image *models.Image, ignoreRetryWindow bool, localFilePath string, priority worker.Priority, scope *reprocessScope) error {
 	// Register cleanup FIRST so localFilePath is removed on any exit path
 	// (panic, early return, or failed submission). On successful submission
 	// the pipeline task takes ownership and cleans up the file itself.
@@ -78,54 +105,78 @@ func (c *Converter) triggerConversion(image *models.Image, ignoreRetryWindow boo
 	settings, err := c.configManager.GetImageProcessingSettings(ctx)
 	if err != nil {
 		converterLog.Warnf("Failed to load image processing settings for %s: %v", image.Identifier, err)
-		return
+		return fmt.Errorf("load image processing settings: %w", err)
 	}
 
+	// 浏览器无法显示的原图（HEIC/TIFF 等）始终需要 WebP 交付变体
+	deliveryRequired := utils.RequiresDeliveryVariant(image.MimeType)
+	// 矢量图直接交付原图，只栅格化缩略图
+	vector := utils.IsVectorImage(image.MimeType)
+	// 手动重新处理时只提交范围内的变体
 	thumbnailEnabled := settings.ThumbnailEnabled && len(settings.ThumbnailSizes) > 0
-	webpEnabled := settings.IsFormatEnabled(models.FormatWebP)
-	avifEnabled := settings.IsFormatEnabled(models.FormatAVIF) && vipsfile.SupportsAVIFEncoding()
-	if !shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled) {
-		return
+	webpEnabled := (settings.IsFormatEnabled(models.FormatWebP) || deliveryRequired) && !vector && scope.covers(models.FormatWebP)
+	avifEnabled := settings.IsFormatEnabled(models.FormatAVIF) && avifEncodingAvailable() && !vector && scope.covers(models.FormatAVIF)
+	jxlEnabled := jxlVariantEnabled(image, settings) && scope.covers(models.FormatJXL)
+	if !shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled, jxlEnabled) {
+		return nil
 	}
 
-	// 跳过 GIF 格式
-	if image.MimeType == "image/gif" {
-		return
+	// 未开启动图保留时跳过 GIF，避免生成丢失动画的变体
+	if image.MimeType == "image/gif" && !settings.PreserveAnimation {
+		return nil
 	}
 
 	// 跳过小于阈值的图片
-	if settings.SkipSmallerThan > 0 {
+	if settings.SkipSmallerThan > 0 && !deliveryRequired && !vector {
 		minSize := int64(settings.SkipSmallerThan * 1024)
 		if image.FileSize < minSize {
-			return
+			return nil
 		}
 	}
 
 	// 创建缩略图变体记录（如果启用）
 	var thumbVariant *models.ImageVariant
-	if thumbnailEnabled {
-		size := settings.ThumbnailSizes[0]
-		thumbFormat := models.FormatThumbnailSize(size.Width)
-		thumbVariant, err = variantRepo.UpsertPending(image.ID, thumbFormat)
+	if thumbnailEnabled && scope.covers(settings.ThumbnailSizes[0].Format()) {
+		thumbVariant, err = variantRepo.UpsertPending(image.ID, settings.ThumbnailSizes[0].Format())
 		if err != nil {
 			converterLog.Warnf("Failed to prepare thumbnail variant for image %s: %v", image.Identifier, err)
-			return
+			return fmt.Errorf("prepare thumbnail variant: %w", err)
 		}
-		if !variantReadyForSubmit(thumbVariant, now, ignoreRetryWindow) {
+		if !scope.ready(thumbVariant, now, ignoreRetryWindow) {
 			thumbVariant = nil
 		}
 	}
 
+	// 其余缩略图尺寸为附加任务，准备失败只跳过对应尺寸
+	var extraThumbs []worker.ThumbnailJob
+	var extraVariants []*models.ImageVariant
+	if thumbnailEnabled {
+		for _, size := range settings.ThumbnailSizes[1:] {
+			if !scope.covers(size.Format()) {
+				continue
+			}
+			variant, err := variantRepo.UpsertPending(image.ID, size.Format())
+			if err != nil {
+				converterLog.Warnf("Failed to prepare %s variant for image %s: %v", size.Format(), image.Identifier, err)
+				continue
+			}
+			if scope.ready(variant, now, ignoreRetryWindow) {
+				extraThumbs = append(extraThumbs, worker.ThumbnailJob{VariantID: variant.ID, Size: size})
+				extraVariants = append(extraVariants, variant)
+			}
+		}
+	}
+
 	// 创建 WebP 变体记录（如果启用）
 	var webpVariant *models.ImageVariant
 	if webpEnabled {
 		webpVariant, err = variantRepo.UpsertPending(image.ID, models.FormatWebP)
 		if err != nil {
 			converterLog.Warnf("Failed to prepare WebP variant for image %s: %v", image.Identifier, err)
-			c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, fmt.Sprintf("submit aborted during webp preparation: %v", err), thumbVariant)
-			return
+			c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, fmt.Sprintf("submit aborted during webp preparation: %v", err), append(extraVariants, thumbVariant)...)
+			return fmt.Errorf("prepare webp variant: %w", err)
 		}
-		if !variantReadyForSubmit(webpVariant, now, ignoreRetryWindow) {
+		if !scope.ready(webpVariant, now, ignoreRetryWindow) {
 			webpVariant = nil
 		}
 	}
@@ -135,24 +186,62 @@ func (c *Converter) triggerConversion(image *models.Image, ignoreRetryWindow boo
 		avifVariant, err = variantRepo.UpsertPending(image.ID, models.FormatAVIF)
 		if err != nil {
 			converterLog.Warnf("Failed to prepare AVIF variant for image %s: %v", image.Identifier, err)
-			c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, fmt.Sprintf("submit aborted during avif preparation: %v", err), thumbVariant, webpVariant)
-			return
+			c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, fmt.Sprintf("submit aborted during avif preparation: %v", err), append(extraVariants, thumbVariant, webpVariant)...)
+			return fmt.Errorf("prepare avif variant: %w", err)
 		}
-		if !variantReadyForSubmit(avifVariant, now, ignoreRetryWindow) {
+		if !scope.ready(avifVariant, now, ignoreRetryWindow) {
 			avifVariant = nil
 		}
 	}
 
+	var jxlVariant *models.ImageVariant
+	if jxlEnabled {
+		jxlVariant, err = variantRepo.UpsertPending(image.ID, models.FormatJXL)
+		if err != nil {
+			converterLog.Warnf("Failed to prepare JXL variant for image %s: %v", image.Identifier, err)
+			c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, fmt.Sprintf("submit aborted during jxl preparation: %v", err), append(extraVariants, thumbVariant, webpVariant, avifVariant)...)
+			return fmt.Errorf("prepare jxl variant: %w", err)
+		}
+		if !scope.ready(jxlVariant, now, ignoreRetryWindow) {
+			jxlVariant = nil
+		}
+	}
+
+	// 注册的扩展阶段各自产出变体，准备失败只跳过对应阶段
+	var stageVariantIDs map[string]uint
+	var stageVariants []*models.ImageVariant
+	if !vector {
+		for _, stage := range worker.ExtensionStages() {
+			if stage.Format() == "" || !stage.Enabled(settings) || !scope.covers(stage.Format()) {
+				continue
+			}
+			variant, err := variantRepo.UpsertPending(image.ID, stage.Format())
+			if err != nil {
+				converterLog.Warnf("Failed to prepare %s variant for image %s: %v", stage.Format(), image.Identifier, err)
+				continue
+			}
+			if scope.ready(variant, now, ignoreRetryWindow) {
+				if stageVariantIDs == nil {
+					stageVariantIDs = make(map[string]uint)
+				}
+				stageVariantIDs[stage.Name()] = variant.ID
+				stageVariants = append(stageVariants, variant)
+			}
+		}
+	}
+
 	// 如果没有需要处理的变体，直接返回
-	if thumbVariant == nil && webpVariant == nil && avifVariant == nil {
-		return
+	primaryPending := thumbVariant != nil || webpVariant != nil || avifVariant != nil || jxlVariant != nil || len(stageVariants) > 0
+	if !primaryPending && len(extraThumbs) == 0 {
+		return nil
 	}
+	pendingVariants := append(append(extraVariants, stageVariants...), thumbVariant, webpVariant, avifVariant, jxlVariant)
 
 	pool := worker.GetGlobalPool()
 	if pool == nil {
 		converterLog.Warnf("Worker pool unavailable for image %s", image.Identifier)
-		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "worker pool not initialized", thumbVariant, webpVariant, avifVariant)
-		return
+		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "worker pool not initialized", pendingVariants...)
+		return errors.New("worker pool not initialized")
 	}
 
 	// 获取图片对应的存储提供者
@@ -160,41 +249,221 @@ func (c *Converter) triggerConversion(image *models.Image, ignoreRetryWindow boo
 	if storageProvider == nil {
 		converterLog.Warnf("Storage provider unavailable for image %s (StorageConfigID=%d)",
 			image.Identifier, image.StorageConfigID)
-		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "storage provider unavailable", thumbVariant, webpVariant, avifVariant)
-		return
+		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "storage provider unavailable", pendingVariants...)
+		return errors.New("storage provider unavailable")
 	}
 
-	// 提交统一流水线任务
-	ok := pool.Submit(func() {
-		task := &worker.ImagePipelineTask{
-			ThumbVariantID:  getVariantID(thumbVariant),
-			WebPVariantID:   getVariantID(webpVariant),
-			AVIFVariantID:   getVariantID(avifVariant),
-			ImageID:         image.ID,
-			StoragePath:     image.StoragePath,
-			ImageIdentifier: image.Identifier,
-			FileSize:        image.FileSize,
-			MimeType:        image.MimeType,
-			Storage:         storageProvider,
-			Settings:        settings,
-			VariantRepo:     c.variantRepo,
-			ImageRepo:       c.imageRepo,
-			CacheHelper:     c.cacheHelper,
-			LocalFilePath:   localFilePath,
-		}
-		task.Execute()
-	})
+	// 提交流水线任务，交互优先级下缩略图与全尺寸变体分开提交
+	ok := c.submitUploadPipeline(image, pipelineJobPayload{
+		ImageID:         image.ID,
+		ThumbVariantID:  getVariantID(thumbVariant),
+		WebPVariantID:   getVariantID(webpVariant),
+		AVIFVariantID:   getVariantID(avifVariant),
+		JXLVariantID:    getVariantID(jxlVariant),
+		StageVariantIDs: stageVariantIDs,
+		ExtraThumbnails: extraThumbs,
+		LocalFilePath:   localFilePath,
+		Reprocess:       scope != nil,
+	}, settings, storageProvider, priority)
 
 	if !ok {
 		converterLog.Warnf("Failed to submit pipeline task for %s", image.Identifier)
-		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "worker task submission rejected", thumbVariant, webpVariant, avifVariant)
-		return
+		c.failPendingVariantsOnSubmitFailure(imageRepo, variantRepo, image, "worker task submission rejected", pendingVariants...)
+		return errors.New("worker task submission rejected")
 	}
 	submitted = true
 
+	// 只有附加缩略图时不改变图片整体状态；重新处理期间旧变体继续提供服务，保持原有状态
+	if !primaryPending || (scope != nil && imageServesVariants(image)) {
+		return nil
+	}
 	if err := c.markImageProcessing(imageRepo, image); err != nil {
 		converterLog.Warnf("Failed to update image %s status after submit: %v", image.Identifier, err)
 	}
+	return nil
+}
+
+// RegenerateCroppedThumbnails 重新生成固定宽高比缩略图（如手动焦点变更后），返回排队的变体数
+// 正在处理中的变体保持不变；该任务不改变图片整体变体状态
+func (c *Converter) RegenerateCroppedThumbnails(image *models.Image) (int, error) {
+	ctx, cancel := utils.DetachedContext(5 * time.Second)
+	defer cancel()
+	variantRepo := c.variantRepo.WithContext(ctx)
+
+	settings, err := c.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("load image processing settings: %w", err)
+	}
+	if !settings.ThumbnailEnabled {
+		return 0, nil
+	}
+
+	var jobs []worker.ThumbnailJob
+	var ids []uint
+	for _, size := range settings.ThumbnailSizes {
+		if !size.IsCropped() {
+			continue
+		}
+		variant, err := variantRepo.UpsertPending(image.ID, size.Format())
+		if err != nil {
+			return 0, fmt.Errorf("prepare %s variant: %w", size.Format(), err)
+		}
+		if variant.Status == models.VariantStatusProcessing {
+			continue
+		}
+		jobs = append(jobs, worker.ThumbnailJob{VariantID: variant.ID, Size: size})
+		ids = append(ids, variant.ID)
+	}
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+	if _, err := variantRepo.RequeueVariants(ids); err != nil {
+		return 0, fmt.Errorf("requeue cropped thumbnails: %w", err)
+	}
+
+	failJobs := func(reason string) {
+		for _, id := range ids {
+			if err := variantRepo.ForceUpdateFailed(id, reason); err != nil {
+				converterLog.Warnf("Failed to mark variant %d failed after submit failure: %v", id, err)
+			}
+		}
+	}
+
+	pool := worker.GetGlobalPool()
+	if pool == nil {
+		failJobs("worker pool not initialized")
+		return 0, fmt.Errorf("worker pool not initialized")
+	}
+	storageProvider := c.getStorageForImage(image)
+	if storageProvider == nil {
+		failJobs("storage provider unavailable")
+		return 0, fmt.Errorf("storage provider unavailable")
+	}
+
+	ok := c.submitPipeline(image, pipelineJobPayload{ImageID: image.ID, ExtraThumbnails: jobs}, settings, storageProvider, worker.PriorityInteractive)
+	if !ok {
+		failJobs("worker task submission rejected")
+		return 0, fmt.Errorf("worker task submission rejected")
+	}
+	return len(jobs), nil
+}
+
+// ReprocessImage 按当前配置重新生成图片变体：清理已不再生成的变体（如已移除的缩略图尺寸），
+// 其余变体重新提交流水线，任务被领取时才重置为 pending，此前旧变体继续提供服务。
+// formats 为空表示全部变体，"thumbnail" 匹配所有缩略图尺寸。返回清理的变体数
+func (c *Converter) ReprocessImage(ctx context.Context, image *models.Image, formats []string) (int, error) {
+	settings, err := c.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("load image processing settings: %w", err)
+	}
+	variantRepo := c.variantRepo.WithContext(ctx)
+
+	variants, err := variantRepo.GetVariantsByImageID(image.ID)
+	if err != nil {
+		return 0, fmt.Errorf("get variants: %w", err)
+	}
+
+	desired := desiredVariantFormats(image, settings)
+	purged := 0
+	for i := range variants {
+		variant := &variants[i]
+		if !variantFormatInScope(variant.Format, formats) || variant.Status == models.VariantStatusProcessing {
+			continue
+		}
+		if desired[variant.Format] {
+			continue
+		}
+		if err := c.purgeVariant(ctx, image, variant); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 && c.cacheHelper != nil {
+		_ = c.cacheHelper.DeleteCachedImageVariants(ctx, image.ID)
+	}
+	// 不会进入流水线的图片保留现有变体，避免重置后无人处理
+	if !shouldTriggerVariantConversion(image, settings) {
+		return purged, nil
+	}
+	return purged, c.triggerConversion(image, true, "", worker.PriorityBulk, &reprocessScope{Formats: formats})
+}
+
+// purgeVariant 删除变体存储对象和记录
+func (c *Converter) purgeVariant(ctx context.Context, image *models.Image, variant *models.ImageVariant) error {
+	if variant.StoragePath != "" {
+		provider, err := getStorageProviderByID(image.StorageConfigID)
+		if err != nil {
+			return err
+		}
+		if err := provider.DeleteWithContext(ctx, variant.StoragePath); err != nil {
+			return fmt.Errorf("delete variant object %s: %w", variant.StoragePath, err)
+		}
+	}
+	if c.cacheHelper != nil && variant.Identifier != "" {
+		_ = c.cacheHelper.DeleteCachedImageData(ctx, variant.Identifier)
+	}
+	return c.variantRepo.WithContext(ctx).PurgeVariant(variant.ID)
+}
+
+// desiredVariantFormats 当前配置下图片应有的变体格式
+func desiredVariantFormats(image *models.Image, settings *config.ImageProcessingSettings) map[string]bool {
+	deliveryRequired := utils.RequiresDeliveryVariant(image.MimeType)
+	vector := utils.IsVectorImage(image.MimeType)
+
+	desired := make(map[string]bool)
+	if settings.ThumbnailEnabled {
+		for _, size := range settings.ThumbnailSizes {
+			desired[size.Format()] = true
+		}
+	}
+	if (settings.IsFormatEnabled(models.FormatWebP) || deliveryRequired) && !vector {
+		desired[models.FormatWebP] = true
+	}
+	if settings.IsFormatEnabled(models.FormatAVIF) && avifEncodingAvailable() && !vector {
+		desired[models.FormatAVIF] = true
+	}
+	if jxlVariantEnabled(image, settings) {
+		desired[models.FormatJXL] = true
+	}
+	return desired
+}
+
+// reprocessScope 手动重新处理的变体范围，nil 表示普通转换
+type reprocessScope struct {
+	Formats []string
+}
+
+// covers 变体格式是否需要提交
+func (s *reprocessScope) covers(format string) bool {
+	return s == nil || variantFormatInScope(format, s.Formats)
+}
+
+// ready 变体是否可以提交；重新处理时已完成或失败的变体也重新生成，处理中的保持不变
+func (s *reprocessScope) ready(variant *models.ImageVariant, now time.Time, ignoreRetryWindow bool) bool {
+	if s == nil {
+		return variantReadyForSubmit(variant, now, ignoreRetryWindow)
+	}
+	return variant != nil && variant.Status != models.VariantStatusProcessing
+}
+
+// imageServesVariants 图片当前是否按已完成的变体交付
+func imageServesVariants(image *models.Image) bool {
+	return image.VariantStatus == models.ImageVariantStatusCompleted ||
+		image.VariantStatus == models.ImageVariantStatusThumbnailCompleted
+}
+
+// variantFormatInScope 判断变体格式是否在筛选范围内
+func variantFormatInScope(format string, formats []string) bool {
+	if len(formats) == 0 {
+		return true
+	}
+	for _, f := range formats {
+		if f == format || (f == models.FormatThumbnail && strings.HasPrefix(format, models.FormatThumbnail+"_")) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Converter) failPendingVariantsOnSubmitFailure(imageRepo *images.Repository, variantRepo *images.VariantRepository, image *models.Image, reason string, variants ...*models.ImageVariant) {
@@ -231,18 +500,21 @@ func shouldTriggerVariantConversion(image *models.Image, settings *config.ImageP
 		return false
 	}
 
+	deliveryRequired := utils.RequiresDeliveryVariant(image.MimeType)
+	vector := utils.IsVectorImage(image.MimeType)
 	thumbnailEnabled := settings.ThumbnailEnabled && len(settings.ThumbnailSizes) > 0
-	webpEnabled := settings.IsFormatEnabled(models.FormatWebP)
-	avifEnabled := settings.IsFormatEnabled(models.FormatAVIF) && vipsfile.SupportsAVIFEncoding()
-	if !shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled) {
+	webpEnabled := (settings.IsFormatEnabled(models.FormatWebP) || deliveryRequired) && !vector
+	avifEnabled := settings.IsFormatEnabled(models.FormatAVIF) && avifEncodingAvailable() && !vector
+	jxlEnabled := jxlVariantEnabled(image, settings)
+	if !shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled, jxlEnabled) {
 		return false
 	}
 
-	if image.MimeType == "image/gif" {
+	if image.MimeType == "image/gif" && !settings.PreserveAnimation {
 		return false
 	}
 
-	if settings.SkipSmallerThan > 0 {
+	if settings.SkipSmallerThan > 0 && !deliveryRequired && !vector {
 		minSize := int64(settings.SkipSmallerThan * 1024)
 		if image.FileSize < minSize {
 			return false
@@ -252,8 +524,16 @@ func shouldTriggerVariantConversion(image *models.Image, settings *config.ImageP
 	return true
 }
 
-func shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled bool) bool {
-	return thumbnailEnabled || webpEnabled || avifEnabled
+func shouldStartVariantPipeline(thumbnailEnabled, webpEnabled, avifEnabled, jxlEnabled bool) bool {
+	return thumbnailEnabled || webpEnabled || avifEnabled || jxlEnabled
+}
+
+// jxlVariantEnabled JPEG XL 原图本身无需再转 JXL，矢量图不生成全尺寸变体
+func jxlVariantEnabled(image *models.Image, settings *config.ImageProcessingSettings) bool {
+	if image.MimeType == "image/jxl" || utils.IsVectorImage(image.MimeType) {
+		return false
+	}
+	return settings.IsFormatEnabled(models.FormatJXL) && jxlEncodingAvailable()
 }
 
 func variantReadyForSubmit(variant *models.ImageVariant, now time.Time, ignoreRetryWindow bool) bool {
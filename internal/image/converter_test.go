@@ -43,14 +43,15 @@ func (p *testStorageProvider) Name() string {
 }
 
 func TestShouldStartVariantPipeline(t *testing.T) {
-	assert.True(t, shouldStartVariantPipeline(true, false, false))
-	assert.True(t, shouldStartVariantPipeline(false, true, false))
-	assert.True(t, shouldStartVariantPipeline(false, false, true))
-	assert.True(t, shouldStartVariantPipeline(true, true, false))
-	assert.True(t, shouldStartVariantPipeline(true, false, true))
-	assert.True(t, shouldStartVariantPipeline(false, true, true))
-	assert.True(t, shouldStartVariantPipeline(true, true, true))
-	assert.False(t, shouldStartVariantPipeline(false, false, false))
+	assert.True(t, shouldStartVariantPipeline(true, false, false, false))
+	assert.True(t, shouldStartVariantPipeline(false, true, false, false))
+	assert.True(t, shouldStartVariantPipeline(false, false, true, false))
+	assert.True(t, shouldStartVariantPipeline(true, true, false, false))
+	assert.True(t, shouldStartVariantPipeline(true, false, true, false))
+	assert.True(t, shouldStartVariantPipeline(false, true, true, false))
+	assert.True(t, shouldStartVariantPipeline(true, true, true, false))
+	assert.True(t, shouldStartVariantPipeline(false, false, false, true))
+	assert.False(t, shouldStartVariantPipeline(false, false, false, false))
 }
 
 func TestShouldTriggerVariantConversion(t *testing.T) {
@@ -66,11 +67,32 @@ func TestShouldTriggerVariantConversion(t *testing.T) {
 		assert.True(t, shouldTriggerVariantConversion(image, settings))
 	})
 
-	t.Run("gif does not trigger conversion", func(t *testing.T) {
+	t.Run("gif does not trigger conversion without animation preservation", func(t *testing.T) {
 		image := &models.Image{MimeType: "image/gif", FileSize: 32 * 1024}
 		assert.False(t, shouldTriggerVariantConversion(image, settings))
 	})
 
+	t.Run("gif triggers conversion with animation preservation", func(t *testing.T) {
+		animated := *settings
+		animated.PreserveAnimation = true
+		image := &models.Image{MimeType: "image/gif", FileSize: 32 * 1024}
+		assert.True(t, shouldTriggerVariantConversion(image, &animated))
+	})
+
+	t.Run("heic always triggers delivery conversion", func(t *testing.T) {
+		image := &models.Image{MimeType: "image/heic", FileSize: 5 * 1024}
+		webpDisabled := &configdb.ImageProcessingSettings{SkipSmallerThan: 10}
+		assert.True(t, shouldTriggerVariantConversion(image, webpDisabled))
+	})
+
+	t.Run("svg only triggers thumbnail conversion", func(t *testing.T) {
+		image := &models.Image{MimeType: "image/svg+xml", FileSize: 2 * 1024}
+		assert.True(t, shouldTriggerVariantConversion(image, settings), "svg bypasses the small file threshold")
+
+		webpOnly := &configdb.ImageProcessingSettings{ConversionEnabledFormats: []string{models.FormatWebP}}
+		assert.False(t, shouldTriggerVariantConversion(image, webpOnly), "svg never gets webp/avif variants")
+	})
+
 	t.Run("small image does not trigger conversion", func(t *testing.T) {
 		image := &models.Image{MimeType: "image/jpeg", FileSize: 5 * 1024}
 		assert.False(t, shouldTriggerVariantConversion(image, settings))
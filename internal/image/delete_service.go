@@ -29,6 +29,7 @@ func NewDeleteService(repo *images.Repository, variantRepo *images.VariantReposi
 	}
 }
 
+// DeleteSingle 将图片移入回收站，变体和存储对象保留到彻底删除时再清理
 func (s *DeleteService) DeleteSingle(ctx context.Context, identifier string, userID uint) (*DeleteResult, error) {
 	img, err := s.repo.WithContext(ctx).GetImageByIdentifier(identifier)
 	if err != nil {
@@ -50,66 +51,21 @@ func (s *DeleteService) DeleteSingle(ctx context.Context, identifier string, use
 		return &DeleteResult{Success: false, Error: errors.New("image not found")}, nil
 	}
 
-	img = imagesToDelete[0]
-	s.deleteVariantsForImage(ctx, img)
-
-	if img.StoragePath != "" {
-		refCount, err := s.repo.WithContext(ctx).CountImagesByStoragePath(img.StoragePath)
-		if err != nil {
-			deleteLog.Errorf("Failed to count references for storage path %s: %v", img.StoragePath, err)
-		} else if refCount == 0 {
-			provider, err := getStorageProviderByID(img.StorageConfigID)
-			if err != nil {
-				deleteLog.Errorf("Failed to get storage provider for image %s: %v", utils.SanitizeLogMessage(img.Identifier), err)
-			} else if err := provider.DeleteWithContext(ctx, img.StoragePath); err != nil {
-				deleteLog.Errorf("Failed to delete original image file %s: %v", img.StoragePath, err)
-			}
-		} else {
-			deleteLog.Debugf("Skipping physical file deletion for %s, still referenced by %d images", img.StoragePath, refCount)
-		}
-	}
-
 	s.clearImageCache(ctx, identifier)
 	return &DeleteResult{Success: true, DeletedCount: 1}, nil
 }
 
+// DeleteBatch 批量将图片移入回收站
 func (s *DeleteService) DeleteBatch(ctx context.Context, identifiers []string, userID uint) (*DeleteResult, error) {
 	if len(identifiers) == 0 {
 		return &DeleteResult{Success: true, DeletedCount: 0}, nil
 	}
 
-	result, imagesToDelete, err := s.repo.DeleteBatchTransaction(ctx, identifiers, userID)
+	result, _, err := s.repo.DeleteBatchTransaction(ctx, identifiers, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute batch delete: %w", err)
 	}
 
-	for _, img := range imagesToDelete {
-		s.deleteVariantsForImage(ctx, img)
-	}
-
-	for _, img := range imagesToDelete {
-		if img.StoragePath == "" {
-			continue
-		}
-
-		refCount, err := s.repo.WithContext(ctx).CountImagesByStoragePath(img.StoragePath)
-		if err != nil {
-			deleteLog.Errorf("Failed to count references for storage path %s: %v", img.StoragePath, err)
-			continue
-		}
-
-		if refCount == 0 {
-			provider, err := getStorageProviderByID(img.StorageConfigID)
-			if err != nil {
-				deleteLog.Errorf("Failed to get storage provider for image %s: %v", utils.SanitizeLogMessage(img.Identifier), err)
-			} else if err := provider.DeleteWithContext(ctx, img.StoragePath); err != nil {
-				deleteLog.Errorf("Failed to delete original image file %s: %v", img.StoragePath, err)
-			}
-		} else {
-			deleteLog.Debugf("Skipping physical file deletion for %s, still referenced by %d images", img.StoragePath, refCount)
-		}
-	}
-
 	for _, identifier := range identifiers {
 		s.clearImageCache(ctx, identifier)
 	}
@@ -127,13 +83,23 @@ func (s *DeleteService) ClearImageCache(ctx context.Context, identifier string)
 	return nil
 }
 
+// deleteVariantsForImage 先删除变体记录再删除存储对象，去重图片共享的变体文件仍被引用时保留
 func (s *DeleteService) deleteVariantsForImage(ctx context.Context, img *models.Image) {
-	variants, err := s.variantRepo.WithContext(ctx).GetVariantsByImageID(img.ID)
+	variantRepo := s.variantRepo.WithContext(ctx)
+	variants, err := variantRepo.GetVariantsByImageID(img.ID)
 	if err != nil {
 		deleteLog.Errorf("Failed to get variants for image %d: %v", img.ID, err)
 		return
 	}
 
+	if err := variantRepo.DeleteByImageID(img.ID); err != nil {
+		deleteLog.Errorf("Failed to delete variant records for image %d: %v", img.ID, err)
+		return
+	}
+	if err := variantRepo.DeleteStageRuns(img.ID); err != nil {
+		deleteLog.Warnf("Failed to delete stage runs for image %d: %v", img.ID, err)
+	}
+
 	provider, err := getStorageProviderByID(img.StorageConfigID)
 	if err != nil {
 		deleteLog.Errorf("Failed to get storage provider for image %d: %v", img.ID, err)
@@ -141,23 +107,27 @@ func (s *DeleteService) deleteVariantsForImage(ctx context.Context, img *models.
 	}
 
 	for _, variant := range variants {
+		if s.cacheHelper != nil {
+			if err := s.cacheHelper.DeleteCachedImageData(ctx, variant.Identifier); err != nil {
+				deleteLog.Warnf("Failed to delete cache for variant %s: %v", utils.SanitizeLogMessage(variant.Identifier), err)
+			}
+		}
 		if variant.StoragePath == "" {
 			continue
 		}
 
+		refs, err := variantRepo.CountVariantsByStoragePath(variant.StoragePath)
+		if err != nil {
+			deleteLog.Errorf("Failed to count references for variant file %s: %v", variant.StoragePath, err)
+			continue
+		}
+		if refs > 0 {
+			continue
+		}
 		if err := provider.DeleteWithContext(ctx, variant.StoragePath); err != nil {
 			deleteLog.Errorf("Failed to delete variant file %s: %v", variant.StoragePath, err)
+			enqueueObjectDeletion(ctx, img.StorageConfigID, variant.StoragePath)
 		}
-
-		if s.cacheHelper != nil {
-			if err := s.cacheHelper.DeleteCachedImageData(ctx, variant.Identifier); err != nil {
-				deleteLog.Warnf("Failed to delete cache for variant %s: %v", utils.SanitizeLogMessage(variant.Identifier), err)
-			}
-		}
-	}
-
-	if err := s.variantRepo.WithContext(ctx).DeleteByImageID(img.ID); err != nil {
-		deleteLog.Errorf("Failed to delete variant records for image %d: %v", img.ID, err)
 	}
 
 	if s.cacheHelper != nil {
@@ -0,0 +1,164 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils"
+)
+
+var expiryLog = utils.ForModule("Expiry")
+
+var (
+	// ErrImageExpired 图片已到期或查看次数已用完
+	ErrImageExpired = errors.New("image has expired")
+	// ErrInvalidExpiry 过期设置不合法
+	ErrInvalidExpiry = errors.New("invalid expiry")
+)
+
+// expiredSweepInterval 过期图片的检查间隔
+var expiredSweepInterval = 5 * time.Minute
+
+// ImageExpiry 图片的过期设置，零值表示永久保存
+type ImageExpiry struct {
+	ExpiresAt *time.Time
+	MaxViews  int // 0 为不限
+}
+
+// NewImageExpiry 由有效期或过期时间（二选一，均为零值表示不限）和最大查看次数构造过期设置
+func NewImageExpiry(expiresIn time.Duration, expiresAt *time.Time, maxViews int, now time.Time) (ImageExpiry, error) {
+	var expiry ImageExpiry
+	switch {
+	case expiresIn != 0 && expiresAt != nil:
+		return expiry, fmt.Errorf("%w: expires_in and expires_at are mutually exclusive", ErrInvalidExpiry)
+	case expiresIn < 0:
+		return expiry, fmt.Errorf("%w: expires_in must be positive", ErrInvalidExpiry)
+	case expiresIn > 0:
+		t := now.Add(expiresIn)
+		expiry.ExpiresAt = &t
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return expiry, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+		}
+		expiry.ExpiresAt = expiresAt
+	}
+	if maxViews < 0 {
+		return expiry, fmt.Errorf("%w: max_views must not be negative", ErrInvalidExpiry)
+	}
+	expiry.MaxViews = maxViews
+	return expiry, nil
+}
+
+// IsZero 是否未设置任何过期条件
+func (e ImageExpiry) IsZero() bool {
+	return e.ExpiresAt == nil && e.MaxViews == 0
+}
+
+func (e ImageExpiry) applyTo(img *models.Image) {
+	img.ExpiresAt = e.ExpiresAt
+	img.MaxViews = e.MaxViews
+}
+
+// CheckImageAvailability 检查图片是否已到期；countView 为 true 时为限次图片计入一次查看，次数用完返回 ErrImageExpired
+func (s *ReadService) CheckImageAvailability(ctx context.Context, image *models.Image, countView bool) error {
+	if image.ExpiresAt != nil && !time.Now().Before(*image.ExpiresAt) {
+		return ErrImageExpired
+	}
+	if image.MaxViews == 0 || !countView {
+		return nil
+	}
+
+	// 缓存的元数据中查看次数可能已过时，以数据库的原子更新为准
+	ok, err := s.repo.WithContext(ctx).ConsumeImageView(image.ID)
+	if err != nil {
+		if isTransientError(err) {
+			return ErrTemporaryFailure
+		}
+		return fmt.Errorf("failed to count image view: %w", err)
+	}
+	if !ok {
+		return ErrImageExpired
+	}
+	return nil
+}
+
+// UpdateImageExpiry 设置或清除图片的过期时间和查看次数限制
+func (s *QueryService) UpdateImageExpiry(ctx context.Context, identifier string, userID uint, expiry ImageExpiry) (*models.Image, error) {
+	return s.repo.WithContext(ctx).UpdateImageExpiry(identifier, userID, expiry.ExpiresAt, expiry.MaxViews)
+}
+
+// applyExpiryToDuplicate 同一用户重复上传时，已有图片若本身会过期，则以本次上传的设置为准；永久保存的图片不会因此变为临时
+func (s *WriteService) applyExpiryToDuplicate(ctx context.Context, img *models.Image, expiry ImageExpiry) *models.Image {
+	if !img.IsEphemeral() {
+		return img
+	}
+	updated, err := s.repo.WithContext(ctx).UpdateImageExpiry(img.Identifier, img.UserID, expiry.ExpiresAt, expiry.MaxViews)
+	if err != nil {
+		writeServiceLog.Warnf("Failed to update expiry of duplicate image %s: %v", utils.SanitizeLogMessage(img.Identifier), err)
+		return img
+	}
+	return updated
+}
+
+// DeleteExpiredImages 彻底删除一批已过期的图片（不经过回收站），返回删除数和本批扫描数
+func (s *DeleteService) DeleteExpiredImages(ctx context.Context, now time.Time, limit int) (deleted, scanned int, err error) {
+	expired, err := s.repo.WithContext(ctx).ListExpiredImages(now, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list expired images: %w", err)
+	}
+
+	byUser := make(map[uint][]string)
+	for _, img := range expired {
+		byUser[img.UserID] = append(byUser[img.UserID], img.Identifier)
+	}
+	trashed := make([]*models.Image, 0, len(expired))
+	for userID, identifiers := range byUser {
+		_, removed, err := s.repo.DeleteBatchTransaction(ctx, identifiers, userID)
+		if err != nil {
+			return 0, len(expired), fmt.Errorf("failed to delete expired images: %w", err)
+		}
+		trashed = append(trashed, removed...)
+	}
+
+	deleted, err = s.purgeImages(ctx, trashed)
+	return deleted, len(expired), err
+}
+
+// StartExpiredImageSweeper 定期彻底删除已到期或查看次数已用完的图片
+func StartExpiredImageSweeper(ctx context.Context, svc *DeleteService) {
+	go func() {
+		ticker := time.NewTicker(expiredSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.deleteExpiredOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (s *DeleteService) deleteExpiredOnce(ctx context.Context) {
+	now := time.Now()
+	total := 0
+	for range trashPurgeMaxBatches {
+		deleted, scanned, err := s.DeleteExpiredImages(ctx, now, trashPurgeBatchSize)
+		total += deleted
+		if err != nil {
+			expiryLog.Warnf("Failed to delete expired images: %v", err)
+			break
+		}
+		if scanned < trashPurgeBatchSize || deleted == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		expiryLog.Infof("Deleted %d expired images", total)
+	}
+}
@@ -0,0 +1,253 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anoixa/image-bed/config"
+	dbconfig "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/accounts"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/jpegmeta"
+	"github.com/anoixa/image-bed/utils/pool"
+)
+
+// ingestDefaultQuality 策略未指定质量时缩放或转存使用的质量
+const ingestDefaultQuality = 90
+
+// ingestFormatMimeTypes 可由 libvips 重新编码的原图格式
+var ingestFormatMimeTypes = map[string]string{
+	vipsfile.FormatPNG:  "image/png",
+	vipsfile.FormatJPEG: "image/jpeg",
+	vipsfile.FormatWebP: "image/webp",
+	vipsfile.FormatAVIF: "image/avif",
+	vipsfile.FormatJXL:  "image/jxl",
+}
+
+// 测试时替换为不依赖 libvips 的实现
+var (
+	probeIngestSource = vipsfile.ProbeImageFile
+	transcodeOriginal = worker.TranscodeOriginal
+)
+
+// IngestPolicyResolver 合并用户级与全局上传策略
+type IngestPolicyResolver struct {
+	accountsRepo  *accounts.Repository
+	configManager *dbconfig.Manager
+}
+
+// NewIngestPolicyResolver 创建上传策略解析器
+func NewIngestPolicyResolver(accountsRepo *accounts.Repository, configManager *dbconfig.Manager) *IngestPolicyResolver {
+	return &IngestPolicyResolver{
+		accountsRepo:  accountsRepo,
+		configManager: configManager,
+	}
+}
+
+// Resolve 返回用户生效的上传策略：用户自定义策略整体优先，否则使用全局配置
+func (r *IngestPolicyResolver) Resolve(ctx context.Context, userID uint) (models.IngestPolicy, error) {
+	if r == nil {
+		return models.IngestPolicy{}, nil
+	}
+	if r.accountsRepo != nil && userID != 0 {
+		custom, err := r.accountsRepo.WithContext(ctx).GetIngestPolicy(userID)
+		if err != nil {
+			return models.IngestPolicy{}, fmt.Errorf("get user ingest policy: %w", err)
+		}
+		if custom != nil {
+			return custom.IngestPolicy, nil
+		}
+	}
+	if r.configManager == nil {
+		return models.IngestPolicy{}, nil
+	}
+	settings, err := r.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		return models.IngestPolicy{}, fmt.Errorf("load image processing settings: %w", err)
+	}
+	return settings.IngestPolicy(), nil
+}
+
+// ingestPlan 原图需要执行的处理
+type ingestPlan struct {
+	format   string // 输出格式，为空表示只做 JPEG 无损精简
+	resize   bool
+	optimize bool // 仅无损优化，结果不更小时保留原图
+}
+
+// planIngest 根据策略和原图信息决定处理方式，无需处理时返回 false。
+// 动图、矢量图和 GIF 保持原样；HEIC/TIFF/BMP 等无法重新编码为同格式的原图只在指定转存格式时处理
+func planIngest(policy models.IngestPolicy, mimeType string, info vipsfile.ImageInfo) (ingestPlan, bool) {
+	if !policy.Active() || info.IsAnimated() || mimeType == "image/gif" || utils.IsVectorImage(mimeType) {
+		return ingestPlan{}, false
+	}
+
+	var source string
+	for format, mt := range ingestFormatMimeTypes {
+		if mt == mimeType {
+			source = format
+		}
+	}
+
+	target := source
+	if policy.ConvertFormat != "" {
+		target = policy.ConvertFormat
+	}
+	if target == "" {
+		return ingestPlan{}, false
+	}
+
+	resize := policy.MaxDimension > 0 && max(info.Width, info.Height) > policy.MaxDimension
+	if resize || target != source {
+		return ingestPlan{format: target, resize: resize}, true
+	}
+	if !policy.Optimize {
+		return ingestPlan{}, false
+	}
+	switch source {
+	case vipsfile.FormatPNG:
+		return ingestPlan{format: vipsfile.FormatPNG, optimize: true}, true
+	case vipsfile.FormatJPEG:
+		return ingestPlan{optimize: true}, true
+	default:
+		return ingestPlan{}, false
+	}
+}
+
+// ingestedOriginal 按上传策略重写后的原图，临时文件由调用方负责清理
+type ingestedOriginal struct {
+	path     string
+	mimeType string
+	size     int64
+	width    int
+	height   int
+}
+
+// applyIngestPolicy 按用户生效的上传策略重写原图，无需处理或优化无收益时返回 nil。
+// localPath 为空时先把 body 写入临时文件
+func (s *WriteService) applyIngestPolicy(ctx context.Context, userID uint, mimeType string, body io.ReadSeeker, localPath string) (*ingestedOriginal, error) {
+	policy, err := s.ingestPolicies.Resolve(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Active() {
+		return nil, nil
+	}
+
+	if localPath == "" {
+		spooled, err := spoolIngestSource(body)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupOwnedTempFile(spooled)
+		localPath = spooled
+	}
+
+	info, err := probeIngestSource(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("probe original: %w", err)
+	}
+	plan, ok := planIngest(policy, mimeType, info)
+	if !ok {
+		return nil, nil
+	}
+
+	sourceStat, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := createIngestTemp("ingest-*")
+	if err != nil {
+		return nil, err
+	}
+	outPath := out.Name()
+	keep := false
+	defer func() {
+		if !keep {
+			cleanupOwnedTempFile(outPath)
+		}
+	}()
+
+	result := &ingestedOriginal{path: outPath, mimeType: mimeType, width: info.Width, height: info.Height}
+	if plan.format == "" {
+		err = stripJPEGMetadata(localPath, out)
+		_ = out.Close()
+	} else {
+		_ = out.Close()
+		quality := policy.Quality
+		if quality <= 0 {
+			quality = ingestDefaultQuality
+		}
+		opts := vipsfile.TranscodeOptions{Format: plan.format, Quality: quality}
+		if plan.resize {
+			opts.MaxDimension = policy.MaxDimension
+		}
+		var outInfo vipsfile.ImageInfo
+		outInfo, err = transcodeOriginal(ctx, localPath, outPath, opts)
+		result.mimeType = ingestFormatMimeTypes[plan.format]
+		result.width, result.height = outInfo.Width, outInfo.Height
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(outPath)
+	if err != nil {
+		return nil, err
+	}
+	if plan.optimize && stat.Size() >= sourceStat.Size() {
+		return nil, nil
+	}
+	result.size = stat.Size()
+	keep = true
+	return result, nil
+}
+
+func spoolIngestSource(body io.ReadSeeker) (string, error) {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("seek upload source: %w", err)
+	}
+	tmp, err := createIngestTemp("ingest-src-*")
+	if err != nil {
+		return "", err
+	}
+	bufPtr := pool.SharedBufferPool.Get().(*[]byte)
+	defer pool.SharedBufferPool.Put(bufPtr)
+	if _, err := io.CopyBuffer(tmp, body, *bufPtr); err != nil {
+		_ = tmp.Close()
+		cleanupOwnedTempFile(tmp.Name())
+		return "", fmt.Errorf("write ingest source: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanupOwnedTempFile(tmp.Name())
+		return "", fmt.Errorf("write ingest source: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+func createIngestTemp(pattern string) (*os.File, error) {
+	if err := os.MkdirAll(config.TempDir, 0700); err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(config.TempDir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("create ingest temp file: %w", err)
+	}
+	return tmp, nil
+}
+
+func stripJPEGMetadata(srcPath string, dst io.Writer) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	_, err = jpegmeta.Strip(src, dst)
+	return err
+}
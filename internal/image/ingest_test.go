@@ -0,0 +1,191 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/accounts"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanIngest(t *testing.T) {
+	large := vipsfile.ImageInfo{Width: 7680, Height: 4320, Pages: 1}
+	small := vipsfile.ImageInfo{Width: 800, Height: 600, Pages: 1}
+
+	tests := []struct {
+		name     string
+		policy   models.IngestPolicy
+		mimeType string
+		info     vipsfile.ImageInfo
+		want     ingestPlan
+		wantOK   bool
+	}{
+		{name: "inactive policy", mimeType: "image/png", info: large},
+		{name: "downscale keeps format", policy: models.IngestPolicy{MaxDimension: 3840}, mimeType: "image/png", info: large, want: ingestPlan{format: "png", resize: true}, wantOK: true},
+		{name: "small image untouched", policy: models.IngestPolicy{MaxDimension: 3840}, mimeType: "image/png", info: small},
+		{name: "convert without resize", policy: models.IngestPolicy{ConvertFormat: "webp"}, mimeType: "image/jpeg", info: small, want: ingestPlan{format: "webp"}, wantOK: true},
+		{name: "convert to same format", policy: models.IngestPolicy{ConvertFormat: "jpeg"}, mimeType: "image/jpeg", info: small},
+		{name: "optimize png", policy: models.IngestPolicy{Optimize: true}, mimeType: "image/png", info: small, want: ingestPlan{format: "png", optimize: true}, wantOK: true},
+		{name: "optimize jpeg", policy: models.IngestPolicy{Optimize: true}, mimeType: "image/jpeg", info: small, want: ingestPlan{optimize: true}, wantOK: true},
+		{name: "optimize webp is a no-op", policy: models.IngestPolicy{Optimize: true}, mimeType: "image/webp", info: small},
+		{name: "heic only converted on request", policy: models.IngestPolicy{MaxDimension: 3840}, mimeType: "image/heic", info: large},
+		{name: "heic converted", policy: models.IngestPolicy{MaxDimension: 3840, ConvertFormat: "jpeg"}, mimeType: "image/heic", info: large, want: ingestPlan{format: "jpeg", resize: true}, wantOK: true},
+		{name: "animation preserved", policy: models.IngestPolicy{MaxDimension: 100}, mimeType: "image/webp", info: vipsfile.ImageInfo{Width: 800, Height: 600, Pages: 12}},
+		{name: "gif preserved", policy: models.IngestPolicy{ConvertFormat: "webp"}, mimeType: "image/gif", info: small},
+		{name: "svg preserved", policy: models.IngestPolicy{ConvertFormat: "png"}, mimeType: "image/svg+xml", info: small},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := planIngest(tt.policy, tt.mimeType, tt.info)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// stubIngestVips 替换 libvips 调用：probe 返回 info，transcode 写入固定内容
+func stubIngestVips(t *testing.T, info vipsfile.ImageInfo, output []byte) *[]vipsfile.TranscodeOptions {
+	t.Helper()
+	origProbe, origTranscode := probeIngestSource, transcodeOriginal
+	t.Cleanup(func() { probeIngestSource, transcodeOriginal = origProbe, origTranscode })
+
+	var calls []vipsfile.TranscodeOptions
+	probeIngestSource = func(string) (vipsfile.ImageInfo, error) { return info, nil }
+	transcodeOriginal = func(_ context.Context, _, dst string, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error) {
+		calls = append(calls, opts)
+		if err := os.WriteFile(dst, output, 0o600); err != nil {
+			return vipsfile.ImageInfo{}, err
+		}
+		scale := float64(opts.MaxDimension) / float64(max(info.Width, info.Height))
+		if opts.MaxDimension == 0 || scale > 1 {
+			scale = 1
+		}
+		return vipsfile.ImageInfo{Width: int(float64(info.Width) * scale), Height: int(float64(info.Height) * scale)}, nil
+	}
+	return &calls
+}
+
+func newIngestTestService(t *testing.T, providerID uint) (*WriteService, *accounts.Repository, string) {
+	t.Helper()
+	db := setupImageServiceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UserIngestPolicy{}))
+	service, _, _ := newTestWriteService(t, db)
+	accountsRepo := accounts.NewRepository(db)
+	service.ingestPolicies = NewIngestPolicyResolver(accountsRepo, nil)
+
+	storageDir := t.TempDir()
+	require.NoError(t, storage.AddOrUpdateProvider(storage.StorageConfig{
+		ID:        providerID,
+		Name:      "test-ingest",
+		Type:      "local",
+		LocalPath: storageDir,
+	}))
+	t.Cleanup(func() { _ = storage.RemoveProvider(providerID) })
+	return service, accountsRepo, storageDir
+}
+
+func TestUploadAppliesUserIngestPolicy(t *testing.T) {
+	const providerID uint = 93801
+	service, accountsRepo, storageDir := newIngestTestService(t, providerID)
+	_, err := accountsRepo.SaveIngestPolicy(1, models.IngestPolicy{MaxDimension: 3840, ConvertFormat: "webp", Quality: 80})
+	require.NoError(t, err)
+	calls := stubIngestVips(t, vipsfile.ImageInfo{Width: 7680, Height: 4320, Pages: 1}, []byte("converted-webp"))
+
+	uploadPath := filepath.Join(t.TempDir(), "screenshot.png")
+	require.NoError(t, os.WriteFile(uploadPath, tinyPNG, 0o644))
+
+	result, err := service.UploadSingleSource(context.Background(), 1,
+		NewTempUploadSource("screenshot.png", uploadPath, int64(len(tinyPNG))), providerID, true, 0)
+	require.NoError(t, err)
+
+	require.Len(t, *calls, 1)
+	assert.Equal(t, vipsfile.TranscodeOptions{MaxDimension: 3840, Format: "webp", Quality: 80}, (*calls)[0])
+
+	clientHash := sha256.Sum256(tinyPNG)
+	img := result.Image
+	assert.Equal(t, hex.EncodeToString(clientHash[:]), img.FileHash, "dedupe uses the bytes the client sent")
+	assert.Equal(t, "image/webp", img.MimeType)
+	assert.True(t, strings.HasSuffix(img.StoragePath, ".webp"))
+	assert.Equal(t, int64(len("converted-webp")), img.FileSize)
+	assert.Equal(t, 3840, img.Width)
+	assert.Equal(t, 2160, img.Height)
+
+	stored, err := os.ReadFile(filepath.Join(storageDir, img.StoragePath))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("converted-webp"), stored)
+
+	_, statErr := os.Stat(uploadPath)
+	assert.True(t, os.IsNotExist(statErr), "request temp file is cleaned up")
+
+	again, err := service.UploadSingleSource(context.Background(), 1,
+		NewTempUploadSource("screenshot.png", writeTempUpload(t, tinyPNG), int64(len(tinyPNG))), providerID, true, 0)
+	require.NoError(t, err)
+	assert.True(t, again.IsDuplicate)
+	assert.Equal(t, img.Identifier, again.Identifier)
+	assert.Len(t, *calls, 1, "duplicates are not processed again")
+}
+
+func TestUploadKeepsOriginalWhenOptimizationDoesNotHelp(t *testing.T) {
+	const providerID uint = 93802
+	service, accountsRepo, storageDir := newIngestTestService(t, providerID)
+	_, err := accountsRepo.SaveIngestPolicy(1, models.IngestPolicy{Optimize: true})
+	require.NoError(t, err)
+	stubIngestVips(t, vipsfile.ImageInfo{Width: 1, Height: 1, Pages: 1}, bytes.Repeat([]byte{1}, len(tinyPNG)+10))
+
+	result, err := service.UploadSingleSource(context.Background(), 1,
+		NewTempUploadSource("tiny.png", writeTempUpload(t, tinyPNG), int64(len(tinyPNG))), providerID, true, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "image/png", result.Image.MimeType)
+	stored, err := os.ReadFile(filepath.Join(storageDir, result.Image.StoragePath))
+	require.NoError(t, err)
+	assert.Equal(t, tinyPNG, stored)
+}
+
+func TestApplyIngestPolicyStripsJPEGMetadata(t *testing.T) {
+	const providerID uint = 93803
+	service, accountsRepo, _ := newIngestTestService(t, providerID)
+	_, err := accountsRepo.SaveIngestPolicy(1, models.IngestPolicy{Optimize: true})
+	require.NoError(t, err)
+	calls := stubIngestVips(t, vipsfile.ImageInfo{Width: 8, Height: 8, Pages: 1}, nil)
+
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 200, A: 255})
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	comment := append([]byte{0xFF, 0xFE, 0x01, 0x02}, bytes.Repeat([]byte("x"), 256)...)
+	data := append(append(append([]byte{}, buf.Bytes()[:2]...), comment...), buf.Bytes()[2:]...)
+
+	ingested, err := service.applyIngestPolicy(context.Background(), 1, "image/jpeg", bytes.NewReader(data), "")
+	require.NoError(t, err)
+	require.NotNil(t, ingested)
+	t.Cleanup(func() { _ = os.Remove(ingested.path) })
+
+	assert.Empty(t, *calls, "jpeg optimization does not re-encode")
+	assert.Equal(t, "image/jpeg", ingested.mimeType)
+	assert.Equal(t, int64(buf.Len()), ingested.size)
+	out, err := os.ReadFile(ingested.path)
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), out)
+}
+
+func writeTempUpload(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upload")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
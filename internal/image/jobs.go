@@ -0,0 +1,301 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	config "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
+)
+
+// 持久化任务类型
+const (
+	JobTypeVariantPipeline     = "variant_pipeline"
+	JobTypeDeleteStorageObject = "delete_storage_object"
+)
+
+// remoteProcessing 变体由其他进程（worker 命令）生成，本进程只负责入队
+var remoteProcessing atomic.Bool
+
+// SetRemoteProcessing 标记变体由其他进程生成：入队时不按本进程的 libvips 能力裁剪格式，
+// 由执行任务的 worker 判断，未支持的格式按跳过处理；暂存的上传文件也不交给任务
+func SetRemoteProcessing(remote bool) {
+	remoteProcessing.Store(remote)
+}
+
+func avifEncodingAvailable() bool {
+	return remoteProcessing.Load() || vipsfile.SupportsAVIFEncoding()
+}
+
+func jxlEncodingAvailable() bool {
+	return remoteProcessing.Load() || vipsfile.SupportsJXLEncoding()
+}
+
+// objectDeletionRetryDelay 存储对象删除失败后首次重试的延迟
+var objectDeletionRetryDelay = time.Minute
+
+// pipelineJobPayload 变体流水线任务负载；设置和存储在执行时按图片重新解析
+type pipelineJobPayload struct {
+	ImageID         uint                  `json:"image_id"`
+	ThumbVariantID  uint                  `json:"thumb_variant_id,omitempty"`
+	WebPVariantID   uint                  `json:"webp_variant_id,omitempty"`
+	AVIFVariantID   uint                  `json:"avif_variant_id,omitempty"`
+	JXLVariantID    uint                  `json:"jxl_variant_id,omitempty"`
+	StageVariantIDs map[string]uint       `json:"stage_variant_ids,omitempty"` // 扩展阶段名称到变体 ID
+	ExtraThumbnails []worker.ThumbnailJob `json:"extra_thumbnails,omitempty"`
+	LocalFilePath   string                `json:"local_file_path,omitempty"`
+	SplitPart       bool                  `json:"split_part,omitempty"` // 缩略图与全尺寸变体拆分提交时的一部分
+	Reprocess       bool                  `json:"reprocess,omitempty"`  // 重新生成已有变体，领取任务时才重置为 pending
+}
+
+// splitPipelinePayload 将负载拆为缩略图与全尺寸变体两部分，任一部分为空时返回 false；
+// 预先暂存的本地文件交给先执行的缩略图任务，全尺寸任务从存储读取原图
+func splitPipelinePayload(p pipelineJobPayload) (thumbs, full pipelineJobPayload, ok bool) {
+	thumbs = pipelineJobPayload{
+		ImageID:         p.ImageID,
+		ThumbVariantID:  p.ThumbVariantID,
+		ExtraThumbnails: p.ExtraThumbnails,
+		LocalFilePath:   p.LocalFilePath,
+		SplitPart:       true,
+		Reprocess:       p.Reprocess,
+	}
+	full = pipelineJobPayload{
+		ImageID:         p.ImageID,
+		WebPVariantID:   p.WebPVariantID,
+		AVIFVariantID:   p.AVIFVariantID,
+		JXLVariantID:    p.JXLVariantID,
+		StageVariantIDs: p.StageVariantIDs,
+		SplitPart:       true,
+		Reprocess:       p.Reprocess,
+	}
+	return thumbs, full, len(thumbs.variantIDs()) > 0 && len(full.variantIDs()) > 0
+}
+
+func (p *pipelineJobPayload) variantIDs() []uint {
+	ids := make([]uint, 0, 4+len(p.StageVariantIDs)+len(p.ExtraThumbnails))
+	for _, id := range []uint{p.ThumbVariantID, p.WebPVariantID, p.AVIFVariantID, p.JXLVariantID} {
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	for _, id := range p.StageVariantIDs {
+		ids = append(ids, id)
+	}
+	for _, job := range p.ExtraThumbnails {
+		ids = append(ids, job.VariantID)
+	}
+	return ids
+}
+
+// deleteObjectPayload 存储对象删除任务负载
+type deleteObjectPayload struct {
+	StorageConfigID uint   `json:"storage_config_id"`
+	Path            string `json:"path"`
+}
+
+// RegisterPipelineJobHandlers 注册变体流水线任务处理函数，只应在负责图片处理的进程中调用
+func RegisterPipelineJobHandlers(queue *worker.Queue, converter *Converter) {
+	queue.Register(JobTypeVariantPipeline, converter.runPipelineJob)
+}
+
+// RegisterStorageJobHandlers 注册存储维护任务处理函数
+func RegisterStorageJobHandlers(queue *worker.Queue) {
+	queue.Register(JobTypeDeleteStorageObject, runDeleteObjectJob)
+}
+
+// submitPipeline 提交变体流水线：启用持久化队列时写入 jobs 表，否则直接交给 Pool
+// priority 决定执行通道，同一通道内按图片所属用户轮转
+func (c *Converter) submitPipeline(image *models.Image, payload pipelineJobPayload, settings *config.ImageProcessingSettings, storageProvider storage.Provider, priority worker.Priority) bool {
+	if queue := worker.GetGlobalQueue(); queue != nil {
+		// 其他主机上的 worker 无法读取本机暂存的文件，入队后即删除，由 worker 从存储下载原图
+		if remoteProcessing.Load() && payload.LocalFilePath != "" {
+			defer func(path string) { _ = os.Remove(path) }(payload.LocalFilePath)
+			payload.LocalFilePath = ""
+		}
+		ctx, cancel := utils.DetachedContext(5 * time.Second)
+		defer cancel()
+		opts := worker.EnqueueOptions{Priority: priority, Owner: image.UserID, Subject: image.ID}
+		if _, err := queue.Enqueue(ctx, JobTypeVariantPipeline, payload, opts); err != nil {
+			converterLog.Warnf("Failed to enqueue pipeline job for %s: %v", image.Identifier, err)
+			return false
+		}
+		return true
+	}
+
+	pool := worker.GetGlobalPool()
+	if pool == nil {
+		return false
+	}
+	return pool.SubmitWith(worker.TaskOptions{Priority: priority, Owner: image.UserID}, func() {
+		ctx, cancel := utils.DetachedContext(5 * time.Second)
+		err := c.requeueReprocessedVariants(ctx, payload)
+		cancel()
+		if err != nil {
+			converterLog.Warnf("Failed to requeue variants for %s: %v", image.Identifier, err)
+			return
+		}
+		_ = c.newPipelineTask(image, payload, settings, storageProvider).Execute(context.Background())
+	})
+}
+
+// submitUploadPipeline 交互优先级下缩略图单独成任务优先处理，WebP/AVIF 等全尺寸变体降为格式转换优先级，
+// 避免大图编码占用交互通道；其他优先级整体提交
+func (c *Converter) submitUploadPipeline(image *models.Image, payload pipelineJobPayload, settings *config.ImageProcessingSettings, storageProvider storage.Provider, priority worker.Priority) bool {
+	thumbs, full, ok := splitPipelinePayload(payload)
+	if priority != worker.PriorityInteractive || !ok {
+		return c.submitPipeline(image, payload, settings, storageProvider, priority)
+	}
+	if !c.submitPipeline(image, thumbs, settings, storageProvider, worker.PriorityInteractive) {
+		return false
+	}
+	// 全尺寸任务提交失败时调用方将全部变体标记为失败，已入队的缩略图任务无法领取变体而直接结束
+	return c.submitPipeline(image, full, settings, storageProvider, worker.PriorityConversion)
+}
+
+func (c *Converter) newPipelineTask(image *models.Image, payload pipelineJobPayload, settings *config.ImageProcessingSettings, storageProvider storage.Provider) *worker.ImagePipelineTask {
+	return &worker.ImagePipelineTask{
+		ThumbVariantID:  payload.ThumbVariantID,
+		WebPVariantID:   payload.WebPVariantID,
+		AVIFVariantID:   payload.AVIFVariantID,
+		JXLVariantID:    payload.JXLVariantID,
+		StageVariants:   payload.StageVariantIDs,
+		ExtraThumbnails: payload.ExtraThumbnails,
+		FocalX:          image.FocalX,
+		FocalY:          image.FocalY,
+		ImageID:         image.ID,
+		StoragePath:     image.StoragePath,
+		ImageIdentifier: image.Identifier,
+		FileSize:        image.FileSize,
+		MimeType:        image.MimeType,
+		Storage:         storageProvider,
+		Settings:        settings,
+		VariantRepo:     c.variantRepo,
+		ImageRepo:       c.imageRepo,
+		CacheHelper:     c.cacheHelper,
+		StageRuns:       c.variantRepo,
+		LocalFilePath:   payload.LocalFilePath,
+		SplitPart:       payload.SplitPart,
+	}
+}
+
+// runPipelineJob 执行持久化的变体流水线任务
+func (c *Converter) runPipelineJob(ctx context.Context, job *models.Job) error {
+	var payload pipelineJobPayload
+	if err := worker.DecodePayload(job, &payload); err != nil {
+		return err
+	}
+
+	image, err := c.imageRepo.WithContext(ctx).GetImageByID(payload.ImageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if payload.LocalFilePath != "" {
+				_ = os.Remove(payload.LocalFilePath)
+			}
+			return worker.PermanentError(fmt.Errorf("image %d no longer exists", payload.ImageID))
+		}
+		return fmt.Errorf("load image %d: %w", payload.ImageID, err)
+	}
+
+	settings, err := c.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("load image processing settings: %w", err)
+	}
+	storageProvider := c.getStorageForImage(image)
+	if storageProvider == nil {
+		return fmt.Errorf("storage provider unavailable (StorageConfigID=%d)", image.StorageConfigID)
+	}
+
+	// 重试的任务：上一次执行失败或在处理中途退出，变体停留在 failed 或 processing
+	if job.Attempts > 1 {
+		if _, err := c.variantRepo.WithContext(ctx).ResetVariantsForRetry(payload.variantIDs()); err != nil {
+			return fmt.Errorf("reset variants for retry: %w", err)
+		}
+	}
+
+	if err := c.requeueReprocessedVariants(ctx, payload); err != nil {
+		return err
+	}
+	return c.newPipelineTask(image, payload, settings, storageProvider).Execute(ctx)
+}
+
+// requeueReprocessedVariants 重新处理的任务开始执行时才把变体重置为 pending，
+// 排队期间旧变体继续提供服务；重试时上一次已完成的变体同样需要重置，否则无法领取
+func (c *Converter) requeueReprocessedVariants(ctx context.Context, payload pipelineJobPayload) error {
+	if !payload.Reprocess {
+		return nil
+	}
+	if _, err := c.variantRepo.WithContext(ctx).RequeueVariants(payload.variantIDs()); err != nil {
+		return fmt.Errorf("requeue variants: %w", err)
+	}
+	return nil
+}
+
+// ActivePipelineImages 返回仍有等待或执行中变体流水线任务的图片 ID 子查询，供 Sweeper 跳过
+func ActivePipelineImages(ctx context.Context) *gorm.DB {
+	queue := worker.GetGlobalQueue()
+	if queue == nil {
+		return nil
+	}
+	return queue.ActiveSubjects(ctx, JobTypeVariantPipeline)
+}
+
+// InFlightPipelineTasks 返回当前进程持有租约的变体流水线任务
+func InFlightPipelineTasks(ctx context.Context) ([]worker.InFlightTaskSnapshot, error) {
+	queue := worker.GetGlobalQueue()
+	if queue == nil {
+		return nil, nil
+	}
+	jobs, err := queue.RunningJobs(ctx, JobTypeVariantPipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]worker.InFlightTaskSnapshot, 0, len(jobs))
+	for _, job := range jobs {
+		var payload pipelineJobPayload
+		if err := worker.DecodePayload(job, &payload); err != nil {
+			converterLog.Warnf("Skipping undecodable pipeline job %d: %v", job.ID, err)
+			continue
+		}
+		snapshots = append(snapshots, worker.InFlightTaskSnapshot{
+			ImageID:    payload.ImageID,
+			VariantIDs: payload.variantIDs(),
+		})
+	}
+	return snapshots, nil
+}
+
+// enqueueObjectDeletion 存储对象删除失败时写入持久化队列稍后重试
+func enqueueObjectDeletion(ctx context.Context, storageConfigID uint, path string) {
+	queue := worker.GetGlobalQueue()
+	if queue == nil || path == "" {
+		return
+	}
+	payload := deleteObjectPayload{StorageConfigID: storageConfigID, Path: path}
+	opts := worker.EnqueueOptions{Priority: worker.PriorityMaintenance, RunAt: time.Now().Add(objectDeletionRetryDelay)}
+	if _, err := queue.Enqueue(ctx, JobTypeDeleteStorageObject, payload, opts); err != nil {
+		deleteLog.Warnf("Failed to queue deletion retry for %s: %v", path, err)
+	}
+}
+
+// runDeleteObjectJob 重试删除存储对象
+func runDeleteObjectJob(ctx context.Context, job *models.Job) error {
+	var payload deleteObjectPayload
+	if err := worker.DecodePayload(job, &payload); err != nil {
+		return err
+	}
+	provider, err := getStorageProviderByID(payload.StorageConfigID)
+	if err != nil {
+		return err
+	}
+	return provider.DeleteWithContext(ctx, payload.Path)
+}
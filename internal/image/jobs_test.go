@@ -0,0 +1,119 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelineJobPayloadRoundTrip(t *testing.T) {
+	payload := pipelineJobPayload{
+		ImageID:         7,
+		ThumbVariantID:  1,
+		AVIFVariantID:   3,
+		StageVariantIDs: map[string]uint{"watermark": 5},
+		ExtraThumbnails: []worker.ThumbnailJob{
+			{VariantID: 9, Size: models.ThumbnailSize{Width: 400, Height: 300, Crop: models.ThumbnailCropFocal}},
+		},
+	}
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	var decoded pipelineJobPayload
+	require.NoError(t, worker.DecodePayload(&models.Job{Type: JobTypeVariantPipeline, Payload: string(data)}, &decoded))
+	assert.Equal(t, payload, decoded)
+	assert.Equal(t, []uint{1, 3, 5, 9}, decoded.variantIDs())
+}
+
+func TestSplitPipelinePayload(t *testing.T) {
+	payload := pipelineJobPayload{
+		ImageID:         7,
+		ThumbVariantID:  1,
+		WebPVariantID:   2,
+		AVIFVariantID:   3,
+		StageVariantIDs: map[string]uint{"watermark": 5},
+		ExtraThumbnails: []worker.ThumbnailJob{{VariantID: 9}},
+		LocalFilePath:   "/tmp/upload",
+		Reprocess:       true,
+	}
+
+	thumbs, full, ok := splitPipelinePayload(payload)
+	require.True(t, ok)
+	assert.Equal(t, []uint{1, 9}, thumbs.variantIDs())
+	assert.Equal(t, "/tmp/upload", thumbs.LocalFilePath)
+	assert.ElementsMatch(t, []uint{2, 3, 5}, full.variantIDs())
+	assert.Empty(t, full.LocalFilePath, "the staged file is owned by a single job")
+	assert.True(t, thumbs.SplitPart)
+	assert.True(t, full.SplitPart)
+	assert.True(t, thumbs.Reprocess)
+	assert.True(t, full.Reprocess)
+
+	_, _, ok = splitPipelinePayload(pipelineJobPayload{ImageID: 7, WebPVariantID: 2})
+	assert.False(t, ok, "nothing to split without thumbnails")
+}
+
+func TestRunPipelineJobMissingImageIsPermanent(t *testing.T) {
+	db := setupConverterTestDB(t)
+	converter := &Converter{
+		imageRepo:   images.NewRepository(db),
+		variantRepo: images.NewVariantRepository(db),
+	}
+
+	job := &models.Job{Type: JobTypeVariantPipeline, Payload: `{"image_id":42}`, Attempts: 1}
+	err := converter.runPipelineJob(context.Background(), job)
+	require.Error(t, err)
+
+	assert.True(t, worker.IsPermanent(err), "missing images must not be retried")
+
+	err = converter.runPipelineJob(context.Background(), &models.Job{Type: JobTypeVariantPipeline, Payload: "{"})
+	require.Error(t, err)
+	assert.True(t, worker.IsPermanent(err), "undecodable payloads must not be retried")
+}
+
+func TestReprocessedVariantsResetOnlyWhenJobRuns(t *testing.T) {
+	db := setupConverterTestDB(t)
+	variantRepo := images.NewVariantRepository(db)
+	converter := &Converter{variantRepo: variantRepo}
+
+	completed := &models.ImageVariant{ImageID: 1, Format: models.FormatWebP, Status: models.VariantStatusCompleted}
+	processing := &models.ImageVariant{ImageID: 1, Format: models.FormatAVIF, Status: models.VariantStatusProcessing}
+	require.NoError(t, db.Create(completed).Error)
+	require.NoError(t, db.Create(processing).Error)
+
+	var scope *reprocessScope
+	assert.False(t, scope.ready(completed, time.Now(), true), "regular conversions skip completed variants")
+	scope = &reprocessScope{}
+	assert.True(t, scope.ready(completed, time.Now(), true))
+	assert.False(t, scope.ready(processing, time.Now(), true))
+
+	ctx := context.Background()
+	payload := pipelineJobPayload{ImageID: 1, WebPVariantID: completed.ID, AVIFVariantID: processing.ID}
+	require.NoError(t, converter.requeueReprocessedVariants(ctx, payload))
+	unchanged, err := variantRepo.GetByID(completed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusCompleted, unchanged.Status, "only reprocess jobs reset variants")
+
+	payload.Reprocess = true
+	require.NoError(t, converter.requeueReprocessedVariants(ctx, payload))
+	requeued, err := variantRepo.GetByID(completed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusPending, requeued.Status)
+	running, err := variantRepo.GetByID(processing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusProcessing, running.Status)
+}
+
+func TestRemoteProcessingDoesNotGateOnLocalEncoders(t *testing.T) {
+	SetRemoteProcessing(true)
+	t.Cleanup(func() { SetRemoteProcessing(false) })
+
+	assert.True(t, avifEncodingAvailable(), "the worker decides whether it can encode AVIF")
+	assert.True(t, jxlEncodingAvailable())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/anoixa/image-bed/config"
 	dbconfig "github.com/anoixa/image-bed/config/db"
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/fulltext"
 	"github.com/anoixa/image-bed/database/repo/images"
 )
 
@@ -25,7 +26,7 @@ func NewQueryService(repo *images.Repository, configManager *dbconfig.Manager) *
 }
 
 // ListImages 获取图片列表
-func (s *QueryService) ListImages(ctx context.Context, storageType string, identifier string, search string, albumID *uint, startTime, endTime int64, sort string, page int, limit int, userID int) (*ListImagesResult, error) {
+func (s *QueryService) ListImages(ctx context.Context, storageType string, identifier string, search string, albumID *uint, tags images.TagFilter, startTime, endTime int64, sort string, page int, limit int, userID int) (*ListImagesResult, error) {
 	if page <= 0 {
 		page = 1
 	}
@@ -51,7 +52,7 @@ func (s *QueryService) ListImages(ctx context.Context, storageType string, ident
 		}, nil
 	}
 
-	list, total, err := s.repo.WithContext(ctx).GetImageList(storageConfigIDs, identifier, search, albumID, startTime, endTime, sort, page, limit, userID)
+	list, total, err := s.repo.WithContext(ctx).GetImageList(storageConfigIDs, identifier, search, albumID, tags, startTime, endTime, sort, page, limit, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get image list: %w", err)
 	}
@@ -61,12 +62,24 @@ func (s *QueryService) ListImages(ctx context.Context, storageType string, ident
 		totalPages++
 	}
 
+	var highlights map[uint]*fulltext.Highlight
+	if search != "" && len(list) > 0 {
+		imageIDs := make([]uint, len(list))
+		for i, img := range list {
+			imageIDs[i] = img.ID
+		}
+		if highlights, err = s.repo.WithContext(ctx).SearchHighlights(imageIDs, search); err != nil {
+			return nil, fmt.Errorf("failed to highlight search results: %w", err)
+		}
+	}
+
 	return &ListImagesResult{
 		Images:     list,
 		Total:      total,
 		Page:       page,
 		Limit:      limit,
 		TotalPages: totalPages,
+		Highlights: highlights,
 	}, nil
 }
 
@@ -118,8 +118,8 @@ func (s *ReadService) CheckImagePermission(image *models.Image, userID uint) boo
 	return userID != 0 && userID == image.UserID
 }
 
-// GetImageWithVariant 获取图片
-func (s *ReadService) GetImageWithVariant(ctx context.Context, identifier string, acceptHeader string, userID uint) (*ImageResultDTO, error) {
+// GetImageWithVariant 获取图片，hints 为客户端的 Client Hints
+func (s *ReadService) GetImageWithVariant(ctx context.Context, identifier string, acceptHeader string, hints ClientHints, userID uint) (*ImageResultDTO, error) {
 	image, err := s.GetImageMetadata(ctx, identifier)
 	if err != nil {
 		return nil, err
@@ -128,8 +128,12 @@ func (s *ReadService) GetImageWithVariant(ctx context.Context, identifier string
 	if !s.CheckImagePermission(image, userID) {
 		return nil, ErrForbidden
 	}
+	// 所有者查看不计入查看次数
+	if err := s.CheckImageAvailability(ctx, image, userID != image.UserID); err != nil {
+		return nil, err
+	}
 
-	return s.buildImageResult(ctx, image, acceptHeader), nil
+	return s.buildImageResult(ctx, image, acceptHeader, hints), nil
 }
 
 // GetRandomImageWithVariant 获取随机图片
@@ -139,10 +143,10 @@ func (s *ReadService) GetRandomImageWithVariant(ctx context.Context, filter *ima
 		return nil, err
 	}
 
-	return s.buildImageResult(ctx, image, acceptHeader), nil
+	return s.buildImageResult(ctx, image, acceptHeader, ClientHints{}), nil
 }
 
-func (s *ReadService) buildImageResult(ctx context.Context, image *models.Image, acceptHeader string) *ImageResultDTO {
+func (s *ReadService) buildImageResult(ctx context.Context, image *models.Image, acceptHeader string, hints ClientHints) *ImageResultDTO {
 	accessURL := utils.BuildImageURL(s.baseURL, image.Identifier)
 
 	if s.variantService == nil {
@@ -154,7 +158,7 @@ func (s *ReadService) buildImageResult(ctx context.Context, image *models.Image,
 		}
 	}
 
-	variantResult, err := s.variantService.SelectBestVariant(ctx, image, acceptHeader)
+	variantResult, err := s.variantService.SelectBestVariant(ctx, image, acceptHeader, hints)
 	if err != nil {
 		return &ImageResultDTO{
 			Image:      image,
@@ -166,7 +170,7 @@ func (s *ReadService) buildImageResult(ctx context.Context, image *models.Image,
 
 	if variantResult.ShouldTriggerConversion && s.converter != nil && s.submitTask != nil {
 		s.submitTask(func() {
-			s.converter.TriggerConversion(image)
+			s.converter.TriggerDeferredConversion(image)
 		})
 	}
 
@@ -0,0 +1,401 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/internal/worker"
+	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
+)
+
+var reprocessLog = utils.ForModule("Reprocess")
+
+const (
+	DefaultReprocessBatchSize       = 50
+	MaxReprocessBatchSize           = 500
+	DefaultReprocessBatchIntervalMs = 2000
+	MaxReprocessBatchIntervalMs     = 10 * 60 * 1000
+)
+
+// reprocessBacklogPollInterval worker 队列积压时的轮询间隔
+var reprocessBacklogPollInterval = time.Second
+
+var (
+	ErrReprocessJobNotFound = errors.New("reprocess job not found")
+	ErrReprocessJobState    = errors.New("reprocess job is not in a valid state for this operation")
+)
+
+// reprocessableFormats 可按格式筛选重新生成的变体
+var reprocessableFormats = []string{models.FormatWebP, models.FormatAVIF, models.FormatJXL, models.FormatThumbnail}
+
+// ReprocessRequest 创建重新处理任务的参数
+type ReprocessRequest struct {
+	StartTime       *time.Time
+	EndTime         *time.Time
+	StorageConfigID *uint
+	MimeType        string
+	AlbumID         *uint
+	Formats         []string
+	BatchSize       int
+	BatchIntervalMs int
+}
+
+// Validate 校验并补全默认值
+func (r *ReprocessRequest) Validate() error {
+	if r.StartTime != nil && r.EndTime != nil && r.EndTime.Before(*r.StartTime) {
+		return errors.New("end time must not be before start time")
+	}
+	for _, format := range r.Formats {
+		if !slices.Contains(reprocessableFormats, format) {
+			return fmt.Errorf("unsupported variant format %q (allowed: %s)", format, strings.Join(reprocessableFormats, ", "))
+		}
+	}
+	if r.BatchSize < 0 || r.BatchSize > MaxReprocessBatchSize {
+		return fmt.Errorf("batch size must be between 1 and %d", MaxReprocessBatchSize)
+	}
+	if r.BatchIntervalMs < 0 || r.BatchIntervalMs > MaxReprocessBatchIntervalMs {
+		return fmt.Errorf("batch interval must be between 0 and %d ms", MaxReprocessBatchIntervalMs)
+	}
+	if r.BatchSize == 0 {
+		r.BatchSize = DefaultReprocessBatchSize
+	}
+	if r.BatchIntervalMs == 0 {
+		r.BatchIntervalMs = DefaultReprocessBatchIntervalMs
+	}
+	return nil
+}
+
+// imageReprocessor 单张图片的重新处理实现（由 Converter 提供）
+type imageReprocessor interface {
+	ReprocessImage(ctx context.Context, image *models.Image, formats []string) (int, error)
+}
+
+// ReprocessService 批量重新生成变体任务
+// 任务状态持久化在数据库中，进度按批次提交；进程重启后 running 状态的任务从游标处继续。
+type ReprocessService struct {
+	jobRepo     *images.ReprocessJobRepository
+	imageRepo   *images.Repository
+	reprocessor imageReprocessor
+	saturated   func() bool
+
+	mu      sync.Mutex
+	running map[uint]*reprocessRunner
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// reprocessRunner 单个任务的执行协程
+type reprocessRunner struct {
+	cancel  context.CancelFunc
+	stopped bool
+	done    chan struct{}
+}
+
+// NewReprocessService 创建批量重新处理服务
+func NewReprocessService(jobRepo *images.ReprocessJobRepository, imageRepo *images.Repository, converter *Converter) *ReprocessService {
+	return &ReprocessService{
+		jobRepo:     jobRepo,
+		imageRepo:   imageRepo,
+		reprocessor: converter,
+		saturated:   workerPoolSaturated,
+		running:     make(map[uint]*reprocessRunner),
+	}
+}
+
+// workerPoolSaturated 等待执行的任务不少于 worker 数时暂停投递，避免挤占上传触发的转换
+func workerPoolSaturated() bool {
+	pool := worker.GetGlobalPool()
+	if pool == nil {
+		return false
+	}
+	stats := pool.GetStats()
+	waiting := int64(stats.QueueSize)
+	if queue := worker.GetGlobalQueue(); queue != nil {
+		backlog, err := queue.Backlog(context.Background())
+		if err != nil {
+			return false
+		}
+		waiting += backlog
+	}
+	return waiting >= int64(max(stats.WorkerCount, 1))
+}
+
+// Create 创建任务并立即开始执行
+func (s *ReprocessService) Create(ctx context.Context, req ReprocessRequest, userID uint) (*models.ReprocessJob, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	job := &models.ReprocessJob{
+		Status:          models.ReprocessJobStatusRunning,
+		CreatedBy:       userID,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		StorageConfigID: req.StorageConfigID,
+		MimeType:        req.MimeType,
+		AlbumID:         req.AlbumID,
+		Formats:         strings.Join(req.Formats, ","),
+		BatchSize:       req.BatchSize,
+		BatchIntervalMs: req.BatchIntervalMs,
+	}
+
+	total, err := s.imageRepo.WithContext(ctx).CountImagesForReprocess(images.ReprocessFilterFromJob(job))
+	if err != nil {
+		return nil, fmt.Errorf("count images: %w", err)
+	}
+	now := time.Now()
+	job.Total = total
+	job.StartedAt = &now
+
+	if err := s.jobRepo.WithContext(ctx).Create(job); err != nil {
+		return nil, fmt.Errorf("create reprocess job: %w", err)
+	}
+
+	s.start(job.ID)
+	return job, nil
+}
+
+// Get 获取任务
+func (s *ReprocessService) Get(ctx context.Context, id uint) (*models.ReprocessJob, error) {
+	job, err := s.jobRepo.WithContext(ctx).GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReprocessJobNotFound
+	}
+	return job, err
+}
+
+// List 列出最近的任务
+func (s *ReprocessService) List(ctx context.Context, limit int) ([]*models.ReprocessJob, error) {
+	return s.jobRepo.WithContext(ctx).List(limit)
+}
+
+// Pause 暂停任务，当前图片处理完后停止
+func (s *ReprocessService) Pause(ctx context.Context, id uint) (*models.ReprocessJob, error) {
+	if err := s.transition(ctx, id, []string{models.ReprocessJobStatusRunning}, models.ReprocessJobStatusPaused, nil); err != nil {
+		return nil, err
+	}
+	s.stop(id)
+	return s.Get(ctx, id)
+}
+
+// Resume 从游标处继续已暂停的任务
+func (s *ReprocessService) Resume(ctx context.Context, id uint) (*models.ReprocessJob, error) {
+	if err := s.transition(ctx, id, []string{models.ReprocessJobStatusPaused}, models.ReprocessJobStatusRunning, nil); err != nil {
+		return nil, err
+	}
+	s.start(id)
+	return s.Get(ctx, id)
+}
+
+// Cancel 取消运行中或已暂停的任务
+func (s *ReprocessService) Cancel(ctx context.Context, id uint) (*models.ReprocessJob, error) {
+	from := []string{models.ReprocessJobStatusRunning, models.ReprocessJobStatusPaused}
+	if err := s.transition(ctx, id, from, models.ReprocessJobStatusCancelled, map[string]any{"finished_at": time.Now()}); err != nil {
+		return nil, err
+	}
+	s.stop(id)
+	return s.Get(ctx, id)
+}
+
+// ResumeRunning 启动时恢复上次未完成的 running 任务
+func (s *ReprocessService) ResumeRunning(ctx context.Context) {
+	jobs, err := s.jobRepo.WithContext(ctx).ListByStatus(models.ReprocessJobStatusRunning)
+	if err != nil {
+		reprocessLog.Warnf("Failed to load running reprocess jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		reprocessLog.Infof("Resuming reprocess job %d from image %d", job.ID, job.LastImageID)
+		s.start(job.ID)
+	}
+}
+
+// Shutdown 停止所有任务执行协程，任务状态保持 running 以便下次启动继续
+func (s *ReprocessService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for _, runner := range s.running {
+		runner.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ReprocessService) transition(ctx context.Context, id uint, from []string, to string, updates map[string]any) error {
+	ok, err := s.jobRepo.WithContext(ctx).TransitionStatus(id, from, to, updates)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrReprocessJobState
+}
+
+func (s *ReprocessService) start(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	previous := s.running[id]
+	if previous != nil && !previous.stopped {
+		return
+	}
+
+	// 暂停后立即继续时，等待上一个协程退出，避免同一任务并发执行
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &reprocessRunner{cancel: cancel, done: make(chan struct{})}
+	s.running[id] = runner
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			if s.running[id] == runner {
+				delete(s.running, id)
+			}
+			s.mu.Unlock()
+			cancel()
+			close(runner.done)
+		}()
+		if previous != nil {
+			select {
+			case <-previous.done:
+			case <-ctx.Done():
+				return
+			}
+		}
+		s.run(ctx, id)
+	}()
+}
+
+func (s *ReprocessService) stop(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runner, ok := s.running[id]; ok {
+		runner.stopped = true
+		runner.cancel()
+	}
+}
+
+// run 按批次处理任务，每批结束后提交进度；状态变更（暂停/取消）在批次之间生效
+func (s *ReprocessService) run(ctx context.Context, id uint) {
+	jobRepo := s.jobRepo.WithContext(ctx)
+	imageRepo := s.imageRepo.WithContext(ctx)
+
+	for {
+		job, err := jobRepo.GetByID(id)
+		if err != nil {
+			if ctx.Err() == nil {
+				reprocessLog.Warnf("Failed to load reprocess job %d: %v", id, err)
+			}
+			return
+		}
+		if job.Status != models.ReprocessJobStatusRunning {
+			return
+		}
+
+		batch, err := imageRepo.ListImagesForReprocess(images.ReprocessFilterFromJob(job), job.LastImageID, job.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.fail(id, fmt.Sprintf("list images: %v", err))
+			}
+			return
+		}
+		if len(batch) == 0 {
+			_, err := s.jobRepo.TransitionStatus(id, []string{models.ReprocessJobStatusRunning}, models.ReprocessJobStatusCompleted, map[string]any{"finished_at": time.Now()})
+			if err != nil {
+				reprocessLog.Warnf("Failed to complete reprocess job %d: %v", id, err)
+			}
+			reprocessLog.Infof("Reprocess job %d completed", id)
+			return
+		}
+
+		lastID := job.LastImageID
+		var queued, submitFailed, purged int64
+		formats := job.FormatList()
+		for _, img := range batch {
+			if !s.waitForCapacity(ctx) {
+				break
+			}
+			n, err := s.reprocessor.ReprocessImage(ctx, img, formats)
+			purged += int64(n)
+			if err != nil && ctx.Err() != nil {
+				// 被暂停/取消打断的图片不计入失败，继续时重新处理
+				break
+			}
+			if err != nil {
+				reprocessLog.Warnf("Reprocess job %d: image %s failed: %v", id, utils.SanitizeLogMessage(img.Identifier), err)
+				submitFailed++
+			} else {
+				queued++
+			}
+			lastID = img.ID
+		}
+
+		// 使用独立 context 提交进度，避免暂停/取消时丢失已处理的部分
+		if lastID != job.LastImageID {
+			if err := s.jobRepo.AdvanceProgress(id, lastID, queued, submitFailed, purged); err != nil {
+				reprocessLog.Warnf("Failed to record progress for reprocess job %d: %v", id, err)
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(job.BatchIntervalMs) * time.Millisecond):
+		}
+	}
+}
+
+// waitForCapacity 等待 worker 队列有空位，context 结束时返回 false
+func (s *ReprocessService) waitForCapacity(ctx context.Context) bool {
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		if s.saturated == nil || !s.saturated() {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(reprocessBacklogPollInterval):
+		}
+	}
+}
+
+func (s *ReprocessService) fail(id uint, msg string) {
+	reprocessLog.Warnf("Reprocess job %d failed: %s", id, msg)
+	_, err := s.jobRepo.TransitionStatus(id, []string{models.ReprocessJobStatusRunning}, models.ReprocessJobStatusFailed, map[string]any{
+		"error_message": msg,
+		"finished_at":   time.Now(),
+	})
+	if err != nil {
+		reprocessLog.Warnf("Failed to mark reprocess job %d failed: %v", id, err)
+	}
+}
@@ -0,0 +1,144 @@
+package image
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeReprocessor struct {
+	mu        sync.Mutex
+	processed []string
+	formats   []string
+	block     chan struct{}
+}
+
+func (f *fakeReprocessor) ReprocessImage(ctx context.Context, image *models.Image, formats []string) (int, error) {
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.processed = append(f.processed, image.Identifier)
+	f.formats = formats
+	return 1, nil
+}
+
+func (f *fakeReprocessor) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.processed)
+}
+
+func TestReprocessRequestValidate(t *testing.T) {
+	req := ReprocessRequest{Formats: []string{models.FormatWebP, models.FormatThumbnail}}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, DefaultReprocessBatchSize, req.BatchSize)
+	assert.Equal(t, DefaultReprocessBatchIntervalMs, req.BatchIntervalMs)
+
+	bad := ReprocessRequest{Formats: []string{"gif"}}
+	assert.Error(t, bad.Validate())
+
+	start := time.Now()
+	end := start.Add(-time.Hour)
+	inverted := ReprocessRequest{StartTime: &start, EndTime: &end}
+	assert.Error(t, inverted.Validate())
+
+	tooLarge := ReprocessRequest{BatchSize: MaxReprocessBatchSize + 1}
+	assert.Error(t, tooLarge.Validate())
+}
+
+func TestReprocessServiceRunsToCompletion(t *testing.T) {
+	db := setupConverterTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReprocessJob{}))
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, db.Create(&models.Image{Identifier: id, StoragePath: id, OriginalName: id, MimeType: "image/png", FileHash: id}).Error)
+	}
+
+	fake := &fakeReprocessor{}
+	svc := newTestReprocessService(t, db, fake)
+
+	job, err := svc.Create(context.Background(), ReprocessRequest{Formats: []string{models.FormatWebP}, BatchSize: 2, BatchIntervalMs: 1}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), job.Total)
+
+	require.Eventually(t, func() bool {
+		stored, err := svc.Get(context.Background(), job.ID)
+		return err == nil && stored.Status == models.ReprocessJobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stored, err := svc.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stored.Queued)
+	assert.Equal(t, int64(3), stored.Purged)
+	assert.NotNil(t, stored.FinishedAt)
+	assert.Equal(t, []string{"a", "b", "c"}, fake.processed)
+	assert.Equal(t, []string{models.FormatWebP}, fake.formats)
+	require.NoError(t, svc.Shutdown(context.Background()))
+}
+
+func TestReprocessServicePauseResumeCancel(t *testing.T) {
+	db := setupConverterTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.ReprocessJob{}))
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, db.Create(&models.Image{Identifier: id, StoragePath: id, OriginalName: id, MimeType: "image/png", FileHash: id}).Error)
+	}
+
+	fake := &fakeReprocessor{block: make(chan struct{})}
+	svc := newTestReprocessService(t, db, fake)
+	ctx := context.Background()
+
+	job, err := svc.Create(ctx, ReprocessRequest{BatchSize: 1, BatchIntervalMs: 1}, 1)
+	require.NoError(t, err)
+
+	paused, err := svc.Pause(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReprocessJobStatusPaused, paused.Status)
+
+	_, err = svc.Pause(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrReprocessJobState)
+
+	close(fake.block)
+	resumed, err := svc.Resume(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReprocessJobStatusRunning, resumed.Status)
+
+	cancelled, err := svc.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReprocessJobStatusCancelled, cancelled.Status)
+
+	_, err = svc.Resume(ctx, job.ID)
+	assert.ErrorIs(t, err, ErrReprocessJobState)
+
+	_, err = svc.Cancel(ctx, job.ID+100)
+	assert.ErrorIs(t, err, ErrReprocessJobNotFound)
+
+	require.NoError(t, svc.Shutdown(ctx))
+	assert.LessOrEqual(t, fake.count(), 2)
+}
+
+func newTestReprocessService(t *testing.T, db *gorm.DB, reprocessor imageReprocessor) *ReprocessService {
+	t.Helper()
+
+	// 内存 SQLite 每个连接是独立的数据库，执行协程需要共享同一连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	return &ReprocessService{
+		jobRepo:     images.NewReprocessJobRepository(db),
+		imageRepo:   images.NewRepository(db),
+		reprocessor: reprocessor,
+		running:     make(map[uint]*reprocessRunner),
+	}
+}
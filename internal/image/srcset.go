@@ -0,0 +1,142 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	config "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/format"
+)
+
+// DefaultSrcsetSizes 未指定 sizes 时使用的默认值
+const DefaultSrcsetSizes = "100vw"
+
+// SrcsetCandidate srcset 中的一个候选地址
+type SrcsetCandidate struct {
+	URL      string `json:"url"`
+	Width    int    `json:"width"`
+	MIMEType string `json:"mime_type,omitempty"` // 为空表示按 Accept 协商
+}
+
+// SrcsetSource <picture> 中的一个 <source>
+type SrcsetSource struct {
+	Type   string `json:"type"`
+	Srcset string `json:"srcset"`
+}
+
+// SrcsetResult 响应式图片标记
+type SrcsetResult struct {
+	Identifier string            `json:"identifier"`
+	Src        string            `json:"src"`
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
+	Sizes      string            `json:"sizes"`
+	Srcset     string            `json:"srcset"`
+	Candidates []SrcsetCandidate `json:"candidates"`
+	Sources    []SrcsetSource    `json:"sources"`
+	Formats    []string          `json:"formats"` // 全尺寸地址可协商的变体格式
+	Img        string            `json:"img"`     // <img srcset> 标记
+	Picture    string            `json:"picture"` // <picture> 标记
+}
+
+// BuildSrcset 按配置的缩略图宽度和变体格式生成响应式图片标记
+func (s *VariantService) BuildSrcset(ctx context.Context, image *models.Image, baseURL, sizes, alt string) (*SrcsetResult, error) {
+	settings, err := s.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return buildSrcset(image, settings, baseURL, sizes, alt), nil
+}
+
+// buildSrcset 缩略图统一为 WebP，放在 type="image/webp" 的 <source> 中；
+// 全尺寸地址按 Accept 协商最优格式，同时作为 <img> 的回退
+func buildSrcset(image *models.Image, settings *config.ImageProcessingSettings, baseURL, sizes, alt string) *SrcsetResult {
+	if sizes == "" {
+		sizes = DefaultSrcsetSizes
+	}
+	src := utils.BuildImageURL(baseURL, image.Identifier)
+	result := &SrcsetResult{
+		Identifier: image.Identifier,
+		Src:        src,
+		Width:      image.Width,
+		Height:     image.Height,
+		Sizes:      sizes,
+		Candidates: []SrcsetCandidate{},
+		Sources:    []SrcsetSource{},
+		Formats:    []string{},
+	}
+
+	// 矢量图和未保留动画的 GIF 不生成变体
+	hasVariants := !utils.IsVectorImage(image.MimeType) && (image.MimeType != "image/gif" || settings.PreserveAnimation)
+	if hasVariants && settings.ThumbnailEnabled {
+		widths := make([]int, 0, len(settings.ThumbnailSizes))
+		for _, size := range settings.ThumbnailSizes {
+			if size.IsCropped() || (image.Width > 0 && size.Width >= image.Width) {
+				continue
+			}
+			widths = append(widths, size.Width)
+		}
+		sort.Ints(widths)
+		for _, width := range widths {
+			result.Candidates = append(result.Candidates, SrcsetCandidate{
+				URL:      utils.BuildThumbnailURLWithWidth(baseURL, image.Identifier, width),
+				Width:    width,
+				MIMEType: format.FormatRegistry[format.FormatWebP].MIMEType,
+			})
+		}
+	}
+	if hasVariants {
+		for _, f := range []format.FormatType{format.FormatJXL, format.FormatAVIF, format.FormatWebP} {
+			if settings.IsFormatEnabled(string(f)) {
+				result.Formats = append(result.Formats, string(f))
+			}
+		}
+	}
+
+	// 原图宽度未知时全尺寸地址无法写入宽度描述符，只作为 src
+	full := SrcsetCandidate{URL: src, Width: image.Width}
+	imgCandidates := []SrcsetCandidate{}
+	if full.Width > 0 {
+		result.Candidates = append(result.Candidates, full)
+		imgCandidates = append(imgCandidates, full)
+	}
+	result.Srcset = joinSrcset(result.Candidates)
+
+	imgSrcset := joinSrcset(imgCandidates)
+	result.Img = imgTag(result, imgSrcset, alt)
+	result.Picture = result.Img
+	if len(result.Candidates) > len(imgCandidates) {
+		source := SrcsetSource{Type: format.FormatRegistry[format.FormatWebP].MIMEType, Srcset: result.Srcset}
+		result.Sources = append(result.Sources, source)
+		result.Picture = fmt.Sprintf("<picture>\n  <source type=\"%s\" srcset=\"%s\" sizes=\"%s\" />\n  %s\n</picture>",
+			source.Type, html.EscapeString(source.Srcset), html.EscapeString(sizes), result.Img)
+		result.Img = imgTag(result, result.Srcset, alt)
+	}
+	return result
+}
+
+func joinSrcset(candidates []SrcsetCandidate) string {
+	parts := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		parts = append(parts, fmt.Sprintf("%s %dw", candidate.URL, candidate.Width))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func imgTag(result *SrcsetResult, srcset, alt string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<img src="%s"`, html.EscapeString(result.Src))
+	if srcset != "" {
+		fmt.Fprintf(&b, ` srcset="%s" sizes="%s"`, html.EscapeString(srcset), html.EscapeString(result.Sizes))
+	}
+	if result.Width > 0 && result.Height > 0 {
+		fmt.Fprintf(&b, ` width="%d" height="%d"`, result.Width, result.Height)
+	}
+	fmt.Fprintf(&b, ` alt="%s" loading="lazy" decoding="async" />`, html.EscapeString(alt))
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package image
+
+import (
+	"testing"
+
+	configdb "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSrcset(t *testing.T) {
+	settings := &configdb.ImageProcessingSettings{
+		ThumbnailEnabled:         true,
+		ConversionEnabledFormats: []string{models.FormatWebP, models.FormatAVIF},
+		ThumbnailSizes: []models.ThumbnailSize{
+			{Width: 800},
+			{Width: 300},
+			{Width: 200, Height: 200},
+			{Width: 3000},
+		},
+	}
+	image := &models.Image{Identifier: "abc.jpg", MimeType: "image/jpeg", Width: 1600, Height: 900}
+
+	result := buildSrcset(image, settings, "https://img.example.com", "", `a "cat"`)
+
+	assert.Equal(t, DefaultSrcsetSizes, result.Sizes)
+	assert.Equal(t, []string{"avif", "webp"}, result.Formats)
+	assert.Equal(t, "https://img.example.com/thumbnails/abc.jpg?width=300 300w, "+
+		"https://img.example.com/thumbnails/abc.jpg?width=800 800w, "+
+		"https://img.example.com/images/abc.jpg 1600w", result.Srcset)
+	require.Len(t, result.Sources, 1)
+	assert.Equal(t, "image/webp", result.Sources[0].Type)
+	assert.Contains(t, result.Picture, `<source type="image/webp"`)
+	assert.Contains(t, result.Picture, `<img src="https://img.example.com/images/abc.jpg" srcset="https://img.example.com/images/abc.jpg 1600w"`)
+	assert.Contains(t, result.Img, `alt="a &#34;cat&#34;"`)
+	assert.Contains(t, result.Img, `width="1600" height="900"`)
+}
+
+func TestBuildSrcsetWithoutVariants(t *testing.T) {
+	settings := &configdb.ImageProcessingSettings{
+		ThumbnailEnabled: true,
+		ThumbnailSizes:   []models.ThumbnailSize{{Width: 300}},
+	}
+	image := &models.Image{Identifier: "logo.svg", MimeType: "image/svg+xml"}
+
+	result := buildSrcset(image, settings, "https://img.example.com", "50vw", "")
+
+	assert.Empty(t, result.Candidates)
+	assert.Empty(t, result.Sources)
+	assert.Equal(t, result.Img, result.Picture)
+	assert.Equal(t, `<img src="https://img.example.com/images/logo.svg" alt="" loading="lazy" decoding="async" />`, result.Img)
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	config "github.com/anoixa/image-bed/config/db"
 	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/internal/worker"
 	"gorm.io/gorm"
 )
 
+// thumbnailPollInterval 同步等待按需生成的缩略图时查询变体状态的间隔
+var thumbnailPollInterval = 100 * time.Millisecond
+
 // ThumbnailResult 缩略图结果
 type ThumbnailResult struct {
 	Format      string
@@ -25,18 +31,20 @@ type ThumbnailResult struct {
 // ThumbnailService 缩略图服务
 type ThumbnailService struct {
 	variantRepo *images.VariantRepository
+	converter   *Converter
 }
 
-// NewThumbnailService 创建缩略图服务
-func NewThumbnailService(variantRepo *images.VariantRepository) *ThumbnailService {
+// NewThumbnailService 创建缩略图服务，converter 为 nil 时不按需生成
+func NewThumbnailService(variantRepo *images.VariantRepository, converter *Converter) *ThumbnailService {
 	return &ThumbnailService{
 		variantRepo: variantRepo,
+		converter:   converter,
 	}
 }
 
 // GetThumbnail 获取缩略图信息
-func (s *ThumbnailService) GetThumbnail(ctx context.Context, image *models.Image, width int) (*ThumbnailResult, error) {
-	format := formatThumbnailSize(width)
+func (s *ThumbnailService) GetThumbnail(ctx context.Context, image *models.Image, size models.ThumbnailSize) (*ThumbnailResult, error) {
+	format := size.Format()
 
 	variant, err := s.variantRepo.WithContext(ctx).GetVariantByImageIDAndFormat(image.ID, format)
 	if err != nil {
@@ -50,6 +58,11 @@ func (s *ThumbnailService) GetThumbnail(ctx context.Context, image *models.Image
 		return nil, nil
 	}
 
+	recordVariantAccess(variant.ID)
+	return thumbnailResultFromVariant(format, variant), nil
+}
+
+func thumbnailResultFromVariant(format string, variant *models.ImageVariant) *ThumbnailResult {
 	return &ThumbnailResult{
 		Format:      format,
 		Identifier:  variant.Identifier,
@@ -59,12 +72,13 @@ func (s *ThumbnailService) GetThumbnail(ctx context.Context, image *models.Image
 		Height:      variant.Height,
 		FileSize:    variant.FileSize,
 		MIMEType:    "image/webp",
-	}, nil
+	}
 }
 
 // EnsureThumbnail 确保缩略图存在
-func (s *ThumbnailService) EnsureThumbnail(ctx context.Context, image *models.Image, width int) (*ThumbnailResult, bool, error) {
-	result, err := s.GetThumbnail(ctx, image, width)
+// 缺失时触发生成；按需生成模式下在配置的时间预算内同步等待，超时后由调用方返回原图
+func (s *ThumbnailService) EnsureThumbnail(ctx context.Context, image *models.Image, size models.ThumbnailSize) (*ThumbnailResult, bool, error) {
+	result, err := s.GetThumbnail(ctx, image, size)
 	if err != nil {
 		return nil, false, err
 	}
@@ -73,7 +87,72 @@ func (s *ThumbnailService) EnsureThumbnail(ctx context.Context, image *models.Im
 		return result, true, nil
 	}
 
-	return nil, false, nil
+	if s.converter == nil {
+		return nil, false, nil
+	}
+	settings, err := s.converter.configManager.GetImageProcessingSettings(ctx)
+	if err != nil || !thumbnailSizeConfigured(settings, size) {
+		return nil, false, nil
+	}
+	// 非按需模式下只补齐尚未处理或变体已被清理的图片
+	if !settings.LazyVariants && image.VariantStatus != models.ImageVariantStatusNone {
+		return nil, false, nil
+	}
+
+	// 变体已在处理中或处于重试等待期时不会重复提交
+	if err := s.converter.triggerConversion(image, false, "", worker.PriorityInteractive, nil); err != nil {
+		return nil, false, nil
+	}
+	if !settings.LazyVariants || settings.LazyWaitMs <= 0 {
+		return nil, false, nil
+	}
+
+	result, err = s.waitForThumbnail(ctx, image, size, time.Duration(settings.LazyWaitMs)*time.Millisecond)
+	if err != nil || result == nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// waitForThumbnail 轮询等待缩略图生成完成，超时、生成失败或请求取消时返回 nil
+func (s *ThumbnailService) waitForThumbnail(ctx context.Context, image *models.Image, size models.ThumbnailSize, budget time.Duration) (*ThumbnailResult, error) {
+	deadline := time.NewTimer(budget)
+	defer deadline.Stop()
+	ticker := time.NewTicker(thumbnailPollInterval)
+	defer ticker.Stop()
+
+	format := size.Format()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-deadline.C:
+			return nil, nil
+		case <-ticker.C:
+		}
+
+		variant, err := s.variantRepo.WithContext(ctx).GetVariantByImageIDAndFormat(image.ID, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thumbnail variant: %w", err)
+		}
+		switch variant.Status {
+		case models.VariantStatusCompleted:
+			recordVariantAccess(variant.ID)
+			return thumbnailResultFromVariant(format, variant), nil
+		case models.VariantStatusFailed:
+			return nil, nil
+		}
+	}
+}
+
+// thumbnailSizeConfigured 只有配置中的尺寸才会被流水线生成
+func thumbnailSizeConfigured(settings *config.ImageProcessingSettings, size models.ThumbnailSize) bool {
+	for _, configured := range settings.ThumbnailSizes {
+		if configured.Format() == size.Format() {
+			return true
+		}
+	}
+	return false
 }
 
 // GetWebPVariant 获取 WebP 格式变体（用于缩略图降级）
@@ -90,6 +169,7 @@ func (s *ThumbnailService) GetWebPVariant(ctx context.Context, image *models.Ima
 		return nil, false, nil
 	}
 
+	recordVariantAccess(variant.ID)
 	return &ThumbnailResult{
 		Format:      "webp",
 		Identifier:  variant.Identifier,
@@ -101,8 +181,3 @@ func (s *ThumbnailService) GetWebPVariant(ctx context.Context, image *models.Ima
 		MIMEType:    "image/webp",
 	}, true, nil
 }
-
-// formatThumbnailSize 生成缩略图格式标识
-func formatThumbnailSize(width int) string {
-	return fmt.Sprintf("thumbnail_%d", width)
-}
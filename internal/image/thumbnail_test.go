@@ -0,0 +1,85 @@
+package image
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	configdb "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	repoimages "github.com/anoixa/image-bed/database/repo/images"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForThumbnailReturnsOnceVariantCompletes(t *testing.T) {
+	orig := thumbnailPollInterval
+	thumbnailPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { thumbnailPollInterval = orig })
+
+	db := setupConverterTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	variantRepo := repoimages.NewVariantRepository(db)
+	service := NewThumbnailService(variantRepo, nil)
+	image := &models.Image{ID: 7}
+	size := models.ThumbnailSize{Width: 600}
+
+	variant, err := variantRepo.UpsertPending(image.ID, size.Format())
+	require.NoError(t, err)
+
+	result, err := service.waitForThumbnail(context.Background(), image, size, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Nil(t, result, "pending variant should time out")
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_, _ = variantRepo.UpdateStatusCAS(variant.ID, models.VariantStatusPending, models.VariantStatusProcessing, "")
+		_ = variantRepo.UpdateCompleted(variant.ID, "thumb.webp", "thumbnails/thumb.webp", 2048, "hash", 600, 400)
+	}()
+	result, err = service.waitForThumbnail(context.Background(), image, size, 2*time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "thumbnails/thumb.webp", result.StoragePath)
+	assert.Equal(t, "image/webp", result.MIMEType)
+}
+
+func TestWaitForThumbnailStopsOnFailure(t *testing.T) {
+	orig := thumbnailPollInterval
+	thumbnailPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { thumbnailPollInterval = orig })
+
+	db := setupConverterTestDB(t)
+	variantRepo := repoimages.NewVariantRepository(db)
+	service := NewThumbnailService(variantRepo, nil)
+	image := &models.Image{ID: 8}
+	size := models.ThumbnailSize{Width: 300}
+
+	require.NoError(t, db.Create(&models.ImageVariant{ImageID: image.ID, Format: size.Format(), Status: models.VariantStatusFailed}).Error)
+
+	start := time.Now()
+	result, err := service.waitForThumbnail(context.Background(), image, size, 5*time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestEnsureThumbnailWithoutConverterOnlyLooksUp(t *testing.T) {
+	db := setupConverterTestDB(t)
+	service := NewThumbnailService(repoimages.NewVariantRepository(db), nil)
+
+	result, exists, err := service.EnsureThumbnail(context.Background(), &models.Image{ID: 9}, models.ThumbnailSize{Width: 600})
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Nil(t, result)
+}
+
+func TestThumbnailSizeConfigured(t *testing.T) {
+	settings := &configdb.ImageProcessingSettings{ThumbnailSizes: []models.ThumbnailSize{{Width: 300}, {Width: 400, Height: 300}}}
+
+	assert.True(t, thumbnailSizeConfigured(settings, models.ThumbnailSize{Width: 300}))
+	assert.True(t, thumbnailSizeConfigured(settings, models.ThumbnailSize{Width: 400, Height: 300}))
+	assert.False(t, thumbnailSizeConfigured(settings, models.ThumbnailSize{Width: 600}))
+}
@@ -0,0 +1,247 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
+)
+
+var trashLog = utils.ForModule("Trash")
+
+// trashPurgeInterval 回收站过期清理的检查间隔
+var trashPurgeInterval = time.Hour
+
+// trashPurgeBatchSize 每批彻底删除的图片数
+const trashPurgeBatchSize = 200
+
+// trashPurgeMaxBatches 单次检查最多处理的批数，剩余的留到下一轮
+const trashPurgeMaxBatches = 50
+
+// RestoreResult 回收站恢复结果
+type RestoreResult struct {
+	Restored    []*models.Image
+	Failed      []string // 不在回收站中或原始文件已丢失的图片
+	FileMissing []string // 原始文件已丢失，只能彻底删除
+}
+
+// ListTrash 分页获取用户回收站中的图片
+func (s *DeleteService) ListTrash(ctx context.Context, userID uint, page, limit int) ([]*models.Image, int64, error) {
+	return s.repo.WithContext(ctx).ListTrashedImages(userID, page, limit)
+}
+
+// RestoreFromTrash 从回收站恢复图片，移入回收站前所在的相册一并恢复
+func (s *DeleteService) RestoreFromTrash(ctx context.Context, identifiers []string, userID uint) (*RestoreResult, error) {
+	repo := s.repo.WithContext(ctx)
+	trashed, err := repo.GetTrashedImagesByIdentifiers(identifiers, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed images: %w", err)
+	}
+
+	result := &RestoreResult{Restored: []*models.Image{}}
+	found := make(map[string]struct{}, len(trashed))
+	ids := make([]uint, 0, len(trashed))
+	for _, img := range trashed {
+		found[img.Identifier] = struct{}{}
+		// 早于回收站功能删除的图片可能已没有原始文件
+		if !s.originalExists(ctx, img) {
+			result.FileMissing = append(result.FileMissing, img.Identifier)
+			result.Failed = append(result.Failed, img.Identifier)
+			continue
+		}
+		ids = append(ids, img.ID)
+		result.Restored = append(result.Restored, img)
+	}
+	for _, identifier := range identifiers {
+		if _, ok := found[identifier]; !ok {
+			result.Failed = append(result.Failed, identifier)
+		}
+	}
+
+	if _, err := repo.RestoreTrashedImages(ids, userID); err != nil {
+		return nil, fmt.Errorf("failed to restore images: %w", err)
+	}
+	for _, img := range result.Restored {
+		img.DeletedAt = gorm.DeletedAt{}
+		s.clearImageCache(ctx, img.Identifier)
+	}
+	return result, nil
+}
+
+// EmptyTrash 彻底删除回收站中的图片，identifiers 为空时清空整个回收站，返回删除的数量
+func (s *DeleteService) EmptyTrash(ctx context.Context, identifiers []string, userID uint) (int, error) {
+	repo := s.repo.WithContext(ctx)
+	if len(identifiers) > 0 {
+		trashed, err := repo.GetTrashedImagesByIdentifiers(identifiers, userID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get trashed images: %w", err)
+		}
+		return s.purgeImages(ctx, trashed)
+	}
+
+	total := 0
+	for {
+		trashed, _, err := repo.ListTrashedImages(userID, 1, trashPurgeBatchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to list trashed images: %w", err)
+		}
+		if len(trashed) == 0 {
+			return total, nil
+		}
+		purged, err := s.purgeImages(ctx, trashed)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+		if purged == 0 {
+			return total, nil
+		}
+	}
+}
+
+// PurgeExpiredTrash 彻底删除一批在 cutoff 之前移入回收站的图片，返回删除数和本批扫描数
+func (s *DeleteService) PurgeExpiredTrash(ctx context.Context, cutoff time.Time, limit int) (purged, scanned int, err error) {
+	expired, err := s.repo.WithContext(ctx).ListExpiredTrashedImages(cutoff, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list expired trash: %w", err)
+	}
+	purged, err = s.purgeImages(ctx, expired)
+	return purged, len(expired), err
+}
+
+// purgeImages 先删除数据库记录，再清理变体以及不再被任何图片（包括回收站中的）或历史版本引用的原始文件
+func (s *DeleteService) purgeImages(ctx context.Context, trashed []*models.Image) (int, error) {
+	if len(trashed) == 0 {
+		return 0, nil
+	}
+
+	repo := s.repo.WithContext(ctx)
+	ids := make([]uint, len(trashed))
+	for i, img := range trashed {
+		ids[i] = img.ID
+	}
+	versions, err := repo.ListVersionsByImageIDs(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list image versions: %w", err)
+	}
+	purgedIDs, err := repo.PurgeTrashedImages(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge images: %w", err)
+	}
+
+	purged := make(map[uint]struct{}, len(purgedIDs))
+	for _, id := range purgedIDs {
+		purged[id] = struct{}{}
+	}
+	for _, img := range trashed {
+		if _, ok := purged[img.ID]; !ok {
+			continue
+		}
+		s.deleteVariantsForImage(ctx, img)
+		s.deleteObjectIfUnreferenced(ctx, img.StorageConfigID, img.StoragePath)
+		s.clearImageCache(ctx, img.Identifier)
+	}
+	for _, version := range versions {
+		if _, ok := purged[version.ImageID]; ok {
+			s.deleteObjectIfUnreferenced(ctx, version.StorageConfigID, version.StoragePath)
+		}
+	}
+	return len(purgedIDs), nil
+}
+
+// deleteObjectIfUnreferenced 秒传的图片和历史版本可能共享原始文件，没有任何记录引用时才删除
+func (s *DeleteService) deleteObjectIfUnreferenced(ctx context.Context, storageConfigID uint, storagePath string) {
+	if storagePath == "" {
+		return
+	}
+
+	repo := s.repo.WithContext(ctx)
+	var refCount int64
+	for _, count := range []func(string) (int64, error){
+		repo.CountImagesByStoragePath,
+		repo.CountTrashedImagesByStoragePath,
+		repo.CountVersionsByStoragePath,
+	} {
+		n, err := count(storagePath)
+		if err != nil {
+			deleteLog.Errorf("Failed to count references for storage path %s: %v", storagePath, err)
+			return
+		}
+		refCount += n
+		if refCount > 0 {
+			deleteLog.Debugf("Skipping physical file deletion for %s, still referenced", storagePath)
+			return
+		}
+	}
+
+	provider, err := getStorageProviderByID(storageConfigID)
+	if err != nil {
+		deleteLog.Errorf("Failed to get storage provider %d for %s: %v", storageConfigID, storagePath, err)
+		return
+	}
+	if err := provider.DeleteWithContext(ctx, storagePath); err != nil {
+		deleteLog.Errorf("Failed to delete original image file %s: %v", storagePath, err)
+		enqueueObjectDeletion(ctx, storageConfigID, storagePath)
+	}
+}
+
+// originalExists 检查原始文件是否仍在存储中，无法确认时视为存在
+func (s *DeleteService) originalExists(ctx context.Context, img *models.Image) bool {
+	if img.StoragePath == "" {
+		return false
+	}
+	provider, err := getStorageProviderByID(img.StorageConfigID)
+	if err != nil {
+		return true
+	}
+	exists, err := provider.Exists(ctx, img.StoragePath)
+	if err != nil {
+		trashLog.Warnf("Failed to check original file of %s: %v", utils.SanitizeLogMessage(img.Identifier), err)
+		return true
+	}
+	return exists
+}
+
+// StartTrashPurger 定期彻底删除在回收站中超过保留天数的图片，保留天数为 0 时不启动
+func StartTrashPurger(ctx context.Context, svc *DeleteService, retentionDays int) {
+	if retentionDays <= 0 {
+		trashLog.Infof("Trash retention is disabled, trashed images are kept until the trash is emptied")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				svc.purgeExpiredOnce(ctx, retentionDays)
+			}
+		}
+	}()
+}
+
+func (s *DeleteService) purgeExpiredOnce(ctx context.Context, retentionDays int) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	total := 0
+	for range trashPurgeMaxBatches {
+		purged, scanned, err := s.PurgeExpiredTrash(ctx, cutoff, trashPurgeBatchSize)
+		total += purged
+		if err != nil {
+			trashLog.Warnf("Failed to purge expired trash: %v", err)
+			break
+		}
+		if scanned < trashPurgeBatchSize || purged == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		trashLog.Infof("Purged %d images that stayed in the trash for more than %d days", total, retentionDays)
+	}
+}
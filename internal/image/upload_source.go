@@ -44,8 +44,9 @@ func (h *tempFileHandle) cleanupIfRequestOwned() {
 type UploadSource struct {
 	FileName        string
 	FileSize        int64
-	TempFilePath    string // optional: local temp file path (ownership transferred, caller must NOT clean up)
-	PrecomputedHash string // optional: SHA256 hash computed during initial write
+	TempFilePath    string      // optional: local temp file path (ownership transferred, caller must NOT clean up)
+	PrecomputedHash string      // optional: SHA256 hash computed during initial write
+	Expiry          ImageExpiry // optional: expiry applied to newly created image records
 	tempFileHandle  *tempFileHandle
 	Open            func() (io.ReadSeekCloser, error)
 }
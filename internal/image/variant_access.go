@@ -0,0 +1,67 @@
+package image
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/batch"
+)
+
+var variantAccessLog = utils.ForModule("VariantAccess")
+
+// variantAccessFlushInterval 访问记录批量写库的间隔
+var variantAccessFlushInterval = time.Minute
+
+// VariantAccessTracker 在内存中汇总变体访问，定期批量更新 last_accessed_at
+type VariantAccessTracker struct {
+	batch *batch.Batcher[uint, uint]
+}
+
+// activeVariantAccessTracker 未启动时不记录访问
+var activeVariantAccessTracker atomic.Pointer[VariantAccessTracker]
+
+// NewVariantAccessTracker 创建访问记录器
+func NewVariantAccessTracker(repo *images.VariantRepository) *VariantAccessTracker {
+	return &VariantAccessTracker{
+		batch: batch.New[uint, uint](nil, func(ctx context.Context, ids []uint) error {
+			return repo.WithContext(ctx).TouchAccessed(ids, time.Now())
+		}),
+	}
+}
+
+// StartVariantAccessTracker 启动全局访问记录，返回的函数停止记录并写入剩余数据
+func StartVariantAccessTracker(repo *images.VariantRepository) func() {
+	tracker := NewVariantAccessTracker(repo)
+	activeVariantAccessTracker.Store(tracker)
+
+	stop := tracker.batch.Start(variantAccessFlushInterval, func(err error) {
+		variantAccessLog.Warnf("Failed to record variant access: %v", err)
+	})
+	return func() {
+		activeVariantAccessTracker.CompareAndSwap(tracker, nil)
+		stop()
+	}
+}
+
+// recordVariantAccess 记录一次变体访问
+func recordVariantAccess(variantID uint) {
+	if tracker := activeVariantAccessTracker.Load(); tracker != nil {
+		tracker.Record(variantID)
+	}
+}
+
+// Record 记录变体被访问
+func (t *VariantAccessTracker) Record(variantID uint) {
+	if variantID == 0 {
+		return
+	}
+	t.batch.Add(variantID, variantID)
+}
+
+// Flush 把已记录的访问写入数据库，失败的记录留到下一轮
+func (t *VariantAccessTracker) Flush(ctx context.Context) error {
+	return t.batch.Flush(ctx)
+}
@@ -0,0 +1,57 @@
+package image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anoixa/image-bed/database/models"
+	repoimages "github.com/anoixa/image-bed/database/repo/images"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantAccessTrackerFlushesInBatches(t *testing.T) {
+	db := setupConverterTestDB(t)
+	variantRepo := repoimages.NewVariantRepository(db)
+
+	var ids []uint
+	for _, format := range []string{models.FormatWebP, models.FormatAVIF} {
+		v := &models.ImageVariant{ImageID: 1, Format: format, Status: models.VariantStatusCompleted}
+		require.NoError(t, db.Create(v).Error)
+		ids = append(ids, v.ID)
+	}
+
+	tracker := NewVariantAccessTracker(variantRepo)
+	tracker.Record(ids[0])
+	tracker.Record(ids[0])
+	tracker.Record(0)
+	assert.Equal(t, 1, tracker.batch.Len())
+
+	require.NoError(t, tracker.Flush(context.Background()))
+	assert.Zero(t, tracker.batch.Len())
+
+	accessed, err := variantRepo.GetByID(ids[0])
+	require.NoError(t, err)
+	assert.NotNil(t, accessed.LastAccessedAt)
+	untouched, err := variantRepo.GetByID(ids[1])
+	require.NoError(t, err)
+	assert.Nil(t, untouched.LastAccessedAt)
+}
+
+func TestStartVariantAccessTrackerFlushesOnStop(t *testing.T) {
+	db := setupConverterTestDB(t)
+	variantRepo := repoimages.NewVariantRepository(db)
+	v := &models.ImageVariant{ImageID: 1, Format: models.FormatWebP, Status: models.VariantStatusCompleted}
+	require.NoError(t, db.Create(v).Error)
+
+	recordVariantAccess(v.ID) // 未启动时忽略
+
+	stop := StartVariantAccessTracker(variantRepo)
+	recordVariantAccess(v.ID)
+	stop()
+	assert.Nil(t, activeVariantAccessTracker.Load())
+
+	accessed, err := variantRepo.GetByID(v.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, accessed.LastAccessedAt)
+}
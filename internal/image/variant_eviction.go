@@ -0,0 +1,160 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/utils"
+)
+
+var evictionLog = utils.ForModule("VariantEviction")
+
+// variantEvictionInterval 冷变体清理的检查间隔
+var variantEvictionInterval = time.Hour
+
+// variantEvictionBatchSize 每批清理的变体数
+const variantEvictionBatchSize = 200
+
+// variantEvictionMaxBatches 单次检查最多处理的批数，剩余的留到下一轮
+const variantEvictionMaxBatches = 50
+
+// StartVariantEvictor 定期删除超过配置天数未被访问的变体，配置为 0 时跳过
+func StartVariantEvictor(ctx context.Context, converter *Converter) {
+	go func() {
+		ticker := time.NewTicker(variantEvictionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				converter.evictOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (c *Converter) evictOnce(ctx context.Context) {
+	settings, err := c.configManager.GetImageProcessingSettings(ctx)
+	if err != nil {
+		evictionLog.Warnf("Failed to load image processing settings: %v", err)
+		return
+	}
+	if settings.VariantEvictionDays <= 0 {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -settings.VariantEvictionDays)
+	total := 0
+	for range variantEvictionMaxBatches {
+		evicted, scanned, err := c.EvictColdVariants(ctx, cutoff, variantEvictionBatchSize)
+		total += evicted
+		if err != nil {
+			evictionLog.Warnf("Failed to evict cold variants: %v", err)
+			break
+		}
+		if scanned < variantEvictionBatchSize || evicted == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		evictionLog.Infof("Evicted %d variants not accessed for %d days", total, settings.VariantEvictionDays)
+	}
+}
+
+// EvictColdVariants 删除一批在 cutoff 之后未被访问的已完成变体，并把对应图片重置为未处理，
+// 之后的访问会重新生成。返回删除数和本批扫描数
+func (c *Converter) EvictColdVariants(ctx context.Context, cutoff time.Time, limit int) (evicted, scanned int, err error) {
+	variantRepo := c.variantRepo.WithContext(ctx)
+	imageRepo := c.imageRepo.WithContext(ctx)
+
+	variants, err := variantRepo.ListColdVariants(cutoff, limit, utils.DeliveryVariantMimeTypes())
+	if err != nil {
+		return 0, 0, fmt.Errorf("list cold variants: %w", err)
+	}
+
+	imagesByID := make(map[uint]*models.Image)
+	var affected []*models.Image
+	for i := range variants {
+		variant := &variants[i]
+		image, ok := imagesByID[variant.ImageID]
+		if !ok {
+			if image, err = imageRepo.GetImageByID(variant.ImageID); err != nil {
+				image = nil
+			}
+			imagesByID[variant.ImageID] = image
+		}
+		if image == nil {
+			// 无法确定存储位置时保留变体，避免删除记录后遗留存储对象
+			continue
+		}
+
+		purged, err := c.evictVariant(ctx, image, variant, cutoff)
+		if err != nil {
+			evictionLog.Warnf("Failed to evict variant %d: %v", variant.ID, err)
+		}
+		if !purged {
+			continue
+		}
+		evicted++
+		if !slices.Contains(affected, image) {
+			affected = append(affected, image)
+		}
+	}
+
+	ids := make([]uint, 0, len(affected))
+	for _, image := range affected {
+		ids = append(ids, image.ID)
+	}
+
+	if _, err := imageRepo.ResetEvictedVariantStatus(ids); err != nil {
+		return evicted, len(variants), fmt.Errorf("reset image variant status: %w", err)
+	}
+	if c.cacheHelper != nil {
+		for _, image := range affected {
+			_ = c.cacheHelper.DeleteCachedImage(ctx, image.Identifier)
+			_ = c.cacheHelper.DeleteCachedImageVariants(ctx, image.ID)
+		}
+	}
+	return evicted, len(variants), nil
+}
+
+// evictVariant 先删除记录再删除存储对象，期间被访问或重新处理的变体保留；
+// 去重图片共享变体文件，仍有其他记录引用时保留对象
+func (c *Converter) evictVariant(ctx context.Context, image *models.Image, variant *models.ImageVariant, cutoff time.Time) (bool, error) {
+	variantRepo := c.variantRepo.WithContext(ctx)
+
+	purged, err := variantRepo.PurgeColdVariant(variant.ID, cutoff)
+	if err != nil || !purged {
+		return false, err
+	}
+
+	if c.cacheHelper != nil && variant.Identifier != "" {
+		_ = c.cacheHelper.DeleteCachedImageData(ctx, variant.Identifier)
+	}
+	if variant.StoragePath == "" {
+		return true, nil
+	}
+
+	refs, err := variantRepo.CountVariantsByStoragePath(variant.StoragePath)
+	if err != nil {
+		return true, fmt.Errorf("count variant references: %w", err)
+	}
+	if refs > 0 {
+		return true, nil
+	}
+
+	provider, err := getStorageProviderByID(image.StorageConfigID)
+	if err != nil {
+		return true, err
+	}
+	if err := provider.DeleteWithContext(ctx, variant.StoragePath); err != nil {
+		evictionLog.Warnf("Failed to delete variant object %s, queued for retry: %v", variant.StoragePath, err)
+		enqueueObjectDeletion(ctx, image.StorageConfigID, variant.StoragePath)
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package image
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	repoimages "github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvictColdVariantsRemovesObjectsAndResetsImage(t *testing.T) {
+	const storageID = 9370
+	require.NoError(t, storage.AddOrUpdateProvider(storage.StorageConfig{
+		ID:        storageID,
+		Name:      "eviction-test",
+		Type:      "local",
+		LocalPath: filepath.Join(t.TempDir(), "store"),
+	}))
+	t.Cleanup(func() { _ = storage.RemoveProvider(storageID) })
+	provider, err := storage.GetByID(storageID)
+	require.NoError(t, err)
+
+	db := setupConverterTestDB(t)
+	imageRepo := repoimages.NewRepository(db)
+	variantRepo := repoimages.NewVariantRepository(db)
+	converter := &Converter{imageRepo: imageRepo, variantRepo: variantRepo}
+	ctx := context.Background()
+
+	newImage := func(identifier string, status models.ImageVariantStatus) *models.Image {
+		img := &models.Image{
+			Identifier:      identifier,
+			FileHash:        "hash-" + identifier,
+			StoragePath:     "original/" + identifier + ".jpg",
+			MimeType:        "image/jpeg",
+			StorageConfigID: storageID,
+			VariantStatus:   status,
+		}
+		require.NoError(t, imageRepo.SaveImage(img))
+		return img
+	}
+	old := time.Now().AddDate(0, 0, -90)
+	newVariant := func(img *models.Image, format, path string) *models.ImageVariant {
+		v := &models.ImageVariant{ImageID: img.ID, Format: format, Status: models.VariantStatusCompleted, StoragePath: path}
+		require.NoError(t, db.Create(v).Error)
+		require.NoError(t, db.Model(v).UpdateColumn("updated_at", old).Error)
+		require.NoError(t, provider.SaveWithContext(ctx, path, strings.NewReader("variant")))
+		return v
+	}
+
+	cold := newImage("cold", models.ImageVariantStatusCompleted)
+	coldWebP := newVariant(cold, models.FormatWebP, "converted/cold.webp")
+	hotThumb := newVariant(cold, "thumbnail_600", "thumbnails/cold_600.webp")
+	require.NoError(t, variantRepo.TouchAccessed([]uint{hotThumb.ID}, time.Now()))
+
+	// 去重图片共享变体文件，另一条记录仍在使用时不能删除对象
+	shared := newImage("shared", models.ImageVariantStatusCompleted)
+	sharedWebP := newVariant(shared, models.FormatWebP, "converted/shared.webp")
+	twin := newImage("twin", models.ImageVariantStatusCompleted)
+	twinWebP := &models.ImageVariant{ImageID: twin.ID, Format: models.FormatWebP, Status: models.VariantStatusCompleted, StoragePath: sharedWebP.StoragePath}
+	require.NoError(t, db.Create(twinWebP).Error)
+
+	// 回收站中的图片可能被恢复，变体和对象保留到彻底删除
+	trashed := newImage("trashed", models.ImageVariantStatusCompleted)
+	trashedWebP := newVariant(trashed, models.FormatWebP, "converted/trashed.webp")
+	require.NoError(t, db.Delete(trashed).Error)
+
+	// 浏览器无法显示 HEIC 原图，交付用 WebP 变体不参与清理
+	heic := newImage("heic", models.ImageVariantStatusCompleted)
+	require.NoError(t, db.Model(heic).Update("mime_type", "image/heic").Error)
+	heicWebP := newVariant(heic, models.FormatWebP, "converted/heic.webp")
+
+	evicted, scanned, err := converter.EvictColdVariants(ctx, time.Now().AddDate(0, 0, -30), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, scanned)
+	assert.Equal(t, 2, evicted)
+
+	exists, err := provider.Exists(ctx, coldWebP.StoragePath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = provider.Exists(ctx, hotThumb.StoragePath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = provider.Exists(ctx, sharedWebP.StoragePath)
+	require.NoError(t, err)
+	assert.True(t, exists, "object still referenced by the twin image")
+	exists, err = provider.Exists(ctx, trashedWebP.StoragePath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, err = variantRepo.GetByID(trashedWebP.ID)
+	assert.NoError(t, err)
+	_, err = variantRepo.GetByID(heicWebP.ID)
+	assert.NoError(t, err)
+
+	_, err = variantRepo.GetByID(coldWebP.ID)
+	assert.Error(t, err)
+	_, err = variantRepo.GetByID(hotThumb.ID)
+	assert.NoError(t, err)
+
+	for _, img := range []*models.Image{cold, shared} {
+		updated, err := imageRepo.GetImageByID(img.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ImageVariantStatusNone, updated.VariantStatus, img.Identifier)
+	}
+	untouched, err := imageRepo.GetImageByID(twin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImageVariantStatusCompleted, untouched.VariantStatus)
+}
@@ -41,18 +41,11 @@ func NewVariantService(repo *images.VariantRepository, cm *config.Manager, cache
 	}
 }
 
-// SelectBestVariant 选择最优格式变体
-func (s *VariantService) SelectBestVariant(ctx context.Context, image *models.Image, acceptHeader string) (*VariantResult, error) {
-	// GIF 和 WebP 格式直接返回原图，不进行格式协商
-	if image.MimeType == "image/gif" || image.MimeType == "image/webp" {
-		return &VariantResult{
-			Format:      format.FormatOriginal,
-			IsOriginal:  true,
-			Image:       image,
-			MIMEType:    image.MimeType,
-			Identifier:  image.Identifier,
-			StoragePath: image.StoragePath,
-		}, nil
+// SelectBestVariant 选择最优格式变体，客户端提供显示宽度或省流提示时可改用更小的缩略图
+func (s *VariantService) SelectBestVariant(ctx context.Context, image *models.Image, acceptHeader string, hints ClientHints) (*VariantResult, error) {
+	// WebP 和 SVG 直接返回原图，不进行格式协商
+	if image.MimeType == "image/webp" || utils.IsVectorImage(image.MimeType) {
+		return originalVariantResult(image), nil
 	}
 
 	settings, err := s.configManager.GetImageProcessingSettings(ctx)
@@ -60,6 +53,11 @@ func (s *VariantService) SelectBestVariant(ctx context.Context, image *models.Im
 		return nil, err
 	}
 
+	// 未开启动图保留时 GIF 没有可用变体，直接返回原图
+	if image.MimeType == "image/gif" && !settings.PreserveAnimation {
+		return originalVariantResult(image), nil
+	}
+
 	// variantNegotiationLog.Debugf("image=%s, variantStatus=%d, acceptHeader=%s", image.Identifier, uint(image.VariantStatus), acceptHeader)
 	// variantNegotiationLog.Debugf("enabledFormats=%v", settings.ConversionEnabledFormats)
 
@@ -72,12 +70,24 @@ func (s *VariantService) SelectBestVariant(ctx context.Context, image *models.Im
 		return s.handleOriginalWithConversion(image, shouldTriggerVariantConversion(image, settings))
 	case models.ImageVariantStatusThumbnailCompleted, models.ImageVariantStatusCompleted:
 
-		return s.handleCompletedVariants(ctx, image, acceptHeader, settings)
+		return s.handleCompletedVariants(ctx, image, acceptHeader, hints, settings)
 	default:
 		return s.handleOriginalWithConversion(image, false)
 	}
 }
 
+// originalVariantResult 返回不参与格式协商的原图结果
+func originalVariantResult(image *models.Image) *VariantResult {
+	return &VariantResult{
+		Format:      format.FormatOriginal,
+		IsOriginal:  true,
+		Image:       image,
+		MIMEType:    image.MimeType,
+		Identifier:  image.Identifier,
+		StoragePath: image.StoragePath,
+	}
+}
+
 // handleOriginalWithConversion 返回原图。
 func (s *VariantService) handleOriginalWithConversion(image *models.Image, shouldTrigger bool) (*VariantResult, error) {
 	result := &VariantResult{
@@ -94,11 +104,11 @@ func (s *VariantService) handleOriginalWithConversion(image *models.Image, shoul
 }
 
 // handleCompletedVariants 处理已完成变体的情况
-func (s *VariantService) handleCompletedVariants(ctx context.Context, image *models.Image, acceptHeader string, settings *config.ImageProcessingSettings) (*VariantResult, error) {
+func (s *VariantService) handleCompletedVariants(ctx context.Context, image *models.Image, acceptHeader string, hints ClientHints, settings *config.ImageProcessingSettings) (*VariantResult, error) {
 	var variants []models.ImageVariant
 	if s.cacheHelper != nil {
 		if err := s.cacheHelper.GetCachedImageVariants(ctx, image.ID, &variants); err == nil {
-			return s.selectFromVariants(image, acceptHeader, settings, variants), nil
+			return s.selectFromVariants(image, acceptHeader, hints, settings, variants), nil
 		}
 	}
 
@@ -112,10 +122,10 @@ func (s *VariantService) handleCompletedVariants(ctx context.Context, image *mod
 		_ = s.cacheHelper.CacheImageVariants(ctx, image.ID, variants)
 	}
 
-	return s.selectFromVariants(image, acceptHeader, settings, variants), nil
+	return s.selectFromVariants(image, acceptHeader, hints, settings, variants), nil
 }
 
-func (s *VariantService) selectFromVariants(image *models.Image, acceptHeader string, settings *config.ImageProcessingSettings, variants []models.ImageVariant) *VariantResult {
+func (s *VariantService) selectFromVariants(image *models.Image, acceptHeader string, hints ClientHints, settings *config.ImageProcessingSettings, variants []models.ImageVariant) *VariantResult {
 
 	available := make(map[format.FormatType]bool)
 	variantMap := make(map[format.FormatType]*models.ImageVariant)
@@ -135,8 +145,27 @@ func (s *VariantService) selectFromVariants(image *models.Image, acceptHeader st
 	negotiator := format.NewNegotiator(settings.ConversionEnabledFormats)
 	selectedFormat := negotiator.Negotiate(acceptHeader, available)
 
+	// 浏览器无法显示原图时，即使未启用 WebP 转换也改用交付变体
+	if selectedFormat == format.FormatOriginal && utils.RequiresDeliveryVariant(image.MimeType) && available[format.FormatWebP] {
+		selectedFormat = format.FormatWebP
+	}
+
 	variantNegotiationLog.Debugf("selectedFormat=%s", selectedFormat)
 
+	// 显示宽度明显小于全尺寸时改用缩略图（WebP）
+	if thumb := selectThumbnailForHints(hints, acceptHeader, settings, variants, image); thumb != nil {
+		variantNegotiationLog.Debugf("clientHints=%+v, thumbnail=%s", hints, thumb.Format)
+		recordVariantAccess(thumb.ID)
+		return &VariantResult{
+			Format:      format.FormatWebP,
+			Image:       image,
+			Variant:     thumb,
+			MIMEType:    format.FormatRegistry[format.FormatWebP].MIMEType,
+			Identifier:  thumb.Identifier,
+			StoragePath: thumb.StoragePath,
+		}
+	}
+
 	result := &VariantResult{
 		Format: selectedFormat,
 		Image:  image,
@@ -155,6 +184,7 @@ func (s *VariantService) selectFromVariants(image *models.Image, acceptHeader st
 		if variant != nil {
 			result.Identifier = variant.Identifier
 			result.StoragePath = variant.StoragePath
+			recordVariantAccess(variant.ID)
 		}
 	}
 
@@ -86,7 +86,7 @@ func TestHandleCompletedVariantsUsesCachedVariants(t *testing.T) {
 	err = cacheHelper.CacheImageVariants(t.Context(), image.ID, variants)
 	require.NoError(t, err)
 
-	result, err := service.handleCompletedVariants(t.Context(), image, "image/avif,image/webp,image/*,*/*", settings)
+	result, err := service.handleCompletedVariants(t.Context(), image, "image/avif,image/webp,image/*,*/*", ClientHints{}, settings)
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.False(t, result.IsOriginal)
@@ -94,3 +94,92 @@ func TestHandleCompletedVariantsUsesCachedVariants(t *testing.T) {
 	require.NotNil(t, result.Variant)
 	assert.Equal(t, variants[0].Identifier, result.Variant.Identifier)
 }
+
+func TestSelectFromVariantsUsesDeliveryVariantForHEIC(t *testing.T) {
+	service := &VariantService{}
+	image := &models.Image{
+		ID:            7,
+		Identifier:    "img-heic",
+		StoragePath:   "original/2026/05/01/img-heic.heic",
+		MimeType:      "image/heic",
+		VariantStatus: models.ImageVariantStatusCompleted,
+	}
+	settings := &configdb.ImageProcessingSettings{}
+	variants := []models.ImageVariant{
+		{
+			ImageID:     image.ID,
+			Identifier:  "img-heic.webp",
+			StoragePath: "converted/webp/2026/05/01/img-heic.webp",
+			Format:      models.FormatWebP,
+			Status:      models.VariantStatusCompleted,
+		},
+	}
+
+	result := service.selectFromVariants(image, "image/png,*/*;q=0.8", ClientHints{}, settings, variants)
+	assert.False(t, result.IsOriginal)
+	assert.Equal(t, format.FormatWebP, result.Format)
+	assert.Equal(t, "image/webp", result.MIMEType)
+	assert.Equal(t, variants[0].StoragePath, result.StoragePath)
+
+	jpeg := *image
+	jpeg.MimeType = "image/jpeg"
+	result = service.selectFromVariants(&jpeg, "image/png,*/*;q=0.8", ClientHints{}, settings, variants)
+	assert.True(t, result.IsOriginal)
+}
+
+func TestSelectFromVariantsHonoursClientHints(t *testing.T) {
+	service := &VariantService{}
+	image := &models.Image{
+		ID:            9,
+		Identifier:    "img-hints",
+		StoragePath:   "original/2026/05/01/img-hints.jpg",
+		MimeType:      "image/jpeg",
+		Width:         2400,
+		VariantStatus: models.ImageVariantStatusCompleted,
+	}
+	settings := &configdb.ImageProcessingSettings{
+		ThumbnailEnabled:         true,
+		ConversionEnabledFormats: []string{models.FormatWebP},
+		ThumbnailSizes: []models.ThumbnailSize{
+			{Width: 300},
+			{Width: 800},
+			{Width: 400, Height: 400},
+		},
+	}
+	variants := []models.ImageVariant{
+		{ID: 1, Format: models.FormatWebP, Identifier: "img-hints.webp", Status: models.VariantStatusCompleted},
+		{ID: 2, Format: "thumbnail_300", Identifier: "img-hints_300.webp", Status: models.VariantStatusCompleted},
+		{ID: 3, Format: "thumbnail_800", Identifier: "img-hints_800.webp", Status: models.VariantStatusCompleted},
+		{ID: 4, Format: "thumbnail_400x400", Identifier: "img-hints_400x400.webp", Status: models.VariantStatusCompleted},
+	}
+	accept := "image/webp,*/*"
+
+	tests := []struct {
+		name  string
+		hints ClientHints
+		want  string
+	}{
+		{name: "no hints", hints: ClientHints{}, want: "img-hints.webp"},
+		{name: "smallest covering width", hints: ClientHints{Width: 350}, want: "img-hints_800.webp"},
+		{name: "exact width", hints: ClientHints{Width: 300}, want: "img-hints_300.webp"},
+		{name: "wider than thumbnails", hints: ClientHints{Width: 1200}, want: "img-hints.webp"},
+		{name: "save data scales by dpr", hints: ClientHints{Width: 900, DPR: 3, SaveData: true}, want: "img-hints_300.webp"},
+		{name: "save data without width", hints: ClientHints{SaveData: true}, want: "img-hints_800.webp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := service.selectFromVariants(image, accept, tt.hints, settings, variants)
+			require.NotNil(t, result.Variant)
+			assert.Equal(t, tt.want, result.Identifier)
+			assert.Equal(t, "image/webp", result.MIMEType)
+		})
+	}
+
+	result := service.selectFromVariants(image, "image/png", ClientHints{Width: 300}, settings, variants)
+	assert.True(t, result.IsOriginal, "clients that do not accept webp keep the original")
+
+	gif := *image
+	gif.MimeType = "image/gif"
+	result = service.selectFromVariants(&gif, accept, ClientHints{Width: 300}, settings, variants)
+	assert.Equal(t, "img-hints.webp", result.Identifier, "animated sources keep the full-size variant")
+}
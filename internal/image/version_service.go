@@ -0,0 +1,197 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
+)
+
+var versionLog = utils.ForModule("Version")
+
+var (
+	ErrImageNotFound        = errors.New("image not found")
+	ErrImageVersionNotFound = errors.New("image version not found")
+	ErrContentUnchanged     = errors.New("new content is identical to the current content")
+)
+
+// VersionService 负责替换图片内容及历史版本的查看、回滚和删除。
+// 替换后标识符和链接不变，原始文件哈希随内容变化，客户端据 ETag 重新获取
+type VersionService struct {
+	repo      *images.Repository
+	writer    *WriteService
+	deleter   *DeleteService
+	converter *Converter
+}
+
+func NewVersionService(repo *images.Repository, writer *WriteService, deleter *DeleteService, converter *Converter) *VersionService {
+	return &VersionService{
+		repo:      repo,
+		writer:    writer,
+		deleter:   deleter,
+		converter: converter,
+	}
+}
+
+// Replace 上传新内容替换图片，当前内容保存为历史版本
+func (s *VersionService) Replace(ctx context.Context, identifier string, userID uint, source UploadSource) (*models.Image, error) {
+	// 替换流程不把临时文件交给转换任务，请求结束即可删除
+	if source.TempFilePath != "" {
+		defer cleanupOwnedTempFile(source.TempFilePath)
+	}
+
+	img, err := s.getOwnedImage(ctx, identifier, userID)
+	if err != nil {
+		return nil, err
+	}
+	storageProvider, err := getStorageProviderByID(img.StorageConfigID)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := source.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+
+	content, err := readUploadContent(src, source)
+	if err != nil {
+		return nil, err
+	}
+	if content.fileHash == img.FileHash {
+		return nil, ErrContentUnchanged
+	}
+
+	width, height, ingested, err := s.writer.prepareOriginal(ctx, userID, content, source.FileName)
+	if err != nil {
+		return nil, err
+	}
+	body, mimeType, sizeHint := content.body, content.mimeType, content.sizeHint
+	if ingested != nil {
+		defer cleanupOwnedTempFile(ingested.path)
+		ingestedFile, err := os.Open(ingested.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open processed original: %w", err)
+		}
+		defer func() { _ = ingestedFile.Close() }()
+
+		body = ingestedFile
+		mimeType = ingested.mimeType
+		sizeHint = ingested.size
+	}
+
+	storagePath := s.writer.pathGenerator.GenerateOriginalIdentifiers(content.fileHash, getSafeFileExtension(mimeType), time.Now()).StoragePath
+	if err := storageProvider.SaveWithContext(ctx, storagePath, body); err != nil {
+		return nil, errors.New("failed to save uploaded file")
+	}
+	fileSize, err := getUploadSourceSize(body, sizeHint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine file size: %w", err)
+	}
+
+	updated, err := s.repo.WithContext(ctx).ReplaceImageContent(img.ID, userID, &models.ImageVersion{
+		StoragePath:     storagePath,
+		StorageConfigID: img.StorageConfigID,
+		OriginalName:    source.FileName,
+		FileSize:        fileSize,
+		MimeType:        mimeType,
+		FileHash:        content.fileHash,
+		Width:           width,
+		Height:          height,
+	})
+	if err != nil {
+		s.deleter.deleteObjectIfUnreferenced(ctx, img.StorageConfigID, storagePath)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to replace image content: %w", err)
+	}
+
+	s.onContentChanged(ctx, img, updated)
+	return updated, nil
+}
+
+// ListVersions 获取图片及其历史版本
+func (s *VersionService) ListVersions(ctx context.Context, identifier string, userID uint) (*models.Image, []*models.ImageVersion, error) {
+	img, err := s.getOwnedImage(ctx, identifier, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions, err := s.repo.WithContext(ctx).ListImageVersions(img.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list image versions: %w", err)
+	}
+	return img, versions, nil
+}
+
+// Revert 回滚到历史版本：该版本的内容成为新版本，当前内容进入历史
+func (s *VersionService) Revert(ctx context.Context, identifier string, userID uint, version int) (*models.Image, error) {
+	img, err := s.getOwnedImage(ctx, identifier, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.WithContext(ctx).RevertImageToVersion(img.ID, userID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImageVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to revert image: %w", err)
+	}
+
+	s.onContentChanged(ctx, img, updated)
+	return updated, nil
+}
+
+// DeleteVersion 删除一个历史版本，文件不再被引用时一并删除
+func (s *VersionService) DeleteVersion(ctx context.Context, identifier string, userID uint, version int) error {
+	img, err := s.getOwnedImage(ctx, identifier, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.WithContext(ctx).DeleteImageVersion(img.ID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrImageVersionNotFound
+		}
+		return fmt.Errorf("failed to delete image version: %w", err)
+	}
+
+	s.deleter.deleteObjectIfUnreferenced(ctx, deleted.StorageConfigID, deleted.StoragePath)
+	return nil
+}
+
+func (s *VersionService) getOwnedImage(ctx context.Context, identifier string, userID uint) (*models.Image, error) {
+	img, err := s.repo.WithContext(ctx).GetImageByIdentifier(identifier)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to get image info: %w", err)
+	}
+	if img.UserID != userID {
+		return nil, ErrImageNotFound
+	}
+	return img, nil
+}
+
+// onContentChanged 清理旧内容的变体和缓存，并按新内容重新生成变体
+func (s *VersionService) onContentChanged(ctx context.Context, previous, updated *models.Image) {
+	s.deleter.deleteVariantsForImage(ctx, previous)
+	s.deleter.clearImageCache(ctx, updated.Identifier)
+
+	submitBackgroundTaskWith(maintenanceTask, func() { s.writer.warmCache(updated) })
+	if s.converter != nil {
+		if !submitBackgroundTaskWith(uploadTask(updated), func() { s.converter.TriggerConversion(updated) }) {
+			versionLog.Warnf("Failed to submit variant conversion for replaced image %s, variants are generated on next access", utils.SanitizeLogMessage(updated.Identifier))
+		}
+	}
+}
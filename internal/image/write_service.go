@@ -1,6 +1,7 @@
 package image
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -18,10 +19,12 @@ import (
 	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/database/repo/albums"
 	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/internal/vipsfile"
 	"github.com/anoixa/image-bed/storage"
 	"github.com/anoixa/image-bed/utils"
 	"github.com/anoixa/image-bed/utils/generator"
 	"github.com/anoixa/image-bed/utils/pool"
+	"github.com/anoixa/image-bed/utils/svg"
 	"github.com/anoixa/image-bed/utils/validator"
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
@@ -37,6 +40,8 @@ type WriteService struct {
 	cacheHelper   *cache.Helper
 	baseURL       string
 	pathGenerator *generator.PathGenerator
+	// ingestPolicies 为 nil 时不处理原图
+	ingestPolicies *IngestPolicyResolver
 }
 
 func NewWriteService(
@@ -45,14 +50,16 @@ func NewWriteService(
 	converter *Converter,
 	cacheHelper *cache.Helper,
 	baseURL string,
+	ingestPolicies *IngestPolicyResolver,
 ) *WriteService {
 	return &WriteService{
-		repo:          repo,
-		albumsRepo:    albumsRepo,
-		converter:     converter,
-		cacheHelper:   cacheHelper,
-		baseURL:       baseURL,
-		pathGenerator: generator.NewPathGenerator(),
+		repo:           repo,
+		albumsRepo:     albumsRepo,
+		converter:      converter,
+		cacheHelper:    cacheHelper,
+		baseURL:        baseURL,
+		pathGenerator:  generator.NewPathGenerator(),
+		ingestPolicies: ingestPolicies,
 	}
 }
 
@@ -81,7 +88,7 @@ func (s *WriteService) UploadSingle(
 		Identifier:  image.Identifier,
 		FileName:    image.OriginalName,
 		FileSize:    image.FileSize,
-		Links:       utils.BuildLinkFormats(s.baseURL, image.Identifier),
+		Links:       utils.BuildLinkFormats(s.baseURL, image.Identifier, image.EmbedAlt()),
 	}, nil
 }
 
@@ -110,7 +117,7 @@ func (s *WriteService) UploadSingleSource(
 		Identifier:  image.Identifier,
 		FileName:    image.OriginalName,
 		FileSize:    image.FileSize,
-		Links:       utils.BuildLinkFormats(s.baseURL, image.Identifier),
+		Links:       utils.BuildLinkFormats(s.baseURL, image.Identifier, image.EmbedAlt()),
 	}, nil
 }
 
@@ -162,7 +169,7 @@ func (s *WriteService) UploadBatchSources(ctx context.Context, userID uint, file
 					result.Image = image
 					result.Identifier = image.Identifier
 					result.FileSize = image.FileSize
-					result.Links = utils.BuildLinkFormats(s.baseURL, image.Identifier)
+					result.Links = utils.BuildLinkFormats(s.baseURL, image.Identifier, image.EmbedAlt())
 				}
 
 				resultsMutex.Lock()
@@ -204,38 +211,12 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 	}
 	defer func() { _ = src.Close() }()
 
-	header := make([]byte, 512)
-	n, err := io.ReadFull(src, header)
-	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
-		return nil, false, fmt.Errorf("failed to read file header: %w", err)
-	}
-	header = header[:n]
-
-	isImage, mimeType := validator.IsImageBytes(header)
-	if !isImage {
-		return nil, false, errors.New("the uploaded file type is not supported")
-	}
-
-	var fileHash string
-	if source.PrecomputedHash != "" {
-		fileHash = source.PrecomputedHash
-	} else {
-		hashStart := time.Now()
-		hash := sha256.New()
-		if _, err := hash.Write(header); err != nil {
-			return nil, false, fmt.Errorf("failed to hash file header: %w", err)
-		}
-
-		bufPtr := pool.SharedBufferPool.Get().(*[]byte)
-		defer pool.SharedBufferPool.Put(bufPtr)
-
-		if _, err = io.CopyBuffer(hash, src, *bufPtr); err != nil {
-			return nil, false, fmt.Errorf("failed to hash file stream: %w", err)
-		}
-
-		fileHash = hex.EncodeToString(hash.Sum(nil))
-		middleware.RecordUploadHashDuration(time.Since(hashStart))
+	content, err := readUploadContent(src, source)
+	if err != nil {
+		return nil, false, err
 	}
+	body, mimeType, fileHash := content.body, content.mimeType, content.fileHash
+	fileSizeHint, localFilePath := content.sizeHint, content.localFilePath
 
 	img, err := s.repo.WithContext(ctx).GetImageByHash(fileHash)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -244,17 +225,18 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 
 	if err == nil {
 		if img.UserID != userID {
-			newImg, err := s.createDedupedImageRecord(ctx, img, userID, source.FileName, storageConfigID, isPublic)
+			newImg, err := s.createDedupedImageRecord(ctx, img, userID, source.FileName, storageConfigID, isPublic, source.Expiry)
 			if err != nil {
 				return nil, false, fmt.Errorf("failed to create deduped image record: %w", err)
 			}
-			submitBackgroundTask(func() { s.warmCache(newImg) })
+			submitBackgroundTaskWith(maintenanceTask, func() { s.warmCache(newImg) })
 			return newImg, true, nil
 		}
 
-		submitBackgroundTask(func() { s.warmCache(img) })
+		img = s.applyExpiryToDuplicate(ctx, img, source.Expiry)
+		submitBackgroundTaskWith(maintenanceTask, func() { s.warmCache(img) })
 		if s.converter != nil {
-			middleware.RecordUploadTaskSubmit(submitBackgroundTask(func() { s.converter.TriggerConversion(img) }))
+			middleware.RecordUploadTaskSubmit(submitBackgroundTaskWith(uploadTask(img), func() { s.converter.TriggerConversion(img) }))
 		}
 		return img, true, nil
 	}
@@ -269,41 +251,56 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 			writeServiceLog.Warnf("Failed to verify soft-deleted image %s for hash reuse: %v", utils.SanitizeLogMessage(deletedImg.Identifier), err)
 		} else if reusable {
 			if deletedImg.UserID != userID {
-				newImg, err := s.createDedupedImageRecord(ctx, deletedImg, userID, source.FileName, storageConfigID, isPublic)
+				newImg, err := s.createDedupedImageRecord(ctx, deletedImg, userID, source.FileName, storageConfigID, isPublic, source.Expiry)
 				if err != nil {
 					return nil, false, fmt.Errorf("failed to create deduped image record: %w", err)
 				}
-				submitBackgroundTask(func() { s.warmCache(newImg) })
+				submitBackgroundTaskWith(maintenanceTask, func() { s.warmCache(newImg) })
 				return newImg, true, nil
 			}
 
 			updates := map[string]any{
-				"deleted_at":    nil,
 				"original_name": source.FileName,
 				"is_public":     isPublic,
+				"expires_at":    source.Expiry.ExpiresAt,
+				"max_views":     source.Expiry.MaxViews,
+				"view_count":    0,
 			}
-			restored, err := s.repo.WithContext(ctx).UpdateImageByIdentifier(deletedImg.Identifier, updates)
+			restored, err := s.repo.WithContext(ctx).RestoreTrashedImageWithUpdates(deletedImg.ID, updates)
 			if err != nil {
 				return nil, false, errors.New("failed to restore existing image data")
 			}
 
-			submitBackgroundTask(func() { s.warmCache(restored) })
+			submitBackgroundTaskWith(maintenanceTask, func() { s.warmCache(restored) })
 			if s.converter != nil {
-				middleware.RecordUploadTaskSubmit(submitBackgroundTask(func() { s.converter.TriggerConversion(restored) }))
+				middleware.RecordUploadTaskSubmit(submitBackgroundTaskWith(uploadTask(restored), func() { s.converter.TriggerConversion(restored) }))
 			}
 
 			return restored, true, nil
 		}
 	}
 
-	if _, err := src.Seek(0, io.SeekStart); err != nil {
-		return nil, false, fmt.Errorf("failed to seek upload source: %w", err)
+	width, height, ingested, err := s.prepareOriginal(ctx, userID, content, source.FileName)
+	if err != nil {
+		return nil, false, err
 	}
+	ingestedConsumed := false
+	if ingested != nil {
+		defer func() {
+			if !ingestedConsumed {
+				cleanupOwnedTempFile(ingested.path)
+			}
+		}()
+		ingestedFile, err := os.Open(ingested.path)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to open processed original: %w", err)
+		}
+		defer func() { _ = ingestedFile.Close() }()
 
-	width, height := utils.GetImageDimensions(src)
-
-	if _, err := src.Seek(0, io.SeekStart); err != nil {
-		return nil, false, fmt.Errorf("failed to seek upload source after dimension extraction: %w", err)
+		body = ingestedFile
+		mimeType = ingested.mimeType
+		fileSizeHint = ingested.size
+		localFilePath = ingested.path
 	}
 
 	ext := getSafeFileExtension(mimeType)
@@ -311,12 +308,12 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 	identifier := ids.Identifier
 	storagePath := ids.StoragePath
 	storageWriteStart := time.Now()
-	if err := storageProvider.SaveWithContext(ctx, storagePath, src); err != nil {
+	if err := storageProvider.SaveWithContext(ctx, storagePath, body); err != nil {
 		return nil, false, errors.New("failed to save uploaded file")
 	}
 	middleware.RecordUploadStorageWriteDuration(time.Since(storageWriteStart))
 
-	actualFileSize, err := getUploadSourceSize(src, source.FileSize)
+	actualFileSize, err := getUploadSourceSize(body, fileSizeHint)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to determine file size: %w", err)
 	}
@@ -334,6 +331,7 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 		IsPublic:        isPublic,
 		UserID:          userID,
 	}
+	source.Expiry.applyTo(newImg)
 
 	dbWriteStart := time.Now()
 	if err := s.repo.WithContext(ctx).SaveImage(newImg); err != nil {
@@ -348,17 +346,19 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 		}
 	}
 
-	submitBackgroundTask(func() { s.warmCache(newImg) })
+	submitBackgroundTaskWith(maintenanceTask, func() { s.warmCache(newImg) })
 	if s.converter != nil {
-		if source.TempFilePath != "" {
-			accepted := submitBackgroundTask(func() { s.converter.TriggerConversionWithLocalFile(newImg, source.TempFilePath) })
+		if localFilePath != "" {
+			accepted := submitBackgroundTaskWith(uploadTask(newImg), func() { s.converter.TriggerConversionWithLocalFile(newImg, localFilePath) })
 			middleware.RecordUploadTaskSubmit(accepted)
-			if accepted {
+			if accepted && ingested != nil {
+				ingestedConsumed = true
+			} else if accepted {
 				tempFileConsumed = true
 				source.ReleaseRequestCleanup()
 			}
 		} else {
-			middleware.RecordUploadTaskSubmit(submitBackgroundTask(func() { s.converter.TriggerConversion(newImg) }))
+			middleware.RecordUploadTaskSubmit(submitBackgroundTaskWith(uploadTask(newImg), func() { s.converter.TriggerConversion(newImg) }))
 		}
 	}
 	// converter == nil: tempFileConsumed stays false, defer cleans up
@@ -366,6 +366,125 @@ func (s *WriteService) processAndSaveImage(ctx context.Context, userID uint, sou
 	return newImg, false, nil
 }
 
+// uploadContent 通过校验并计算哈希后的上传内容
+type uploadContent struct {
+	body          io.ReadSeeker
+	svgData       []byte // 净化后的 SVG，非矢量图为 nil
+	mimeType      string
+	fileHash      string
+	sizeHint      int64
+	localFilePath string
+}
+
+// readUploadContent 校验文件类型、净化 SVG 并计算内容哈希
+func readUploadContent(src io.ReadSeeker, source UploadSource) (*uploadContent, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(src, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file header: %w", err)
+	}
+	header = header[:n]
+
+	isImage, mimeType := validator.IsImageBytes(header)
+	if !isImage {
+		return nil, errors.New("the uploaded file type is not supported")
+	}
+	// HEIC/TIFF/JXL 等格式依赖 libvips 编译时的可选支持
+	if !vipsfile.SupportsMimeType(mimeType) {
+		return nil, fmt.Errorf("the uploaded file type %s is not supported by this server", mimeType)
+	}
+
+	// SVG 在哈希和落盘前先净化，后续流程只使用净化后的内容
+	content := &uploadContent{
+		body:          src,
+		mimeType:      mimeType,
+		sizeHint:      source.FileSize,
+		localFilePath: source.TempFilePath,
+	}
+	precomputedHash := source.PrecomputedHash
+	if utils.IsVectorImage(mimeType) {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek upload source: %w", err)
+		}
+		content.svgData, err = svg.Sanitize(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid svg file: %w", err)
+		}
+		content.body = bytes.NewReader(content.svgData)
+		header = nil
+		content.sizeHint = int64(len(content.svgData))
+		// 预计算哈希和临时文件都对应未净化的内容
+		precomputedHash = ""
+		content.localFilePath = ""
+	}
+
+	if precomputedHash != "" {
+		content.fileHash = precomputedHash
+		return content, nil
+	}
+
+	hashStart := time.Now()
+	hash := sha256.New()
+	if _, err := hash.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to hash file header: %w", err)
+	}
+
+	bufPtr := pool.SharedBufferPool.Get().(*[]byte)
+	defer pool.SharedBufferPool.Put(bufPtr)
+
+	if _, err = io.CopyBuffer(hash, content.body, *bufPtr); err != nil {
+		return nil, fmt.Errorf("failed to hash file stream: %w", err)
+	}
+
+	content.fileHash = hex.EncodeToString(hash.Sum(nil))
+	middleware.RecordUploadHashDuration(time.Since(hashStart))
+	return content, nil
+}
+
+// prepareOriginal 读取图片尺寸并按上传策略重写原图。
+// 返回的 ingested 非 nil 时其临时文件由调用方负责清理，尺寸已按处理后的原图更新
+func (s *WriteService) prepareOriginal(ctx context.Context, userID uint, content *uploadContent, fileName string) (width, height int, ingested *ingestedOriginal, err error) {
+	body := content.body
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to seek upload source: %w", err)
+	}
+
+	if content.svgData != nil {
+		width, height = svg.Dimensions(content.svgData)
+	} else {
+		width, height = utils.GetImageDimensions(body)
+	}
+	if width == 0 && content.localFilePath != "" {
+		// 标准库无法解码 HEIC/AVIF/TIFF/JXL，改由 libvips 读取文件头
+		if info, err := vipsfile.ProbeImageFile(content.localFilePath); err == nil {
+			width, height = info.Width, info.Height
+		}
+	}
+
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to seek upload source after dimension extraction: %w", err)
+	}
+
+	// 上传策略在去重之后执行，FileHash 仍是客户端原始内容的哈希，重复上传可直接命中
+	if content.svgData == nil && s.ingestPolicies != nil {
+		ingested, err = s.applyIngestPolicy(ctx, userID, content.mimeType, body, content.localFilePath)
+		if err != nil {
+			writeServiceLog.Warnf("Ingest policy not applied to %s, keeping original: %v", utils.SanitizeLogMessage(fileName), err)
+			ingested = nil
+		}
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			if ingested != nil {
+				cleanupOwnedTempFile(ingested.path)
+			}
+			return 0, 0, nil, fmt.Errorf("failed to seek upload source: %w", err)
+		}
+	}
+	if ingested != nil && ingested.width > 0 {
+		width, height = ingested.width, ingested.height
+	}
+	return width, height, ingested, nil
+}
+
 func (s *WriteService) canReuseSoftDeletedImage(ctx context.Context, img *models.Image) (bool, error) {
 	if img == nil || img.StoragePath == "" {
 		return false, nil
@@ -411,7 +530,7 @@ func getUploadSourceSize(src io.Seeker, hintedSize int64) (int64, error) {
 }
 
 // createDedupedImageRecord 为不同用户创建去重后的新图片记录
-func (s *WriteService) createDedupedImageRecord(ctx context.Context, existing *models.Image, userID uint, originalName string, _ uint, isPublic bool) (*models.Image, error) {
+func (s *WriteService) createDedupedImageRecord(ctx context.Context, existing *models.Image, userID uint, originalName string, _ uint, isPublic bool, expiry ImageExpiry) (*models.Image, error) {
 	ids := s.pathGenerator.GenerateOriginalIdentifiers(existing.FileHash+fmt.Sprintf("_%d", userID), filepath.Ext(originalName), time.Now())
 
 	newImg := &models.Image{
@@ -427,6 +546,7 @@ func (s *WriteService) createDedupedImageRecord(ctx context.Context, existing *m
 		IsPublic:        isPublic,
 		UserID:          userID,
 	}
+	expiry.applyTo(newImg)
 
 	if err := s.repo.WithContext(ctx).SaveImage(newImg); err != nil {
 		return nil, err
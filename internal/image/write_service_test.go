@@ -63,7 +63,7 @@ func newTestWriteService(t *testing.T, db *gorm.DB) (*WriteService, *repoimages.
 	variantRepo := repoimages.NewVariantRepository(db)
 	helper := cache.NewHelper(provider)
 
-	service := NewWriteService(repo, nil, nil, helper, "http://localhost:8080")
+	service := NewWriteService(repo, nil, nil, helper, "http://localhost:8080", nil)
 	return service, repo, variantRepo
 }
 
@@ -84,7 +84,7 @@ func TestCreateDedupedImageRecordPreservesOriginalStorageConfig(t *testing.T) {
 	}
 	require.NoError(t, repo.SaveImage(existing))
 
-	deduped, err := service.createDedupedImageRecord(context.Background(), existing, 2, "copy.webp", 99, false)
+	deduped, err := service.createDedupedImageRecord(context.Background(), existing, 2, "copy.webp", 99, false, ImageExpiry{})
 	require.NoError(t, err)
 
 	assert.Equal(t, existing.StoragePath, deduped.StoragePath)
@@ -330,6 +330,72 @@ func TestUploadSingleSourceCleansTempFileForReusableSoftDeletedImage(t *testing.
 	assert.True(t, os.IsNotExist(statErr))
 }
 
+func TestUploadSingleSourceRestoresTrashedImageForSameUser(t *testing.T) {
+	db := setupImageServiceTestDB(t)
+	service, repo, _ := newTestWriteService(t, db)
+
+	const providerID uint = 91006
+	tempDir := t.TempDir()
+	require.NoError(t, storage.AddOrUpdateProvider(storage.StorageConfig{
+		ID:        providerID,
+		Name:      "test-local-trash-restore",
+		Type:      "local",
+		LocalPath: tempDir,
+	}))
+	t.Cleanup(func() {
+		_ = storage.RemoveProvider(providerID)
+	})
+
+	fileHashBytes := sha256.Sum256(tinyPNG)
+	fileHash := hex.EncodeToString(fileHashBytes[:])
+
+	storagePath := "original/2026/04/trashed.png"
+	absolutePath := filepath.Join(tempDir, storagePath)
+	require.NoError(t, os.MkdirAll(filepath.Dir(absolutePath), 0o755))
+	require.NoError(t, os.WriteFile(absolutePath, tinyPNG, 0o644))
+
+	trashed := &models.Image{
+		Identifier:      "trashed-same-user",
+		StoragePath:     storagePath,
+		OriginalName:    "trashed.png",
+		FileSize:        int64(len(tinyPNG)),
+		MimeType:        "image/png",
+		StorageConfigID: providerID,
+		FileHash:        fileHash,
+		Width:           1,
+		Height:          1,
+		UserID:          1,
+		IsPublic:        true,
+	}
+	require.NoError(t, repo.SaveImage(trashed))
+	_, _, err := repo.DeleteBatchTransaction(context.Background(), []string{trashed.Identifier}, 1)
+	require.NoError(t, err)
+
+	uploadPath := filepath.Join(t.TempDir(), "trashed-upload.png")
+	require.NoError(t, os.WriteFile(uploadPath, tinyPNG, 0o644))
+
+	result, err := service.UploadSingleSource(
+		context.Background(),
+		1,
+		NewTempUploadSource("again.png", uploadPath, int64(len(tinyPNG))),
+		providerID,
+		false,
+		0,
+	)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.True(t, result.IsDuplicate)
+	assert.Equal(t, trashed.Identifier, result.Identifier)
+
+	restored, err := repo.GetImageByIdentifier(trashed.Identifier)
+	require.NoError(t, err, "image is restored from the trash")
+	assert.Equal(t, "again.png", restored.OriginalName)
+	assert.False(t, restored.IsPublic)
+
+	_, statErr := os.Stat(uploadPath)
+	assert.True(t, os.IsNotExist(statErr))
+}
+
 func TestUploadSingleSourceCleansTempFileWhenConversionTaskIsDropped(t *testing.T) {
 	db := setupImageServiceTestDB(t)
 	service, _, _ := newTestWriteService(t, db)
@@ -0,0 +1,167 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/database/repo/tags"
+)
+
+// 自动补全返回数量
+const (
+	DefaultAutocompleteLimit = 10
+	MaxAutocompleteLimit     = 50
+)
+
+var (
+	// ErrTagNotFound 标签未找到或无权限
+	ErrTagNotFound = tags.ErrTagNotFound
+	// ErrTagExists 同名标签已存在
+	ErrTagExists = tags.ErrTagExists
+	// ErrInvalidTagName 标签名不合法
+	ErrInvalidTagName = errors.New("invalid tag name")
+)
+
+type TagInfo = tags.TagInfo
+
+// BulkResult 批量打标签/取消标签结果
+type BulkResult struct {
+	AffectedCount     int64    `json:"affected_count"`
+	FailedIdentifiers []string `json:"failed_identifiers"`
+}
+
+type Service struct {
+	repo       *tags.Repository
+	imagesRepo *images.Repository
+}
+
+func NewService(repo *tags.Repository, imagesRepo *images.Repository) *Service {
+	return &Service{repo: repo, imagesRepo: imagesRepo}
+}
+
+// NormalizeName 规范化标签名：去除首尾空白、合并连续空白并转为小写。
+// 逗号用作查询参数分隔符，不允许出现在标签名中
+func NormalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" || utf8.RuneCountInString(name) > models.TagNameMaxLength || strings.Contains(name, ",") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTagName, name)
+	}
+	return name, nil
+}
+
+// NormalizeNames 规范化并去重标签名
+func NormalizeNames(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		normalized, err := NormalizeName(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		result = append(result, normalized)
+	}
+	return result, nil
+}
+
+func (s *Service) ListTags(ctx context.Context, userID uint) ([]*TagInfo, error) {
+	return s.repo.WithContext(ctx).ListTags(userID)
+}
+
+// Autocomplete 按前缀补全标签名
+func (s *Service) Autocomplete(ctx context.Context, userID uint, prefix string, limit int) ([]*TagInfo, error) {
+	if limit <= 0 {
+		limit = DefaultAutocompleteLimit
+	}
+	limit = min(limit, MaxAutocompleteLimit)
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	return s.repo.WithContext(ctx).SearchTags(userID, prefix, limit)
+}
+
+func (s *Service) CreateTag(ctx context.Context, userID uint, name string) (*models.Tag, error) {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	tag := &models.Tag{UserID: userID, Name: name}
+	if err := s.repo.WithContext(ctx).CreateTag(tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *Service) RenameTag(ctx context.Context, tagID, userID uint, name string) (*models.Tag, error) {
+	name, err := NormalizeName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.WithContext(ctx).RenameTag(tagID, userID, name)
+}
+
+func (s *Service) DeleteTag(ctx context.Context, tagID, userID uint) error {
+	return s.repo.WithContext(ctx).DeleteTag(tagID, userID)
+}
+
+// TagImages 为用户的图片批量添加标签，不存在的标签自动创建
+func (s *Service) TagImages(ctx context.Context, userID uint, identifiers, names []string) (*BulkResult, error) {
+	names, err := NormalizeNames(names)
+	if err != nil {
+		return nil, err
+	}
+	imageIDs, failed, err := s.resolveImages(ctx, userID, identifiers)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := s.repo.WithContext(ctx).TagImages(userID, imageIDs, names)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResult{AffectedCount: affected, FailedIdentifiers: failed}, nil
+}
+
+// UntagImages 从用户的图片上批量移除标签
+func (s *Service) UntagImages(ctx context.Context, userID uint, identifiers, names []string) (*BulkResult, error) {
+	names, err := NormalizeNames(names)
+	if err != nil {
+		return nil, err
+	}
+	imageIDs, failed, err := s.resolveImages(ctx, userID, identifiers)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := s.repo.WithContext(ctx).UntagImages(userID, imageIDs, names)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkResult{AffectedCount: affected, FailedIdentifiers: failed}, nil
+}
+
+// resolveImages 把标识符解析为用户的图片 ID，返回找不到的标识符
+func (s *Service) resolveImages(ctx context.Context, userID uint, identifiers []string) ([]uint, []string, error) {
+	imgs, err := s.imagesRepo.WithContext(ctx).GetImagesByIdentifiersAndUser(identifiers, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[string]struct{}, len(imgs))
+	imageIDs := make([]uint, 0, len(imgs))
+	for _, img := range imgs {
+		found[img.Identifier] = struct{}{}
+		imageIDs = append(imageIDs, img.ID)
+	}
+	failed := []string{}
+	for _, identifier := range identifiers {
+		if _, ok := found[identifier]; !ok {
+			failed = append(failed, identifier)
+		}
+	}
+	return imageIDs, failed, nil
+}
@@ -0,0 +1,20 @@
+package tags
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeNames(t *testing.T) {
+	names, err := NormalizeNames([]string{"  Travel ", "travel", "New   York", "2026"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"travel", "new york", "2026"}, names)
+
+	for _, invalid := range []string{"", "   ", "a,b", strings.Repeat("x", 51)} {
+		_, err := NormalizeName(invalid)
+		assert.ErrorIs(t, err, ErrInvalidTagName, "name %q", invalid)
+	}
+}
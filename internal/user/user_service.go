@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/database/repo/accounts"
 	cryptopackage "github.com/anoixa/image-bed/utils/crypto"
 )
@@ -18,6 +19,7 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidOldPassword = errors.New("invalid old password")
 	ErrSamePassword       = errors.New("new password cannot be the same as old password")
+	ErrInvalidPolicy      = errors.New("invalid ingest policy")
 )
 
 // NewService 创建新的用户服务
@@ -78,3 +80,32 @@ func (s *Service) ChangePassword(req ChangePasswordRequest) error {
 
 	return nil
 }
+
+// GetIngestPolicy 获取用户自定义的上传策略，未设置时返回 nil
+func (s *Service) GetIngestPolicy(userID uint) (*models.UserIngestPolicy, error) {
+	policy, err := s.accountsRepo.GetIngestPolicy(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest policy: %w", err)
+	}
+	return policy, nil
+}
+
+// UpdateIngestPolicy 保存用户自定义的上传策略，整体覆盖全局配置
+func (s *Service) UpdateIngestPolicy(userID uint, policy models.IngestPolicy) (*models.UserIngestPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	saved, err := s.accountsRepo.SaveIngestPolicy(userID, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save ingest policy: %w", err)
+	}
+	return saved, nil
+}
+
+// ResetIngestPolicy 删除用户自定义的上传策略，恢复使用全局配置
+func (s *Service) ResetIngestPolicy(userID uint) error {
+	if err := s.accountsRepo.DeleteIngestPolicy(userID); err != nil {
+		return fmt.Errorf("failed to delete ingest policy: %w", err)
+	}
+	return nil
+}
@@ -60,3 +60,26 @@ func TestChangePasswordRevokesAllUserSessions(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, ok)
 }
+
+func TestIngestPolicyOverride(t *testing.T) {
+	db := setupUserServiceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.UserIngestPolicy{}))
+	service := NewService(accounts.NewRepository(db), nil)
+
+	_, err := service.UpdateIngestPolicy(1, models.IngestPolicy{ConvertFormat: "bmp"})
+	require.ErrorIs(t, err, ErrInvalidPolicy)
+
+	saved, err := service.UpdateIngestPolicy(1, models.IngestPolicy{MaxDimension: 3840, Optimize: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3840, saved.MaxDimension)
+
+	policy, err := service.GetIngestPolicy(1)
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+	assert.True(t, policy.Optimize)
+
+	require.NoError(t, service.ResetIngestPolicy(1))
+	policy, err = service.GetIngestPolicy(1)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+}
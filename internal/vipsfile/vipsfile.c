@@ -1,4 +1,6 @@
 #include "vipsfile.h"
+#include <math.h>
+#include <string.h>
 
 int ib_load_image_from_file(const char *filename, VipsImage **out) {
     *out = vips_image_new_from_file(filename, NULL);
@@ -65,10 +67,135 @@ int ib_thumbnail_image(
     );
 }
 
+int ib_thumbnail_focal(
+    VipsImage *in,
+    int width,
+    int height,
+    double focal_x,
+    double focal_y,
+    VipsImage **out
+) {
+    int in_width = vips_image_get_width(in);
+    int in_height = vips_image_get_height(in);
+    if (vips_image_get_orientation_swap(in)) {
+        int tmp = in_width;
+        in_width = in_height;
+        in_height = tmp;
+    }
+    if (in_width <= 0 || in_height <= 0) {
+        vips_error("ib_thumbnail_focal", "invalid image size");
+        return -1;
+    }
+
+    double scale = VIPS_MAX((double) width / in_width, (double) height / in_height);
+    int scaled_width = VIPS_MAX(width, (int) ceil(in_width * scale));
+    int scaled_height = VIPS_MAX(height, (int) ceil(in_height * scale));
+
+    VipsImage *scaled;
+    if (vips_thumbnail_image(
+        in,
+        &scaled,
+        scaled_width,
+        "height", scaled_height,
+        "size", VIPS_SIZE_FORCE,
+        NULL
+    )) {
+        return -1;
+    }
+
+    int left = (int) round(focal_x * scaled_width - width / 2.0);
+    int top = (int) round(focal_y * scaled_height - height / 2.0);
+    left = VIPS_CLIP(0, left, scaled_width - width);
+    top = VIPS_CLIP(0, top, scaled_height - height);
+
+    int result = vips_extract_area(scaled, out, left, top, width, height, NULL);
+    g_object_unref(scaled);
+    return result;
+}
+
+/* Attach a built-in profile to an untagged image so it can be embedded. */
+static void ib_attach_profile(VipsImage *image, const char *profile) {
+    VipsBlob *blob = NULL;
+    const void *data;
+    size_t length;
+
+    if (vips_profile_load(profile, &blob, NULL) != 0) {
+        vips_error_clear();
+        return;
+    }
+    data = vips_blob_get(blob, &length);
+    vips_image_set_blob_copy(image, VIPS_META_ICC_NAME, data, length);
+    vips_area_unref((VipsArea *) blob);
+}
+
+int ib_colour_normalize(
+    VipsImage *in,
+    const char *profile,
+    const char *wide_profile,
+    VipsImage **out
+) {
+    VipsInterpretation interpretation = vips_image_guess_interpretation(in);
+    int has_profile = vips_image_get_typeof(in, VIPS_META_ICC_NAME) != 0;
+    int depth = in->BandFmt == VIPS_FORMAT_USHORT ? 16 : 8;
+
+    /* CMYK without an embedded profile falls back to the built-in CMYK
+     * profile; the transform also handles the inverted Adobe CMYK JPEGs. */
+    if (interpretation == VIPS_INTERPRETATION_CMYK) {
+        return vips_icc_transform(
+            in,
+            out,
+            profile,
+            "embedded", TRUE,
+            "input_profile", "cmyk",
+            "intent", VIPS_INTENT_PERCEPTUAL,
+            "depth", depth,
+            NULL
+        );
+    }
+
+    /* Tagged sources (Display P3, Adobe RGB, ...) are converted, or kept in
+     * the wide-gamut profile when one is requested. */
+    if (has_profile) {
+        if (wide_profile != NULL && wide_profile[0] != '\0') {
+            profile = wide_profile;
+        }
+        return vips_icc_transform(
+            in,
+            out,
+            profile,
+            "embedded", TRUE,
+            "intent", VIPS_INTENT_PERCEPTUAL,
+            "depth", depth,
+            NULL
+        );
+    }
+
+    /* Untagged images are already interpreted as sRGB by browsers. */
+    if (strcmp(profile, "srgb") == 0 || interpretation != VIPS_INTERPRETATION_sRGB) {
+        if (vips_copy(in, out, NULL) != 0) {
+            return -1;
+        }
+        if (interpretation == VIPS_INTERPRETATION_sRGB) {
+            ib_attach_profile(*out, profile);
+        }
+        return 0;
+    }
+
+    return vips_icc_transform(
+        in,
+        out,
+        profile,
+        "input_profile", "srgb",
+        "intent", VIPS_INTENT_PERCEPTUAL,
+        "depth", depth,
+        NULL
+    );
+}
+
 int ib_save_webp_file(
     VipsImage *in,
     const char *filename,
-    int strip,
+    int keep,
     int quality,
     int lossless,
     int near_lossless,
@@ -78,10 +205,26 @@ int ib_save_webp_file(
     int kmin,
     int kmax
 ) {
+    if (icc_profile == NULL || icc_profile[0] == '\0') {
+        return vips_webpsave(
+            in,
+            filename,
+            "keep", keep,
+            "Q", quality,
+            "lossless", lossless,
+            "near_lossless", near_lossless,
+            "reduction_effort", reduction_effort,
+            "min_size", min_size,
+            "kmin", kmin,
+            "kmax", kmax,
+            NULL
+        );
+    }
+
     return vips_webpsave(
         in,
         filename,
-        "strip", strip,
+        "keep", keep,
         "Q", quality,
         "lossless", lossless,
         "near_lossless", near_lossless,
@@ -97,7 +240,7 @@ int ib_save_webp_file(
 int ib_save_avif_file(
     VipsImage *in,
     const char *filename,
-    int keep_metadata,
+    int keep,
     int quality,
     int lossless,
     int effort,
@@ -120,7 +263,7 @@ int ib_save_avif_file(
         "lossless", lossless,
         "effort", effort,
         "bitdepth", bitdepth,
-        "keep", keep_metadata ? VIPS_FOREIGN_KEEP_ALL : VIPS_FOREIGN_KEEP_NONE,
+        "keep", keep,
         NULL
     );
 
@@ -128,6 +271,88 @@ int ib_save_avif_file(
     return ret;
 }
 
+int ib_save_jxl_file(
+    VipsImage *in,
+    const char *filename,
+    int keep,
+    int quality,
+    int lossless,
+    int effort
+) {
+    return vips_jxlsave(
+        in,
+        filename,
+        "Q", quality,
+        "lossless", lossless,
+        "effort", effort,
+        "keep", keep,
+        NULL
+    );
+}
+
+int ib_save_png_file(
+    VipsImage *in,
+    const char *filename,
+    int compression
+) {
+    return vips_pngsave(
+        in,
+        filename,
+        "compression", compression,
+        NULL
+    );
+}
+
+int ib_save_jpeg_file(
+    VipsImage *in,
+    const char *filename,
+    int quality
+) {
+    return vips_jpegsave(
+        in,
+        filename,
+        "Q", quality,
+        "optimize_coding", TRUE,
+        NULL
+    );
+}
+
+int ib_normalize_frame_delays(
+    VipsImage *in,
+    int min_delay,
+    int fallback_delay,
+    VipsImage **out
+) {
+    int *delays = NULL;
+    int *fixed = NULL;
+    int n = 0;
+    int i;
+
+    /* Metadata must not be modified on a shared image, so work on a copy. */
+    if (vips_copy(in, out, NULL) != 0) {
+        return -1;
+    }
+
+    if (vips_image_get_typeof(*out, "delay") == 0) {
+        return 0;
+    }
+    if (vips_image_get_array_int(*out, "delay", &delays, &n) != 0 || n <= 0) {
+        vips_error_clear();
+        return 0;
+    }
+
+    /* Browsers play GIF frames with a delay <= 10ms at 100ms; animated WebP
+     * has no such rule, so make the adjustment explicit. */
+    fixed = g_new(int, n);
+    for (i = 0; i < n; i++) {
+        fixed[i] = delays[i] < min_delay ? fallback_delay : delays[i];
+    }
+    vips_image_set_array_int(*out, "delay", fixed, n);
+    g_free(fixed);
+
+    return 0;
+}
+
 void ib_unref_image(VipsImage *in) {
     if (in != NULL) {
         g_object_unref(in);
@@ -146,6 +371,41 @@ void ib_get_image_info(VipsImage *in, int *width, int *height, int *has_alpha) {
     }
 }
 
+void ib_get_animation_info(VipsImage *in, int *n_pages, int *page_height, int *loop) {
+    if (n_pages != NULL) {
+        *n_pages = vips_image_get_n_pages(in);
+    }
+    if (page_height != NULL) {
+        *page_height = vips_image_get_page_height(in);
+    }
+    if (loop != NULL) {
+        *loop = 0;
+        if (vips_image_get_typeof(in, "loop") != 0 && vips_image_get_int(in, "loop", loop) != 0) {
+            vips_error_clear();
+            *loop = 0;
+        }
+    }
+}
+
+int ib_get_frame_delays(VipsImage *in, int **delays) {
+    int n = 0;
+
+    *delays = NULL;
+    if (vips_image_get_typeof(in, "delay") == 0) {
+        return 0;
+    }
+    if (vips_image_get_array_int(in, "delay", delays, &n) != 0) {
+        vips_error_clear();
+        *delays = NULL;
+        return 0;
+    }
+    return n;
+}
+
+int ib_supports_operation(const char *name) {
+    return vips_type_find("VipsOperation", name) != 0;
+}
+
 int ib_supports_heifsave(void) {
     return vips_type_find("VipsOperation", "heifsave") != 0;
 }
@@ -31,12 +31,28 @@ import (
 	"github.com/davidbyttow/govips/v2/vips"
 )
 
+// ImageInfo describes a decoded image. For multi-page images Height is the
+// height of a single page (frame), not of the whole loaded strip.
 type ImageInfo struct {
 	Width    int
 	Height   int
 	HasAlpha bool
+	Pages    int   // number of pages declared by the source file
+	Loop     int   // animation loop count, 0 means forever
+	Delays   []int // per-frame delays in milliseconds, empty for still images
 }
 
+// IsAnimated reports whether the source holds more than one frame.
+func (i ImageInfo) IsAnimated() bool {
+	return i.Pages > 1
+}
+
+// Built-in ICC profiles accepted as colour conversion targets.
+const (
+	ProfileSRGB = "srgb"
+	ProfileP3   = "p3"
+)
+
 type WebPOptions struct {
 	Quality         int
 	ReductionEffort int
@@ -44,9 +60,18 @@ type WebPOptions struct {
 	Lossless        bool
 	NearLossless    bool
 	IccProfile      string
-	MinSize         bool
-	MinKeyFrames    int
-	MaxKeyFrames    int
+	// ColorProfile converts the pixels to this built-in profile before
+	// saving; empty leaves colour untouched.
+	ColorProfile string
+	// EmbedProfile keeps the ICC profile of the saved pixels even when
+	// StripMetadata is set.
+	EmbedProfile bool
+	MinSize      bool
+	MinKeyFrames int
+	MaxKeyFrames int
+	// MinFrameDelay rewrites frame delays below this value (ms) to
+	// DefaultFrameDelay, matching how browsers play such GIFs. 0 keeps delays as-is.
+	MinFrameDelay int
 }
 
 type AVIFOptions struct {
@@ -55,11 +80,28 @@ type AVIFOptions struct {
 	StripMetadata bool
 	Lossless      bool
 	Bitdepth      int
+	ColorProfile  string
+	// WideGamutProfile is used instead of ColorProfile for sources with an
+	// embedded RGB profile. The profile is always embedded in that case.
+	WideGamutProfile string
+	EmbedProfile     bool
+}
+
+type JXLOptions struct {
+	Quality       int
+	Effort        int
+	StripMetadata bool
+	Lossless      bool
+	ColorProfile  string
+	EmbedProfile  bool
 }
 
 type ImportOptions struct {
 	Access      string
 	FailOnError bool
+	// Pages is the number of pages to load; 0 uses the loader default (first
+	// page only) and AllPages loads every frame of an animated image.
+	Pages int
 }
 
 type ThumbnailOptions struct {
@@ -67,8 +109,20 @@ type ThumbnailOptions struct {
 	Height int
 	Crop   int
 	Size   int
+	// Focal crops the exact Width x Height box around FocalX/FocalY, given as
+	// fractions of the (auto-rotated) source size. Crop is ignored when set.
+	Focal  bool
+	FocalX float64
+	FocalY float64
 }
 
+// Crop strategies for fixed-aspect thumbnails.
+const (
+	CropCentre    = int(vips.InterestingCentre)
+	CropAttention = int(vips.InterestingAttention)
+	CropEntropy   = int(vips.InterestingEntropy)
+)
+
 type ImageHandle struct {
 	ptr *C.VipsImage
 }
@@ -76,8 +130,19 @@ type ImageHandle struct {
 var startupOnce sync.Once
 var startupErr error
 var started atomic.Bool
+var onDemandConfig atomic.Pointer[vips.Config]
 var avifSupportOnce sync.Once
 var avifSupport bool
+var operationSupport sync.Map
+
+const (
+	// AllPages loads every page/frame of a multi-page image.
+	AllPages = -1
+	// GIFMinFrameDelay is the smallest GIF delay (ms) browsers honour as-is.
+	GIFMinFrameDelay = 20
+	// DefaultFrameDelay is the delay browsers substitute for too-short GIF frames.
+	DefaultFrameDelay = 100
+)
 
 var ErrNotInitialized = errors.New("vipsfile not initialized: call vipsfile.Startup before using file-based vips operations")
 
@@ -91,6 +156,12 @@ func Startup(config *vips.Config) error {
 	return startupErr
 }
 
+// StartupOnDemand defers libvips startup until the first file-based operation,
+// for processes that rarely need it (e.g. serve without local processing).
+func StartupOnDemand(config *vips.Config) {
+	onDemandConfig.Store(config)
+}
+
 func Shutdown() {
 	if started.CompareAndSwap(true, false) {
 		vips.Shutdown()
@@ -106,7 +177,13 @@ func ShutdownThread() {
 
 func ensureStarted() error {
 	if !started.Load() {
-		return ErrNotInitialized
+		config := onDemandConfig.Load()
+		if config == nil {
+			return ErrNotInitialized
+		}
+		if err := Startup(config); err != nil {
+			return err
+		}
 	}
 	return startupErr
 }
@@ -123,6 +200,65 @@ func SupportsAVIFEncoding() bool {
 	return avifSupport
 }
 
+// supportsOperation reports whether the libvips runtime provides the named
+// operation, e.g. "heifload". Results are cached per name.
+func supportsOperation(name string) bool {
+	if err := ensureStarted(); err != nil {
+		return false
+	}
+	if v, ok := operationSupport.Load(name); ok {
+		return v.(bool)
+	}
+
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	supported := C.ib_supports_operation(cName) != 0
+	operationSupport.Store(name, supported)
+	return supported
+}
+
+// SupportsHEIFDecoding reports whether HEIC/HEIF and AVIF files can be loaded.
+func SupportsHEIFDecoding() bool {
+	return supportsOperation("heifload")
+}
+
+// SupportsTIFFDecoding reports whether TIFF files can be loaded.
+func SupportsTIFFDecoding() bool {
+	return supportsOperation("tiffload")
+}
+
+// SupportsJXLDecoding reports whether JPEG XL files can be loaded.
+func SupportsJXLDecoding() bool {
+	return supportsOperation("jxlload")
+}
+
+// SupportsJXLEncoding reports whether JPEG XL files can be written.
+func SupportsJXLEncoding() bool {
+	return supportsOperation("jxlsave")
+}
+
+// SupportsSVGDecoding reports whether SVG files can be rasterized.
+func SupportsSVGDecoding() bool {
+	return supportsOperation("svgload")
+}
+
+// SupportsMimeType reports whether an uploaded file of the given MIME type can
+// be decoded. Formats libvips always ships with are not checked.
+func SupportsMimeType(mimeType string) bool {
+	switch mimeType {
+	case "image/heic", "image/heif", "image/avif":
+		return SupportsHEIFDecoding()
+	case "image/tiff":
+		return SupportsTIFFDecoding()
+	case "image/jxl":
+		return SupportsJXLDecoding()
+	case "image/svg+xml":
+		return SupportsSVGDecoding()
+	default:
+		return true
+	}
+}
+
 func DefaultImportOptions() ImportOptions {
 	return ImportOptions{
 		Access:      "sequential",
@@ -130,6 +266,13 @@ func DefaultImportOptions() ImportOptions {
 	}
 }
 
+// AnimatedImportOptions returns DefaultImportOptions with all frames loaded.
+func AnimatedImportOptions() ImportOptions {
+	opts := DefaultImportOptions()
+	opts.Pages = AllPages
+	return opts
+}
+
 func DefaultWebPOptions() WebPOptions {
 	return WebPOptions{
 		Quality:         75,
@@ -146,6 +289,50 @@ func DefaultAVIFOptions() AVIFOptions {
 	}
 }
 
+func DefaultJXLOptions() JXLOptions {
+	return JXLOptions{
+		Quality:       80,
+		Effort:        7,
+		StripMetadata: true,
+	}
+}
+
+// FitThumbnailOptions scales an image down to fit within the given box,
+// keeping the aspect ratio and never upscaling.
+func FitThumbnailOptions(width, height int) ThumbnailOptions {
+	return ThumbnailOptions{
+		Width:  width,
+		Height: height,
+		Crop:   int(vips.InterestingNone),
+		Size:   int(vips.SizeDown),
+	}
+}
+
+// CropThumbnailOptions fills the exact width x height box, cropping the
+// overflow with the given strategy.
+func CropThumbnailOptions(width, height, crop int) ThumbnailOptions {
+	return ThumbnailOptions{
+		Width:  width,
+		Height: height,
+		Crop:   crop,
+		Size:   int(vips.SizeBoth),
+	}
+}
+
+// FocalThumbnailOptions fills the exact width x height box, keeping the
+// focal point as close to the centre as the image edges allow.
+func FocalThumbnailOptions(width, height int, focalX, focalY float64) ThumbnailOptions {
+	return ThumbnailOptions{
+		Width:  width,
+		Height: height,
+		Crop:   int(vips.InterestingNone),
+		Size:   int(vips.SizeBoth),
+		Focal:  true,
+		FocalX: min(max(focalX, 0), 1),
+		FocalY: min(max(focalY, 0), 1),
+	}
+}
+
 func DefaultThumbnailOptions(width int) ThumbnailOptions {
 	return ThumbnailOptions{
 		Width:  width,
@@ -168,6 +355,13 @@ func buildFileOption(path string, opts ImportOptions) string {
 			suffix += ","
 		}
 		suffix += "fail=TRUE"
+		hasPrev = true
+	}
+	if opts.Pages != 0 {
+		if hasPrev {
+			suffix += ","
+		}
+		suffix += fmt.Sprintf("n=%d", opts.Pages)
 	}
 	if suffix == "" {
 		return option
@@ -215,6 +409,17 @@ func LoadImageFromFileWithOptions(path string, opts ImportOptions) (*ImageHandle
 	return &ImageHandle{ptr: img}, info, nil
 }
 
+// ProbeImageFile reads image information from the file header. libvips loads
+// lazily, so no pixel data is decoded.
+func ProbeImageFile(path string) (ImageInfo, error) {
+	img, info, err := LoadImageFromFile(path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	img.Close()
+	return info, nil
+}
+
 func ThumbnailFileToWebP(srcPath, dstPath string, width int, opts WebPOptions) (ImageInfo, error) {
 	return ThumbnailFileToWebPWithOptions(srcPath, dstPath, DefaultThumbnailOptions(width), DefaultImportOptions(), opts)
 }
@@ -226,7 +431,7 @@ func ThumbnailFileToWebPWithOptions(srcPath, dstPath string, thumb ThumbnailOpti
 
 	cDst := C.CString(dstPath)
 	defer C.free(unsafe.Pointer(cDst))
-	cProfile := C.CString(webpProfileOrNone(webpOpts.IccProfile))
+	cProfile := C.CString(webpOpts.IccProfile)
 	defer C.free(unsafe.Pointer(cProfile))
 
 	origin, _, err := LoadImageFromFileWithOptions(srcPath, importOpts)
@@ -235,16 +440,28 @@ func ThumbnailFileToWebPWithOptions(srcPath, dstPath string, thumb ThumbnailOpti
 	}
 	defer origin.Close()
 
-	var img *C.VipsImage
-	if C.ib_thumbnail_image(origin.ptr, C.int(thumb.Width), C.int(thumb.Height), C.int(thumb.Crop), C.int(thumb.Size), &img) != 0 {
-		return ImageInfo{}, lastError("thumbnail from image")
+	img, err := thumbnailImage(origin, thumb)
+	if err != nil {
+		return ImageInfo{}, err
 	}
 	defer C.ib_unref_image(img)
 
+	converted, releaseColour, err := normalizeColour(img, webpOpts.ColorProfile, "")
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer releaseColour()
+
+	out, release, err := normalizeFrameDelays(converted, webpOpts.MinFrameDelay)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer release()
+
 	if C.ib_save_webp_file(
-		img,
+		out,
 		cDst,
-		boolToInt(webpOpts.StripMetadata),
+		keepFlags(webpOpts.StripMetadata, webpOpts.EmbedProfile),
 		C.int(webpOpts.Quality),
 		boolToInt(webpOpts.Lossless),
 		boolToInt(webpOpts.NearLossless),
@@ -257,7 +474,84 @@ func ThumbnailFileToWebPWithOptions(srcPath, dstPath string, thumb ThumbnailOpti
 		return ImageInfo{}, lastError("save webp to file")
 	}
 
-	return imageInfoFromVips(img), nil
+	return imageInfoFromVips(out), nil
+}
+
+// Output formats accepted by TranscodeFile.
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+	FormatJXL  = "jxl"
+)
+
+// TranscodeOptions describes how an original is rewritten before it is stored.
+type TranscodeOptions struct {
+	// MaxDimension fits the image within MaxDimension x MaxDimension, never
+	// upscaling. 0 keeps the source size.
+	MaxDimension int
+	Format       string
+	// Quality applies to lossy formats; PNG is always saved losslessly.
+	Quality int
+}
+
+// TranscodeFile re-encodes srcPath into dstPath, downscaling it first when
+// MaxDimension is set. Metadata is kept; when resizing, the orientation is
+// applied to the pixels.
+func TranscodeFile(srcPath, dstPath string, importOpts ImportOptions, opts TranscodeOptions) (ImageInfo, error) {
+	if err := ensureStarted(); err != nil {
+		return ImageInfo{}, err
+	}
+
+	origin, _, err := LoadImageFromFileWithOptions(srcPath, importOpts)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer origin.Close()
+
+	img := origin
+	if opts.MaxDimension > 0 {
+		resized, err := thumbnailImage(origin, FitThumbnailOptions(opts.MaxDimension, opts.MaxDimension))
+		if err != nil {
+			return ImageInfo{}, err
+		}
+		img = &ImageHandle{ptr: resized}
+		defer img.Close()
+	}
+
+	switch opts.Format {
+	case FormatPNG:
+		err = img.SavePNGToFile(dstPath)
+	case FormatJPEG:
+		err = img.SaveJPEGToFile(dstPath, opts.Quality)
+	case FormatWebP:
+		err = img.SaveWebPToFile(dstPath, WebPOptions{Quality: opts.Quality, ReductionEffort: 4})
+	case FormatAVIF:
+		err = img.SaveAVIFToFile(dstPath, AVIFOptions{Quality: opts.Quality, Effort: 4})
+	case FormatJXL:
+		err = img.SaveJXLToFile(dstPath, JXLOptions{Quality: opts.Quality, Effort: 7})
+	default:
+		err = fmt.Errorf("unsupported transcode format %q", opts.Format)
+	}
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	return imageInfoFromVips(img.ptr), nil
+}
+
+func thumbnailImage(origin *ImageHandle, thumb ThumbnailOptions) (*C.VipsImage, error) {
+	var img *C.VipsImage
+	if thumb.Focal && thumb.Width > 0 && thumb.Height > 0 {
+		if C.ib_thumbnail_focal(origin.ptr, C.int(thumb.Width), C.int(thumb.Height), C.double(thumb.FocalX), C.double(thumb.FocalY), &img) != 0 {
+			return nil, lastError("focal thumbnail from image")
+		}
+		return img, nil
+	}
+	if C.ib_thumbnail_image(origin.ptr, C.int(thumb.Width), C.int(thumb.Height), C.int(thumb.Crop), C.int(thumb.Size), &img) != 0 {
+		return nil, lastError("thumbnail from image")
+	}
+	return img, nil
 }
 
 func (h *ImageHandle) SaveWebPToFile(dstPath string, opts WebPOptions) error {
@@ -267,13 +561,25 @@ func (h *ImageHandle) SaveWebPToFile(dstPath string, opts WebPOptions) error {
 
 	cDst := C.CString(dstPath)
 	defer C.free(unsafe.Pointer(cDst))
-	cProfile := C.CString(webpProfileOrNone(opts.IccProfile))
+	cProfile := C.CString(opts.IccProfile)
 	defer C.free(unsafe.Pointer(cProfile))
 
+	converted, releaseColour, err := normalizeColour(h.ptr, opts.ColorProfile, "")
+	if err != nil {
+		return err
+	}
+	defer releaseColour()
+
+	img, release, err := normalizeFrameDelays(converted, opts.MinFrameDelay)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if C.ib_save_webp_file(
-		h.ptr,
+		img,
 		cDst,
-		boolToInt(opts.StripMetadata),
+		keepFlags(opts.StripMetadata, opts.EmbedProfile),
 		C.int(opts.Quality),
 		boolToInt(opts.Lossless),
 		boolToInt(opts.NearLossless),
@@ -301,10 +607,18 @@ func (h *ImageHandle) SaveAVIFToFile(dstPath string, opts AVIFOptions) error {
 	cDst := C.CString(dstPath)
 	defer C.free(unsafe.Pointer(cDst))
 
+	img, release, err := normalizeColour(h.ptr, opts.ColorProfile, opts.WideGamutProfile)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// A wide-gamut result is only displayed correctly with its profile embedded.
+	embed := opts.EmbedProfile || (opts.WideGamutProfile != "" && hasICCProfile(img))
 	if C.ib_save_avif_file(
-		h.ptr,
+		img,
 		cDst,
-		boolToInt(!opts.StripMetadata),
+		keepFlags(opts.StripMetadata, embed),
 		C.int(opts.Quality),
 		boolToInt(opts.Lossless),
 		C.int(opts.Effort),
@@ -316,6 +630,64 @@ func (h *ImageHandle) SaveAVIFToFile(dstPath string, opts AVIFOptions) error {
 	return nil
 }
 
+func (h *ImageHandle) SaveJXLToFile(dstPath string, opts JXLOptions) error {
+	if h == nil || h.ptr == nil {
+		return fmt.Errorf("nil vips image")
+	}
+
+	cDst := C.CString(dstPath)
+	defer C.free(unsafe.Pointer(cDst))
+
+	img, release, err := normalizeColour(h.ptr, opts.ColorProfile, "")
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if C.ib_save_jxl_file(
+		img,
+		cDst,
+		keepFlags(opts.StripMetadata, opts.EmbedProfile),
+		C.int(opts.Quality),
+		boolToInt(opts.Lossless),
+		C.int(opts.Effort),
+	) != 0 {
+		return lastError("save jxl to file")
+	}
+
+	return nil
+}
+
+// SavePNGToFile saves losslessly with maximum zlib compression.
+func (h *ImageHandle) SavePNGToFile(dstPath string) error {
+	if h == nil || h.ptr == nil {
+		return fmt.Errorf("nil vips image")
+	}
+
+	cDst := C.CString(dstPath)
+	defer C.free(unsafe.Pointer(cDst))
+
+	if C.ib_save_png_file(h.ptr, cDst, 9) != 0 {
+		return lastError("save png to file")
+	}
+	return nil
+}
+
+// SaveJPEGToFile saves with optimized Huffman tables.
+func (h *ImageHandle) SaveJPEGToFile(dstPath string, quality int) error {
+	if h == nil || h.ptr == nil {
+		return fmt.Errorf("nil vips image")
+	}
+
+	cDst := C.CString(dstPath)
+	defer C.free(unsafe.Pointer(cDst))
+
+	if C.ib_save_jpeg_file(h.ptr, cDst, C.int(quality)) != 0 {
+		return lastError("save jpeg to file")
+	}
+	return nil
+}
+
 func (h *ImageHandle) Close() {
 	if h == nil || h.ptr == nil {
 		return
@@ -324,21 +696,90 @@ func (h *ImageHandle) Close() {
 	h.ptr = nil
 }
 
+// normalizeFrameDelays returns img, or a copy of it with short frame delays
+// raised, together with a func that releases the copy.
+func normalizeFrameDelays(img *C.VipsImage, minDelay int) (*C.VipsImage, func(), error) {
+	noop := func() {}
+	if minDelay <= 0 {
+		return img, noop, nil
+	}
+
+	var delays *C.int
+	if C.ib_get_frame_delays(img, &delays) <= 0 {
+		return img, noop, nil
+	}
+
+	var out *C.VipsImage
+	if C.ib_normalize_frame_delays(img, C.int(minDelay), C.int(DefaultFrameDelay), &out) != 0 {
+		return nil, noop, lastError("normalize frame delays")
+	}
+	return out, func() { C.ib_unref_image(out) }, nil
+}
+
 func imageInfoFromVips(img *C.VipsImage) ImageInfo {
 	var width, height, hasAlpha C.int
+	var pages, pageHeight, loop C.int
 	C.ib_get_image_info(img, &width, &height, &hasAlpha)
-	return ImageInfo{
+	C.ib_get_animation_info(img, &pages, &pageHeight, &loop)
+
+	info := ImageInfo{
 		Width:    int(width),
-		Height:   int(height),
+		Height:   int(pageHeight),
 		HasAlpha: hasAlpha != 0,
+		Pages:    int(pages),
+		Loop:     int(loop),
+	}
+	if info.Height <= 0 {
+		info.Height = int(height)
 	}
+
+	var delays *C.int
+	if n := int(C.ib_get_frame_delays(img, &delays)); n > 0 && delays != nil {
+		info.Delays = make([]int, n)
+		for i, d := range unsafe.Slice(delays, n) {
+			info.Delays[i] = int(d)
+		}
+	}
+	return info
 }
 
-func webpProfileOrNone(profile string) string {
+// normalizeColour returns img, or a copy converted to profile, together with
+// a func that releases the copy. Sources with an embedded RGB profile go to
+// wideProfile instead when it is set.
+func normalizeColour(img *C.VipsImage, profile, wideProfile string) (*C.VipsImage, func(), error) {
+	noop := func() {}
 	if profile == "" {
-		return "none"
+		return img, noop, nil
+	}
+
+	cProfile := C.CString(profile)
+	defer C.free(unsafe.Pointer(cProfile))
+	cWide := C.CString(wideProfile)
+	defer C.free(unsafe.Pointer(cWide))
+
+	var out *C.VipsImage
+	if C.ib_colour_normalize(img, cProfile, cWide, &out) != 0 {
+		return nil, noop, lastError("convert colour profile")
 	}
-	return profile
+	return out, func() { C.ib_unref_image(out) }, nil
+}
+
+// keepFlags maps the metadata options onto the libvips "keep" flags.
+func keepFlags(strip, embedProfile bool) C.int {
+	switch {
+	case !strip:
+		return C.int(C.VIPS_FOREIGN_KEEP_ALL)
+	case embedProfile:
+		return C.int(C.VIPS_FOREIGN_KEEP_ICC)
+	default:
+		return C.int(C.VIPS_FOREIGN_KEEP_NONE)
+	}
+}
+
+func hasICCProfile(img *C.VipsImage) bool {
+	cName := C.CString("icc-profile-data")
+	defer C.free(unsafe.Pointer(cName))
+	return C.vips_image_get_typeof(img, cName) != 0
 }
 
 // MallocTrim releases free memory from the heap back to the OS.
@@ -26,10 +26,26 @@ int ib_thumbnail_image(
     VipsImage **out
 );
 
+int ib_thumbnail_focal(
+    VipsImage *in,
+    int width,
+    int height,
+    double focal_x,
+    double focal_y,
+    VipsImage **out
+);
+
+int ib_colour_normalize(
+    VipsImage *in,
+    const char *profile,
+    const char *wide_profile,
+    VipsImage **out
+);
+
 int ib_save_webp_file(
     VipsImage *in,
     const char *filename,
-    int strip,
+    int keep,
     int quality,
     int lossless,
     int near_lossless,
@@ -43,15 +59,46 @@ int ib_save_webp_file(
 int ib_save_avif_file(
     VipsImage *in,
     const char *filename,
-    int keep_metadata,
+    int keep,
     int quality,
     int lossless,
     int effort,
     int bitdepth
 );
 
+int ib_save_jxl_file(
+    VipsImage *in,
+    const char *filename,
+    int keep,
+    int quality,
+    int lossless,
+    int effort
+);
+
+int ib_save_png_file(
+    VipsImage *in,
+    const char *filename,
+    int compression
+);
+
+int ib_save_jpeg_file(
+    VipsImage *in,
+    const char *filename,
+    int quality
+);
+
+int ib_normalize_frame_delays(
+    VipsImage *in,
+    int min_delay,
+    int fallback_delay,
+    VipsImage **out
+);
+
 void ib_unref_image(VipsImage *in);
 void ib_get_image_info(VipsImage *in, int *width, int *height, int *has_alpha);
+void ib_get_animation_info(VipsImage *in, int *n_pages, int *page_height, int *loop);
+int ib_get_frame_delays(VipsImage *in, int **delays);
 int ib_supports_heifsave(void);
+int ib_supports_operation(const char *name);
 
 #endif
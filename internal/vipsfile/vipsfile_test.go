@@ -3,6 +3,7 @@ package vipsfile
 import (
 	"image"
 	"image/color"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"os"
@@ -81,6 +82,106 @@ func TestThumbnailFileToWebP(t *testing.T) {
 func TestBuildFileOption(t *testing.T) {
 	assert.Equal(t, "image.png[access=sequential,fail=TRUE]", buildFileOption("image.png", DefaultImportOptions()))
 	assert.Equal(t, "image.png", buildFileOption("image.png", ImportOptions{}))
+	assert.Equal(t, "image.gif[access=sequential,fail=TRUE,n=-1]", buildFileOption("image.gif", AnimatedImportOptions()))
+	assert.Equal(t, "image.gif[n=-1]", buildFileOption("image.gif", ImportOptions{Pages: AllPages}))
+}
+
+func TestLoadAnimatedGIFAndSaveAnimatedWebP(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestGIF(t, 8, 6, []int{0, 5, 20})
+	dst := filepath.Join(t.TempDir(), "out.webp")
+
+	probe, err := ProbeImageFile(src)
+	require.NoError(t, err)
+	assert.Equal(t, 3, probe.Pages)
+	assert.True(t, probe.IsAnimated())
+
+	img, info, err := LoadImageFromFileWithOptions(src, AnimatedImportOptions())
+	require.NoError(t, err)
+	defer img.Close()
+
+	assert.Equal(t, 8, info.Width)
+	assert.Equal(t, 6, info.Height)
+	require.Len(t, info.Delays, 3)
+	assert.Equal(t, 200, info.Delays[2])
+
+	err = img.SaveWebPToFile(dst, WebPOptions{
+		Quality:         75,
+		ReductionEffort: 4,
+		StripMetadata:   true,
+		MinFrameDelay:   GIFMinFrameDelay,
+	})
+	require.NoError(t, err)
+
+	out, err := ProbeImageFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, 3, out.Pages)
+
+	reloaded, outInfo, err := LoadImageFromFileWithOptions(dst, AnimatedImportOptions())
+	require.NoError(t, err)
+	defer reloaded.Close()
+	require.Len(t, outInfo.Delays, 3)
+	assert.GreaterOrEqual(t, outInfo.Delays[0], GIFMinFrameDelay)
+	assert.Equal(t, 200, outInfo.Delays[2])
+}
+
+func TestThumbnailAnimatedGIFKeepsFrames(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestGIF(t, 8, 6, []int{10, 10})
+	dst := filepath.Join(t.TempDir(), "thumb.webp")
+
+	info, err := ThumbnailFileToWebPWithOptions(src, dst, DefaultThumbnailOptions(4), AnimatedImportOptions(), DefaultWebPOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 4, info.Width)
+	assert.Equal(t, 3, info.Height)
+
+	out, err := ProbeImageFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, 2, out.Pages)
+}
+
+func TestThumbnailSVGToWebP(t *testing.T) {
+	ensureTestStartup(t)
+	if !SupportsSVGDecoding() {
+		t.Skip("svgload unavailable in current libvips runtime")
+	}
+
+	src := filepath.Join(t.TempDir(), "logo.svg")
+	require.NoError(t, os.WriteFile(src, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="16" height="8"><rect width="16" height="8" fill="red"/></svg>`), 0o600))
+	dst := filepath.Join(t.TempDir(), "thumb.webp")
+
+	info, err := ThumbnailFileToWebP(src, dst, 8, DefaultWebPOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 8, info.Width)
+	assert.Equal(t, 4, info.Height)
+}
+
+func TestThumbnailFileToWebPCropped(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestPNG(t, 200, 100, false)
+
+	tests := []struct {
+		name string
+		opts ThumbnailOptions
+	}{
+		{"attention", CropThumbnailOptions(50, 50, CropAttention)},
+		{"centre", CropThumbnailOptions(64, 36, CropCentre)},
+		{"focal", FocalThumbnailOptions(50, 50, 0.9, 0.5)},
+		{"focal edge", FocalThumbnailOptions(64, 36, 0, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "thumb.webp")
+			info, err := ThumbnailFileToWebPWithOptions(src, dst, tt.opts, DefaultImportOptions(), DefaultWebPOptions())
+			require.NoError(t, err)
+			assert.Equal(t, tt.opts.Width, info.Width)
+			assert.Equal(t, tt.opts.Height, info.Height)
+		})
+	}
 }
 
 func TestLoadImageFromFile_NotFound(t *testing.T) {
@@ -128,6 +229,27 @@ func TestLoadImageFromFileAndSaveAVIF(t *testing.T) {
 	assert.Positive(t, stat.Size())
 }
 
+func TestLoadImageFromFileAndSaveJXL(t *testing.T) {
+	ensureTestStartup(t)
+	if !SupportsJXLEncoding() {
+		t.Skip("jxl encoder unavailable in current libvips runtime")
+	}
+
+	src := writeTestPNG(t, 4, 2, false)
+	dst := filepath.Join(t.TempDir(), "out.jxl")
+
+	img, _, err := LoadImageFromFile(src)
+	require.NoError(t, err)
+	defer img.Close()
+
+	require.NoError(t, img.SaveJXLToFile(dst, DefaultJXLOptions()))
+
+	info, err := ProbeImageFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, 4, info.Width)
+	assert.Equal(t, 2, info.Height)
+}
+
 func TestLoadJPEGFromFileAndSaveAVIF(t *testing.T) {
 	ensureTestStartup(t)
 
@@ -156,6 +278,41 @@ func TestLoadJPEGFromFileAndSaveAVIF(t *testing.T) {
 	assert.Positive(t, stat.Size())
 }
 
+func TestTranscodeFileDownscalesToJPEG(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestPNG(t, 400, 100, false)
+	dst := filepath.Join(t.TempDir(), "out.jpg")
+
+	info, err := TranscodeFile(src, dst, DefaultImportOptions(), TranscodeOptions{
+		MaxDimension: 200,
+		Format:       FormatJPEG,
+		Quality:      85,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, info.Width)
+	assert.Equal(t, 50, info.Height)
+
+	out, err := ProbeImageFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, 200, out.Width)
+}
+
+func TestTranscodeFileKeepsSmallerImages(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestPNG(t, 40, 20, true)
+	dst := filepath.Join(t.TempDir(), "out.png")
+
+	info, err := TranscodeFile(src, dst, DefaultImportOptions(), TranscodeOptions{MaxDimension: 200, Format: FormatPNG})
+	require.NoError(t, err)
+	assert.Equal(t, 40, info.Width)
+	assert.True(t, info.HasAlpha)
+
+	_, err = TranscodeFile(src, dst, DefaultImportOptions(), TranscodeOptions{Format: "bmp"})
+	assert.ErrorContains(t, err, "unsupported transcode format")
+}
+
 func writeTestPNG(t *testing.T, width, height int, alpha bool) string {
 	t.Helper()
 
@@ -179,6 +336,32 @@ func writeTestPNG(t *testing.T, width, height int, alpha bool) string {
 	return path
 }
 
+// writeTestGIF writes an animated GIF; delays are in 1/100s as stored by the format.
+func writeTestGIF(t *testing.T, width, height int, delays []int) string {
+	t.Helper()
+
+	anim := &gif.GIF{}
+	palette := color.Palette{color.Black, color.White, color.NRGBA{R: 200, A: 255}}
+	for i, delay := range delays {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				frame.SetColorIndex(x, y, uint8((x+y+i)%len(palette)))
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	path := filepath.Join(t.TempDir(), "input.gif")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	require.NoError(t, gif.EncodeAll(f, anim))
+	return path
+}
+
 func writeTestJPEG(t *testing.T, width, height int) string {
 	t.Helper()
 
@@ -202,3 +385,66 @@ func writeTestJPEG(t *testing.T, width, height int) string {
 	require.NoError(t, jpeg.Encode(f, img, &jpeg.Options{Quality: 90}))
 	return path
 }
+
+func TestSaveWebPEmbedsProfileOnRequest(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestPNG(t, 8, 8, false)
+	img, _, err := LoadImageFromFile(src)
+	require.NoError(t, err)
+	defer img.Close()
+
+	for _, embed := range []bool{true, false} {
+		dst := filepath.Join(t.TempDir(), "out.webp")
+		require.NoError(t, img.SaveWebPToFile(dst, WebPOptions{
+			Quality:       80,
+			StripMetadata: true,
+			ColorProfile:  ProfileSRGB,
+			EmbedProfile:  embed,
+		}))
+
+		out, _, err := LoadImageFromFile(dst)
+		require.NoError(t, err)
+		assert.Equal(t, embed, hasICCProfile(out.ptr))
+		out.Close()
+	}
+}
+
+func TestSaveAVIFKeepsWideGamutForTaggedSource(t *testing.T) {
+	ensureTestStartup(t)
+
+	src := writeTestPNG(t, 16, 16, false)
+	img, _, err := LoadImageFromFile(src)
+	require.NoError(t, err)
+	defer img.Close()
+
+	// Produce a source with an embedded Display P3 profile first.
+	p3Path := filepath.Join(t.TempDir(), "p3.webp")
+	require.NoError(t, img.SaveWebPToFile(p3Path, WebPOptions{
+		Quality:       90,
+		StripMetadata: true,
+		ColorProfile:  ProfileP3,
+		EmbedProfile:  true,
+	}))
+	tagged, _, err := LoadImageFromFile(p3Path)
+	require.NoError(t, err)
+	defer tagged.Close()
+	require.True(t, hasICCProfile(tagged.ptr))
+
+	dst := filepath.Join(t.TempDir(), "out.avif")
+	err = tagged.SaveAVIFToFile(dst, AVIFOptions{
+		Quality:          75,
+		Effort:           4,
+		StripMetadata:    true,
+		ColorProfile:     ProfileSRGB,
+		WideGamutProfile: ProfileP3,
+	})
+	if err != nil {
+		t.Skipf("avif encoder unavailable in current libvips runtime: %v", err)
+	}
+
+	out, _, err := LoadImageFromFile(dst)
+	require.NoError(t, err)
+	defer out.Close()
+	assert.True(t, hasICCProfile(out.ptr), "wide-gamut AVIF keeps its profile even when metadata is stripped")
+}
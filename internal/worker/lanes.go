@@ -0,0 +1,161 @@
+package worker
+
+// Priority 任务优先级通道，数值越小越先执行；零值按 PriorityConversion 处理
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota + 1 // 用户刚上传图片的缩略图等交互任务
+	PriorityConversion                      // 按需或恢复的 WebP/AVIF 等格式转换
+	PriorityBulk                            // 批量重处理
+	PriorityMaintenance                     // 存储清理、缓存预热等维护任务
+)
+
+// priorityLanes 通道数量
+const priorityLanes = int(PriorityMaintenance)
+
+// laneStarvationLimit 低优先级通道被连续跳过超过该次数后插队执行一次，避免饿死
+var laneStarvationLimit = 16
+
+var priorityNames = [priorityLanes]string{"interactive", "conversion", "bulk", "maintenance"}
+
+func (p Priority) normalize() Priority {
+	if p < PriorityInteractive || p > PriorityMaintenance {
+		return PriorityConversion
+	}
+	return p
+}
+
+func (p Priority) lane() int {
+	return int(p.normalize()) - 1
+}
+
+// String 通道名称
+func (p Priority) String() string {
+	return priorityNames[p.lane()]
+}
+
+// jobPriority 持久化任务的排序值，越大越先被领取
+func (p Priority) jobPriority() int {
+	return (priorityLanes + 1 - int(p.normalize())) * 10
+}
+
+// priorityFromJob 由持久化任务的排序值还原通道
+func priorityFromJob(priority int) Priority {
+	switch {
+	case priority >= PriorityInteractive.jobPriority():
+		return PriorityInteractive
+	case priority >= PriorityConversion.jobPriority():
+		return PriorityConversion
+	case priority >= PriorityBulk.jobPriority():
+		return PriorityBulk
+	default:
+		return PriorityMaintenance
+	}
+}
+
+// TaskOptions 提交任务的调度参数
+type TaskOptions struct {
+	Priority Priority
+	Owner    uint // 任务所属用户，同一通道内按用户轮转；0 表示系统任务
+}
+
+// LaneStats 单个通道的排队情况
+type LaneStats struct {
+	Name      string `json:"name"`
+	Depth     int    `json:"depth"`
+	Owners    int    `json:"owners"`
+	Submitted uint64 `json:"submitted"`
+}
+
+// laneScheduler 多通道调度：通道间按优先级，通道内按用户轮转（调用方持有锁）
+type laneScheduler struct {
+	lanes   [priorityLanes]fairLane
+	pending int
+}
+
+// fairLane 通道内每个用户一个 FIFO 队列，按轮转顺序取任务
+type fairLane struct {
+	queues    map[uint][]func()
+	ring      []uint
+	next      int
+	depth     int
+	skipped   int
+	submitted uint64
+}
+
+func (s *laneScheduler) push(opts TaskOptions, task func()) {
+	l := &s.lanes[opts.Priority.lane()]
+	if l.queues == nil {
+		l.queues = make(map[uint][]func())
+	}
+	if _, ok := l.queues[opts.Owner]; !ok {
+		l.ring = append(l.ring, opts.Owner)
+	}
+	l.queues[opts.Owner] = append(l.queues[opts.Owner], task)
+	l.depth++
+	l.submitted++
+	s.pending++
+}
+
+// pop 取出下一个任务：优先最高通道，但被跳过过多次的低优先级通道先执行一次
+func (s *laneScheduler) pop() (func(), bool) {
+	if s.pending == 0 {
+		return nil, false
+	}
+
+	chosen := -1
+	for i := range s.lanes {
+		if s.lanes[i].depth == 0 {
+			continue
+		}
+		if chosen < 0 {
+			chosen = i
+			continue
+		}
+		s.lanes[i].skipped++
+	}
+	for i := priorityLanes - 1; i > chosen; i-- {
+		if s.lanes[i].depth > 0 && s.lanes[i].skipped > laneStarvationLimit {
+			chosen = i
+			break
+		}
+	}
+
+	s.lanes[chosen].skipped = 0
+	s.pending--
+	return s.lanes[chosen].pop(), true
+}
+
+func (l *fairLane) pop() func() {
+	if l.next >= len(l.ring) {
+		l.next = 0
+	}
+	owner := l.ring[l.next]
+	queue := l.queues[owner]
+	task := queue[0]
+	queue[0] = nil
+	queue = queue[1:]
+	l.depth--
+
+	if len(queue) == 0 {
+		delete(l.queues, owner)
+		l.ring = append(l.ring[:l.next], l.ring[l.next+1:]...)
+	} else {
+		l.queues[owner] = queue
+		l.next++
+	}
+	return task
+}
+
+func (s *laneScheduler) stats() []LaneStats {
+	stats := make([]LaneStats, priorityLanes)
+	for i := range s.lanes {
+		stats[i] = LaneStats{
+			Name:      priorityNames[i],
+			Depth:     s.lanes[i].depth,
+			Owners:    len(s.lanes[i].ring),
+			Submitted: s.lanes[i].submitted,
+		}
+	}
+	return stats
+}
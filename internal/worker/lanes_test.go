@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaneSchedulerOrdersByPriorityThenOwner(t *testing.T) {
+	var s laneScheduler
+	var order []string
+	add := func(priority Priority, owner uint, name string) {
+		s.push(TaskOptions{Priority: priority, Owner: owner}, func() { order = append(order, name) })
+	}
+
+	add(PriorityMaintenance, 0, "cleanup")
+	add(PriorityBulk, 1, "reprocess")
+	add(PriorityInteractive, 1, "a1")
+	add(PriorityInteractive, 1, "a2")
+	add(PriorityInteractive, 1, "a3")
+	add(PriorityInteractive, 2, "b1")
+	add(PriorityConversion, 2, "webp")
+
+	for {
+		task, ok := s.pop()
+		if !ok {
+			break
+		}
+		task()
+	}
+
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3", "webp", "reprocess", "cleanup"}, order)
+	assert.Equal(t, 0, s.pending)
+}
+
+func TestLaneSchedulerPreventsStarvation(t *testing.T) {
+	orig := laneStarvationLimit
+	laneStarvationLimit = 3
+	t.Cleanup(func() { laneStarvationLimit = orig })
+
+	var s laneScheduler
+	ran := ""
+	for range 10 {
+		s.push(TaskOptions{Priority: PriorityInteractive, Owner: 1}, func() { ran += "i" })
+	}
+	s.push(TaskOptions{Priority: PriorityMaintenance}, func() { ran += "m" })
+
+	for {
+		task, ok := s.pop()
+		if !ok {
+			break
+		}
+		task()
+	}
+	assert.Equal(t, "iiimiiiiiii", ran)
+}
+
+func TestLaneSchedulerStats(t *testing.T) {
+	var s laneScheduler
+	s.push(TaskOptions{Priority: PriorityBulk, Owner: 1}, func() {})
+	s.push(TaskOptions{Priority: PriorityBulk, Owner: 2}, func() {})
+	s.push(TaskOptions{}, func() {})
+
+	stats := s.stats()
+	require.Len(t, stats, priorityLanes)
+	assert.Equal(t, LaneStats{Name: "conversion", Depth: 1, Owners: 1, Submitted: 1}, stats[1])
+	assert.Equal(t, LaneStats{Name: "bulk", Depth: 2, Owners: 2, Submitted: 2}, stats[2])
+	assert.Equal(t, 0, stats[0].Depth)
+}
+
+func TestPriorityJobMappingRoundTrips(t *testing.T) {
+	for _, p := range []Priority{PriorityInteractive, PriorityConversion, PriorityBulk, PriorityMaintenance} {
+		assert.Equal(t, p, priorityFromJob(p.jobPriority()), p.String())
+	}
+	assert.Greater(t, PriorityInteractive.jobPriority(), PriorityMaintenance.jobPriority())
+	assert.Equal(t, PriorityConversion, Priority(0).normalize())
+}
+
+func TestPoolRunsHigherPriorityFirst(t *testing.T) {
+	pool := NewPool(1, 10)
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.True(t, pool.Submit(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	for _, p := range []Priority{PriorityMaintenance, PriorityBulk, PriorityInteractive} {
+		wg.Add(1)
+		require.True(t, pool.SubmitWith(TaskOptions{Priority: p}, func() {
+			defer wg.Done()
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+		}))
+	}
+
+	stats := pool.GetStats()
+	assert.Equal(t, 3, stats.QueueSize)
+	assert.Equal(t, 1, stats.Lanes[PriorityInteractive.lane()].Depth)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []Priority{PriorityInteractive, PriorityBulk, PriorityMaintenance}, order)
+}
@@ -41,17 +41,14 @@ type VariantRepository interface {
 // ImageRepository 图片仓库接口
 type ImageRepository interface {
 	UpdateVariantStatus(imageID uint, status models.ImageVariantStatus) error
+	AdvanceVariantStatus(imageID uint, status models.ImageVariantStatus) error
 	TouchVariantProcessingStatus(imageID uint) error
 	GetImageByID(id uint) (*models.Image, error)
 }
 
-// pipelineResult 处理结果
-type pipelineResult struct {
-	StoragePath string
-	Width       int
-	Height      int
-	FileSize    int64
-	FileHash    string
+// StageRunRecorder 阶段执行记录仓库接口
+type StageRunRecorder interface {
+	SaveStageRuns(imageID uint, runs []models.ImageStageRun) error
 }
 
 // readImageDimensions reads image width and height from the file header without full decode.
@@ -131,7 +128,8 @@ const (
 // detectImageComplexity detects image complexity level.
 // fileSize is the compressed file size in bytes (used to estimate compression ratio).
 func detectImageComplexity(info vipsfile.ImageInfo, fileSize int64) ImageComplexity {
-	pixelCount := info.Width * info.Height
+	// 动图的文件大小包含全部帧，按总像素数估算
+	pixelCount := info.Width * info.Height * max(info.Pages, 1)
 
 	if pixelCount == 0 || fileSize == 0 {
 		return ComplexityMedium
@@ -167,27 +165,66 @@ func adaptiveWebPQuality(complexity ImageComplexity, baseQuality int) int {
 	}
 }
 
+// animationPlan 源图帧处理方式
+type animationPlan struct {
+	Animated bool // 源文件包含多帧
+	Preserve bool // 变体保留全部帧
+}
+
+// resolveAnimationPlan 根据探测到的帧数和配置决定动图处理方式。
+// 帧数超过上限时不保留动画，防止帧数炸弹耗尽内存。
+func resolveAnimationPlan(info vipsfile.ImageInfo, settings *dbconfig.ImageProcessingSettings) animationPlan {
+	plan := animationPlan{Animated: info.IsAnimated()}
+	if !plan.Animated || settings == nil || !settings.PreserveAnimation {
+		return plan
+	}
+	plan.Preserve = info.Pages <= settings.AnimationFrameLimit()
+	return plan
+}
+
+// thumbnailImportOptions 缩略图加载参数，动图按配置决定是否只取首帧
+func (p animationPlan) thumbnailImportOptions(settings *dbconfig.ImageProcessingSettings) vipsfile.ImportOptions {
+	if p.Preserve && !settings.StaticThumbnail {
+		return vipsfile.AnimatedImportOptions()
+	}
+	return vipsfile.DefaultImportOptions()
+}
+
+// ThumbnailJob 附加缩略图尺寸的处理任务
+type ThumbnailJob struct {
+	VariantID uint
+	Size      models.ThumbnailSize
+}
+
 // ImagePipelineTask 统一图片处理任务
 type ImagePipelineTask struct {
 	ThumbVariantID  uint
 	WebPVariantID   uint
 	AVIFVariantID   uint
+	JXLVariantID    uint
+	StageVariants   map[string]uint // 扩展阶段名称到变体 ID，由提交方为启用的变体阶段创建
+	ExtraThumbnails []ThumbnailJob  // 首个尺寸以外的缩略图，失败不影响图片整体状态
+	FocalX          *float64        // 手动焦点，用于固定宽高比缩略图
+	FocalY          *float64
 	ImageID         uint
 	StoragePath     string
 	ImageIdentifier string
-	FileSize        int64  // used by detectImageComplexity instead of len(fileBytes)
-	MimeType        string // used for GIF guard in generateThumbnail
+	FileSize        int64 // used by detectImageComplexity instead of len(fileBytes)
+	MimeType        string
 	Storage         storage.Provider
 	Settings        *dbconfig.ImageProcessingSettings
 	VariantRepo     VariantRepository
 	ImageRepo       ImageRepository
 	CacheHelper     *cache.Helper
-	LocalFilePath   string // optional: pre-staged local file, skip download from remote
-	inFlightLease   *inFlightTaskLease
+	StageRuns       StageRunRecorder // 可选，记录各阶段结果和耗时
+	LocalFilePath   string           // optional: pre-staged local file, skip download from remote
+	SplitPart       bool             // 缩略图与全尺寸变体拆分为两个任务提交，图片状态只前进不回退
 }
 
 const avifMinSavingsPercent int64 = 5
 
+const jxlMinSavingsPercent int64 = 5
+
 var processingHeartbeatInterval = 4 * time.Minute
 
 // getProcessingFilePath returns an OS file path suitable for vips file-based APIs.
@@ -267,8 +304,9 @@ func (t *ImagePipelineTask) getProcessingFilePath(ctx context.Context) (path str
 	return tmp.Name(), func() { _ = os.Remove(tmp.Name()) }, nil
 }
 
-// Execute 执行任务
-func (t *ImagePipelineTask) Execute() {
+// Execute 执行任务，返回的错误交由持久化队列决定是否重试
+// 变体已被其他执行者领取时视为无事可做，返回 nil；ctx 取消（如进程退出或失去租约）时中止处理
+func (t *ImagePipelineTask) Execute(ctx context.Context) error {
 	var acquiredVariants []uint
 	defer t.finalize(&acquiredVariants)
 
@@ -284,216 +322,210 @@ func (t *ImagePipelineTask) Execute() {
 		}()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 
-	if t.ThumbVariantID > 0 {
-		acquired, err := t.VariantRepo.UpdateStatusCAS(
-			t.ThumbVariantID,
-			models.VariantStatusPending,
-			models.VariantStatusProcessing,
-			"",
-		)
-		if err != nil {
-			pipelineLog.Warnf("Failed to enter processing state for thumbnail variant %d: %v", t.ThumbVariantID, err)
-			return
+	for _, stage := range pipelineStages() {
+		id := t.variantIDFor(stage)
+		if id == 0 {
+			continue
 		}
-		if !acquired {
-			return
-		}
-		acquiredVariants = append(acquiredVariants, t.ThumbVariantID)
-	}
-
-	if t.WebPVariantID > 0 {
 		acquired, err := t.VariantRepo.UpdateStatusCAS(
-			t.WebPVariantID,
-			models.VariantStatusPending,
-			models.VariantStatusProcessing,
-			"",
-		)
-		if err != nil {
-			pipelineLog.Warnf("Failed to enter processing state for WebP variant %d: %v", t.WebPVariantID, err)
-			return
-		}
-		if !acquired {
-			return
-		}
-		acquiredVariants = append(acquiredVariants, t.WebPVariantID)
-	}
-
-	if t.AVIFVariantID > 0 {
-		acquired, err := t.VariantRepo.UpdateStatusCAS(
-			t.AVIFVariantID,
+			id,
 			models.VariantStatusPending,
 			models.VariantStatusProcessing,
 			"",
 		)
 		if err != nil {
-			pipelineLog.Warnf("Failed to enter processing state for AVIF variant %d: %v", t.AVIFVariantID, err)
-			return
+			pipelineLog.Warnf("Failed to enter processing state for %s variant %d: %v", stage.Name(), id, err)
+			return fmt.Errorf("acquire %s variant %d: %w", stage.Name(), id, err)
 		}
 		if !acquired {
-			return
+			return nil
 		}
-		acquiredVariants = append(acquiredVariants, t.AVIFVariantID)
+		acquiredVariants = append(acquiredVariants, id)
 	}
 
-	t.inFlightLease = beginInFlightTask(t.ImageID, acquiredVariants)
-	defer func() {
-		if t.inFlightLease != nil {
-			t.inFlightLease.Release()
-		}
-	}()
+	t.ExtraThumbnails = t.acquireExtraThumbnails(&acquiredVariants)
 
 	semaphore := GetGlobalSemaphore()
 	if err := semaphore.Acquire(ctx); err != nil {
 		pipelineLog.Warnf("Failed to acquire processing slot for image %s: %v", t.ImageIdentifier, err)
-		if t.ThumbVariantID > 0 {
-			t.markVariantFailed(&acquiredVariants, t.ThumbVariantID, fmt.Sprintf("semaphore: %v", err))
-		}
-		if t.WebPVariantID > 0 {
-			t.markVariantFailed(&acquiredVariants, t.WebPVariantID, fmt.Sprintf("semaphore: %v", err))
-		}
-		if t.AVIFVariantID > 0 {
-			t.markVariantFailed(&acquiredVariants, t.AVIFVariantID, fmt.Sprintf("semaphore: %v", err))
-		}
-		_ = t.ImageRepo.UpdateVariantStatus(t.ImageID, models.ImageVariantStatusFailed)
+		t.failStageVariants(&acquiredVariants, fmt.Sprintf("semaphore: %v", err))
+		t.markImageFailed()
 		t.deleteCacheOnTerminalState("failed")
-		return
+		return fmt.Errorf("acquire processing slot: %w", err)
 	}
 	defer semaphore.Release()
 	stopHeartbeat := t.startProcessingHeartbeat(ctx, acquiredVariants)
 	defer stopHeartbeat()
 
-	pipelineLog.Debugf("Processing image=%s thumb_variant=%d webp_variant=%d avif_variant=%d",
-		t.StoragePath, t.ThumbVariantID, t.WebPVariantID, t.AVIFVariantID)
+	pipelineLog.Debugf("Processing image=%s thumb_variant=%d webp_variant=%d avif_variant=%d jxl_variant=%d",
+		t.StoragePath, t.ThumbVariantID, t.WebPVariantID, t.AVIFVariantID, t.JXLVariantID)
 	if err := t.runPipeline(ctx, &acquiredVariants); err != nil {
 		pipelineLog.Warnf("Processing failed for image %s: %v", t.ImageIdentifier, err)
-		_ = t.ImageRepo.UpdateVariantStatus(t.ImageID, models.ImageVariantStatusFailed)
+		t.markImageFailed()
 		t.deleteCacheOnTerminalState("failed")
-		return
+		return err
 	}
 
 	pipelineLog.Debugf("Processing completed for image=%s", t.ImageIdentifier)
+	return nil
 }
 
-// runPipeline 执行处理流水线
+// runPipeline 执行处理流水线：依次运行内置阶段和注册的扩展阶段
 func (t *ImagePipelineTask) runPipeline(ctx context.Context, acquiredVariants *[]uint) error {
 	filePath, cleanup, err := t.getProcessingFilePath(ctx)
 	if err != nil {
-		if t.ThumbVariantID > 0 {
-			t.markVariantFailed(acquiredVariants, t.ThumbVariantID, fmt.Sprintf("get file: %v", err))
-		}
-		if t.WebPVariantID > 0 {
-			t.markVariantFailed(acquiredVariants, t.WebPVariantID, fmt.Sprintf("get file: %v", err))
-		}
-		if t.AVIFVariantID > 0 {
-			t.markVariantFailed(acquiredVariants, t.AVIFVariantID, fmt.Sprintf("get file: %v", err))
-		}
+		t.failStageVariants(acquiredVariants, fmt.Sprintf("get file: %v", err))
 		return fmt.Errorf("get processing file: %w", err)
 	}
 	defer cleanup()
 
-	var thumbResult, webpResult, avifResult *pipelineResult
-	var hasSuccess, hasFailed bool
-	var thumbSkipped, webpSkipped, avifSkipped bool
-
-	if t.ThumbVariantID > 0 {
-		result, err := t.generateThumbnail(ctx, filePath)
-		switch {
-		case err != nil:
-			t.markVariantFailed(acquiredVariants, t.ThumbVariantID, err.Error())
-			hasFailed = true
-		case result == nil:
-			t.deleteTrackedVariant(acquiredVariants, t.ThumbVariantID)
-			thumbSkipped = true
-		default:
-			thumbResult = result
-			hasSuccess = true
+	// 探测帧数（只读文件头），探测失败时按单帧处理，由后续加载步骤报告错误
+	probe, err := activeVipsEngine.Probe(ctx, filePath, vipsfile.DefaultImportOptions())
+	if err != nil {
+		pipelineLog.Debugf("Failed to probe image %s: %v", t.ImageIdentifier, err)
+	}
+	plan := resolveAnimationPlan(probe, t.Settings)
+	if plan.Animated && !plan.Preserve {
+		pipelineLog.Debugf("Not preserving animation for %s: %d frames", t.ImageIdentifier, probe.Pages)
+	}
+
+	t.generateExtraThumbnails(ctx, filePath, plan, acquiredVariants)
+
+	in := &StageInput{
+		ImageID:     t.ImageID,
+		Identifier:  t.ImageIdentifier,
+		StoragePath: t.StoragePath,
+		MimeType:    t.MimeType,
+		FileSize:    t.FileSize,
+		FilePath:    filePath,
+		Info:        probe,
+		Settings:    t.Settings,
+		Storage:     t.Storage,
+		task:        t,
+		plan:        plan,
+		results:     make(map[string]*StageOutput),
+	}
+	defer in.close()
+
+	var runs []*stageRun
+	var hasSuccess, hasFailed, fullRequested bool
+	for _, stage := range pipelineStages() {
+		variantID := t.variantIDFor(stage)
+		if stage.Format() != "" && variantID == 0 {
+			continue
 		}
-	}
-
-	// Pre-load image once if both WebP and AVIF need it to avoid double decode.
-	var originImg *vipsfile.ImageHandle
-	var imgInfo vipsfile.ImageInfo
-	needLoad := t.WebPVariantID > 0 || t.AVIFVariantID > 0
-	if needLoad {
-		var err error
-		originImg, imgInfo, err = vipsfile.LoadImageFromFile(filePath)
-		if err != nil {
-			if t.WebPVariantID > 0 {
-				t.markVariantFailed(acquiredVariants, t.WebPVariantID, fmt.Sprintf("load image: %v", err))
-			}
-			if t.AVIFVariantID > 0 {
-				t.markVariantFailed(acquiredVariants, t.AVIFVariantID, fmt.Sprintf("load image: %v", err))
-			}
-			hasFailed = true
-		} else {
-			defer originImg.Close()
+		// 仅重新生成附加缩略图或拆分后的缩略图任务不执行非变体阶段
+		if stage.Format() == "" && !t.runsNonVariantStages() {
+			continue
 		}
-	}
 
-	if t.WebPVariantID > 0 {
-		result, err := t.generateWebP(ctx, filePath, originImg, imgInfo)
-		switch {
-		case err != nil:
-			t.markVariantFailed(acquiredVariants, t.WebPVariantID, err.Error())
-			hasFailed = true
-		case result == nil:
-			t.deleteTrackedVariant(acquiredVariants, t.WebPVariantID)
-			webpSkipped = true
-		default:
-			webpResult = result
-			hasSuccess = true
+		policy := stage.FailurePolicy()
+		run := &stageRun{
+			stage:     stage,
+			variantID: variantID,
+			required:  policy == StageRequired || (policy == StageFallback && !fullRequested),
 		}
-	}
+		// 可选阶段不算作已请求的全尺寸变体，之后的 StageFallback 阶段仍按必需处理
+		if isFullSizeStage(stage) && run.required {
+			fullRequested = true
+		}
+		runs = append(runs, run)
 
-	avifRequired := t.AVIFVariantID > 0 && t.WebPVariantID == 0
-	if t.AVIFVariantID > 0 {
-		result, err := t.generateAVIF(ctx, filePath, webpResult, originImg, imgInfo)
+		start := time.Now()
+		output, err := t.runStage(ctx, in, stage)
+		run.duration = time.Since(start)
 		switch {
 		case err != nil:
-			t.markVariantFailed(acquiredVariants, t.AVIFVariantID, err.Error())
-			if avifRequired {
+			run.err = err
+			t.markVariantFailed(acquiredVariants, variantID, err.Error())
+			if run.required {
 				hasFailed = true
+			} else {
+				pipelineLog.Debugf("Optional stage %s failed for %s: %v", stage.Name(), t.ImageIdentifier, err)
 			}
-		case result == nil:
-			t.deleteTrackedVariant(acquiredVariants, t.AVIFVariantID)
-			avifSkipped = true
+		case output == nil:
+			run.skipped = true
+			t.deleteTrackedVariant(acquiredVariants, variantID)
 		default:
-			avifResult = result
+			run.output = output
+			in.results[stage.Name()] = output
 			hasSuccess = true
 		}
 	}
 
 	if hasSuccess {
-		if err := t.saveVariantResults(acquiredVariants, thumbResult, webpResult, avifResult); err != nil {
+		if err := t.saveStageResults(acquiredVariants, runs); err != nil {
 			pipelineLog.Warnf("Failed to persist variant results for image %s: %v", t.ImageIdentifier, err)
 			hasFailed = true
 		}
 	}
+	t.recordStageRuns(runs)
 
 	if hasFailed {
 		return fmt.Errorf("some variants failed")
 	}
 
-	_ = t.ImageRepo.UpdateVariantStatus(t.ImageID, resolveImageVariantStatus(
-		t.ThumbVariantID > 0,
-		t.WebPVariantID > 0,
-		t.AVIFVariantID > 0,
-		thumbResult != nil,
-		webpResult != nil,
-		avifResult != nil,
-		thumbSkipped,
-		webpSkipped,
-		avifSkipped,
-	))
+	// 仅重新生成附加缩略图（如焦点变更）时保持图片原有状态
+	if t.hasPrimaryVariants() {
+		thumb, full := stageOutcomes(runs)
+		status := resolveImageVariantStatus(thumb, full...)
+		if t.SplitPart {
+			_ = t.ImageRepo.AdvanceVariantStatus(t.ImageID, status)
+		} else {
+			_ = t.ImageRepo.UpdateVariantStatus(t.ImageID, status)
+		}
+	}
 	t.deleteCacheOnTerminalState("success")
 	return nil
 }
 
+// runStage 执行单个阶段，未启用的阶段和不保留动画时的全尺寸变体阶段按跳过处理
+func (t *ImagePipelineTask) runStage(ctx context.Context, in *StageInput, stage Stage) (*StageOutput, error) {
+	if t.Settings == nil {
+		return nil, fmt.Errorf("image processing settings not provided")
+	}
+	if !stage.Enabled(t.Settings) {
+		pipelineLog.Debugf("Skipping %s for %s: stage disabled", stage.Name(), t.ImageIdentifier)
+		return nil, nil
+	}
+	// 不保留动画的多帧图片不生成全尺寸变体，继续提供原图，避免动图变成静态图
+	if isFullSizeStage(stage) && in.plan.Animated && !in.plan.Preserve {
+		return nil, nil
+	}
+	return stage.Run(ctx, in)
+}
+
+// variantIDFor 返回阶段在本任务中对应的变体 ID，未请求时为 0
+func (t *ImagePipelineTask) variantIDFor(stage Stage) uint {
+	switch stage.(type) {
+	case thumbnailStage:
+		return t.ThumbVariantID
+	case webpStage:
+		return t.WebPVariantID
+	case avifStage:
+		return t.AVIFVariantID
+	case jxlStage:
+		return t.JXLVariantID
+	}
+	if stage.Format() == "" {
+		return 0
+	}
+	return t.StageVariants[stage.Name()]
+}
+
+// failStageVariants 把本任务请求的全部变体标记为失败
+func (t *ImagePipelineTask) failStageVariants(acquiredVariants *[]uint, errMsg string) {
+	for _, stage := range pipelineStages() {
+		t.markVariantFailed(acquiredVariants, t.variantIDFor(stage), errMsg)
+	}
+	t.failExtraThumbnails(acquiredVariants, errMsg)
+}
+
 // generateThumbnail 生成缩略图
-func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath string) (*pipelineResult, error) {
+func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath string, plan animationPlan) (*StageOutput, error) {
 	settings := t.Settings
 	if settings == nil {
 		return nil, fmt.Errorf("image processing settings not provided")
@@ -503,21 +535,56 @@ func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath stri
 		return nil, nil
 	}
 
-	// GIF guard: converter.go already blocks GIFs before task creation;
-	// this is defense-in-depth using the stored MIME type.
-	if t.MimeType == "image/gif" {
-		pipelineLog.Debugf("Skipping thumbnail for %s: GIF format", t.ImageIdentifier)
-		return nil, nil
-	}
-
 	if len(settings.ThumbnailSizes) == 0 {
 		return nil, fmt.Errorf("no thumbnail sizes configured")
 	}
-	size := settings.ThumbnailSizes[0]
+
+	return t.renderThumbnail(ctx, filePath, plan, settings.ThumbnailSizes[0])
+}
+
+// generateExtraThumbnails 生成附加尺寸缩略图，逐个写库，失败只标记对应变体
+func (t *ImagePipelineTask) generateExtraThumbnails(ctx context.Context, filePath string, plan animationPlan, acquiredVariants *[]uint) {
+	for _, job := range t.ExtraThumbnails {
+		if t.Settings == nil || !t.Settings.ThumbnailEnabled {
+			t.deleteTrackedVariant(acquiredVariants, job.VariantID)
+			continue
+		}
+
+		result, err := t.renderThumbnail(ctx, filePath, plan, job.Size)
+		if err != nil {
+			t.markVariantFailed(acquiredVariants, job.VariantID, err.Error())
+			continue
+		}
+
+		if err := t.VariantRepo.UpdateCompleted(
+			job.VariantID,
+			filepath.Base(result.StoragePath),
+			result.StoragePath,
+			result.FileSize,
+			result.FileHash,
+			result.Width,
+			result.Height,
+		); err != nil {
+			pipelineLog.Warnf("Failed to mark thumbnail variant %d completed: %v", job.VariantID, err)
+			t.markVariantFailed(acquiredVariants, job.VariantID, "failed to persist result: "+err.Error())
+			continue
+		}
+		t.releaseTrackedVariant(acquiredVariants, job.VariantID)
+	}
+}
+
+// renderThumbnail 按尺寸配置生成单个缩略图并保存到存储
+func (t *ImagePipelineTask) renderThumbnail(ctx context.Context, filePath string, plan animationPlan, size models.ThumbnailSize) (*StageOutput, error) {
+	settings := t.Settings
 
 	pg := generator.NewPathGenerator()
-	thumbIdentifiers := pg.GenerateThumbnailIdentifiers(t.StoragePath, size.Width)
-	thumbPath := thumbIdentifiers.StoragePath
+	thumbPath := pg.GenerateThumbnailIdentifiers(t.StoragePath, size.Width).StoragePath
+	importOpts := plan.thumbnailImportOptions(settings)
+	if size.IsCropped() {
+		thumbPath = pg.GenerateCroppedThumbnailIdentifiers(t.StoragePath, size.Width, size.Height).StoragePath
+		// 裁剪缩略图只取首帧
+		importOpts = vipsfile.DefaultImportOptions()
+	}
 
 	tmpPath, cleanupTmpPath, err := createVariantTempPath()
 	if err != nil {
@@ -525,11 +592,17 @@ func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath stri
 	}
 	defer cleanupTmpPath()
 
-	info, err := vipsfile.ThumbnailFileToWebP(filePath, tmpPath, size.Width, vipsfile.WebPOptions{
-		Quality:         settings.ThumbnailQuality,
-		ReductionEffort: settings.WebPEffort,
-		StripMetadata:   true,
-	})
+	info, err := activeVipsEngine.Thumbnail(ctx, filePath, tmpPath,
+		t.thumbnailOptions(size),
+		importOpts,
+		vipsfile.WebPOptions{
+			Quality:         settings.ThumbnailQuality,
+			ReductionEffort: settings.WebPEffort,
+			StripMetadata:   true,
+			ColorProfile:    variantColorProfile(settings),
+			EmbedProfile:    settings.EmbedICCProfile,
+			MinFrameDelay:   vipsfile.GIFMinFrameDelay,
+		})
 	if err != nil {
 		return nil, fmt.Errorf("thumbnail from file: %w", err)
 	}
@@ -544,7 +617,7 @@ func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath stri
 		return nil, fmt.Errorf("save thumbnail: %w", err)
 	}
 
-	return &pipelineResult{
+	return &StageOutput{
 		StoragePath: thumbPath,
 		Width:       info.Width,
 		Height:      info.Height,
@@ -553,8 +626,27 @@ func (t *ImagePipelineTask) generateThumbnail(ctx context.Context, filePath stri
 	}, nil
 }
 
+// thumbnailOptions 根据尺寸配置选择缩放参数，手动焦点优先于自动裁剪策略
+func (t *ImagePipelineTask) thumbnailOptions(size models.ThumbnailSize) vipsfile.ThumbnailOptions {
+	if !size.IsCropped() {
+		return vipsfile.DefaultThumbnailOptions(size.Width)
+	}
+	if t.FocalX != nil && t.FocalY != nil {
+		return vipsfile.FocalThumbnailOptions(size.Width, size.Height, *t.FocalX, *t.FocalY)
+	}
+
+	switch size.CropMode() {
+	case models.ThumbnailCropCentre, models.ThumbnailCropFocal:
+		return vipsfile.CropThumbnailOptions(size.Width, size.Height, vipsfile.CropCentre)
+	case models.ThumbnailCropEntropy:
+		return vipsfile.CropThumbnailOptions(size.Width, size.Height, vipsfile.CropEntropy)
+	default:
+		return vipsfile.CropThumbnailOptions(size.Width, size.Height, vipsfile.CropAttention)
+	}
+}
+
 // generateWebP 生成 WebP 原图
-func (t *ImagePipelineTask) generateWebP(ctx context.Context, filePath string, originImg *vipsfile.ImageHandle, info vipsfile.ImageInfo) (*pipelineResult, error) {
+func (t *ImagePipelineTask) generateWebP(ctx context.Context, filePath string, originImg sourceImage, info vipsfile.ImageInfo) (*StageOutput, error) {
 	settings := t.Settings
 	if settings == nil {
 		return nil, fmt.Errorf("image processing settings not provided")
@@ -564,8 +656,10 @@ func (t *ImagePipelineTask) generateWebP(ctx context.Context, filePath string, o
 }
 
 // generateWebPWithSettings 使用指定设置生成 WebP
-func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePath string, settings *dbconfig.ImageProcessingSettings, originImg *vipsfile.ImageHandle, info vipsfile.ImageInfo) (*pipelineResult, error) {
-	if !settings.IsFormatEnabled(models.FormatWebP) {
+func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePath string, settings *dbconfig.ImageProcessingSettings, originImg sourceImage, info vipsfile.ImageInfo) (*StageOutput, error) {
+	// 浏览器无法显示的原图必须有 WebP 交付变体，不受格式开关和尺寸上限影响
+	deliveryRequired := utils.RequiresDeliveryVariant(t.MimeType)
+	if !settings.IsFormatEnabled(models.FormatWebP) && !deliveryRequired {
 		pipelineLog.Debugf("Skipping WebP for %s: format disabled", t.ImageIdentifier)
 		return nil, nil
 	}
@@ -574,6 +668,9 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 	if settings.MaxDimension > 0 {
 		if w, h, ok := readImageDimensions(filePath); ok {
 			if w > settings.MaxDimension || h > settings.MaxDimension {
+				if deliveryRequired {
+					return t.generateScaledDeliveryWebP(ctx, filePath, settings)
+				}
 				pipelineLog.Debugf("Skipping WebP for %s: dimensions %dx%d exceed max_dimension %d", t.ImageIdentifier, w, h, settings.MaxDimension)
 				return nil, nil
 			}
@@ -583,7 +680,7 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 	var width, height int
 	if originImg == nil {
 		var err error
-		originImg, info, err = vipsfile.LoadImageFromFile(filePath)
+		originImg, info, err = activeVipsEngine.Open(ctx, filePath, vipsfile.DefaultImportOptions())
 		if err != nil {
 			return nil, fmt.Errorf("load image from file: %w", err)
 		}
@@ -593,6 +690,9 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 	height = info.Height
 	if settings.MaxDimension > 0 {
 		if width > settings.MaxDimension || height > settings.MaxDimension {
+			if deliveryRequired {
+				return t.generateScaledDeliveryWebP(ctx, filePath, settings)
+			}
 			return nil, nil
 		}
 	}
@@ -610,10 +710,13 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 	}
 	defer cleanupTmpPath()
 
-	if err := originImg.SaveWebPToFile(tmpPath, vipsfile.WebPOptions{
+	if err := originImg.SaveWebP(ctx, tmpPath, vipsfile.WebPOptions{
 		Quality:         adaptiveQuality,
 		ReductionEffort: settings.WebPEffort,
 		StripMetadata:   true,
+		ColorProfile:    variantColorProfile(settings),
+		EmbedProfile:    settings.EmbedICCProfile,
+		MinFrameDelay:   vipsfile.GIFMinFrameDelay,
 	}); err != nil {
 		return nil, fmt.Errorf("export webp: %w", err)
 	}
@@ -628,7 +731,7 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 		return nil, fmt.Errorf("save webp: %w", err)
 	}
 
-	return &pipelineResult{
+	return &StageOutput{
 		StoragePath: originPath,
 		Width:       width,
 		Height:      height,
@@ -637,7 +740,52 @@ func (t *ImagePipelineTask) generateWebPWithSettings(ctx context.Context, filePa
 	}, nil
 }
 
-func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, webpResult *pipelineResult, originImg *vipsfile.ImageHandle, info vipsfile.ImageInfo) (*pipelineResult, error) {
+// generateScaledDeliveryWebP 为超过 MaxDimension 的 HEIC/TIFF 等原图生成缩小到限制尺寸内的交付变体
+func (t *ImagePipelineTask) generateScaledDeliveryWebP(ctx context.Context, filePath string, settings *dbconfig.ImageProcessingSettings) (*StageOutput, error) {
+	pg := generator.NewPathGenerator()
+	webpIdentifiers := pg.GenerateConvertedIdentifiers(t.StoragePath, models.FormatWebP)
+	originPath := webpIdentifiers.StoragePath
+
+	tmpPath, cleanupTmpPath, err := createVariantTempPath()
+	if err != nil {
+		return nil, fmt.Errorf("create webp temp path: %w", err)
+	}
+	defer cleanupTmpPath()
+
+	info, err := activeVipsEngine.Thumbnail(ctx, filePath, tmpPath,
+		vipsfile.FitThumbnailOptions(settings.MaxDimension, settings.MaxDimension),
+		vipsfile.DefaultImportOptions(),
+		vipsfile.WebPOptions{
+			Quality:         settings.WebPQuality,
+			ReductionEffort: settings.WebPEffort,
+			StripMetadata:   true,
+			ColorProfile:    variantColorProfile(settings),
+			EmbedProfile:    settings.EmbedICCProfile,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("export scaled webp: %w", err)
+	}
+
+	tmpFile, fileSize, fileHash, cleanupTmp, err := stageVariantFileFromPath(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("stage webp: %w", err)
+	}
+	defer cleanupTmp()
+
+	if err := t.Storage.SaveWithContext(ctx, originPath, tmpFile); err != nil {
+		return nil, fmt.Errorf("save webp: %w", err)
+	}
+
+	return &StageOutput{
+		StoragePath: originPath,
+		Width:       info.Width,
+		Height:      info.Height,
+		FileSize:    fileSize,
+		FileHash:    fileHash,
+	}, nil
+}
+
+func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, webpResult *StageOutput, originImg sourceImage, info vipsfile.ImageInfo) (*StageOutput, error) {
 	settings := t.Settings
 	if settings == nil {
 		return nil, fmt.Errorf("image processing settings not provided")
@@ -662,7 +810,7 @@ func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, w
 
 	if originImg == nil {
 		var err error
-		originImg, info, err = vipsfile.LoadImageFromFile(filePath)
+		originImg, info, err = activeVipsEngine.Open(ctx, filePath, vipsfile.DefaultImportOptions())
 		if err != nil {
 			return nil, fmt.Errorf("load image from file: %w", err)
 		}
@@ -674,6 +822,12 @@ func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, w
 		return nil, nil
 	}
 
+	// heifsave 会把多帧写成图片集合而非动画，动图只保留 WebP 变体
+	if info.IsAnimated() {
+		pipelineLog.Debugf("Skipping AVIF for %s: animated source", t.ImageIdentifier)
+		return nil, nil
+	}
+
 	pg := generator.NewPathGenerator()
 	avifIdentifiers := pg.GenerateConvertedIdentifiers(t.StoragePath, models.FormatAVIF)
 	avifPath := avifIdentifiers.StoragePath
@@ -688,12 +842,18 @@ func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, w
 	if settings.AVIFExperimental {
 		bitdepth = 10
 	}
-	if err := originImg.SaveAVIFToFile(tmpPath, vipsfile.AVIFOptions{
+	avifOpts := vipsfile.AVIFOptions{
 		Quality:       settings.AVIFQuality,
 		Effort:        settings.AVIFSpeed,
 		StripMetadata: true,
 		Bitdepth:      bitdepth,
-	}); err != nil {
+		ColorProfile:  variantColorProfile(settings),
+		EmbedProfile:  settings.EmbedICCProfile,
+	}
+	if settings.AVIFWideGamut && avifOpts.ColorProfile != "" {
+		avifOpts.WideGamutProfile = vipsfile.ProfileP3
+	}
+	if err := originImg.SaveAVIF(ctx, tmpPath, avifOpts); err != nil {
 		return nil, fmt.Errorf("export avif: %w", err)
 	}
 
@@ -715,7 +875,7 @@ func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, w
 		return nil, fmt.Errorf("save avif: %w", err)
 	}
 
-	return &pipelineResult{
+	return &StageOutput{
 		StoragePath: avifPath,
 		Width:       info.Width,
 		Height:      info.Height,
@@ -724,21 +884,156 @@ func (t *ImagePipelineTask) generateAVIF(ctx context.Context, filePath string, w
 	}, nil
 }
 
+func (t *ImagePipelineTask) generateJXL(ctx context.Context, filePath string, bestResult *StageOutput, originImg sourceImage, info vipsfile.ImageInfo) (*StageOutput, error) {
+	settings := t.Settings
+	if settings == nil {
+		return nil, fmt.Errorf("image processing settings not provided")
+	}
+	if !settings.IsFormatEnabled(models.FormatJXL) {
+		pipelineLog.Debugf("Skipping JXL for %s: format disabled", t.ImageIdentifier)
+		return nil, nil
+	}
+	if !vipsfile.SupportsJXLEncoding() {
+		pipelineLog.Debugf("Skipping JXL for %s: JXL encoding not supported", t.ImageIdentifier)
+		return nil, nil
+	}
+
+	if settings.MaxDimension > 0 {
+		if w, h, ok := readImageDimensions(filePath); ok {
+			if w > settings.MaxDimension || h > settings.MaxDimension {
+				pipelineLog.Debugf("Skipping JXL for %s: dimensions %dx%d exceed max_dimension %d", t.ImageIdentifier, w, h, settings.MaxDimension)
+				return nil, nil
+			}
+		}
+	}
+
+	if originImg == nil {
+		var err error
+		originImg, info, err = activeVipsEngine.Open(ctx, filePath, vipsfile.DefaultImportOptions())
+		if err != nil {
+			return nil, fmt.Errorf("load image from file: %w", err)
+		}
+		defer originImg.Close()
+	}
+
+	if settings.MaxDimension > 0 && (info.Width > settings.MaxDimension || info.Height > settings.MaxDimension) {
+		pipelineLog.Debugf("Skipping JXL for %s: dimensions %dx%d exceed max_dimension %d", t.ImageIdentifier, info.Width, info.Height, settings.MaxDimension)
+		return nil, nil
+	}
+
+	// 动图交给 WebP 变体，避免依赖 jxlsave 的动画支持
+	if info.IsAnimated() {
+		pipelineLog.Debugf("Skipping JXL for %s: animated source", t.ImageIdentifier)
+		return nil, nil
+	}
+
+	pg := generator.NewPathGenerator()
+	jxlIdentifiers := pg.GenerateConvertedIdentifiers(t.StoragePath, models.FormatJXL)
+	jxlPath := jxlIdentifiers.StoragePath
+
+	tmpPath, cleanupTmpPath, err := createVariantTempPath()
+	if err != nil {
+		return nil, fmt.Errorf("create jxl temp path: %w", err)
+	}
+	defer cleanupTmpPath()
+
+	opts := vipsfile.DefaultJXLOptions()
+	if settings.JXLQuality > 0 {
+		opts.Quality = settings.JXLQuality
+	}
+	if settings.JXLEffort > 0 {
+		opts.Effort = settings.JXLEffort
+	}
+	opts.ColorProfile = variantColorProfile(settings)
+	opts.EmbedProfile = settings.EmbedICCProfile
+	if err := originImg.SaveJXL(ctx, tmpPath, opts); err != nil {
+		return nil, fmt.Errorf("export jxl: %w", err)
+	}
+
+	tmpFile, fileSize, fileHash, cleanupTmp, err := stageVariantFileFromPath(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("stage jxl: %w", err)
+	}
+	defer cleanupTmp()
+
+	// 协商时 JXL 优先级最高，必须比原图和已生成的 WebP/AVIF 都更小才保留
+	baselineSize := t.FileSize
+	if bestResult != nil && bestResult.FileSize > 0 && (baselineSize <= 0 || bestResult.FileSize < baselineSize) {
+		baselineSize = bestResult.FileSize
+	}
+	if !shouldKeepJXL(fileSize, baselineSize) {
+		return nil, nil
+	}
+
+	if err := t.Storage.SaveWithContext(ctx, jxlPath, tmpFile); err != nil {
+		return nil, fmt.Errorf("save jxl: %w", err)
+	}
+
+	return &StageOutput{
+		StoragePath: jxlPath,
+		Width:       info.Width,
+		Height:      info.Height,
+		FileSize:    fileSize,
+		FileHash:    fileHash,
+	}, nil
+}
+
+// variantColorProfile 变体的目标色彩配置，保持原图色彩时为空
+func variantColorProfile(settings *dbconfig.ImageProcessingSettings) string {
+	if settings.ConvertsToSRGB() {
+		return vipsfile.ProfileSRGB
+	}
+	return ""
+}
+
+// smallestResult 返回文件最小的已生成变体
+func smallestResult(results ...*StageOutput) *StageOutput {
+	var smallest *StageOutput
+	for _, r := range results {
+		if r == nil || r.FileSize <= 0 {
+			continue
+		}
+		if smallest == nil || r.FileSize < smallest.FileSize {
+			smallest = r
+		}
+	}
+	return smallest
+}
+
 func shouldKeepAVIF(candidateSize, baselineSize int64) bool {
+	return savesEnough(candidateSize, baselineSize, avifMinSavingsPercent)
+}
+
+func shouldKeepJXL(candidateSize, baselineSize int64) bool {
+	return savesEnough(candidateSize, baselineSize, jxlMinSavingsPercent)
+}
+
+// savesEnough 候选文件比基准至少小 minSavingsPercent 时返回 true
+func savesEnough(candidateSize, baselineSize, minSavingsPercent int64) bool {
 	if candidateSize <= 0 {
 		return false
 	}
 	if baselineSize <= 0 {
 		return true
 	}
-	return candidateSize*100 < baselineSize*(100-avifMinSavingsPercent)
+	return candidateSize*100 < baselineSize*(100-minSavingsPercent)
 }
 
-func resolveImageVariantStatus(hasThumbVariant, hasWebPVariant, hasAVIFVariant, thumbCompleted, webpCompleted, avifCompleted, thumbSkipped, webpSkipped, avifSkipped bool) models.ImageVariantStatus {
-	fullCompleted := webpCompleted || avifCompleted
-	allSkipped := (!hasThumbVariant || thumbSkipped) &&
-		(!hasWebPVariant || webpSkipped) &&
-		(!hasAVIFVariant || avifSkipped)
+// variantOutcome 单个变体在本次流水线中的处理结果
+type variantOutcome struct {
+	Requested bool
+	Completed bool
+	Skipped   bool
+}
+
+func resolveImageVariantStatus(thumb variantOutcome, full ...variantOutcome) models.ImageVariantStatus {
+	thumbCompleted := thumb.Completed
+	fullCompleted := false
+	allSkipped := !thumb.Requested || thumb.Skipped
+	for _, outcome := range full {
+		fullCompleted = fullCompleted || outcome.Completed
+		allSkipped = allSkipped && (!outcome.Requested || outcome.Skipped)
+	}
 
 	switch {
 	case thumbCompleted && fullCompleted:
@@ -754,71 +1049,109 @@ func resolveImageVariantStatus(hasThumbVariant, hasWebPVariant, hasAVIFVariant,
 	}
 }
 
-// saveVariantResults 保存变体结果，任一变体写库失败时返回 error
-func (t *ImagePipelineTask) saveVariantResults(acquiredVariants *[]uint, thumbResult, webpResult, avifResult *pipelineResult) error {
+// saveStageResults 保存变体阶段的产出，必需阶段写库失败时返回 error
+func (t *ImagePipelineTask) saveStageResults(acquiredVariants *[]uint, runs []*stageRun) error {
 	var firstErr error
-
-	if t.ThumbVariantID > 0 && thumbResult != nil {
+	for _, run := range runs {
+		if run.variantID == 0 || run.output == nil {
+			continue
+		}
+		result := run.output
 		if err := t.VariantRepo.UpdateCompleted(
-			t.ThumbVariantID,
-			filepath.Base(thumbResult.StoragePath),
-			thumbResult.StoragePath,
-			thumbResult.FileSize,
-			thumbResult.FileHash,
-			thumbResult.Width,
-			thumbResult.Height,
+			run.variantID,
+			filepath.Base(result.StoragePath),
+			result.StoragePath,
+			result.FileSize,
+			result.FileHash,
+			result.Width,
+			result.Height,
 		); err != nil {
-			pipelineLog.Warnf("Failed to mark thumbnail variant %d completed: %v", t.ThumbVariantID, err)
-			t.markVariantFailed(acquiredVariants, t.ThumbVariantID, "failed to persist result: "+err.Error())
-			if firstErr == nil {
+			pipelineLog.Warnf("Failed to mark %s variant %d completed: %v", run.stage.Name(), run.variantID, err)
+			t.markVariantFailed(acquiredVariants, run.variantID, "failed to persist result: "+err.Error())
+			run.output, run.err = nil, fmt.Errorf("persist result: %w", err)
+			if firstErr == nil && run.required {
 				firstErr = err
 			}
-		} else {
-			t.releaseTrackedVariant(acquiredVariants, t.ThumbVariantID)
+			continue
 		}
+		t.releaseTrackedVariant(acquiredVariants, run.variantID)
 	}
+	return firstErr
+}
 
-	if t.WebPVariantID > 0 && webpResult != nil {
-		if err := t.VariantRepo.UpdateCompleted(
-			t.WebPVariantID,
-			filepath.Base(webpResult.StoragePath),
-			webpResult.StoragePath,
-			webpResult.FileSize,
-			webpResult.FileHash,
-			webpResult.Width,
-			webpResult.Height,
-		); err != nil {
-			pipelineLog.Warnf("Failed to mark WebP variant %d completed: %v", t.WebPVariantID, err)
-			t.markVariantFailed(acquiredVariants, t.WebPVariantID, "failed to persist result: "+err.Error())
-			if firstErr == nil {
-				firstErr = err
-			}
-		} else {
-			t.releaseTrackedVariant(acquiredVariants, t.WebPVariantID)
+// recordStageRuns 记录各阶段结果和耗时，写入失败只记日志
+func (t *ImagePipelineTask) recordStageRuns(runs []*stageRun) {
+	if t.StageRuns == nil || len(runs) == 0 {
+		return
+	}
+	records := make([]models.ImageStageRun, 0, len(runs))
+	for _, run := range runs {
+		records = append(records, run.record())
+	}
+	if err := t.StageRuns.SaveStageRuns(t.ImageID, records); err != nil {
+		pipelineLog.Warnf("Failed to record stage runs for image %s: %v", t.ImageIdentifier, err)
+	}
+}
+
+// acquireExtraThumbnails 逐个抢占附加缩略图变体，未抢到的尺寸由其他任务处理
+func (t *ImagePipelineTask) acquireExtraThumbnails(acquiredVariants *[]uint) []ThumbnailJob {
+	var jobs []ThumbnailJob
+	for _, job := range t.ExtraThumbnails {
+		if job.VariantID == 0 {
+			continue
 		}
+		acquired, err := t.VariantRepo.UpdateStatusCAS(
+			job.VariantID,
+			models.VariantStatusPending,
+			models.VariantStatusProcessing,
+			"",
+		)
+		if err != nil {
+			pipelineLog.Warnf("Failed to enter processing state for thumbnail variant %d: %v", job.VariantID, err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+		*acquiredVariants = append(*acquiredVariants, job.VariantID)
+		jobs = append(jobs, job)
 	}
+	return jobs
+}
 
-	if t.AVIFVariantID > 0 && avifResult != nil {
-		if err := t.VariantRepo.UpdateCompleted(
-			t.AVIFVariantID,
-			filepath.Base(avifResult.StoragePath),
-			avifResult.StoragePath,
-			avifResult.FileSize,
-			avifResult.FileHash,
-			avifResult.Width,
-			avifResult.Height,
-		); err != nil {
-			pipelineLog.Warnf("Failed to mark AVIF variant %d completed: %v", t.AVIFVariantID, err)
-			t.markVariantFailed(acquiredVariants, t.AVIFVariantID, "failed to persist result: "+err.Error())
-			if firstErr == nil && t.WebPVariantID == 0 {
-				firstErr = err
-			}
-		} else {
-			t.releaseTrackedVariant(acquiredVariants, t.AVIFVariantID)
+func (t *ImagePipelineTask) failExtraThumbnails(acquiredVariants *[]uint, errMsg string) {
+	for _, job := range t.ExtraThumbnails {
+		t.markVariantFailed(acquiredVariants, job.VariantID, errMsg)
+	}
+}
+
+// hasPrimaryVariants 任务是否包含决定图片整体状态的变体
+func (t *ImagePipelineTask) hasPrimaryVariants() bool {
+	for _, stage := range pipelineStages() {
+		if t.variantIDFor(stage) > 0 {
+			return true
 		}
 	}
+	return false
+}
 
-	return firstErr
+// runsNonVariantStages 非变体阶段随包含全尺寸变体的任务执行，未拆分时随任意主变体执行
+func (t *ImagePipelineTask) runsNonVariantStages() bool {
+	if !t.SplitPart {
+		return t.hasPrimaryVariants()
+	}
+	for _, stage := range pipelineStages() {
+		if isFullSizeStage(stage) && t.variantIDFor(stage) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ImagePipelineTask) markImageFailed() {
+	if t.hasPrimaryVariants() {
+		_ = t.ImageRepo.UpdateVariantStatus(t.ImageID, models.ImageVariantStatusFailed)
+	}
 }
 
 func (t *ImagePipelineTask) releaseTrackedVariant(acquiredVariants *[]uint, id uint) {
@@ -833,7 +1166,6 @@ func (t *ImagePipelineTask) releaseTrackedVariant(acquiredVariants *[]uint, id u
 		}
 	}
 	*acquiredVariants = filtered
-	t.syncInFlightTracking(*acquiredVariants)
 }
 
 func (t *ImagePipelineTask) markVariantFailed(acquiredVariants *[]uint, id uint, errMsg string) {
@@ -911,12 +1243,6 @@ func (t *ImagePipelineTask) touchProcessingState(acquiredVariants []uint) {
 	}
 }
 
-func (t *ImagePipelineTask) syncInFlightTracking(acquiredVariants []uint) {
-	if t.inFlightLease != nil {
-		t.inFlightLease.Update(t.ImageID, acquiredVariants)
-	}
-}
-
 // finalize ensures variant state consistency on all exit paths.
 //   - On panic: rolls back processing variants to failed.
 //   - On normal exit: rolls back any variant still stuck in processing back
@@ -11,7 +11,9 @@ import (
 	"testing"
 	"time"
 
+	dbconfig "github.com/anoixa/image-bed/config/db"
 	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/internal/vipsfile"
 	"github.com/anoixa/image-bed/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,6 +23,7 @@ import (
 type mockVariantRepo struct {
 	updateCompletedErr error
 	updateFailedCalls  []uint
+	completedCalls     []uint
 	touchCalls         [][]uint
 	statusCASCalls     []statusCASCall
 }
@@ -43,6 +46,9 @@ func (m *mockVariantRepo) UpdateStatusCAS(id uint, expected, newStatus, errMsg s
 }
 
 func (m *mockVariantRepo) UpdateCompleted(id uint, identifier, storagePath string, fileSize int64, fileHash string, width, height int) error {
+	if m.updateCompletedErr == nil {
+		m.completedCalls = append(m.completedCalls, id)
+	}
 	return m.updateCompletedErr
 }
 
@@ -71,9 +77,16 @@ func (m *mockVariantRepo) ResetStaleProcessing(olderThan time.Duration) (int64,
 
 type mockImageRepo struct {
 	touchedImageIDs []uint
+	statuses        []models.ImageVariantStatus
 }
 
 func (m *mockImageRepo) UpdateVariantStatus(imageID uint, status models.ImageVariantStatus) error {
+	m.statuses = append(m.statuses, status)
+	return nil
+}
+
+func (m *mockImageRepo) AdvanceVariantStatus(imageID uint, status models.ImageVariantStatus) error {
+	m.statuses = append(m.statuses, status)
 	return nil
 }
 
@@ -112,16 +125,17 @@ func TestGetProcessingFilePath_LocalStorage(t *testing.T) {
 	assert.NoError(t, statErr, "local storage file must not be deleted by cleanup")
 }
 
-func TestSaveVariantResults_UpdateCompletedError_CallsUpdateFailed(t *testing.T) {
+func TestSaveStageResults_UpdateCompletedError_CallsUpdateFailed(t *testing.T) {
 	repo := &mockVariantRepo{updateCompletedErr: errors.New("db down")}
 	task := &ImagePipelineTask{
 		WebPVariantID: 7,
 		VariantRepo:   repo,
 	}
-	result := &pipelineResult{StoragePath: "webp/foo.webp", Width: 100, Height: 100, FileSize: 1000, FileHash: "abc"}
+	result := &StageOutput{StoragePath: "webp/foo.webp", Width: 100, Height: 100, FileSize: 1000, FileHash: "abc"}
 	acquiredVariants := []uint{7}
+	run := &stageRun{stage: webpStage{}, variantID: 7, required: true, output: result}
 
-	err := task.saveVariantResults(&acquiredVariants, nil, result, nil)
+	err := task.saveStageResults(&acquiredVariants, []*stageRun{run})
 
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "db down")
@@ -172,31 +186,77 @@ func TestCreateVariantTempPath(t *testing.T) {
 
 func TestResolveImageVariantStatus(t *testing.T) {
 	t.Run("thumbnail completed and webp skipped", func(t *testing.T) {
-		status := resolveImageVariantStatus(true, true, false, true, false, false, false, true, false)
+		status := resolveImageVariantStatus(variantOutcome{Requested: true, Completed: true}, variantOutcome{Requested: true, Skipped: true}, variantOutcome{})
 		assert.Equal(t, models.ImageVariantStatusThumbnailCompleted, status)
 	})
 
 	t.Run("both completed", func(t *testing.T) {
-		status := resolveImageVariantStatus(true, true, false, true, true, false, false, false, false)
+		status := resolveImageVariantStatus(variantOutcome{Requested: true, Completed: true}, variantOutcome{Requested: true, Completed: true}, variantOutcome{})
 		assert.Equal(t, models.ImageVariantStatusCompleted, status)
 	})
 
 	t.Run("webp only completed", func(t *testing.T) {
-		status := resolveImageVariantStatus(false, true, false, false, true, false, false, false, false)
+		status := resolveImageVariantStatus(variantOutcome{}, variantOutcome{Requested: true, Completed: true}, variantOutcome{})
 		assert.Equal(t, models.ImageVariantStatusCompleted, status)
 	})
 
 	t.Run("all skipped", func(t *testing.T) {
-		status := resolveImageVariantStatus(true, true, false, false, false, false, true, true, false)
+		status := resolveImageVariantStatus(variantOutcome{Requested: true, Skipped: true}, variantOutcome{Requested: true, Skipped: true}, variantOutcome{})
 		assert.Equal(t, models.ImageVariantStatusNone, status)
 	})
 
+	t.Run("jxl only completed", func(t *testing.T) {
+		status := resolveImageVariantStatus(variantOutcome{}, variantOutcome{}, variantOutcome{}, variantOutcome{Requested: true, Completed: true})
+		assert.Equal(t, models.ImageVariantStatusCompleted, status)
+	})
+
 	t.Run("avif only completed", func(t *testing.T) {
-		status := resolveImageVariantStatus(false, false, true, false, false, true, false, false, false)
+		status := resolveImageVariantStatus(variantOutcome{}, variantOutcome{}, variantOutcome{Requested: true, Completed: true})
 		assert.Equal(t, models.ImageVariantStatusCompleted, status)
 	})
 }
 
+func TestResolveAnimationPlan(t *testing.T) {
+	settings := &dbconfig.ImageProcessingSettings{PreserveAnimation: true, MaxAnimationFrames: 10}
+
+	t.Run("still image", func(t *testing.T) {
+		plan := resolveAnimationPlan(vipsfile.ImageInfo{Pages: 1}, settings)
+		assert.Equal(t, animationPlan{}, plan)
+	})
+
+	t.Run("animated within frame limit", func(t *testing.T) {
+		plan := resolveAnimationPlan(vipsfile.ImageInfo{Pages: 10}, settings)
+		assert.Equal(t, animationPlan{Animated: true, Preserve: true}, plan)
+	})
+
+	t.Run("animated over frame limit", func(t *testing.T) {
+		plan := resolveAnimationPlan(vipsfile.ImageInfo{Pages: 11}, settings)
+		assert.Equal(t, animationPlan{Animated: true}, plan)
+	})
+
+	t.Run("preservation disabled", func(t *testing.T) {
+		plan := resolveAnimationPlan(vipsfile.ImageInfo{Pages: 2}, &dbconfig.ImageProcessingSettings{})
+		assert.Equal(t, animationPlan{Animated: true}, plan)
+	})
+
+	t.Run("static thumbnail option", func(t *testing.T) {
+		plan := animationPlan{Animated: true, Preserve: true}
+		assert.Equal(t, vipsfile.AllPages, plan.thumbnailImportOptions(settings).Pages)
+
+		static := *settings
+		static.StaticThumbnail = true
+		assert.Equal(t, 0, plan.thumbnailImportOptions(&static).Pages)
+	})
+}
+
+func TestDetectImageComplexityCountsAllFrames(t *testing.T) {
+	info := vipsfile.ImageInfo{Width: 100, Height: 100}
+	assert.Equal(t, ComplexityLow, detectImageComplexity(info, 40_000))
+
+	info.Pages = 50
+	assert.Equal(t, ComplexityHigh, detectImageComplexity(info, 40_000))
+}
+
 func TestShouldKeepAVIF(t *testing.T) {
 	assert.True(t, shouldKeepAVIF(94, 100))
 	assert.False(t, shouldKeepAVIF(95, 100))
@@ -204,6 +264,22 @@ func TestShouldKeepAVIF(t *testing.T) {
 	assert.True(t, shouldKeepAVIF(90, 0))
 }
 
+func TestShouldKeepJXL(t *testing.T) {
+	assert.True(t, shouldKeepJXL(94, 100))
+	assert.False(t, shouldKeepJXL(95, 100))
+	assert.False(t, shouldKeepJXL(0, 100))
+	assert.True(t, shouldKeepJXL(90, 0))
+}
+
+func TestSmallestResult(t *testing.T) {
+	webp := &StageOutput{FileSize: 800}
+	avif := &StageOutput{FileSize: 600}
+
+	assert.Same(t, avif, smallestResult(webp, avif))
+	assert.Same(t, webp, smallestResult(webp, nil))
+	assert.Nil(t, smallestResult(nil, &StageOutput{}))
+}
+
 func TestFinalizeOnlyRollsBackStillTrackedVariants(t *testing.T) {
 	repo := &mockVariantRepo{}
 	task := &ImagePipelineTask{VariantRepo: repo}
@@ -220,3 +296,46 @@ func TestFinalizeOnlyRollsBackStillTrackedVariants(t *testing.T) {
 		errMsg:    "",
 	}, repo.statusCASCalls[0])
 }
+
+func TestThumbnailOptions(t *testing.T) {
+	task := &ImagePipelineTask{}
+
+	assert.Equal(t, vipsfile.DefaultThumbnailOptions(600), task.thumbnailOptions(models.ThumbnailSize{Width: 600}))
+	assert.Equal(t, vipsfile.CropThumbnailOptions(400, 400, vipsfile.CropAttention),
+		task.thumbnailOptions(models.ThumbnailSize{Width: 400, Height: 400}))
+	assert.Equal(t, vipsfile.CropThumbnailOptions(640, 360, vipsfile.CropEntropy),
+		task.thumbnailOptions(models.ThumbnailSize{Width: 640, Height: 360, Crop: models.ThumbnailCropEntropy}))
+	assert.Equal(t, vipsfile.CropThumbnailOptions(640, 360, vipsfile.CropCentre),
+		task.thumbnailOptions(models.ThumbnailSize{Width: 640, Height: 360, Crop: models.ThumbnailCropFocal}))
+
+	x, y := 0.25, 0.75
+	task.FocalX, task.FocalY = &x, &y
+	assert.Equal(t, vipsfile.FocalThumbnailOptions(640, 360, 0.25, 0.75),
+		task.thumbnailOptions(models.ThumbnailSize{Width: 640, Height: 360, Crop: models.ThumbnailCropCentre}))
+	assert.Equal(t, vipsfile.DefaultThumbnailOptions(600), task.thumbnailOptions(models.ThumbnailSize{Width: 600}))
+}
+
+func TestAcquireExtraThumbnailsSkipsEmptyJobs(t *testing.T) {
+	repo := &mockVariantRepo{}
+	task := &ImagePipelineTask{
+		VariantRepo: repo,
+		ExtraThumbnails: []ThumbnailJob{
+			{VariantID: 21, Size: models.ThumbnailSize{Width: 400, Height: 400}},
+			{VariantID: 0, Size: models.ThumbnailSize{Width: 300}},
+		},
+	}
+
+	var acquiredVariants []uint
+	jobs := task.acquireExtraThumbnails(&acquiredVariants)
+
+	require.Len(t, jobs, 1)
+	assert.Equal(t, uint(21), jobs[0].VariantID)
+	assert.Equal(t, []uint{21}, acquiredVariants)
+	assert.False(t, task.hasPrimaryVariants())
+}
+
+func TestVariantColorProfile(t *testing.T) {
+	assert.Equal(t, vipsfile.ProfileSRGB, variantColorProfile(&dbconfig.ImageProcessingSettings{}))
+	assert.Equal(t, vipsfile.ProfileSRGB, variantColorProfile(&dbconfig.ImageProcessingSettings{ColorSpace: dbconfig.ColorSpaceSRGB}))
+	assert.Empty(t, variantColorProfile(&dbconfig.ImageProcessingSettings{ColorSpace: dbconfig.ColorSpacePreserve}))
+}
@@ -0,0 +1,448 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/jobs"
+	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
+)
+
+var queueLog = utils.ForModule("JobQueue")
+
+var (
+	queuePollInterval    = 5 * time.Second
+	queueLeaseDuration   = 5 * time.Minute
+	queueJobTimeout      = 15 * time.Minute
+	queueRetryBaseDelay  = 10 * time.Second
+	queueRetryMaxDelay   = 10 * time.Minute
+	queuePruneInterval   = time.Hour
+	queueCompletedRetain = 7 * 24 * time.Hour
+)
+
+var (
+	globalQueue     *Queue
+	globalQueueOnce sync.Once
+)
+
+// JobHandler 持久化任务处理函数；返回 PermanentError 包装的错误时不再重试
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// PermanentError 标记不可重试的任务错误（如负载无法解析、目标已不存在）
+func PermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent 判断错误是否被标记为不可重试
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// EnqueueOptions 入队参数
+type EnqueueOptions struct {
+	Priority    Priority // 领取顺序和在 Pool 中的通道
+	Owner       uint     // 所属用户，领取时在同一优先级内按用户轮转
+	Subject     uint     // 任务处理的对象（如图片 ID），用于查询对象是否仍有未完成的任务
+	RunAt       time.Time
+	MaxAttempts int
+}
+
+// QueueStats 当前进程的任务队列统计
+type QueueStats struct {
+	Owner     string `json:"owner"`
+	Running   int    `json:"running"`
+	Claimed   uint64 `json:"claimed"`
+	Completed uint64 `json:"completed"`
+	Retried   uint64 `json:"retried"`
+	Failed    uint64 `json:"failed"`
+}
+
+// Queue 基于数据库的持久化任务队列
+// 任务先写入 jobs 表，调度协程按租约领取后交给 Pool 执行；进程崩溃时租约过期，任务会被重新领取。
+type Queue struct {
+	repo  *jobs.Repository
+	pool  *Pool
+	owner string
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	running  map[uint]context.CancelFunc // 执行中的任务，值为取消任务 context 的函数（尚未开始执行时为 nil）
+
+	wake     chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	started  atomic.Bool
+	stopOnce sync.Once
+
+	claimed   atomic.Uint64
+	completed atomic.Uint64
+	retried   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewQueue 创建任务队列，owner 标识当前进程
+func NewQueue(repo *jobs.Repository, pool *Pool) *Queue {
+	return &Queue{
+		repo:     repo,
+		pool:     pool,
+		owner:    newQueueOwner(),
+		handlers: make(map[string]JobHandler),
+		running:  make(map[uint]context.CancelFunc),
+		wake:     make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+func newQueueOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+// InitGlobalQueue 初始化全局任务队列
+func InitGlobalQueue(repo *jobs.Repository, pool *Pool) *Queue {
+	globalQueueOnce.Do(func() {
+		globalQueue = NewQueue(repo, pool)
+	})
+	return globalQueue
+}
+
+// GetGlobalQueue 获取全局任务队列，未初始化时返回 nil
+func GetGlobalQueue() *Queue {
+	return globalQueue
+}
+
+// Register 注册任务类型的处理函数，只会领取已注册类型的任务
+func (q *Queue) Register(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue 写入任务并唤醒调度协程
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload any, opts EnqueueOptions) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal job payload: %w", err)
+	}
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Priority:    opts.Priority.jobPriority(),
+		OwnerID:     opts.Owner,
+		SubjectID:   opts.Subject,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if err := q.repo.WithContext(ctx).Enqueue(job); err != nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动调度与续租协程
+func (q *Queue) Start() {
+	if !q.started.CompareAndSwap(false, true) {
+		return
+	}
+	go q.loop()
+	queueLog.Infof("Started (owner=%s, lease=%s)", q.owner, queueLeaseDuration)
+}
+
+// Stop 停止领取新任务和续租；已交给 Pool 的任务继续执行
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.stopCh)
+		if q.started.Load() {
+			<-q.doneCh
+		}
+		queueLog.Infof("Stopped")
+	})
+}
+
+// ReleaseRunning 归还当前进程仍持有租约的任务，供下次启动或其他进程立即领取；
+// 仍在执行的任务随之取消，避免与重新领取的执行者同时处理
+func (q *Queue) ReleaseRunning(ctx context.Context) (int64, error) {
+	ids := q.runningIDs()
+	released, err := q.repo.WithContext(ctx).ReleaseLeases(q.owner, ids)
+	if err != nil {
+		return released, err
+	}
+	q.cancelRunning(ids)
+	return released, nil
+}
+
+// RunningJobs 返回当前进程正在执行的指定类型任务
+func (q *Queue) RunningJobs(ctx context.Context, jobType string) ([]*models.Job, error) {
+	return q.repo.WithContext(ctx).ListRunningByOwner(q.owner, jobType)
+}
+
+// ActiveSubjects 返回仍有等待或执行中任务的对象 ID 子查询（所有进程）
+func (q *Queue) ActiveSubjects(ctx context.Context, jobType string) *gorm.DB {
+	return q.repo.WithContext(ctx).ActiveSubjectsQuery(jobType)
+}
+
+// Backlog 返回等待领取的任务数
+func (q *Queue) Backlog(ctx context.Context) (int64, error) {
+	return q.repo.WithContext(ctx).CountReady()
+}
+
+// Stats 返回当前进程的队列统计
+func (q *Queue) Stats() QueueStats {
+	q.mu.RLock()
+	running := len(q.running)
+	q.mu.RUnlock()
+	return QueueStats{
+		Owner:     q.owner,
+		Running:   running,
+		Claimed:   q.claimed.Load(),
+		Completed: q.completed.Load(),
+		Retried:   q.retried.Load(),
+		Failed:    q.failed.Load(),
+	}
+}
+
+func (q *Queue) loop() {
+	defer close(q.doneCh)
+
+	poll := time.NewTicker(queuePollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(queueLeaseDuration / 3)
+	defer heartbeat.Stop()
+	prune := time.NewTicker(queuePruneInterval)
+	defer prune.Stop()
+
+	q.dispatch()
+	for {
+		select {
+		case <-q.stopCh:
+			return
+		case <-q.wake:
+			q.dispatch()
+		case <-poll.C:
+			q.dispatch()
+		case <-heartbeat.C:
+			q.extendLeases()
+		case <-prune.C:
+			q.prune()
+		}
+	}
+}
+
+// capacity 可以再领取的任务数：运行中（含 Pool 队列中）的任务不超过 worker 数的两倍
+func (q *Queue) capacity() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return max(q.pool.GetStats().WorkerCount*2-len(q.running), 0)
+}
+
+func (q *Queue) handlerTypes() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+func (q *Queue) dispatch() {
+	limit := q.capacity()
+	if limit == 0 {
+		return
+	}
+
+	if n, err := q.repo.FailExhausted(); err != nil {
+		queueLog.Warnf("Failed to fail exhausted jobs: %v", err)
+	} else if n > 0 {
+		q.failed.Add(uint64(n))
+		queueLog.Warnf("Marked %d jobs failed after their final lease expired", n)
+	}
+
+	claimed, err := q.repo.Claim(q.owner, q.handlerTypes(), queueLeaseDuration, limit)
+	if err != nil {
+		queueLog.Warnf("Failed to claim jobs: %v", err)
+		return
+	}
+
+	for _, job := range claimed {
+		q.claimed.Add(1)
+		q.mu.Lock()
+		q.running[job.ID] = nil
+		q.mu.Unlock()
+
+		taskOpts := TaskOptions{Priority: priorityFromJob(job.Priority), Owner: job.OwnerID}
+		if !q.pool.SubmitWith(taskOpts, func() { q.execute(job) }) {
+			// Pool 拒绝（队列满或内存背压）不计入尝试次数，等待下次轮询
+			q.finish(job.ID)
+			if _, err := q.repo.ReleaseLeases(q.owner, []uint{job.ID}); err != nil {
+				queueLog.Warnf("Failed to return job %d after submit rejection: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+func (q *Queue) execute(job *models.Job) {
+	defer func() {
+		q.finish(job.ID)
+		q.notify()
+	}()
+
+	err := q.runHandler(job)
+	switch {
+	case err == nil:
+		q.completed.Add(1)
+		if err := q.repo.Complete(job.ID, q.owner); err != nil {
+			queueLog.Warnf("Failed to complete job %d: %v", job.ID, err)
+		}
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		q.failed.Add(1)
+		queueLog.Warnf("Job %d (%s) failed after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+		if err := q.repo.Fail(job.ID, q.owner, err.Error()); err != nil {
+			queueLog.Warnf("Failed to mark job %d failed: %v", job.ID, err)
+		}
+	default:
+		q.retried.Add(1)
+		delay := retryDelay(job.Attempts)
+		queueLog.Infof("Job %d (%s) attempt %d failed, retrying in %s: %v", job.ID, job.Type, job.Attempts, delay, err)
+		if err := q.repo.Reschedule(job.ID, q.owner, time.Now().Add(delay), err.Error()); err != nil {
+			queueLog.Warnf("Failed to reschedule job %d: %v", job.ID, err)
+		}
+	}
+}
+
+func (q *Queue) runHandler(job *models.Job) (err error) {
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if handler == nil {
+		return PermanentError(fmt.Errorf("no handler registered for job type %q", job.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), queueJobTimeout)
+	defer cancel()
+	q.mu.Lock()
+	if _, ok := q.running[job.ID]; ok {
+		q.running[job.ID] = cancel
+	}
+	q.mu.Unlock()
+	return handler(ctx, job)
+}
+
+func (q *Queue) finish(id uint) {
+	q.mu.Lock()
+	delete(q.running, id)
+	q.mu.Unlock()
+}
+
+func (q *Queue) runningIDs() []uint {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	ids := make([]uint, 0, len(q.running))
+	for id := range q.running {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// cancelRunning 取消指定任务的 context
+func (q *Queue) cancelRunning(ids []uint) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	for _, id := range ids {
+		if cancel := q.running[id]; cancel != nil {
+			cancel()
+		}
+	}
+}
+
+// extendLeases 为执行中的任务续租；租约已被回收（如续租中断超过租期后被其他进程领取）的任务立即取消
+func (q *Queue) extendLeases() {
+	ids := q.runningIDs()
+	if _, err := q.repo.ExtendLeases(q.owner, ids, time.Now().Add(queueLeaseDuration)); err != nil {
+		queueLog.Warnf("Failed to extend job leases: %v", err)
+		return
+	}
+	held, err := q.repo.HeldLeases(q.owner, ids)
+	if err != nil {
+		queueLog.Warnf("Failed to check job leases: %v", err)
+		return
+	}
+	var lost []uint
+	for _, id := range ids {
+		if !slices.Contains(held, id) {
+			lost = append(lost, id)
+		}
+	}
+	if len(lost) > 0 {
+		queueLog.Warnf("Lost leases of %d running jobs, cancelling them", len(lost))
+		q.cancelRunning(lost)
+	}
+}
+
+func (q *Queue) prune() {
+	n, err := q.repo.DeleteCompletedBefore(time.Now().Add(-queueCompletedRetain))
+	if err != nil {
+		queueLog.Warnf("Failed to prune completed jobs: %v", err)
+		return
+	}
+	if n > 0 {
+		queueLog.Debugf("Pruned %d completed jobs", n)
+	}
+}
+
+// retryDelay 指数退避：10s、20s、40s……上限 10 分钟
+func retryDelay(attempts int) time.Duration {
+	delay := queueRetryBaseDelay
+	for i := 1; i < attempts && delay < queueRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, queueRetryMaxDelay)
+}
+
+// DecodePayload 解析任务负载，失败时返回不可重试错误
+func DecodePayload(job *models.Job, v any) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return PermanentError(fmt.Errorf("decode %s payload: %w", job.Type, err))
+	}
+	return nil
+}
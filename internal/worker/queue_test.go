@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/database/repo/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestQueueRetriesThenCompletes(t *testing.T) {
+	shortenQueueTimings(t)
+	queue, repo := newTestQueue(t)
+
+	var calls atomic.Int32
+	queue.Register("flaky", func(ctx context.Context, job *models.Job) error {
+		var payload struct{ Name string }
+		require.NoError(t, DecodePayload(job, &payload))
+		assert.Equal(t, "demo", payload.Name)
+		if calls.Add(1) == 1 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+	queue.Start()
+	defer queue.Stop()
+
+	job, err := queue.Enqueue(context.Background(), "flaky", map[string]string{"Name": "demo"}, EnqueueOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		stored, err := repo.GetByID(job.ID)
+		return err == nil && stored.Status == models.JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stored, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Empty(t, stored.LeaseOwner)
+	assert.Equal(t, uint64(1), queue.Stats().Retried)
+}
+
+func TestQueuePermanentErrorFailsImmediately(t *testing.T) {
+	shortenQueueTimings(t)
+	queue, repo := newTestQueue(t)
+
+	queue.Register("broken", func(ctx context.Context, job *models.Job) error {
+		return PermanentError(errors.New("bad payload"))
+	})
+	queue.Start()
+	defer queue.Stop()
+
+	job, err := queue.Enqueue(context.Background(), "broken", nil, EnqueueOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		stored, err := repo.GetByID(job.ID)
+		return err == nil && stored.Status == models.JobStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stored, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, "bad payload", stored.LastError)
+}
+
+func TestQueueCancelsJobsThatLoseTheirLease(t *testing.T) {
+	shortenQueueTimings(t)
+	queue, repo := newTestQueue(t)
+
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	queue.Register("slow", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return ctx.Err()
+	})
+
+	job, err := queue.Enqueue(context.Background(), "slow", nil, EnqueueOptions{})
+	require.NoError(t, err)
+	queue.dispatch()
+	<-started
+
+	queue.extendLeases()
+	select {
+	case <-cancelled:
+		t.Fatal("a job holding its lease must keep running")
+	default:
+	}
+
+	// 模拟租约过期后被其他进程领取
+	_, err = repo.ReleaseLeases(queue.owner, []uint{job.ID})
+	require.NoError(t, err)
+	queue.extendLeases()
+	select {
+	case err := <-cancelled:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not cancelled after losing its lease")
+	}
+}
+
+func TestQueueIgnoresUnregisteredTypes(t *testing.T) {
+	shortenQueueTimings(t)
+	queue, repo := newTestQueue(t)
+	queue.Register("known", func(ctx context.Context, job *models.Job) error { return nil })
+
+	other := &models.Job{Type: "unknown"}
+	require.NoError(t, repo.Enqueue(other))
+
+	queue.dispatch()
+
+	stored, err := repo.GetByID(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+}
+
+func TestRetryDelayBacksOffExponentially(t *testing.T) {
+	assert.Equal(t, queueRetryBaseDelay, retryDelay(1))
+	assert.Equal(t, 2*queueRetryBaseDelay, retryDelay(2))
+	assert.Equal(t, 4*queueRetryBaseDelay, retryDelay(3))
+	assert.Equal(t, queueRetryMaxDelay, retryDelay(50))
+}
+
+func shortenQueueTimings(t *testing.T) {
+	t.Helper()
+	origPoll, origBase := queuePollInterval, queueRetryBaseDelay
+	queuePollInterval = 20 * time.Millisecond
+	queueRetryBaseDelay = 10 * time.Millisecond
+	t.Cleanup(func() {
+		queuePollInterval = origPoll
+		queueRetryBaseDelay = origBase
+	})
+}
+
+func newTestQueue(t *testing.T) (*Queue, *jobs.Repository) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=private"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Job{}))
+	// 内存 SQLite 每个连接是独立的数据库
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	pool := NewPool(1, 4)
+	t.Cleanup(pool.Stop)
+
+	repo := jobs.NewRepository(db)
+	return NewQueue(repo, pool), repo
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, IsPermanent(fmt.Errorf("wrapped: %w", PermanentError(errors.New("bad")))))
+	assert.False(t, IsPermanent(errors.New("temporary")))
+	assert.Nil(t, PermanentError(nil))
+}
@@ -0,0 +1,281 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	dbconfig "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/anoixa/image-bed/utils"
+)
+
+// StageFailurePolicy 阶段失败对图片整体状态的影响
+type StageFailurePolicy int
+
+const (
+	// StageRequired 失败时图片整体标记为失败
+	StageRequired StageFailurePolicy = iota
+	// StageFallback 前面已有请求的全尺寸变体阶段时失败只影响本阶段，否则按 StageRequired 处理
+	StageFallback
+	// StageOptional 失败只记录，不影响图片整体状态
+	StageOptional
+)
+
+// Stage 图片流水线中的一个处理步骤
+type Stage interface {
+	// Name 阶段名称，全局唯一，用于依赖声明和执行记录
+	Name() string
+	// Inputs 读取结果的前序阶段，必须先于本阶段注册；前序阶段未产出时对应结果为 nil
+	Inputs() []string
+	// Format 产出的变体格式，为空表示阶段不产生变体（如占位哈希、调色板）
+	Format() string
+	// Enabled 按处理设置判断阶段是否启用，未启用时对应变体按跳过处理
+	Enabled(settings *dbconfig.ImageProcessingSettings) bool
+	// FailurePolicy 失败策略
+	FailurePolicy() StageFailurePolicy
+	// Run 执行阶段，返回 nil 结果表示跳过；产生变体的阶段需自行把文件写入 in.Storage
+	Run(ctx context.Context, in *StageInput) (*StageOutput, error)
+}
+
+// StageOutput 阶段产出，变体阶段填写文件信息，其他阶段通过 Data 返回结果
+type StageOutput struct {
+	StoragePath string         `json:"storage_path,omitempty"`
+	Width       int            `json:"width,omitempty"`
+	Height      int            `json:"height,omitempty"`
+	FileSize    int64          `json:"file_size,omitempty"`
+	FileHash    string         `json:"file_hash,omitempty"`
+	Data        map[string]any `json:"data,omitempty"`
+}
+
+// StageInput 阶段执行时可读取的图片信息和前序阶段结果
+type StageInput struct {
+	ImageID     uint
+	Identifier  string
+	StoragePath string
+	MimeType    string
+	FileSize    int64
+	FilePath    string             // 原图本地路径，只读
+	Info        vipsfile.ImageInfo // 文件头探测结果
+	Settings    *dbconfig.ImageProcessingSettings
+	Storage     storage.Provider
+
+	task    *ImagePipelineTask
+	plan    animationPlan
+	results map[string]*StageOutput
+
+	// 全尺寸内置阶段共用一次解码
+	source     sourceImage
+	sourceInfo vipsfile.ImageInfo
+	sourceErr  error
+	sourceOpen bool
+}
+
+// Result 返回前序阶段的产出，阶段未执行、跳过或失败时返回 nil
+func (in *StageInput) Result(stage string) *StageOutput {
+	return in.results[stage]
+}
+
+// openSource 首次调用时打开原图，后续调用复用同一解码结果
+func (in *StageInput) openSource(ctx context.Context) (sourceImage, vipsfile.ImageInfo, error) {
+	if !in.sourceOpen {
+		in.sourceOpen = true
+		importOpts := vipsfile.DefaultImportOptions()
+		if in.plan.Preserve {
+			importOpts = vipsfile.AnimatedImportOptions()
+		}
+		in.source, in.sourceInfo, in.sourceErr = activeVipsEngine.Open(ctx, in.FilePath, importOpts)
+	}
+	if in.sourceErr != nil {
+		return nil, vipsfile.ImageInfo{}, fmt.Errorf("load image: %w", in.sourceErr)
+	}
+	return in.source, in.sourceInfo, nil
+}
+
+func (in *StageInput) close() {
+	if in.source != nil {
+		in.source.Close()
+		in.source = nil
+	}
+}
+
+// 内置阶段名称
+const (
+	StageThumbnail = "thumbnail"
+	StageWebP      = "webp"
+	StageAVIF      = "avif"
+	StageJXL       = "jxl"
+)
+
+// builtinStages 内置的变体阶段，执行顺序固定在所有注册阶段之前
+var builtinStages = []Stage{thumbnailStage{}, webpStage{}, avifStage{}, jxlStage{}}
+
+var (
+	stageRegistryMu sync.RWMutex
+	stageRegistry   []Stage
+)
+
+// RegisterStage 注册扩展阶段，按注册顺序在内置阶段之后执行。
+// 名称重复或依赖未注册的阶段时 panic，应在 init 或启动时调用
+func RegisterStage(stage Stage) {
+	stageRegistryMu.Lock()
+	defer stageRegistryMu.Unlock()
+
+	known := make([]string, 0, len(builtinStages)+len(stageRegistry))
+	for _, s := range slices.Concat(builtinStages, stageRegistry) {
+		known = append(known, s.Name())
+	}
+	if stage.Name() == "" || slices.Contains(known, stage.Name()) {
+		panic(fmt.Sprintf("worker: stage %q already registered", stage.Name()))
+	}
+	for _, input := range stage.Inputs() {
+		if !slices.Contains(known, input) {
+			panic(fmt.Sprintf("worker: stage %q depends on unregistered stage %q", stage.Name(), input))
+		}
+	}
+	stageRegistry = append(stageRegistry, stage)
+}
+
+// ExtensionStages 返回已注册的扩展阶段
+func ExtensionStages() []Stage {
+	stageRegistryMu.RLock()
+	defer stageRegistryMu.RUnlock()
+	return slices.Clone(stageRegistry)
+}
+
+// pipelineStages 返回按执行顺序排列的全部阶段
+func pipelineStages() []Stage {
+	return slices.Concat(builtinStages, ExtensionStages())
+}
+
+// isFullSizeStage 阶段是否产出全尺寸变体（不保留动画时跳过）
+func isFullSizeStage(stage Stage) bool {
+	return stage.Format() != "" && stage.Format() != models.FormatThumbnail
+}
+
+type thumbnailStage struct{}
+
+func (thumbnailStage) Name() string                      { return StageThumbnail }
+func (thumbnailStage) Inputs() []string                  { return nil }
+func (thumbnailStage) Format() string                    { return models.FormatThumbnail }
+func (thumbnailStage) FailurePolicy() StageFailurePolicy { return StageRequired }
+
+func (thumbnailStage) Enabled(settings *dbconfig.ImageProcessingSettings) bool {
+	return settings.ThumbnailEnabled
+}
+
+func (thumbnailStage) Run(ctx context.Context, in *StageInput) (*StageOutput, error) {
+	return in.task.generateThumbnail(ctx, in.FilePath, in.plan)
+}
+
+type webpStage struct{}
+
+func (webpStage) Name() string                      { return StageWebP }
+func (webpStage) Inputs() []string                  { return nil }
+func (webpStage) Format() string                    { return models.FormatWebP }
+func (webpStage) FailurePolicy() StageFailurePolicy { return StageRequired }
+
+// Enabled 浏览器无法显示的原图始终需要 WebP 交付变体，格式开关在 Run 中结合原图类型判断
+func (webpStage) Enabled(*dbconfig.ImageProcessingSettings) bool { return true }
+
+func (webpStage) Run(ctx context.Context, in *StageInput) (*StageOutput, error) {
+	if !in.Settings.IsFormatEnabled(models.FormatWebP) && !utils.RequiresDeliveryVariant(in.MimeType) {
+		return nil, nil
+	}
+	img, info, err := in.openSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return in.task.generateWebP(ctx, in.FilePath, img, info)
+}
+
+type avifStage struct{}
+
+func (avifStage) Name() string                      { return StageAVIF }
+func (avifStage) Inputs() []string                  { return []string{StageWebP} }
+func (avifStage) Format() string                    { return models.FormatAVIF }
+func (avifStage) FailurePolicy() StageFailurePolicy { return StageFallback }
+
+func (avifStage) Enabled(settings *dbconfig.ImageProcessingSettings) bool {
+	return settings.IsFormatEnabled(models.FormatAVIF)
+}
+
+func (avifStage) Run(ctx context.Context, in *StageInput) (*StageOutput, error) {
+	img, info, err := in.openSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return in.task.generateAVIF(ctx, in.FilePath, in.Result(StageWebP), img, info)
+}
+
+type jxlStage struct{}
+
+func (jxlStage) Name() string                      { return StageJXL }
+func (jxlStage) Inputs() []string                  { return []string{StageWebP, StageAVIF} }
+func (jxlStage) Format() string                    { return models.FormatJXL }
+func (jxlStage) FailurePolicy() StageFailurePolicy { return StageFallback }
+
+func (jxlStage) Enabled(settings *dbconfig.ImageProcessingSettings) bool {
+	return settings.IsFormatEnabled(models.FormatJXL)
+}
+
+func (jxlStage) Run(ctx context.Context, in *StageInput) (*StageOutput, error) {
+	img, info, err := in.openSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return in.task.generateJXL(ctx, in.FilePath, smallestResult(in.Result(StageWebP), in.Result(StageAVIF)), img, info)
+}
+
+// stageRun 阶段在一次任务中的执行情况
+type stageRun struct {
+	stage     Stage
+	variantID uint
+	required  bool // 失败是否导致图片整体失败
+	output    *StageOutput
+	skipped   bool
+	err       error
+	duration  time.Duration
+}
+
+func (r *stageRun) record() models.ImageStageRun {
+	rec := models.ImageStageRun{
+		Stage:      r.stage.Name(),
+		Status:     models.StageRunSkipped,
+		DurationMs: r.duration.Milliseconds(),
+	}
+	switch {
+	case r.err != nil:
+		rec.Status = models.StageRunFailed
+		rec.Error = r.err.Error()
+	case r.output != nil:
+		rec.Status = models.StageRunCompleted
+		if data, err := json.Marshal(r.output); err == nil {
+			rec.Output = string(data)
+		}
+	}
+	return rec
+}
+
+// stageOutcomes 汇总缩略图和全尺寸变体阶段的结果，用于计算图片整体状态；
+// 失败不影响整体状态的阶段（StageOptional、已有全尺寸变体时的 StageFallback）不计入
+func stageOutcomes(runs []*stageRun) (thumb variantOutcome, full []variantOutcome) {
+	for _, run := range runs {
+		if !run.required {
+			continue
+		}
+		outcome := variantOutcome{Requested: true, Completed: run.output != nil, Skipped: run.skipped}
+		switch {
+		case run.stage.Format() == models.FormatThumbnail:
+			thumb = outcome
+		case isFullSizeStage(run.stage):
+			full = append(full, outcome)
+		}
+	}
+	return thumb, full
+}
@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dbconfig "github.com/anoixa/image-bed/config/db"
+	"github.com/anoixa/image-bed/database/models"
+	"github.com/anoixa/image-bed/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStage 可配置的扩展阶段
+type testStage struct {
+	name     string
+	inputs   []string
+	format   string
+	policy   StageFailurePolicy
+	disabled bool
+	run      func(in *StageInput) (*StageOutput, error)
+}
+
+func (s testStage) Name() string                                                { return s.name }
+func (s testStage) Inputs() []string                                            { return s.inputs }
+func (s testStage) Format() string                                              { return s.format }
+func (s testStage) Enabled(*dbconfig.ImageProcessingSettings) bool              { return !s.disabled }
+func (s testStage) FailurePolicy() StageFailurePolicy                           { return s.policy }
+func (s testStage) Run(_ context.Context, in *StageInput) (*StageOutput, error) { return s.run(in) }
+
+type recordingStageRuns struct {
+	runs []models.ImageStageRun
+}
+
+func (r *recordingStageRuns) SaveStageRuns(_ uint, runs []models.ImageStageRun) error {
+	r.runs = append(r.runs, runs...)
+	return nil
+}
+
+// useStages 在测试期间替换扩展阶段注册表
+func useStages(t *testing.T, stages ...Stage) {
+	t.Helper()
+	stageRegistryMu.Lock()
+	orig := stageRegistry
+	stageRegistry = nil
+	stageRegistryMu.Unlock()
+	t.Cleanup(func() {
+		stageRegistryMu.Lock()
+		stageRegistry = orig
+		stageRegistryMu.Unlock()
+	})
+	for _, stage := range stages {
+		RegisterStage(stage)
+	}
+}
+
+func newStageTestTask(t *testing.T, stageVariants map[string]uint) (*ImagePipelineTask, *mockVariantRepo, *mockImageRepo, *recordingStageRuns) {
+	t.Helper()
+	origEngine := activeVipsEngine
+	activeVipsEngine = fakeVipsEngine{}
+	t.Cleanup(func() { activeVipsEngine = origEngine })
+
+	dir := t.TempDir()
+	ls, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "img"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "img", "a.png"), []byte("fakepng"), 0600))
+
+	variantRepo := &mockVariantRepo{}
+	imageRepo := &mockImageRepo{}
+	recorder := &recordingStageRuns{}
+	return &ImagePipelineTask{
+		StageVariants:   stageVariants,
+		ImageID:         1,
+		StoragePath:     "img/a.png",
+		ImageIdentifier: "a",
+		MimeType:        "image/png",
+		Storage:         ls,
+		Settings:        dbconfig.DefaultImageProcessingSettings(),
+		VariantRepo:     variantRepo,
+		ImageRepo:       imageRepo,
+		StageRuns:       recorder,
+	}, variantRepo, imageRepo, recorder
+}
+
+func TestRegisterStageRejectsDuplicatesAndUnknownInputs(t *testing.T) {
+	useStages(t, testStage{name: "phash", inputs: []string{StageThumbnail}})
+
+	assert.PanicsWithValue(t, `worker: stage "webp" already registered`, func() {
+		RegisterStage(testStage{name: StageWebP})
+	})
+	assert.PanicsWithValue(t, `worker: stage "phash" already registered`, func() {
+		RegisterStage(testStage{name: "phash"})
+	})
+	assert.PanicsWithValue(t, `worker: stage "palette" depends on unregistered stage "moderation"`, func() {
+		RegisterStage(testStage{name: "palette", inputs: []string{"moderation"}})
+	})
+
+	stages := pipelineStages()
+	require.Len(t, stages, 5)
+	assert.Equal(t, "phash", stages[4].Name(), "extension stages run after the builtin stages")
+}
+
+func TestRunPipelineRunsExtensionStages(t *testing.T) {
+	var sawWatermark *StageOutput
+	useStages(t,
+		testStage{name: "watermark", format: "watermarked", policy: StageRequired, run: func(in *StageInput) (*StageOutput, error) {
+			return &StageOutput{StoragePath: "watermarked/a.webp", Width: 640, Height: 480, FileSize: 10}, nil
+		}},
+		testStage{name: "palette", inputs: []string{"watermark"}, policy: StageOptional, run: func(in *StageInput) (*StageOutput, error) {
+			sawWatermark = in.Result("watermark")
+			return &StageOutput{Data: map[string]any{"dominant": "#ff0000"}}, nil
+		}},
+		testStage{name: "moderation", policy: StageOptional, run: func(*StageInput) (*StageOutput, error) {
+			return nil, errors.New("classifier offline")
+		}},
+		testStage{name: "phash", policy: StageRequired, disabled: true, run: func(*StageInput) (*StageOutput, error) {
+			t.Fatal("disabled stage must not run")
+			return nil, nil
+		}},
+	)
+	task, variantRepo, imageRepo, recorder := newStageTestTask(t, map[string]uint{"watermark": 5})
+	acquired := []uint{5}
+
+	require.NoError(t, task.runPipeline(context.Background(), &acquired))
+
+	assert.Equal(t, []uint{5}, variantRepo.completedCalls)
+	assert.Empty(t, acquired)
+	require.NotNil(t, sawWatermark)
+	assert.Equal(t, "watermarked/a.webp", sawWatermark.StoragePath)
+	assert.Equal(t, []models.ImageVariantStatus{models.ImageVariantStatusCompleted}, imageRepo.statuses,
+		"optional stage failures do not fail the image")
+
+	require.Len(t, recorder.runs, 4)
+	byStage := make(map[string]models.ImageStageRun)
+	for _, run := range recorder.runs {
+		byStage[run.Stage] = run
+		assert.GreaterOrEqual(t, run.DurationMs, int64(0))
+	}
+	assert.Equal(t, models.StageRunCompleted, byStage["watermark"].Status)
+	assert.JSONEq(t, `{"storage_path":"watermarked/a.webp","width":640,"height":480,"file_size":10}`, byStage["watermark"].Output)
+	assert.JSONEq(t, `{"data":{"dominant":"#ff0000"}}`, byStage["palette"].Output)
+	assert.Equal(t, models.StageRunFailed, byStage["moderation"].Status)
+	assert.Equal(t, "classifier offline", byStage["moderation"].Error)
+	assert.Equal(t, models.StageRunSkipped, byStage["phash"].Status)
+}
+
+func TestRunPipelineOptionalVariantStageDoesNotCountTowardsImageStatus(t *testing.T) {
+	useStages(t,
+		testStage{name: "preview", format: "preview", policy: StageOptional, run: func(*StageInput) (*StageOutput, error) {
+			return &StageOutput{StoragePath: "preview/a.webp", FileSize: 10}, nil
+		}},
+		testStage{name: "heic", format: "heic", policy: StageFallback, run: func(*StageInput) (*StageOutput, error) {
+			return nil, errors.New("encoder crashed")
+		}},
+	)
+
+	task, variantRepo, imageRepo, _ := newStageTestTask(t, map[string]uint{"preview": 8, "heic": 9})
+	acquired := []uint{8, 9}
+
+	require.Error(t, task.runPipeline(context.Background(), &acquired),
+		"an optional variant is no fallback for a failed required one")
+	assert.Equal(t, []uint{8}, variantRepo.completedCalls)
+	assert.Contains(t, variantRepo.updateFailedCalls, uint(9))
+	assert.Empty(t, imageRepo.statuses)
+}
+
+func TestRunPipelineFallbackStageFailsImageWithoutFallback(t *testing.T) {
+	failing := func(*StageInput) (*StageOutput, error) { return nil, errors.New("encoder crashed") }
+	useStages(t, testStage{name: "heic", format: "heic", policy: StageFallback, run: failing})
+
+	task, variantRepo, imageRepo, recorder := newStageTestTask(t, map[string]uint{"heic": 9})
+	acquired := []uint{9}
+
+	require.Error(t, task.runPipeline(context.Background(), &acquired))
+	assert.Contains(t, variantRepo.updateFailedCalls, uint(9))
+	assert.Empty(t, imageRepo.statuses)
+	require.Len(t, recorder.runs, 1)
+	assert.Equal(t, models.StageRunFailed, recorder.runs[0].Status)
+}
+
+func TestStageOutcomes(t *testing.T) {
+	runs := []*stageRun{
+		{stage: thumbnailStage{}, required: true, output: &StageOutput{}},
+		{stage: webpStage{}, required: true, skipped: true},
+		{stage: testStage{name: "palette"}, required: true, output: &StageOutput{}},
+		{stage: testStage{name: "watermark", format: "watermarked"}, required: true, err: errors.New("boom")},
+		{stage: avifStage{}, err: errors.New("encoder crashed")},
+		{stage: testStage{name: "heic", format: "heic", policy: StageOptional}, err: errors.New("boom")},
+	}
+
+	thumb, full := stageOutcomes(runs)
+	assert.Equal(t, variantOutcome{Requested: true, Completed: true}, thumb)
+	assert.Equal(t, []variantOutcome{
+		{Requested: true, Skipped: true},
+		{Requested: true},
+	}, full, "stages without a variant or whose failure is tolerated do not count towards the image status")
+}
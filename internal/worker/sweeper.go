@@ -8,6 +8,7 @@ import (
 	"github.com/anoixa/image-bed/database/models"
 	"github.com/anoixa/image-bed/database/repo/images"
 	"github.com/anoixa/image-bed/utils"
+	"gorm.io/gorm"
 )
 
 const sweeperInterval = 5 * time.Minute
@@ -47,10 +48,18 @@ var sweeperStats = struct {
 // TriggerFunc re-enqueues an image for variant processing.
 type TriggerFunc func(image *models.Image)
 
+// ActiveImagesFunc returns a subquery selecting images that still have a
+// pending or running pipeline job in the jobs table, or nil if there is no
+// job queue. A subquery keeps large backlogs out of the bound parameters.
+type ActiveImagesFunc func(ctx context.Context) *gorm.DB
+
 // StartVariantSweeper runs a background goroutine that periodically resets
 // stale processing variants back to pending so they can be retried.
 // If triggerFn is non-nil, images with reset variants are re-submitted for processing.
-func StartVariantSweeper(ctx context.Context, variantRepo *images.VariantRepository, imageRepo *images.Repository, triggerFn TriggerFunc) {
+// Pipeline jobs interrupted by a crash are reclaimed by the durable job queue
+// once their lease expires, so images returned by activeFn are left to their
+// job; the sweeper only covers variants whose job is gone.
+func StartVariantSweeper(ctx context.Context, variantRepo *images.VariantRepository, imageRepo *images.Repository, activeFn ActiveImagesFunc, triggerFn TriggerFunc) {
 	go func() {
 		ticker := time.NewTicker(sweeperInterval)
 		defer ticker.Stop()
@@ -63,13 +72,13 @@ func StartVariantSweeper(ctx context.Context, variantRepo *images.VariantReposit
 				sweeperLog.Infof("Stopped")
 				return
 			case <-ticker.C:
-				sweepOnce(ctx, variantRepo, imageRepo, triggerFn)
+				sweepOnce(ctx, variantRepo, imageRepo, activeFn, triggerFn)
 			}
 		}
 	}()
 }
 
-func sweepOnce(ctx context.Context, variantRepo *images.VariantRepository, imageRepo *images.Repository, triggerFn TriggerFunc) {
+func sweepOnce(ctx context.Context, variantRepo *images.VariantRepository, imageRepo *images.Repository, activeFn ActiveImagesFunc, triggerFn TriggerFunc) {
 	start := time.Now()
 	now := start
 	sweeperStats.runs.Add(1)
@@ -78,7 +87,12 @@ func sweepOnce(ctx context.Context, variantRepo *images.VariantRepository, image
 
 	var retriggered uint64
 
-	reset, failed, retriedImageIDs, err := variantRepo.WithContext(ctx).RecoverStaleProcessing(staleThreshold, staleMaxRetries)
+	var activeImages *gorm.DB
+	if activeFn != nil {
+		activeImages = activeFn(ctx)
+	}
+
+	reset, failed, retriedImageIDs, err := variantRepo.WithContext(ctx).RecoverStaleProcessing(staleThreshold, staleMaxRetries, activeImages)
 	if err != nil {
 		recordSweeperError(now, err.Error())
 		sweeperLog.Warnf("Failed to reset stale variants: %v", err)
@@ -109,8 +123,9 @@ func sweepOnce(ctx context.Context, variantRepo *images.VariantRepository, image
 
 	// Images that are no longer processing and have at least one failed
 	// variant should surface as failed rather than silently reverting to none.
+	// Images whose job is still queued or running keep their status.
 	repoWithCtx := imageRepo.WithContext(ctx)
-	failedRows, err := repoWithCtx.MarkStaleProcessingAsFailed(cutoff, retriedImageIDs)
+	failedRows, err := repoWithCtx.MarkStaleProcessingAsFailed(cutoff, retriedImageIDs, activeImages)
 	if err != nil {
 		recordSweeperError(now, err.Error())
 		sweeperLog.Warnf("Failed to mark stale processing images as failed: %v", err)
@@ -120,7 +135,7 @@ func sweepOnce(ctx context.Context, variantRepo *images.VariantRepository, image
 
 	// Remaining stale images without failed variants can return to none and be
 	// retriggered on demand.
-	resetRows, err := repoWithCtx.ResetStaleProcessingToNone(cutoff, retriedImageIDs)
+	resetRows, err := repoWithCtx.ResetStaleProcessingToNone(cutoff, retriedImageIDs, activeImages)
 	if err != nil {
 		recordSweeperError(now, err.Error())
 		sweeperLog.Warnf("Failed to reset stale image variant_status: %v", err)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/anoixa/image-bed/database/models"
 	repoimages "github.com/anoixa/image-bed/database/repo/images"
+	"github.com/anoixa/image-bed/database/repo/jobs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
@@ -45,7 +46,7 @@ func TestSweepOnceRetriggeredImagesAreNotResetByBulkImageUpdates(t *testing.T) {
 	require.NoError(t, db.Create(variant).Error)
 
 	var triggerCalls atomic.Int32
-	sweepOnce(context.Background(), variantRepo, imageRepo, func(img *models.Image) {
+	sweepOnce(context.Background(), variantRepo, imageRepo, nil, func(img *models.Image) {
 		require.Equal(t, image.ID, img.ID)
 		triggerCalls.Add(1)
 	})
@@ -94,7 +95,7 @@ func TestSweepOnceMarksImagesFailedWhenRecoveredVariantHitsRetryLimit(t *testing
 	require.NoError(t, db.Create(variant).Error)
 
 	var triggerCalls atomic.Int32
-	sweepOnce(context.Background(), variantRepo, imageRepo, func(*models.Image) {
+	sweepOnce(context.Background(), variantRepo, imageRepo, nil, func(*models.Image) {
 		triggerCalls.Add(1)
 	})
 
@@ -111,6 +112,56 @@ func TestSweepOnceMarksImagesFailedWhenRecoveredVariantHitsRetryLimit(t *testing
 	assert.Equal(t, int32(0), triggerCalls.Load())
 }
 
+func TestSweepOnceLeavesImagesWithActiveJobsToTheQueue(t *testing.T) {
+	db := setupSweeperTestDB(t)
+	imageRepo := repoimages.NewRepository(db)
+	variantRepo := repoimages.NewVariantRepository(db)
+
+	staleTime := time.Now().Add(-20 * time.Minute)
+	image := &models.Image{
+		Identifier:    "sweeper-active",
+		OriginalName:  "active.jpg",
+		FileHash:      "sweeper-active-hash",
+		StoragePath:   "original/active.jpg",
+		MimeType:      "image/jpeg",
+		UserID:        1,
+		VariantStatus: models.ImageVariantStatusProcessing,
+		CreatedAt:     staleTime,
+		UpdatedAt:     staleTime,
+	}
+	require.NoError(t, imageRepo.SaveImage(image))
+
+	variant := &models.ImageVariant{
+		ImageID:    image.ID,
+		Format:     models.FormatWebP,
+		Status:     models.VariantStatusProcessing,
+		CreatedAt:  staleTime,
+		UpdatedAt:  staleTime,
+		Identifier: "active.webp",
+	}
+	require.NoError(t, db.Create(variant).Error)
+
+	jobsRepo := jobs.NewRepository(db)
+	require.NoError(t, jobsRepo.Enqueue(&models.Job{Type: "variant_pipeline", SubjectID: image.ID}))
+	activeFn := func(ctx context.Context) *gorm.DB {
+		return jobsRepo.WithContext(ctx).ActiveSubjectsQuery("variant_pipeline")
+	}
+	var triggerCalls atomic.Int32
+	sweepOnce(context.Background(), variantRepo, imageRepo, activeFn, func(*models.Image) {
+		triggerCalls.Add(1)
+	})
+
+	updatedVariant, err := variantRepo.GetByID(variant.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VariantStatusProcessing, updatedVariant.Status)
+	assert.Zero(t, updatedVariant.RetryCount)
+
+	updatedImage, err := imageRepo.GetImageByID(image.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ImageVariantStatusProcessing, updatedImage.VariantStatus)
+	assert.Equal(t, int32(0), triggerCalls.Load())
+}
+
 func setupSweeperTestDB(t *testing.T) *gorm.DB {
 	t.Helper()
 
@@ -118,6 +169,6 @@ func setupSweeperTestDB(t *testing.T) *gorm.DB {
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.ImageVariant{}))
+	require.NoError(t, db.AutoMigrate(&models.Image{}, &models.ImageVariant{}, &models.Job{}))
 	return db
 }
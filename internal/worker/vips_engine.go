@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/anoixa/image-bed/internal/vipsfile"
+)
+
+// vipsEngine 流水线使用的 libvips 操作，默认在进程内执行，启用隔离后交给子进程
+type vipsEngine interface {
+	// Probe 只读取文件头信息
+	Probe(ctx context.Context, path string, opts vipsfile.ImportOptions) (vipsfile.ImageInfo, error)
+	// Thumbnail 生成 WebP 缩略图到 dst
+	Thumbnail(ctx context.Context, src, dst string, thumb vipsfile.ThumbnailOptions, importOpts vipsfile.ImportOptions, webp vipsfile.WebPOptions) (vipsfile.ImageInfo, error)
+	// Open 打开原图，供多个全尺寸格式共用
+	Open(ctx context.Context, src string, opts vipsfile.ImportOptions) (sourceImage, vipsfile.ImageInfo, error)
+	// Transcode 按上传策略缩小或转换原图到 dst
+	Transcode(ctx context.Context, src, dst string, importOpts vipsfile.ImportOptions, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error)
+}
+
+// sourceImage 已打开的原图
+type sourceImage interface {
+	SaveWebP(ctx context.Context, dst string, opts vipsfile.WebPOptions) error
+	SaveAVIF(ctx context.Context, dst string, opts vipsfile.AVIFOptions) error
+	SaveJXL(ctx context.Context, dst string, opts vipsfile.JXLOptions) error
+	Close()
+}
+
+// activeVipsEngine 当前使用的引擎，只在 Pool 启动前和退出后切换
+var activeVipsEngine vipsEngine = localVipsEngine{}
+
+// TranscodeOriginal 在图片处理并发限制内按上传策略重写原图，启用隔离时在子进程中执行
+func TranscodeOriginal(ctx context.Context, src, dst string, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error) {
+	semaphore := GetGlobalSemaphore()
+	if err := semaphore.Acquire(ctx); err != nil {
+		return vipsfile.ImageInfo{}, err
+	}
+	defer semaphore.Release()
+
+	return activeVipsEngine.Transcode(ctx, src, dst, vipsfile.DefaultImportOptions(), opts)
+}
+
+// localVipsEngine 在当前进程内直接调用 vipsfile
+type localVipsEngine struct{}
+
+func (localVipsEngine) Probe(_ context.Context, path string, opts vipsfile.ImportOptions) (vipsfile.ImageInfo, error) {
+	img, info, err := vipsfile.LoadImageFromFileWithOptions(path, opts)
+	if err != nil {
+		return vipsfile.ImageInfo{}, err
+	}
+	img.Close()
+	return info, nil
+}
+
+func (localVipsEngine) Thumbnail(_ context.Context, src, dst string, thumb vipsfile.ThumbnailOptions, importOpts vipsfile.ImportOptions, webp vipsfile.WebPOptions) (vipsfile.ImageInfo, error) {
+	return vipsfile.ThumbnailFileToWebPWithOptions(src, dst, thumb, importOpts, webp)
+}
+
+func (localVipsEngine) Open(_ context.Context, src string, opts vipsfile.ImportOptions) (sourceImage, vipsfile.ImageInfo, error) {
+	img, info, err := vipsfile.LoadImageFromFileWithOptions(src, opts)
+	if err != nil {
+		return nil, vipsfile.ImageInfo{}, err
+	}
+	return localSourceImage{handle: img}, info, nil
+}
+
+func (localVipsEngine) Transcode(_ context.Context, src, dst string, importOpts vipsfile.ImportOptions, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error) {
+	return vipsfile.TranscodeFile(src, dst, importOpts, opts)
+}
+
+type localSourceImage struct {
+	handle *vipsfile.ImageHandle
+}
+
+func (s localSourceImage) SaveWebP(_ context.Context, dst string, opts vipsfile.WebPOptions) error {
+	return s.handle.SaveWebPToFile(dst, opts)
+}
+
+func (s localSourceImage) SaveAVIF(_ context.Context, dst string, opts vipsfile.AVIFOptions) error {
+	return s.handle.SaveAVIFToFile(dst, opts)
+}
+
+func (s localSourceImage) SaveJXL(_ context.Context, dst string, opts vipsfile.JXLOptions) error {
+	return s.handle.SaveJXLToFile(dst, opts)
+}
+
+func (s localSourceImage) Close() {
+	s.handle.Close()
+}
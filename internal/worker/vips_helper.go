@@ -0,0 +1,485 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/anoixa/image-bed/utils"
+	"github.com/anoixa/image-bed/utils/pool"
+)
+
+var vipsHelperLog = utils.ForModule("VipsHelper")
+
+// VipsHelperCommand 子进程使用的隐藏子命令名
+const VipsHelperCommand = "vips-helper"
+
+// 子进程通过继承的文件描述符收发请求，stdout/stderr 留给日志和 libvips 警告
+const (
+	helperRequestFD  = 3
+	helperResponseFD = 4
+)
+
+// helperMaxRequests 子进程处理一定数量请求后退役，限制 libvips 内存碎片累积
+var helperMaxRequests = 500
+
+// helperStopTimeout 关闭时等待子进程自行退出的时长
+var helperStopTimeout = 2 * time.Second
+
+// ErrVipsHelperCrashed 子进程在处理请求时退出（段错误、内存超限等）
+var ErrVipsHelperCrashed = errors.New("vips helper crashed")
+
+// 子进程支持的操作
+const (
+	helperOpProbe     = "probe"
+	helperOpThumbnail = "thumbnail"
+	helperOpWebP      = "webp"
+	helperOpAVIF      = "avif"
+	helperOpJXL       = "jxl"
+	helperOpTranscode = "transcode"
+)
+
+type helperRequest struct {
+	Op        string                     `json:"op"`
+	Src       string                     `json:"src"`
+	Dst       string                     `json:"dst,omitempty"`
+	Import    vipsfile.ImportOptions     `json:"import"`
+	Thumbnail *vipsfile.ThumbnailOptions `json:"thumbnail,omitempty"`
+	WebP      *vipsfile.WebPOptions      `json:"webp,omitempty"`
+	AVIF      *vipsfile.AVIFOptions      `json:"avif,omitempty"`
+	JXL       *vipsfile.JXLOptions       `json:"jxl,omitempty"`
+	Transcode *vipsfile.TranscodeOptions `json:"transcode,omitempty"`
+}
+
+type helperResponse struct {
+	Info  vipsfile.ImageInfo `json:"info"`
+	Error string             `json:"error,omitempty"`
+}
+
+// VipsIsolationOptions 子进程隔离配置
+type VipsIsolationOptions struct {
+	Processes     int           // 子进程上限，默认与图片处理并发数一致
+	MemoryLimitMB int           // 每个子进程的数据段上限，0 表示不限制
+	Timeout       time.Duration // 单次操作超时，超时后杀掉子进程
+}
+
+// EnableVipsIsolation 将流水线中的 libvips 操作交给子进程执行，需在 Pool 开始处理任务前调用
+func EnableVipsIsolation(opts VipsIsolationOptions) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve executable: %w", err)
+	}
+	if opts.Processes <= 0 {
+		opts.Processes = GetGlobalSemaphore().config.MaxConcurrentImages
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Minute
+	}
+
+	memoryLimitMB := opts.MemoryLimitMB
+	activeVipsEngine = &helperEngine{supervisor: newHelperSupervisor(opts, func() *exec.Cmd {
+		return exec.Command(exe, VipsHelperCommand, "--memory-mb", strconv.Itoa(memoryLimitMB))
+	})}
+	vipsHelperLog.Infof("libvips isolation enabled: processes=%d memory_limit=%dMB timeout=%s",
+		opts.Processes, opts.MemoryLimitMB, opts.Timeout)
+	return nil
+}
+
+// DisableVipsIsolation 停止所有子进程并恢复进程内处理，需在 Pool 停止后调用
+func DisableVipsIsolation() {
+	engine, ok := activeVipsEngine.(*helperEngine)
+	if !ok {
+		return
+	}
+	activeVipsEngine = localVipsEngine{}
+	engine.supervisor.Close()
+}
+
+// RunVipsHelper 子进程入口：限制自身内存后循环处理父进程的请求，直到请求管道关闭
+// 调用前需要完成 vipsfile.Startup
+func RunVipsHelper(memoryLimitMB int) error {
+	if memoryLimitMB > 0 {
+		if err := pool.LimitDataSegment(int64(memoryLimitMB) * 1024 * 1024); err != nil {
+			vipsHelperLog.Warnf("Failed to apply memory limit: %v", err)
+		}
+	}
+
+	in := os.NewFile(helperRequestFD, "vips-helper-requests")
+	out := os.NewFile(helperResponseFD, "vips-helper-responses")
+	if in == nil || out == nil {
+		return errors.New("vips helper must be started by the image processing supervisor")
+	}
+	defer func() { _ = in.Close() }()
+	defer func() { _ = out.Close() }()
+
+	return serveHelper(in, out, localVipsEngine{})
+}
+
+// serveHelper 逐行读取 JSON 请求并写回结果
+func serveHelper(r io.Reader, w io.Writer, engine vipsEngine) error {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	for {
+		var req helperRequest
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("decode request: %w", err)
+		}
+
+		var resp helperResponse
+		info, err := handleHelperRequest(engine, &req)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Info = info
+		if err := enc.Encode(&resp); err != nil {
+			return fmt.Errorf("encode response: %w", err)
+		}
+	}
+}
+
+func handleHelperRequest(engine vipsEngine, req *helperRequest) (vipsfile.ImageInfo, error) {
+	ctx := context.Background()
+	switch req.Op {
+	case helperOpProbe:
+		return engine.Probe(ctx, req.Src, req.Import)
+	case helperOpThumbnail:
+		if req.Thumbnail == nil || req.WebP == nil {
+			return vipsfile.ImageInfo{}, errors.New("thumbnail request missing options")
+		}
+		return engine.Thumbnail(ctx, req.Src, req.Dst, *req.Thumbnail, req.Import, *req.WebP)
+	case helperOpTranscode:
+		if req.Transcode == nil {
+			return vipsfile.ImageInfo{}, errors.New("transcode request missing options")
+		}
+		return engine.Transcode(ctx, req.Src, req.Dst, req.Import, *req.Transcode)
+	case helperOpWebP, helperOpAVIF, helperOpJXL:
+		img, info, err := engine.Open(ctx, req.Src, req.Import)
+		if err != nil {
+			return vipsfile.ImageInfo{}, err
+		}
+		defer img.Close()
+
+		switch {
+		case req.Op == helperOpWebP && req.WebP != nil:
+			err = img.SaveWebP(ctx, req.Dst, *req.WebP)
+		case req.Op == helperOpAVIF && req.AVIF != nil:
+			err = img.SaveAVIF(ctx, req.Dst, *req.AVIF)
+		case req.Op == helperOpJXL && req.JXL != nil:
+			err = img.SaveJXL(ctx, req.Dst, *req.JXL)
+		default:
+			err = fmt.Errorf("%s request missing options", req.Op)
+		}
+		return info, err
+	default:
+		return vipsfile.ImageInfo{}, fmt.Errorf("unknown operation %q", req.Op)
+	}
+}
+
+// helperEngine 把每个操作转发给子进程；原图在子进程中按需重新解码
+type helperEngine struct {
+	supervisor *helperSupervisor
+}
+
+func (e *helperEngine) Probe(ctx context.Context, path string, opts vipsfile.ImportOptions) (vipsfile.ImageInfo, error) {
+	return e.supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: path, Import: opts})
+}
+
+func (e *helperEngine) Thumbnail(ctx context.Context, src, dst string, thumb vipsfile.ThumbnailOptions, importOpts vipsfile.ImportOptions, webp vipsfile.WebPOptions) (vipsfile.ImageInfo, error) {
+	return e.supervisor.Do(ctx, &helperRequest{
+		Op:        helperOpThumbnail,
+		Src:       src,
+		Dst:       dst,
+		Import:    importOpts,
+		Thumbnail: &thumb,
+		WebP:      &webp,
+	})
+}
+
+func (e *helperEngine) Open(ctx context.Context, src string, opts vipsfile.ImportOptions) (sourceImage, vipsfile.ImageInfo, error) {
+	info, err := e.Probe(ctx, src, opts)
+	if err != nil {
+		return nil, vipsfile.ImageInfo{}, err
+	}
+	return &helperSourceImage{supervisor: e.supervisor, src: src, opts: opts}, info, nil
+}
+
+func (e *helperEngine) Transcode(ctx context.Context, src, dst string, importOpts vipsfile.ImportOptions, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error) {
+	return e.supervisor.Do(ctx, &helperRequest{
+		Op:        helperOpTranscode,
+		Src:       src,
+		Dst:       dst,
+		Import:    importOpts,
+		Transcode: &opts,
+	})
+}
+
+type helperSourceImage struct {
+	supervisor *helperSupervisor
+	src        string
+	opts       vipsfile.ImportOptions
+}
+
+func (s *helperSourceImage) SaveWebP(ctx context.Context, dst string, opts vipsfile.WebPOptions) error {
+	_, err := s.supervisor.Do(ctx, &helperRequest{Op: helperOpWebP, Src: s.src, Dst: dst, Import: s.opts, WebP: &opts})
+	return err
+}
+
+func (s *helperSourceImage) SaveAVIF(ctx context.Context, dst string, opts vipsfile.AVIFOptions) error {
+	_, err := s.supervisor.Do(ctx, &helperRequest{Op: helperOpAVIF, Src: s.src, Dst: dst, Import: s.opts, AVIF: &opts})
+	return err
+}
+
+func (s *helperSourceImage) SaveJXL(ctx context.Context, dst string, opts vipsfile.JXLOptions) error {
+	_, err := s.supervisor.Do(ctx, &helperRequest{Op: helperOpJXL, Src: s.src, Dst: dst, Import: s.opts, JXL: &opts})
+	return err
+}
+
+func (s *helperSourceImage) Close() {}
+
+// helperSupervisor 管理子进程：按需启动，崩溃或超时后丢弃，下次请求时自动补充
+type helperSupervisor struct {
+	opts    VipsIsolationOptions
+	command func() *exec.Cmd
+
+	idle   chan *helperProcess
+	slots  chan struct{}
+	closed atomic.Bool
+
+	mu        sync.Mutex
+	processes map[*helperProcess]struct{}
+}
+
+func newHelperSupervisor(opts VipsIsolationOptions, command func() *exec.Cmd) *helperSupervisor {
+	if opts.Processes <= 0 {
+		opts.Processes = 1
+	}
+	return &helperSupervisor{
+		opts:      opts,
+		command:   command,
+		idle:      make(chan *helperProcess, opts.Processes),
+		slots:     make(chan struct{}, opts.Processes),
+		processes: make(map[*helperProcess]struct{}),
+	}
+}
+
+// Do 在空闲子进程上执行请求；子进程崩溃或超时时返回错误并替换该进程
+func (s *helperSupervisor) Do(ctx context.Context, req *helperRequest) (vipsfile.ImageInfo, error) {
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+
+	proc, err := s.acquire(ctx)
+	if err != nil {
+		return vipsfile.ImageInfo{}, err
+	}
+
+	resp, err := proc.call(ctx, req)
+	if err != nil {
+		s.discard(proc, true)
+		if ctx.Err() != nil {
+			vipsHelperLog.Warnf("Killed vips helper after %s on %s: %v", req.Op, req.Src, ctx.Err())
+			return vipsfile.ImageInfo{}, fmt.Errorf("vips helper %s: %w", req.Op, ctx.Err())
+		}
+		vipsHelperLog.Warnf("Vips helper exited during %s on %s: %v", req.Op, req.Src, err)
+		return vipsfile.ImageInfo{}, fmt.Errorf("%w during %s: %v", ErrVipsHelperCrashed, req.Op, err)
+	}
+
+	s.release(proc)
+	if resp.Error != "" {
+		return resp.Info, errors.New(resp.Error)
+	}
+	return resp.Info, nil
+}
+
+// Close 停止所有子进程
+func (s *helperSupervisor) Close() {
+	if !s.closed.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.mu.Lock()
+	procs := make([]*helperProcess, 0, len(s.processes))
+	for proc := range s.processes {
+		procs = append(procs, proc)
+	}
+	s.processes = make(map[*helperProcess]struct{})
+	s.mu.Unlock()
+
+	for _, proc := range procs {
+		proc.stop(false)
+	}
+}
+
+func (s *helperSupervisor) acquire(ctx context.Context) (*helperProcess, error) {
+	if s.closed.Load() {
+		return nil, errors.New("vips helper supervisor closed")
+	}
+
+	select {
+	case proc := <-s.idle:
+		return proc, nil
+	default:
+	}
+
+	select {
+	case proc := <-s.idle:
+		return proc, nil
+	case s.slots <- struct{}{}:
+		proc, err := startHelperProcess(s.command())
+		if err != nil {
+			<-s.slots
+			return nil, fmt.Errorf("start vips helper: %w", err)
+		}
+		s.mu.Lock()
+		s.processes[proc] = struct{}{}
+		s.mu.Unlock()
+		return proc, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *helperSupervisor) release(proc *helperProcess) {
+	if s.closed.Load() || proc.calls >= helperMaxRequests {
+		s.discard(proc, false)
+		return
+	}
+	s.idle <- proc
+}
+
+// discard 移除子进程；force 为 true 时直接结束进程，不等待其自行退出
+func (s *helperSupervisor) discard(proc *helperProcess, force bool) {
+	s.mu.Lock()
+	_, tracked := s.processes[proc]
+	delete(s.processes, proc)
+	s.mu.Unlock()
+
+	proc.stop(force)
+	if tracked {
+		<-s.slots
+	}
+}
+
+// helperProcess 单个子进程及其请求/响应管道
+type helperProcess struct {
+	cmd      *exec.Cmd
+	requests io.WriteCloser
+	enc      *json.Encoder
+	dec      *json.Decoder
+
+	calls    int
+	exited   chan struct{}
+	waitErr  error
+	stopOnce sync.Once
+}
+
+func startHelperProcess(cmd *exec.Cmd) (*helperProcess, error) {
+	reqR, reqW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	respR, respW, err := os.Pipe()
+	if err != nil {
+		_ = reqR.Close()
+		_ = reqW.Close()
+		return nil, err
+	}
+
+	cmd.ExtraFiles = []*os.File{reqR, respW}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		_ = reqR.Close()
+		_ = reqW.Close()
+		_ = respR.Close()
+		_ = respW.Close()
+		return nil, err
+	}
+	// 子进程持有另一端，父进程关闭后子进程退出时读取方能收到 EOF
+	_ = reqR.Close()
+	_ = respW.Close()
+
+	proc := &helperProcess{
+		cmd:      cmd,
+		requests: reqW,
+		enc:      json.NewEncoder(reqW),
+		dec:      json.NewDecoder(respR),
+		exited:   make(chan struct{}),
+	}
+	go func() {
+		proc.waitErr = cmd.Wait()
+		_ = respR.Close()
+		close(proc.exited)
+	}()
+	return proc, nil
+}
+
+// call 发送请求并等待响应；超时或子进程退出时返回错误，调用方负责丢弃该进程
+func (p *helperProcess) call(ctx context.Context, req *helperRequest) (helperResponse, error) {
+	p.calls++
+
+	type result struct {
+		resp helperResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		if r.err = p.enc.Encode(req); r.err == nil {
+			r.err = p.dec.Decode(&r.resp)
+		}
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return helperResponse{}, p.exitError(r.err)
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		return helperResponse{}, ctx.Err()
+	}
+}
+
+// exitError 优先返回子进程的退出原因（如 signal: segmentation fault）
+func (p *helperProcess) exitError(ioErr error) error {
+	select {
+	case <-p.exited:
+		if p.waitErr != nil {
+			return p.waitErr
+		}
+	case <-time.After(helperStopTimeout):
+	}
+	return ioErr
+}
+
+// stop 关闭请求管道让子进程正常退出，超时则强制结束
+func (p *helperProcess) stop(force bool) {
+	p.stopOnce.Do(func() {
+		_ = p.requests.Close()
+		if force {
+			_ = p.cmd.Process.Kill()
+		}
+		select {
+		case <-p.exited:
+		case <-time.After(helperStopTimeout):
+			_ = p.cmd.Process.Kill()
+			<-p.exited
+		}
+	})
+}
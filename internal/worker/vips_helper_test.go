@@ -0,0 +1,190 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/anoixa/image-bed/internal/vipsfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeHelperEnv = "IMAGE_BED_FAKE_VIPS_HELPER"
+
+// fakeVipsEngine 模拟 libvips：src 为 crash 时进程直接退出，为 hang 时一直阻塞
+type fakeVipsEngine struct{}
+
+func (fakeVipsEngine) Probe(_ context.Context, path string, _ vipsfile.ImportOptions) (vipsfile.ImageInfo, error) {
+	switch path {
+	case "crash":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Hour)
+	case "broken":
+		return vipsfile.ImageInfo{}, errors.New("not a known image format")
+	}
+	return vipsfile.ImageInfo{Width: 640, Height: 480, Pages: 1}, nil
+}
+
+func (e fakeVipsEngine) Thumbnail(ctx context.Context, src, dst string, thumb vipsfile.ThumbnailOptions, importOpts vipsfile.ImportOptions, _ vipsfile.WebPOptions) (vipsfile.ImageInfo, error) {
+	if _, err := e.Probe(ctx, src, importOpts); err != nil {
+		return vipsfile.ImageInfo{}, err
+	}
+	return vipsfile.ImageInfo{Width: thumb.Width, Height: thumb.Width}, nil
+}
+
+func (e fakeVipsEngine) Open(ctx context.Context, src string, opts vipsfile.ImportOptions) (sourceImage, vipsfile.ImageInfo, error) {
+	info, err := e.Probe(ctx, src, opts)
+	if err != nil {
+		return nil, vipsfile.ImageInfo{}, err
+	}
+	return fakeSourceImage{}, info, nil
+}
+
+func (e fakeVipsEngine) Transcode(ctx context.Context, src, _ string, importOpts vipsfile.ImportOptions, opts vipsfile.TranscodeOptions) (vipsfile.ImageInfo, error) {
+	info, err := e.Probe(ctx, src, importOpts)
+	if err != nil {
+		return vipsfile.ImageInfo{}, err
+	}
+	if opts.MaxDimension > 0 && info.Width > opts.MaxDimension {
+		info.Height = info.Height * opts.MaxDimension / info.Width
+		info.Width = opts.MaxDimension
+	}
+	return info, nil
+}
+
+type fakeSourceImage struct{}
+
+func (fakeSourceImage) SaveWebP(context.Context, string, vipsfile.WebPOptions) error { return nil }
+func (fakeSourceImage) SaveAVIF(context.Context, string, vipsfile.AVIFOptions) error {
+	return errors.New("avif encoder unavailable")
+}
+func (fakeSourceImage) SaveJXL(context.Context, string, vipsfile.JXLOptions) error { return nil }
+func (fakeSourceImage) Close()                                                     {}
+
+// TestFakeVipsHelperProcess 作为子进程入口，由 newFakeHelperSupervisor 重新执行测试二进制启动
+func TestFakeVipsHelperProcess(t *testing.T) {
+	if os.Getenv(fakeHelperEnv) != "1" {
+		t.Skip("helper process entry point")
+	}
+	err := serveHelper(os.NewFile(helperRequestFD, "requests"), os.NewFile(helperResponseFD, "responses"), fakeVipsEngine{})
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func newFakeHelperSupervisor(t *testing.T, timeout time.Duration) *helperSupervisor {
+	t.Helper()
+	supervisor := newHelperSupervisor(VipsIsolationOptions{Processes: 1, Timeout: timeout}, func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestFakeVipsHelperProcess$")
+		cmd.Env = append(os.Environ(), fakeHelperEnv+"=1")
+		return cmd
+	})
+	t.Cleanup(supervisor.Close)
+	return supervisor
+}
+
+func TestServeHelperHandlesRequests(t *testing.T) {
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- serveHelper(reqR, respW, fakeVipsEngine{})
+	}()
+
+	enc := json.NewEncoder(reqW)
+	dec := json.NewDecoder(respR)
+	roundTrip := func(req helperRequest) helperResponse {
+		require.NoError(t, enc.Encode(&req))
+		var resp helperResponse
+		require.NoError(t, dec.Decode(&resp))
+		return resp
+	}
+
+	resp := roundTrip(helperRequest{Op: helperOpProbe, Src: "photo.jpg"})
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, 640, resp.Info.Width)
+
+	resp = roundTrip(helperRequest{Op: helperOpThumbnail, Src: "photo.jpg", Thumbnail: &vipsfile.ThumbnailOptions{Width: 300}, WebP: &vipsfile.WebPOptions{}})
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, 300, resp.Info.Width)
+
+	resp = roundTrip(helperRequest{Op: helperOpAVIF, Src: "photo.jpg", AVIF: &vipsfile.AVIFOptions{}})
+	assert.Equal(t, "avif encoder unavailable", resp.Error)
+
+	resp = roundTrip(helperRequest{Op: helperOpTranscode, Src: "photo.jpg", Transcode: &vipsfile.TranscodeOptions{MaxDimension: 320, Format: vipsfile.FormatJPEG}})
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, 320, resp.Info.Width)
+	assert.Equal(t, 240, resp.Info.Height)
+
+	resp = roundTrip(helperRequest{Op: helperOpTranscode, Src: "photo.jpg"})
+	assert.Equal(t, "transcode request missing options", resp.Error)
+
+	resp = roundTrip(helperRequest{Op: helperOpWebP, Src: "photo.jpg"})
+	assert.Equal(t, "webp request missing options", resp.Error)
+
+	resp = roundTrip(helperRequest{Op: "resize"})
+	assert.Contains(t, resp.Error, "unknown operation")
+
+	require.NoError(t, reqW.Close())
+	require.NoError(t, <-done)
+}
+
+func TestHelperSupervisorReplacesCrashedProcess(t *testing.T) {
+	supervisor := newFakeHelperSupervisor(t, 10*time.Second)
+	ctx := context.Background()
+
+	info, err := supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "photo.jpg"})
+	require.NoError(t, err)
+	assert.Equal(t, 480, info.Height)
+
+	_, err = supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "crash"})
+	require.ErrorIs(t, err, ErrVipsHelperCrashed)
+	assert.Contains(t, err.Error(), "exit status 3")
+
+	_, err = supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "broken"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrVipsHelperCrashed)
+
+	info, err = supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "photo.jpg"})
+	require.NoError(t, err, "a fresh helper should replace the crashed one")
+	assert.Equal(t, 640, info.Width)
+}
+
+func TestHelperSupervisorKillsHungProcess(t *testing.T) {
+	supervisor := newFakeHelperSupervisor(t, 500*time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	_, err := supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "hang"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	_, err = supervisor.Do(ctx, &helperRequest{Op: helperOpProbe, Src: "photo.jpg"})
+	require.NoError(t, err)
+}
+
+func TestHelperSupervisorRetiresProcessAfterMaxRequests(t *testing.T) {
+	orig := helperMaxRequests
+	helperMaxRequests = 2
+	t.Cleanup(func() { helperMaxRequests = orig })
+
+	supervisor := newFakeHelperSupervisor(t, 10*time.Second)
+	seen := make(map[*helperProcess]struct{})
+	for range 4 {
+		proc, err := supervisor.acquire(context.Background())
+		require.NoError(t, err)
+		seen[proc] = struct{}{}
+		_, err = proc.call(context.Background(), &helperRequest{Op: helperOpProbe, Src: "photo.jpg"})
+		require.NoError(t, err)
+		supervisor.release(proc)
+	}
+	assert.Len(t, seen, 2)
+}
@@ -19,28 +19,15 @@ var (
 	workerPoolLog       = utils.ForModule("WorkerPool")
 )
 
+// InFlightTaskSnapshot 正在执行的变体流水线任务，由 jobs 表中持有租约的任务解析得到
 type InFlightTaskSnapshot struct {
 	ImageID    uint
 	VariantIDs []uint
 }
 
-type inFlightTaskRegistry struct {
-	mu     sync.Mutex
-	nextID uint64
-	tasks  map[uint64]InFlightTaskSnapshot
-}
-
-type inFlightTaskLease struct {
-	registry *inFlightTaskRegistry
-	id       uint64
-}
-
 var (
 	globalPool     *Pool
 	globalPoolOnce sync.Once
-	inFlightTasks  = inFlightTaskRegistry{
-		tasks: make(map[uint64]InFlightTaskSnapshot),
-	}
 )
 
 var workerMemoryCheck = func() error {
@@ -103,20 +90,23 @@ type ImageProcessingSemaphore struct {
 
 // PoolStats 任务池统计信息
 type PoolStats struct {
-	Submitted   uint64 // 已提交任务数
-	Executed    uint64 // 已执行任务数
-	Failed      uint64 // 失败任务数（panic）
-	QueueSize   int    // 当前队列长度
-	QueueCap    int    // 队列容量
-	WorkerCount int    // Worker 数量
+	Submitted   uint64      // 已提交任务数
+	Executed    uint64      // 已执行任务数
+	Failed      uint64      // 失败任务数（panic）
+	QueueSize   int         // 当前队列长度
+	QueueCap    int         // 队列容量
+	WorkerCount int         // Worker 数量
+	Lanes       []LaneStats // 各优先级通道的排队情况
 }
 
-// Pool 独立的异步任务池
+// Pool 独立的异步任务池，按优先级通道和用户轮转调度
 type Pool struct {
-	taskCh   chan func()
-	wg       sync.WaitGroup
-	isClosed atomic.Bool
-	doneCh   chan struct{}
+	mu        sync.Mutex
+	cond      *sync.Cond
+	scheduler laneScheduler
+	wg        sync.WaitGroup
+	isClosed  atomic.Bool
+	doneCh    chan struct{}
 
 	submittedCount atomic.Uint64
 	executedCount  atomic.Uint64
@@ -198,10 +188,6 @@ func ShutdownGlobalPool(ctx context.Context) error {
 	return globalPool.ShutdownContext(ctx)
 }
 
-func CurrentInFlightTasks() []InFlightTaskSnapshot {
-	return inFlightTasks.Snapshots()
-}
-
 // NewPool 创建新的任务池
 func NewPool(workers, queueSize int) *Pool {
 	if queueSize <= 0 {
@@ -212,10 +198,10 @@ func NewPool(workers, queueSize int) *Pool {
 	}
 
 	p := &Pool{
-		taskCh:   make(chan func(), queueSize),
 		queueCap: queueSize,
 		doneCh:   make(chan struct{}),
 	}
+	p.cond = sync.NewCond(&p.mu)
 
 	InitGlobalSemaphore(DefaultImageProcessingConfig())
 
@@ -241,7 +227,11 @@ func (p *Pool) worker() {
 	defer runtime.UnlockOSThread()
 	defer vipsfile.ShutdownThread()
 
-	for task := range p.taskCh {
+	for {
+		task, ok := p.next()
+		if !ok {
+			return
+		}
 		if task != nil {
 			p.executeTaskWithRecovery(task)
 			// Release libvips thread-local state between independent jobs.
@@ -252,6 +242,16 @@ func (p *Pool) worker() {
 	}
 }
 
+// next 阻塞等待下一个任务；池关闭且队列清空后返回 false
+func (p *Pool) next() (func(), bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.scheduler.pending == 0 && !p.isClosed.Load() {
+		p.cond.Wait()
+	}
+	return p.scheduler.pop()
+}
+
 // executeTaskWithRecovery 包装单个任务
 func (p *Pool) executeTaskWithRecovery(task func()) {
 	p.executedCount.Add(1)
@@ -264,8 +264,13 @@ func (p *Pool) executeTaskWithRecovery(task func()) {
 	task()
 }
 
-// Submit 提交异步任务到队列
-func (p *Pool) Submit(task func()) (ok bool) {
+// Submit 以默认通道提交异步任务
+func (p *Pool) Submit(task func()) bool {
+	return p.SubmitWith(TaskOptions{}, task)
+}
+
+// SubmitWith 按优先级通道和所属用户提交异步任务
+func (p *Pool) SubmitWith(opts TaskOptions, task func()) bool {
 	if p.isClosed.Load() {
 		return false
 	}
@@ -273,19 +278,20 @@ func (p *Pool) Submit(task func()) (ok bool) {
 		workerPoolLog.Warnf("Rejecting task submission after backpressure wait: %v", err)
 		return false
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			ok = false
-		}
-	}()
-	select {
-	case p.taskCh <- task:
-		p.submittedCount.Add(1)
-		return true
-	default:
-		workerPoolLog.Warnf("Task queue full, dropping task")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.isClosed.Load() {
 		return false
 	}
+	if p.scheduler.pending >= p.queueCap {
+		workerPoolLog.Warnf("Task queue full, dropping %s task", opts.Priority)
+		return false
+	}
+	p.scheduler.push(opts, task)
+	p.submittedCount.Add(1)
+	p.cond.Signal()
+	return true
 }
 
 var backpressureTimeout = 30 * time.Second
@@ -321,7 +327,9 @@ func (p *Pool) Stop() {
 func (p *Pool) ShutdownContext(ctx context.Context) error {
 	if p.isClosed.CompareAndSwap(false, true) {
 		workerPoolLog.Infof("Stopping")
-		close(p.taskCh)
+		p.mu.Lock()
+		p.cond.Broadcast()
+		p.mu.Unlock()
 	}
 
 	select {
@@ -335,77 +343,18 @@ func (p *Pool) ShutdownContext(ctx context.Context) error {
 
 // GetStats 获取任务池当前的运行状态
 func (p *Pool) GetStats() PoolStats {
+	p.mu.Lock()
+	queueSize := p.scheduler.pending
+	lanes := p.scheduler.stats()
+	p.mu.Unlock()
+
 	return PoolStats{
 		Submitted:   p.submittedCount.Load(),
 		Executed:    p.executedCount.Load(),
 		Failed:      p.failedCount.Load(),
-		QueueSize:   len(p.taskCh),
+		QueueSize:   queueSize,
 		QueueCap:    p.queueCap,
 		WorkerCount: p.workerCount,
+		Lanes:       lanes,
 	}
 }
-
-func beginInFlightTask(imageID uint, variantIDs []uint) *inFlightTaskLease {
-	if imageID == 0 && len(variantIDs) == 0 {
-		return nil
-	}
-	return inFlightTasks.Begin(imageID, variantIDs)
-}
-
-func (r *inFlightTaskRegistry) Begin(imageID uint, variantIDs []uint) *inFlightTaskLease {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	r.nextID++
-	id := r.nextID
-	r.tasks[id] = InFlightTaskSnapshot{
-		ImageID:    imageID,
-		VariantIDs: append([]uint(nil), variantIDs...),
-	}
-
-	return &inFlightTaskLease{
-		registry: r,
-		id:       id,
-	}
-}
-
-func (r *inFlightTaskRegistry) Snapshots() []InFlightTaskSnapshot {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	snapshots := make([]InFlightTaskSnapshot, 0, len(r.tasks))
-	for _, snapshot := range r.tasks {
-		snapshots = append(snapshots, InFlightTaskSnapshot{
-			ImageID:    snapshot.ImageID,
-			VariantIDs: append([]uint(nil), snapshot.VariantIDs...),
-		})
-	}
-	return snapshots
-}
-
-func (l *inFlightTaskLease) Update(imageID uint, variantIDs []uint) {
-	if l == nil || l.registry == nil {
-		return
-	}
-
-	l.registry.mu.Lock()
-	defer l.registry.mu.Unlock()
-
-	if _, ok := l.registry.tasks[l.id]; !ok {
-		return
-	}
-	l.registry.tasks[l.id] = InFlightTaskSnapshot{
-		ImageID:    imageID,
-		VariantIDs: append([]uint(nil), variantIDs...),
-	}
-}
-
-func (l *inFlightTaskLease) Release() {
-	if l == nil || l.registry == nil {
-		return
-	}
-
-	l.registry.mu.Lock()
-	defer l.registry.mu.Unlock()
-	delete(l.registry.tasks, l.id)
-}
@@ -86,18 +86,3 @@ func TestShutdownContextTimesOutWhenWorkerIsStillRunning(t *testing.T) {
 	close(blocker)
 	require.NoError(t, pool.ShutdownContext(context.Background()))
 }
-
-func TestInFlightTaskLeaseTracksLatestVariantSet(t *testing.T) {
-	lease := beginInFlightTask(42, []uint{1, 2, 3})
-	require.NotNil(t, lease)
-	defer lease.Release()
-
-	lease.Update(42, []uint{2, 3})
-	snapshots := CurrentInFlightTasks()
-	require.Len(t, snapshots, 1)
-	assert.Equal(t, uint(42), snapshots[0].ImageID)
-	assert.Equal(t, []uint{2, 3}, snapshots[0].VariantIDs)
-
-	lease.Release()
-	assert.Empty(t, CurrentInFlightTasks())
-}
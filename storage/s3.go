@@ -388,6 +388,12 @@ func getContentTypeFromPathS3(storagePath string) string {
 		return "image/bmp"
 	case ".tiff", ".tif":
 		return "image/tiff"
+	case ".heic":
+		return "image/heic"
+	case ".heif":
+		return "image/heif"
+	case ".jxl":
+		return "image/jxl"
 	default:
 		return "application/octet-stream"
 	}
@@ -0,0 +1,110 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MaxPending 两次写库之间最多保留的 key 数，超出后新出现的 key 丢弃到下一轮
+const MaxPending = 100_000
+
+// flushTimeout 后台写库的超时
+const flushTimeout = 30 * time.Second
+
+// Batcher 在内存中按 key 合并高频记录，定期批量写库，避免每次请求写库
+type Batcher[K comparable, V any] struct {
+	merge func(existing *V, v V)
+	write func(ctx context.Context, items []V) error
+
+	mu      sync.Mutex
+	pending map[K]*V
+}
+
+// New 创建 Batcher；merge 把同一 key 的新记录合并到已有记录，为空时保留已有记录
+func New[K comparable, V any](merge func(existing *V, v V), write func(ctx context.Context, items []V) error) *Batcher[K, V] {
+	return &Batcher[K, V]{
+		merge:   merge,
+		write:   write,
+		pending: make(map[K]*V),
+	}
+}
+
+// Add 记录一条数据，与已有的同 key 记录合并
+func (b *Batcher[K, V]) Add(key K, v V) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.pending[key]; ok {
+		if b.merge != nil {
+			b.merge(existing, v)
+		}
+		return
+	}
+	if len(b.pending) < MaxPending {
+		b.pending[key] = &v
+	}
+}
+
+// Len 返回待写入的记录数
+func (b *Batcher[K, V]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// Flush 把已合并的记录写入数据库，失败的记录留到下一轮
+func (b *Batcher[K, V]) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	if len(b.pending) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	batch := b.pending
+	b.pending = make(map[K]*V)
+	b.mu.Unlock()
+
+	items := make([]V, 0, len(batch))
+	for _, v := range batch {
+		items = append(items, *v)
+	}
+	if err := b.write(ctx, items); err != nil {
+		for key, v := range batch {
+			b.Add(key, *v)
+		}
+		return err
+	}
+	return nil
+}
+
+// Start 每隔 interval 在后台写库，失败时交给 onError；返回的函数停止定时写库并写入剩余记录
+func (b *Batcher[K, V]) Start(interval time.Duration, onError func(error)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.flushWithTimeout(onError)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		b.flushWithTimeout(onError)
+	}
+}
+
+func (b *Batcher[K, V]) flushWithTimeout(onError func(error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := b.Flush(ctx); err != nil && onError != nil {
+		onError(err)
+	}
+}
@@ -0,0 +1,78 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	key string
+	n   int
+}
+
+func TestBatcherMergesAndRetriesFailedWrites(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		written [][]counter
+		failErr error
+	)
+	b := New[string](func(existing *counter, v counter) { existing.n += v.n }, func(_ context.Context, items []counter) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failErr != nil {
+			return failErr
+		}
+		written = append(written, items)
+		return nil
+	})
+
+	b.Add("a", counter{"a", 1})
+	b.Add("a", counter{"a", 2})
+	b.Add("b", counter{"b", 1})
+	assert.Equal(t, 2, b.Len())
+
+	failErr = errors.New("db down")
+	require.Error(t, b.Flush(context.Background()))
+	assert.Equal(t, 2, b.Len(), "failed writes stay pending")
+
+	b.Add("a", counter{"a", 4})
+	failErr = nil
+	require.NoError(t, b.Flush(context.Background()))
+	require.Len(t, written, 1)
+	assert.ElementsMatch(t, []counter{{"a", 7}, {"b", 1}}, written[0])
+
+	require.NoError(t, b.Flush(context.Background()))
+	assert.Len(t, written, 1, "nothing left to write")
+}
+
+func TestBatcherKeepsFirstRecordWithoutMerge(t *testing.T) {
+	var written []counter
+	b := New[string](nil, func(_ context.Context, items []counter) error {
+		written = append(written, items...)
+		return nil
+	})
+
+	b.Add("a", counter{"a", 1})
+	b.Add("a", counter{"a", 2})
+	require.NoError(t, b.Flush(context.Background()))
+	assert.Equal(t, []counter{{"a", 1}}, written)
+}
+
+func TestBatcherStopFlushesRemaining(t *testing.T) {
+	var written []counter
+	b := New[string](nil, func(_ context.Context, items []counter) error {
+		written = append(written, items...)
+		return nil
+	})
+
+	stop := b.Start(time.Hour, nil)
+	b.Add("a", counter{"a", 1})
+	stop()
+	assert.Equal(t, []counter{{"a", 1}}, written)
+}
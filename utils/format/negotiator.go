@@ -9,6 +9,7 @@ import (
 type FormatType string
 
 const (
+	FormatJXL      FormatType = "jxl"
 	FormatAVIF     FormatType = "avif"
 	FormatWebP     FormatType = "webp"
 	FormatOriginal FormatType = "original"
@@ -24,6 +25,12 @@ type FormatInfo struct {
 
 // FormatRegistry 格式注册表
 var FormatRegistry = map[FormatType]FormatInfo{
+	FormatJXL: {
+		Type:      FormatJXL,
+		MIMEType:  "image/jxl",
+		Extension: ".jxl",
+		Priority:  40,
+	},
 	FormatAVIF: {
 		Type:      FormatAVIF,
 		MIMEType:  "image/avif",
@@ -44,6 +51,12 @@ var FormatRegistry = map[FormatType]FormatInfo{
 	},
 }
 
+// explicitOnlyFormats 只有 Accept 显式列出时才协商的格式；
+// JPEG XL 浏览器支持有限，通配符 */* 或 image/* 不代表客户端能解码
+var explicitOnlyFormats = map[FormatType]bool{
+	FormatJXL: true,
+}
+
 // ClientPreference 客户端偏好
 type ClientPreference struct {
 	FormatType FormatType
@@ -68,7 +81,7 @@ func NewNegotiator(enabled []string) *Negotiator {
 func (n *Negotiator) Negotiate(acceptHeader string, available map[FormatType]bool) FormatType {
 	clientPrefs := parseAcceptHeader(acceptHeader)
 
-	candidates := []FormatType{FormatAVIF, FormatWebP}
+	candidates := []FormatType{FormatJXL, FormatAVIF, FormatWebP}
 
 	for _, format := range candidates {
 		if n.enabledFormats[format] && available[format] && clientSupports(clientPrefs, format) {
@@ -79,6 +92,11 @@ func (n *Negotiator) Negotiate(acceptHeader string, available map[FormatType]boo
 	return FormatOriginal
 }
 
+// Accepts 检查 Accept 头是否接受某格式
+func Accepts(acceptHeader string, format FormatType) bool {
+	return clientSupports(parseAcceptHeader(acceptHeader), format)
+}
+
 // clientSupports 检查客户端是否支持某格式
 func clientSupports(prefs []ClientPreference, format FormatType) bool {
 	for _, pref := range prefs {
@@ -87,6 +105,10 @@ func clientSupports(prefs []ClientPreference, format FormatType) bool {
 		}
 	}
 
+	if explicitOnlyFormats[format] {
+		return false
+	}
+
 	for _, pref := range prefs {
 		if pref.FormatType == "" && pref.QValue > 0 {
 			return true
@@ -146,6 +168,8 @@ func parseMediaRange(part string) (string, float64) {
 // mimeToFormat MIME 类型映射到 FormatType
 func mimeToFormat(mime string) FormatType {
 	switch mime {
+	case "image/jxl":
+		return FormatJXL
 	case "image/avif":
 		return FormatAVIF
 	case "image/webp":
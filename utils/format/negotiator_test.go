@@ -75,6 +75,34 @@ func TestNegotiator_Negotiate(t *testing.T) {
 			available:    map[FormatType]bool{FormatAVIF: true, FormatWebP: true},
 			want:         FormatAVIF,
 		},
+		{
+			name:         "Safari lists JXL explicitly - should return JXL",
+			acceptHeader: "image/webp,image/avif,image/jxl,image/heic,image/heic-sequence,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5",
+			enabled:      []string{"jxl", "avif", "webp"},
+			available:    map[FormatType]bool{FormatJXL: true, FormatAVIF: true, FormatWebP: true},
+			want:         FormatJXL,
+		},
+		{
+			name:         "Chrome wildcard does not imply JXL - should return AVIF",
+			acceptHeader: "image/avif,image/webp,image/apng,image/*,*/*;q=0.8",
+			enabled:      []string{"jxl", "avif", "webp"},
+			available:    map[FormatType]bool{FormatJXL: true, FormatAVIF: true, FormatWebP: true},
+			want:         FormatAVIF,
+		},
+		{
+			name:         "JXL disabled in config - should return AVIF",
+			acceptHeader: "image/jxl,image/avif,image/webp,*/*",
+			enabled:      []string{"avif", "webp"},
+			available:    map[FormatType]bool{FormatJXL: true, FormatAVIF: true, FormatWebP: true},
+			want:         FormatAVIF,
+		},
+		{
+			name:         "Client rejects JXL (q=0) - should return WebP",
+			acceptHeader: "image/jxl;q=0,image/webp,*/*",
+			enabled:      []string{"jxl", "webp"},
+			available:    map[FormatType]bool{FormatJXL: true, FormatWebP: true},
+			want:         FormatWebP,
+		},
 		{
 			name:         "WebP with lower quality preference - still return WebP",
 			acceptHeader: "image/webp;q=0.5,*/*;q=0.8",
@@ -184,6 +212,7 @@ func TestMimeToFormat(t *testing.T) {
 		mime string
 		want FormatType
 	}{
+		{"image/jxl", FormatJXL},
 		{"image/avif", FormatAVIF},
 		{"image/webp", FormatWebP},
 		{"image/jpeg", ""},
@@ -226,6 +255,12 @@ func TestClientSupports(t *testing.T) {
 			format:   FormatWebP,
 			expected: true,
 		},
+		{
+			name:     "Wildcard does not cover JXL",
+			prefs:    []ClientPreference{{FormatType: "", QValue: 1.0}},
+			format:   FormatJXL,
+			expected: false,
+		},
 		{
 			name:     "No support",
 			prefs:    []ClientPreference{{FormatType: FormatAVIF, QValue: 1.0}},
@@ -243,3 +278,15 @@ func TestClientSupports(t *testing.T) {
 		})
 	}
 }
+
+func TestAccepts(t *testing.T) {
+	if !Accepts("image/avif,image/webp,*/*;q=0.8", FormatWebP) {
+		t.Error("expected webp to be accepted")
+	}
+	if Accepts("image/png", FormatWebP) {
+		t.Error("expected webp to be rejected")
+	}
+	if Accepts("", FormatWebP) {
+		t.Error("expected empty Accept to reject webp")
+	}
+}
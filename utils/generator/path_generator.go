@@ -45,6 +45,18 @@ func (pg *PathGenerator) GenerateThumbnailIdentifiers(originalStoragePath string
 	}
 }
 
+// GenerateCroppedThumbnailIdentifiers 生成固定宽高缩略图的 identifier 和 storage_path
+func (pg *PathGenerator) GenerateCroppedThumbnailIdentifiers(originalStoragePath string, width, height int) StorageIdentifiers {
+	hash := pg.extractHashFromPath(originalStoragePath)
+	datePath := pg.extractDatePath(originalStoragePath)
+	identifier := fmt.Sprintf("%s_%dx%d", hash, width, height)
+
+	return StorageIdentifiers{
+		Identifier:  identifier,
+		StoragePath: fmt.Sprintf("thumbnails/%s/%s.webp", datePath, identifier),
+	}
+}
+
 // GenerateConvertedIdentifiers 生成格式转换的 identifier 和 storage_path
 func (pg *PathGenerator) GenerateConvertedIdentifiers(originalStoragePath string, format string) StorageIdentifiers {
 	hash := pg.extractHashFromPath(originalStoragePath)
@@ -114,6 +126,10 @@ func (pg *PathGenerator) ParseFormatFromStoragePath(storagePath string) string {
 		return "thumbnail"
 	case "converted":
 		if len(parts) >= 2 {
+			// JPEG XL 变体目录沿用 jpegxl
+			if parts[1] == "jpegxl" {
+				return "jxl"
+			}
 			return parts[1] // webp, avif, etc.
 		}
 	}
@@ -85,6 +85,18 @@ func TestPathGenerator_GenerateThumbnailIdentifiers(t *testing.T) {
 	}
 }
 
+func TestPathGenerator_GenerateCroppedThumbnailIdentifiers(t *testing.T) {
+	pg := NewPathGenerator()
+
+	got := pg.GenerateCroppedThumbnailIdentifiers("original/2024/01/15/a1b2c3d4e5f6.jpg", 640, 360)
+	if got.Identifier != "a1b2c3d4e5f6_640x360" {
+		t.Errorf("GenerateCroppedThumbnailIdentifiers() Identifier = %v", got.Identifier)
+	}
+	if got.StoragePath != "thumbnails/2024/01/15/a1b2c3d4e5f6_640x360.webp" {
+		t.Errorf("GenerateCroppedThumbnailIdentifiers() StoragePath = %v", got.StoragePath)
+	}
+}
+
 func TestPathGenerator_GenerateConvertedIdentifiers(t *testing.T) {
 	pg := NewPathGenerator()
 
@@ -109,6 +121,13 @@ func TestPathGenerator_GenerateConvertedIdentifiers(t *testing.T) {
 			wantIdentifier:      "a1b2c3d4e5f6",
 			wantStoragePath:     "converted/avif/2024/01/15/a1b2c3d4e5f6.avif",
 		},
+		{
+			name:                "jxl format",
+			originalStoragePath: "original/2024/01/15/a1b2c3d4e5f6.jpg",
+			format:              "jxl",
+			wantIdentifier:      "a1b2c3d4e5f6",
+			wantStoragePath:     "converted/jpegxl/2024/01/15/a1b2c3d4e5f6.jxl",
+		},
 	}
 
 	for _, tt := range tests {
@@ -218,3 +237,25 @@ func TestPathGenerator_Consistency(t *testing.T) {
 		t.Errorf("WebP storage path missing date: %s", webp.StoragePath)
 	}
 }
+
+func TestPathGenerator_ParseFormatFromStoragePath(t *testing.T) {
+	pg := NewPathGenerator()
+
+	tests := map[string]string{
+		"original/2024/01/15/a1b2c3d4e5f6.jpg":         "original",
+		"thumbnails/2024/01/15/a1b2c3d4e5f6_600.webp":  "thumbnail",
+		"converted/webp/2024/01/15/a1b2c3d4e5f6.webp":  "webp",
+		"converted/jpegxl/2024/01/15/a1b2c3d4e5f6.jxl": "jxl",
+	}
+
+	for path, want := range tests {
+		if got := pg.ParseFormatFromStoragePath(path); got != want {
+			t.Errorf("ParseFormatFromStoragePath(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	jxl := pg.GenerateConvertedIdentifiers("original/2024/01/15/a1b2c3d4e5f6.jpg", "jxl")
+	if got := pg.ParseFormatFromStoragePath(jxl.StoragePath); got != "jxl" {
+		t.Errorf("ParseFormatFromStoragePath(%q) = %v, want jxl", jxl.StoragePath, got)
+	}
+}
@@ -0,0 +1,144 @@
+package jpegmeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrInvalid      = errors.New("invalid jpeg stream")
+	ErrMultiPicture = errors.New("multi-picture jpeg cannot be rewritten")
+)
+
+const (
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerCOM  = 0xFE
+	markerAPP0 = 0xE0
+)
+
+var (
+	extendedXMPSignature = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	mpfSignature         = []byte("MPF\x00")
+)
+
+// Strip 无损移除 JPEG 中不影响显示的段：注释、厂商私有 APPn 和扩展 XMP（编辑历史等）。
+// JFIF、Exif（含方向）、ICC、标准 XMP、IPTC 和 Adobe 颜色变换段原样保留，图像数据逐字节复制。
+// 含 MPF 的多图 JPEG 依赖段偏移，返回 ErrMultiPicture
+func Strip(r io.Reader, w io.Writer) (int64, error) {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	var written int64
+
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xFF || soi[1] != markerSOI {
+		return 0, ErrInvalid
+	}
+	if _, err := bw.Write(soi[:]); err != nil {
+		return 0, err
+	}
+	written += 2
+
+	for {
+		marker, err := readMarker(br)
+		if err != nil {
+			return written, err
+		}
+
+		// 没有长度字段的独立标记
+		if marker == markerEOI || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			if _, err := bw.Write([]byte{0xFF, marker}); err != nil {
+				return written, err
+			}
+			written += 2
+			if marker == markerEOI {
+				n, err := io.Copy(bw, br)
+				written += n
+				if err != nil {
+					return written, err
+				}
+				return written, bw.Flush()
+			}
+			continue
+		}
+
+		var lengthBytes [2]byte
+		if _, err := io.ReadFull(br, lengthBytes[:]); err != nil {
+			return written, ErrInvalid
+		}
+		length := int(binary.BigEndian.Uint16(lengthBytes[:]))
+		if length < 2 {
+			return written, ErrInvalid
+		}
+		payload := make([]byte, length-2)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return written, ErrInvalid
+		}
+
+		if marker == 0xE2 && bytes.HasPrefix(payload, mpfSignature) {
+			return written, ErrMultiPicture
+		}
+		if removable(marker, payload) {
+			continue
+		}
+
+		if _, err := bw.Write([]byte{0xFF, marker, lengthBytes[0], lengthBytes[1]}); err != nil {
+			return written, err
+		}
+		if _, err := bw.Write(payload); err != nil {
+			return written, err
+		}
+		written += int64(length) + 2
+
+		// 扫描段之后是熵编码数据，原样复制到结尾
+		if marker == markerSOS {
+			n, err := io.Copy(bw, br)
+			written += n
+			if err != nil {
+				return written, err
+			}
+			return written, bw.Flush()
+		}
+	}
+}
+
+// readMarker 读取下一个标记，跳过标记前的 0xFF 填充字节
+func readMarker(br *bufio.Reader) (byte, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	if b != 0xFF {
+		return 0, fmt.Errorf("%w: expected marker, got 0x%02x", ErrInvalid, b)
+	}
+	for b == 0xFF {
+		if b, err = br.ReadByte(); err != nil {
+			return 0, ErrInvalid
+		}
+	}
+	if b == 0x00 {
+		return 0, fmt.Errorf("%w: stuffed byte outside scan", ErrInvalid)
+	}
+	return b, nil
+}
+
+// removable 判断段能否移除而不影响解码和显示
+func removable(marker byte, payload []byte) bool {
+	switch {
+	case marker == markerCOM:
+		return true
+	case marker == 0xE1:
+		return bytes.HasPrefix(payload, extendedXMPSignature)
+	case marker >= markerAPP0+3 && marker <= markerAPP0+10, marker == markerAPP0+12, marker == markerAPP0+15:
+		// APP3-APP10、APP12（Ducky 等）、APP15 只存放相机或软件的私有数据；
+		// APP11 (JUMBF/C2PA)、APP13 (IPTC)、APP14 (Adobe) 保留
+		return true
+	default:
+		return false
+	}
+}
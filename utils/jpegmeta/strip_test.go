@@ -0,0 +1,84 @@
+package jpegmeta
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestJPEG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := range 16 {
+		img.Set(x, x%8, color.RGBA{R: uint8(x * 16), G: 80, B: 160, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	return buf.Bytes()
+}
+
+func segment(marker byte, payload []byte) []byte {
+	length := len(payload) + 2
+	return append([]byte{0xFF, marker, byte(length >> 8), byte(length)}, payload...)
+}
+
+// withSegments 在 SOI 之后插入额外的段
+func withSegments(data []byte, segments ...[]byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, data[2:]...)
+}
+
+func TestStripRemovesNonEssentialSegments(t *testing.T) {
+	base := encodeTestJPEG(t)
+	exif := segment(0xE1, append([]byte("Exif\x00\x00"), bytes.Repeat([]byte{1}, 20)...))
+	icc := segment(0xE2, append([]byte("ICC_PROFILE\x00"), bytes.Repeat([]byte{2}, 20)...))
+	input := withSegments(base,
+		exif,
+		icc,
+		segment(0xFE, []byte("converted by some tool")),
+		segment(0xE1, append([]byte("http://ns.adobe.com/xmp/extension/\x00"), bytes.Repeat([]byte{3}, 500)...)),
+		segment(0xEC, []byte("Ducky\x00\x00")),
+	)
+
+	var out bytes.Buffer
+	n, err := Strip(bytes.NewReader(input), &out)
+	require.NoError(t, err)
+	assert.Equal(t, int64(out.Len()), n)
+	assert.Equal(t, withSegments(base, exif, icc), out.Bytes())
+
+	decoded, err := jpeg.Decode(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 16, decoded.Bounds().Dx())
+}
+
+func TestStripKeepsCleanFileUnchanged(t *testing.T) {
+	base := encodeTestJPEG(t)
+	trailer := append(append([]byte{}, base...), []byte("trailing")...)
+
+	var out bytes.Buffer
+	_, err := Strip(bytes.NewReader(trailer), &out)
+	require.NoError(t, err)
+	assert.Equal(t, trailer, out.Bytes())
+}
+
+func TestStripRejectsUnsupportedInput(t *testing.T) {
+	base := encodeTestJPEG(t)
+
+	_, err := Strip(bytes.NewReader([]byte("not a jpeg")), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Strip(bytes.NewReader(base[:len(base)/4]), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	mpf := withSegments(base, segment(0xE2, []byte("MPF\x00II*\x00")))
+	_, err = Strip(bytes.NewReader(mpf), &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrMultiPicture)
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"maps"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	_ "image/gif"
@@ -15,13 +17,35 @@ import (
 
 // mimeToExtMap MIME类型到安全扩展名的映射
 var mimeToExtMap = map[string]string{
-	"image/jpeg": ".jpg",
-	"image/png":  ".png",
-	"image/gif":  ".gif",
-	"image/webp": ".webp",
-	"image/bmp":  ".bmp",
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/bmp":     ".bmp",
+	"image/heic":    ".heic",
+	"image/heif":    ".heif",
+	"image/avif":    ".avif",
+	"image/tiff":    ".tiff",
+	"image/jxl":     ".jxl",
+	"image/svg+xml": ".svg",
 }
 
+// deliveryVariantMimeTypes 浏览器普遍无法直接显示、必须提供 WebP 交付变体的格式
+var deliveryVariantMimeTypes = map[string]bool{
+	"image/heic": true,
+	"image/heif": true,
+	"image/tiff": true,
+	"image/jxl":  true,
+}
+
+// DeliveryVariantMimeTypes 返回必须提供 WebP 交付变体的原图格式
+func DeliveryVariantMimeTypes() []string {
+	return slices.Sorted(maps.Keys(deliveryVariantMimeTypes))
+}
+
+// SVGMimeType SVG 矢量图的 MIME 类型
+const SVGMimeType = "image/svg+xml"
+
 // GetSafeExtension 根据MIME类型返回安全的文件扩展名
 func GetSafeExtension(mimeType string) string {
 	mimeType = strings.Split(mimeType, ";")[0]
@@ -33,6 +57,17 @@ func GetSafeExtension(mimeType string) string {
 	return ""
 }
 
+// RequiresDeliveryVariant 判断原图是否需要生成浏览器可显示的交付变体
+func RequiresDeliveryVariant(mimeType string) bool {
+	mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	return deliveryVariantMimeTypes[mimeType]
+}
+
+// IsVectorImage 判断原图是否为矢量图：直接交付原图，只生成位图缩略图
+func IsVectorImage(mimeType string) bool {
+	return strings.TrimSpace(strings.Split(mimeType, ";")[0]) == SVGMimeType
+}
+
 // GetExtensionFromFilename 从文件名获取扩展名
 func GetExtensionFromFilename(filename string) string {
 	return strings.ToLower(filepath.Ext(filename))
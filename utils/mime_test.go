@@ -130,3 +130,21 @@ func TestGetImageDimensions(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, int64(0), pos, "reader should be reset after dimension detection")
 }
+
+func TestRequiresDeliveryVariant(t *testing.T) {
+	assert.True(t, RequiresDeliveryVariant("image/heic"))
+	assert.True(t, RequiresDeliveryVariant("image/heif"))
+	assert.True(t, RequiresDeliveryVariant("image/tiff"))
+	assert.True(t, RequiresDeliveryVariant("image/jxl; charset=binary"))
+	assert.False(t, RequiresDeliveryVariant("image/avif"))
+	assert.False(t, RequiresDeliveryVariant("image/jpeg"))
+	assert.Equal(t, ".heic", GetSafeExtension("image/heic"))
+	assert.Equal(t, ".tiff", GetSafeExtension("image/tiff"))
+}
+
+func TestIsVectorImage(t *testing.T) {
+	assert.True(t, IsVectorImage("image/svg+xml"))
+	assert.True(t, IsVectorImage("image/svg+xml; charset=utf-8"))
+	assert.False(t, IsVectorImage("image/png"))
+	assert.Equal(t, ".svg", GetSafeExtension(SVGMimeType))
+}
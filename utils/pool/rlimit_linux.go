@@ -0,0 +1,17 @@
+//go:build linux
+
+package pool
+
+import "syscall"
+
+// LimitDataSegment 限制当前进程的数据段（堆与私有匿名映射），超过容器内存上限时取容器上限
+// 分配失败时 libvips 返回错误或进程退出，不会拖垮父进程
+func LimitDataSegment(limit int64) error {
+	if containerLimit := getContainerMemoryLimit(); containerLimit > 0 && containerLimit < limit {
+		limit = containerLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+	return syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: uint64(limit), Max: uint64(limit)})
+}
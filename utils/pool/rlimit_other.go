@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pool
+
+import "errors"
+
+// LimitDataSegment 仅 Linux 支持，其他平台只依赖超时和崩溃重启
+func LimitDataSegment(limit int64) error {
+	return errors.New("memory limit for helper processes is only supported on linux")
+}
@@ -0,0 +1,307 @@
+package svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxSize SVG 净化时允许读取的最大字节数
+const MaxSize = 10 << 20
+
+// ContentSecurityPolicy 直接访问 SVG 原图时使用的 CSP：禁止脚本、外部资源和同源访问
+const ContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; img-src data:; sandbox"
+
+var (
+	ErrTooLarge   = errors.New("svg document is too large")
+	ErrInvalid    = errors.New("invalid svg document")
+	ErrMissingSVG = errors.New("svg root element not found")
+)
+
+// blockedElements 整个子树都会被移除的元素
+var blockedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"audio":         true,
+	"video":         true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// animationElements 可通过 attributeName 修改其他属性的动画元素
+var animationElements = map[string]bool{
+	"set":              true,
+	"animate":          true,
+	"animatemotion":    true,
+	"animatetransform": true,
+}
+
+// urlAttributes 值为 URL 的属性（不含命名空间前缀，小写）
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+}
+
+var (
+	cssURLPattern    = regexp.MustCompile(`(?i)url\s*\(\s*['"]?\s*([^'")\s]*)`)
+	cssImportPattern = regexp.MustCompile(`(?i)@import`)
+	// image-set() 和 src() 可以直接用字符串引用外部资源，无需 url()
+	cssStringURLPattern = regexp.MustCompile(`(?i)(image-set|\bsrc)\s*\(`)
+	dataImagePattern    = regexp.MustCompile(`(?i)^data:image/(png|jpeg|jpg|gif|webp|avif);`)
+)
+
+// Sanitize 读取并净化 SVG 文档：移除脚本、事件处理器、外部引用和 foreignObject，
+// 同时丢弃注释、处理指令和 DOCTYPE（避免实体扩展）
+func Sanitize(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = true
+
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+
+	// RawToken 不校验标签配对，需要自行维护元素栈
+	var stack []xml.Name
+	depth := 0
+	skipDepth := 0 // >0 表示正在跳过被移除元素的子树
+	styleDepth := 0
+	var styleText strings.Builder
+	seenRoot := false
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name)
+			depth++
+			if skipDepth > 0 {
+				continue
+			}
+			local := strings.ToLower(t.Name.Local)
+			if depth == 1 {
+				if local != "svg" {
+					return nil, ErrMissingSVG
+				}
+				seenRoot = true
+			}
+			if blockedElements[local] || (animationElements[local] && !safeAnimation(t.Attr)) {
+				skipDepth = depth
+				continue
+			}
+			if local == "style" {
+				styleDepth = depth
+				styleText.Reset()
+			}
+			writeStartElement(&out, t)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1] != t.Name {
+				return nil, fmt.Errorf("%w: unexpected end element </%s>", ErrInvalid, qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			if skipDepth > 0 {
+				if depth == skipDepth {
+					skipDepth = 0
+				}
+				depth--
+				continue
+			}
+			if depth == styleDepth {
+				if css := styleText.String(); safeCSS(css) {
+					_ = xml.EscapeText(&out, []byte(css))
+				}
+				styleDepth = 0
+			}
+			depth--
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 || depth == 0 {
+				continue
+			}
+			if styleDepth > 0 {
+				styleText.Write(t)
+				continue
+			}
+			_ = xml.EscapeText(&out, t)
+		}
+	}
+
+	if !seenRoot {
+		return nil, ErrMissingSVG
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: unclosed element <%s>", ErrInvalid, qualifiedName(stack[len(stack)-1]))
+	}
+	return out.Bytes(), nil
+}
+
+func writeStartElement(out *bytes.Buffer, el xml.StartElement) {
+	out.WriteString("<" + qualifiedName(el.Name))
+	for _, attr := range el.Attr {
+		if !safeAttribute(attr) {
+			continue
+		}
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		_ = xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// safeAttribute 过滤事件处理器、外部 URL 引用以及包含外部资源的内联样式
+func safeAttribute(attr xml.Attr) bool {
+	local := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	if urlAttributes[local] {
+		return safeReference(attr.Value)
+	}
+	if local == "style" {
+		return safeCSS(attr.Value)
+	}
+	// 表现属性（fill、filter、mask 等）同样按 CSS 解析，可以写 url(...)
+	return safeCSS(attr.Value)
+}
+
+// safeAnimation 拒绝修改事件处理器或链接目标的动画
+func safeAnimation(attrs []xml.Attr) bool {
+	for _, attr := range attrs {
+		if strings.ToLower(attr.Name.Local) != "attributename" {
+			continue
+		}
+		target := strings.ToLower(strings.TrimSpace(attr.Value))
+		if i := strings.LastIndex(target, ":"); i >= 0 {
+			target = target[i+1:]
+		}
+		if strings.HasPrefix(target, "on") || urlAttributes[target] || target == "style" {
+			return false
+		}
+	}
+	return true
+}
+
+// safeReference 只允许文档内片段引用和内嵌的位图 data URI
+func safeReference(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "#") {
+		return true
+	}
+	return dataImagePattern.MatchString(value)
+}
+
+// safeCSS 拒绝 @import、指向外部资源的 url() 以及 image-set()/src()；
+// CSS 转义（如 \75 rl(）可以绕过上述匹配，含反斜杠的样式整体丢弃
+func safeCSS(css string) bool {
+	if strings.Contains(css, `\`) {
+		return false
+	}
+	if cssImportPattern.MatchString(css) || cssStringURLPattern.MatchString(css) {
+		return false
+	}
+	lower := strings.ToLower(css)
+	if strings.Contains(lower, "javascript:") || strings.Contains(lower, "expression(") {
+		return false
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		if !safeReference(match[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Dimensions 从根元素的 width/height 或 viewBox 推断 SVG 的像素尺寸，无法推断时返回 0
+func Dimensions(data []byte) (int, int) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return 0, 0
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if strings.ToLower(el.Name.Local) != "svg" {
+			return 0, 0
+		}
+
+		var width, height float64
+		var viewBox string
+		for _, attr := range el.Attr {
+			switch strings.ToLower(attr.Name.Local) {
+			case "width":
+				width = parseLength(attr.Value)
+			case "height":
+				height = parseLength(attr.Value)
+			case "viewbox":
+				viewBox = attr.Value
+			}
+		}
+
+		if width <= 0 || height <= 0 {
+			fields := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+			if len(fields) == 4 {
+				vbWidth, errW := strconv.ParseFloat(fields[2], 64)
+				vbHeight, errH := strconv.ParseFloat(fields[3], 64)
+				if errW == nil && errH == nil && vbWidth > 0 && vbHeight > 0 {
+					switch {
+					case width > 0:
+						height = width * vbHeight / vbWidth
+					case height > 0:
+						width = height * vbWidth / vbHeight
+					default:
+						width, height = vbWidth, vbHeight
+					}
+				}
+			}
+		}
+
+		if width <= 0 || height <= 0 {
+			return 0, 0
+		}
+		return int(math.Round(width)), int(math.Round(height))
+	}
+}
+
+// parseLength 解析无单位或 px 单位的长度，百分比等相对单位返回 0
+func parseLength(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(value)), "px")
+	n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
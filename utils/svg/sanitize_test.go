@@ -0,0 +1,138 @@
+package svg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sanitizeString(t *testing.T, input string) string {
+	t.Helper()
+	out, err := Sanitize(strings.NewReader(input))
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestSanitize_RemovesScriptsAndForeignObject(t *testing.T) {
+	out := sanitizeString(t, `<svg xmlns="http://www.w3.org/2000/svg">
+<script>alert(1)</script>
+<foreignObject width="10" height="10"><div xmlns="http://www.w3.org/1999/xhtml"><iframe src="https://evil.example"/></div></foreignObject>
+<rect width="10" height="10" fill="red"/>
+</svg>`)
+
+	assert.NotContains(t, out, "script")
+	assert.NotContains(t, out, "alert")
+	assert.NotContains(t, out, "foreignObject")
+	assert.NotContains(t, out, "iframe")
+	assert.Contains(t, out, `<rect width="10" height="10" fill="red"></rect>`)
+}
+
+func TestSanitize_RemovesEventHandlers(t *testing.T) {
+	out := sanitizeString(t, `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><circle r="5" onClick="x()" /></svg>`)
+
+	assert.NotContains(t, strings.ToLower(out), "onload")
+	assert.NotContains(t, strings.ToLower(out), "onclick")
+	assert.Contains(t, out, `<circle r="5"></circle>`)
+}
+
+func TestSanitize_ExternalReferences(t *testing.T) {
+	out := sanitizeString(t, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+<defs><linearGradient id="g"/></defs>
+<use xlink:href="#g"/>
+<use href="https://evil.example/sprite.svg#icon"/>
+<image href="javascript:alert(1)"/>
+<image href="data:image/png;base64,AAAA"/>
+<a href="https://example.com"><text>link</text></a>
+<rect fill="url(#g)" style="fill:url(https://evil.example/x)"/>
+<rect filter="url(http://evil.example/f.svg#f)"/>
+<style>@import url(https://evil.example/a.css); rect { fill: red }</style>
+<style>circle { fill: url(#g) }</style>
+</svg>`)
+
+	assert.Contains(t, out, `xlink:href="#g"`)
+	assert.Contains(t, out, `xmlns:xlink="http://www.w3.org/1999/xlink"`)
+	assert.Contains(t, out, `href="data:image/png;base64,AAAA"`)
+	assert.Contains(t, out, `fill="url(#g)"`)
+	assert.Contains(t, out, `circle { fill: url(#g) }`)
+	assert.NotContains(t, out, "evil.example")
+	assert.NotContains(t, out, "javascript:")
+	assert.NotContains(t, out, "https://example.com")
+	assert.NotContains(t, out, "@import")
+}
+
+func TestSanitize_CSSEscapesAndStringReferences(t *testing.T) {
+	out := sanitizeString(t, `<svg xmlns="http://www.w3.org/2000/svg">
+<style>rect { fill: \75 rl(https://evil.example/a) }</style>
+<style>@\69 mport "https://evil.example/b.css";</style>
+<style>rect { fill: image-set("https://evil.example/c.png" 1x) }</style>
+<style>rect { fill: src("https://evil.example/d.png") }</style>
+<rect style="background:-webkit-image-set('https://evil.example/e.png' 1x)"/>
+<rect fill="u\72l(https://evil.example/f)"/>
+<style>rect { fill: blue }</style>
+</svg>`)
+
+	assert.NotContains(t, out, "evil.example")
+	assert.Contains(t, out, "rect { fill: blue }")
+}
+
+func TestSanitize_RemovesHrefAnimation(t *testing.T) {
+	out := sanitizeString(t, `<svg xmlns="http://www.w3.org/2000/svg"><a><set attributeName="href" to="javascript:alert(1)"/><text>x</text></a><animate attributeName="opacity" from="0" to="1"/></svg>`)
+
+	assert.NotContains(t, out, "javascript:")
+	assert.NotContains(t, out, "<set")
+	assert.Contains(t, out, `<animate attributeName="opacity" from="0" to="1"></animate>`)
+}
+
+func TestSanitize_DropsDoctypeAndComments(t *testing.T) {
+	out := sanitizeString(t, `<?xml version="1.0"?>
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd">
+<!-- comment -->
+<?xml-stylesheet href="https://evil.example/a.css"?>
+<svg xmlns="http://www.w3.org/2000/svg"><title>a &amp; b</title></svg>`)
+
+	assert.True(t, strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.NotContains(t, out, "DOCTYPE")
+	assert.NotContains(t, out, "comment")
+	assert.NotContains(t, out, "evil.example")
+	assert.Contains(t, out, "<title>a &amp; b</title>")
+}
+
+func TestSanitize_Rejects(t *testing.T) {
+	_, err := Sanitize(strings.NewReader(`<html><body></body></html>`))
+	assert.ErrorIs(t, err, ErrMissingSVG)
+
+	_, err = Sanitize(strings.NewReader(`<!DOCTYPE svg [<!ENTITY x "boom">]><svg>&x;</svg>`))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Sanitize(strings.NewReader(`<svg><g></svg>`))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = Sanitize(bytes.NewReader(make([]byte, MaxSize+1)))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestDimensions(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		width  int
+		height int
+	}{
+		{"width and height", `<svg width="120" height="80px"/>`, 120, 80},
+		{"viewBox only", `<?xml version="1.0"?><svg viewBox="0 0 64 32"/>`, 64, 32},
+		{"width with viewBox ratio", `<svg width="100" viewBox="0,0,50,25"/>`, 100, 50},
+		{"relative units fall back to viewBox", `<svg width="100%" height="100%" viewBox="0 0 24 24"/>`, 24, 24},
+		{"unknown", `<svg width="100%"/>`, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := Dimensions([]byte(tt.data))
+			assert.Equal(t, tt.width, width)
+			assert.Equal(t, tt.height, height)
+		})
+	}
+}
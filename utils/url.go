@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"html"
 	"net/url"
 	"strings"
 )
@@ -31,21 +32,29 @@ type LinkFormats struct {
 	MarkdownWithLink string `json:"markdown_with_link"`
 }
 
-// BuildLinkFormats 构建各种格式的图片链接
-func BuildLinkFormats(baseURL, identifier string) LinkFormats {
+// BuildLinkFormats 构建各种格式的图片链接，alt 为空时 HTML 使用空 alt，Markdown 使用标识符
+func BuildLinkFormats(baseURL, identifier, alt string) LinkFormats {
 	url := BuildImageURL(baseURL, identifier)
 	thumbnailURL := BuildThumbnailURL(baseURL, identifier)
 
+	markdownAlt := identifier
+	if alt != "" {
+		markdownAlt = markdownAltReplacer.Replace(alt)
+	}
+
 	return LinkFormats{
 		URL:              url,
 		ThumbnailURL:     thumbnailURL,
-		HTML:             fmt.Sprintf(`<img src="%s" alt="" />`, url),
+		HTML:             fmt.Sprintf(`<img src="%s" alt="%s" />`, url, html.EscapeString(alt)),
 		BBCode:           fmt.Sprintf(`[img]%s[/img]`, url),
-		Markdown:         fmt.Sprintf(`![%s](%s)`, identifier, url),
-		MarkdownWithLink: fmt.Sprintf(`[![%s](%s)](%s)`, identifier, thumbnailURL, url),
+		Markdown:         fmt.Sprintf(`![%s](%s)`, markdownAlt, url),
+		MarkdownWithLink: fmt.Sprintf(`[![%s](%s)](%s)`, markdownAlt, thumbnailURL, url),
 	}
 }
 
+// markdownAltReplacer 转义会截断 Markdown 图片替代文本的字符，换行折叠为空格
+var markdownAltReplacer = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "\r\n", " ", "\n", " ", "\r", " ")
+
 // ExtractCookieDomain 从 URL 中提取有效的 Cookie Domain
 func ExtractCookieDomain(rawURL string) string {
 	if rawURL == "" {
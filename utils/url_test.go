@@ -74,3 +74,21 @@ func TestExtractCookieDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildLinkFormats(t *testing.T) {
+	links := BuildLinkFormats("https://img.example.com", "abc123", `A "red" [kite]`)
+	if links.HTML != `<img src="https://img.example.com/images/abc123" alt="A &#34;red&#34; [kite]" />` {
+		t.Errorf("unexpected HTML: %s", links.HTML)
+	}
+	if links.Markdown != `![A "red" \[kite\]](https://img.example.com/images/abc123)` {
+		t.Errorf("unexpected Markdown: %s", links.Markdown)
+	}
+
+	links = BuildLinkFormats("https://img.example.com", "abc123", "")
+	if links.HTML != `<img src="https://img.example.com/images/abc123" alt="" />` {
+		t.Errorf("unexpected HTML without alt: %s", links.HTML)
+	}
+	if links.Markdown != `![abc123](https://img.example.com/images/abc123)` {
+		t.Errorf("unexpected Markdown without alt: %s", links.Markdown)
+	}
+}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"net/http"
 	"strings"
@@ -9,11 +10,17 @@ import (
 
 // allowedImageMimeTypes Allowed image types
 var allowedImageMimeTypes = map[string]bool{
-	"image/jpeg": true,
-	"image/png":  true,
-	"image/gif":  true,
-	"image/webp": true,
-	"image/bmp":  true,
+	"image/jpeg":    true,
+	"image/png":     true,
+	"image/gif":     true,
+	"image/webp":    true,
+	"image/bmp":     true,
+	"image/heic":    true,
+	"image/heif":    true,
+	"image/avif":    true,
+	"image/tiff":    true,
+	"image/jxl":     true,
+	"image/svg+xml": true,
 }
 
 // 文件魔数验证
@@ -23,6 +30,26 @@ var imageMagicNumbers = map[string][][]byte{
 	"image/gif":  {{0x47, 0x49, 0x46, 0x38, 0x37, 0x61}, {0x47, 0x49, 0x46, 0x38, 0x39, 0x61}},
 	"image/webp": {{0x52, 0x49, 0x46, 0x46}}, // RIFF header, need more check
 	"image/bmp":  {{0x42, 0x4D}},             // BM
+	// TIFF: II*\0（小端）/ MM\0*（大端）
+	"image/tiff": {{0x49, 0x49, 0x2A, 0x00}, {0x4D, 0x4D, 0x00, 0x2A}},
+	"image/jxl": {
+		{0xFF, 0x0A}, // 裸码流
+		{0x00, 0x00, 0x00, 0x0C, 0x4A, 0x58, 0x4C, 0x20, 0x0D, 0x0A, 0x87, 0x0A}, // ISOBMFF 容器
+	},
+}
+
+// ISOBMFF ftyp 品牌到 MIME 类型映射（HEIC/HEIF/AVIF 共用容器格式）
+var ftypBrandToMimeType = map[string]string{
+	"avif": "image/avif",
+	"avis": "image/avif",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"heim": "image/heic",
+	"heis": "image/heic",
+	"hevc": "image/heic",
+	"hevx": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
 }
 
 // extensionToMimeType 扩展名到 MIME 类型映射
@@ -33,6 +60,13 @@ var extensionToMimeType = map[string]string{
 	".gif":  "image/gif",
 	".webp": "image/webp",
 	".bmp":  "image/bmp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".avif": "image/avif",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".jxl":  "image/jxl",
+	".svg":  "image/svg+xml",
 }
 
 // IsImage Verify if the file content is an allowed image type.
@@ -63,7 +97,7 @@ func IsImage(file io.ReadSeeker, filename string) (bool, string, error) {
 		return false, "", err
 	}
 
-	detectedMime := http.DetectContentType(buffer)
+	detectedMime := DetectImageMimeType(buffer)
 	if detectedMime != expectedMime {
 		// 允许一些变体，如 image/jpeg 检测为 image/pjpeg
 		// WebP 可能不被系统支持，返回 application/octet-stream，但通过魔数验证即可
@@ -92,6 +126,19 @@ func validateMagicNumber(data []byte, mimeType string) bool {
 			bytes.Equal(data[8:12], []byte{0x57, 0x45, 0x42, 0x50}) // WEBP
 	}
 
+	// SVG 是文本格式，没有固定魔数
+	if mimeType == "image/svg+xml" {
+		return isSVG(data)
+	}
+
+	// HEIC/HEIF/AVIF 需要解析 ftyp 品牌
+	if _, ok := imageMagicNumbers[mimeType]; !ok {
+		if detected := detectISOBMFFImage(data); detected != "" {
+			return isCompatibleMime(detected, mimeType)
+		}
+		return false
+	}
+
 	magics, ok := imageMagicNumbers[mimeType]
 	if !ok {
 		return false
@@ -114,6 +161,9 @@ func isCompatibleMime(detected, expected string) bool {
 		"image/gif":  {"image/gif"},
 		"image/webp": {"image/webp"},
 		"image/bmp":  {"image/bmp", "image/x-bmp", "image/x-ms-bmp"},
+		"image/heic": {"image/heic", "image/heif"},
+		"image/heif": {"image/heif", "image/heic"},
+		"image/avif": {"image/avif"},
 	}
 
 	allowed, ok := compat[expected]
@@ -129,8 +179,91 @@ func isCompatibleMime(detected, expected string) bool {
 	return false
 }
 
+// DetectImageMimeType 嗅探文件头的 MIME 类型，补充 http.DetectContentType
+// 无法识别的 HEIC/HEIF/AVIF、TIFF、JPEG XL 和 SVG
+func DetectImageMimeType(data []byte) string {
+	if mimeType := detectISOBMFFImage(data); mimeType != "" {
+		return mimeType
+	}
+	if isSVG(data) {
+		return "image/svg+xml"
+	}
+	for _, mimeType := range []string{"image/tiff", "image/jxl"} {
+		for _, magic := range imageMagicNumbers[mimeType] {
+			if len(data) >= len(magic) && bytes.Equal(data[:len(magic)], magic) {
+				return mimeType
+			}
+		}
+	}
+	return http.DetectContentType(data)
+}
+
+// detectISOBMFFImage 根据 ftyp box 的主品牌和兼容品牌识别 HEIC/HEIF/AVIF
+func detectISOBMFFImage(data []byte) string {
+	if len(data) < 16 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return ""
+	}
+
+	boxSize := int(binary.BigEndian.Uint32(data[:4]))
+	if boxSize < 16 || boxSize > len(data) {
+		boxSize = len(data)
+	}
+
+	// 主品牌优先；mif1/msf1 等通用品牌再看兼容品牌中是否有更具体的类型
+	major, ok := ftypBrandToMimeType[string(data[8:12])]
+	if ok && major != "image/heif" {
+		return major
+	}
+	for offset := 16; offset+4 <= boxSize; offset += 4 {
+		if compat, found := ftypBrandToMimeType[string(data[offset:offset+4])]; found && compat != "image/heif" {
+			return compat
+		}
+	}
+	if ok {
+		return major
+	}
+	return ""
+}
+
+// isSVG 跳过 BOM、XML 声明、注释和 DOCTYPE 后检查根元素是否为 <svg>
+func isSVG(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	for {
+		data = bytes.TrimLeft(data, " \t\r\n")
+		switch {
+		case bytes.HasPrefix(data, []byte("<?")):
+			data = skipPast(data, "?>")
+		case bytes.HasPrefix(data, []byte("<!--")):
+			data = skipPast(data, "-->")
+		case bytes.HasPrefix(data, []byte("<!")):
+			data = skipPast(data, ">")
+		default:
+			if !bytes.HasPrefix(data, []byte("<svg")) || len(data) < 5 {
+				return false
+			}
+			switch data[4] {
+			case ' ', '\t', '\r', '\n', '>', '/':
+				return true
+			}
+			return false
+		}
+		if data == nil {
+			return false
+		}
+	}
+}
+
+// skipPast 返回 marker 之后的数据，找不到时返回 nil
+func skipPast(data []byte, marker string) []byte {
+	idx := bytes.Index(data, []byte(marker))
+	if idx < 0 {
+		return nil
+	}
+	return data[idx+len(marker):]
+}
+
 func IsImageBytes(data []byte) (bool, string) {
-	mimeType := http.DetectContentType(data)
+	mimeType := DetectImageMimeType(data)
 
 	if _, ok := allowedImageMimeTypes[mimeType]; ok {
 		return true, mimeType
@@ -195,16 +195,130 @@ func TestIsImageBytes_Empty(t *testing.T) {
 // TestAllowedImageMimeTypes 测试允许的图片类型列表
 func TestAllowedImageMimeTypes(t *testing.T) {
 	expectedTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-		"image/gif":  true,
-		"image/webp": true,
-		"image/bmp":  true,
+		"image/jpeg":    true,
+		"image/png":     true,
+		"image/gif":     true,
+		"image/webp":    true,
+		"image/bmp":     true,
+		"image/heic":    true,
+		"image/heif":    true,
+		"image/avif":    true,
+		"image/tiff":    true,
+		"image/jxl":     true,
+		"image/svg+xml": true,
 	}
 
 	assert.Equal(t, expectedTypes, allowedImageMimeTypes)
 }
 
+// isobmffHeader 构造 ftyp box：主品牌 + 兼容品牌
+func isobmffHeader(major string, compatible ...string) []byte {
+	size := 16 + 4*len(compatible)
+	data := []byte{0x00, 0x00, 0x00, byte(size)}
+	data = append(data, "ftyp"...)
+	data = append(data, major...)
+	data = append(data, 0x00, 0x00, 0x00, 0x00)
+	for _, brand := range compatible {
+		data = append(data, brand...)
+	}
+	return data
+}
+
+// TestDetectImageMimeType_ExtendedFormats 测试标准库无法识别的格式
+func TestDetectImageMimeType_ExtendedFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"heic major brand", isobmffHeader("heic", "mif1", "heic"), "image/heic"},
+		{"heif generic with heic compatible", isobmffHeader("mif1", "mif1", "heic"), "image/heic"},
+		{"heif generic only", isobmffHeader("mif1", "mif1", "miaf"), "image/heif"},
+		{"avif major brand", isobmffHeader("avif", "mif1", "miaf"), "image/avif"},
+		{"avif in compatible brands", isobmffHeader("mif1", "avif", "miaf"), "image/avif"},
+		{"tiff little endian", []byte{0x49, 0x49, 0x2A, 0x00, 0x08, 0x00}, "image/tiff"},
+		{"tiff big endian", []byte{0x4D, 0x4D, 0x00, 0x2A, 0x00, 0x08}, "image/tiff"},
+		{"jxl codestream", []byte{0xFF, 0x0A, 0xFA, 0x7F}, "image/jxl"},
+		{"jxl container", []byte{0x00, 0x00, 0x00, 0x0C, 0x4A, 0x58, 0x4C, 0x20, 0x0D, 0x0A, 0x87, 0x0A}, "image/jxl"},
+		{"mp4 is not an image", isobmffHeader("isom", "iso2", "mp41"), "video/mp4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectImageMimeType(tt.data))
+		})
+	}
+}
+
+// TestIsImage_ExtendedFormats 测试 HEIC/AVIF/TIFF/JXL 扩展名与魔数校验
+func TestIsImage_ExtendedFormats(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     []byte
+		want     string
+	}{
+		{"photo.heic", isobmffHeader("heic", "mif1", "heic"), "image/heic"},
+		{"photo.heif", isobmffHeader("mif1", "mif1", "heic"), "image/heif"},
+		{"photo.avif", isobmffHeader("avif", "mif1"), "image/avif"},
+		{"scan.tif", []byte{0x49, 0x49, 0x2A, 0x00, 0x08, 0x00}, "image/tiff"},
+		{"photo.jxl", []byte{0xFF, 0x0A, 0xFA, 0x7F}, "image/jxl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			isValid, mimeType, err := IsImage(bytes.NewReader(tt.data), tt.filename)
+			require.NoError(t, err)
+			assert.True(t, isValid)
+			assert.Equal(t, tt.want, mimeType)
+		})
+	}
+
+	isValid, _, err := IsImage(bytes.NewReader(isobmffHeader("avif", "mif1")), "photo.heic")
+	require.NoError(t, err)
+	assert.False(t, isValid, "avif content with heic extension should be rejected")
+}
+
+// TestIsImageBytes_ExtendedFormats 测试上传路径的内容嗅探
+func TestIsImageBytes_ExtendedFormats(t *testing.T) {
+	isValid, mimeType := IsImageBytes(isobmffHeader("heic", "mif1", "heic"))
+	assert.True(t, isValid)
+	assert.Equal(t, "image/heic", mimeType)
+
+	isValid, _ = IsImageBytes(isobmffHeader("isom", "mp41"))
+	assert.False(t, isValid)
+}
+
+// TestDetectImageMimeType_SVG 测试 SVG 文本嗅探
+func TestDetectImageMimeType_SVG(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"bare root", `<svg xmlns="http://www.w3.org/2000/svg"/>`, true},
+		{"xml declaration and comment", "\xEF\xBB\xBF<?xml version=\"1.0\"?>\n<!-- logo -->\n<svg width=\"10\">", true},
+		{"doctype", `<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "x.dtd"><svg>`, true},
+		{"html document", `<!DOCTYPE html><html><svg></svg></html>`, false},
+		{"svg prefix only", `<svgfoo>`, false},
+		{"unterminated comment", `<!-- <svg>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isValid, mimeType := IsImageBytes([]byte(tt.data))
+			assert.Equal(t, tt.want, isValid)
+			if tt.want {
+				assert.Equal(t, "image/svg+xml", mimeType)
+			}
+		})
+	}
+
+	isValid, mimeType, err := IsImage(bytes.NewReader([]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)), "logo.svg")
+	require.NoError(t, err)
+	assert.True(t, isValid)
+	assert.Equal(t, "image/svg+xml", mimeType)
+}
+
 // BenchmarkIsImage 基准测试
 func BenchmarkIsImage(b *testing.B) {
 	data := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46}